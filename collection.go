@@ -49,8 +49,55 @@ type Collection interface {
 	Exists() bool
 
 	// Find defines a new result set with elements from the collection.
+	//
+	// A struct, or a pointer to one, with fields for all the primary keys of
+	// the collection is turned into conditions on those keys, which saves
+	// spelling them out for tables with composite keys:
+	//
+	//   res = col.Find(Membership{GroupID: 3, UserID: 7})
+	//
+	// The result set returns an error if any of the key fields is zero or
+	// unset.
 	Find(...interface{}) Result
 
+	// Update updates the row that has the primary keys of the given struct, as
+	// Find would match it, with the values of the struct.
+	Update(interface{}) error
+
+	// Delete deletes the row that has the primary keys of the given struct, as
+	// Find would match it.
+	Delete(interface{}) error
+
+	// FindOrCreate scans the row that matches cond into item, which must be a
+	// pointer to a map or struct. If there is no such row, one is inserted
+	// with the values of item and those of cond on top, and then scanned into
+	// item. created tells whether the row was inserted:
+	//
+	//   tag := Tag{Color: "grey"}
+	//   created, err := col.FindOrCreate(db.Cond{"name": "urgent"}, &tag)
+	//
+	// cond may only have column = value conditions, and the columns of cond
+	// must form a UNIQUE constraint or primary key: the row is inserted with
+	// ON CONFLICT DO NOTHING on those columns, or its equivalent, and read
+	// back, so two sessions racing to create it end up with the same row.
+	// Without the constraint both inserts succeed and there may be duplicates.
+	//
+	// The insert and the read run in a transaction, unless the collection
+	// already belongs to one.
+	FindOrCreate(cond Cond, item interface{}) (created bool, err error)
+
+	// SetDefaultOrderBy sets the order of the result sets created with Find,
+	// unless they're given one with OrderBy, which replaces the default
+	// instead of adding to it. This keeps reads and pagination stable without
+	// repeating the order everywhere:
+	//
+	//   col.SetDefaultOrderBy("-created_at")
+	//
+	// The default is shared by every session that comes from the same one,
+	// including its transactions. Calling it without arguments removes the
+	// default.
+	SetDefaultOrderBy(fields ...interface{})
+
 	// Truncate removes all elements on the collection and resets the
 	// collection's IDs.
 	Truncate() error
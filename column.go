@@ -0,0 +1,83 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+// Column represents a reference to a column. Columns can be used as
+// arguments of db.Func(), where they are quoted as identifiers instead of
+// being bound as values.
+type Column interface {
+	// ColumnName returns the name of the column.
+	ColumnName() string
+
+	// Add returns an expression that adds v to the column.
+	Add(v interface{}) Expr
+
+	// Sub returns an expression that subtracts v from the column.
+	Sub(v interface{}) Expr
+
+	// Mul returns an expression that multiplies the column by v.
+	Mul(v interface{}) Expr
+
+	// Collate returns an ascending sort on the column that compares values
+	// using the given collation. See Order.Collate.
+	Collate(collation string) Order
+}
+
+// Col returns a reference to the given column.
+//
+// Examples:
+//
+//	// coalesce("nickname", "name")
+//	db.Func("coalesce", db.Col("nickname"), db.Col("name"))
+//
+//	// lower("artist"."name")
+//	db.Func("lower", db.Col("artist.name"))
+//
+//	// UPDATE "counters" SET "n" = "n" + $1
+//	sess.Update("counters").Set(db.Cond{"n": db.Col("n").Add(1)})
+func Col(name string) Column {
+	return dbColumn(name)
+}
+
+type dbColumn string
+
+func (c dbColumn) ColumnName() string {
+	return string(c)
+}
+
+func (c dbColumn) Add(v interface{}) Expr {
+	return newExpr(ExprOperatorAdd, c, v)
+}
+
+func (c dbColumn) Sub(v interface{}) Expr {
+	return newExpr(ExprOperatorSub, c, v)
+}
+
+func (c dbColumn) Mul(v interface{}) Expr {
+	return newExpr(ExprOperatorMul, c, v)
+}
+
+func (c dbColumn) Collate(collation string) Order {
+	return Asc(string(c)).Collate(collation)
+}
+
+var _ = Column(dbColumn(""))
@@ -23,6 +23,7 @@ package db
 
 import (
 	"reflect"
+	"strings"
 	"time"
 )
 
@@ -70,6 +71,9 @@ const (
 
 	ComparisonOperatorOnOrAfter
 	ComparisonOperatorOnOrBefore
+
+	ComparisonOperatorILike
+	ComparisonOperatorNotILike
 )
 
 type dbComparisonOperator struct {
@@ -148,7 +152,8 @@ func In(v interface{}) Comparison {
 	}
 }
 
-// NotIn indicates whether the argument is not part of the reference.
+// NotIn indicates whether the argument is not part of the reference. With an
+// empty list, the condition is always true.
 func NotIn(v interface{}) Comparison {
 	return &dbComparisonOperator{
 		t: ComparisonOperatorNotIn,
@@ -254,26 +259,55 @@ func IsNotDistinctFrom(v interface{}) Comparison {
 }
 */
 
-// Like indicates whether the reference matches the wildcard value.
-func Like(v string) Comparison {
+// likeEscapeChar is the escape character declared on the ESCAPE clause of
+// LIKE comparisons.
+const likeEscapeChar = `\`
+
+var likeEscaper = strings.NewReplacer(
+	likeEscapeChar, likeEscapeChar+likeEscapeChar,
+	"%", likeEscapeChar+"%",
+	"_", likeEscapeChar+"_",
+)
+
+// LikePattern is a LIKE pattern with escaped wildcards, as made by
+// EscapeLike. Comparisons with a LikePattern are compiled with an ESCAPE
+// clause that declares the escape character, plain strings are left to the
+// database's default.
+type LikePattern string
+
+// EscapeLike escapes the "%" and "_" wildcards and the escape character itself
+// so that term is matched literally when used as part of a LIKE pattern.
+// Wildcards may be added around it, the result is still a LikePattern:
+//
+//	db.Cond{"name": db.Like("%" + db.EscapeLike(term) + "%")}
+func EscapeLike(term string) LikePattern {
+	return LikePattern(likeEscaper.Replace(term))
+}
+
+// Like indicates whether the reference matches the wildcard value, a string
+// or a LikePattern. The value is always sent as a parameter, but the "%" and
+// "_" wildcards it contains are still interpreted by the database, use
+// EscapeLike on user-supplied input that is meant to be matched literally.
+func Like(v interface{}) Comparison {
 	return &dbComparisonOperator{
 		t: ComparisonOperatorLike,
 		v: v,
 	}
 }
 
-// NotLike indicates whether the reference does not match the wildcard value.
-func NotLike(v string) Comparison {
+// NotLike indicates whether the reference does not match the wildcard value,
+// see Like.
+func NotLike(v interface{}) Comparison {
 	return &dbComparisonOperator{
 		t: ComparisonOperatorNotLike,
 		v: v,
 	}
 }
 
-/*
 // ILike indicates whether the reference matches the wildcard value (case
-// insensitive).
-func ILike(v string) Comparison {
+// insensitive), see Like. Adapters without a native ILIKE operator fall back
+// to a case insensitive equivalent.
+func ILike(v interface{}) Comparison {
 	return &dbComparisonOperator{
 		t: ComparisonOperatorILike,
 		v: v,
@@ -281,14 +315,13 @@ func ILike(v string) Comparison {
 }
 
 // NotILike indicates whether the reference does not match the wildcard value
-// (case insensitive).
-func NotILike(v string) Comparison {
+// (case insensitive), see Like.
+func NotILike(v interface{}) Comparison {
 	return &dbComparisonOperator{
 		t: ComparisonOperatorNotILike,
 		v: v,
 	}
 }
-*/
 
 // RegExp indicates whether the reference matches the regexp pattern.
 func RegExp(v string) Comparison {
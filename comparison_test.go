@@ -0,0 +1,26 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestEscapeLike(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out string
+	}{
+		{``, ``},
+		{`foo`, `foo`},
+		{`100%`, `100\%`},
+		{`foo_bar`, `foo\_bar`},
+		{`C:\dir`, `C:\\dir`},
+		{`\%_`, `\\\%\_`},
+		{`%%__`, `\%\%\_\_`},
+	}
+
+	for _, testCase := range testCases {
+		if out := string(EscapeLike(testCase.in)); out != testCase.out {
+			t.Fatalf("EscapeLike(%q): expecting %q, got %q", testCase.in, testCase.out, out)
+		}
+	}
+}
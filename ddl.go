@@ -0,0 +1,45 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+// ColumnConstraint represents a constraint on a column definition, like
+// "NOT NULL". Column constraints are used by the DDL statements of
+// sqlbuilder, like CreateTable and AlterTable.
+type ColumnConstraint string
+
+// Column constraints understood by all SQL databases.
+const (
+	PrimaryKey = ColumnConstraint("PRIMARY KEY")
+	NotNull    = ColumnConstraint("NOT NULL")
+	Unique     = ColumnConstraint("UNIQUE")
+)
+
+// ColumnDefault returns a constraint that sets the default value of a column.
+// The given expression is used verbatim.
+//
+// Example:
+//
+//	// DEFAULT now()
+//	db.ColumnDefault("now()")
+func ColumnDefault(expr string) ColumnConstraint {
+	return ColumnConstraint("DEFAULT " + expr)
+}
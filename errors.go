@@ -23,6 +23,7 @@ package db
 
 import (
 	"errors"
+	"strconv"
 )
 
 // Error messages.
@@ -49,4 +50,86 @@ var (
 	ErrMissingConnURL           = errors.New(`upper: missing DSN`)
 	ErrNotImplemented           = errors.New(`upper: call not implemented`)
 	ErrAlreadyWithinTransaction = errors.New(`upper: already within a transaction`)
+	ErrNotWithinTransaction     = errors.New(`upper: not within a transaction`)
+	ErrTransactionClosed        = errors.New(`upper: transaction was already committed or rolled back`)
+	ErrSessionClosing           = errors.New(`upper: session is closing`)
+	ErrUniqueViolation          = errors.New(`upper: unique constraint violation`)
+	ErrForeignKeyViolation      = errors.New(`upper: foreign key constraint violation`)
+	ErrCheckViolation           = errors.New(`upper: check constraint violation`)
+	ErrNotNullViolation         = errors.New(`upper: not null constraint violation`)
+	ErrStaleObject              = errors.New(`upper: transaction aborted by a deadlock or a serialization failure, it can be retried`)
+	ErrLockNotAcquired          = errors.New(`upper: lock is held by someone else`)
+	ErrInvalidBatchSize         = errors.New(`upper: batch size must be greater than zero`)
+	ErrQueryTimeout             = errors.New(`upper: query timed out`)
 )
+
+// Error is returned by adapters in place of an error reported by the database
+// that matches one of the portable errors of this package, like
+// ErrUniqueViolation. Kind is the portable error and Unwrap returns the
+// original one:
+//
+//	if err, ok := err.(*db.Error); ok && err.Kind == db.ErrUniqueViolation {
+//		...
+//	}
+//
+// Error also implements Is, so errors.Is(err, db.ErrUniqueViolation) works
+// as well.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+// Error returns the message of the original error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the portable error.
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// LockHolder is a database session that holds a lock, see LockWaitError.
+type LockHolder struct {
+	// PID identifies the session, like the backend PID on PostgreSQL or the
+	// session ID on SQL Server.
+	PID int64
+
+	// Query is the last statement the session sent, it may be empty.
+	Query string
+}
+
+// LockWaitError is returned in place of a timeout or lock error when lock
+// diagnostics are enabled (see Settings.SetLockDiagnostics). It lists the
+// sessions that held locks on Table right after the statement failed, which
+// are likely the ones it was waiting on. Unwrap returns the original error.
+type LockWaitError struct {
+	Table   string
+	Holders []LockHolder
+	Err     error
+}
+
+// Error returns the original message followed by the lock holders.
+func (e *LockWaitError) Error() string {
+	msg := e.Err.Error() + " (locks on " + e.Table + " held by"
+	for i, h := range e.Holders {
+		if i > 0 {
+			msg += ","
+		}
+		msg += " " + strconv.FormatInt(h.PID, 10)
+		if h.Query != "" {
+			msg += " " + strconv.Quote(h.Query)
+		}
+	}
+	return msg + ")"
+}
+
+// Unwrap returns the original error.
+func (e *LockWaitError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+// Operation is the kind of change an Event reports.
+type Operation string
+
+// Operations reported by events.
+const (
+	OperationInsert Operation = "insert"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+// Event reports a change the session made to a table through a collection,
+// like Insert, InsertReturning, UpdateReturning or the Update and Delete
+// methods of a Result, which only report it when some row changed. Changes made within a transaction are reported once
+// it's committed and not at all if it's rolled back. Events are only about the
+// changes made by the process that reports them, other clients are not
+// observed.
+type Event struct {
+	// Operation is the kind of change.
+	Operation Operation
+
+	// Table is the name of the table that was changed.
+	Table string
+
+	// Keys holds the primary keys of the affected rows in the form
+	// Collection.Insert returns them: the value of the key, or a Cond with
+	// the value of each column for composite keys. On PostgreSQL the keys of
+	// the rows a Result updates or deletes are read from RETURNING, other
+	// databases only know them when the Result was made by Find with primary
+	// key values. Keys is nil when the rows are not known, like when a Result
+	// with other conditions is updated or deleted on those databases, or the
+	// table is truncated; subscribers should then assume any row of the table
+	// could have changed.
+	Keys []interface{}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+// Expr represents an arithmetic or string expression that is compiled into
+// SQL instead of being bound as a value. Operands may be columns (see db.Col),
+// functions, raw values or other expressions, anything else is bound as a
+// value.
+type Expr interface {
+	// Operator returns the expression operator.
+	Operator() ExprOperator
+
+	// Operands returns the expression operands.
+	Operands() []interface{}
+
+	// Add returns an expression that adds v to this expression.
+	Add(v interface{}) Expr
+
+	// Sub returns an expression that subtracts v from this expression.
+	Sub(v interface{}) Expr
+
+	// Mul returns an expression that multiplies this expression by v.
+	Mul(v interface{}) Expr
+}
+
+// ExprOperator is a type we use to label expression operators.
+type ExprOperator uint8
+
+// Expression operators
+const (
+	ExprOperatorNone ExprOperator = iota
+
+	ExprOperatorAdd
+	ExprOperatorSub
+	ExprOperatorMul
+
+	ExprOperatorConcat
+)
+
+// Concat returns an expression that concatenates the given strings, each
+// adapter uses its own concatenation operator.
+//
+// Example:
+//
+//	// "first_name" || $1 || "last_name"
+//	db.Concat(db.Col("first_name"), " ", db.Col("last_name"))
+func Concat(args ...interface{}) Expr {
+	return &dbExpr{op: ExprOperatorConcat, operands: args}
+}
+
+type dbExpr struct {
+	op       ExprOperator
+	operands []interface{}
+}
+
+func newExpr(op ExprOperator, a, b interface{}) Expr {
+	return &dbExpr{op: op, operands: []interface{}{a, b}}
+}
+
+func (e *dbExpr) Operator() ExprOperator {
+	return e.op
+}
+
+func (e *dbExpr) Operands() []interface{} {
+	return e.operands
+}
+
+func (e *dbExpr) Add(v interface{}) Expr {
+	return newExpr(ExprOperatorAdd, e, v)
+}
+
+func (e *dbExpr) Sub(v interface{}) Expr {
+	return newExpr(ExprOperatorSub, e, v)
+}
+
+func (e *dbExpr) Mul(v interface{}) Expr {
+	return newExpr(ExprOperatorMul, e, v)
+}
+
+var _ = Expr(&dbExpr{})
@@ -51,6 +51,17 @@ type Function interface {
 //
 //	// RTRIM("Hello  ")
 //	db.Func("RTRIM", "Hello  ")
+//
+// Arguments are bound as values, wrap column names with db.Col to pass them as
+// quoted identifiers instead:
+//
+//	// coalesce("nickname", "name")
+//	db.Func("coalesce", db.Col("nickname"), db.Col("name"))
+//
+// Functions can be used as columns, as values and as condition keys:
+//
+//	// SELECT * FROM "artist" WHERE (lower("name") = $1)
+//	sess.SelectFrom("artist").Where(db.Func("lower", db.Col("name")), "bob")
 func Func(name string, args ...interface{}) Function {
 	if len(args) == 1 {
 		if reflect.TypeOf(args[0]).Kind() == reflect.Slice {
@@ -91,7 +91,8 @@ func (c *Cache) ReadRaw(h Hashable) (interface{}, bool) {
 	return nil, false
 }
 
-// Write stores a value in memory. If the value already exists its overwritten.
+// Write stores a value in memory. If the value already exists its
+// overwritten, and purged if it's not the same value.
 func (c *Cache) Write(h Hashable, value interface{}) {
 	key := h.Hash()
 
@@ -99,6 +100,9 @@ func (c *Cache) Write(h Hashable, value interface{}) {
 	defer c.mu.Unlock()
 
 	if el, ok := c.cache[key]; ok {
+		if p, ok := el.Value.(*item).value.(HasOnPurge); ok && p != value {
+			p.OnPurge()
+		}
 		el.Value.(*item).value = value
 		c.li.MoveToFront(el)
 		return
@@ -71,6 +71,30 @@ func TestCacheReadExistentValue(t *testing.T) {
 	}
 }
 
+type purgeableT struct {
+	purged bool
+}
+
+func (p *purgeableT) OnPurge() {
+	p.purged = true
+}
+
+func TestCacheOverwritePurges(t *testing.T) {
+	c := NewCache()
+
+	first, second := &purgeableT{}, &purgeableT{}
+	c.Write(&key, first)
+	c.Write(&key, first)
+	if first.purged {
+		t.Fatal("Expecting the value not to be purged.")
+	}
+
+	c.Write(&key, second)
+	if !first.purged || second.purged {
+		t.Fatal("Expecting the overwritten value to be purged.")
+	}
+}
+
 func BenchmarkNewCache(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		NewCache()
@@ -0,0 +1,189 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/reflectx"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+var errBatchMissingKey = errors.New("EachBatch needs the primary key %q in each item")
+
+// hasPrimaryKeys is implemented by the sessions that can tell the primary
+// keys of a table.
+type hasPrimaryKeys interface {
+	PrimaryKeys(tableName string) ([]string, error)
+}
+
+// EachBatch walks over the result set in batches of up to batchSize items,
+// using the primary key as cursor. The primary key columns are added to the
+// selected fields when they're missing, so that each batch can be read from
+// the last item of the previous one.
+func (r *Result) EachBatch(ctx context.Context, batchSize int, sliceOfStructs interface{}, fn func() error) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if batchSize < 1 {
+		return db.ErrInvalidBatchSize
+	}
+
+	sess, ok := r.SQLBuilder().(hasPrimaryKeys)
+	if !ok {
+		return db.ErrUnsupported
+	}
+
+	res, err := r.fastForward()
+	if err != nil {
+		return err
+	}
+
+	pks, err := sess.PrimaryKeys(res.table)
+	if err != nil {
+		return err
+	}
+	if len(pks) == 0 {
+		return fmt.Errorf(errMissingPrimaryKeys.Error(), res.table)
+	}
+
+	columns := make([]interface{}, len(pks))
+	for i := range pks {
+		columns[i] = pks[i]
+	}
+
+	fields := res.fields
+	if len(fields) > 0 {
+		fields = withColumns(fields, pks)
+	}
+
+	var last db.Cond
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		sel := r.SQLBuilder().Select(fields...).
+			From(res.table).
+			OrderBy(columns...).
+			Limit(batchSize)
+		res.applyConds(
+			func(conds ...interface{}) { sel = sel.And(conds...) },
+			func(conds ...interface{}) { sel = sel.Or(conds...) },
+		)
+		if last != nil {
+			sel = sel.And(keysAfter(pks, last))
+		}
+
+		if err := sel.IteratorContext(ctx).All(sliceOfStructs); err != nil {
+			return err
+		}
+
+		items := reflect.Indirect(reflect.ValueOf(sliceOfStructs))
+		if items.Len() == 0 {
+			return nil
+		}
+
+		if last, err = itemKey(pks, items.Index(items.Len()-1)); err != nil {
+			return err
+		}
+
+		if err := fn(); err != nil {
+			return err
+		}
+
+		if items.Len() < batchSize {
+			return nil
+		}
+	}
+}
+
+// withColumns appends to fields the columns that are not already part of
+// it.
+func withColumns(fields []interface{}, columns []string) []interface{} {
+	out := append([]interface{}{}, fields...)
+	for _, column := range columns {
+		found := false
+		for _, field := range fields {
+			if field == column {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, column)
+		}
+	}
+	return out
+}
+
+// itemKey reads the values of the pks columns from a map or from a struct
+// mapped with db tags.
+func itemKey(pks []string, item reflect.Value) (db.Cond, error) {
+	for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+		item = item.Elem()
+	}
+
+	row := make(map[string]interface{}, len(pks))
+	for _, pk := range pks {
+		switch item.Kind() {
+		case reflect.Map:
+			v := item.MapIndex(reflect.ValueOf(pk))
+			if !v.IsValid() {
+				return nil, fmt.Errorf(errBatchMissingKey.Error(), pk)
+			}
+			row[pk] = v.Interface()
+		case reflect.Struct:
+			fi, ok := mapper.TypeMap(item.Type()).Names[pk]
+			if !ok {
+				return nil, fmt.Errorf(errBatchMissingKey.Error(), pk)
+			}
+			row[pk] = reflectx.FieldByIndexesReadOnly(item, fi.Index).Interface()
+		default:
+			return nil, sqlbuilder.ErrExpectingMapOrStruct
+		}
+	}
+	return rowKey(pks, row), nil
+}
+
+// keysAfter returns a condition that matches the rows that come after the
+// given key when sorting by pks.
+func keysAfter(pks []string, key db.Cond) db.Compound {
+	if len(pks) == 1 {
+		return db.Cond{pks[0] + " >": key[pks[0]]}
+	}
+
+	conds := make([]db.Compound, len(pks))
+	for i := range pks {
+		cond := db.Cond{}
+		for _, pk := range pks[:i] {
+			cond[pk] = key[pk]
+		}
+		cond[pks[i]+" >"] = key[pks[i]]
+		conds[i] = cond
+	}
+	return db.Or(conds...)
+}
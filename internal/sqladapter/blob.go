@@ -0,0 +1,191 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"context"
+	"io"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// blobChunkSize is the number of bytes StreamColumn and WriteColumn send in a
+// single statement.
+var blobChunkSize = 1 << 20
+
+// hasBlobChunks is implemented by the adapters that can read and write binary
+// columns in chunks.
+type hasBlobChunks interface {
+	// BlobChunk returns an expression that takes a part of column, given its
+	// 1-based start and its length as arguments.
+	BlobChunk(column string) string
+
+	// BlobAppend returns an assignment that appends its argument to column.
+	BlobAppend(column string) string
+}
+
+var _ = sqlbuilder.ColumnStreamer(&Result{})
+
+func (r *Result) blobChunks() (hasBlobChunks, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	blobs, ok := r.SQLBuilder().(hasBlobChunks)
+	if !ok {
+		return nil, db.ErrUnsupported
+	}
+	return blobs, nil
+}
+
+// StreamColumn returns a reader of the value of column on the first row of
+// the result set.
+func (r *Result) StreamColumn(ctx context.Context, column string) (io.ReadCloser, error) {
+	blobs, err := r.blobChunks()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.fastForward()
+	if err != nil {
+		return nil, err
+	}
+
+	expr := blobs.BlobChunk(column)
+	cr := &columnReader{
+		ctx: ctx,
+		sel: func(pos int) sqlbuilder.Selector {
+			sel := r.SQLBuilder().Select(db.Raw(expr, pos, blobChunkSize)).
+				From(res.table).
+				OrderBy(res.orderBy...).
+				Offset(res.offset).
+				Limit(1)
+			res.applyConds(
+				func(conds ...interface{}) { sel = sel.And(conds...) },
+				func(conds ...interface{}) { sel = sel.Or(conds...) },
+			)
+			return sel
+		},
+		pos: 1,
+	}
+
+	// Reading the first chunk tells whether the row exists.
+	if err := cr.fetch(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// WriteColumn replaces the value of column on every row of the result set
+// with the contents of src.
+func (r *Result) WriteColumn(ctx context.Context, column string, src io.Reader) error {
+	blobs, err := r.blobChunks()
+	if err != nil {
+		return err
+	}
+
+	res, err := r.fastForward()
+	if err != nil {
+		return err
+	}
+
+	update := func(values ...interface{}) (int64, error) {
+		upd := r.SQLBuilder().Update(res.table).Set(values...)
+		res.applyConds(
+			func(conds ...interface{}) { upd = upd.And(conds...) },
+			func(conds ...interface{}) { upd = upd.Or(conds...) },
+		)
+		result, err := upd.ExecContext(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+
+	changed, err := update(column, []byte{})
+	if err != nil {
+		return err
+	}
+
+	expr := blobs.BlobAppend(column)
+	buf := make([]byte, blobChunkSize)
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			if _, err := update(db.Raw(expr, buf[:n])); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if changed > 0 {
+				r.emit(db.OperationUpdate, nil)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// columnReader reads a binary column in chunks.
+type columnReader struct {
+	ctx context.Context
+	sel func(pos int) sqlbuilder.Selector
+	pos int
+	buf []byte
+	eof bool
+}
+
+func (cr *columnReader) fetch() error {
+	var chunk []byte
+	if err := cr.sel(cr.pos).IteratorContext(cr.ctx).ScanOne(&chunk); err != nil {
+		return err
+	}
+	cr.pos += len(chunk)
+	cr.buf = chunk
+	cr.eof = len(chunk) < blobChunkSize
+	return nil
+}
+
+func (cr *columnReader) Read(p []byte) (int, error) {
+	for len(cr.buf) == 0 {
+		if cr.eof {
+			return 0, io.EOF
+		}
+		if err := cr.fetch(); err != nil {
+			if err == db.ErrNoMoreRows {
+				// The row went away between chunks.
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+	}
+	n := copy(p, cr.buf)
+	cr.buf = cr.buf[n:]
+	return n, nil
+}
+
+func (cr *columnReader) Close() error {
+	cr.buf, cr.eof = nil, true
+	return nil
+}
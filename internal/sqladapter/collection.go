@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 
 	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
@@ -12,7 +13,11 @@ import (
 
 var mapper = reflectx.NewMapper("db")
 
-var errMissingPrimaryKeys = errors.New("Table %q has no primary keys")
+var (
+	errMissingPrimaryKeys = errors.New("Table %q has no primary keys")
+	errZeroPrimaryKey     = errors.New("Primary key %q of table %q is zero or unset")
+	errExpectingKeyStruct = errors.New("Expecting a struct with the primary keys of table %q but got %T")
+)
 
 // Collection represents a SQL table.
 type Collection interface {
@@ -52,8 +57,21 @@ type BaseCollection interface {
 	// database.
 	UpdateReturning(interface{}) error
 
+	// Update updates the row that has the primary keys of the given struct.
+	Update(interface{}) error
+
+	// Delete deletes the row that has the primary keys of the given struct.
+	Delete(interface{}) error
+
+	// FindOrCreate scans the row that matches cond into item, inserting it
+	// first if it doesn't exist.
+	FindOrCreate(cond db.Cond, item interface{}) (created bool, err error)
+
 	// PrimaryKeys returns the table's primary keys.
 	PrimaryKeys() []string
+
+	// SetDefaultOrderBy sets the order of the result sets created with Find.
+	SetDefaultOrderBy(fields ...interface{})
 }
 
 type condsFilter interface {
@@ -104,11 +122,107 @@ func (c *collection) Find(conds ...interface{}) db.Result {
 		res.setErr(c.err)
 		return res
 	}
-	return NewResult(
+	if len(conds) == 1 {
+		cond, ok, err := c.primaryKeyCond(conds[0])
+		if err != nil {
+			res := &Result{}
+			res.setErr(err)
+			return res
+		}
+		if ok {
+			conds = []interface{}{cond}
+		}
+	}
+	res := NewResult(
 		c.Database(),
 		c.Name(),
 		c.filterConds(conds...),
 	)
+	if orderBy := c.Database().DefaultOrderBy(c.Name()); len(orderBy) > 0 {
+		return res.OrderBy(orderBy...)
+	}
+	return res
+}
+
+// primaryKeyCond returns a db.Cond with the values of the primary key fields
+// of the given struct. ok is false if item is not a struct, or a pointer to
+// one, with fields for all the primary keys.
+func (c *collection) primaryKeyCond(item interface{}) (cond db.Cond, ok bool, err error) {
+	if len(c.pk) == 0 {
+		return nil, false, nil
+	}
+
+	v := reflect.ValueOf(item)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false, nil
+	}
+
+	fieldMap := mapper.TypeMap(v.Type()).Names
+	cond = db.Cond{}
+	for _, pk := range c.pk {
+		fi, ok := fieldMap[pk]
+		if !ok {
+			return nil, false, nil
+		}
+		f := reflectx.ValidFieldByIndexes(v, fi.Index)
+		if !f.IsValid() || reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface()) {
+			return nil, true, fmt.Errorf(errZeroPrimaryKey.Error(), pk, c.Name())
+		}
+		cond[pk] = db.Eq(f.Interface())
+	}
+	return cond, true, nil
+}
+
+// keyedResult returns a result set with the row that has the primary keys of
+// the given struct.
+func (c *collection) keyedResult(item interface{}) (db.Result, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if len(c.pk) == 0 {
+		if !c.Exists() {
+			return nil, db.ErrCollectionDoesNotExist
+		}
+		return nil, fmt.Errorf(errMissingPrimaryKeys.Error(), c.Name())
+	}
+	cond, ok, err := c.primaryKeyCond(item)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf(errExpectingKeyStruct.Error(), c.Name(), item)
+	}
+	return c.Find(cond), nil
+}
+
+// Update updates the row that has the primary keys of the given struct with
+// the values of the struct.
+func (c *collection) Update(item interface{}) error {
+	res, err := c.keyedResult(item)
+	if err != nil {
+		return err
+	}
+	return res.Update(item)
+}
+
+// Delete deletes the row that has the primary keys of the given struct.
+func (c *collection) Delete(item interface{}) error {
+	res, err := c.keyedResult(item)
+	if err != nil {
+		return err
+	}
+	return res.Delete()
+}
+
+// SetDefaultOrderBy sets the order of the result sets created with Find.
+func (c *collection) SetDefaultOrderBy(fields ...interface{}) {
+	c.Database().SetDefaultOrderBy(c.Name(), fields)
 }
 
 // Exists returns true if the collection exists.
@@ -324,5 +438,31 @@ func (c *collection) Truncate() error {
 	if _, err := c.Database().Exec(&stmt); err != nil {
 		return err
 	}
+	c.Database().Emit(db.Event{Operation: db.OperationDelete, Table: c.Name()})
 	return nil
 }
+
+// defaultOrders holds the default order of each table.
+type defaultOrders struct {
+	mu sync.RWMutex
+	m  map[string][]interface{}
+}
+
+func (o *defaultOrders) set(table string, fields []interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(fields) == 0 {
+		delete(o.m, table)
+		return
+	}
+	if o.m == nil {
+		o.m = make(map[string][]interface{})
+	}
+	o.m[table] = append([]interface{}(nil), fields...)
+}
+
+func (o *defaultOrders) get(table string) []interface{} {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.m[table]
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// InformationSchemaColumn maps a row of information_schema.columns, adapters
+// that support the standard information schema can use it to implement
+// column introspection.
+type InformationSchemaColumn struct {
+	Name       string         `db:"column_name"`
+	Position   int            `db:"ordinal_position"`
+	DataType   string         `db:"data_type"`
+	MaxLength  sql.NullInt64  `db:"character_maximum_length"`
+	Precision  sql.NullInt64  `db:"numeric_precision"`
+	Scale      sql.NullInt64  `db:"numeric_scale"`
+	IsNullable string         `db:"is_nullable"`
+	Default    sql.NullString `db:"column_default"`
+}
+
+// InformationSchemaColumns are the columns that need to be selected from
+// information_schema.columns to fill an InformationSchemaColumn.
+var InformationSchemaColumns = []interface{}{
+	"column_name",
+	"ordinal_position",
+	"data_type",
+	"character_maximum_length",
+	"numeric_precision",
+	"numeric_scale",
+	"is_nullable",
+	"column_default",
+}
+
+// Descriptor converts the row into a sqlbuilder.ColumnDescriptor.
+func (c *InformationSchemaColumn) Descriptor() sqlbuilder.ColumnDescriptor {
+	desc := sqlbuilder.ColumnDescriptor{
+		Name:     c.Name,
+		Position: c.Position,
+		DataType: c.DataType,
+		Nullable: strings.EqualFold(c.IsNullable, "YES"),
+	}
+	if c.MaxLength.Valid && c.MaxLength.Int64 > 0 {
+		desc.MaxLength = &c.MaxLength.Int64
+	}
+	if c.Precision.Valid {
+		desc.Precision = &c.Precision.Int64
+	}
+	if c.Scale.Valid {
+		desc.Scale = &c.Scale.Int64
+	}
+	if c.Default.Valid {
+		desc.Default = &c.Default.String
+	}
+	return desc
+}
+
+// SplitTableName splits a schema-qualified table name, like "public.users",
+// into its schema and table parts. The schema is empty if name is not
+// qualified.
+func SplitTableName(name string) (schema string, table string) {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
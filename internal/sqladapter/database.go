@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"math"
-	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -36,6 +35,20 @@ type hasConvertValues interface {
 	ConvertValues(values []interface{}) []interface{}
 }
 
+// hasCheckStatement is implemented by adapters that can tell a statement
+// won't run on the server before sending it, like when the server is too old
+// for some syntax, and return a clearer error than the server's.
+type hasCheckStatement interface {
+	CheckStatement(stmt *exql.Statement) error
+}
+
+// hasExecFallback is implemented by adapters that run statements their server
+// can't run as other statements with the same effect, like upserts on old
+// versions. handled is false for the statements they leave alone.
+type hasExecFallback interface {
+	ExecFallback(ctx context.Context, stmt *exql.Statement, args []interface{}) (res sql.Result, handled bool, err error)
+}
+
 // Database represents a SQL database.
 type Database interface {
 	PartialDatabase
@@ -91,9 +104,50 @@ type BaseDatabase interface {
 	// Close closes the database session
 	Close() error
 
+	// CloseContext stops accepting new work on the session and waits for
+	// in-flight transactions to finish before closing it.
+	CloseContext(context.Context) error
+
 	// Ping checks if the database server is reachable.
 	Ping() error
 
+	// Warmup opens up to n connections and leaves them idle in the pool.
+	Warmup(ctx context.Context, n int) error
+
+	// SetDefaultOrderBy sets the order Find uses on the given table, see
+	// db.Collection.SetDefaultOrderBy.
+	SetDefaultOrderBy(table string, fields []interface{})
+
+	// DefaultOrderBy returns the order Find uses on the given table.
+	DefaultOrderBy(table string) []interface{}
+
+	// Subscribe calls fn with the events of the changes made to table, see
+	// sqlbuilder.Database.Subscribe.
+	Subscribe(table string, fn func(db.Event)) (unsubscribe func())
+
+	// Emit reports an event to the subscribers, once the session's
+	// transaction is committed if it runs one.
+	Emit(ev db.Event)
+
+	// Subscribed returns true if someone is subscribed to the events of
+	// table.
+	Subscribed(table string) bool
+
+	// Outbox writes an event to the outbox table within the session's
+	// transaction, see sqlbuilder.Tx.Outbox.
+	Outbox(event interface{}) error
+
+	// DrainOutbox passes the unpublished messages of the outbox table to
+	// handler, see sqlbuilder.Database.DrainOutbox.
+	DrainOutbox(ctx context.Context, handler func(sqlbuilder.OutboxMessage) error) error
+
+	// SetResultCache sets the cache of the queries marked with
+	// sqlbuilder.Selector.Cacheable.
+	SetResultCache(cache sqlbuilder.ResultCache)
+
+	// ResultCache returns the result cache, or nil within transactions.
+	ResultCache() sqlbuilder.ResultCache
+
 	// ClearCache clears all caches the session is using
 	ClearCache()
 
@@ -134,6 +188,9 @@ type BaseDatabase interface {
 
 	// SetTxOptions sets default TxOptions for the session.
 	SetTxOptions(txOptions sql.TxOptions)
+
+	// ConvertValues converts native values into driver specific values.
+	ConvertValues(values []interface{}) []interface{}
 }
 
 // NewBaseDatabase provides a BaseDatabase given a PartialDatabase
@@ -143,6 +200,10 @@ func NewBaseDatabase(p PartialDatabase) BaseDatabase {
 		PartialDatabase:   p,
 		cachedCollections: cache.NewCache(),
 		cachedStatements:  cache.NewCache(),
+		drainer:           newDrainer(),
+		defaultOrders:     &defaultOrders{},
+		events:            newEventBus(),
+		resultCache:       &resultCache{},
 	}
 	return d
 }
@@ -167,6 +228,13 @@ type database struct {
 	sessID uint64
 	txID   uint64
 
+	drainer       *drainer       // shared with clones
+	defaultOrders *defaultOrders // shared with clones
+	events        *eventBus      // shared with clones
+	resultCache   *resultCache   // shared with clones
+	txActive      int32          // 1 if this session holds a transaction slot in drainer
+	txBound       int32          // 1 if this session was ever bound to a transaction
+
 	cacheMu           sync.Mutex // guards cachedStatements and cachedCollections
 	cachedStatements  *cache.Cache
 	cachedCollections *cache.Cache
@@ -180,6 +248,8 @@ var (
 
 // Session returns the underlying *sql.DB
 func (d *database) Session() *sql.DB {
+	d.sessMu.Lock()
+	defer d.sessMu.Unlock()
 	return d.sess
 }
 
@@ -220,11 +290,25 @@ func (d *database) TxOptions() *sql.TxOptions {
 
 // BindTx binds a *sql.Tx into *database
 func (d *database) BindTx(ctx context.Context, t *sql.Tx) error {
+	if err := d.drainer.acquire(); err != nil {
+		t.Rollback()
+		return err
+	}
+	atomic.StoreInt32(&d.txActive, 1)
+	atomic.StoreInt32(&d.txBound, 1)
+
 	d.sessMu.Lock()
-	defer d.sessMu.Unlock()
+	d.baseTx = newBaseTx(ctx, t, d.events)
+	d.sessMu.Unlock()
 
-	d.baseTx = newBaseTx(t)
 	if err := d.Ping(); err != nil {
+		t.Rollback()
+		d.sessMu.Lock()
+		d.baseTx = nil
+		d.sessMu.Unlock()
+		if atomic.CompareAndSwapInt32(&d.txActive, 1, 0) {
+			d.drainer.release()
+		}
 		return err
 	}
 
@@ -236,6 +320,8 @@ func (d *database) BindTx(ctx context.Context, t *sql.Tx) error {
 // Tx returns a BaseTx, which, if not nil, means that this session is within a
 // transaction
 func (d *database) Transaction() BaseTx {
+	d.sessMu.Lock()
+	defer d.sessMu.Unlock()
 	return d.baseTx
 }
 
@@ -274,9 +360,114 @@ func (d *database) BindSession(sess *sql.DB) error {
 // Ping checks whether a connection to the database is still alive by pinging
 // it
 func (d *database) Ping() error {
-	if d.sess != nil {
-		return d.sess.Ping()
+	if sess := d.Session(); sess != nil {
+		return sess.Ping()
+	}
+	return nil
+}
+
+// SetDefaultOrderBy sets the order Find uses on the given table.
+func (d *database) SetDefaultOrderBy(table string, fields []interface{}) {
+	d.defaultOrders.set(table, fields)
+}
+
+// DefaultOrderBy returns the order Find uses on the given table.
+func (d *database) DefaultOrderBy(table string) []interface{} {
+	return d.defaultOrders.get(table)
+}
+
+// Subscribe calls fn with the events of the changes made to table, or to all
+// tables if it's empty, through this session and its clones. It returns a
+// function that stops the calls.
+func (d *database) Subscribe(table string, fn func(db.Event)) (unsubscribe func()) {
+	return d.events.subscribe(table, fn)
+}
+
+// Emit reports ev to the subscribers of the session right away or, if the
+// session runs a transaction, once the transaction is committed.
+func (d *database) Emit(ev db.Event) {
+	if tx, ok := d.Transaction().(*baseTx); ok {
+		tx.queue(ev)
+		return
+	}
+	d.events.publish(ev)
+}
+
+// Subscribed returns true if someone is subscribed to the events of table,
+// or to the events of all tables.
+func (d *database) Subscribed(table string) bool {
+	return d.events.subscribed(table)
+}
+
+// SetResultCache sets the cache of the queries marked with Cacheable for the
+// session and its clones.
+func (d *database) SetResultCache(cache sqlbuilder.ResultCache) {
+	d.resultCache.set(cache)
+}
+
+// ResultCache returns the cache set with SetResultCache, or nil if the
+// session runs a transaction, so its queries skip the cache.
+func (d *database) ResultCache() sqlbuilder.ResultCache {
+	if d.Transaction() != nil {
+		return nil
+	}
+	return d.resultCache.get()
+}
+
+// resultCache holds the result cache of a session.
+type resultCache struct {
+	mu    sync.RWMutex
+	cache sqlbuilder.ResultCache
+}
+
+func (c *resultCache) set(cache sqlbuilder.ResultCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache = cache
+}
+
+func (c *resultCache) get() sqlbuilder.ResultCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cache
+}
+
+// Warmup opens and pings up to n connections and returns them to the pool,
+// where they stay idle.
+func (d *database) Warmup(ctx context.Context, n int) error {
+	sess := d.Session()
+	if sess == nil {
+		return db.ErrNotConnected
+	}
+
+	if max := d.MaxOpenConns(); max > 0 && n > max {
+		n = max
+	}
+	if idle := d.MaxIdleConns(); n > idle {
+		n = idle
+	}
+
+	// Connections are held until all of them are open, otherwise the pool
+	// would hand out the same one over and over.
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		conn, err := sess.Conn(ctx)
+		if err != nil {
+			return d.PartialDatabase.Err(err)
+		}
+		conns = append(conns, conn)
+
+		if err := conn.PingContext(ctx); err != nil {
+			return d.PartialDatabase.Err(err)
+		}
 	}
+
 	return nil
 }
 
@@ -325,7 +516,11 @@ func (d *database) NewClone(p PartialDatabase, checkConn bool) (BaseDatabase, er
 	nd := NewBaseDatabase(p).(*database)
 
 	nd.name = d.name
-	nd.sess = d.sess
+	nd.sess = d.Session()
+	nd.drainer = d.drainer
+	nd.defaultOrders = d.defaultOrders
+	nd.events = d.events
+	nd.resultCache = d.resultCache
 
 	if checkConn {
 		if err := nd.Ping(); err != nil {
@@ -342,6 +537,22 @@ func (d *database) NewClone(p PartialDatabase, checkConn bool) (BaseDatabase, er
 	return nd, nil
 }
 
+// CloseContext stops accepting new transactions and queries, waits until all
+// transactions started on this session or any of its clones are committed or
+// rolled back and then closes the session. If ctx expires first, the session
+// is left open and accepts new work again, and a
+// *sqlbuilder.CloseTimeoutError with the number of pending operations is
+// returned; Close may be used to force the session to close.
+func (d *database) CloseContext(ctx context.Context) error {
+	if d.Transaction() != nil {
+		return d.Close()
+	}
+	if err := d.drainer.drain(ctx); err != nil {
+		return err
+	}
+	return d.Close()
+}
+
 // Close terminates the current database session
 func (d *database) Close() error {
 	defer func() {
@@ -349,8 +560,11 @@ func (d *database) Close() error {
 		d.sess = nil
 		d.baseTx = nil
 		d.sessMu.Unlock()
+		if atomic.CompareAndSwapInt32(&d.txActive, 1, 0) {
+			d.drainer.release()
+		}
 	}()
-	if d.sess != nil {
+	if sess := d.Session(); sess != nil {
 		if cleaner, ok := d.PartialDatabase.(hasCleanUp); ok {
 			cleaner.CleanUp()
 		}
@@ -361,7 +575,7 @@ func (d *database) Close() error {
 		tx := d.Transaction()
 		if tx == nil {
 			// Not within a transaction.
-			return d.sess.Close()
+			return sess.Close()
 		}
 
 		if !tx.Committed() {
@@ -394,6 +608,10 @@ func (d *database) Collection(name string) db.Collection {
 func (d *database) StatementPrepare(ctx context.Context, stmt *exql.Statement) (sqlStmt *sql.Stmt, err error) {
 	var query string
 
+	if err = d.checkStatement(stmt); err != nil {
+		return
+	}
+
 	if d.Settings.LoggingEnabled() {
 		defer func(start time.Time) {
 			d.Logger().Log(&db.QueryStatus{
@@ -408,6 +626,10 @@ func (d *database) StatementPrepare(ctx context.Context, stmt *exql.Statement) (
 		}(time.Now())
 	}
 
+	if err = d.txClosedErr(); err != nil {
+		return
+	}
+
 	tx := d.Transaction()
 
 	query, _ = d.compileStatement(stmt, nil)
@@ -416,22 +638,122 @@ func (d *database) StatementPrepare(ctx context.Context, stmt *exql.Statement) (
 		return
 	}
 
-	sqlStmt, err = compat.PrepareContext(d.sess, ctx, query)
+	sqlStmt, err = compat.PrepareContext(d.Session(), ctx, query)
 	return
 }
 
-// ConvertValues converts native values into driver specific values.
+// ConvertValues converts native values into driver specific values. Values
+// wrapped with db.Verbatim skip the conversion and are unwrapped instead.
 func (d *database) ConvertValues(values []interface{}) []interface{} {
-	if converter, ok := d.PartialDatabase.(hasConvertValues); ok {
+	return unwrapVerbatimValues(d.convertValues(values))
+}
+
+// convertValues is like ConvertValues but leaves db.Verbatim values wrapped,
+// so statements can be compiled before they're unwrapped.
+func (d *database) convertValues(values []interface{}) []interface{} {
+	converter, ok := d.PartialDatabase.(hasConvertValues)
+	if !ok {
+		return values
+	}
+
+	var verbatim map[int]bool
+	for i := range values {
+		if _, ok := unwrapVerbatim(values[i]); ok {
+			if verbatim == nil {
+				verbatim = make(map[int]bool)
+			}
+			verbatim[i] = true
+		}
+	}
+
+	if verbatim == nil {
 		return converter.ConvertValues(values)
 	}
-	return values
+
+	rest := make([]interface{}, 0, len(values)-len(verbatim))
+	for i := range values {
+		if !verbatim[i] {
+			rest = append(rest, values[i])
+		}
+	}
+	rest = converter.ConvertValues(rest)
+
+	out := make([]interface{}, 0, len(values))
+	for i := range values {
+		if verbatim[i] {
+			out = append(out, values[i])
+			continue
+		}
+		out, rest = append(out, rest[0]), rest[1:]
+	}
+	return out
+}
+
+// unwrapVerbatimValues returns the given values with the ones wrapped by
+// db.Verbatim unwrapped, the given slice is not modified.
+func unwrapVerbatimValues(values []interface{}) []interface{} {
+	var out []interface{}
+	for i := range values {
+		v, ok := unwrapVerbatim(values[i])
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]interface{}(nil), values...)
+		}
+		out[i] = v
+	}
+	if out == nil {
+		return values
+	}
+	return out
+}
+
+// unwrapVerbatim returns the value wrapped by a db.VerbatimValue, which may
+// also be given as an sql.NamedArg.
+func unwrapVerbatim(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case db.VerbatimValue:
+		return v.Verbatim(), true
+	case sql.NamedArg:
+		if w, ok := v.Value.(db.VerbatimValue); ok {
+			v.Value = w.Verbatim()
+			return v, true
+		}
+	}
+	return nil, false
 }
 
 // StatementExec compiles and executes a statement that does not return any
-// rows.
+// rows. Idempotent statements that fail because of a broken connection are
+// retried once, outside of transactions. Errors are translated by the
+// adapter's Err method, see contextErr for canceled and timed out statements.
 func (d *database) StatementExec(ctx context.Context, stmt *exql.Statement, args ...interface{}) (res sql.Result, err error) {
 	var query string
+	var retried bool
+
+	if fallback, ok := d.PartialDatabase.(hasExecFallback); ok {
+		var handled bool
+		if res, handled, err = fallback.ExecFallback(ctx, stmt, args); handled {
+			return
+		}
+	}
+
+	if err = d.checkStatement(stmt); err != nil {
+		return
+	}
+
+	var done func()
+	if done, err = d.acquire(); err != nil {
+		return
+	}
+	defer done()
+
+	if metrics := d.Settings.Metrics(); metrics != nil {
+		defer func(start time.Time) {
+			metrics.RecordQuery(statementOp(stmt), statementTable(stmt), time.Since(start), err)
+		}(time.Now())
+	}
 
 	if d.Settings.LoggingEnabled() {
 		defer func(start time.Time) {
@@ -442,6 +764,7 @@ func (d *database) StatementExec(ctx context.Context, stmt *exql.Statement, args
 				Query:   query,
 				Args:    args,
 				Err:     err,
+				Retried: retried,
 				Start:   start,
 				End:     time.Now(),
 				Context: ctx,
@@ -461,18 +784,50 @@ func (d *database) StatementExec(ctx context.Context, stmt *exql.Statement, args
 		}(time.Now())
 	}
 
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	in := args
+	query, args, res, err = d.statementExec(ctx, stmt, in)
+	if err != nil && d.canRetry(stmt, err) {
+		retried = true
+		query, args, res, err = d.statementExec(ctx, stmt, in)
+	}
+	if err != nil {
+		err = d.withLockHolders(stmt, contextErr(ctx, d.PartialDatabase.Err(err)))
+	}
+	return
+}
+
+func (d *database) statementExec(ctx context.Context, stmt *exql.Statement, in []interface{}) (query string, args []interface{}, res sql.Result, err error) {
+	args = in
+
 	if execer, ok := d.PartialDatabase.(hasStatementExec); ok {
 		query, args = d.compileStatement(stmt, args)
+		query = d.withSQLComment(ctx, query)
 		res, err = execer.StatementExec(ctx, query, args...)
 		return
 	}
 
 	tx := d.Transaction()
 
-	if d.Settings.PreparedStatementCacheEnabled() && tx == nil {
+	if tx == nil && d.AcquireTimeout() > 0 {
+		var conn *sql.Conn
+		if conn, err = d.acquireConn(ctx); err != nil {
+			return
+		}
+		defer conn.Close()
+
+		query, args = d.compileStatement(stmt, args)
+		query = d.withSQLComment(ctx, query)
+		res, err = conn.ExecContext(ctx, query, args...)
+		return
+	}
+
+	if d.usePreparedStatement(tx) {
 		var p *Stmt
 		if p, query, args, err = d.prepareStatement(ctx, stmt, args); err != nil {
-			return nil, err
+			return
 		}
 		defer p.Close()
 
@@ -481,18 +836,57 @@ func (d *database) StatementExec(ctx context.Context, stmt *exql.Statement, args
 	}
 
 	query, args = d.compileStatement(stmt, args)
+	query = d.withSQLComment(ctx, query)
 	if tx != nil {
 		res, err = compat.ExecContext(tx.(*baseTx), ctx, query, args)
 		return
 	}
 
-	res, err = compat.ExecContext(d.sess, ctx, query, args)
+	res, err = compat.ExecContext(d.Session(), ctx, query, args)
 	return
 }
 
-// StatementQuery compiles and executes a statement that returns rows.
-func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, args ...interface{}) (rows *sql.Rows, err error) {
+// StatementQuery compiles and executes a statement that returns rows. Reads
+// that fail because of a broken connection are retried once, outside of
+// transactions. Errors are translated by the adapter's Err method, see
+// contextErr for canceled and timed out statements.
+//
+// The rows are handed over as they are, so the statement is no longer in
+// flight once it returns, see StatementRows.
+func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, args ...interface{}) (*sql.Rows, error) {
+	rows, done, err := d.StatementRows(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	done()
+	return rows, nil
+}
+
+// StatementRows is like StatementQuery, but the statement is in flight, and
+// CloseContext waits for it, until done is called once the rows are closed.
+// done must be called even if the rows are never read.
+func (d *database) StatementRows(ctx context.Context, stmt *exql.Statement, args ...interface{}) (rows *sql.Rows, done func(), err error) {
 	var query string
+	var retried bool
+
+	if err = d.checkStatement(stmt); err != nil {
+		return
+	}
+
+	if done, err = d.acquire(); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			done()
+		}
+	}()
+
+	if metrics := d.Settings.Metrics(); metrics != nil {
+		defer func(start time.Time) {
+			metrics.RecordQuery(statementOp(stmt), statementTable(stmt), time.Since(start), err)
+		}(time.Now())
+	}
 
 	if d.Settings.LoggingEnabled() {
 		defer func(start time.Time) {
@@ -502,6 +896,7 @@ func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, arg
 				Query:   query,
 				Args:    args,
 				Err:     err,
+				Retried: retried,
 				Start:   start,
 				End:     time.Now(),
 				Context: ctx,
@@ -509,12 +904,57 @@ func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, arg
 		}(time.Now())
 	}
 
+	// The rows are read after returning, so the context is canceled once
+	// they're closed, see statementQuery.
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
+
+	in := args
+	query, args, rows, err = d.statementQuery(ctx, stmt, in, cancel)
+	if err != nil && d.canRetry(stmt, err) {
+		retried = true
+		query, args, rows, err = d.statementQuery(ctx, stmt, in, cancel)
+	}
+	if err != nil {
+		err = d.withLockHolders(stmt, contextErr(ctx, d.PartialDatabase.Err(err)))
+	}
+	return
+}
+
+// statementQuery sends stmt and calls release once the returned rows are
+// closed. Outside of transactions that's what the connection the rows are
+// read from tells, so the statement runs on a connection of its own whenever
+// there's something to release, instead of through the prepared statement
+// cache. Within a transaction release is called once it's over.
+func (d *database) statementQuery(ctx context.Context, stmt *exql.Statement, in []interface{}, release func()) (query string, args []interface{}, rows *sql.Rows, err error) {
+	args = in
+
 	tx := d.Transaction()
 
-	if d.Settings.PreparedStatementCacheEnabled() && tx == nil {
+	if tx == nil && (d.AcquireTimeout() > 0 || d.DefaultQueryTimeout() > 0) {
+		var conn *sql.Conn
+		if conn, err = d.acquireConn(ctx); err != nil {
+			return
+		}
+
+		query, args = d.compileStatement(stmt, args)
+		query = d.withSQLComment(ctx, query)
+		if rows, err = conn.QueryContext(ctx, query, args...); err != nil {
+			conn.Close()
+			return
+		}
+		releaseConn(conn, release)
+		return
+	}
+
+	if d.usePreparedStatement(tx) {
 		var p *Stmt
 		if p, query, args, err = d.prepareStatement(ctx, stmt, args); err != nil {
-			return nil, err
+			return
 		}
 		defer p.Close()
 
@@ -523,14 +963,44 @@ func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, arg
 	}
 
 	query, args = d.compileStatement(stmt, args)
+	query = d.withSQLComment(ctx, query)
 	if tx != nil {
-		rows, err = compat.QueryContext(tx.(*baseTx), ctx, query, args)
+		if rows, err = compat.QueryContext(tx.(*baseTx), ctx, query, args); err == nil && d.DefaultQueryTimeout() > 0 {
+			tx.(*baseTx).onDone(release)
+		}
 		return
 	}
 
-	rows, err = compat.QueryContext(d.sess, ctx, query, args)
+	rows, err = compat.QueryContext(d.Session(), ctx, query, args)
 	return
+}
 
+// checkStatement returns the adapter's error for statements the server can't
+// run, if any.
+func (d *database) checkStatement(stmt *exql.Statement) error {
+	if checker, ok := d.PartialDatabase.(hasCheckStatement); ok {
+		return checker.CheckStatement(stmt)
+	}
+	return nil
+}
+
+// usePreparedStatement returns true if a statement sent outside of a
+// transaction should go through the prepared statement cache. Prepared statements are canceled like any other
+// when their context is done: database/sql hands the context to drivers that
+// implement driver.StmtExecContext, like lib/pq (since v1.9.0) and pgx, which
+// send a cancel request to the server.
+func (d *database) usePreparedStatement(tx BaseTx) bool {
+	return d.Settings.PreparedStatementCacheEnabled() && tx == nil
+}
+
+// canRetry returns true if stmt failed with err because of a broken
+// connection and it's safe to send it again. Statements within a transaction
+// are never retried, as the transaction is lost along with its connection.
+func (d *database) canRetry(stmt *exql.Statement, err error) bool {
+	if d.Transaction() != nil {
+		return false
+	}
+	return isRetryable(stmt) && isBadConnErr(d.PartialDatabase.Err(err))
 }
 
 // StatementQueryRow compiles and executes a statement that returns at most one
@@ -538,6 +1008,22 @@ func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, arg
 func (d *database) StatementQueryRow(ctx context.Context, stmt *exql.Statement, args ...interface{}) (row *sql.Row, err error) {
 	var query string
 
+	if err = d.checkStatement(stmt); err != nil {
+		return
+	}
+
+	var done func()
+	if done, err = d.acquire(); err != nil {
+		return
+	}
+	defer done()
+
+	if metrics := d.Settings.Metrics(); metrics != nil {
+		defer func(start time.Time) {
+			metrics.RecordQuery(statementOp(stmt), statementTable(stmt), time.Since(start), err)
+		}(time.Now())
+	}
+
 	if d.Settings.LoggingEnabled() {
 		defer func(start time.Time) {
 			d.Logger().Log(&db.QueryStatus{
@@ -553,12 +1039,35 @@ func (d *database) StatementQueryRow(ctx context.Context, stmt *exql.Statement,
 		}(time.Now())
 	}
 
+	// The row is scanned after returning, so the context is canceled once
+	// that's done, like in statementQuery.
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
+
 	tx := d.Transaction()
 
-	if d.Settings.PreparedStatementCacheEnabled() && tx == nil {
+	if tx == nil && (d.AcquireTimeout() > 0 || d.DefaultQueryTimeout() > 0) {
+		var conn *sql.Conn
+		if conn, err = d.acquireConn(ctx); err != nil {
+			return nil, contextErr(ctx, err)
+		}
+
+		query, args = d.compileStatement(stmt, args)
+		query = d.withSQLComment(ctx, query)
+		row = conn.QueryRowContext(ctx, query, args...)
+		// Scanning the row closes it, errors are also deferred until then.
+		releaseConn(conn, cancel)
+		return
+	}
+
+	if d.usePreparedStatement(tx) {
 		var p *Stmt
 		if p, query, args, err = d.prepareStatement(ctx, stmt, args); err != nil {
-			return nil, err
+			return nil, contextErr(ctx, err)
 		}
 		defer p.Close()
 
@@ -567,12 +1076,16 @@ func (d *database) StatementQueryRow(ctx context.Context, stmt *exql.Statement,
 	}
 
 	query, args = d.compileStatement(stmt, args)
+	query = d.withSQLComment(ctx, query)
 	if tx != nil {
 		row = compat.QueryRowContext(tx.(*baseTx), ctx, query, args)
+		if d.DefaultQueryTimeout() > 0 {
+			tx.(*baseTx).onDone(cancel)
+		}
 		return
 	}
 
-	row = compat.QueryRowContext(d.sess, ctx, query, args)
+	row = compat.QueryRowContext(d.Session(), ctx, query, args)
 	return
 }
 
@@ -582,39 +1095,150 @@ func (d *database) Driver() interface{} {
 		// A transaction
 		return tx.(*baseTx).Tx
 	}
-	return d.sess
+	return d.Session()
+}
+
+// withQueryTimeout returns a copy of ctx that expires after the default query
+// timeout, unless ctx expires earlier.
+func (d *database) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := d.DefaultQueryTimeout()
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// contextErr makes err match context.Canceled if the statement was canceled
+// by the caller, or db.ErrQueryTimeout if it ran out of time, as drivers
+// usually report both as an error of their own.
+func contextErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var kind error
+	switch ctx.Err() {
+	case context.Canceled:
+		kind = context.Canceled
+	case context.DeadlineExceeded:
+		kind = db.ErrQueryTimeout
+	default:
+		return err
+	}
+	if err == kind {
+		return err
+	}
+	if e, ok := err.(interface{ Is(error) bool }); ok && e.Is(kind) {
+		return err
+	}
+	return &db.Error{Kind: kind, Err: err}
+}
+
+// acquireConn takes a connection from the pool, waiting at most the acquire
+// timeout for one to be released, if any. ErrTooManyClients is returned when
+// the wait times out before ctx is done.
+func (d *database) acquireConn(ctx context.Context) (*sql.Conn, error) {
+	sess := d.Session()
+	if sess == nil {
+		return nil, db.ErrNotConnected
+	}
+
+	if d.AcquireTimeout() <= 0 {
+		return sess.Conn(ctx)
+	}
+
+	actx, cancel := context.WithTimeout(ctx, d.AcquireTimeout())
+	defer cancel()
+
+	conn, err := sess.Conn(actx)
+	if err != nil {
+		if ctx.Err() == nil && actx.Err() == context.DeadlineExceeded {
+			return nil, db.ErrTooManyClients
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// releaseConn returns conn to the pool once the rows read from it are closed,
+// which is what conn.Close waits for, and calls fn after that.
+func releaseConn(conn *sql.Conn, fn func()) {
+	go func() {
+		conn.Close()
+		fn()
+	}()
+}
+
+// acquire registers a statement that runs outside of a transaction, the ones
+// within a transaction are covered by the transaction itself. The returned
+// function must be called once the statement is done. Statements sent through
+// a transaction that was committed or rolled back, like the ones from a
+// collection that outlived it, fail with db.ErrTransactionClosed.
+func (d *database) acquire() (func(), error) {
+	if err := d.txClosedErr(); err != nil {
+		return nil, err
+	}
+	if d.Transaction() != nil {
+		return func() {}, nil
+	}
+	if err := d.drainer.acquire(); err != nil {
+		return nil, err
+	}
+	return d.drainer.release, nil
+}
+
+// txClosedErr returns db.ErrTransactionClosed if the session was bound to a
+// transaction that is over.
+func (d *database) txClosedErr() error {
+	if atomic.LoadInt32(&d.txBound) == 0 {
+		return nil
+	}
+	if tx := d.Transaction(); tx != nil && tx.TxErr() != sql.ErrTxDone {
+		return nil
+	}
+	return db.ErrTransactionClosed
 }
 
 // compileStatement compiles the given statement into a string.
 func (d *database) compileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
-	if converter, ok := d.PartialDatabase.(hasConvertValues); ok {
-		args = converter.ConvertValues(args)
-	}
-	return d.PartialDatabase.CompileStatement(stmt, args)
+	// Verbatim values are unwrapped once the statement is compiled, otherwise
+	// slices would be expanded into lists of placeholders.
+	query, args := d.PartialDatabase.CompileStatement(stmt, d.convertValues(args))
+	args = unwrapVerbatimValues(args)
+	// Comments are added once placeholders are in place, so their text is
+	// sent untouched.
+	return exql.CommentPrefix(d.QueryComment(), stmt.Comment) + query, args
 }
 
 // prepareStatement compiles a query and tries to use previously generated
 // statement.
 func (d *database) prepareStatement(ctx context.Context, stmt *exql.Statement, args []interface{}) (*Stmt, string, []interface{}, error) {
+	// The lock is not held while the statement is prepared, as that's a round
+	// trip to the server. Goroutines that race to prepare the same statement
+	// each get their own; the cache purges the one that is overwritten.
 	d.sessMu.Lock()
-	defer d.sessMu.Unlock()
+	sess, tx := d.sess, d.baseTx
+	d.sessMu.Unlock()
 
-	sess, tx := d.sess, d.Transaction()
 	if sess == nil && tx == nil {
 		return nil, "", nil, db.ErrNotConnected
 	}
 
+	query, args := d.compileStatement(stmt, args)
+
 	pc, ok := d.cachedStatements.ReadRaw(stmt)
 	if ok {
 		// The statement was cached.
 		ps, err := pc.(*Stmt).Open()
 		if err == nil {
-			_, args = d.compileStatement(stmt, args)
-			return ps, ps.query, args, nil
+			if ps.query == query {
+				return ps, ps.query, args, nil
+			}
+			// The statement compiles differently now, like after the
+			// session's identifier quoting changed, so it's prepared again.
+			ps.Close()
 		}
 	}
 
-	query, args := d.compileStatement(stmt, args)
 	sqlStmt, err := func(query *string) (*sql.Stmt, error) {
 		if tx != nil {
 			return compat.PrepareContext(tx.(*baseTx), ctx, *query)
@@ -677,37 +1301,26 @@ func (d *database) WaitForConnection(connectFn func() error) error {
 // ReplaceWithDollarSign turns a SQL statament with '?' placeholders into
 // dollar placeholders, like $1, $2, ..., $n
 func ReplaceWithDollarSign(in string) string {
-	buf := []byte(in)
-	out := make([]byte, 0, len(buf))
-
-	i, j, k, t := 0, 1, 0, len(buf)
-
-	for i < t {
-		if buf[i] == '?' {
-			out = append(out, buf[k:i]...)
-			k = i + 1
-
-			if k < t && buf[k] == '?' {
-				i = k
-			} else {
-				out = append(out, []byte("$"+strconv.Itoa(j))...)
-				j++
-			}
-		}
-		i++
-	}
-	out = append(out, buf[k:i]...)
-
-	return string(out)
+	return exql.ReplacePlaceholders(in, exql.DollarPlaceholder)
 }
 
 func copySettings(from BaseDatabase, into BaseDatabase) {
 	into.SetLogging(from.LoggingEnabled())
 	into.SetLogger(from.Logger())
+	into.SetMetrics(from.Metrics())
 	into.SetPreparedStatementCache(from.PreparedStatementCacheEnabled())
 	into.SetConnMaxLifetime(from.ConnMaxLifetime())
 	into.SetMaxIdleConns(from.MaxIdleConns())
 	into.SetMaxOpenConns(from.MaxOpenConns())
+	into.SetDefaultQueryTimeout(from.DefaultQueryTimeout())
+	into.SetAcquireTimeout(from.AcquireTimeout())
+	into.SetQueryComment(from.QueryComment())
+	into.SetSQLCommenter(from.SQLCommenter())
+	into.SetForceUTC(from.ForceUTC())
+	into.SetLockDiagnostics(from.LockDiagnostics())
+	into.SetQuoteAllIdentifiers(from.QuoteAllIdentifiers())
+	into.SetReservedWords(from.ReservedWords()...)
+	into.SetOutboxTable(from.OutboxTable())
 
 	txOptions := from.TxOptions()
 	if txOptions != nil {
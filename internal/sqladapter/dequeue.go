@@ -0,0 +1,182 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// hasDequeue is implemented by the sessions Dequeue can open transactions on.
+type hasDequeue interface {
+	NewTx(ctx context.Context) (sqlbuilder.Tx, error)
+	PrimaryKeys(tableName string) ([]string, error)
+	Transaction() BaseTx
+}
+
+var _ = sqlbuilder.Dequeuer(&Result{})
+
+// Dequeue begins a transaction and locks up to limit rows of the result set,
+// skipping the rows that are locked by other transactions.
+func (r *Result) Dequeue(ctx context.Context, limit int) (sqlbuilder.Dequeued, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	sess, ok := r.SQLBuilder().(hasDequeue)
+	if !ok {
+		return nil, db.ErrUnsupported
+	}
+	if sess.Transaction() != nil {
+		return nil, db.ErrAlreadyWithinTransaction
+	}
+
+	res, err := r.fastForward()
+	if err != nil {
+		return nil, err
+	}
+
+	pks, err := sess.PrimaryKeys(res.table)
+	if err != nil {
+		return nil, err
+	}
+	if len(pks) == 0 {
+		return nil, fmt.Errorf(errMissingPrimaryKeys.Error(), res.table)
+	}
+
+	tx, err := sess.NewTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]interface{}, len(pks))
+	for i := range pks {
+		columns[i] = pks[i]
+	}
+
+	sel := tx.Select(columns...).
+		From(res.table).
+		OrderBy(res.orderBy...).
+		Limit(limit).
+		SkipLocked()
+	res.applyConds(
+		func(conds ...interface{}) { sel = sel.And(conds...) },
+		func(conds ...interface{}) { sel = sel.Or(conds...) },
+	)
+
+	var rows []map[string]interface{}
+	if err := sel.All(&rows); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	keys := make([]db.Compound, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, rowKey(pks, row))
+	}
+
+	return &dequeued{
+		tx:      tx,
+		table:   res.table,
+		fields:  res.fields,
+		orderBy: res.orderBy,
+		keys:    keys,
+	}, nil
+}
+
+type dequeued struct {
+	tx      sqlbuilder.Tx
+	table   string
+	fields  []interface{}
+	orderBy []interface{}
+	keys    []db.Compound
+}
+
+var _ = sqlbuilder.Dequeued(&dequeued{})
+
+func (d *dequeued) Len() int {
+	return len(d.keys)
+}
+
+func (d *dequeued) Tx() sqlbuilder.Tx {
+	return d.tx
+}
+
+func (d *dequeued) cond() *db.Union {
+	return db.Or(d.keys...)
+}
+
+func (d *dequeued) All(dst interface{}) error {
+	if len(d.keys) == 0 {
+		// Nothing was locked, this only validates and empties dst.
+		return d.tx.Select(d.fields...).From(d.table).Where(db.Raw("1 = 0")).All(dst)
+	}
+	return d.tx.Select(d.fields...).
+		From(d.table).
+		Where(d.cond()).
+		OrderBy(d.orderBy...).
+		All(dst)
+}
+
+func (d *dequeued) Complete(values interface{}) error {
+	if values != nil && len(d.keys) > 0 {
+		_, err := d.tx.Update(d.table).Set(values).Where(d.cond()).Exec()
+		if err != nil {
+			_ = d.tx.Rollback()
+			return err
+		}
+		if e, ok := d.tx.(hasEmit); ok {
+			e.Emit(db.Event{Operation: db.OperationUpdate, Table: d.table, Keys: d.eventKeys()})
+		}
+	}
+	return d.tx.Commit()
+}
+
+// eventKeys returns the keys of the locked rows in the form db.Event has them.
+func (d *dequeued) eventKeys() []interface{} {
+	keys := make([]interface{}, 0, len(d.keys))
+	for _, key := range d.keys {
+		keys = append(keys, eventKey(key.(db.Cond)))
+	}
+	return keys
+}
+
+func (d *dequeued) Release() error {
+	return d.tx.Rollback()
+}
+
+// rowKey returns a condition that matches the row with the primary key
+// values of the given map.
+func rowKey(pks []string, row map[string]interface{}) db.Cond {
+	key := db.Cond{}
+	for _, pk := range pks {
+		v := row[pk]
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		key[pk] = v
+	}
+	return key
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"context"
+	"sync"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// drainer keeps track of the operations that are running on a *sql.DB. It is
+// shared by a session and all of its clones so CloseContext can wait for
+// transactions that were started on any of them.
+type drainer struct {
+	mu      sync.Mutex
+	active  int
+	closing bool
+	idle    chan struct{}
+}
+
+func newDrainer() *drainer {
+	return &drainer{}
+}
+
+// acquire registers a new operation, it fails if the session is being closed.
+func (dr *drainer) acquire() error {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	if dr.closing {
+		return db.ErrSessionClosing
+	}
+	dr.active++
+	return nil
+}
+
+// release marks an operation previously registered with acquire as done.
+func (dr *drainer) release() {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	dr.active--
+	if dr.active == 0 && dr.idle != nil {
+		close(dr.idle)
+		dr.idle = nil
+	}
+}
+
+// drain stops accepting new operations and waits until all active operations
+// are done or until ctx expires, in which case new operations are accepted
+// again.
+func (dr *drainer) drain(ctx context.Context) error {
+	dr.mu.Lock()
+	dr.closing = true
+	if dr.active == 0 {
+		dr.mu.Unlock()
+		return nil
+	}
+	if dr.idle == nil {
+		dr.idle = make(chan struct{})
+	}
+	idle := dr.idle
+	dr.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	if dr.active == 0 {
+		return nil
+	}
+	dr.closing = false
+	return &sqlbuilder.CloseTimeoutError{Err: ctx.Err(), Pending: dr.active}
+}
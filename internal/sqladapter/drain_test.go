@@ -0,0 +1,74 @@
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainer(t *testing.T) {
+	dr := newDrainer()
+
+	assert.NoError(t, dr.acquire())
+	assert.NoError(t, dr.acquire())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	err := dr.drain(ctx)
+	if assert.IsType(t, &sqlbuilder.CloseTimeoutError{}, err) {
+		closeErr := err.(*sqlbuilder.CloseTimeoutError)
+		assert.Equal(t, context.DeadlineExceeded, closeErr.Err)
+		assert.Equal(t, 2, closeErr.Pending)
+	}
+
+	// New work is accepted again once draining timed out.
+	assert.NoError(t, dr.acquire())
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+
+		// No new work is accepted while draining.
+		assert.Equal(t, db.ErrSessionClosing, dr.acquire())
+
+		dr.release()
+		dr.release()
+		dr.release()
+	}()
+
+	assert.NoError(t, dr.drain(context.Background()))
+}
+
+func TestDrainWaitsForRows(t *testing.T) {
+	sess, err := sql.Open("sqladapter-stub", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sess.Close()
+
+	d := NewBaseDatabase(convertValuesStub{}).(*database)
+	d.sess = sess
+
+	iter := sqlbuilder.WithSession(d, &exql.Template{}).Iterator(`SELECT 1`)
+	if !assert.NoError(t, iter.Err()) {
+		return
+	}
+	<-stubQueries
+
+	// The query is in-flight until its rows are closed.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err = d.drainer.drain(ctx)
+	if assert.IsType(t, &sqlbuilder.CloseTimeoutError{}, err) {
+		assert.Equal(t, 1, err.(*sqlbuilder.CloseTimeoutError).Pending)
+	}
+
+	assert.NoError(t, iter.Close())
+	assert.NoError(t, d.drainer.drain(context.Background()))
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"database/sql"
+	"reflect"
+	"sync"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+type hasEmit interface {
+	Emit(ev db.Event)
+}
+
+// hasReturning is implemented by the sessions that can read the primary keys
+// of the rows an update or a delete changed from its RETURNING clause.
+type hasReturning interface {
+	SupportsReturning() bool
+	Subscribed(table string) bool
+	PrimaryKeys(tableName string) ([]string, error)
+}
+
+// eventBus keeps the subscribers to the events of a session. It is shared by
+// a session and all of its clones, so the changes made within transactions
+// reach the subscribers of the session they were started on.
+type eventBus struct {
+	mu          sync.RWMutex
+	subscribers []*subscriber // replaced, never modified, on unsubscribe
+}
+
+type subscriber struct {
+	table string
+	fn    func(db.Event)
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{}
+}
+
+// subscribe adds fn as a subscriber to the events of table, or of all tables
+// if table is empty, and returns a function that removes it.
+func (b *eventBus) subscribe(table string, fn func(db.Event)) func() {
+	s := &subscriber{table: table, fn: fn}
+
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, s)
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.unsubscribe(s)
+		})
+	}
+}
+
+func (b *eventBus) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscribers := make([]*subscriber, 0, len(b.subscribers))
+	for _, t := range b.subscribers {
+		if t != s {
+			subscribers = append(subscribers, t)
+		}
+	}
+	b.subscribers = subscribers
+}
+
+// subscribed returns true if there's a subscriber to the events of table or
+// of all tables.
+func (b *eventBus) subscribed(table string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, s := range b.subscribers {
+		if s.table == "" || s.table == table {
+			return true
+		}
+	}
+	return false
+}
+
+// publish calls the subscribers of each event in order. Subscribers are called
+// without holding the lock, so they may subscribe or unsubscribe.
+func (b *eventBus) publish(events ...db.Event) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, ev := range events {
+		for _, s := range subscribers {
+			if s.table == "" || s.table == ev.Table {
+				s.fn(ev)
+			}
+		}
+	}
+}
+
+// EmitInsert reports the insertion of the row with the given ID, as returned
+// by Insert, into the collection's table.
+func EmitInsert(c Collection, id interface{}) {
+	ev := db.Event{Operation: db.OperationInsert, Table: c.Name()}
+	if id != nil && len(c.PrimaryKeys()) > 0 {
+		ev.Keys = []interface{}{id}
+	}
+	c.Database().Emit(ev)
+}
+
+// emit reports a change to the rows of the result. keys are the primary keys
+// of the changed rows, if they're nil they're taken from the conditions of
+// the result when possible.
+func (r *Result) emit(op db.Operation, keys []interface{}) {
+	e, ok := r.SQLBuilder().(hasEmit)
+	if !ok {
+		return
+	}
+	res, err := r.fastForward()
+	if err != nil {
+		return
+	}
+	if keys == nil {
+		keys = r.keys(res)
+	}
+	e.Emit(db.Event{Operation: op, Table: res.table, Keys: keys})
+}
+
+// returningKeys returns the primary keys of the table of the result if the
+// session reads the keys of the rows an update or a delete changes from its
+// RETURNING clause, which is only worth it when someone is subscribed to the
+// events of the table. It returns nil otherwise.
+func (r *Result) returningKeys() []string {
+	sess, ok := r.SQLBuilder().(hasReturning)
+	if !ok || !sess.SupportsReturning() {
+		return nil
+	}
+	res, err := r.fastForward()
+	if err != nil || !sess.Subscribed(res.table) {
+		return nil
+	}
+	pKey, err := sess.PrimaryKeys(res.table)
+	if err != nil {
+		return nil
+	}
+	return pKey
+}
+
+// changeRows runs an update or a delete of the rows of the result and returns
+// the number of rows it changed, op is reported if there were any. When the
+// session supports it the statement is given a RETURNING clause with the
+// primary keys, by returning, so the event carries the keys of the rows that
+// actually changed.
+func (r *Result) changeRows(op db.Operation, exec func() (sql.Result, error), returning func(pKey []string) sqlbuilder.Iterator) (int64, error) {
+	pKey := r.returningKeys()
+	if len(pKey) == 0 {
+		res, err := exec()
+		if err != nil {
+			return 0, r.setErr(err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return 0, r.setErr(err)
+		}
+		if affected > 0 {
+			r.emit(op, nil)
+		}
+		return affected, nil
+	}
+
+	var rows []map[string]interface{}
+	if err := returning(pKey).All(&rows); err != nil {
+		return 0, r.setErr(err)
+	}
+	if len(rows) > 0 {
+		keys := make([]interface{}, len(rows))
+		for i := range rows {
+			keys[i] = eventKey(rowKey(pKey, rows[i]))
+		}
+		r.emit(op, keys)
+	}
+	return int64(len(rows)), nil
+}
+
+// eventKey returns the primary key of a row in the form db.Event has it: the
+// value of the key, or a Cond with the value of each column for composite
+// keys.
+func eventKey(key db.Cond) interface{} {
+	if len(key) == 1 {
+		for _, v := range key {
+			return v
+		}
+	}
+	return key
+}
+
+// keys returns the primary keys of the rows of res if its only condition is
+// a db.Cond on all the primary keys of the table, with single values, like
+// the one Collection.Find(id) builds, or a list of values for tables with a
+// single primary key. It returns nil otherwise. It's used for the sessions
+// that can't read the keys from a RETURNING clause.
+func (r *Result) keys(res *result) []interface{} {
+	p, ok := r.SQLBuilder().(hasPrimaryKeys)
+	if !ok || len(res.conds) != 1 || len(res.conds[0]) != 1 {
+		return nil
+	}
+	cond, ok := res.conds[0][0].(db.Cond)
+	if !ok {
+		return nil
+	}
+	pKey, err := p.PrimaryKeys(res.table)
+	if err != nil || len(pKey) == 0 || len(cond) != len(pKey) {
+		return nil
+	}
+
+	key := db.Cond{}
+	for _, column := range pKey {
+		value, ok := cond[column]
+		if !ok {
+			return nil
+		}
+		if cmp, ok := value.(db.Comparison); ok {
+			switch cmp.Operator() {
+			case db.ComparisonOperatorEqual, db.ComparisonOperatorIn:
+				value = cmp.Value()
+			default:
+				return nil
+			}
+		}
+		switch value.(type) {
+		case nil, db.RawValue, db.Function, db.Comparison:
+			return nil
+		}
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+			// A list of values is only a list of keys if there is a single
+			// primary key.
+			if len(pKey) > 1 {
+				return nil
+			}
+			keys := make([]interface{}, v.Len())
+			for i := range keys {
+				keys[i] = v.Index(i).Interface()
+			}
+			return keys
+		}
+		key[column] = value
+	}
+
+	if len(pKey) == 1 {
+		return []interface{}{key[pKey[0]]}
+	}
+	return []interface{}{key}
+}
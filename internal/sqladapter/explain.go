@@ -0,0 +1,68 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// hasExplainStatement is implemented by adapters that can show query plans.
+// The given transaction is rolled back after ExplainStatement returns, so
+// adapters may run query in it.
+type hasExplainStatement interface {
+	ExplainStatement(ctx context.Context, tx *sql.Tx, query string, args []interface{}, opts sqlbuilder.ExplainOptions) (string, error)
+}
+
+// StatementExplain compiles the given statement and returns the plan the
+// database uses to run it.
+func (d *database) StatementExplain(ctx context.Context, stmt *exql.Statement, args []interface{}, opts sqlbuilder.ExplainOptions) (string, error) {
+	explainer, ok := d.PartialDatabase.(hasExplainStatement)
+	if !ok {
+		return "", db.ErrUnsupported
+	}
+
+	sess := d.Session()
+	if sess == nil {
+		return "", db.ErrNotConnected
+	}
+
+	query, args := d.compileStatement(stmt, args)
+
+	// The plan is taken within a transaction of its own that is never
+	// committed, so ANALYZE can run write statements without side effects.
+	tx, err := sess.BeginTx(ctx, nil)
+	if err != nil {
+		return "", d.PartialDatabase.Err(err)
+	}
+	defer tx.Rollback()
+
+	plan, err := explainer.ExplainStatement(ctx, tx, query, args, opts)
+	if err != nil {
+		return "", d.PartialDatabase.Err(err)
+	}
+	return plan, nil
+}
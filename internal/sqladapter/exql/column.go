@@ -56,14 +56,14 @@ func (c *Column) Compile(layout *Template) (compiled string, err error) {
 			if nameChunks[i] == "*" {
 				continue
 			}
-			nameChunks[i] = layout.MustCompile(layout.IdentifierQuote, Raw{Value: nameChunks[i]})
+			nameChunks[i] = layout.QuoteIdentifier(nameChunks[i])
 		}
 
 		compiled = strings.Join(nameChunks, layout.ColumnSeparator)
 
 		if len(chunks) > 1 {
 			alias = trimString(chunks[1])
-			alias = layout.MustCompile(layout.IdentifierQuote, Raw{Value: alias})
+			alias = layout.QuoteIdentifier(alias)
 		}
 	case Raw:
 		compiled = value.String()
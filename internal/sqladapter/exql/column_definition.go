@@ -0,0 +1,195 @@
+package exql
+
+import (
+	"strings"
+)
+
+// ColumnDefinition represents the definition of a column in a CREATE TABLE or
+// ALTER TABLE statement.
+type ColumnDefinition struct {
+	Name        string
+	Type        string
+	Constraints []string
+	hash        hash
+}
+
+var _ = Fragment(&ColumnDefinition{})
+
+// Hash returns a unique identifier for the struct.
+func (c *ColumnDefinition) Hash() string {
+	return c.hash.Hash(c)
+}
+
+// Compile transforms the ColumnDefinition into its equivalent SQL
+// representation. Generic type names, like "serial" or "text", are translated
+// using the template's ColumnTypes, other types are used verbatim.
+func (c *ColumnDefinition) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(c); ok {
+		return z, nil
+	}
+
+	name, err := ColumnWithName(c.Name).Compile(layout)
+	if err != nil {
+		return "", err
+	}
+
+	chunks := []string{name, layout.ColumnType(c.Type)}
+	chunks = append(chunks, c.Constraints...)
+
+	compiled = strings.Join(chunks, " ")
+
+	layout.Write(c, compiled)
+
+	return
+}
+
+// ColumnDefinitions represents the list of columns and table constraints of a
+// CREATE TABLE statement.
+type ColumnDefinitions struct {
+	Columns    []Fragment
+	PrimaryKey []string
+	hash       hash
+}
+
+var _ = Fragment(&ColumnDefinitions{})
+
+// Hash returns a unique identifier for the struct.
+func (c *ColumnDefinitions) Hash() string {
+	return c.hash.Hash(c)
+}
+
+// Compile transforms the ColumnDefinitions into its equivalent SQL
+// representation.
+func (c *ColumnDefinitions) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(c); ok {
+		return z, nil
+	}
+
+	chunks := make([]string, 0, len(c.Columns)+1)
+	for i := range c.Columns {
+		chunk, err := c.Columns[i].Compile(layout)
+		if err != nil {
+			return "", err
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if len(c.PrimaryKey) > 0 {
+		keys := make([]string, 0, len(c.PrimaryKey))
+		for i := range c.PrimaryKey {
+			key, err := ColumnWithName(c.PrimaryKey[i]).Compile(layout)
+			if err != nil {
+				return "", err
+			}
+			keys = append(keys, key)
+		}
+		chunks = append(chunks, "PRIMARY KEY ("+strings.Join(keys, layout.IdentifierSeparator)+")")
+	}
+
+	compiled = strings.Join(chunks, layout.IdentifierSeparator)
+
+	layout.Write(c, compiled)
+
+	return
+}
+
+type alterColumnT struct {
+	Column string
+}
+
+// AddColumn represents the addition of a column in an ALTER TABLE statement.
+type AddColumn struct {
+	Column Fragment
+	hash   hash
+}
+
+var _ = Fragment(&AddColumn{})
+
+// Hash returns a unique identifier for the struct.
+func (a *AddColumn) Hash() string {
+	return a.hash.Hash(a)
+}
+
+// Compile transforms the AddColumn into its equivalent SQL representation.
+func (a *AddColumn) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(a); ok {
+		return z, nil
+	}
+
+	column, err := a.Column.Compile(layout)
+	if err != nil {
+		return "", err
+	}
+
+	compiled = layout.MustCompile(layout.AddColumnLayout, alterColumnT{Column: column})
+
+	layout.Write(a, compiled)
+
+	return
+}
+
+// DropColumn represents the removal of a column in an ALTER TABLE statement.
+type DropColumn struct {
+	Name string
+	hash hash
+}
+
+var _ = Fragment(&DropColumn{})
+
+// Hash returns a unique identifier for the struct.
+func (d *DropColumn) Hash() string {
+	return d.hash.Hash(d)
+}
+
+// Compile transforms the DropColumn into its equivalent SQL representation.
+func (d *DropColumn) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(d); ok {
+		return z, nil
+	}
+
+	column, err := ColumnWithName(d.Name).Compile(layout)
+	if err != nil {
+		return "", err
+	}
+
+	compiled = layout.MustCompile(layout.DropColumnLayout, alterColumnT{Column: column})
+
+	layout.Write(d, compiled)
+
+	return
+}
+
+// Alterations represents the list of actions of an ALTER TABLE statement.
+type Alterations struct {
+	Actions []Fragment
+	hash    hash
+}
+
+var _ = Fragment(&Alterations{})
+
+// Hash returns a unique identifier for the struct.
+func (a *Alterations) Hash() string {
+	return a.hash.Hash(a)
+}
+
+// Compile transforms the Alterations into its equivalent SQL representation.
+func (a *Alterations) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(a); ok {
+		return z, nil
+	}
+
+	chunks := make([]string, 0, len(a.Actions))
+	for i := range a.Actions {
+		chunk, err := a.Actions[i].Compile(layout)
+		if err != nil {
+			return "", err
+		}
+		chunks = append(chunks, strings.TrimSpace(chunk))
+	}
+
+	compiled = strings.Join(chunks, layout.IdentifierSeparator)
+
+	layout.Write(a, compiled)
+
+	return
+}
@@ -1,7 +1,11 @@
 package exql
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+
+	"github.com/frazercomputing/upper-io-db/internal/cache"
 )
 
 func TestColumnHash(t *testing.T) {
@@ -121,3 +125,108 @@ func BenchmarkColumnWithAsKeywordCompile(b *testing.B) {
 		c.Compile(defaultTemplate)
 	}
 }
+
+func TestColumnMinimalQuoting(t *testing.T) {
+	layout := defaultTemplate.WithMinimalQuoting("ORDER", "user")
+
+	if layout != defaultTemplate.WithMinimalQuoting("user", "order") {
+		t.Fatal("Expecting the same template for the same words")
+	}
+
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"name", `name`},
+		{"role.name", `role.name`},
+		{"order", `"order"`},
+		{"user.order AS sort", `"user"."order" AS sort`},
+		{"Name", `"Name"`},
+		{"first name", `first AS name`},
+		{"user_id2", `user_id2`},
+	}
+
+	for _, test := range tests {
+		s, err := (&Column{Name: test.in}).Compile(layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != test.out {
+			t.Fatalf("Got: %s, Expecting: %s", s, test.out)
+		}
+
+		// The original template still quotes everything.
+		s, err = (&Column{Name: "name"}).Compile(defaultTemplate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != `"name"` {
+			t.Fatalf("Got: %s, Expecting: %s", s, `"name"`)
+		}
+	}
+
+	s, err := TableWithName("order AS o").Compile(layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != `"order" AS o` {
+		t.Fatalf("Got: %s, Expecting: %s", s, `"order" AS o`)
+	}
+}
+
+func TestColumnCacheKeyedByTemplate(t *testing.T) {
+	shared := cache.NewCache()
+
+	quoted := &Template{IdentifierQuote: `"{{.Value}}"`, ColumnSeparator: `.`, Cache: shared}
+	bracketed := &Template{IdentifierQuote: `[{{.Value}}]`, ColumnSeparator: `.`, Cache: shared}
+
+	column := &Column{Name: "role.name"}
+
+	for i := 0; i < 2; i++ {
+		s, err := column.Compile(quoted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != `"role"."name"` {
+			t.Fatalf("Got: %s, Expecting: %s", s, `"role"."name"`)
+		}
+
+		s, err = column.Compile(bracketed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != `[role].[name]` {
+			t.Fatalf("Got: %s, Expecting: %s", s, `[role].[name]`)
+		}
+
+		s, err = column.Compile(quoted.WithMinimalQuoting())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != `role.name` {
+			t.Fatalf("Got: %s, Expecting: %s", s, `role.name`)
+		}
+	}
+}
+
+func TestColumnCompileConcurrently(t *testing.T) {
+	layout := &Template{IdentifierQuote: `"{{.Value}}"`, ColumnSeparator: `.`, Cache: cache.NewCache()}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			column := &Column{Name: fmt.Sprintf("role.name%d", i)}
+			s, err := column.Compile(layout)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if e := fmt.Sprintf(`"role"."name%d"`, i); s != e {
+				t.Errorf("Got: %s, Expecting: %s", s, e)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -14,7 +14,11 @@ const (
 	defaultOrKeyword           = `OR`
 	defaultDescKeyword         = `DESC`
 	defaultAscKeyword          = `ASC`
+	defaultCollateLayout       = `COLLATE "{{.}}"`
+	defaultNullsFirstKeyword   = `NULLS FIRST`
+	defaultNullsLastKeyword    = `NULLS LAST`
 	defaultAssignmentOperator  = `=`
+	defaultConcatOperator      = `||`
 	defaultClauseGroup         = `({{.}})`
 	defaultClauseOperator      = ` {{.}} `
 	defaultColumnValue         = `{{.Column}} {{.Operator}} {{.Value}}`
@@ -62,6 +66,12 @@ const (
     {{end}}
   `
 
+	defaultLockLayout = `FOR UPDATE{{if .SkipLocked}} SKIP LOCKED{{end}}`
+
+	defaultSampleLayout = `TABLESAMPLE SYSTEM ({{.Percent}})`
+
+	defaultRandomFunction = `random()`
+
 	defaultSelectLayout = `
     SELECT
       {{if .Distinct}}
@@ -75,7 +85,7 @@ const (
       {{end}}
 
       {{if defined .Table}}
-        FROM {{.Table | compile}}
+        FROM {{.Table | compile}} {{.Sample | compile}}
       {{end}}
 
       {{.Joins | compile}}
@@ -93,10 +103,13 @@ const (
       {{if .Offset}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{.Lock | compile}}
   `
 	defaultDeleteLayout = `
     DELETE
       FROM {{.Table | compile}}
+      {{.From | compile}}
       {{.Where | compile}}
     {{if .Limit}}
       LIMIT {{.Limit}}
@@ -109,9 +122,16 @@ const (
     UPDATE
       {{.Table | compile}}
     SET {{.ColumnValues | compile}}
+      {{.From | compile}}
       {{.Where | compile}}
   `
 
+	defaultUpdateFromLayout = `FROM {{.Sources | compile}} {{.Joins | compile}}`
+
+	defaultValuesTableLayout = `(VALUES {{.Rows | compile}}) AS {{.Alias | compile}} ({{.Columns | compile}})`
+
+	defaultDeleteUsingLayout = `USING {{.Sources | compile}} {{.Joins | compile}}`
+
 	defaultCountLayout = `
     SELECT
       COUNT(1) AS _t
@@ -161,7 +181,11 @@ const (
 var defaultTemplate = &Template{
 	AndKeyword:          defaultAndKeyword,
 	AscKeyword:          defaultAscKeyword,
+	CollateLayout:       defaultCollateLayout,
+	NullsFirstKeyword:   defaultNullsFirstKeyword,
+	NullsLastKeyword:    defaultNullsLastKeyword,
 	AssignmentOperator:  defaultAssignmentOperator,
+	ConcatOperator:      defaultConcatOperator,
 	ClauseGroup:         defaultClauseGroup,
 	ClauseOperator:      defaultClauseOperator,
 	ColumnAliasLayout:   defaultColumnAliasLayout,
@@ -177,6 +201,12 @@ var defaultTemplate = &Template{
 	IdentifierSeparator: defaultIdentifierSeparator,
 	InsertLayout:        defaultInsertLayout,
 	JoinLayout:          defaultJoinLayout,
+	LockLayout:          defaultLockLayout,
+	SampleLayout:        defaultSampleLayout,
+	RandomFunction:      defaultRandomFunction,
+	UpdateFromLayout:    defaultUpdateFromLayout,
+	DeleteUsingLayout:   defaultDeleteUsingLayout,
+	ValuesTableLayout:   defaultValuesTableLayout,
 	OnLayout:            defaultOnLayout,
 	OrKeyword:           defaultOrKeyword,
 	OrderByLayout:       defaultOrderByLayout,
@@ -0,0 +1,44 @@
+package exql
+
+import (
+	"errors"
+)
+
+// ErrDeleteUsingUnsupported is returned when a DELETE statement joins other
+// tables on a template that has no DeleteUsingLayout.
+var ErrDeleteUsingUnsupported = errors.New("deleting with joins is not supported by this database")
+
+// DeleteUsing represents the tables a DELETE statement is joined with, like
+// PostgreSQL's DELETE ... USING or SQL Server's DELETE t FROM t JOIN ....
+// Table is the table rows are deleted from, as some databases require it to
+// be listed along with the sources.
+type DeleteUsing struct {
+	Table   Fragment
+	Sources Fragment
+	Joins   Fragment
+	hash    hash
+}
+
+var _ = Fragment(&DeleteUsing{})
+
+// Hash returns a unique identifier for the struct.
+func (u *DeleteUsing) Hash() string {
+	return u.hash.Hash(u)
+}
+
+// Compile transforms the DeleteUsing into its equivalent SQL representation.
+func (u *DeleteUsing) Compile(layout *Template) (compiled string, err error) {
+	if c, ok := layout.Read(u); ok {
+		return c, nil
+	}
+
+	if layout.DeleteUsingLayout == "" {
+		return "", ErrDeleteUsingUnsupported
+	}
+
+	compiled = layout.MustCompile(layout.DeleteUsingLayout, u)
+
+	layout.Write(u, compiled)
+
+	return
+}
@@ -0,0 +1,92 @@
+package exql
+
+import (
+	"strings"
+)
+
+// Operation represents an arithmetic operation between two or more operands,
+// like "n" + ?.
+type Operation struct {
+	Operator string
+	Operands []Fragment
+	hash     hash
+}
+
+var _ = Fragment(&Operation{})
+
+// Hash returns a unique identifier for the struct.
+func (o *Operation) Hash() string {
+	return o.hash.Hash(o)
+}
+
+// Compile transforms the Operation into an equivalent SQL representation.
+func (o *Operation) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(o); ok {
+		return z, nil
+	}
+
+	operands, err := compileOperands(layout, o.Operands)
+	if err != nil {
+		return "", err
+	}
+	compiled = strings.Join(operands, " "+o.Operator+" ")
+
+	layout.Write(o, compiled)
+	return
+}
+
+// Concatenation represents a string concatenation. It is compiled with the
+// template's ConcatOperator, or with CONCAT() if the template does not define
+// one.
+type Concatenation struct {
+	Operands []Fragment
+	hash     hash
+}
+
+var _ = Fragment(&Concatenation{})
+
+// Hash returns a unique identifier for the struct.
+func (c *Concatenation) Hash() string {
+	return c.hash.Hash(c)
+}
+
+// Compile transforms the Concatenation into an equivalent SQL representation.
+func (c *Concatenation) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(c); ok {
+		return z, nil
+	}
+
+	if layout.ConcatOperator == "" {
+		fn := FunctionCall{Name: "CONCAT", Args: c.Operands}
+		if compiled, err = fn.Compile(layout); err != nil {
+			return "", err
+		}
+	} else {
+		operands, err := compileOperands(layout, c.Operands)
+		if err != nil {
+			return "", err
+		}
+		compiled = strings.Join(operands, " "+layout.ConcatOperator+" ")
+	}
+
+	layout.Write(c, compiled)
+	return
+}
+
+// compileOperands compiles the given operands, nested operations are wrapped
+// in parentheses to preserve precedence.
+func compileOperands(layout *Template, operands []Fragment) ([]string, error) {
+	out := make([]string, len(operands))
+	for i := range operands {
+		s, err := operands[i].Compile(layout)
+		if err != nil {
+			return nil, err
+		}
+		switch operands[i].(type) {
+		case *Operation, *Concatenation:
+			s = "(" + s + ")"
+		}
+		out[i] = s
+	}
+	return out, nil
+}
@@ -0,0 +1,60 @@
+package exql
+
+import (
+	"testing"
+
+	"github.com/frazercomputing/upper-io-db/internal/cache"
+)
+
+func TestOperation(t *testing.T) {
+	op := &Operation{
+		Operator: "*",
+		Operands: []Fragment{
+			&Operation{Operator: "+", Operands: []Fragment{ColumnWithName("n"), RawValue("?")}},
+			RawValue("?"),
+		},
+	}
+
+	s, err := op.Compile(defaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := `("n" + ?) * ?`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
+
+func TestConcatenation(t *testing.T) {
+	c := &Concatenation{
+		Operands: []Fragment{ColumnWithName("first_name"), RawValue("?"), ColumnWithName("last_name")},
+	}
+
+	s, err := c.Compile(defaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := `"first_name" || ? || "last_name"`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	noConcat := &Template{
+		ColumnSeparator:     defaultColumnSeparator,
+		IdentifierQuote:     defaultIdentifierQuote,
+		IdentifierSeparator: defaultIdentifierSeparator,
+		Cache:               cache.NewCache(),
+	}
+
+	s, err = c.Compile(noConcat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e = `CONCAT("first_name", ?, "last_name")`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
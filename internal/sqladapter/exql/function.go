@@ -0,0 +1,38 @@
+package exql
+
+import (
+	"strings"
+)
+
+// FunctionCall represents a call to a SQL function, like coalesce("a", "b").
+type FunctionCall struct {
+	Name string
+	Args []Fragment
+	hash hash
+}
+
+var _ = Fragment(&FunctionCall{})
+
+// Hash returns a unique identifier for the struct.
+func (f *FunctionCall) Hash() string {
+	return f.hash.Hash(f)
+}
+
+// Compile transforms the FunctionCall into an equivalent SQL representation.
+func (f *FunctionCall) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(f); ok {
+		return z, nil
+	}
+
+	args := make([]string, len(f.Args))
+	for i := range f.Args {
+		if args[i], err = f.Args[i].Compile(layout); err != nil {
+			return "", err
+		}
+	}
+
+	compiled = f.Name + "(" + strings.Join(args, layout.IdentifierSeparator) + ")"
+
+	layout.Write(f, compiled)
+	return
+}
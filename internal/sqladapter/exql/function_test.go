@@ -0,0 +1,36 @@
+package exql
+
+import (
+	"testing"
+)
+
+func TestFunctionCall(t *testing.T) {
+	fn := &FunctionCall{
+		Name: "coalesce",
+		Args: []Fragment{ColumnWithName("a"), ColumnWithName("b.c"), RawValue("?")},
+	}
+
+	s, err := fn.Compile(defaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := `coalesce("a", "b"."c", ?)`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
+
+func TestFunctionCallNoArgs(t *testing.T) {
+	fn := &FunctionCall{Name: "NOW"}
+
+	s, err := fn.Compile(defaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := `NOW()`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
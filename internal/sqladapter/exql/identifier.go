@@ -0,0 +1,72 @@
+package exql
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/frazercomputing/upper-io-db/internal/cache"
+)
+
+// plainIdentifier matches the identifiers that mean the same quoted or not on
+// every supported database, as long as they're not reserved words.
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// QuoteIdentifier quotes name using IdentifierQuote. Templates returned by
+// WithMinimalQuoting leave plain lowercase names that are not reserved words
+// as they are.
+func (layout *Template) QuoteIdentifier(name string) string {
+	if layout.minimalQuoting && plainIdentifier.MatchString(name) {
+		if _, reserved := layout.reservedWords[name]; !reserved {
+			return name
+		}
+	}
+	return layout.MustCompile(layout.IdentifierQuote, Raw{Value: name})
+}
+
+// WithMinimalQuoting returns a copy of the template that only quotes the
+// identifiers that need it: the given reserved words, compared without
+// regard to case, and names made of anything other than lowercase letters,
+// digits and underscores. Quoting every identifier, which is what templates do
+// by default, is the safest choice, this is meant for databases or tools that
+// rely on unquoted names.
+//
+// Copies are cached, calling WithMinimalQuoting again with the same words
+// returns the same template.
+func (layout *Template) WithMinimalQuoting(reservedWords ...string) *Template {
+	words := make([]string, len(reservedWords))
+	for i := range reservedWords {
+		words[i] = strings.ToLower(reservedWords[i])
+	}
+	sort.Strings(words)
+	key := strings.Join(words, ",")
+
+	layout.templateMutex.Lock()
+	defer layout.templateMutex.Unlock()
+
+	if t, ok := layout.minimalQuotingTemplates[key]; ok {
+		return t
+	}
+
+	t := &Template{}
+	src, dst := reflect.ValueOf(layout).Elem(), reflect.ValueOf(t).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if dst.Type().Field(i).PkgPath == "" {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	t.Cache = cache.NewCache()
+	t.minimalQuoting = true
+	t.reservedWords = make(map[string]struct{}, len(words))
+	for _, word := range words {
+		t.reservedWords[word] = struct{}{}
+	}
+
+	if layout.minimalQuotingTemplates == nil {
+		layout.minimalQuotingTemplates = make(map[string]*Template)
+	}
+	layout.minimalQuotingTemplates[key] = t
+
+	return t
+}
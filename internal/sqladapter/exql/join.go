@@ -129,7 +129,7 @@ func (o *On) Compile(layout *Template) (compiled string, err error) {
 		return c, nil
 	}
 
-	grouped, err := groupCondition(layout, o.Conditions, layout.MustCompile(layout.ClauseOperator, layout.AndKeyword))
+	grouped, err := groupCondition(layout, o.Conditions, layout.MustCompile(layout.ClauseOperator, layout.AndKeyword), true)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,115 @@
+package exql
+
+import (
+	"strings"
+)
+
+// LiteralLen returns the length of the string, quoted identifier, comment or
+// dollar-quoted string that starts at in[i], or zero if there's none or it's
+// not terminated. Placeholders within them are not placeholders, so scanners
+// step over them:
+//
+//	'it''s ?', E'it\'s ?', "col?", `col?`, -- ?, /* ? */, $$ ? $$, $fn$ ? $fn$
+//
+// Backslashes escape quotes only in escape strings, like E'it\'s', as
+// PostgreSQL does with standard_conforming_strings on.
+func LiteralLen(in string, i int) int {
+	return literalLen(in, i, false)
+}
+
+// LiteralLen is like the LiteralLen function but, if the template has
+// BackslashEscapes set, backslashes also escape quotes in single and double
+// quoted strings. It can be called on a nil template.
+func (layout *Template) LiteralLen(in string, i int) int {
+	return literalLen(in, i, layout != nil && layout.BackslashEscapes)
+}
+
+func literalLen(in string, i int, backslashEscapes bool) int {
+	switch c := in[i]; {
+	case c == '\'':
+		escapes := backslashEscapes || i > 0 && (in[i-1] == 'E' || in[i-1] == 'e') && (i < 2 || !isIdentifierChar(in[i-2]))
+		return quotedLen(in[i:], c, escapes)
+	case c == '"':
+		return quotedLen(in[i:], c, backslashEscapes)
+	case c == '`':
+		return quotedLen(in[i:], c, false)
+	case c == '-' && strings.HasPrefix(in[i:], "--"):
+		if n := strings.IndexByte(in[i:], '\n'); n >= 0 {
+			return n
+		}
+		return len(in) - i
+	case c == '/' && strings.HasPrefix(in[i:], "/*"):
+		return blockCommentLen(in[i:])
+	case c == '$' && (i == 0 || !isIdentifierChar(in[i-1])):
+		tag := dollarQuoteTag(in[i:])
+		if tag == "" {
+			return 0
+		}
+		if n := strings.Index(in[i+len(tag):], tag); n >= 0 {
+			return len(tag) + n + len(tag)
+		}
+	}
+	return 0
+}
+
+// quotedLen returns the length of the quoted text s starts with. Quotes are
+// escaped by doubling them.
+func quotedLen(s string, quote byte, escapes bool) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// blockCommentLen returns the length of the block comment s starts with,
+// block comments can be nested.
+func blockCommentLen(s string) int {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*':
+			depth++
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// dollarQuoteTag returns the tag of the dollar quote s starts with, like $$
+// or $fn$. Numbered placeholders, like $1, are not dollar quotes.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c >= '0' && c <= '9' && i == 1:
+			return ""
+		case c == '_' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
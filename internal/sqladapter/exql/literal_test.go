@@ -0,0 +1,63 @@
+package exql
+
+import (
+	"testing"
+)
+
+func TestLiteralLen(t *testing.T) {
+	tests := []struct {
+		in  string
+		i   int
+		out int
+	}{
+		{`a = 1`, 0, 0},
+		{`'it''s ?' x`, 0, 9},
+		{`'unterminated`, 0, 0},
+		{`'a\' x`, 0, 4},
+		{`E'it\'s' x`, 1, 7},
+		{`e'\\' x`, 1, 4},
+		{`some'it\'s'`, 4, 5},
+		{`"col?""x" y`, 0, 9},
+		{"`col?` y", 0, 6},
+		{"-- ? \nSELECT", 0, 5},
+		{"-- ?", 0, 4},
+		{`/* a /* ? */ b */ c`, 0, 17},
+		{`/* a`, 0, 0},
+		{`$$ ? $$ x`, 0, 7},
+		{`$fn$ $$ ? $fn$ x`, 0, 14},
+		{`$1 = ?`, 0, 0},
+		{`a$b$ ? $b$`, 1, 0},
+		{`$$ unterminated`, 0, 0},
+	}
+
+	for _, test := range tests {
+		if n := LiteralLen(test.in, test.i); n != test.out {
+			t.Fatalf("LiteralLen(%q, %d): Got: %d, Expecting: %d", test.in, test.i, n, test.out)
+		}
+	}
+}
+
+func TestTemplateLiteralLen(t *testing.T) {
+	layout := &Template{BackslashEscapes: true}
+	tests := []struct {
+		in  string
+		i   int
+		out int
+	}{
+		{`'it\'s ?' x`, 0, 9},
+		{`"a\"b" x`, 0, 6},
+		{"`a\\` x", 0, 4},
+		{`E'it\'s' x`, 1, 7},
+	}
+
+	for _, test := range tests {
+		if n := layout.LiteralLen(test.in, test.i); n != test.out {
+			t.Fatalf("LiteralLen(%q, %d): Got: %d, Expecting: %d", test.in, test.i, n, test.out)
+		}
+	}
+
+	var none *Template
+	if n := none.LiteralLen(`'it\'s ?' x`, 0); n != 5 {
+		t.Fatalf("Got: %d, Expecting: %d", n, 5)
+	}
+}
@@ -0,0 +1,39 @@
+package exql
+
+import (
+	"errors"
+)
+
+// ErrRowLocksUnsupported is returned when a statement asks for row locks on a
+// template that has no LockLayout.
+var ErrRowLocksUnsupported = errors.New("row locks are not supported by this database")
+
+// Lock represents a row locking clause, like FOR UPDATE.
+type Lock struct {
+	SkipLocked bool
+	hash       hash
+}
+
+var _ = Fragment(&Lock{})
+
+// Hash returns a unique identifier for the struct.
+func (l *Lock) Hash() string {
+	return l.hash.Hash(l)
+}
+
+// Compile transforms the Lock into its equivalent SQL representation.
+func (l *Lock) Compile(layout *Template) (compiled string, err error) {
+	if c, ok := layout.Read(l); ok {
+		return c, nil
+	}
+
+	if layout.LockLayout == "" {
+		return "", ErrRowLocksUnsupported
+	}
+
+	compiled = layout.MustCompile(layout.LockLayout, l)
+
+	layout.Write(l, compiled)
+
+	return
+}
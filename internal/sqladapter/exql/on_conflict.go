@@ -0,0 +1,80 @@
+package exql
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUpsertUnsupported is returned when an OnConflict clause or the inserted
+// discriminator are used on a template that has no layout for them.
+var ErrUpsertUnsupported = errors.New("upserts are not supported on this database")
+
+// OnConflict represents the clause that turns an INSERT into an upsert.
+type OnConflict struct {
+	// Target has the columns of the unique constraint rows may conflict on.
+	Target *Columns
+
+	// Update has the columns that are set to the proposed values when a row
+	// conflicts. Conflicting rows are left as they are when it's empty.
+	Update *Columns
+
+	// Insert has the columns given to the INSERT statement.
+	Insert *Columns
+
+	hash hash
+}
+
+var _ = Fragment(&OnConflict{})
+
+type onConflictT struct {
+	Target []string
+	Update []string
+	Insert []string
+}
+
+// Hash returns a unique identifier for the struct.
+func (o *OnConflict) Hash() string {
+	return o.hash.Hash(o)
+}
+
+// Compile transforms the OnConflict into its equivalent SQL representation.
+func (o *OnConflict) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(o); ok {
+		return z, nil
+	}
+
+	if layout.OnConflictLayout == "" {
+		return "", ErrUpsertUnsupported
+	}
+
+	data := onConflictT{}
+	if data.Target, err = compileColumnList(layout, o.Target); err != nil {
+		return "", err
+	}
+	if data.Update, err = compileColumnList(layout, o.Update); err != nil {
+		return "", err
+	}
+	if data.Insert, err = compileColumnList(layout, o.Insert); err != nil {
+		return "", err
+	}
+
+	compiled = strings.TrimSpace(layout.MustCompile(layout.OnConflictLayout, data))
+
+	layout.Write(o, compiled)
+	return
+}
+
+func compileColumnList(layout *Template, columns *Columns) ([]string, error) {
+	if columns == nil {
+		return nil, nil
+	}
+	out := make([]string, 0, len(columns.Columns))
+	for _, column := range columns.Columns {
+		s, err := column.Compile(layout)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
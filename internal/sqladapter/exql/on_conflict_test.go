@@ -0,0 +1,80 @@
+package exql
+
+import (
+	"testing"
+
+	"github.com/frazercomputing/upper-io-db/internal/cache"
+)
+
+func TestOnConflict(t *testing.T) {
+	layout := &Template{
+		IdentifierQuote:     `"{{.Value}}"`,
+		IdentifierSeparator: `, `,
+		ColumnSeparator:     `.`,
+		OnConflictLayout:    `ON CONFLICT ({{range $i, $c := .Target}}{{if $i}}, {{end}}{{$c}}{{end}}) {{if .Update}}DO UPDATE SET {{range $i, $c := .Update}}{{if $i}}, {{end}}{{$c}} = EXCLUDED.{{$c}}{{end}}{{else}}DO NOTHING{{end}}`,
+		InsertedLayout:      `(xmax = 0) AS "inserted"`,
+		Cache:               cache.NewCache(),
+	}
+
+	onConflict := &OnConflict{
+		Target: JoinColumns(&Column{Name: "email"}),
+		Update: JoinColumns(&Column{Name: "name"}, &Column{Name: "age"}),
+	}
+
+	s, err := onConflict.Compile(layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := `ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name", "age" = EXCLUDED."age"`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	s, err = (&OnConflict{Target: JoinColumns(&Column{Name: "email"})}).Compile(layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e = `ON CONFLICT ("email") DO NOTHING`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	if _, err = onConflict.Compile(&Template{Cache: cache.NewCache()}); err != ErrUpsertUnsupported {
+		t.Fatalf("Got: %v, Expecting: %v", err, ErrUpsertUnsupported)
+	}
+}
+
+func TestReturningInserted(t *testing.T) {
+	layout := &Template{
+		IdentifierQuote:     `"{{.Value}}"`,
+		IdentifierSeparator: `, `,
+		ColumnSeparator:     `.`,
+		InsertedLayout:      `(xmax = 0) AS "inserted"`,
+		Cache:               cache.NewCache(),
+	}
+
+	returning := ReturningColumns(&Column{Name: "id"})
+	returning.Inserted = true
+
+	s, err := returning.Compile(layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := `"id", (xmax = 0) AS "inserted"`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	inserted := ReturningColumns()
+	if !inserted.IsEmpty() {
+		t.Fatal("Expecting an empty clause")
+	}
+	inserted.Inserted = true
+	if inserted.IsEmpty() {
+		t.Fatal("Expecting a non-empty clause")
+	}
+
+	if _, err = inserted.Compile(&Template{Cache: cache.NewCache()}); err != ErrUpsertUnsupported {
+		t.Fatalf("Got: %v, Expecting: %v", err, ErrUpsertUnsupported)
+	}
+}
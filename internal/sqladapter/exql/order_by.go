@@ -1,10 +1,21 @@
 package exql
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
+// ErrCollateUnsupported is returned when a collation is given to a sort column
+// on a template that has no CollateLayout.
+var ErrCollateUnsupported = errors.New("COLLATE is not supported on this database")
+
+// collationName matches the collation names that can be put into a query
+// without quoting, like "C", "en_US.utf8", "und-x-icu" or
+// "Latin1_General_CI_AS".
+var collationName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.@-]*$`)
+
 // Order represents the order in which SQL results are sorted.
 type Order uint8
 
@@ -15,11 +26,23 @@ const (
 	Descendent
 )
 
+// Nulls represents the position of NULL values in an ORDER BY clause.
+type Nulls uint8
+
+// Possible values for Nulls
+const (
+	DefaultNulls = Nulls(iota)
+	NullsFirst
+	NullsLast
+)
+
 // SortColumn represents the column-order relation in an ORDER BY clause.
 type SortColumn struct {
 	Column Fragment
 	Order
-	hash hash
+	Nulls     Nulls
+	Collation string
+	hash      hash
 }
 
 var _ = Fragment(&SortColumn{})
@@ -85,8 +108,33 @@ func (s *SortColumn) Compile(layout *Template) (compiled string, err error) {
 
 	data := sortColumnT{Column: column, Order: orderBy}
 
+	if s.Collation != "" {
+		if layout.CollateLayout == "" {
+			return "", ErrCollateUnsupported
+		}
+		if !collationName.MatchString(s.Collation) {
+			return "", fmt.Errorf("invalid collation name %q", s.Collation)
+		}
+		data.Column = column + " " + layout.MustCompile(layout.CollateLayout, s.Collation)
+	}
+
 	compiled = layout.MustCompile(layout.SortByColumnLayout, data)
 
+	if s.Nulls != DefaultNulls {
+		keyword := layout.NullsFirstKeyword
+		first, rest := "0", "1"
+		if s.Nulls == NullsLast {
+			keyword = layout.NullsLastKeyword
+			first, rest = "1", "0"
+		}
+		if keyword != "" {
+			compiled = compiled + " " + keyword
+		} else {
+			// Databases without NULLS FIRST/LAST sort by nullness first.
+			compiled = "CASE WHEN " + column + " IS NULL THEN " + first + " ELSE " + rest + " END" + layout.IdentifierSeparator + compiled
+		}
+	}
+
 	layout.Write(s, compiled)
 
 	return
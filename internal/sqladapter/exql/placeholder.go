@@ -0,0 +1,138 @@
+package exql
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+// DollarPlaceholder renders the i-th (1-based) placeholder as $i, like
+// PostgreSQL expects.
+func DollarPlaceholder(i int) string {
+	return "$" + strconv.Itoa(i)
+}
+
+// ColonPlaceholder renders the i-th (1-based) placeholder as :i.
+func ColonPlaceholder(i int) string {
+	return ":" + strconv.Itoa(i)
+}
+
+// ReplacePlaceholders turns a statement with '?' placeholders into one that
+// uses the placeholders rendered by fn. A double question mark ("??") is an
+// escaped literal '?' and is not counted as a placeholder. Question marks
+// within strings, quoted identifiers, comments and dollar-quoted strings (see
+// LiteralLen) are left as they are. When fn is nil the statement is returned
+// as it is, escaped question marks included.
+func ReplacePlaceholders(in string, fn func(i int) string) string {
+	return replacePlaceholders(nil, in, fn)
+}
+
+// replacePlaceholders works like ReplacePlaceholders, literals are told apart
+// the way the given template does. Templates without a Placeholder function
+// keep '?' placeholders, so their escaped question marks are kept as well.
+func replacePlaceholders(layout *Template, in string, fn func(i int) string) string {
+	if fn == nil {
+		return in
+	}
+	keepEscaped := layout != nil && layout.Placeholder == nil
+
+	buf := []byte(in)
+	out := make([]byte, 0, len(buf))
+
+	i, j, k, t := 0, 1, 0, len(buf)
+
+	for i < t {
+		if n := layout.LiteralLen(in, i); n > 0 {
+			i += n
+			continue
+		}
+		if buf[i] == '?' {
+			out = append(out, buf[k:i]...)
+			k = i + 1
+
+			if k < t && buf[k] == '?' {
+				if keepEscaped {
+					out = append(out, '?')
+				}
+				i = k
+			} else {
+				out = append(out, fn(j)...)
+				j++
+			}
+		}
+		i++
+	}
+	out = append(out, buf[k:i]...)
+
+	return string(out)
+}
+
+// ReplacePlaceholders turns the '?' placeholders of the given statement into
+// the ones the template's Placeholder function renders.
+func (layout *Template) ReplacePlaceholders(in string) string {
+	return replacePlaceholders(layout, in, layout.Placeholder)
+}
+
+// BindArguments replaces the '?' placeholders of the given statement like
+// ReplacePlaceholders and binds the i-th placeholder to the i-th argument.
+// Arguments of type sql.NamedArg are rendered with the template's
+// NamedPlaceholder function and sent to the driver by name, the same name is
+// sent only once. Templates without NamedPlaceholder send the value of named
+// arguments by position instead and, if they use numbered placeholders, all
+// the references to the same name share a number. Arguments beyond the last
+// placeholder, like named arguments that the statement refers to by name, are
+// sent as they are.
+func (layout *Template) BindArguments(in string, args []interface{}) (string, []interface{}) {
+	if !hasNamedArgs(args) {
+		return layout.ReplacePlaceholders(in), args
+	}
+
+	out := make([]interface{}, 0, len(args))
+	bound := map[string]int{}
+	n := 0
+
+	query := replacePlaceholders(layout, in, func(j int) string {
+		if j > len(args) {
+			return layout.placeholder(j)
+		}
+		n = j
+
+		named, ok := args[j-1].(sql.NamedArg)
+		if !ok || named.Name == "" {
+			out = append(out, args[j-1])
+			return layout.placeholder(len(out))
+		}
+
+		if layout.NamedPlaceholder != nil {
+			if _, ok := bound[named.Name]; !ok {
+				bound[named.Name] = len(out)
+				out = append(out, named)
+			}
+			return layout.NamedPlaceholder(named.Name)
+		}
+
+		if i, ok := bound[named.Name]; ok && layout.Placeholder != nil {
+			return layout.Placeholder(i + 1)
+		}
+		bound[named.Name] = len(out)
+		out = append(out, named.Value)
+		return layout.placeholder(len(out))
+	})
+
+	return query, append(out, args[n:]...)
+}
+
+func (layout *Template) placeholder(i int) string {
+	if layout.Placeholder == nil {
+		return "?"
+	}
+	return layout.Placeholder(i)
+}
+
+func hasNamedArgs(args []interface{}) bool {
+	for i := range args {
+		if _, ok := args[i].(sql.NamedArg); ok {
+			return true
+		}
+	}
+	return false
+}
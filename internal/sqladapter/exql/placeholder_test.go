@@ -0,0 +1,101 @@
+package exql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestReplacePlaceholders(t *testing.T) {
+	in := `SELECT ?? FROM "t" WHERE a = ? AND b IN (?, ?)`
+
+	layout := &Template{
+		Placeholder: func(i int) string {
+			return "@p" + string('0'+rune(i))
+		},
+	}
+
+	tests := []struct {
+		out      string
+		expected string
+	}{
+		{ReplacePlaceholders(in, DollarPlaceholder), `SELECT ? FROM "t" WHERE a = $1 AND b IN ($2, $3)`},
+		{ReplacePlaceholders(in, ColonPlaceholder), `SELECT ? FROM "t" WHERE a = :1 AND b IN (:2, :3)`},
+		{ReplacePlaceholders(in, nil), in},
+		{(&Template{}).ReplacePlaceholders(in), in},
+		{layout.ReplacePlaceholders(in), `SELECT ? FROM "t" WHERE a = @p1 AND b IN (@p2, @p3)`},
+	}
+
+	for _, test := range tests {
+		if test.out != test.expected {
+			t.Fatalf("Got: %s, Expecting: %s", test.out, test.expected)
+		}
+	}
+}
+
+func TestReplacePlaceholdersLiterals(t *testing.T) {
+	in := "CREATE FUNCTION f(a int) RETURNS int AS $fn$ SELECT CASE WHEN a ? '$2' THEN $1 END; $fn$ LANGUAGE sql; " +
+		"SELECT f(?), 'is it?', \"col?\", $$ ?? $$ /* ? */ FROM t WHERE b = ? -- ?"
+	expected := "CREATE FUNCTION f(a int) RETURNS int AS $fn$ SELECT CASE WHEN a ? '$2' THEN $1 END; $fn$ LANGUAGE sql; " +
+		"SELECT f($1), 'is it?', \"col?\", $$ ?? $$ /* ? */ FROM t WHERE b = $2 -- ?"
+
+	if out := ReplacePlaceholders(in, DollarPlaceholder); out != expected {
+		t.Fatalf("Got: %s, Expecting: %s", out, expected)
+	}
+}
+
+func TestBindArguments(t *testing.T) {
+	in := `SELECT * FROM "t" WHERE a = ? AND b = ? AND c = ? AND d = ?`
+	args := []interface{}{1, sql.Named("x", "X"), 2, sql.Named("x", "X"), sql.Named("out", 0)}
+
+	tests := []struct {
+		layout       *Template
+		expected     string
+		expectedArgs []interface{}
+	}{
+		{
+			&Template{Placeholder: DollarPlaceholder},
+			`SELECT * FROM "t" WHERE a = $1 AND b = $2 AND c = $3 AND d = $2`,
+			[]interface{}{1, "X", 2, sql.Named("out", 0)},
+		},
+		{
+			&Template{},
+			`SELECT * FROM "t" WHERE a = ? AND b = ? AND c = ? AND d = ?`,
+			[]interface{}{1, "X", 2, "X", sql.Named("out", 0)},
+		},
+		{
+			&Template{
+				Placeholder: func(i int) string {
+					return "@p" + string('0'+rune(i))
+				},
+				NamedPlaceholder: func(name string) string {
+					return "@" + name
+				},
+			},
+			`SELECT * FROM "t" WHERE a = @p1 AND b = @x AND c = @p3 AND d = @x`,
+			[]interface{}{1, sql.Named("x", "X"), 2, sql.Named("out", 0)},
+		},
+	}
+
+	for _, test := range tests {
+		out, outArgs := test.layout.BindArguments(in, args)
+		if out != test.expected {
+			t.Fatalf("Got: %s, Expecting: %s", out, test.expected)
+		}
+		if !reflect.DeepEqual(outArgs, test.expectedArgs) {
+			t.Fatalf("Got: %v, Expecting: %v", outArgs, test.expectedArgs)
+		}
+	}
+
+	// Statements without named arguments are left as they are.
+	out, outArgs := (&Template{Placeholder: DollarPlaceholder}).BindArguments(`a = ? AND b = ?`, []interface{}{1, 2})
+	if out != `a = $1 AND b = $2` || !reflect.DeepEqual(outArgs, []interface{}{1, 2}) {
+		t.Fatalf("Got: %s %v", out, outArgs)
+	}
+
+	// Templates that keep '?' placeholders keep escaped question marks too.
+	out, _ = (&Template{}).BindArguments(`a ?? b AND c = ?`, []interface{}{sql.Named("x", 1)})
+	if out != `a ?? b AND c = ?` {
+		t.Fatalf("Got: %s", out)
+	}
+}
@@ -12,7 +12,6 @@ var (
 // Raw represents a value that is meant to be used in a query without escaping.
 type Raw struct {
 	Value string // Value should not be modified after assigned.
-	hash  hash
 }
 
 // RawValue creates and returns a new raw value.
@@ -20,9 +19,10 @@ func RawValue(v string) *Raw {
 	return &Raw{Value: strings.TrimSpace(v)}
 }
 
-// Hash returns a unique identifier for the struct.
+// Hash returns a unique identifier for the struct. Raw values are identified
+// by their full content, so different SQL strings never share a cache key.
 func (r *Raw) Hash() string {
-	return r.hash.Hash(r)
+	return `*exql.Raw:` + r.Value
 }
 
 // Compile returns the raw value.
@@ -38,11 +38,15 @@ func TestRawHash(t *testing.T) {
 	raw := &Raw{Value: "foo"}
 
 	s = raw.Hash()
-	e = `*exql.Raw:5772950988983410957`
+	e = `*exql.Raw:foo`
 
 	if s != e {
 		t.Fatalf("Got: %s, Expecting: %s", s, e)
 	}
+
+	if (&Raw{Value: "foo"}).Hash() == (&Raw{Value: "bar"}).Hash() {
+		t.Fatal("Expecting different hashes for different raw values")
+	}
 }
 
 func BenchmarkRawCreate(b *testing.B) {
@@ -3,6 +3,12 @@ package exql
 // Returning represents a RETURNING clause.
 type Returning struct {
 	*Columns
+
+	// Inserted adds a boolean "inserted" column, as given by the template's
+	// InsertedLayout, that tells rows inserted by an upsert apart from the
+	// ones it updated.
+	Inserted bool
+
 	hash hash
 }
 
@@ -18,15 +24,32 @@ func ReturningColumns(columns ...Fragment) *Returning {
 	return &Returning{Columns: &Columns{Columns: columns}}
 }
 
+// IsEmpty returns true if the clause has no columns.
+func (r *Returning) IsEmpty() bool {
+	return !r.Inserted && r.Columns.IsEmpty()
+}
+
 // Compile transforms the clause into its equivalent SQL representation.
 func (r *Returning) Compile(layout *Template) (compiled string, err error) {
 	if z, ok := layout.Read(r); ok {
 		return z, nil
 	}
 
-	compiled, err = r.Columns.Compile(layout)
-	if err != nil {
-		return "", err
+	if !r.Columns.IsEmpty() {
+		compiled, err = r.Columns.Compile(layout)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if r.Inserted {
+		if layout.InsertedLayout == "" {
+			return "", ErrUpsertUnsupported
+		}
+		if compiled != "" {
+			compiled += layout.IdentifierSeparator
+		}
+		compiled += layout.InsertedLayout
 	}
 
 	layout.Write(r, compiled)
@@ -0,0 +1,69 @@
+package exql
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrSampleUnsupported is returned when a statement asks for a sample of a
+// table on a template that has no SampleLayout.
+var ErrSampleUnsupported = errors.New("table samples are not supported by this database")
+
+// ErrRandomOrderUnsupported is returned when a statement is sorted randomly
+// on a template that has no RandomFunction.
+var ErrRandomOrderUnsupported = errors.New("random order is not supported by this database")
+
+// Sample represents a table sampling clause, like TABLESAMPLE SYSTEM (10).
+type Sample struct {
+	Percent float64
+	hash    hash
+}
+
+var _ = Fragment(&Sample{})
+
+type sampleT struct {
+	Percent string
+}
+
+// Hash returns a unique identifier for the struct.
+func (s *Sample) Hash() string {
+	return s.hash.Hash(s)
+}
+
+// Compile transforms the Sample into its equivalent SQL representation.
+func (s *Sample) Compile(layout *Template) (compiled string, err error) {
+	if c, ok := layout.Read(s); ok {
+		return c, nil
+	}
+
+	if layout.SampleLayout == "" {
+		return "", ErrSampleUnsupported
+	}
+
+	data := sampleT{Percent: strconv.FormatFloat(s.Percent, 'f', -1, 64)}
+	compiled = layout.MustCompile(layout.SampleLayout, data)
+
+	layout.Write(s, compiled)
+
+	return
+}
+
+// Random represents a random value to sort by, like random().
+type Random struct {
+	hash hash
+}
+
+var _ = Fragment(&Random{})
+
+// Hash returns a unique identifier for the struct.
+func (r *Random) Hash() string {
+	return r.hash.Hash(r)
+}
+
+// Compile transforms the Random into its equivalent SQL representation.
+func (r *Random) Compile(layout *Template) (string, error) {
+	if layout.RandomFunction == "" {
+		return "", ErrRandomOrderUnsupported
+	}
+	return layout.RandomFunction, nil
+}
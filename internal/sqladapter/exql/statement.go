@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 
+	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/cache"
 )
 
@@ -19,17 +20,32 @@ type Statement struct {
 	Values       Fragment
 	Distinct     bool
 	ColumnValues Fragment
+	From         Fragment
 	OrderBy      Fragment
 	GroupBy      Fragment
 	Joins        Fragment
 	Where        Fragment
 	Returning    Fragment
+	OnConflict   Fragment
+	Definitions  Fragment
+	Alterations  Fragment
+	Lock         Fragment
+	Sample       Fragment
+
+	IfNotExists bool
+	IfExists    bool
+	Temporary   bool
+	Cascade     bool
 
 	Limit
 	Offset
 
 	SQL string
 
+	// Comment is sent along with the statement as a SQL comment, it's not
+	// part of the compiled statement. See CommentPrefix.
+	Comment string
+
 	hash    hash
 	amendFn func(string) string
 }
@@ -82,6 +98,16 @@ func (s *Statement) template(layout *Template) (string, error) {
 		return layout.UpdateLayout, nil
 	case Insert:
 		return layout.InsertLayout, nil
+	case CreateTable:
+		if layout.CreateTableLayout == "" {
+			return "", db.ErrUnsupported
+		}
+		return layout.CreateTableLayout, nil
+	case AlterTable:
+		if layout.AlterTableLayout == "" {
+			return "", db.ErrUnsupported
+		}
+		return layout.AlterTableLayout, nil
 	default:
 		return "", errUnknownTemplateType
 	}
@@ -111,6 +137,31 @@ func (s *Statement) Compile(layout *Template) (compiled string, err error) {
 	return s.Amend(compiled), nil
 }
 
+var (
+	commentDelimiterReplacer = strings.NewReplacer("/*", "", "*/", "")
+	newLineReplacer          = strings.NewReplacer("\n", " ", "\r", " ")
+)
+
+// CommentPrefix returns the given comments as SQL comments, ready to be
+// prepended to a compiled statement. Comment delimiters are removed from the
+// text, so it can't break out of the comment, and empty comments are skipped.
+func CommentPrefix(comments ...string) string {
+	prefix := ""
+	for _, comment := range comments {
+		comment = newLineReplacer.Replace(comment)
+		// Removing a delimiter may join the characters around it into a new
+		// one, like in "**//".
+		for strings.Contains(comment, "/*") || strings.Contains(comment, "*/") {
+			comment = commentDelimiterReplacer.Replace(comment)
+		}
+		comment = strings.TrimSpace(comment)
+		if comment != "" {
+			prefix += "/* " + comment + " */ "
+		}
+	}
+	return prefix
+}
+
 // RawSQL represents a raw SQL statement.
 func RawSQL(s string) *Statement {
 	return &Statement{
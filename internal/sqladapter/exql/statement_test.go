@@ -918,3 +918,25 @@ func BenchmarkStatementComplexQueryNoCache(b *testing.B) {
 		_, _ = stmt.Compile(defaultTemplate)
 	}
 }
+
+func TestCommentPrefix(t *testing.T) {
+	tests := []struct {
+		in  []string
+		out string
+	}{
+		{nil, ""},
+		{[]string{"", " "}, ""},
+		{[]string{"checkout-service:recalc"}, "/* checkout-service:recalc */ "},
+		{[]string{"a", "", "b"}, "/* a */ /* b */ "},
+		{[]string{"id = ?"}, "/* id = ? */ "},
+		{[]string{"a\nb"}, "/* a b */ "},
+		{[]string{"x */ DROP TABLE users; /*"}, "/* x  DROP TABLE users; */ "},
+		{[]string{"x **//"}, "/* x */ "},
+	}
+
+	for _, test := range tests {
+		if s := CommentPrefix(test.in...); s != test.out {
+			t.Fatalf("Got: %q, Expecting: %q", s, test.out)
+		}
+	}
+}
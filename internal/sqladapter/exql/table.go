@@ -35,7 +35,7 @@ func quotedTableName(layout *Template, input string) string {
 	for i := range nameChunks {
 		// nameChunks[i] = strings.TrimSpace(nameChunks[i])
 		nameChunks[i] = trimString(nameChunks[i])
-		nameChunks[i] = layout.MustCompile(layout.IdentifierQuote, Raw{Value: nameChunks[i]})
+		nameChunks[i] = layout.QuoteIdentifier(nameChunks[i])
 	}
 
 	name = strings.Join(nameChunks, layout.ColumnSeparator)
@@ -45,7 +45,7 @@ func quotedTableName(layout *Template, input string) string {
 	if len(chunks) > 1 {
 		// alias = strings.TrimSpace(chunks[1])
 		alias = trimString(chunks[1])
-		alias = layout.MustCompile(layout.IdentifierQuote, Raw{Value: alias})
+		alias = layout.QuoteIdentifier(alias)
 	}
 
 	return layout.MustCompile(layout.TableAliasLayout, tableT{name, alias})
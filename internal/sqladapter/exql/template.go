@@ -3,6 +3,8 @@ package exql
 import (
 	"bytes"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"text/template"
 
@@ -27,6 +29,9 @@ const (
 	Delete
 
 	SQL
+
+	CreateTable
+	AlterTable
 )
 
 type (
@@ -38,45 +43,112 @@ type (
 
 // Template is an SQL template.
 type Template struct {
+	AddColumnLayout     string
+	AlterTableLayout    string
 	AndKeyword          string
 	AscKeyword          string
 	AssignmentOperator  string
 	ClauseGroup         string
 	ClauseOperator      string
+	CollateLayout       string
 	ColumnAliasLayout   string
 	ColumnSeparator     string
 	ColumnValue         string
+	ConcatOperator      string
 	CountLayout         string
+	CreateTableLayout   string
 	DeleteLayout        string
+	DeleteUsingLayout   string
 	DescKeyword         string
+	DropColumnLayout    string
 	DropDatabaseLayout  string
 	DropTableLayout     string
 	GroupByLayout       string
 	IdentifierQuote     string
 	IdentifierSeparator string
 	InsertLayout        string
+	InsertedLayout      string
 	JoinLayout          string
+	LockLayout          string
+	NullsFirstKeyword   string
+	NullsLastKeyword    string
+	OnConflictLayout    string
 	OnLayout            string
 	OrKeyword           string
 	OrderByLayout       string
+	RandomFunction      string
+	SampleLayout        string
 	SelectLayout        string
 	SortByColumnLayout  string
 	TableAliasLayout    string
 	TruncateLayout      string
+	UpdateFromLayout    string
 	UpdateLayout        string
 	UsingLayout         string
 	ValueQuote          string
 	ValueSeparator      string
+	ValuesTableLayout   string
 	WhereLayout         string
 
 	ComparisonOperator map[db.ComparisonOperator]string
 
+	// ColumnTypes maps generic column types, like "serial" or "text", to the
+	// ones the database understands.
+	ColumnTypes map[string]string
+
+	// Placeholder renders the i-th (1-based) placeholder of a statement, like
+	// $1 or @p1. When nil, statements keep the '?' placeholders they are
+	// compiled with.
+	Placeholder func(i int) string
+
+	// NamedPlaceholder renders a placeholder for a sql.NamedArg, like @name,
+	// for drivers that bind arguments by name. See BindArguments.
+	NamedPlaceholder func(name string) string
+
+	// BackslashEscapes tells that a backslash escapes the character that
+	// follows it within any string, like MySQL does by default, instead of
+	// only within escape strings, like E'it\'s'. See LiteralLen.
+	BackslashEscapes bool
+
+	// ReturningColumnsOnly is set on templates that can only return columns
+	// of the inserted rows, not expressions, like SQL Server's OUTPUT clause.
+	ReturningColumnsOnly bool
+
 	templateMutex sync.RWMutex
 	templateMap   map[string]*template.Template
 
+	minimalQuoting          bool
+	reservedWords           map[string]struct{}
+	minimalQuotingTemplates map[string]*Template
+
 	*cache.Cache
 }
 
+// Read returns the compiled form of the given fragment under this template,
+// if it's cached. Entries are keyed by template, so templates can share a
+// cache, as copies of a template do, without reading each other's output.
+func (layout *Template) Read(h cache.Hashable) (string, bool) {
+	return layout.Cache.Read(layout.cacheKey(h))
+}
+
+// Write caches the compiled form of the given fragment under this template.
+func (layout *Template) Write(h cache.Hashable, compiled interface{}) {
+	layout.Cache.Write(layout.cacheKey(h), compiled)
+}
+
+func (layout *Template) cacheKey(h cache.Hashable) cache.Hashable {
+	return cache.String(strconv.FormatUint(uint64(reflect.ValueOf(layout).Pointer()), 16) + "/" + h.Hash())
+}
+
+// ColumnType returns the column type the database uses for the given generic
+// type, or columnType itself if there's no translation.
+func (layout *Template) ColumnType(columnType string) string {
+	if t, ok := layout.ColumnTypes[strings.ToLower(columnType)]; ok {
+		return t
+	}
+	return columnType
+}
+
 func (layout *Template) MustCompile(templateText string, data interface{}) string {
 	var b bytes.Buffer
 
@@ -120,10 +192,6 @@ func (t *Template) getTemplate(k string) (*template.Template, bool) {
 	t.templateMutex.RLock()
 	defer t.templateMutex.RUnlock()
 
-	if t.templateMap == nil {
-		t.templateMap = make(map[string]*template.Template)
-	}
-
 	v, ok := t.templateMap[k]
 	return v, ok
 }
@@ -132,5 +200,8 @@ func (t *Template) setTemplate(k string, v *template.Template) {
 	t.templateMutex.Lock()
 	defer t.templateMutex.Unlock()
 
+	if t.templateMap == nil {
+		t.templateMap = make(map[string]*template.Template)
+	}
 	t.templateMap[k] = v
 }
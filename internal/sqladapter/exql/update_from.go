@@ -0,0 +1,44 @@
+package exql
+
+import (
+	"errors"
+)
+
+// ErrUpdateFromUnsupported is returned when an UPDATE statement reads from
+// other tables on a template that has no UpdateFromLayout.
+var ErrUpdateFromUnsupported = errors.New("updating from other tables is not supported by this database")
+
+// UpdateFrom represents the tables an UPDATE statement reads from, like
+// PostgreSQL's UPDATE ... FROM or SQL Server's UPDATE ... FROM ... JOIN.
+// Table is the updated table, as some databases require it to be listed
+// along with the sources.
+type UpdateFrom struct {
+	Table   Fragment
+	Sources Fragment
+	Joins   Fragment
+	hash    hash
+}
+
+var _ = Fragment(&UpdateFrom{})
+
+// Hash returns a unique identifier for the struct.
+func (u *UpdateFrom) Hash() string {
+	return u.hash.Hash(u)
+}
+
+// Compile transforms the UpdateFrom into its equivalent SQL representation.
+func (u *UpdateFrom) Compile(layout *Template) (compiled string, err error) {
+	if c, ok := layout.Read(u); ok {
+		return c, nil
+	}
+
+	if layout.UpdateFromLayout == "" {
+		return "", ErrUpdateFromUnsupported
+	}
+
+	compiled = layout.MustCompile(layout.UpdateFromLayout, u)
+
+	layout.Write(u, compiled)
+
+	return
+}
@@ -0,0 +1,74 @@
+package exql
+
+import (
+	"errors"
+)
+
+// ErrValuesTableUnsupported is returned when a list of rows is used as a table
+// on a template that has no ValuesTableLayout.
+var ErrValuesTableUnsupported = errors.New("VALUES lists can't be used as tables on this database")
+
+// ValuesTable represents a list of rows used as a table, like
+// (VALUES (1, 'a'), (2, 'b')) AS "t" ("id", "name").
+type ValuesTable struct {
+	Rows    *ValueGroups
+	Alias   Fragment
+	Columns *Columns
+	hash    hash
+}
+
+var _ = Fragment(&ValuesTable{})
+
+// Hash returns a unique identifier for the struct.
+func (v *ValuesTable) Hash() string {
+	return v.hash.Hash(v)
+}
+
+// Compile transforms the ValuesTable into its equivalent SQL representation.
+func (v *ValuesTable) Compile(layout *Template) (compiled string, err error) {
+	if c, ok := layout.Read(v); ok {
+		return c, nil
+	}
+
+	if layout.ValuesTableLayout == "" {
+		return "", ErrValuesTableUnsupported
+	}
+
+	compiled = layout.MustCompile(layout.ValuesTableLayout, v)
+
+	layout.Write(v, compiled)
+
+	return
+}
+
+// Cast represents a value converted to a column type, like CAST(? AS bigint).
+// Type is translated with the template's ColumnTypes.
+type Cast struct {
+	Value Fragment
+	Type  string
+	hash  hash
+}
+
+var _ = Fragment(&Cast{})
+
+// Hash returns a unique identifier for the struct.
+func (c *Cast) Hash() string {
+	return c.hash.Hash(c)
+}
+
+// Compile transforms the Cast into its equivalent SQL representation.
+func (c *Cast) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(c); ok {
+		return z, nil
+	}
+
+	value, err := c.Value.Compile(layout)
+	if err != nil {
+		return "", err
+	}
+	compiled = "CAST(" + value + " AS " + layout.ColumnType(c.Type) + ")"
+
+	layout.Write(c, compiled)
+
+	return
+}
@@ -68,7 +68,7 @@ func (o *Or) Compile(layout *Template) (compiled string, err error) {
 		return z, nil
 	}
 
-	compiled, err = groupCondition(layout, o.Conditions, layout.MustCompile(layout.ClauseOperator, layout.OrKeyword))
+	compiled, err = groupCondition(layout, o.Conditions, layout.MustCompile(layout.ClauseOperator, layout.OrKeyword), false)
 	if err != nil {
 		return "", err
 	}
@@ -84,7 +84,7 @@ func (a *And) Compile(layout *Template) (compiled string, err error) {
 		return c, nil
 	}
 
-	compiled, err = groupCondition(layout, a.Conditions, layout.MustCompile(layout.ClauseOperator, layout.AndKeyword))
+	compiled, err = groupCondition(layout, a.Conditions, layout.MustCompile(layout.ClauseOperator, layout.AndKeyword), true)
 	if err != nil {
 		return "", err
 	}
@@ -100,7 +100,7 @@ func (w *Where) Compile(layout *Template) (compiled string, err error) {
 		return c, nil
 	}
 
-	grouped, err := groupCondition(layout, w.Conditions, layout.MustCompile(layout.ClauseOperator, layout.AndKeyword))
+	grouped, err := groupCondition(layout, w.Conditions, layout.MustCompile(layout.ClauseOperator, layout.AndKeyword), true)
 	if err != nil {
 		return "", err
 	}
@@ -114,7 +114,18 @@ func (w *Where) Compile(layout *Template) (compiled string, err error) {
 	return
 }
 
-func groupCondition(layout *Template, terms []Fragment, joinKeyword string) (string, error) {
+// hasOrKeyword returns true if the given raw condition contains an OR
+// keyword.
+func hasOrKeyword(layout *Template, raw string) bool {
+	for _, word := range strings.Fields(raw) {
+		if strings.EqualFold(word, layout.OrKeyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func groupCondition(layout *Template, terms []Fragment, joinKeyword string, isAnd bool) (string, error) {
 	l := len(terms)
 
 	chunks := make([]string, 0, l)
@@ -125,6 +136,13 @@ func groupCondition(layout *Template, terms []Fragment, joinKeyword string) (str
 			if err != nil {
 				return "", err
 			}
+			// Raw conditions containing OR are grouped when they're joined with
+			// AND, otherwise AND would take precedence over their own OR.
+			if isAnd && l > 1 {
+				if raw, ok := terms[i].(*Raw); ok && hasOrKeyword(layout, raw.Value) {
+					chunk = layout.MustCompile(layout.ClauseGroup, chunk)
+				}
+			}
 			chunks = append(chunks, chunk)
 		}
 	}
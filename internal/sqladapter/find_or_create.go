@@ -0,0 +1,188 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/reflectx"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+var errFindOrCreateCond = errors.New("FindOrCreate only accepts column = value conditions, got %v")
+
+// FindOrCreate scans the row that matches cond into item, or inserts one
+// with the values of cond over the ones of item first.
+func (c *collection) FindOrCreate(cond db.Cond, item interface{}) (created bool, err error) {
+	if c.err != nil {
+		return false, c.err
+	}
+	if item == nil || reflect.TypeOf(item).Kind() != reflect.Ptr {
+		return false, fmt.Errorf("Expecting a pointer but got %T", item)
+	}
+	if len(cond) == 0 {
+		return false, db.ErrMissingConditions
+	}
+
+	values, err := findOrCreateValues(cond, item)
+	if err != nil {
+		return false, err
+	}
+
+	// Rows that already exist don't need a transaction.
+	err = c.Find(cond).One(item)
+	if err != db.ErrNoMoreRows {
+		return false, err
+	}
+
+	var tx DatabaseTx
+	inTx := false
+
+	if currTx := c.Database().Transaction(); currTx != nil {
+		tx = NewDatabaseTx(c.Database())
+		inTx = true
+	} else {
+		tx, err = c.Database().NewDatabaseTx(c.Database().Context())
+		if err != nil {
+			return false, err
+		}
+		defer tx.(Database).Close()
+	}
+
+	created, err = c.insertMissing(tx.(Database), cond, values, item)
+	if !inTx {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+	}
+
+	if err != nil && errorKind(err, []error{db.ErrUniqueViolation}) != nil {
+		// Another session inserted the row first, on a database that has no
+		// ON CONFLICT, like QL.
+		if c.Find(cond).One(item) == nil {
+			return false, nil
+		}
+	}
+	return created, err
+}
+
+// insertMissing inserts values into the table, unless a row that conflicts
+// with cond exists, and scans the row that matches cond into item.
+func (c *collection) insertMissing(sess Database, cond db.Cond, values map[string]interface{}, item interface{}) (bool, error) {
+	target := make([]string, 0, len(cond))
+	for column := range cond {
+		target = append(target, strings.TrimSpace(column.(string)))
+	}
+
+	res, err := sess.InsertInto(c.Name()).Values(values).OnConflict(target...).DoNothing().Exec()
+	if err == exql.ErrUpsertUnsupported {
+		res, err = sess.InsertInto(c.Name()).Values(values).Exec()
+	}
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	col := sess.Collection(c.Name())
+	if err := col.Find(cond).One(item); err != nil {
+		return false, err
+	}
+
+	created := affected > 0
+	if created {
+		sess.Emit(db.Event{Operation: db.OperationInsert, Table: c.Name(), Keys: c.itemKeys(item)})
+	}
+	return created, nil
+}
+
+// findOrCreateValues returns the values to insert for FindOrCreate: those of
+// cond over the ones of item.
+func findOrCreateValues(cond db.Cond, item interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	columns, vals, err := sqlbuilder.Map(item, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range columns {
+		values[columns[i]] = vals[i]
+	}
+
+	for key, value := range cond {
+		column, ok := key.(string)
+		if !ok || strings.ContainsAny(strings.TrimSpace(column), " \t\n") {
+			return nil, fmt.Errorf(errFindOrCreateCond.Error(), key)
+		}
+		if cmp, ok := value.(db.Comparison); ok {
+			if cmp.Operator() != db.ComparisonOperatorEqual {
+				return nil, fmt.Errorf(errFindOrCreateCond.Error(), column)
+			}
+			value = cmp.Value()
+		}
+		values[strings.TrimSpace(column)] = value
+	}
+
+	return values, nil
+}
+
+// itemKeys returns the primary keys of the row that was scanned into item, in
+// the form of db.Event.Keys, or nil if item doesn't have them all.
+func (c *collection) itemKeys(item interface{}) []interface{} {
+	if len(c.pk) == 0 {
+		return nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(item))
+	key := db.Cond{}
+	for _, pk := range c.pk {
+		var f reflect.Value
+		switch v.Kind() {
+		case reflect.Struct:
+			if fi, ok := mapper.TypeMap(v.Type()).Names[pk]; ok {
+				f = reflectx.ValidFieldByIndexes(v, fi.Index)
+			}
+		case reflect.Map:
+			if v.Type().Key().Kind() == reflect.String {
+				f = v.MapIndex(reflect.ValueOf(pk).Convert(v.Type().Key()))
+			}
+		}
+		if !f.IsValid() {
+			return nil
+		}
+		key[pk] = f.Interface()
+	}
+
+	if len(c.pk) == 1 {
+		return []interface{}{key[c.pk[0]]}
+	}
+	return []interface{}{key}
+}
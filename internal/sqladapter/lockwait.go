@@ -0,0 +1,77 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"context"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+)
+
+// lockDiagnosticsTimeout bounds the query that looks up lock holders, it runs
+// after the statement failed and shouldn't make things worse.
+const lockDiagnosticsTimeout = time.Second
+
+// lockWaitErrors are the errors lock holders are looked up for.
+var lockWaitErrors = []error{
+	db.ErrQueryTimeout,
+	db.ErrLockNotAcquired,
+}
+
+// hasLockHolders is implemented by adapters that can list the sessions that
+// hold locks on a table.
+type hasLockHolders interface {
+	LockHolders(ctx context.Context, table string) ([]db.LockHolder, error)
+}
+
+// withLockHolders wraps timeout and lock errors in a *db.LockWaitError that
+// lists the sessions holding locks on the statement's table, if lock
+// diagnostics are enabled. err is returned as it is if the holders can't be
+// looked up.
+func (d *database) withLockHolders(stmt *exql.Statement, err error) error {
+	if err == nil || !d.LockDiagnostics() {
+		return err
+	}
+	if errorKind(err, lockWaitErrors) == nil {
+		return err
+	}
+	diag, ok := d.PartialDatabase.(hasLockHolders)
+	if !ok {
+		return err
+	}
+	table := statementTable(stmt)
+	if table == "" {
+		return err
+	}
+
+	// The statement's context has likely expired already.
+	ctx, cancel := context.WithTimeout(context.Background(), lockDiagnosticsTimeout)
+	defer cancel()
+
+	holders, diagErr := diag.LockHolders(ctx, table)
+	if diagErr != nil || len(holders) == 0 {
+		return err
+	}
+	return &db.LockWaitError{Table: table, Holders: holders, Err: err}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"strings"
+
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+)
+
+var statementOps = map[exql.Type]string{
+	exql.Truncate:     "truncate",
+	exql.DropTable:    "drop_table",
+	exql.DropDatabase: "drop_database",
+	exql.Count:        "count",
+	exql.Insert:       "insert",
+	exql.Select:       "select",
+	exql.Update:       "update",
+	exql.Delete:       "delete",
+	exql.SQL:          "raw",
+	exql.CreateTable:  "create_table",
+	exql.AlterTable:   "alter_table",
+}
+
+// statementOp returns the name of the kind of statement, as reported to
+// db.Metrics.
+func statementOp(stmt *exql.Statement) string {
+	if op, ok := statementOps[stmt.Type]; ok {
+		return op
+	}
+	return "unknown"
+}
+
+// statementTable returns the name of the main table of the statement, which
+// is the first one for statements that read from several tables, or an empty
+// string if it can't be inferred.
+func statementTable(stmt *exql.Statement) string {
+	var name interface{}
+	switch t := stmt.Table.(type) {
+	case *exql.Table:
+		name = t.Name
+	case *exql.Columns:
+		if len(t.Columns) > 0 {
+			if c, ok := t.Columns[0].(*exql.Column); ok {
+				name = c.Name
+			}
+		}
+	}
+	s, ok := name.(string)
+	if !ok {
+		return ""
+	}
+	// Remove the alias, if any.
+	if fields := strings.Fields(s); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
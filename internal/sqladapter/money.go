@@ -0,0 +1,165 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is a MONEY value as an amount in minor units (cents), scanned from
+// the server's locale-formatted text, see ParseMoney, without going through
+// floating point. It's written as a plain decimal number. Adapters expose it
+// as their own Money type.
+type Money int64
+
+// Value satisfies the driver.Valuer interface.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan satisfies the sql.Scanner interface. NULL is scanned as zero.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.parse(string(v))
+	case string:
+		return m.parse(v)
+	case int64:
+		*m = Money(v * 100)
+	case float64:
+		*m = Money(math.Round(v * 100))
+	default:
+		return fmt.Errorf("upper: can't scan %T into Money", src)
+	}
+	return nil
+}
+
+func (m *Money) parse(s string) error {
+	cents, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = Money(cents)
+	return nil
+}
+
+// String returns the amount as a decimal number, like "-1234.56".
+func (m Money) String() string {
+	return FormatMoney(int64(m))
+}
+
+var (
+	_ driver.Valuer = Money(0)
+)
+
+// ParseMoney parses a monetary amount as formatted by the database server
+// and returns it in minor units (cents). Currency symbols, spaces and
+// thousands separators are ignored, either "." or "," may be the decimal
+// separator and negative amounts may be written with a sign or between
+// parentheses. A single separator followed by exactly three digits is taken
+// as a thousands separator. Amounts with more than two decimals are rounded.
+func ParseMoney(s string) (int64, error) {
+	var (
+		digits   []byte
+		seps     []int
+		negative bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == '.' || c == ',':
+			if len(digits) > 0 {
+				seps = append(seps, len(digits))
+				digits = append(digits, c)
+			}
+		case c == '-' || c == '(':
+			negative = true
+		}
+	}
+	if len(digits) == 0 {
+		return 0, fmt.Errorf("upper: can't parse %q as a monetary amount", s)
+	}
+
+	// Find out whether the last separator is the decimal one.
+	point := -1
+	if n := len(seps); n > 0 {
+		last := seps[n-1]
+		decimals := len(digits) - last - 1
+		switch {
+		case n > 1 && digits[seps[n-2]] != digits[last]:
+			point = last
+		case n > 1:
+			// Repeated separator: thousands.
+		case decimals != 3 && decimals > 0:
+			point = last
+		}
+	}
+
+	var whole, frac string
+	if point >= 0 {
+		whole, frac = string(digits[:point]), string(digits[point+1:])
+	} else {
+		whole = string(digits)
+	}
+	whole = strings.NewReplacer(".", "", ",", "").Replace(whole)
+	if whole == "" {
+		whole = "0"
+	}
+
+	cents, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || cents > math.MaxInt64/100 {
+		return 0, fmt.Errorf("upper: can't parse %q as a monetary amount", s)
+	}
+	cents *= 100
+
+	frac += "00"
+	c, _ := strconv.ParseInt(frac[:2], 10, 64)
+	cents += c
+	if len(frac) > 2 && frac[2] >= '5' {
+		cents++
+	}
+
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// FormatMoney formats an amount in minor units (cents) as a plain decimal
+// number, like "-1234.56".
+func FormatMoney(cents int64) string {
+	sign := ""
+	u := uint64(cents)
+	if cents < 0 {
+		sign = "-"
+		u = uint64(-cents)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, u/100, u%100)
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// outboxBatchSize is the number of messages DrainOutbox locks and handles in
+// each transaction.
+const outboxBatchSize = 100
+
+// Outbox writes event to the outbox table within the session's transaction.
+func (d *database) Outbox(event interface{}) error {
+	if d.Transaction() == nil {
+		return db.ErrNotWithinTransaction
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.InsertInto(d.OutboxTable()).
+		Values(map[string]interface{}{
+			"type":       outboxType(event),
+			"payload":    string(payload),
+			"created_at": time.Now(),
+		}).
+		Exec()
+	return err
+}
+
+// DrainOutbox passes the unpublished messages of the outbox table to handler
+// and marks them as published, a batch at a time.
+func (d *database) DrainOutbox(ctx context.Context, handler func(sqlbuilder.OutboxMessage) error) error {
+	if d.Transaction() != nil {
+		return db.ErrAlreadyWithinTransaction
+	}
+	for {
+		n, err := d.drainOutboxBatch(ctx, handler)
+		if err != nil {
+			return err
+		}
+		if n < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// drainOutboxBatch handles a batch of messages within a transaction and
+// returns the number of messages it read.
+func (d *database) drainOutboxBatch(ctx context.Context, handler func(sqlbuilder.OutboxMessage) error) (int, error) {
+	tx, err := d.NewDatabaseTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.(Database).Close()
+
+	table := d.OutboxTable()
+
+	var messages []sqlbuilder.OutboxMessage
+	err = tx.Select("id", "type", "payload", "created_at").
+		From(table).
+		Where("published_at IS NULL").
+		OrderBy("id").
+		Limit(outboxBatchSize).
+		SkipLocked().
+		All(&messages)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	var handlerErr error
+	published := make([]int64, 0, len(messages))
+	for _, message := range messages {
+		if handlerErr = handler(message); handlerErr != nil {
+			break
+		}
+		published = append(published, message.ID)
+	}
+
+	if len(published) > 0 {
+		_, err = tx.Update(table).
+			Set("published_at", time.Now()).
+			Where(db.Cond{"id": published}).
+			Exec()
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(messages), handlerErr
+}
+
+// outboxType returns the name of the Go type of event, like "orders.Placed".
+func outboxType(event interface{}) string {
+	t := reflect.TypeOf(event)
+	if t == nil {
+		return ""
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
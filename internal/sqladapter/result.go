@@ -60,12 +60,25 @@ type result struct {
 	orderBy []interface{}
 	groupBy []interface{}
 	conds   [][]interface{}
+	ors     []bool // ors[i] is true if conds[i] is joined with OR.
 }
 
 func filter(conds []interface{}) []interface{} {
 	return conds
 }
 
+// applyConds adds the conditions of the result to a query, calling and or or
+// with each group of conditions in the order they were given.
+func (res *result) applyConds(and, or func(conds ...interface{})) {
+	for i := range res.conds {
+		if res.ors[i] {
+			or(filter(res.conds[i])...)
+		} else {
+			and(filter(res.conds[i])...)
+		}
+	}
+}
+
 // NewResult creates and Results a new Result set on the given table, this set
 // is limited by the given exql.Where conditions.
 func NewResult(builder sqlbuilder.SQLBuilder, table string, conds []interface{}) *Result {
@@ -96,6 +109,7 @@ func (r *Result) from(table string) *Result {
 func (r *Result) where(conds []interface{}) *Result {
 	return r.frame(func(res *result) error {
 		res.conds = [][]interface{}{conds}
+		res.ors = []bool{false}
 		return nil
 	})
 }
@@ -126,6 +140,17 @@ func (r *Result) Where(conds ...interface{}) db.Result {
 func (r *Result) And(conds ...interface{}) db.Result {
 	return r.frame(func(res *result) error {
 		res.conds = append(res.conds, conds)
+		res.ors = append(res.ors, false)
+		return nil
+	})
+}
+
+// Or widens the result set with conditions that are joined with OR to the
+// existing ones.
+func (r *Result) Or(conds ...interface{}) db.Result {
+	return r.frame(func(res *result) error {
+		res.conds = append(res.conds, conds)
+		res.ors = append(res.ors, true)
 		return nil
 	})
 }
@@ -239,6 +264,10 @@ func (r *Result) One(dst interface{}) error {
 		return r.setErr(err)
 	}
 	err = query.Iterator().One(dst)
+	if err == db.ErrNoMoreRows {
+		// An empty result is not a failure of the result set, keep it usable.
+		return err
+	}
 	return r.setErr(err)
 }
 
@@ -269,13 +298,28 @@ func (r *Result) Next(dst interface{}) bool {
 
 // Delete deletes all matching items from the collection.
 func (r *Result) Delete() error {
-	query, err := r.buildDelete()
+	query, err := r.buildDelete(0)
 	if err != nil {
 		return r.setErr(err)
 	}
 
-	_, err = query.Exec()
-	return r.setErr(err)
+	_, err = r.delete(query)
+	return err
+}
+
+// DeleteLimit deletes at most n matching items from the collection.
+func (r *Result) DeleteLimit(n int) (int64, error) {
+	query, err := r.buildDelete(n)
+	if err != nil {
+		return 0, r.setErr(err)
+	}
+	return r.delete(query)
+}
+
+func (r *Result) delete(query sqlbuilder.Deleter) (int64, error) {
+	return r.changeRows(db.OperationDelete, query.Exec, func(pKey []string) sqlbuilder.Iterator {
+		return query.Returning(pKey...).Iterator()
+	})
 }
 
 // Close closes the Result set.
@@ -289,13 +333,46 @@ func (r *Result) Close() error {
 // Update updates matching items from the collection with values of the given
 // map or struct.
 func (r *Result) Update(values interface{}) error {
-	query, err := r.buildUpdate(values)
+	query, err := r.buildUpdate(values, 0)
 	if err != nil {
 		return r.setErr(err)
 	}
 
-	_, err = query.Exec()
-	return r.setErr(err)
+	_, err = r.update(query)
+	return err
+}
+
+// UpdateLimit updates at most n matching items from the collection.
+func (r *Result) UpdateLimit(values interface{}, n int) (int64, error) {
+	query, err := r.buildUpdate(values, n)
+	if err != nil {
+		return 0, r.setErr(err)
+	}
+	return r.update(query)
+}
+
+func (r *Result) update(query sqlbuilder.Updater) (int64, error) {
+	return r.changeRows(db.OperationUpdate, query.Exec, func(pKey []string) sqlbuilder.Iterator {
+		return query.Returning(pKey...).Iterator()
+	})
+}
+
+// Increment atomically adds delta to the given column.
+func (r *Result) Increment(column string, delta interface{}) (int64, error) {
+	return r.updateAffected(map[string]interface{}{column: db.Col(column).Add(delta)})
+}
+
+// Decrement atomically subtracts delta from the given column.
+func (r *Result) Decrement(column string, delta interface{}) (int64, error) {
+	return r.updateAffected(map[string]interface{}{column: db.Col(column).Sub(delta)})
+}
+
+func (r *Result) updateAffected(values interface{}) (int64, error) {
+	query, err := r.buildUpdate(values, 0)
+	if err != nil {
+		return 0, r.setErr(err)
+	}
+	return r.update(query)
 }
 
 func (r *Result) TotalPages() (uint, error) {
@@ -326,15 +403,15 @@ func (r *Result) TotalEntries() (uint64, error) {
 	return total, nil
 }
 
-// Exists returns true if at least one item on the collection exists.
+// Exists returns true if at least one item on the collection exists. Instead
+// of counting all matching rows it asks for a single one, so the database can
+// stop scanning as soon as it finds a match.
 func (r *Result) Exists() (bool, error) {
-	query, err := r.buildCount()
+	query, err := r.buildExists()
 	if err != nil {
 		return false, r.setErr(err)
 	}
 
-	query = query.Limit(1)
-
 	value := struct {
 		Exists uint64 `db:"_t"`
 	}{}
@@ -346,11 +423,7 @@ func (r *Result) Exists() (bool, error) {
 		return false, r.setErr(err)
 	}
 
-	if value.Exists > 0 {
-		return true, nil
-	}
-
-	return false, nil
+	return true, nil
 }
 
 // Count counts the elements on the set.
@@ -390,9 +463,10 @@ func (r *Result) buildPaginator() (sqlbuilder.Paginator, error) {
 		GroupBy(res.groupBy...).
 		OrderBy(res.orderBy...)
 
-	for i := range res.conds {
-		sel = sel.And(filter(res.conds[i])...)
-	}
+	res.applyConds(
+		func(conds ...interface{}) { sel = sel.And(conds...) },
+		func(conds ...interface{}) { sel = sel.Or(conds...) },
+	)
 
 	pag := sel.Paginate(res.pageSize).
 		Page(res.pageNumber).
@@ -409,7 +483,7 @@ func (r *Result) buildPaginator() (sqlbuilder.Paginator, error) {
 	return pag, nil
 }
 
-func (r *Result) buildDelete() (sqlbuilder.Deleter, error) {
+func (r *Result) buildDelete(limit int) (sqlbuilder.Deleter, error) {
 	if err := r.Err(); err != nil {
 		return nil, err
 	}
@@ -420,16 +494,17 @@ func (r *Result) buildDelete() (sqlbuilder.Deleter, error) {
 	}
 
 	del := r.SQLBuilder().DeleteFrom(res.table).
-		Limit(res.limit)
+		Limit(limit)
 
-	for i := range res.conds {
-		del = del.And(filter(res.conds[i])...)
-	}
+	res.applyConds(
+		func(conds ...interface{}) { del = del.And(conds...) },
+		func(conds ...interface{}) { del = del.Or(conds...) },
+	)
 
 	return del, nil
 }
 
-func (r *Result) buildUpdate(values interface{}) (sqlbuilder.Updater, error) {
+func (r *Result) buildUpdate(values interface{}, limit int) (sqlbuilder.Updater, error) {
 	if err := r.Err(); err != nil {
 		return nil, err
 	}
@@ -441,15 +516,39 @@ func (r *Result) buildUpdate(values interface{}) (sqlbuilder.Updater, error) {
 
 	upd := r.SQLBuilder().Update(res.table).
 		Set(values).
-		Limit(res.limit)
+		Limit(limit)
 
-	for i := range res.conds {
-		upd = upd.And(filter(res.conds[i])...)
-	}
+	res.applyConds(
+		func(conds ...interface{}) { upd = upd.And(conds...) },
+		func(conds ...interface{}) { upd = upd.Or(conds...) },
+	)
 
 	return upd, nil
 }
 
+func (r *Result) buildExists() (sqlbuilder.Selector, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	res, err := r.fastForward()
+	if err != nil {
+		return nil, err
+	}
+
+	sel := r.SQLBuilder().Select(db.Raw("1 AS _t")).
+		From(res.table).
+		GroupBy(res.groupBy...).
+		Limit(1)
+
+	res.applyConds(
+		func(conds ...interface{}) { sel = sel.And(conds...) },
+		func(conds ...interface{}) { sel = sel.Or(conds...) },
+	)
+
+	return sel, nil
+}
+
 func (r *Result) buildCount() (sqlbuilder.Selector, error) {
 	if err := r.Err(); err != nil {
 		return nil, err
@@ -464,9 +563,10 @@ func (r *Result) buildCount() (sqlbuilder.Selector, error) {
 		From(res.table).
 		GroupBy(res.groupBy...)
 
-	for i := range res.conds {
-		sel = sel.And(filter(res.conds[i])...)
-	}
+	res.applyConds(
+		func(conds ...interface{}) { sel = sel.And(conds...) },
+		func(conds ...interface{}) { sel = sel.Or(conds...) },
+	)
 
 	return sel, nil
 }
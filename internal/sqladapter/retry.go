@@ -0,0 +1,71 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"database/sql/driver"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+)
+
+// badConnMessages are fragments of error messages that drivers return when
+// the connection to the server broke without reporting driver.ErrBadConn.
+var badConnMessages = []string{
+	"broken pipe",
+	"connection reset by peer",
+	"use of closed network connection",
+}
+
+// isBadConnErr returns true if err, or an error it wraps, means that the
+// connection the statement was sent on is no longer usable.
+func isBadConnErr(err error) bool {
+	for ; err != nil; err = unwrap(err) {
+		switch err {
+		case driver.ErrBadConn, io.EOF, io.ErrUnexpectedEOF:
+			return true
+		}
+		if netErr, ok := err.(*net.OpError); ok {
+			return !netErr.Timeout()
+		}
+		msg := err.Error()
+		for _, s := range badConnMessages {
+			if strings.Contains(msg, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRetryable returns true if stmt can be sent again to the server without
+// side effects: reads and statements that are idempotent. Inserts, updates,
+// deletes and raw SQL are never retried.
+func isRetryable(stmt *exql.Statement) bool {
+	switch stmt.Type {
+	case exql.Select, exql.Count, exql.Truncate:
+		return true
+	}
+	return false
+}
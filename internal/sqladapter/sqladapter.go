@@ -23,7 +23,9 @@
 package sqladapter
 
 import (
+	"crypto/rand"
 	"database/sql/driver"
+	"encoding/hex"
 )
 
 // IsKeyValue reports whether v is a valid value for a primary key that can be
@@ -42,3 +44,13 @@ func IsKeyValue(v interface{}) bool {
 	}
 	return false
 }
+
+// TempTableName appends a random suffix to the given name, so temporary
+// tables created by concurrent transactions don't collide.
+func TempTableName(name string) string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		panic(err.Error())
+	}
+	return name + "_" + hex.EncodeToString(suffix)
+}
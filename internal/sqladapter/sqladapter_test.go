@@ -1,8 +1,23 @@
 package sqladapter
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/cache"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,3 +52,889 @@ func TestReplaceWithDollarSign(t *testing.T) {
 		assert.Equal(t, test.out, ReplaceWithDollarSign(test.in))
 	}
 }
+
+func TestIsBadConnErr(t *testing.T) {
+	tests := []struct {
+		err error
+		out bool
+	}{
+		{nil, false},
+		{driver.ErrBadConn, true},
+		{io.EOF, true},
+		{io.ErrUnexpectedEOF, true},
+		{&net.OpError{Op: "write", Err: errors.New("write: broken pipe")}, true},
+		{errors.New("read tcp 127.0.0.1:5432: read: connection reset by peer"), true},
+		{errors.New(`pq: relation "foo" does not exist`), false},
+		{&db.Error{Kind: db.ErrQueryTimeout, Err: driver.ErrBadConn}, true},
+		{&db.Error{Kind: db.ErrQueryTimeout, Err: io.EOF}, true},
+		{&db.Error{Kind: db.ErrQueryTimeout, Err: &net.OpError{Op: "read", Err: errors.New("read: EOF")}}, true},
+		{&db.Error{Kind: db.ErrUniqueViolation, Err: errors.New("duplicate key")}, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, isBadConnErr(test.err))
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, isRetryable(&exql.Statement{Type: exql.Select}))
+	assert.True(t, isRetryable(&exql.Statement{Type: exql.Count}))
+	assert.False(t, isRetryable(&exql.Statement{Type: exql.Insert}))
+	assert.False(t, isRetryable(&exql.Statement{Type: exql.Update}))
+	assert.False(t, isRetryable(&exql.Statement{Type: exql.Delete}))
+	assert.False(t, isRetryable(&exql.Statement{Type: exql.SQL}))
+}
+
+func TestSplitTableName(t *testing.T) {
+	tests := []struct {
+		in     string
+		schema string
+		table  string
+	}{
+		{"users", "", "users"},
+		{"public.users", "public", "users"},
+		{"db.dbo.users", "db.dbo", "users"},
+	}
+
+	for _, test := range tests {
+		schema, table := SplitTableName(test.in)
+		assert.Equal(t, test.schema, schema)
+		assert.Equal(t, test.table, table)
+	}
+}
+
+func TestTempTableName(t *testing.T) {
+	a, b := TempTableName("staging"), TempTableName("staging")
+	assert.True(t, strings.HasPrefix(a, "staging_"))
+	assert.Equal(t, len("staging_")+8, len(a))
+	assert.NotEqual(t, a, b)
+}
+
+func TestWithQueryTimeout(t *testing.T) {
+	d := &database{Settings: db.NewSettings()}
+
+	// No timeout by default.
+	ctx, cancel := d.withQueryTimeout(context.Background())
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	cancel()
+
+	d.SetDefaultQueryTimeout(time.Minute)
+
+	// Unbounded contexts are capped.
+	ctx, cancel = d.withQueryTimeout(context.Background())
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.True(t, deadline.Before(time.Now().Add(time.Minute+time.Second)))
+	cancel()
+	assert.Equal(t, context.Canceled, ctx.Err())
+
+	// Tighter deadlines win.
+	parent, parentCancel := context.WithTimeout(context.Background(), time.Second)
+	defer parentCancel()
+	parentDeadline, _ := parent.Deadline()
+
+	ctx, cancel = d.withQueryTimeout(parent)
+	defer cancel()
+	deadline, _ = ctx.Deadline()
+	assert.Equal(t, parentDeadline, deadline)
+}
+
+func TestQueryTimeoutReleased(t *testing.T) {
+	sess, err := sql.Open("sqladapter-stub", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sess.Close()
+
+	d := NewBaseDatabase(convertValuesStub{}).(*database)
+	d.sess = sess
+	d.SetDefaultQueryTimeout(time.Minute)
+
+	released := func(ctx context.Context) bool {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}
+
+	// The timeout of a query is released once its rows are closed.
+	rows, err := d.StatementQuery(context.Background(), exql.RawSQL(`SELECT 1`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx := <-stubQueries
+	assert.True(t, rows.Next())
+	assert.False(t, released(ctx))
+	assert.NoError(t, rows.Close())
+	assert.True(t, released(ctx))
+
+	// And the one of a row once it's scanned.
+	row, err := d.StatementQueryRow(context.Background(), exql.RawSQL(`SELECT 1`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx = <-stubQueries
+	assert.False(t, released(ctx))
+	var n int
+	assert.NoError(t, row.Scan(&n))
+	assert.Equal(t, 1, n)
+	assert.True(t, released(ctx))
+}
+
+func TestStatementTable(t *testing.T) {
+	tests := []struct {
+		stmt  *exql.Statement
+		op    string
+		table string
+	}{
+		{&exql.Statement{Type: exql.Insert, Table: exql.TableWithName("artist")}, "insert", "artist"},
+		{&exql.Statement{Type: exql.Select, Table: exql.JoinColumns(exql.ColumnWithName("artist AS a"), exql.ColumnWithName("publication"))}, "select", "artist"},
+		{&exql.Statement{Type: exql.Select, Table: exql.JoinColumns(exql.RawValue("(SELECT 1)"))}, "select", ""},
+		{exql.RawSQL("SELECT 1"), "raw", ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.op, statementOp(test.stmt))
+		assert.Equal(t, test.table, statementTable(test.stmt))
+	}
+}
+
+func TestSQLComment(t *testing.T) {
+	assert.Equal(t, "", sqlComment(nil))
+	assert.Equal(t,
+		`/*action='checkout',route='%2Fcart%2F%7Bid%7D',traceparent='00-abc-01'*/`,
+		sqlComment(map[string]string{"traceparent": "00-abc-01", "action": "checkout", "route": "/cart/{id}"}),
+	)
+	assert.Equal(t,
+		`/*app%20name='it%27s%20%2A%2F%20here'*/`,
+		sqlComment(map[string]string{"app name": "it's */ here"}),
+	)
+
+	d := &database{Settings: db.NewSettings()}
+
+	ctx := context.Background()
+	assert.Equal(t, "SELECT $1", d.withSQLComment(ctx, "SELECT $1"))
+
+	ctx = db.WithSQLCommentTags(ctx, map[string]string{"controller": "cart", "action": "view"})
+	assert.Equal(t, "SELECT $1 /*action='view',controller='cart'*/", d.withSQLComment(ctx, "SELECT $1"))
+
+	d.SetSQLCommenter(func(ctx context.Context) map[string]string {
+		return map[string]string{"action": "checkout", "traceparent": "00-abc-01"}
+	})
+	assert.Equal(t, "SELECT $1 /*action='checkout',controller='cart',traceparent='00-abc-01'*/", d.withSQLComment(ctx, "SELECT $1"))
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in    string
+		cents int64
+	}{
+		{"$0.00", 0},
+		{"$1,234.56", 123456},
+		{"-$1,234.56", -123456},
+		{"($1,234.56)", -123456},
+		{"$1,234,567.8", 123456780},
+		{"1.234,56 €", 123456},
+		{"-1.234.567,89 €", -123456789},
+		{"1 234,56 €", 123456},
+		{"Fr. 1'234.50", 123450},
+		{"¥1,235", 123500},
+		{"1234.5600", 123456},
+		{"-0.0050", -1},
+		{"12", 1200},
+	}
+
+	for _, test := range tests {
+		cents, err := ParseMoney(test.in)
+		if assert.NoError(t, err, test.in) {
+			assert.Equal(t, test.cents, cents, test.in)
+		}
+	}
+
+	_, err := ParseMoney("$")
+	assert.Error(t, err)
+
+	assert.Equal(t, "0.00", FormatMoney(0))
+	assert.Equal(t, "-1234.56", FormatMoney(-123456))
+	assert.Equal(t, "0.05", FormatMoney(5))
+	assert.Equal(t, "-0.01", FormatMoney(-1))
+}
+
+func TestColumnStreamerUnsupported(t *testing.T) {
+	res := NewResult(sqlbuilder.WithTemplate(&exql.Template{}), "documents", nil)
+
+	_, err := res.StreamColumn(context.Background(), "data")
+	assert.Equal(t, db.ErrUnsupported, err)
+
+	err = res.WriteColumn(context.Background(), "data", strings.NewReader("x"))
+	assert.Equal(t, db.ErrUnsupported, err)
+}
+
+func TestKeysAfter(t *testing.T) {
+	assert.Equal(t,
+		db.Cond{"id >": 7},
+		keysAfter([]string{"id"}, db.Cond{"id": 7}),
+	)
+
+	after := keysAfter([]string{"a", "b", "c"}, db.Cond{"a": 1, "b": 2, "c": 3})
+	if assert.IsType(t, &db.Union{}, after) {
+		sentences := after.Sentences()
+		assert.Equal(t, 3, len(sentences))
+		assert.Equal(t, db.Cond{"a >": 1}, sentences[0])
+	}
+}
+
+func TestItemKey(t *testing.T) {
+	type reading struct {
+		SensorID int64   `db:"sensor_id"`
+		Seq      int64   `db:"seq"`
+		Amount   float64 `db:"amount"`
+	}
+	pks := []string{"sensor_id", "seq"}
+
+	key, err := itemKey(pks, reflect.ValueOf(&reading{SensorID: 2, Seq: 5}))
+	assert.NoError(t, err)
+	assert.Equal(t, db.Cond{"sensor_id": int64(2), "seq": int64(5)}, key)
+
+	key, err = itemKey(pks, reflect.ValueOf(map[string]interface{}{"sensor_id": int64(3), "seq": []byte("7")}))
+	assert.NoError(t, err)
+	assert.Equal(t, db.Cond{"sensor_id": int64(3), "seq": "7"}, key)
+
+	_, err = itemKey(pks, reflect.ValueOf(struct {
+		Amount float64 `db:"amount"`
+	}{}))
+	assert.Error(t, err)
+
+	assert.Equal(t, []interface{}{"seq", "amount", "sensor_id"}, withColumns([]interface{}{"seq", "amount"}, pks))
+}
+
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) {
+	return stubConn{}, nil
+}
+
+type stubConn struct{}
+
+func (stubConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (stubConn) Close() error {
+	return nil
+}
+
+func (stubConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+// stubQueries receives the context of each query sent to a stubConn.
+var stubQueries = make(chan context.Context, 1)
+
+func (stubConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	select {
+	case stubQueries <- ctx:
+	default:
+	}
+	return &stubRows{}, nil
+}
+
+// stubRows has a single row with a single column.
+type stubRows struct {
+	read bool
+}
+
+func (*stubRows) Columns() []string {
+	return []string{"n"}
+}
+
+func (*stubRows) Close() error {
+	return nil
+}
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func init() {
+	sql.Register("sqladapter-stub", stubDriver{})
+}
+
+type lockHoldersStub struct {
+	PartialDatabase
+	holders []db.LockHolder
+}
+
+func (l *lockHoldersStub) LockHolders(ctx context.Context, table string) ([]db.LockHolder, error) {
+	return l.holders, nil
+}
+
+func TestWithLockHolders(t *testing.T) {
+	holders := []db.LockHolder{{PID: 42, Query: "UPDATE artist SET name = 'x'"}}
+	d := &database{
+		Settings:        db.NewSettings(),
+		PartialDatabase: &lockHoldersStub{holders: holders},
+	}
+
+	stmt := &exql.Statement{Type: exql.Update, Table: exql.TableWithName("artist")}
+	timeout := &db.Error{Kind: db.ErrQueryTimeout, Err: errors.New("canceling statement due to statement timeout")}
+
+	// Disabled by default.
+	assert.Equal(t, timeout, d.withLockHolders(stmt, timeout))
+
+	d.SetLockDiagnostics(true)
+
+	err := d.withLockHolders(stmt, timeout)
+	if assert.IsType(t, &db.LockWaitError{}, err) {
+		lockErr := err.(*db.LockWaitError)
+		assert.Equal(t, "artist", lockErr.Table)
+		assert.Equal(t, holders, lockErr.Holders)
+		assert.Equal(t, timeout, lockErr.Unwrap())
+		assert.Equal(t, `canceling statement due to statement timeout (locks on artist held by 42 "UPDATE artist SET name = 'x'")`, lockErr.Error())
+	}
+
+	// Other errors are left alone.
+	other := errors.New("syntax error")
+	assert.Equal(t, other, d.withLockHolders(stmt, other))
+}
+
+func TestContextErr(t *testing.T) {
+	driverErr := errors.New("pq: canceling statement due to user request")
+
+	ctx := context.Background()
+	assert.Nil(t, contextErr(ctx, nil))
+	assert.Equal(t, driverErr, contextErr(ctx, driverErr))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err := contextErr(canceled, driverErr)
+	if assert.IsType(t, &db.Error{}, err) {
+		assert.True(t, err.(*db.Error).Is(context.Canceled))
+		assert.Equal(t, driverErr, err.(*db.Error).Unwrap())
+	}
+	assert.Equal(t, context.Canceled, contextErr(canceled, context.Canceled))
+
+	expired, cancel := context.WithTimeout(ctx, -time.Second)
+	defer cancel()
+	err = contextErr(expired, driverErr)
+	if assert.IsType(t, &db.Error{}, err) {
+		assert.True(t, err.(*db.Error).Is(db.ErrQueryTimeout))
+		assert.False(t, err.(*db.Error).Is(context.Canceled))
+	}
+
+	// Errors that already match are not wrapped again.
+	assert.Equal(t, err, contextErr(expired, err))
+}
+
+func TestErrorKind(t *testing.T) {
+	kinds := []error{db.ErrStaleObject, context.Canceled}
+
+	assert.Nil(t, errorKind(nil, kinds))
+	assert.Nil(t, errorKind(errors.New("other"), kinds))
+	assert.Equal(t, context.Canceled, errorKind(context.Canceled, kinds))
+	assert.Equal(t, db.ErrStaleObject, errorKind(&db.Error{Kind: db.ErrStaleObject, Err: errors.New("deadlock")}, kinds))
+
+	wrapped := &sqlbuilder.CloseTimeoutError{Err: &db.Error{Kind: context.Canceled, Err: errors.New("canceled")}}
+	assert.Equal(t, context.Canceled, errorKind(wrapped, kinds))
+}
+
+func TestAcquireConn(t *testing.T) {
+	sess, err := sql.Open("sqladapter-stub", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sess.Close()
+	sess.SetMaxOpenConns(1)
+
+	d := &database{Settings: db.NewSettings(), sess: sess}
+	d.SetAcquireTimeout(20 * time.Millisecond)
+
+	ctx := context.Background()
+
+	held, err := d.acquireConn(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The pool is exhausted.
+	_, err = d.acquireConn(ctx)
+	assert.Equal(t, db.ErrTooManyClients, err)
+
+	// The statement's own deadline is reported as such.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = d.acquireConn(canceled)
+	assert.Equal(t, context.Canceled, err)
+
+	assert.NoError(t, held.Close())
+
+	conn, err := d.acquireConn(ctx)
+	if assert.NoError(t, err) {
+		assert.NoError(t, conn.Close())
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	sess, err := sql.Open("sqladapter-stub", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sess.Close()
+
+	d := &database{Settings: db.NewSettings(), sess: sess}
+	d.SetMaxIdleConns(3)
+
+	assert.NoError(t, d.Warmup(context.Background(), 5))
+	assert.Equal(t, 3, sess.Stats().OpenConnections)
+
+	d.SetMaxOpenConns(2)
+	assert.NoError(t, d.Warmup(context.Background(), 5))
+	assert.Equal(t, 2, sess.Stats().OpenConnections)
+
+	assert.Equal(t, db.ErrNotConnected, (&database{Settings: db.NewSettings()}).Warmup(context.Background(), 1))
+}
+
+func TestSessionTemplate(t *testing.T) {
+	layout := &exql.Template{IdentifierQuote: `"{{.Value}}"`, Cache: cache.NewCache()}
+
+	settings := db.NewSettings()
+	assert.True(t, settings.QuoteAllIdentifiers())
+	assert.True(t, layout == SessionTemplate(settings, layout))
+	assert.True(t, layout == SessionTemplate(nil, layout))
+
+	settings.SetQuoteAllIdentifiers(false)
+	settings.SetReservedWords("order")
+	assert.Equal(t, []string{"order"}, settings.ReservedWords())
+
+	minimal := SessionTemplate(settings, layout)
+	assert.False(t, layout == minimal)
+	assert.True(t, minimal == SessionTemplate(settings, layout))
+	assert.Equal(t, `"order"`, minimal.QuoteIdentifier("order"))
+	assert.Equal(t, `name`, minimal.QuoteIdentifier("name"))
+	assert.Equal(t, `"name"`, layout.QuoteIdentifier("name"))
+}
+
+func TestConcurrentSessionState(t *testing.T) {
+	sess, err := sql.Open("sqladapter-stub", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	d := NewBaseDatabase(nil).(*database)
+	d.sess = sess
+
+	layout := &exql.Template{IdentifierQuote: `"{{.Value}}"`, ColumnSeparator: `.`, Cache: cache.NewCache()}
+	d.SetQuoteAllIdentifiers(false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = d.Driver()
+				_ = d.Ping()
+				_ = d.Transaction()
+
+				d.SetReservedWords("order")
+				col := exql.ColumnWithName(fmt.Sprintf("order.c%d", j%5))
+				compiled, err := col.Compile(SessionTemplate(d, layout))
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf(`"order".c%d`, j%5), compiled)
+			}
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, d.Close())
+	}()
+	wg.Wait()
+
+	assert.Nil(t, d.Session())
+}
+
+func TestTxClosedErr(t *testing.T) {
+	d := &database{Settings: db.NewSettings()}
+	assert.NoError(t, d.txClosedErr())
+
+	tx := &baseTx{ctx: context.Background()}
+	d.baseTx = tx
+	d.txBound = 1
+	assert.NoError(t, d.txClosedErr())
+
+	tx.done.Store(struct{}{})
+	assert.Equal(t, db.ErrTransactionClosed, d.txClosedErr())
+	_, err := d.acquire()
+	assert.Equal(t, db.ErrTransactionClosed, err)
+
+	// Sessions are closed after Commit or Rollback.
+	d.baseTx = nil
+	assert.Equal(t, db.ErrTransactionClosed, d.txClosedErr())
+
+	// Expired transactions are reported by database/sql.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d.baseTx = &baseTx{ctx: ctx}
+	assert.NoError(t, d.txClosedErr())
+}
+
+type convertValuesStub struct {
+	PartialDatabase
+}
+
+func (convertValuesStub) ConvertValues(values []interface{}) []interface{} {
+	for i := range values {
+		if v, ok := values[i].([]string); ok {
+			values[i] = strings.Join(v, ",")
+		}
+	}
+	return values
+}
+
+func TestConvertValuesVerbatim(t *testing.T) {
+	tags := []string{"a", "b"}
+
+	d := NewBaseDatabase(convertValuesStub{})
+	assert.Equal(t, []interface{}{"a,b", 1}, d.ConvertValues([]interface{}{tags, 1}))
+
+	values := []interface{}{tags, db.Verbatim(tags), 1, sql.Named("tags", db.Verbatim(tags))}
+	converted := d.ConvertValues(values)
+	assert.Equal(t, []interface{}{"a,b", tags, 1, sql.Named("tags", tags)}, converted)
+
+	// The given values are not modified, so they can be converted again.
+	assert.Equal(t, db.Verbatim(tags), values[1])
+
+	d = NewBaseDatabase(nil)
+	assert.Equal(t, []interface{}{tags, 1}, d.ConvertValues([]interface{}{db.Verbatim(tags), 1}))
+}
+
+func (convertValuesStub) CompileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
+	compiled, err := stmt.Compile(&exql.Template{Cache: cache.NewCache()})
+	if err != nil {
+		panic(err.Error())
+	}
+	return sqlbuilder.Preprocess(compiled, args)
+}
+
+func TestCompileStatementVerbatim(t *testing.T) {
+	tags := []string{"a", "b"}
+	d := NewBaseDatabase(convertValuesStub{}).(*database)
+
+	// A verbatim slice is a single argument, not a list.
+	query, args := d.compileStatement(exql.RawSQL(`SELECT set_tags(?), ?`), []interface{}{db.Verbatim(tags), tags})
+	assert.Equal(t, `SELECT set_tags(?), ?`, query)
+	assert.Equal(t, []interface{}{tags, "a,b"}, args)
+
+	query, args = d.compileStatement(exql.RawSQL(`SELECT ? IN ?`), []interface{}{1, []int{1, 2}})
+	assert.Equal(t, `SELECT ? IN (?, ?)`, query)
+	assert.Equal(t, []interface{}{1, 1, 2}, args)
+}
+
+func TestUsePreparedStatement(t *testing.T) {
+	d := NewBaseDatabase(nil).(*database)
+	assert.False(t, d.usePreparedStatement(nil))
+
+	d.SetPreparedStatementCache(true)
+	assert.True(t, d.usePreparedStatement(nil))
+	assert.False(t, d.usePreparedStatement(&baseTx{}))
+}
+
+func TestEvents(t *testing.T) {
+	d := NewBaseDatabase(nil).(*database)
+	assert.False(t, d.Subscribed("artist"))
+
+	var mu sync.Mutex
+	var artists, all []db.Event
+	unsubscribe := d.Subscribe("artist", func(ev db.Event) {
+		mu.Lock()
+		artists = append(artists, ev)
+		mu.Unlock()
+	})
+	assert.True(t, d.Subscribed("artist"))
+	assert.False(t, d.Subscribed("album"))
+	d.Subscribe("", func(ev db.Event) {
+		mu.Lock()
+		all = append(all, ev)
+		mu.Unlock()
+	})
+
+	insert := db.Event{Operation: db.OperationInsert, Table: "artist", Keys: []interface{}{1}}
+	d.Emit(insert)
+	d.Emit(db.Event{Operation: db.OperationDelete, Table: "album"})
+	assert.Equal(t, []db.Event{insert}, artists)
+	assert.Equal(t, 2, len(all))
+
+	// Clones share subscribers.
+	clone, err := d.NewClone(nil, false)
+	assert.NoError(t, err)
+	clone.Emit(insert)
+	assert.Equal(t, 2, len(artists))
+
+	// Events are held until the transaction is committed.
+	tx := &baseTx{events: d.events}
+	clone.(*database).baseTx = tx
+	clone.Emit(insert)
+	assert.Equal(t, 2, len(artists))
+	assert.Equal(t, []db.Event{insert}, tx.takePending())
+
+	unsubscribe()
+	unsubscribe()
+	d.Emit(insert)
+	assert.Equal(t, 2, len(artists))
+	assert.Equal(t, 4, len(all))
+
+	// Subscribers may unsubscribe themselves while events are published.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(table string) {
+			defer wg.Done()
+			var unsubscribe func()
+			unsubscribe = d.Subscribe(table, func(ev db.Event) {
+				unsubscribe()
+			})
+			d.Emit(db.Event{Operation: db.OperationInsert, Table: table})
+		}(fmt.Sprintf("t%d", i))
+	}
+	wg.Wait()
+	assert.Equal(t, 1, len(d.events.subscribers))
+	assert.Equal(t, 14, len(all))
+}
+
+type eventsStub struct {
+	sqlbuilder.SQLBuilder
+	events []db.Event
+}
+
+func (s *eventsStub) PrimaryKeys(table string) ([]string, error) {
+	if table == "album" {
+		return []string{"artist_id", "title"}, nil
+	}
+	return []string{"id"}, nil
+}
+
+func (s *eventsStub) Emit(ev db.Event) {
+	s.events = append(s.events, ev)
+}
+
+func TestResultEventKeys(t *testing.T) {
+	tests := []struct {
+		table string
+		conds []interface{}
+		keys  []interface{}
+	}{
+		{"artist", []interface{}{db.Cond{"id": db.Eq(7)}}, []interface{}{7}},
+		{"artist", []interface{}{db.Cond{"id": "a1"}}, []interface{}{"a1"}},
+		{"artist", []interface{}{db.Cond{"id": []int{1, 2}}}, []interface{}{1, 2}},
+		{"artist", []interface{}{db.Cond{"id": db.In([]int{1, 2})}}, []interface{}{1, 2}},
+		{"artist", []interface{}{db.Cond{"id": db.Gt(7)}}, nil},
+		{"artist", []interface{}{db.Cond{"id": db.Raw("7")}}, nil},
+		{"artist", []interface{}{db.Cond{"name": "Ozzy"}}, nil},
+		{"artist", []interface{}{db.Cond{"id": 7, "name": "Ozzy"}}, nil},
+		{"artist", []interface{}{db.Cond{"id": 7}, db.Cond{"name": "Ozzy"}}, nil},
+		{"artist", nil, nil},
+		{"album", []interface{}{db.Cond{"artist_id": 1, "title": "Paranoid"}}, []interface{}{db.Cond{"artist_id": 1, "title": "Paranoid"}}},
+		{"album", []interface{}{db.Cond{"artist_id": []int{1, 2}, "title": "Paranoid"}}, nil},
+	}
+
+	for _, test := range tests {
+		stub := &eventsStub{}
+		NewResult(stub, test.table, test.conds).emit(db.OperationUpdate, nil)
+		if assert.Equal(t, 1, len(stub.events)) {
+			assert.Equal(t, db.Event{Operation: db.OperationUpdate, Table: test.table, Keys: test.keys}, stub.events[0], fmt.Sprint(test.conds))
+		}
+	}
+
+	// Results with OR conditions could match other rows.
+	stub := &eventsStub{}
+	NewResult(stub, "artist", []interface{}{db.Cond{"id": 7}}).Or(db.Cond{"id": 8}).(*Result).emit(db.OperationDelete, nil)
+	assert.Nil(t, stub.events[0].Keys)
+}
+
+type returningStub struct {
+	eventsStub
+}
+
+func (s *returningStub) PrimaryKeys(table string) ([]string, error) {
+	return []string{"n"}, nil
+}
+
+func (s *returningStub) SupportsReturning() bool {
+	return true
+}
+
+func (s *returningStub) Subscribed(table string) bool {
+	return table == "artist"
+}
+
+func TestResultChangeRows(t *testing.T) {
+	sess, err := sql.Open("sqladapter-stub", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sess.Close()
+
+	// The keys of the changed rows are read from RETURNING.
+	stub := &returningStub{}
+	affected, err := NewResult(stub, "artist", []interface{}{db.Cond{"name": "Ozzy"}}).changeRows(
+		db.OperationUpdate,
+		func() (sql.Result, error) {
+			return nil, errors.New("RETURNING was expected")
+		},
+		func(pKey []string) sqlbuilder.Iterator {
+			assert.Equal(t, []string{"n"}, pKey)
+			rows, err := sess.Query("UPDATE ... RETURNING n")
+			assert.NoError(t, err)
+			return sqlbuilder.NewIterator(rows)
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+	assert.Equal(t, []db.Event{{Operation: db.OperationUpdate, Table: "artist", Keys: []interface{}{int64(1)}}}, stub.events)
+
+	// Without subscribers the statement is run as it is, and nothing is
+	// reported if no row changed.
+	stub = &returningStub{}
+	affected, err = NewResult(stub, "album", nil).changeRows(
+		db.OperationDelete,
+		func() (sql.Result, error) {
+			return driver.RowsAffected(0), nil
+		},
+		nil,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+	assert.Empty(t, stub.events)
+}
+
+func TestOutbox(t *testing.T) {
+	type orderPlaced struct {
+		OrderID int64
+	}
+
+	assert.Equal(t, "sqladapter.orderPlaced", outboxType(orderPlaced{}))
+	assert.Equal(t, "sqladapter.orderPlaced", outboxType(&orderPlaced{}))
+	assert.Equal(t, "map[string]interface {}", outboxType(map[string]interface{}{}))
+	assert.Equal(t, "", outboxType(nil))
+
+	d := NewBaseDatabase(nil).(*database)
+	assert.Equal(t, "outbox", d.OutboxTable())
+	assert.Equal(t, db.ErrNotWithinTransaction, d.Outbox(orderPlaced{}))
+
+	d.baseTx = &baseTx{}
+	assert.Equal(t, db.ErrAlreadyWithinTransaction, d.DrainOutbox(context.Background(), func(sqlbuilder.OutboxMessage) error {
+		return nil
+	}))
+}
+
+type namedCollectionStub struct {
+	PartialCollection
+	name string
+}
+
+func (c namedCollectionStub) Name() string {
+	return c.name
+}
+
+func TestPrimaryKeyCond(t *testing.T) {
+	type membership struct {
+		GroupID int64   `db:"group_id"`
+		UserID  *int64  `db:"user_id"`
+		Role    string  `db:"role"`
+		Score   float64 `db:"score,omitempty"`
+	}
+
+	c := &collection{
+		PartialCollection: namedCollectionStub{name: "membership"},
+		pk:                []string{"group_id", "user_id"},
+	}
+
+	userID := int64(7)
+	cond, ok, err := c.primaryKeyCond(membership{GroupID: 3, UserID: &userID, Role: "admin"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, db.Cond{"group_id": db.Eq(int64(3)), "user_id": db.Eq(&userID)}, cond)
+
+	_, ok, err = c.primaryKeyCond(&membership{GroupID: 3, UserID: &userID})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, ok, err = c.primaryKeyCond(&membership{GroupID: 3})
+	assert.True(t, ok)
+	assert.EqualError(t, err, `Primary key "user_id" of table "membership" is zero or unset`)
+
+	_, ok, err = c.primaryKeyCond(membership{UserID: &userID})
+	assert.True(t, ok)
+	assert.EqualError(t, err, `Primary key "group_id" of table "membership" is zero or unset`)
+
+	for _, item := range []interface{}{
+		db.Cond{"group_id": 3},
+		db.And(db.Cond{"group_id": 3}),
+		(*membership)(nil),
+		struct {
+			GroupID int64 `db:"group_id"`
+		}{3},
+		3,
+	} {
+		_, ok, err = c.primaryKeyCond(item)
+		assert.NoError(t, err)
+		assert.False(t, ok, fmt.Sprint(item))
+	}
+
+	_, err = c.keyedResult(db.Cond{"group_id": 3})
+	assert.EqualError(t, err, `Expecting a struct with the primary keys of table "membership" but got db.Cond`)
+}
+
+func TestFindOrCreateValues(t *testing.T) {
+	type tag struct {
+		ID    int64  `db:"id,omitempty"`
+		Name  string `db:"name"`
+		Color string `db:"color"`
+	}
+
+	values, err := findOrCreateValues(db.Cond{"name": "urgent", "color ": db.Eq("red")}, &tag{Name: "other", Color: "grey"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "urgent", "color": "red"}, values)
+
+	values, err = findOrCreateValues(db.Cond{"name": "urgent"}, &map[string]interface{}{"color": "grey"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "urgent", "color": "grey"}, values)
+
+	_, err = findOrCreateValues(db.Cond{"name": db.NotEq("urgent")}, &tag{})
+	assert.EqualError(t, err, `FindOrCreate only accepts column = value conditions, got name`)
+
+	_, err = findOrCreateValues(db.Cond{"id >": 3}, &tag{})
+	assert.EqualError(t, err, `FindOrCreate only accepts column = value conditions, got id >`)
+}
+
+func TestItemKeys(t *testing.T) {
+	type membership struct {
+		GroupID int64  `db:"group_id"`
+		UserID  int64  `db:"user_id"`
+		Role    string `db:"role"`
+	}
+
+	c := &collection{pk: []string{"group_id", "user_id"}}
+	assert.Equal(t, []interface{}{db.Cond{"group_id": int64(3), "user_id": int64(7)}}, c.itemKeys(&membership{GroupID: 3, UserID: 7}))
+	assert.Equal(t, []interface{}{db.Cond{"group_id": 3, "user_id": 7}}, c.itemKeys(&map[string]interface{}{"group_id": 3, "user_id": 7}))
+	assert.Nil(t, c.itemKeys(&map[string]interface{}{"group_id": 3}))
+
+	c = &collection{pk: []string{"id"}}
+	assert.Equal(t, []interface{}{int64(3)}, c.itemKeys(&struct {
+		ID int64 `db:"id"`
+	}{3}))
+	assert.Nil(t, c.itemKeys(&membership{}))
+
+	c = &collection{}
+	assert.Nil(t, c.itemKeys(&membership{}))
+}
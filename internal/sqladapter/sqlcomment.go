@@ -0,0 +1,78 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqladapter
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	db "github.com/frazercomputing/upper-io-db"
+)
+
+// sqlComment formats the given tags as a sqlcommenter comment, with keys
+// sorted and keys and values URL-encoded, as required by the spec:
+//
+//	/*action='checkout',controller='cart'*/
+func sqlComment(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		// PathEscape encodes quotes and asterisks, so the values can't break
+		// out of the quotes nor out of the comment.
+		pairs = append(pairs, url.PathEscape(k)+"='"+url.PathEscape(tags[k])+"'")
+	}
+
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
+
+// withSQLComment appends the sqlcommenter tags of ctx to the given query, the
+// query is returned unchanged if there are no tags.
+func (d *database) withSQLComment(ctx context.Context, query string) string {
+	tags := db.SQLCommentTags(ctx)
+	if fn := d.SQLCommenter(); fn != nil {
+		merged := map[string]string{}
+		for k, v := range tags {
+			merged[k] = v
+		}
+		for k, v := range fn(ctx) {
+			merged[k] = v
+		}
+		tags = merged
+	}
+
+	comment := sqlComment(tags)
+	if comment == "" {
+		return query
+	}
+	return query + " " + comment
+}
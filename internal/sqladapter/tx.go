@@ -24,7 +24,9 @@ package sqladapter
 import (
 	"context"
 	"database/sql"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
@@ -45,6 +47,12 @@ type BaseTx interface {
 
 	// Committed returns true if the transaction was already commited.
 	Committed() bool
+
+	// Done returns true once the transaction can't be used anymore.
+	Done() bool
+
+	// TxErr returns nil while the transaction can be used.
+	TxErr() error
 }
 
 type databaseTx struct {
@@ -62,11 +70,54 @@ func NewDatabaseTx(db Database) DatabaseTx {
 
 type baseTx struct {
 	*sql.Tx
+	ctx       context.Context
 	committed atomic.Value
+	done      atomic.Value
+
+	events    *eventBus
+	pendingMu sync.Mutex
+	pending   []db.Event // published on commit
+	onDoneFns []func()   // called once the transaction is over
 }
 
-func newBaseTx(tx *sql.Tx) BaseTx {
-	return &baseTx{Tx: tx}
+func newBaseTx(ctx context.Context, tx *sql.Tx, events *eventBus) BaseTx {
+	return &baseTx{Tx: tx, ctx: ctx, events: events}
+}
+
+// queue holds ev until the transaction is committed.
+func (b *baseTx) queue(ev db.Event) {
+	b.pendingMu.Lock()
+	b.pending = append(b.pending, ev)
+	b.pendingMu.Unlock()
+}
+
+// onDone calls fn once the transaction is committed or rolled back, which
+// closes the rows read within it.
+func (b *baseTx) onDone(fn func()) {
+	b.pendingMu.Lock()
+	b.onDoneFns = append(b.onDoneFns, fn)
+	b.pendingMu.Unlock()
+}
+
+// finish calls the functions given to onDone.
+func (b *baseTx) finish() {
+	b.pendingMu.Lock()
+	fns := b.onDoneFns
+	b.onDoneFns = nil
+	b.pendingMu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// takePending returns the queued events and forgets them.
+func (b *baseTx) takePending() []db.Event {
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+	pending := b.pending
+	b.pending = nil
+	return pending
 }
 
 func (b *baseTx) Committed() bool {
@@ -78,11 +129,43 @@ func (b *baseTx) Committed() bool {
 }
 
 func (b *baseTx) Commit() (err error) {
+	// The transaction is over after Commit, even if it failed.
+	defer b.finish()
+	defer b.done.Store(struct{}{})
+
 	err = b.Tx.Commit()
 	if err != nil {
+		b.takePending()
 		return err
 	}
 	b.committed.Store(struct{}{})
+
+	if pending := b.takePending(); len(pending) > 0 && b.events != nil {
+		b.events.publish(pending...)
+	}
+	return nil
+}
+
+func (b *baseTx) Rollback() error {
+	defer b.finish()
+	defer b.done.Store(struct{}{})
+	b.takePending()
+	return b.Tx.Rollback()
+}
+
+func (b *baseTx) Done() bool {
+	return b.TxErr() != nil
+}
+
+// TxErr returns sql.ErrTxDone after Commit or Rollback, or the context's error
+// if it expired, as database/sql rolls the transaction back then.
+func (b *baseTx) TxErr() error {
+	if b.done.Load() != nil {
+		return sql.ErrTxDone
+	}
+	if b.ctx != nil {
+		return b.ctx.Err()
+	}
 	return nil
 }
 
@@ -98,12 +181,56 @@ func (w *databaseTx) Rollback() error {
 
 // RunTx creates a transaction context and runs fn within it.
 func RunTx(d sqlbuilder.Database, ctx context.Context, fn func(tx sqlbuilder.Tx) error) error {
+	return RunTxWithStats(d, ctx, fn, nil)
+}
+
+// txErrorKinds are the portable errors TxStats.ErrKind is matched against.
+var txErrorKinds = []error{
+	db.ErrStaleObject,
+	db.ErrUniqueViolation,
+	db.ErrForeignKeyViolation,
+	db.ErrCheckViolation,
+	db.ErrNotNullViolation,
+	db.ErrQueryTimeout,
+	db.ErrTooManyClients,
+	context.Canceled,
+	context.DeadlineExceeded,
+}
+
+// RunTxWithStats works like RunTx and, if stats is not nil, fills it in once
+// the transaction is done, whether it was committed or not. Transactions are
+// run once, so Attempts is 1 and Wait is zero.
+func RunTxWithStats(d sqlbuilder.Database, ctx context.Context, fn func(tx sqlbuilder.Tx) error, stats *sqlbuilder.TxStats) (err error) {
+	if stats != nil {
+		defer func(start time.Time) {
+			*stats = sqlbuilder.TxStats{
+				Attempts: 1,
+				Duration: time.Since(start),
+				Err:      err,
+				ErrKind:  errorKind(err, txErrorKinds),
+			}
+		}(time.Now())
+	}
+	return runTx(d, ctx, fn)
+}
+
+func runTx(d sqlbuilder.Database, ctx context.Context, fn func(tx sqlbuilder.Tx) error) error {
 	tx, err := d.NewTx(ctx)
 	if err != nil {
 		return err
 	}
 
 	defer tx.Close()
+
+	// A panic in fn rolls the transaction back before going up the stack, so
+	// it doesn't keep holding locks.
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
 	if err := fn(tx); err != nil {
 		tx.Rollback()
 		return err
@@ -115,3 +242,26 @@ var (
 	_ = BaseTx(&baseTx{})
 	_ = DatabaseTx(&databaseTx{})
 )
+
+// errorKind returns the first error in kinds that err, or any error wrapped by
+// it, is or matches with its Is method. It returns nil if there's none.
+func errorKind(err error, kinds []error) error {
+	for ; err != nil; err = unwrap(err) {
+		for _, kind := range kinds {
+			if err == kind {
+				return kind
+			}
+			if e, ok := err.(interface{ Is(error) bool }); ok && e.Is(kind) {
+				return kind
+			}
+		}
+	}
+	return nil
+}
+
+func unwrap(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+// Package metrics serves the statement statistics collected by
+// db.QueryMetrics over HTTP, in the Prometheus text format:
+//
+//	m := db.NewQueryMetrics()
+//	sess.SetMetrics(m)
+//	http.Handle("/metrics", metrics.Handler(m))
+package metrics
+
+import (
+	"net/http"
+
+	db "github.com/frazercomputing/upper-io-db"
+)
+
+// Handler returns an http.Handler that serves the current statistics of m.
+func Handler(m *db.QueryMetrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.WriteTo(w)
+	})
+}
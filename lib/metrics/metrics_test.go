@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+)
+
+func TestHandler(t *testing.T) {
+	m := db.NewQueryMetrics()
+	m.RecordQuery("select", "artist", time.Millisecond, nil)
+
+	w := httptest.NewRecorder()
+	Handler(m).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if line := `upper_queries_total{op="select",table="artist"} 1`; !strings.Contains(w.Body.String(), line+"\n") {
+		t.Fatalf("missing %q in:\n%s", line, w.Body.String())
+	}
+}
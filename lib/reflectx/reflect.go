@@ -333,11 +333,11 @@ func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc func(string)
 				}
 			}
 
-			parts := strings.Split(name, ",")
+			parts := splitOptions(name)
 			if len(parts) > 1 {
 				name = parts[0]
 				for _, opt := range parts[1:] {
-					kv := strings.Split(opt, "=")
+					kv := strings.SplitN(opt, "=", 2)
 					if len(kv) > 1 {
 						fi.Options[kv[0]] = kv[1]
 					} else {
@@ -407,3 +407,26 @@ func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc func(string)
 
 	return flds
 }
+
+// splitOptions splits a tag into its comma separated parts, commas within
+// parentheses are kept, so options like "type=numeric(12,2)" are not broken.
+func splitOptions(tag string) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	for i := 0; i < len(tag); i++ {
+		switch tag[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, tag[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, tag[start:])
+}
@@ -437,6 +437,24 @@ func TestFieldMap(t *testing.T) {
 	}
 }
 
+func TestOptionsWithParentheses(t *testing.T) {
+	type Payment struct {
+		Amount float64 `db:"amount,type=numeric(12,2),omitempty"`
+	}
+
+	m := NewMapper("db")
+	fi := m.TypeMap(reflect.TypeOf(Payment{})).GetByPath("amount")
+	if fi == nil {
+		t.Fatal("Expecting to find amount in mapping")
+	}
+	if fi.Options["type"] != "numeric(12,2)" {
+		t.Errorf("Expecting type option to be %q, got %q", "numeric(12,2)", fi.Options["type"])
+	}
+	if _, ok := fi.Options["omitempty"]; !ok {
+		t.Errorf("Expecting omitempty option to be set")
+	}
+}
+
 func TestTagNameMapping(t *testing.T) {
 	type Strategy struct {
 		StrategyID   string `protobuf:"bytes,1,opt,name=strategy_id" json:"strategy_id,omitempty"`
@@ -0,0 +1,132 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/immutable"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+)
+
+type tableAltererQuery struct {
+	table   string
+	actions []exql.Fragment
+}
+
+func (aq *tableAltererQuery) statement() *exql.Statement {
+	return &exql.Statement{
+		Type:        exql.AlterTable,
+		Table:       exql.TableWithName(aq.table),
+		Alterations: &exql.Alterations{Actions: aq.actions},
+	}
+}
+
+type tableAlterer struct {
+	builder *sqlBuilder
+
+	fn   func(*tableAltererQuery) error
+	prev *tableAlterer
+}
+
+var _ = immutable.Immutable(&tableAlterer{})
+
+func (ta *tableAlterer) SQLBuilder() *sqlBuilder {
+	if ta.prev == nil {
+		return ta.builder
+	}
+	return ta.prev.SQLBuilder()
+}
+
+func (ta *tableAlterer) template() *exql.Template {
+	return ta.SQLBuilder().t.Template
+}
+
+func (ta *tableAlterer) String() string {
+	s, err := ta.Compile()
+	if err != nil {
+		panic(err.Error())
+	}
+	return prepareQueryForDisplay(ta.template(), s)
+}
+
+func (ta *tableAlterer) setTable(table string) *tableAlterer {
+	return ta.frame(func(aq *tableAltererQuery) error {
+		aq.table = table
+		return nil
+	})
+}
+
+func (ta *tableAlterer) frame(fn func(*tableAltererQuery) error) *tableAlterer {
+	return &tableAlterer{prev: ta, fn: fn}
+}
+
+func (ta *tableAlterer) AddColumn(name string, columnType string, constraints ...db.ColumnConstraint) TableAlterer {
+	return ta.frame(func(aq *tableAltererQuery) error {
+		aq.actions = append(aq.actions, &exql.AddColumn{
+			Column: columnDefinition(name, columnType, constraints),
+		})
+		return nil
+	})
+}
+
+func (ta *tableAlterer) DropColumn(name string) TableAlterer {
+	return ta.frame(func(aq *tableAltererQuery) error {
+		aq.actions = append(aq.actions, &exql.DropColumn{Name: name})
+		return nil
+	})
+}
+
+func (ta *tableAlterer) Exec() (sql.Result, error) {
+	return ta.ExecContext(ta.SQLBuilder().sess.Context())
+}
+
+func (ta *tableAlterer) ExecContext(ctx context.Context) (sql.Result, error) {
+	aq, err := ta.build()
+	if err != nil {
+		return nil, err
+	}
+	return ta.SQLBuilder().sess.StatementExec(ctx, aq.statement())
+}
+
+func (ta *tableAlterer) statement() (*exql.Statement, error) {
+	aq, err := ta.build()
+	if err != nil {
+		return nil, err
+	}
+	return aq.statement(), nil
+}
+
+func (ta *tableAlterer) build() (*tableAltererQuery, error) {
+	aq, err := immutable.FastForward(ta)
+	if err != nil {
+		return nil, err
+	}
+	return aq.(*tableAltererQuery), nil
+}
+
+func (ta *tableAlterer) Compile() (string, error) {
+	s, err := ta.statement()
+	if err != nil {
+		return "", err
+	}
+	return s.Compile(ta.template())
+}
+
+func (ta *tableAlterer) Prev() immutable.Immutable {
+	if ta == nil {
+		return nil
+	}
+	return ta.prev
+}
+
+func (ta *tableAlterer) Fn(in interface{}) error {
+	if ta.fn == nil {
+		return nil
+	}
+	return ta.fn(in.(*tableAltererQuery))
+}
+
+func (ta *tableAlterer) Base() interface{} {
+	return &tableAltererQuery{}
+}
@@ -33,6 +33,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter/compat"
@@ -68,10 +70,72 @@ type hasStatement interface {
 	statement() *exql.Statement
 }
 
+// cursor is the part of *sql.Rows an iterator reads rows from, see
+// doneCursor.
+type cursor interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// rowsCursor returns rows as a cursor, a nil *sql.Rows is a nil cursor.
+func rowsCursor(rows *sql.Rows) cursor {
+	if rows == nil {
+		return nil
+	}
+	return rows
+}
+
+// hasStatementRows is implemented by sessions that count a query as in
+// flight until done is called, once its rows are closed.
+type hasStatementRows interface {
+	StatementRows(ctx context.Context, stmt *exql.Statement, args ...interface{}) (rows *sql.Rows, done func(), err error)
+}
+
+// queryCursor sends stmt and returns its rows as a cursor, the session is told
+// once they're closed if it keeps track of that.
+func queryCursor(ctx context.Context, sess exprDB, stmt *exql.Statement, args []interface{}) (cursor, error) {
+	if s, ok := sess.(hasStatementRows); ok {
+		rows, done, err := s.StatementRows(ctx, stmt, args...)
+		if err != nil {
+			return nil, err
+		}
+		return &doneCursor{Rows: rows, done: done}, nil
+	}
+	rows, err := sess.StatementQuery(ctx, stmt, args...)
+	return rowsCursor(rows), err
+}
+
+// doneCursor calls done once its rows are closed, either by Close or by Next
+// running out of rows.
+type doneCursor struct {
+	*sql.Rows
+
+	once sync.Once
+	done func()
+}
+
+func (c *doneCursor) Next() bool {
+	if c.Rows.Next() {
+		return true
+	}
+	c.once.Do(c.done)
+	return false
+}
+
+func (c *doneCursor) Close() error {
+	err := c.Rows.Close()
+	c.once.Do(c.done)
+	return err
+}
+
 type iterator struct {
 	sess   exprDB
-	cursor *sql.Rows // This is the main query cursor. It starts as a nil value.
+	cursor cursor // This is the main query cursor. It starts as a nil value.
 	err    error
+	total  *totalCount // The column added by WithTotalCount, if any.
 }
 
 type fieldValue struct {
@@ -126,7 +190,7 @@ func WithTemplate(t *exql.Template) SQLBuilder {
 
 // NewIterator creates an iterator using the given *sql.Rows.
 func NewIterator(rows *sql.Rows) Iterator {
-	return &iterator{nil, rows, nil}
+	return &iterator{cursor: rowsCursor(rows)}
 }
 
 func (b *sqlBuilder) Iterator(query interface{}, args ...interface{}) Iterator {
@@ -134,8 +198,18 @@ func (b *sqlBuilder) Iterator(query interface{}, args ...interface{}) Iterator {
 }
 
 func (b *sqlBuilder) IteratorContext(ctx context.Context, query interface{}, args ...interface{}) Iterator {
-	rows, err := b.QueryContext(ctx, query, args...)
-	return &iterator{b.sess, rows, err}
+	switch q := query.(type) {
+	case *exql.Statement:
+		rows, err := queryCursor(ctx, b.sess, q, args)
+		return &iterator{sess: b.sess, cursor: rows, err: err}
+	case string:
+		rows, err := queryCursor(ctx, b.sess, exql.RawSQL(q), args)
+		return &iterator{sess: b.sess, cursor: rows, err: err}
+	case db.RawValue:
+		return b.IteratorContext(ctx, q.Raw(), q.Arguments()...)
+	default:
+		return &iterator{sess: b.sess, err: fmt.Errorf("unsupported query type %T", query)}
+	}
 }
 
 func (b *sqlBuilder) Prepare(query interface{}) (*sql.Stmt, error) {
@@ -155,6 +229,43 @@ func (b *sqlBuilder) PrepareContext(ctx context.Context, query interface{}) (*sq
 	}
 }
 
+func (b *sqlBuilder) PrepareStmt(query interface{}) (*Stmt, error) {
+	return b.PrepareStmtContext(b.sess.Context(), query)
+}
+
+func (b *sqlBuilder) PrepareStmtContext(ctx context.Context, query interface{}) (*Stmt, error) {
+	var stmt *exql.Statement
+	var args []interface{}
+
+	if ins, ok := query.(*inserter); ok {
+		query = ins.placeholders()
+	}
+
+	switch q := query.(type) {
+	case *exql.Statement:
+		stmt = q
+	case string:
+		stmt = exql.RawSQL(q)
+	case db.RawValue:
+		return b.PrepareStmtContext(ctx, q.Raw())
+	case compilable:
+		c, err := q.Compile()
+		if err != nil {
+			return nil, err
+		}
+		c, args = PreprocessWithTemplate(b.t.Template, c, q.Arguments())
+		stmt = exql.RawSQL(c)
+	default:
+		return nil, fmt.Errorf("unsupported query type %T", query)
+	}
+
+	p, err := b.sess.StatementPrepare(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{Stmt: p, sess: b.sess, args: args}, nil
+}
+
 func (b *sqlBuilder) Exec(query interface{}, args ...interface{}) (sql.Result, error) {
 	return b.ExecContext(b.sess.Context(), query, args...)
 }
@@ -234,6 +345,27 @@ func (b *sqlBuilder) DeleteFrom(table string) Deleter {
 	return qd.setTable(table)
 }
 
+func (b *sqlBuilder) CreateTable(table string) TableCreator {
+	qc := &tableCreator{
+		builder: b,
+	}
+	return qc.setTable(table)
+}
+
+func (b *sqlBuilder) DropTable(table string) TableDropper {
+	qd := &tableDropper{
+		builder: b,
+	}
+	return qd.setTable(table)
+}
+
+func (b *sqlBuilder) AlterTable(table string) TableAlterer {
+	qa := &tableAlterer{
+		builder: b,
+	}
+	return qa.setTable(table)
+}
+
 func (b *sqlBuilder) Update(table string) Updater {
 	qu := &updater{
 		builder: b,
@@ -363,36 +495,49 @@ func extractArguments(fragments []interface{}) []interface{} {
 	return args
 }
 
-func columnFragments(columns []interface{}) ([]exql.Fragment, []interface{}, error) {
+func columnFragments(layout *exql.Template, columns []interface{}) ([]exql.Fragment, []interface{}, error) {
 	l := len(columns)
 	f := make([]exql.Fragment, l)
 	args := []interface{}{}
 
 	for i := 0; i < l; i++ {
 		switch v := columns[i].(type) {
+		case *ValuesList:
+			fragment, fragmentArgs, err := v.fragment(layout)
+			if err != nil {
+				return nil, nil, err
+			}
+			f[i] = fragment
+			args = append(args, fragmentArgs...)
 		case compilable:
 			c, err := v.Compile()
 			if err != nil {
 				return nil, nil, err
 			}
-			q, a := Preprocess(c, v.Arguments())
+			q, a := PreprocessWithTemplate(layout, c, v.Arguments())
 			if _, ok := v.(Selector); ok {
 				q = "(" + q + ")"
 			}
 			f[i] = exql.RawValue(q)
 			args = append(args, a...)
 		case db.Function:
-			fnName, fnArgs := v.Name(), v.Arguments()
-			if len(fnArgs) == 0 {
-				fnName = fnName + "()"
-			} else {
-				fnName = fnName + "(?" + strings.Repeat("?, ", len(fnArgs)-1) + ")"
+			fn, fnArgs, err := functionFragment(v)
+			if err != nil {
+				return nil, nil, err
 			}
-			fnName, fnArgs = Preprocess(fnName, fnArgs)
-			f[i] = exql.RawValue(fnName)
+			f[i] = fn
 			args = append(args, fnArgs...)
+		case db.Expr:
+			expr, exprArgs, err := exprFragment(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			f[i] = expr
+			args = append(args, exprArgs...)
+		case db.Column:
+			f[i] = exql.ColumnWithName(v.ColumnName())
 		case db.RawValue:
-			q, a := Preprocess(v.Raw(), v.Arguments())
+			q, a := PreprocessWithTemplate(layout, v.Raw(), v.Arguments())
 			f[i] = exql.RawValue(q)
 			args = append(args, a...)
 		case exql.Fragment:
@@ -410,11 +555,20 @@ func columnFragments(columns []interface{}) ([]exql.Fragment, []interface{}, err
 	return f, args, nil
 }
 
-func prepareQueryForDisplay(in string) (out string) {
+func prepareQueryForDisplay(layout *exql.Template, in string) (out string) {
 	j := 1
-	for i := range in {
+	for i := 0; i < len(in); i++ {
+		if n := layout.LiteralLen(in, i); n > 0 {
+			out = out + in[i:i+n]
+			i += n - 1
+			continue
+		}
 		if in[i] == '?' {
-			out = out + "$" + strconv.Itoa(j)
+			if layout.Placeholder != nil {
+				out = out + layout.Placeholder(j)
+			} else {
+				out = out + "$" + strconv.Itoa(j)
+			}
 			j++
 		} else {
 			out = out + string(in[i])
@@ -444,7 +598,44 @@ func (iter *iterator) Scan(dst ...interface{}) error {
 	if err := iter.Err(); err != nil {
 		return err
 	}
-	return iter.cursor.Scan(dst...)
+	if err := iter.cursor.Scan(dst...); err != nil {
+		return err
+	}
+	forceUTC(iter.sess, dst)
+	return nil
+}
+
+func (iter *iterator) RawBytes(column string) ([]byte, error) {
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	if iter.cursor == nil {
+		return nil, db.ErrNoMoreRows
+	}
+	columns, err := iter.cursor.Columns()
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i := range columns {
+		if columns[i] == column {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("column %q is not in the result set", column)
+	}
+	// The current row is scanned again, without copying, into RawBytes.
+	raw := make([]sql.RawBytes, len(columns))
+	dst := make([]interface{}, len(columns))
+	for i := range raw {
+		dst[i] = &raw[i]
+	}
+	if err := iter.cursor.Scan(dst...); err != nil {
+		return nil, err
+	}
+	return raw[index], nil
 }
 
 func (iter *iterator) setErr(err error) error {
@@ -511,6 +702,9 @@ func (iter *iterator) next(dst ...interface{}) error {
 		}
 		return nil
 	case 1:
+		if !isRowDestination(dst[0]) {
+			break
+		}
 		if err := fetchRow(iter, dst[0]); err != nil {
 			defer iter.Close()
 			return err
@@ -518,7 +712,48 @@ func (iter *iterator) next(dst ...interface{}) error {
 		return nil
 	}
 
-	return errors.New("Next does not currently supports more than one parameters")
+	// Scanning columns into the given values, in order.
+	if ok := iter.cursor.Next(); !ok {
+		defer iter.Close()
+		err := iter.cursor.Err()
+		if err == nil {
+			err = db.ErrNoMoreRows
+		}
+		return err
+	}
+	if err := iter.cursor.Scan(dst...); err != nil {
+		defer iter.Close()
+		return err
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// isRowDestination returns true if dst is a pointer to a map or struct that
+// represents a whole row, as opposed to a pointer to a single column value.
+// Pointers are followed, so a **time.Time or a **sql.NullString is a column
+// value too.
+func isRowDestination(dst interface{}) bool {
+	t := reflect.TypeOf(dst)
+	if t == nil || t.Kind() != reflect.Ptr {
+		// Let fetchRow report the error.
+		return true
+	}
+	for t.Kind() == reflect.Ptr {
+		if t.Implements(ScannerType) {
+			return false
+		}
+		t = t.Elem()
+	}
+	if t == timeType {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Struct:
+		return true
+	}
+	return false
 }
 
 func (iter *iterator) Close() (err error) {
@@ -618,3 +853,16 @@ func joinArguments(args ...[]interface{}) []interface{} {
 	}
 	return flatten
 }
+
+// orWhere groups the conditions that were already on where and the given
+// conditions and joins both groups with OR.
+func orWhere(where *exql.Where, conds exql.Where) *exql.Where {
+	if len(conds.Conditions) == 0 {
+		return where
+	}
+	if where == nil || len(where.Conditions) == 0 {
+		return &conds
+	}
+	left, right := exql.And(*where), exql.And(conds)
+	return exql.WhereConditions(exql.JoinWithOr(&left, &right))
+}
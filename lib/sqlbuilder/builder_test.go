@@ -1,12 +1,17 @@
 package sqlbuilder
 
 import (
+	"database/sql"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/cache"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 )
 
 func TestSelect(t *testing.T) {
@@ -866,6 +871,240 @@ func TestSelect(t *testing.T) {
 		)
 
 	}
+
+	{
+		sel := b.SelectFrom("foo").Where(db.Cond{"bar": db.Between(1, 10)})
+		assert.Equal(
+			`SELECT * FROM "foo" WHERE ("bar" BETWEEN $1 AND $2)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{1, 10},
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("foo").Where(db.Cond{"bar": db.NotBetween(1, 10)})
+		assert.Equal(
+			`SELECT * FROM "foo" WHERE ("bar" NOT BETWEEN $1 AND $2)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{1, 10},
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("foo").Where(db.Cond{"bar": db.NotIn([]int{1, 2, 3})})
+		assert.Equal(
+			`SELECT * FROM "foo" WHERE ("bar" NOT IN ($1, $2, $3))`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{1, 2, 3},
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("foo").Where(db.Cond{"bar": db.NotIn([]int{})})
+		assert.Equal(
+			`SELECT * FROM "foo" WHERE (1 = 1)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}(nil),
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("foo").Where(db.Cond{"bar NOT IN": []int{}, "baz IN": []int{}})
+		assert.Equal(
+			`SELECT * FROM "foo" WHERE (1 = 1 AND "baz" IN (NULL))`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}(nil),
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("foo").Where(db.Cond{"bar not in": []int{1, 2}})
+		assert.Equal(
+			`SELECT * FROM "foo" WHERE ("bar" NOT IN ($1, $2))`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{1, 2},
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("foo").Where(db.Cond{"bar": db.IsNull(), "baz": db.IsNotNull()})
+		assert.Equal(
+			`SELECT * FROM "foo" WHERE ("bar" IS NULL AND "baz" IS NOT NULL)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}(nil),
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("foo").Where(db.Cond{"bar": db.Like("%" + db.EscapeLike(`50%_off\`) + "%")}).And(db.Cond{"baz": db.NotLike("a_c")})
+		assert.Equal(
+			`SELECT * FROM "foo" WHERE ("bar" LIKE $1 ESCAPE '\' AND "baz" NOT LIKE $2)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{`%50\%\_off\\%`, "a_c"},
+			sel.Arguments(),
+		)
+	}
+
+	{
+		// Other operators take escaped patterns as plain strings.
+		sel := b.SelectFrom("foo").Where(db.Cond{"bar": db.Eq(db.EscapeLike("a_b"))})
+		assert.Equal(
+			`SELECT * FROM "foo" WHERE ("bar" = $1)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{`a\_b`},
+			sel.Arguments(),
+		)
+	}
+}
+
+func TestInsertOnConflict(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	item := map[string]interface{}{"email": "ozzy@example.com", "name": "Ozzy"}
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO NOTHING`,
+		b.InsertInto("artist").Values(item).OnConflict("email").String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name"`,
+		b.InsertInto("artist").Values(item).OnConflict("email").DoUpdate().String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO UPDATE SET "email" = EXCLUDED."email"`,
+		b.InsertInto("artist").Values(item).OnConflict("email").DoUpdate("email").String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO NOTHING`,
+		b.InsertInto("artist").Values(item).OnConflict("email").DoUpdate().DoNothing().String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name" RETURNING "id", (xmax = 0) AS "inserted"`,
+		b.InsertInto("artist").Values(item).OnConflict("email").DoUpdate().Returning("id").ReturningInserted().String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name" RETURNING (xmax = 0) AS "inserted"`,
+		b.InsertInto("artist").Values(item).ReturningInserted().OnConflict("email").DoUpdate().String(),
+	)
+
+	assert.Equal(
+		[]interface{}{"ozzy@example.com", "Ozzy"},
+		b.InsertInto("artist").Values(item).OnConflict("email").DoUpdate().Arguments(),
+	)
+
+	{
+		_, err := b.InsertInto("artist").Values(item).OnConflict().(*inserter).Compile()
+		assert.Equal(ErrMissingConflictTarget, err)
+
+		_, err = b.InsertInto("artist").Values(item).DoUpdate().(*inserter).Compile()
+		assert.Equal(ErrMissingConflictTarget, err)
+
+		_, err = b.InsertInto("artist").Values(item).Returning("id").ReturningInserted().(*inserter).Compile()
+		assert.Equal(ErrMissingConflictTarget, err)
+	}
+}
+
+func TestInsertColumnsPlaceholders(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	q := b.InsertInto("artist").Columns("name", "rank")
+	assert.Equal(`INSERT INTO "artist" ("name", "rank") VALUES (default)`, q.String())
+
+	// Placeholders are only added when preparing.
+	p := q.(*inserter).placeholders()
+	assert.Equal(`INSERT INTO "artist" ("name", "rank") VALUES ($1, $2)`, p.String())
+	assert.Empty(p.Arguments())
+}
+
+type convertingSession struct {
+	exprDB
+}
+
+func (convertingSession) ConvertValues(values []interface{}) []interface{} {
+	for i := range values {
+		if s, ok := values[i].(string); ok {
+			values[i] = strings.ToUpper(s)
+		}
+	}
+	return values
+}
+
+func TestStmtArguments(t *testing.T) {
+	stmt := &Stmt{sess: convertingSession{}, args: []interface{}{"a", 1}}
+	assert.Equal(t, []interface{}{"A", 1, "B", 2}, stmt.arguments([]interface{}{"b", 2}))
+
+	// Builder arguments are not modified by calls.
+	assert.Equal(t, []interface{}{"a", 1}, stmt.args)
+
+	stmt = &Stmt{sess: convertingSession{}}
+	assert.Equal(t, []interface{}{"B"}, stmt.arguments([]interface{}{"b"}))
+}
+
+func TestInsertDefault(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	{
+		q := b.InsertInto("artist").Columns("id", "name", "created_at").Values(db.Default, "Ozzy", db.Default)
+		assert.Equal(`INSERT INTO "artist" ("id", "name", "created_at") VALUES (DEFAULT, $1, DEFAULT)`, q.String())
+		assert.Equal([]interface{}{"Ozzy"}, q.Arguments())
+	}
+
+	{
+		q := b.InsertInto("artist").Columns("name", "created_at").Values("Ozzy", db.Default).Values("Flea", "2019-01-01")
+		assert.Equal(`INSERT INTO "artist" ("name", "created_at") VALUES ($1, DEFAULT), ($2, $3)`, q.String())
+		assert.Equal([]interface{}{"Ozzy", "Flea", "2019-01-01"}, q.Arguments())
+	}
+
+	{
+		q := b.InsertInto("artist").Values(map[string]interface{}{"name": "Ozzy", "created_at": db.Default, "rank": 3})
+		assert.Equal(`INSERT INTO "artist" ("created_at", "name", "rank") VALUES (DEFAULT, $1, $2)`, q.String())
+		assert.Equal([]interface{}{"Ozzy", 3}, q.Arguments())
+	}
+
+	{
+		q := b.InsertInto("artist").Columns("name", "slug").Values("Ozzy", db.Raw("lower(?)", "OZZY"))
+		assert.Equal(`INSERT INTO "artist" ("name", "slug") VALUES ($1, lower($2))`, q.String())
+		assert.Equal([]interface{}{"Ozzy", "OZZY"}, q.Arguments())
+	}
+
+	{
+		q := b.Update("artist").Set("created_at", db.Default).Where("id", 1)
+		assert.Equal(`UPDATE "artist" SET "created_at" = DEFAULT WHERE ("id" = $1)`, q.String())
+		assert.Equal([]interface{}{1}, q.Arguments())
+	}
 }
 
 func TestInsert(t *testing.T) {
@@ -891,6 +1130,16 @@ func TestInsert(t *testing.T) {
 		b.InsertInto("artist").Values(map[string]string{"id": "12", "name": "Chavela Vargas"}).Returning("id").String(),
 	)
 
+	{
+		q := b.InsertInto("artist").Values(map[string]string{"name": "Chavela Vargas"}).
+			ReturningExpr("id", "created_at AS added", db.Raw("now() - created_at AS age"), db.Func("concat", db.Col("name"), "!"))
+		assert.Equal(
+			`INSERT INTO "artist" ("name") VALUES ($1) RETURNING "id", "created_at" AS "added", now() - created_at AS age, concat("name", $2)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{"Chavela Vargas", "!"}, q.Arguments())
+	}
+
 	assert.Equal(
 		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) RETURNING "id"`,
 		b.InsertInto("artist").Values(map[string]string{"id": "12", "name": "Chavela Vargas"}).Amend(func(query string) string {
@@ -1258,6 +1507,569 @@ func TestDelete(t *testing.T) {
 	)
 }
 
+func TestRawCacheKey(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	// Raw fragments of the same length on the same position.
+	q1 := b.SelectFrom("foo").Where(db.Raw("a = 1"))
+	q2 := b.SelectFrom("foo").Where(db.Raw("b = 2"))
+
+	sq1, err := q1.(*selector).build()
+	assert.NoError(err)
+
+	sq2, err := q2.(*selector).build()
+	assert.NoError(err)
+
+	stmt1, stmt2 := sq1.statement(), sq2.statement()
+	assert.NotEqual(stmt1.Hash(), stmt2.Hash())
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(`SELECT * FROM "foo" WHERE (a = 1)`, q1.String())
+		assert.Equal(`SELECT * FROM "foo" WHERE (b = 2)`, q2.String())
+	}
+
+	cached1, ok := testTemplate.Read(stmt1)
+	assert.True(ok)
+	assert.Equal(`SELECT * FROM "foo" WHERE (a = 1)`, prepareQueryForDisplay(&testTemplate, cached1))
+
+	cached2, ok := testTemplate.Read(stmt2)
+	assert.True(ok)
+	assert.Equal(`SELECT * FROM "foo" WHERE (b = 2)`, prepareQueryForDisplay(&testTemplate, cached2))
+}
+
+func TestCreateTable(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	type base struct {
+		CreatedAt time.Time  `db:"created_at"`
+		DeletedAt *time.Time `db:"deleted_at"`
+	}
+
+	type account struct {
+		ID       int64          `db:"id,omitempty"`
+		Name     string         `db:"name"`
+		Email    *string        `db:"email"`
+		Nickname sql.NullString `db:"nickname"`
+		Amount   float64        `db:"amount,type=numeric(12,2)"`
+		Active   bool           `db:"active"`
+		Avatar   []byte         `db:"avatar"`
+		Ignored  string         `db:"-"`
+		base
+	}
+
+	assert.Equal(
+		`CREATE TABLE "accounts" ("id" BIGSERIAL PRIMARY KEY, "name" TEXT NOT NULL, "email" TEXT, "nickname" TEXT, "amount" numeric(12,2) NOT NULL, "active" BOOLEAN NOT NULL, "avatar" BLOB NOT NULL, "created_at" TIMESTAMP NOT NULL, "deleted_at" TIMESTAMP)`,
+		b.CreateTable("accounts").Struct(account{}).String(),
+	)
+
+	assert.Equal(
+		`CREATE TABLE IF NOT EXISTS "accounts" ("id" BIGSERIAL PRIMARY KEY, "name" TEXT NOT NULL, "email" TEXT, "nickname" TEXT, "amount" numeric(12,2) NOT NULL, "active" BOOLEAN NOT NULL, "avatar" BLOB NOT NULL, "created_at" TIMESTAMP NOT NULL, "deleted_at" TIMESTAMP)`,
+		b.CreateTable("accounts").Struct(&account{}).IfNotExists().String(),
+	)
+
+	type membership struct {
+		AccountID int64  `db:"account_id,pk"`
+		GroupID   int32  `db:"group_id,pk"`
+		Role      string `db:"role,type=varchar(20)"`
+	}
+
+	assert.Equal(
+		`CREATE TABLE "memberships" ("account_id" BIGINT NOT NULL, "group_id" INTEGER NOT NULL, "role" varchar(20) NOT NULL, PRIMARY KEY ("account_id", "group_id"))`,
+		b.CreateTable("memberships").Struct(membership{}).String(),
+	)
+
+	type setting struct {
+		Key   string                 `db:"key,pk"`
+		Value map[string]interface{} `db:"value"`
+	}
+
+	assert.Equal(
+		`CREATE TABLE "settings" ("key" text PRIMARY KEY, "value" jsonb NOT NULL, "updated_at" timestamp DEFAULT now())`,
+		b.CreateTable("settings").
+			Column("key", "text", db.PrimaryKey).
+			Column("value", "jsonb", db.NotNull).
+			Column("updated_at", "timestamp", db.ColumnDefault("now()")).
+			String(),
+	)
+
+	assert.Equal(
+		`CREATE TEMPORARY TABLE "memberships" ("account_id" BIGINT NOT NULL, "group_id" INTEGER NOT NULL, "role" varchar(20) NOT NULL, PRIMARY KEY ("account_id", "group_id"))`,
+		b.CreateTable("memberships").Struct(membership{}).Temporary().String(),
+	)
+
+	_, err := b.CreateTable("settings").Struct(setting{}).(*tableCreator).Compile()
+	assert.Error(err)
+
+	_, err = b.CreateTable("settings").Struct(1).(*tableCreator).Compile()
+	assert.Equal(ErrExpectingStruct, err)
+}
+
+func TestDropTable(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	assert.Equal(
+		`DROP TABLE "accounts"`,
+		b.DropTable("accounts").String(),
+	)
+
+	assert.Equal(
+		`DROP TABLE IF EXISTS "accounts"`,
+		b.DropTable("accounts").IfExists().String(),
+	)
+
+	assert.Equal(
+		`DROP TABLE IF EXISTS "accounts" CASCADE`,
+		b.DropTable("accounts").IfExists().Cascade().String(),
+	)
+}
+
+func TestAlterTable(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	assert.Equal(
+		`ALTER TABLE "accounts" ADD COLUMN "nickname" text`,
+		b.AlterTable("accounts").AddColumn("nickname", "text").String(),
+	)
+
+	assert.Equal(
+		`ALTER TABLE "accounts" ADD COLUMN "active" BOOLEAN NOT NULL DEFAULT true, DROP COLUMN "nickname"`,
+		b.AlterTable("accounts").
+			AddColumn("active", "BOOLEAN", db.NotNull, db.ColumnDefault("true")).
+			DropColumn("nickname").
+			String(),
+	)
+}
+
+func TestComment(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	assert.Equal(
+		`/* checkout-service:recalc */ SELECT * FROM "artist" WHERE ("id" = $1)`,
+		b.SelectFrom("artist").Where("id", 1).Comment("checkout-service:recalc").String(),
+	)
+
+	assert.Equal(
+		`/* a?b */ INSERT INTO "artist" ("name") VALUES ($1)`,
+		b.InsertInto("artist").Values(map[string]string{"name": "Ozzie"}).Comment("a?b").String(),
+	)
+
+	assert.Equal(
+		`/* oops; DROP TABLE artist; */ UPDATE "artist" SET "name" = $1`,
+		b.Update("artist").Set("name", "Ozzie").Comment("oops*/; DROP TABLE artist; /*").String(),
+	)
+
+	assert.Equal(
+		`DELETE FROM "artist" WHERE (id = $1)`,
+		b.DeleteFrom("artist").Where("id = ?", 1).Comment("").String(),
+	)
+
+	// The comment is not part of the compiled statement, so it doesn't end up
+	// in subqueries.
+	q := b.SelectFrom("artist").Where("id IN ?", b.Select("id").From("publication").Comment("inner")).Comment("outer")
+	assert.Equal(
+		`/* outer */ SELECT * FROM "artist" WHERE (id IN (SELECT "id" FROM "publication"))`,
+		q.String(),
+	)
+}
+
+func TestIsRowDestination(t *testing.T) {
+	var (
+		item         struct{ Name string }
+		itemPtr      *struct{ Name string }
+		row          map[string]interface{}
+		name         string
+		id           int64
+		createdAt    time.Time
+		nullName     sql.NullString
+		data         []byte
+		createdAtPtr *time.Time
+		nullNamePtr  *sql.NullString
+	)
+
+	assert.True(t, isRowDestination(&item))
+	assert.True(t, isRowDestination(&itemPtr))
+	assert.True(t, isRowDestination(&row))
+
+	assert.False(t, isRowDestination(&name))
+	assert.False(t, isRowDestination(&id))
+	assert.False(t, isRowDestination(&createdAt))
+	assert.False(t, isRowDestination(&nullName))
+	assert.False(t, isRowDestination(&data))
+	assert.False(t, isRowDestination(&createdAtPtr))
+	assert.False(t, isRowDestination(&nullNamePtr))
+}
+
+func TestForceUTC(t *testing.T) {
+	loc := time.FixedZone("CST", -6*60*60)
+	at := time.Date(2019, 1, 1, 6, 0, 0, 0, loc)
+
+	var (
+		value  = at
+		ptr    = &at
+		nilPtr *time.Time
+		iface  interface{} = at
+		other  interface{} = "now"
+		values             = []interface{}{&value, &ptr, &nilPtr, &iface, &other}
+	)
+
+	settings := db.NewSettings()
+	forceUTC(settings, values)
+	assert.Equal(t, loc, value.Location())
+
+	settings.SetForceUTC(true)
+	forceUTC(settings, values)
+	assert.Equal(t, time.UTC, value.Location())
+	assert.Equal(t, time.UTC, ptr.Location())
+	assert.Equal(t, loc, at.Location())
+	assert.Nil(t, nilPtr)
+	assert.Equal(t, time.UTC, iface.(time.Time).Location())
+	assert.Equal(t, "now", other)
+	assert.True(t, at.Equal(value))
+}
+
+func TestOrderByAllowed(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	allowed := map[string]string{
+		"name":    "name",
+		"created": "a.created_at",
+	}
+
+	tests := []struct {
+		field, dir string
+		order      string
+		err        error
+	}{
+		{"name", "", "name", nil},
+		{"name", "ASC", "name", nil},
+		{"created", "desc", "-a.created_at", nil},
+		{"-created", "", "-a.created_at", nil},
+		{"-created", "asc", "", ErrOrderByNotAllowed},
+		{"password", "", "", ErrOrderByNotAllowed},
+		{"name; DROP TABLE artist", "", "", ErrOrderByNotAllowed},
+		{"name", "desc, password", "", ErrInvalidSortDirection},
+	}
+
+	for _, test := range tests {
+		order, err := OrderByAllowed(allowed, test.field, test.dir)
+		assert.Equal(t, test.err, err)
+		assert.Equal(t, test.order, order)
+	}
+
+	order, err := OrderByAllowed(allowed, "created", "desc")
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`SELECT * FROM "artist" AS "a" ORDER BY "a"."created_at" DESC`,
+		b.SelectFrom("artist AS a").OrderBy(order).String(),
+	)
+}
+
+func TestOrderByNulls(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	assert.Equal(t,
+		`SELECT * FROM "artist" ORDER BY "rating" DESC NULLS LAST, "name" ASC NULLS FIRST, "id" ASC`,
+		b.SelectFrom("artist").OrderBy(db.Desc("rating").NullsLast(), db.Asc("name").NullsFirst(), db.Asc("id")).String(),
+	)
+}
+
+func TestOrderByCollate(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	assert.Equal(t,
+		`SELECT * FROM "artist" ORDER BY "name" COLLATE "C" ASC`,
+		b.SelectFrom("artist").OrderBy(db.Col("name").Collate("C")).String(),
+	)
+
+	assert.Equal(t,
+		`SELECT * FROM "artist" ORDER BY "name" COLLATE "en_US.utf8" DESC NULLS LAST, "id" ASC`,
+		b.SelectFrom("artist").OrderBy(db.Desc("name").Collate("en_US.utf8").NullsLast(), "id").String(),
+	)
+
+	assert.Panics(t, func() {
+		_ = b.SelectFrom("artist").OrderBy(db.Col("name").Collate(`C" DESC; DROP TABLE "artist`)).String()
+	})
+}
+
+func TestFuncColumns(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	{
+		sel := b.Select(db.Func("coalesce", db.Col("nickname"), db.Col("artist.name"), "anonymous")).From("artist")
+		assert.Equal(t, `SELECT coalesce("nickname", "artist"."name", $1) FROM "artist"`, sel.String())
+		assert.Equal(t, []interface{}{"anonymous"}, sel.Arguments())
+	}
+
+	{
+		sel := b.SelectFrom("artist").Where(db.Func("lower", db.Col("name")), "bob")
+		assert.Equal(t, `SELECT * FROM "artist" WHERE (lower("name") = $1)`, sel.String())
+		assert.Equal(t, []interface{}{"bob"}, sel.Arguments())
+	}
+
+	{
+		sel := b.SelectFrom("artist").Where(db.Cond{
+			db.Func("substr", db.Col("name"), 1, 3): db.In([]string{"bob", "ali"}),
+		})
+		assert.Equal(t, `SELECT * FROM "artist" WHERE (substr("name", $1, $2) IN ($3, $4))`, sel.String())
+		assert.Equal(t, []interface{}{1, 3, "bob", "ali"}, sel.Arguments())
+	}
+
+	{
+		sel := b.SelectFrom("artist").OrderBy(db.Func("length", db.Func("trim", db.Col("name"))))
+		assert.Equal(t, `SELECT * FROM "artist" ORDER BY length(trim("name"))`, sel.String())
+	}
+
+	{
+		q := b.Update("artist").Set(map[string]interface{}{"name": db.Func("upper", db.Col("name"))}).Where("id", 1)
+		assert.Equal(t, `UPDATE "artist" SET "name" = upper("name") WHERE ("id" = $1)`, q.String())
+		assert.Equal(t, []interface{}{1}, q.Arguments())
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	{
+		q := b.Update("counters").Set(db.Cond{"n": db.Col("n").Add(1)}).Where("id", 7)
+		assert.Equal(t, `UPDATE "counters" SET "n" = "n" + $1 WHERE ("id" = $2)`, q.String())
+		assert.Equal(t, []interface{}{1, 7}, q.Arguments())
+	}
+
+	{
+		q := b.Update("counters").Set(map[string]interface{}{"n": db.Col("n").Sub(2).Mul(db.Col("factor"))})
+		assert.Equal(t, `UPDATE "counters" SET "n" = ("n" - $1) * "factor"`, q.String())
+		assert.Equal(t, []interface{}{2}, q.Arguments())
+	}
+
+	{
+		sel := b.Select(db.Concat(db.Col("first_name"), " ", db.Col("last_name"))).From("artist")
+		assert.Equal(t, `SELECT "first_name" || $1 || "last_name" FROM "artist"`, sel.String())
+		assert.Equal(t, []interface{}{" "}, sel.Arguments())
+	}
+
+	{
+		sel := b.SelectFrom("artist").Where("rating >", db.Col("min_rating").Add(1))
+		assert.Equal(t, `SELECT * FROM "artist" WHERE ("rating" > "min_rating" + $1)`, sel.String())
+		assert.Equal(t, []interface{}{1}, sel.Arguments())
+	}
+
+	{
+		sel := b.Select(db.Func("upper", db.Concat(db.Col("name"), "!"))).From("artist")
+		assert.Equal(t, `SELECT upper("name" || $1) FROM "artist"`, sel.String())
+	}
+
+	{
+		expr := unknownExpr{db.Col("n").Add(1)}
+
+		_, err := b.Select(expr).From("artist").(*selector).Compile()
+		assert.EqualError(t, err, "unsupported expression operator 9")
+
+		_, err = b.SelectFrom("artist").Where("rating >", expr).(*selector).Compile()
+		assert.EqualError(t, err, "unsupported expression operator 9")
+
+		_, err = b.Update("counters").Set(db.Cond{"n": expr}).(*updater).Compile()
+		assert.EqualError(t, err, "unsupported expression operator 9")
+	}
+}
+
+// unknownExpr is an expression with an operator that no template supports.
+type unknownExpr struct {
+	db.Expr
+}
+
+func (unknownExpr) Operator() db.ExprOperator {
+	return 9
+}
+
+func TestSelectLock(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	assert.Equal(t,
+		`SELECT * FROM "jobs" WHERE ("status" = $1) ORDER BY "id" ASC LIMIT 5 FOR UPDATE`,
+		b.SelectFrom("jobs").Where("status", "pending").OrderBy("id").Limit(5).ForUpdate().String(),
+	)
+
+	assert.Equal(t,
+		`SELECT * FROM "jobs" LIMIT 5 FOR UPDATE SKIP LOCKED`,
+		b.SelectFrom("jobs").Limit(5).ForUpdate().SkipLocked().String(),
+	)
+}
+
+func TestSelectSample(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	assert.Equal(t,
+		`SELECT * FROM "events" TABLESAMPLE SYSTEM (1.5) WHERE ("kind" = $1)`,
+		b.SelectFrom("events").Sample(1.5).Where("kind", "click").String(),
+	)
+
+	assert.Equal(t,
+		`SELECT * FROM "artist" ORDER BY random() LIMIT 10`,
+		b.SelectFrom("artist").OrderBy("name").Random().Limit(10).String(),
+	)
+
+	_, err := b.SelectFrom("events").Sample(0).(*selector).build()
+	assert.Equal(t, ErrInvalidSamplePercent, err)
+
+	_, err = b.SelectFrom("events").Sample(101).(*selector).build()
+	assert.Equal(t, ErrInvalidSamplePercent, err)
+
+	// Copy the exported fields only, the lock and the caches of a template
+	// must not be shared.
+	noSample := &exql.Template{}
+	src, dst := reflect.ValueOf(&testTemplate).Elem(), reflect.ValueOf(noSample).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if dst.Type().Field(i).PkgPath == "" {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	noSample.Cache = cache.NewCache()
+	noSample.SampleLayout, noSample.RandomFunction = "", ""
+	b = &sqlBuilder{t: newTemplateWithUtils(noSample)}
+
+	_, err = b.SelectFrom("events").Sample(10).(*selector).build()
+	assert.Equal(t, exql.ErrSampleUnsupported, err)
+
+	_, err = b.SelectFrom("events").Random().(*selector).build()
+	assert.Equal(t, exql.ErrRandomOrderUnsupported, err)
+}
+
+func TestSelectWithTotalCount(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	assert.Equal(t,
+		`SELECT *, COUNT(*) OVER () AS "total" FROM "artist" ORDER BY "name" ASC LIMIT 10 OFFSET 20`,
+		b.SelectFrom("artist").OrderBy("name").Limit(10).Offset(20).WithTotalCount("total").String(),
+	)
+
+	var total int
+	assert.Equal(t,
+		`SELECT "id", "name", COUNT(*) OVER () AS "total" FROM "artist" WHERE (name LIKE $1)`,
+		b.Select("id", "name").From("artist").Where("name LIKE ?", "A%").WithTotalCount("total", &total).String(),
+	)
+
+	sel := b.SelectFrom("artist").WithTotalCount("total")
+	assert.Equal(t,
+		`SELECT count(1) AS _t FROM "artist"`,
+		sel.(*selector).setColumns(db.Raw("count(1) AS _t")).String(),
+	)
+}
+
+func TestValuesList(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	scores := Values([][]interface{}{{1, 0.5}, {2, 0.8}}, []string{"id", "score"}).As("s")
+
+	{
+		q := b.Select("a.name", "s.score").From("artist AS a").Join(scores).On("s.id = a.id").Where("s.score > ?", 0.6)
+		assert.Equal(
+			`SELECT "a"."name", "s"."score" FROM "artist" AS "a" JOIN (VALUES (CAST($1 AS bigint), CAST($2 AS double precision)), ($3, $4)) AS "s" ("id", "score") ON (s.id = a.id) WHERE (s.score > $5)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{1, 0.5, 2, 0.8, 0.6}, q.Arguments())
+	}
+
+	{
+		q := b.Update("artist").Set("score = s.score").From(scores.Types("integer", "")).Where("artist.id = s.id")
+		assert.Equal(
+			`UPDATE "artist" SET "score" = s.score FROM (VALUES (CAST($1 AS integer), $2), ($3, $4)) AS "s" ("id", "score") WHERE (artist.id = s.id)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{1, 0.5, 2, 0.8}, q.Arguments())
+	}
+
+	assert.Equal(
+		`SELECT * FROM (VALUES (CAST($1 AS text), now())) AS "v" ("name", "at")`,
+		b.SelectFrom(Values([][]interface{}{{"Ozzie", db.Raw("now()")}}, []string{"name", "at"})).String(),
+	)
+
+	assert.Panics(func() {
+		_ = b.SelectFrom(Values(nil, []string{"id"})).String()
+	})
+
+	assert.Panics(func() {
+		_ = b.SelectFrom(Values([][]interface{}{{1, 2}, {3}}, []string{"a", "b"})).String()
+	})
+}
+
+func TestReservedWordColumns(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	assert.Equal(t,
+		`SELECT "order", "user"."select" FROM "group" WHERE ("order" = $1) ORDER BY "order" DESC`,
+		b.Select("order", "user.select").From("group").Where(db.Cond{"order": 1}).OrderBy("-order").String(),
+	)
+	assert.Equal(t,
+		`INSERT INTO "group" ("order", "where") VALUES ($1, $2)`,
+		b.InsertInto("group").Columns("order", "where").Values(1, 2).String(),
+	)
+	assert.Equal(t,
+		`UPDATE "group" SET "order" = $1 WHERE ("limit" > $2)`,
+		b.Update("group").Set("order", 1).Where(db.Cond{"limit >": 2}).String(),
+	)
+
+	m := &sqlBuilder{t: newTemplateWithUtils(testTemplate.WithMinimalQuoting("order", "group", "select", "user", "where", "limit"))}
+
+	assert.Equal(t,
+		`SELECT "order", "user"."select", name, "Name" FROM "group" WHERE ("order" = $1) ORDER BY "order" DESC`,
+		m.Select("order", "user.select", "name", "Name").From("group").Where(db.Cond{"order": 1}).OrderBy("-order").String(),
+	)
+	assert.Equal(t,
+		`UPDATE "group" SET "order" = $1 WHERE ("limit" > $2 AND id = $3)`,
+		m.Update("group").Set("order", 1).Where(db.Cond{"limit >": 2}, db.Cond{"id": 3}).String(),
+	)
+}
+
+func TestBaseQueryReuse(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	{
+		base := b.SelectFrom("artist").Where("active", true)
+
+		top := base.OrderBy("-rating").Limit(10)
+		newest := base.And("id >", 5).OrderBy("-created_at")
+
+		assert.Equal(t, `SELECT * FROM "artist" WHERE ("active" = $1) ORDER BY "rating" DESC LIMIT 10`, top.String())
+		assert.Equal(t, []interface{}{true}, top.Arguments())
+
+		assert.Equal(t, `SELECT * FROM "artist" WHERE ("active" = $1 AND "id" > $2) ORDER BY "created_at" DESC`, newest.String())
+		assert.Equal(t, []interface{}{true, 5}, newest.Arguments())
+
+		assert.Equal(t, `SELECT * FROM "artist" WHERE ("active" = $1)`, base.String())
+		assert.Equal(t, []interface{}{true}, base.Arguments())
+	}
+
+	{
+		base := b.Update("artist").Set("name", "Ozzy")
+
+		one := base.Where("id", 1)
+		all := base.Set("active", false)
+
+		assert.Equal(t, `UPDATE "artist" SET "name" = $1 WHERE ("id" = $2)`, one.String())
+		assert.Equal(t, `UPDATE "artist" SET "name" = $1, "active" = $2`, all.String())
+		assert.Equal(t, `UPDATE "artist" SET "name" = $1`, base.String())
+		assert.Equal(t, []interface{}{"Ozzy"}, base.Arguments())
+	}
+
+	{
+		base := b.DeleteFrom("artist").Where("active", false)
+
+		one := base.And("id", 1)
+		other := base.Where("id", 2)
+
+		assert.Equal(t, `DELETE FROM "artist" WHERE ("active" = $1 AND "id" = $2)`, one.String())
+		assert.Equal(t, `DELETE FROM "artist" WHERE ("id" = $1)`, other.String())
+		assert.Equal(t, `DELETE FROM "artist" WHERE ("active" = $1)`, base.String())
+		assert.Equal(t, []interface{}{false}, base.Arguments())
+	}
+}
+
 func TestPaginate(t *testing.T) {
 	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
 	assert := assert.New(t)
@@ -30,6 +30,9 @@ var comparisonOperators = map[db.ComparisonOperator]string{
 	db.ComparisonOperatorLike:    "LIKE",
 	db.ComparisonOperatorNotLike: "NOT LIKE",
 
+	db.ComparisonOperatorILike:    "ILIKE",
+	db.ComparisonOperatorNotILike: "NOT ILIKE",
+
 	db.ComparisonOperatorRegExp:    "REGEXP",
 	db.ComparisonOperatorNotRegExp: "NOT REGEXP",
 }
@@ -52,6 +55,16 @@ func (ow *operatorWrapper) cmp() db.Comparison {
 	}
 
 	if ow.cv.Operator != "" {
+		// Lists given to IN and NOT IN, like in db.Cond{"id NOT IN": ids},
+		// are compiled like the ones given to db.In and db.NotIn.
+		if args, isSlice := toInterfaceArguments(ow.v); isSlice {
+			switch strings.Join(strings.Fields(strings.ToUpper(ow.cv.Operator)), " ") {
+			case "IN":
+				return db.In(args)
+			case "NOT IN":
+				return db.NotIn(args)
+			}
+		}
 		return db.Op(ow.cv.Operator, ow.v)
 	}
 
@@ -91,6 +104,11 @@ func (ow *operatorWrapper) preprocess() (string, []interface{}) {
 	case db.ComparisonOperatorIn, db.ComparisonOperatorNotIn:
 		values := c.Value().([]interface{})
 		if len(values) < 1 {
+			if c.Operator() == db.ComparisonOperatorNotIn {
+				// Nothing is in an empty list, while "NOT IN (NULL)" is never
+				// true.
+				return "1 " + ow.tu.comparisonOperatorMapper(db.ComparisonOperatorEqual) + " 1", nil
+			}
 			placeholder, args = "(NULL)", []interface{}{}
 			break
 		}
@@ -115,13 +133,34 @@ func (ow *operatorWrapper) preprocess() (string, []interface{}) {
 		args = []interface{}{v}
 	}
 
+	escape := ""
+	if pattern, ok := c.Value().(db.LikePattern); ok {
+		switch c.Operator() {
+		case db.ComparisonOperatorLike, db.ComparisonOperatorNotLike,
+			db.ComparisonOperatorILike, db.ComparisonOperatorNotILike:
+			// Patterns made by db.EscapeLike declare their escape character,
+			// other operators take them as plain strings.
+			escape = " ESCAPE " + likeEscape(ow.tu.Template)
+		}
+		args = []interface{}{string(pattern)}
+	}
+
 	if args == nil {
 		args = []interface{}{c.Value()}
 	}
 
 	if strings.Contains(op, ":column") {
-		return strings.Replace(op, ":column", column, -1), args
+		return strings.Replace(op, ":column", column, -1) + escape, args
 	}
 
-	return column + " " + op + " " + placeholder, args
+	return column + " " + op + " " + placeholder + escape, args
+}
+
+// likeEscape returns the literal that declares the backslash as the escape
+// character of the patterns made by db.EscapeLike.
+func likeEscape(layout *exql.Template) string {
+	if layout.BackslashEscapes {
+		return `'\\'`
+	}
+	return `'\'`
 }
@@ -2,6 +2,7 @@ package sqlbuilder
 
 import (
 	"database/sql/driver"
+	"fmt"
 	"reflect"
 	"strings"
 
@@ -13,16 +14,22 @@ var (
 	sqlDefault = exql.RawValue(`DEFAULT`)
 )
 
-func expandQuery(in string, args []interface{}, fn func(interface{}) (string, []interface{})) (string, []interface{}) {
+// expandQuery replaces the placeholders of in with what fn returns for their
+// arguments, literals are told apart the way the given template does.
+func expandQuery(layout *exql.Template, in string, args []interface{}, fn func(*exql.Template, interface{}) (string, []interface{})) (string, []interface{}) {
 	argn := 0
 	argx := make([]interface{}, 0, len(args))
 	for i := 0; i < len(in); i++ {
+		if n := layout.LiteralLen(in, i); n > 0 {
+			i += n - 1
+			continue
+		}
 		if in[i] != '?' {
 			continue
 		}
 		if len(args) > argn {
-			k, values := fn(args[argn])
-			k, values = expandQuery(k, values, fn)
+			k, values := fn(layout, args[argn])
+			k, values = expandQuery(layout, k, values, fn)
 
 			if k != "" {
 				in = in[:i] + k + in[i+1:]
@@ -56,9 +63,10 @@ func toInterfaceArguments(value interface{}) (args []interface{}, isSlice bool)
 	if v.Type().Kind() == reflect.Slice {
 		var i, total int
 
-		// Byte slice gets transformed into a string.
+		// Byte slices, including named ones, are single values, like bytea or
+		// blobs, not lists.
 		if v.Type().Elem().Kind() == reflect.Uint8 {
-			return []interface{}{string(value.([]byte))}, false
+			return []interface{}{value}, false
 		}
 
 		total = v.Len()
@@ -93,6 +101,10 @@ func toColumnsValuesAndArguments(columnNames []string, columnValues []interface{
 		switch v := columnValues[i].(type) {
 		case *exql.Raw, exql.Raw:
 			values.Values = append(values.Values, sqlDefault)
+		case db.RawValue:
+			// Raw values, like db.Default, are put in place.
+			values.Values = append(values.Values, exql.RawValue(v.Raw()))
+			arguments = append(arguments, v.Arguments()...)
 		case *exql.Value:
 			// Adding value.
 			values.Values = append(values.Values, v)
@@ -109,7 +121,12 @@ func toColumnsValuesAndArguments(columnNames []string, columnValues []interface{
 	return columns, values, arguments, nil
 }
 
-func preprocessFn(arg interface{}) (string, []interface{}) {
+func preprocessFn(layout *exql.Template, arg interface{}) (string, []interface{}) {
+	if _, ok := arg.(db.VerbatimValue); ok {
+		// Verbatim values are given to the driver as they are, even lists.
+		return "", []interface{}{arg}
+	}
+
 	values, isSlice := toInterfaceArguments(arg)
 
 	if isSlice {
@@ -122,7 +139,7 @@ func preprocessFn(arg interface{}) (string, []interface{}) {
 	if len(values) == 1 {
 		switch t := arg.(type) {
 		case db.RawValue:
-			return Preprocess(t.Raw(), t.Arguments())
+			return PreprocessWithTemplate(layout, t.Raw(), t.Arguments())
 		case compilable:
 			c, err := t.Compile()
 			if err == nil {
@@ -137,8 +154,77 @@ func preprocessFn(arg interface{}) (string, []interface{}) {
 	return "", []interface{}{arg}
 }
 
+// functionFragment converts the given function into an exql fragment, column
+// references are quoted as identifiers and any other argument is bound as a
+// placeholder.
+func functionFragment(fn db.Function) (exql.Fragment, []interface{}, error) {
+	call := &exql.FunctionCall{Name: fn.Name()}
+	args := []interface{}{}
+
+	for _, arg := range fn.Arguments() {
+		f, a, err := operandFragment(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		call.Args = append(call.Args, f)
+		args = append(args, a...)
+	}
+
+	return call, args, nil
+}
+
+// exprFragment converts the given expression into an exql fragment.
+func exprFragment(expr db.Expr) (exql.Fragment, []interface{}, error) {
+	operands := make([]exql.Fragment, 0, len(expr.Operands()))
+	args := []interface{}{}
+
+	for _, operand := range expr.Operands() {
+		f, a, err := operandFragment(operand)
+		if err != nil {
+			return nil, nil, err
+		}
+		operands = append(operands, f)
+		args = append(args, a...)
+	}
+
+	switch expr.Operator() {
+	case db.ExprOperatorAdd:
+		return &exql.Operation{Operator: "+", Operands: operands}, args, nil
+	case db.ExprOperatorSub:
+		return &exql.Operation{Operator: "-", Operands: operands}, args, nil
+	case db.ExprOperatorMul:
+		return &exql.Operation{Operator: "*", Operands: operands}, args, nil
+	case db.ExprOperatorConcat:
+		return &exql.Concatenation{Operands: operands}, args, nil
+	}
+
+	return nil, nil, fmt.Errorf(ErrUnsupportedExprOperator.Error(), expr.Operator())
+}
+
+// operandFragment converts a function argument or expression operand into an
+// exql fragment.
+func operandFragment(operand interface{}) (exql.Fragment, []interface{}, error) {
+	switch v := operand.(type) {
+	case db.Column:
+		return exql.ColumnWithName(v.ColumnName()), nil, nil
+	case db.Function:
+		return functionFragment(v)
+	case db.Expr:
+		return exprFragment(v)
+	}
+	q, a := Preprocess("?", []interface{}{operand})
+	return exql.RawValue(q), a, nil
+}
+
 // Preprocess expands arguments that needs to be expanded and compiles a query
 // into a single string.
 func Preprocess(in string, args []interface{}) (string, []interface{}) {
-	return expandQuery(in, args, preprocessFn)
+	return PreprocessWithTemplate(nil, in, args)
+}
+
+// PreprocessWithTemplate works like Preprocess, strings within the query are
+// told apart the way the given template does, see exql.Template's
+// BackslashEscapes.
+func PreprocessWithTemplate(t *exql.Template, in string, args []interface{}) (string, []interface{}) {
+	return expandQuery(t, in, args, preprocessFn)
 }
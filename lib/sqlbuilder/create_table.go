@@ -0,0 +1,274 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/immutable"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/reflectx"
+)
+
+// columnTypes maps Go types to generic column types, adapters translate the
+// generic types into their own with exql.Template.ColumnTypes.
+var columnTypes = map[string]string{
+	"bool":            "BOOLEAN",
+	"int":             "BIGINT",
+	"int8":            "SMALLINT",
+	"int16":           "SMALLINT",
+	"int32":           "INTEGER",
+	"int64":           "BIGINT",
+	"uint":            "BIGINT",
+	"uint8":           "SMALLINT",
+	"uint16":          "INTEGER",
+	"uint32":          "BIGINT",
+	"uint64":          "BIGINT",
+	"float32":         "REAL",
+	"float64":         "DOUBLE PRECISION",
+	"string":          "TEXT",
+	"[]uint8":         "BLOB",
+	"time.Time":       "TIMESTAMP",
+	"sql.NullBool":    "BOOLEAN",
+	"sql.NullInt64":   "BIGINT",
+	"sql.NullFloat64": "DOUBLE PRECISION",
+	"sql.NullString":  "TEXT",
+}
+
+type tableCreatorQuery struct {
+	table       string
+	ifNotExists bool
+	temporary   bool
+
+	columns    []exql.Fragment
+	primaryKey []string
+}
+
+func (cq *tableCreatorQuery) statement() *exql.Statement {
+	return &exql.Statement{
+		Type:        exql.CreateTable,
+		Table:       exql.TableWithName(cq.table),
+		IfNotExists: cq.ifNotExists,
+		Temporary:   cq.temporary,
+		Definitions: &exql.ColumnDefinitions{
+			Columns:    cq.columns,
+			PrimaryKey: cq.primaryKey,
+		},
+	}
+}
+
+// columnType returns the generic column type of t and whether the column
+// accepts NULL values.
+func columnType(t reflect.Type) (string, bool) {
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+	if strings.HasPrefix(t.String(), "sql.Null") {
+		nullable = true
+	}
+	if columnType, ok := columnTypes[t.String()]; ok {
+		return columnType, nullable
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return columnTypes["[]uint8"], nullable
+	}
+	// Named types, like "type Status string".
+	return columnTypes[t.Kind().String()], nullable
+}
+
+func (cq *tableCreatorQuery) setStruct(structValue interface{}) error {
+	t := reflect.TypeOf(structValue)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return ErrExpectingStruct
+	}
+
+	typeMap := mapper.TypeMap(t)
+
+	fields := []*reflectx.FieldInfo{}
+	primaryKey := []string{}
+	for _, fi := range typeMap.Index {
+		if typeMap.Names[fi.Path] != fi || strings.Contains(fi.Path, ".") {
+			// Embedded structs and fields of nested structs.
+			continue
+		}
+		if _, ok := fi.Options["pk"]; ok {
+			primaryKey = append(primaryKey, fi.Name)
+		}
+		fields = append(fields, fi)
+	}
+	if len(primaryKey) == 0 {
+		if _, ok := typeMap.Names["id"]; ok {
+			primaryKey = []string{"id"}
+		}
+	}
+
+	columns := make([]exql.Fragment, 0, len(fields))
+	for _, fi := range fields {
+		colType, nullable := columnType(fi.Field.Type)
+
+		isPrimaryKey := len(primaryKey) == 1 && primaryKey[0] == fi.Name
+		if isPrimaryKey && (colType == "INTEGER" || colType == "SMALLINT") {
+			colType = "SERIAL"
+		} else if isPrimaryKey && colType == "BIGINT" {
+			colType = "BIGSERIAL"
+		}
+
+		if override := fi.Options["type"]; override != "" {
+			colType = override
+		}
+		if colType == "" {
+			return fmt.Errorf("can't infer a column type for %q (%v), set one with the \"type\" tag option", fi.Name, fi.Field.Type)
+		}
+
+		def := &exql.ColumnDefinition{Name: fi.Name, Type: colType}
+		if isPrimaryKey {
+			def.Constraints = []string{"PRIMARY KEY"}
+		} else if !nullable {
+			def.Constraints = []string{"NOT NULL"}
+		}
+		columns = append(columns, def)
+	}
+
+	cq.columns = append(cq.columns, columns...)
+	if len(primaryKey) > 1 {
+		cq.primaryKey = primaryKey
+	}
+	return nil
+}
+
+// columnDefinition returns the definition of a column with the given type and
+// constraints.
+func columnDefinition(name string, columnType string, constraints []db.ColumnConstraint) *exql.ColumnDefinition {
+	def := &exql.ColumnDefinition{Name: name, Type: columnType}
+	for i := range constraints {
+		def.Constraints = append(def.Constraints, string(constraints[i]))
+	}
+	return def
+}
+
+type tableCreator struct {
+	builder *sqlBuilder
+
+	fn   func(*tableCreatorQuery) error
+	prev *tableCreator
+}
+
+var _ = immutable.Immutable(&tableCreator{})
+
+func (tc *tableCreator) SQLBuilder() *sqlBuilder {
+	if tc.prev == nil {
+		return tc.builder
+	}
+	return tc.prev.SQLBuilder()
+}
+
+func (tc *tableCreator) template() *exql.Template {
+	return tc.SQLBuilder().t.Template
+}
+
+func (tc *tableCreator) String() string {
+	s, err := tc.Compile()
+	if err != nil {
+		panic(err.Error())
+	}
+	return prepareQueryForDisplay(tc.template(), s)
+}
+
+func (tc *tableCreator) setTable(table string) *tableCreator {
+	return tc.frame(func(cq *tableCreatorQuery) error {
+		cq.table = table
+		return nil
+	})
+}
+
+func (tc *tableCreator) frame(fn func(*tableCreatorQuery) error) *tableCreator {
+	return &tableCreator{prev: tc, fn: fn}
+}
+
+func (tc *tableCreator) Struct(structValue interface{}) TableCreator {
+	return tc.frame(func(cq *tableCreatorQuery) error {
+		return cq.setStruct(structValue)
+	})
+}
+
+func (tc *tableCreator) Column(name string, columnType string, constraints ...db.ColumnConstraint) TableCreator {
+	return tc.frame(func(cq *tableCreatorQuery) error {
+		cq.columns = append(cq.columns, columnDefinition(name, columnType, constraints))
+		return nil
+	})
+}
+
+func (tc *tableCreator) IfNotExists() TableCreator {
+	return tc.frame(func(cq *tableCreatorQuery) error {
+		cq.ifNotExists = true
+		return nil
+	})
+}
+
+func (tc *tableCreator) Temporary() TableCreator {
+	return tc.frame(func(cq *tableCreatorQuery) error {
+		cq.temporary = true
+		return nil
+	})
+}
+
+func (tc *tableCreator) Exec() (sql.Result, error) {
+	return tc.ExecContext(tc.SQLBuilder().sess.Context())
+}
+
+func (tc *tableCreator) ExecContext(ctx context.Context) (sql.Result, error) {
+	cq, err := tc.build()
+	if err != nil {
+		return nil, err
+	}
+	return tc.SQLBuilder().sess.StatementExec(ctx, cq.statement())
+}
+
+func (tc *tableCreator) statement() (*exql.Statement, error) {
+	cq, err := tc.build()
+	if err != nil {
+		return nil, err
+	}
+	return cq.statement(), nil
+}
+
+func (tc *tableCreator) build() (*tableCreatorQuery, error) {
+	cq, err := immutable.FastForward(tc)
+	if err != nil {
+		return nil, err
+	}
+	return cq.(*tableCreatorQuery), nil
+}
+
+func (tc *tableCreator) Compile() (string, error) {
+	s, err := tc.statement()
+	if err != nil {
+		return "", err
+	}
+	return s.Compile(tc.template())
+}
+
+func (tc *tableCreator) Prev() immutable.Immutable {
+	if tc == nil {
+		return nil
+	}
+	return tc.prev
+}
+
+func (tc *tableCreator) Fn(in interface{}) error {
+	if tc.fn == nil {
+		return nil
+	}
+	return tc.fn(in.(*tableCreatorQuery))
+}
+
+func (tc *tableCreator) Base() interface{} {
+	return &tableCreatorQuery{}
+}
@@ -0,0 +1,204 @@
+package dbtest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter"
+)
+
+var (
+	errZeroPrimaryKey = errors.New("Primary key %q of table %q is zero or unset")
+	errExpectingPtr   = errors.New("Expecting a pointer but got %T")
+)
+
+// collection is a table of a session.
+type collection struct {
+	sess *Database
+	name string
+}
+
+var _ = db.Collection(&collection{})
+
+func (c *collection) Name() string {
+	return c.name
+}
+
+// Exists returns true once the table was written to.
+func (c *collection) Exists() bool {
+	c.sess.tables.mu.Lock()
+	defer c.sess.tables.mu.Unlock()
+	return c.sess.tables.table(c.name, false) != nil
+}
+
+func (c *collection) Insert(item interface{}) (interface{}, error) {
+	pk := c.sess.primaryKeys(c.name)
+	values, err := toRow(item, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	c.sess.tables.mu.Lock()
+	key, err := c.sess.tables.table(c.name, true).insert(values, pk)
+	c.sess.tables.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	c.sess.emit(db.Event{Operation: db.OperationInsert, Table: c.name, Keys: []interface{}{key}})
+	return key, nil
+}
+
+func (c *collection) InsertReturning(item interface{}) error {
+	if item == nil || reflect.TypeOf(item).Kind() != reflect.Ptr {
+		return fmt.Errorf(errExpectingPtr.Error(), item)
+	}
+	key, err := c.Insert(item)
+	if err != nil {
+		return err
+	}
+	return c.Find(key).One(item)
+}
+
+func (c *collection) UpdateReturning(item interface{}) error {
+	if item == nil || reflect.TypeOf(item).Kind() != reflect.Ptr {
+		return fmt.Errorf(errExpectingPtr.Error(), item)
+	}
+	cond, err := c.keyCond(item)
+	if err != nil {
+		return err
+	}
+	if err := c.Find(cond).Update(item); err != nil {
+		return err
+	}
+	return c.Find(cond).One(item)
+}
+
+func (c *collection) Update(item interface{}) error {
+	cond, err := c.keyCond(item)
+	if err != nil {
+		return err
+	}
+	return c.Find(cond).Update(item)
+}
+
+func (c *collection) Delete(item interface{}) error {
+	cond, err := c.keyCond(item)
+	if err != nil {
+		return err
+	}
+	return c.Find(cond).Delete()
+}
+
+// keyCond returns a db.Cond with the primary keys of the given map or struct.
+func (c *collection) keyCond(item interface{}) (db.Cond, error) {
+	values, err := toValues(item)
+	if err != nil {
+		return nil, err
+	}
+	cond := db.Cond{}
+	for _, column := range c.sess.primaryKeys(c.name) {
+		v, ok := values[column]
+		if !ok || isZero(v) {
+			return nil, fmt.Errorf(errZeroPrimaryKey.Error(), column, c.name)
+		}
+		cond[column] = v
+	}
+	return cond, nil
+}
+
+func (c *collection) Find(conds ...interface{}) db.Result {
+	res := &result{sess: c.sess, table: c.name}
+
+	if len(conds) == 1 {
+		if _, ok := conds[0].(db.Compound); !ok {
+			if reflect.Indirect(reflect.ValueOf(conds[0])).Kind() == reflect.Struct {
+				cond, err := c.keyCond(conds[0])
+				if err != nil {
+					res.err = err
+					return res
+				}
+				conds[0] = cond
+			} else if pk := c.sess.primaryKeys(c.name); len(pk) == 1 && sqladapter.IsKeyValue(conds[0]) {
+				conds[0] = db.Cond{pk[0]: db.Eq(conds[0])}
+			}
+		}
+	}
+	res.conds, res.err = toCompounds(conds)
+
+	c.sess.shared.mu.Lock()
+	res.orderBy = c.sess.shared.orders[c.name]
+	c.sess.shared.mu.Unlock()
+
+	return res
+}
+
+func (c *collection) FindOrCreate(cond db.Cond, item interface{}) (bool, error) {
+	if item == nil || reflect.TypeOf(item).Kind() != reflect.Ptr {
+		return false, fmt.Errorf(errExpectingPtr.Error(), item)
+	}
+
+	pk := c.sess.primaryKeys(c.name)
+	values, err := toRow(item, pk)
+	if err != nil {
+		return false, err
+	}
+	for key, value := range cond {
+		column, ok := key.(string)
+		if !ok {
+			return false, db.ErrUnsupported
+		}
+		if cmp, ok := value.(db.Comparison); ok {
+			if cmp.Operator() != db.ComparisonOperatorEqual {
+				return false, db.ErrUnsupported
+			}
+			value = cmp.Value()
+		}
+		values[column] = normalize(value)
+	}
+
+	res := c.Find(cond).(*result)
+
+	c.sess.tables.mu.Lock()
+	t := c.sess.tables.table(c.name, true)
+	rows, err := res.rows(t)
+	if err != nil || len(rows) > 0 {
+		c.sess.tables.mu.Unlock()
+		if err != nil {
+			return false, err
+		}
+		return false, scanOne(t, rows[0], item)
+	}
+	key, err := t.insert(values, pk)
+	c.sess.tables.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	c.sess.emit(db.Event{Operation: db.OperationInsert, Table: c.name, Keys: []interface{}{key}})
+	return true, c.Find(key).One(item)
+}
+
+func (c *collection) SetDefaultOrderBy(fields ...interface{}) {
+	c.sess.shared.mu.Lock()
+	defer c.sess.shared.mu.Unlock()
+	if len(fields) == 0 {
+		delete(c.sess.shared.orders, c.name)
+		return
+	}
+	c.sess.shared.orders[c.name] = append([]interface{}(nil), fields...)
+}
+
+// Truncate removes all the rows of the table and resets its keys.
+func (c *collection) Truncate() error {
+	c.sess.tables.mu.Lock()
+	if t := c.sess.tables.table(c.name, false); t != nil {
+		t.rows, t.seq = nil, 0
+	}
+	c.sess.tables.mu.Unlock()
+
+	c.sess.emit(db.Event{Operation: db.OperationDelete, Table: c.name})
+	return nil
+}
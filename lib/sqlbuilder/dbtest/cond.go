@@ -0,0 +1,384 @@
+package dbtest
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+)
+
+// keyOperators maps the operators that can be given in the keys of a
+// db.Cond, like "age >", to comparison operators.
+var keyOperators = map[string]db.ComparisonOperator{
+	"":         db.ComparisonOperatorEqual,
+	"=":        db.ComparisonOperatorEqual,
+	"==":       db.ComparisonOperatorEqual,
+	"<>":       db.ComparisonOperatorNotEqual,
+	"!=":       db.ComparisonOperatorNotEqual,
+	"<":        db.ComparisonOperatorLessThan,
+	">":        db.ComparisonOperatorGreaterThan,
+	"<=":       db.ComparisonOperatorLessThanOrEqualTo,
+	">=":       db.ComparisonOperatorGreaterThanOrEqualTo,
+	"IN":       db.ComparisonOperatorIn,
+	"NOT IN":   db.ComparisonOperatorNotIn,
+	"IS":       db.ComparisonOperatorIs,
+	"IS NOT":   db.ComparisonOperatorIsNot,
+	"LIKE":     db.ComparisonOperatorLike,
+	"NOT LIKE": db.ComparisonOperatorNotLike,
+}
+
+// matches reports whether r satisfies all of the given conditions, as given
+// to Find or Where.
+func matches(r row, conds []interface{}) (bool, error) {
+	conds, err := toCompounds(conds)
+	if err != nil {
+		return false, err
+	}
+	for _, cond := range conds {
+		ok, err := matchCompound(r, cond.(db.Compound))
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// toCompounds turns the conditions given to Find or Where into compounds. A
+// column, or a column and an operator, followed by a value is turned into a
+// db.Cond, other strings are raw SQL, which is not supported.
+func toCompounds(conds []interface{}) ([]interface{}, error) {
+	if len(conds) == 0 {
+		return nil, nil
+	}
+	if column, ok := conds[0].(string); ok {
+		if len(conds) != 2 || strings.Contains(column, "?") {
+			return nil, db.ErrUnsupported
+		}
+		return []interface{}{db.Cond{column: conds[1]}}, nil
+	}
+	for _, cond := range conds {
+		if _, ok := cond.(db.Compound); !ok {
+			return nil, db.ErrUnsupported
+		}
+	}
+	return conds, nil
+}
+
+func matchCompound(r row, c db.Compound) (bool, error) {
+	switch c := c.(type) {
+	case db.RawValue:
+		return false, db.ErrUnsupported
+	case db.Cond:
+		for _, key := range c.Keys() {
+			ok, err := matchConstraint(r, key, c[key])
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	or := c.Operator() == db.OperatorOr
+	for _, sentence := range c.Sentences() {
+		ok, err := matchCompound(r, sentence)
+		if err != nil {
+			return false, err
+		}
+		if ok == or {
+			return or, nil
+		}
+	}
+	return !or, nil
+}
+
+func matchConstraint(r row, key interface{}, value interface{}) (bool, error) {
+	s, ok := key.(string)
+	if !ok {
+		return false, db.ErrUnsupported
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return false, db.ErrUnsupported
+	}
+	column := fields[0]
+
+	op, ok := keyOperators[strings.ToUpper(strings.Join(fields[1:], " "))]
+	if !ok {
+		return false, db.ErrUnsupported
+	}
+	if cmp, ok := value.(db.Comparison); ok {
+		if len(fields) > 1 {
+			return false, db.ErrUnsupported
+		}
+		op, value = cmp.Operator(), cmp.Value()
+	}
+	if err := checkValue(value); err != nil {
+		return false, err
+	}
+
+	return match(normalize(r[column]), op, normalize(value))
+}
+
+// match compares a value of a row with the value of a condition like a
+// database would: NULL values only match IS and IS NOT.
+func match(v interface{}, op db.ComparisonOperator, want interface{}) (bool, error) {
+	switch op {
+	case db.ComparisonOperatorEqual, db.ComparisonOperatorNotEqual:
+		if want == nil {
+			return (v == nil) == (op == db.ComparisonOperatorEqual), nil
+		}
+		if isList(want) {
+			if op == db.ComparisonOperatorEqual {
+				op = db.ComparisonOperatorIn
+			} else {
+				op = db.ComparisonOperatorNotIn
+			}
+		}
+	case db.ComparisonOperatorIs, db.ComparisonOperatorIsNot:
+		var same bool
+		if want == nil || v == nil {
+			same = want == v
+		} else {
+			c, err := compare(v, want)
+			if err != nil {
+				return false, err
+			}
+			same = c == 0
+		}
+		return same == (op == db.ComparisonOperatorIs), nil
+	}
+
+	switch op {
+	case db.ComparisonOperatorIn, db.ComparisonOperatorNotIn,
+		db.ComparisonOperatorBetween, db.ComparisonOperatorNotBetween,
+		db.ComparisonOperatorLike, db.ComparisonOperatorNotLike:
+	default:
+		if _, ok := comparisons[op]; !ok {
+			return false, db.ErrUnsupported
+		}
+	}
+	if v == nil {
+		return false, nil
+	}
+
+	switch op {
+	case db.ComparisonOperatorIn, db.ComparisonOperatorNotIn:
+		if !isList(want) {
+			return false, fmt.Errorf("dbtest: expecting a list of values, got %T", want)
+		}
+		in := false
+		list := reflect.ValueOf(want)
+		for i := 0; i < list.Len() && !in; i++ {
+			item := normalize(list.Index(i).Interface())
+			if item == nil {
+				continue
+			}
+			c, err := compare(v, item)
+			if err != nil {
+				return false, err
+			}
+			in = c == 0
+		}
+		return in == (op == db.ComparisonOperatorIn), nil
+
+	case db.ComparisonOperatorBetween, db.ComparisonOperatorNotBetween:
+		bounds, ok := want.([]interface{})
+		if !ok || len(bounds) != 2 {
+			return false, fmt.Errorf("dbtest: expecting two bounds, got %v", want)
+		}
+		lo, err := compare(v, normalize(bounds[0]))
+		if err != nil {
+			return false, err
+		}
+		hi, err := compare(v, normalize(bounds[1]))
+		if err != nil {
+			return false, err
+		}
+		return (lo >= 0 && hi <= 0) == (op == db.ComparisonOperatorBetween), nil
+
+	case db.ComparisonOperatorLike, db.ComparisonOperatorNotLike:
+		s, ok := toString(v)
+		pattern, patternOK := toString(want)
+		if !ok || !patternOK {
+			return false, fmt.Errorf("dbtest: can't match %T with LIKE %T", v, want)
+		}
+		return likePattern(pattern).MatchString(s) == (op == db.ComparisonOperatorLike), nil
+	}
+
+	if want == nil {
+		return false, nil
+	}
+	c, err := compare(v, want)
+	if err != nil {
+		return false, err
+	}
+	return comparisons[op](c), nil
+}
+
+// comparisons tells whether the result of compare satisfies an operator.
+var comparisons = map[db.ComparisonOperator]func(c int) bool{
+	db.ComparisonOperatorEqual:                func(c int) bool { return c == 0 },
+	db.ComparisonOperatorNotEqual:             func(c int) bool { return c != 0 },
+	db.ComparisonOperatorLessThan:             func(c int) bool { return c < 0 },
+	db.ComparisonOperatorBefore:               func(c int) bool { return c < 0 },
+	db.ComparisonOperatorGreaterThan:          func(c int) bool { return c > 0 },
+	db.ComparisonOperatorAfter:                func(c int) bool { return c > 0 },
+	db.ComparisonOperatorLessThanOrEqualTo:    func(c int) bool { return c <= 0 },
+	db.ComparisonOperatorOnOrBefore:           func(c int) bool { return c <= 0 },
+	db.ComparisonOperatorGreaterThanOrEqualTo: func(c int) bool { return c >= 0 },
+	db.ComparisonOperatorOnOrAfter:            func(c int) bool { return c >= 0 },
+}
+
+// likePattern turns a LIKE pattern into a regular expression, a backslash
+// escapes the character that follows, like db.EscapeLike does.
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile("(?s)" + b.String())
+}
+
+// normalize dereferences pointers and calls Value on driver.Valuers, so
+// values compare like the ones the database would get.
+func normalize(v interface{}) interface{} {
+	for {
+		if valuer, ok := v.(driver.Valuer); ok {
+			rv := reflect.ValueOf(v)
+			if rv.Kind() == reflect.Ptr && rv.IsNil() {
+				return nil
+			}
+			value, err := valuer.Value()
+			if err != nil {
+				return v
+			}
+			v = value
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Ptr {
+			return v
+		}
+		if rv.IsNil() {
+			return nil
+		}
+		v = rv.Elem().Interface()
+	}
+}
+
+func isList(v interface{}) bool {
+	if _, ok := v.([]byte); ok {
+		return false
+	}
+	kind := reflect.ValueOf(v).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+func isZero(v interface{}) bool {
+	v = normalize(v)
+	if v == nil {
+		return true
+	}
+	return reflect.DeepEqual(v, reflect.Zero(reflect.TypeOf(v)).Interface())
+}
+
+func toString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case db.LikePattern:
+		return string(s), true
+	case []byte:
+		return string(s), true
+	}
+	return "", false
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	if n, ok := toInt64(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// compare returns -1, 0 or 1 if a is less than, equal to or greater than b.
+// Numbers of any type are compared by value, and strings with []byte.
+func compare(a, b interface{}) (int, error) {
+	a, b = normalize(a), normalize(b)
+
+	if x, ok := toInt64(a); ok {
+		if y, ok := toInt64(b); ok {
+			return compareOrdered(x < y, x > y), nil
+		}
+	}
+	if x, ok := toFloat64(a); ok {
+		if y, ok := toFloat64(b); ok {
+			return compareOrdered(x < y, x > y), nil
+		}
+	}
+	if x, ok := toString(a); ok {
+		if y, ok := toString(b); ok {
+			return strings.Compare(x, y), nil
+		}
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return compareOrdered(x.Before(y), x.After(y)), nil
+		}
+	}
+	if x, ok := a.(bool); ok {
+		if y, ok := b.(bool); ok {
+			return compareOrdered(!x && y, x && !y), nil
+		}
+	}
+	if a != nil && b != nil && reflect.TypeOf(a) == reflect.TypeOf(b) && reflect.TypeOf(a).Comparable() {
+		if a == b {
+			return 0, nil
+		}
+	}
+	return 0, fmt.Errorf("dbtest: can't compare %T with %T", a, b)
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,431 @@
+// Package dbtest provides an in-memory fake of sqlbuilder.Database, so code
+// written against this package can be unit tested without a database server:
+//
+//	sess := dbtest.New()
+//	sess.SetPrimaryKeys("membership", "group_id", "user_id")
+//
+//	repo := NewArtistRepository(sess)
+//	...
+//
+// Tables are created as they're written to and hold rows as maps of column
+// names to values. Collections support:
+//
+//   - Insert, InsertReturning, UpdateReturning, Update, Delete, FindOrCreate,
+//     Truncate and SetDefaultOrderBy. Primary keys are "id" unless they're
+//     set with SetPrimaryKeys. Items with a single primary key that's not set
+//     or zero get the next integer of the table, like a serial column, and
+//     inserting a key that's already taken fails with db.ErrUniqueViolation.
+//     Struct fields are all stored, zero values included; columns that an
+//     item doesn't set are NULL, column defaults are not modelled.
+//
+//   - Find with a primary key value, a struct with the primary keys, a
+//     db.Cond, db.And and db.Or, or a column and a value. Conditions compare
+//     with =, <>, !=, <, <=, >, >=, IN, NOT IN, IS, IS NOT, LIKE and NOT LIKE,
+//     in the key or with the db.Comparison functions, like db.Gt, db.In,
+//     db.Between, db.After or db.IsNull. A slice is matched with IN and nil
+//     with IS NULL. Numbers are compared by value whatever their type, and
+//     strings with []byte. A NULL never matches a comparison other than IS.
+//
+//   - Results with Where, And, Or, OrderBy (by columns, "-column" or
+//     "column DESC"), Limit, Offset, Paginate, Page, TotalPages,
+//     TotalEntries, Count, Exists, One, All, Next, Update, Delete,
+//     UpdateLimit, DeleteLimit, Increment, Decrement and EachBatch. Rows are
+//     scanned into structs and maps like the ones read from a database.
+//
+// Raw SQL, db.Raw and db.Func conditions, Select, Group, cursor pagination,
+// joins and the SQL builder (Select, InsertInto, Exec, Query and the like)
+// are not supported and return db.ErrUnsupported when they're run.
+//
+// Transactions work on a copy of the tables, the rows they insert, change or
+// delete are applied to the tables on commit. They're serialized: NewTx and
+// Tx wait for the running transaction to end. Isolation is not modelled, the
+// changes made outside a transaction while it runs are kept, except for the
+// rows the transaction changes too, which get its values. Subscribe reports the changes made
+// through collections, the ones made within transactions after they're
+// committed. Unlike the adapters, events always carry the keys of the rows
+// that changed, except when a table is truncated.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/cache"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// Database is an in-memory sqlbuilder.Database, see the package docs for the
+// subset of the interface it supports. It's safe for concurrent use.
+type Database struct {
+	sqlbuilder.SQLBuilder
+	db.Settings
+
+	shared *shared
+	tables *tables  // replaced by a copy within transactions
+	tx     *txState // the transaction the session runs, if any
+
+	ctx       context.Context
+	txOptions *sql.TxOptions
+}
+
+var (
+	_ = sqlbuilder.Database(&Database{})
+	_ = sqlbuilder.Tx(&tx{})
+)
+
+// shared holds the state shared by a session, its copies and transactions.
+type shared struct {
+	txMu sync.Mutex // held while a transaction runs
+
+	mu          sync.Mutex // guards the fields below
+	keys        map[string][]string
+	orders      map[string][]interface{}
+	subscribers []*subscriber
+	resultCache sqlbuilder.ResultCache
+}
+
+type subscriber struct {
+	table string
+	fn    func(db.Event)
+}
+
+// New returns a session with no tables.
+func New() *Database {
+	return &Database{
+		SQLBuilder: sqlbuilder.WithSession(unsupportedSession{}, &exql.Template{Cache: cache.NewCache()}),
+		Settings:   db.NewSettings(),
+		shared: &shared{
+			keys:   map[string][]string{},
+			orders: map[string][]interface{}{},
+		},
+		tables: &tables{m: map[string]*table{}},
+	}
+}
+
+// SetPrimaryKeys sets the primary key columns of table, which are "id"
+// unless they're set. It must be called before the table is written to.
+func (d *Database) SetPrimaryKeys(table string, columns ...string) {
+	d.shared.mu.Lock()
+	defer d.shared.mu.Unlock()
+	d.shared.keys[table] = append([]string(nil), columns...)
+}
+
+func (d *Database) primaryKeys(table string) []string {
+	d.shared.mu.Lock()
+	defer d.shared.mu.Unlock()
+	if keys, ok := d.shared.keys[table]; ok {
+		return keys
+	}
+	return []string{"id"}
+}
+
+// Driver returns nil, there's no driver behind the session.
+func (d *Database) Driver() interface{} {
+	return nil
+}
+
+// Open does nothing, the session is always open.
+func (d *Database) Open(db.ConnectionURL) error {
+	return nil
+}
+
+// Ping always succeeds.
+func (d *Database) Ping() error {
+	return nil
+}
+
+// Close does nothing, the tables are kept.
+func (d *Database) Close() error {
+	return nil
+}
+
+// CloseContext does nothing, like Close.
+func (d *Database) CloseContext(context.Context) error {
+	return nil
+}
+
+// Warmup does nothing.
+func (d *Database) Warmup(ctx context.Context, n int) error {
+	return nil
+}
+
+// Collection returns the collection of the given table.
+func (d *Database) Collection(name string) db.Collection {
+	return &collection{sess: d, name: name}
+}
+
+// Collections returns the names of the tables that were written to.
+func (d *Database) Collections() ([]string, error) {
+	d.tables.mu.Lock()
+	defer d.tables.mu.Unlock()
+	names := make([]string, 0, len(d.tables.m))
+	for name := range d.tables.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Name returns "dbtest".
+func (d *Database) Name() string {
+	return "dbtest"
+}
+
+// ConnectionURL returns nil.
+func (d *Database) ConnectionURL() db.ConnectionURL {
+	return nil
+}
+
+// ClearCache does nothing.
+func (d *Database) ClearCache() {
+}
+
+// Context returns the default context of the session.
+func (d *Database) Context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
+// WithContext returns a copy of the session that uses the given context as
+// default. The copy shares the tables of the session.
+func (d *Database) WithContext(ctx context.Context) sqlbuilder.Database {
+	nd := *d
+	nd.ctx = ctx
+	return &nd
+}
+
+// SetTxOptions sets the default TxOptions of the session, which are ignored.
+func (d *Database) SetTxOptions(txOptions sql.TxOptions) {
+	d.txOptions = &txOptions
+}
+
+// TxOptions returns the default TxOptions of the session.
+func (d *Database) TxOptions() *sql.TxOptions {
+	return d.txOptions
+}
+
+// NewTx starts a transaction, once the running one, if any, ends.
+func (d *Database) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	if ctx == nil {
+		ctx = d.Context()
+	}
+	d.shared.txMu.Lock()
+
+	return &tx{
+		Database: &Database{
+			SQLBuilder: d.SQLBuilder,
+			Settings:   d.Settings,
+			shared:     d.shared,
+			tables:     d.tables.clone(),
+			tx:         &txState{parent: d},
+			ctx:        ctx,
+			txOptions:  d.txOptions,
+		},
+	}, nil
+}
+
+// Tx runs fn within a transaction, which is committed if fn returns nil and
+// rolled back otherwise.
+func (d *Database) Tx(ctx context.Context, fn func(sess sqlbuilder.Tx) error) error {
+	return d.TxWithStats(ctx, fn, nil)
+}
+
+// TxWithStats works like Tx. Transactions are never retried, so stats always
+// reports one attempt.
+func (d *Database) TxWithStats(ctx context.Context, fn func(sess sqlbuilder.Tx) error, stats *sqlbuilder.TxStats) error {
+	start := time.Now()
+	err := d.runTx(ctx, fn)
+	if stats != nil {
+		*stats = sqlbuilder.TxStats{
+			Attempts: 1,
+			Duration: time.Since(start),
+			Err:      err,
+		}
+	}
+	return err
+}
+
+func (d *Database) runTx(ctx context.Context, fn func(sess sqlbuilder.Tx) error) error {
+	sess, err := d.NewTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer sess.Rollback()
+
+	if err := fn(sess); err != nil {
+		return err
+	}
+	return sess.Commit()
+}
+
+// Subscribe calls fn with the events of the changes made through
+// collections to table, or to any table if it's empty, see
+// sqlbuilder.Database.Subscribe.
+func (d *Database) Subscribe(table string, fn func(db.Event)) (unsubscribe func()) {
+	s := &subscriber{table: table, fn: fn}
+
+	d.shared.mu.Lock()
+	d.shared.subscribers = append(d.shared.subscribers, s)
+	d.shared.mu.Unlock()
+
+	return func() {
+		d.shared.mu.Lock()
+		defer d.shared.mu.Unlock()
+		subscribers := make([]*subscriber, 0, len(d.shared.subscribers))
+		for _, other := range d.shared.subscribers {
+			if other != s {
+				subscribers = append(subscribers, other)
+			}
+		}
+		d.shared.subscribers = subscribers
+	}
+}
+
+// emit reports ev to the subscribers, once the transaction is committed if
+// the session runs one.
+func (d *Database) emit(ev db.Event) {
+	if d.tx != nil {
+		d.tx.mu.Lock()
+		d.tx.events = append(d.tx.events, ev)
+		d.tx.mu.Unlock()
+		return
+	}
+	d.publish(ev)
+}
+
+func (d *Database) publish(ev db.Event) {
+	d.shared.mu.Lock()
+	subscribers := d.shared.subscribers
+	d.shared.mu.Unlock()
+
+	for _, s := range subscribers {
+		if s.table == "" || s.table == ev.Table {
+			s.fn(ev)
+		}
+	}
+}
+
+// DrainOutbox returns db.ErrUnsupported.
+func (d *Database) DrainOutbox(ctx context.Context, handler func(sqlbuilder.OutboxMessage) error) error {
+	return db.ErrUnsupported
+}
+
+// SetResultCache sets the result cache of the session. It's never used, as
+// the SQL builder is not supported.
+func (d *Database) SetResultCache(cache sqlbuilder.ResultCache) {
+	d.shared.mu.Lock()
+	defer d.shared.mu.Unlock()
+	d.shared.resultCache = cache
+}
+
+// ResultCache returns the cache set with SetResultCache.
+func (d *Database) ResultCache() sqlbuilder.ResultCache {
+	d.shared.mu.Lock()
+	defer d.shared.mu.Unlock()
+	return d.shared.resultCache
+}
+
+// tx is a transaction, it works on a copy of the tables of its parent session.
+type tx struct {
+	*Database
+}
+
+// txState is the state of a transaction, shared by its copies.
+type txState struct {
+	parent *Database
+
+	mu     sync.Mutex // guards events and done
+	events []db.Event
+	done   bool
+}
+
+func (t *tx) WithContext(ctx context.Context) sqlbuilder.Tx {
+	nd := *t.Database
+	nd.ctx = ctx
+	return &tx{Database: &nd}
+}
+
+func (t *tx) Commit() error {
+	st := t.Database.tx
+
+	st.mu.Lock()
+	if st.done {
+		st.mu.Unlock()
+		return sql.ErrTxDone
+	}
+	st.done = true
+	events := st.events
+	st.mu.Unlock()
+
+	st.parent.tables.merge(t.tables)
+	t.shared.txMu.Unlock()
+
+	for _, ev := range events {
+		st.parent.publish(ev)
+	}
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	st := t.Database.tx
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.done {
+		return sql.ErrTxDone
+	}
+	st.done = true
+	t.shared.txMu.Unlock()
+	return nil
+}
+
+func (t *tx) Done() bool {
+	return t.TxErr() != nil
+}
+
+func (t *tx) TxErr() error {
+	st := t.Database.tx
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.done {
+		return sql.ErrTxDone
+	}
+	return t.Context().Err()
+}
+
+// Outbox returns db.ErrUnsupported.
+func (t *tx) Outbox(event interface{}) error {
+	return db.ErrUnsupported
+}
+
+// unsupportedSession runs the statements of the SQL builder, none of which
+// is supported.
+type unsupportedSession struct{}
+
+func (unsupportedSession) StatementExec(ctx context.Context, stmt *exql.Statement, args ...interface{}) (sql.Result, error) {
+	return nil, db.ErrUnsupported
+}
+
+func (unsupportedSession) StatementPrepare(ctx context.Context, stmt *exql.Statement) (*sql.Stmt, error) {
+	return nil, db.ErrUnsupported
+}
+
+func (unsupportedSession) StatementQuery(ctx context.Context, stmt *exql.Statement, args ...interface{}) (*sql.Rows, error) {
+	return nil, db.ErrUnsupported
+}
+
+func (unsupportedSession) StatementQueryRow(ctx context.Context, stmt *exql.Statement, args ...interface{}) (*sql.Row, error) {
+	return nil, db.ErrUnsupported
+}
+
+func (unsupportedSession) Context() context.Context {
+	return context.Background()
+}
@@ -0,0 +1,311 @@
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+type artist struct {
+	ID        int64      `db:"id,omitempty"`
+	Name      string     `db:"name"`
+	Rank      int        `db:"rank"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func newArtists(t *testing.T) (*Database, db.Collection) {
+	sess := New()
+	col := sess.Collection("artist")
+	for i, name := range []string{"Ozzy", "Flea", "Slash", "Tom"} {
+		id, err := col.Insert(artist{Name: name, Rank: i + 1})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i+1), id)
+	}
+	return sess, col
+}
+
+func names(artists []artist) []string {
+	s := make([]string, len(artists))
+	for i := range artists {
+		s[i] = artists[i].Name
+	}
+	return s
+}
+
+func TestCollection(t *testing.T) {
+	sess, col := newArtists(t)
+	assert.True(t, col.Exists())
+	assert.False(t, sess.Collection("other").Exists())
+
+	var a artist
+	assert.NoError(t, col.Find(2).One(&a))
+	assert.Equal(t, artist{ID: 2, Name: "Flea", Rank: 2}, a)
+
+	assert.Equal(t, db.ErrNoMoreRows, col.Find(10).One(&a))
+
+	a.Rank = 10
+	assert.NoError(t, col.Update(a))
+	assert.NoError(t, col.Find(artist{ID: 2}).One(&a))
+	assert.Equal(t, 10, a.Rank)
+
+	assert.NoError(t, col.Delete(a))
+	n, err := col.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), n)
+
+	assert.Error(t, col.Delete(artist{}))
+
+	// Keys are not reused, unless the table is truncated.
+	b := artist{Name: "Axl"}
+	assert.NoError(t, col.InsertReturning(&b))
+	assert.Equal(t, int64(5), b.ID)
+
+	_, err = col.Insert(artist{ID: 5, Name: "Duff"})
+	assert.Equal(t, db.ErrUniqueViolation, err.(*db.Error).Kind)
+
+	b.Name = "W. Axl Rose"
+	assert.NoError(t, col.UpdateReturning(&b))
+	assert.Equal(t, "W. Axl Rose", b.Name)
+
+	assert.NoError(t, col.Truncate())
+	id, err := col.Insert(map[string]interface{}{"name": "Duff"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	// Columns an item doesn't set are NULL.
+	var m map[string]interface{}
+	assert.NoError(t, col.Find(id).One(&m))
+	assert.Equal(t, map[string]interface{}{"id": int64(1), "name": "Duff", "rank": nil, "deleted_at": nil}, m)
+}
+
+func TestConditions(t *testing.T) {
+	_, col := newArtists(t)
+
+	now := time.Now()
+	assert.NoError(t, col.Find(4).Update(map[string]interface{}{"deleted_at": now}))
+
+	tests := []struct {
+		conds []interface{}
+		want  []string
+	}{
+		{nil, []string{"Ozzy", "Flea", "Slash", "Tom"}},
+		{[]interface{}{db.Cond{"name": "Slash"}}, []string{"Slash"}},
+		{[]interface{}{"name", "Slash"}, []string{"Slash"}},
+		{[]interface{}{db.Cond{"rank >": 2}}, []string{"Slash", "Tom"}},
+		{[]interface{}{db.Cond{"rank": db.Lte(2)}}, []string{"Ozzy", "Flea"}},
+		{[]interface{}{db.Cond{"rank": []int{1, 3}}}, []string{"Ozzy", "Slash"}},
+		{[]interface{}{db.Cond{"rank NOT IN": []int{1, 3}}}, []string{"Flea", "Tom"}},
+		{[]interface{}{db.Cond{"rank": db.Between(2, 3)}}, []string{"Flea", "Slash"}},
+		{[]interface{}{db.Cond{"name": db.Like("%l%")}}, []string{"Flea", "Slash"}},
+		{[]interface{}{db.Cond{"name NOT LIKE": "_lea"}}, []string{"Ozzy", "Slash", "Tom"}},
+		{[]interface{}{db.Cond{"deleted_at": nil}}, []string{"Ozzy", "Flea", "Slash"}},
+		{[]interface{}{db.Cond{"deleted_at": db.IsNotNull()}}, []string{"Tom"}},
+		{[]interface{}{db.Cond{"deleted_at": db.Before(now.Add(time.Second))}}, []string{"Tom"}},
+		{[]interface{}{db.Cond{"deleted_at !=": now}}, nil},
+		{[]interface{}{db.Or(db.Cond{"name": "Ozzy"}, db.Cond{"rank": 4})}, []string{"Ozzy", "Tom"}},
+		{[]interface{}{db.And(db.Cond{"rank >": 1}, db.Cond{"rank <": 4}), db.Cond{"name <>": "Flea"}}, []string{"Slash"}},
+		{[]interface{}{[]int64{2, 3}}, []string{"Flea", "Slash"}},
+	}
+	for _, test := range tests {
+		var artists []artist
+		assert.NoError(t, col.Find(test.conds...).All(&artists), test.conds)
+		if test.want == nil {
+			assert.Empty(t, artists, test.conds)
+			continue
+		}
+		assert.Equal(t, test.want, names(artists), test.conds)
+	}
+
+	var artists []artist
+	assert.NoError(t, col.Find(db.Cond{"rank": 1}).Or(db.Cond{"rank": 2}).And(db.Cond{"name": "Flea"}).All(&artists))
+	assert.Equal(t, []string{"Flea"}, names(artists))
+
+	for _, res := range []db.Result{
+		col.Find("rank > ?", 1),
+		col.Find(db.Raw("rank > 1")),
+		col.Find(db.Cond{"rank": db.RegExp("1")}),
+		col.Find().Select("name"),
+		col.Find().Group("rank"),
+	} {
+		assert.Equal(t, db.ErrUnsupported, res.All(&artists))
+	}
+}
+
+func TestResult(t *testing.T) {
+	sess, col := newArtists(t)
+
+	var artists []artist
+	assert.NoError(t, col.Find().OrderBy("-rank").Limit(2).Offset(1).All(&artists))
+	assert.Equal(t, []string{"Slash", "Flea"}, names(artists))
+
+	assert.NoError(t, col.Find().OrderBy("name DESC").Paginate(3).Page(2).All(&artists))
+	assert.Equal(t, []string{"Flea"}, names(artists))
+
+	pages, err := col.Find().Paginate(3).TotalPages()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), pages)
+
+	col.SetDefaultOrderBy("name")
+	assert.NoError(t, col.Find().All(&artists))
+	assert.Equal(t, []string{"Flea", "Ozzy", "Slash", "Tom"}, names(artists))
+	col.SetDefaultOrderBy()
+
+	res := col.Find(db.Cond{"rank >": 2})
+	var a artist
+	var seen []string
+	for res.Next(&a) {
+		seen = append(seen, a.Name)
+	}
+	assert.NoError(t, res.Err())
+	assert.NoError(t, res.Close())
+	assert.Equal(t, []string{"Slash", "Tom"}, seen)
+
+	exists, err := col.Find(db.Cond{"name": "Nobody"}).Exists()
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	n, err := col.Find(db.Cond{"rank <": 3}).Increment("rank", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, col.Find().OrderBy("rank").All(&artists))
+	assert.Equal(t, []string{"Slash", "Tom", "Ozzy", "Flea"}, names(artists))
+
+	n, err = col.Find().OrderBy("-rank").DeleteLimit(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Error(t, col.Find(2).One(&a))
+
+	var batches [][]string
+	err = col.Find().EachBatch(context.Background(), 2, &artists, func() error {
+		batches = append(batches, names(artists))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Ozzy", "Slash"}, {"Tom"}}, batches)
+
+	// The SQL builder is not supported.
+	assert.Equal(t, db.ErrUnsupported, sess.SelectFrom("artist").All(&artists))
+	_, err = sess.Exec("DELETE FROM artist")
+	assert.Equal(t, db.ErrUnsupported, err)
+}
+
+func TestCompositeKeys(t *testing.T) {
+	sess := New()
+	sess.SetPrimaryKeys("membership", "group_id", "user_id")
+	col := sess.Collection("membership")
+
+	type membership struct {
+		GroupID int    `db:"group_id"`
+		UserID  int    `db:"user_id"`
+		Role    string `db:"role"`
+	}
+
+	key, err := col.Insert(membership{GroupID: 1, UserID: 2, Role: "admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, db.Cond{"group_id": 1, "user_id": 2}, key)
+
+	_, err = col.Insert(membership{GroupID: 1, UserID: 2})
+	assert.Equal(t, db.ErrUniqueViolation, err.(*db.Error).Kind)
+
+	m := membership{GroupID: 1, UserID: 3}
+	created, err := col.FindOrCreate(db.Cond{"group_id": 1, "user_id": 3}, &m)
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	m = membership{Role: "guest"}
+	created, err = col.FindOrCreate(db.Cond{"group_id": 1, "user_id": 2}, &m)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "admin", m.Role)
+
+	assert.NoError(t, col.Find(membership{GroupID: 1, UserID: 3}).One(&m))
+	assert.Equal(t, "", m.Role)
+}
+
+func TestTx(t *testing.T) {
+	sess, col := newArtists(t)
+
+	var events []db.Event
+	unsubscribe := sess.Subscribe("artist", func(ev db.Event) {
+		events = append(events, ev)
+	})
+	defer unsubscribe()
+
+	errRollback := errors.New("rollback")
+	err := sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		_, err := tx.Collection("artist").Insert(artist{Name: "Axl"})
+		assert.NoError(t, err)
+		n, err := tx.Collection("artist").Find().Count()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(5), n)
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+	assert.Empty(t, events)
+
+	n, err := col.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), n)
+
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		if err := tx.Collection("artist").Find(1).Delete(); err != nil {
+			return err
+		}
+		assert.Empty(t, events)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []db.Event{{Operation: db.OperationDelete, Table: "artist", Keys: []interface{}{int64(1)}}}, events)
+
+	n, err = col.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), n)
+
+	tx, err := sess.NewTx(nil)
+	assert.NoError(t, err)
+	assert.False(t, tx.Done())
+	assert.NoError(t, tx.Commit())
+	assert.True(t, tx.Done())
+	assert.Equal(t, sql.ErrTxDone, tx.Rollback())
+}
+
+func TestTxKeepsOutsideWrites(t *testing.T) {
+	sess, col := newArtists(t)
+
+	tx, err := sess.NewTx(nil)
+	assert.NoError(t, err)
+
+	txCol := tx.Collection("artist")
+	_, err = txCol.Insert(artist{Name: "Axl"})
+	assert.NoError(t, err)
+	assert.NoError(t, txCol.Find(2).Update(map[string]interface{}{"rank": 20}))
+	assert.NoError(t, txCol.Find(3).Delete())
+
+	// Made outside of the transaction, while it runs.
+	assert.NoError(t, col.Find(1).Update(map[string]interface{}{"rank": 10}))
+	_, err = sess.Collection("label").Insert(map[string]interface{}{"name": "Geffen"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, tx.Commit())
+
+	var artists []artist
+	assert.NoError(t, col.Find().OrderBy("id").All(&artists))
+	assert.Equal(t, []string{"Ozzy", "Flea", "Tom", "Axl"}, names(artists))
+	assert.Equal(t, 10, artists[0].Rank)
+	assert.Equal(t, 20, artists[1].Rank)
+	assert.Equal(t, int64(5), artists[3].ID)
+
+	n, err := sess.Collection("label").Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), n)
+
+	id, err := col.Insert(artist{Name: "Duff"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), id)
+}
@@ -0,0 +1,632 @@
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// result is a result set of a table. Methods that refine it return a copy.
+type result struct {
+	sess  *Database
+	table string
+
+	conds   []interface{}
+	orderBy []interface{}
+	limit   int
+	offset  int
+
+	pageSize   uint
+	pageNumber uint
+
+	err error
+
+	iterMu sync.Mutex
+	iter   sqlbuilder.Iterator
+}
+
+var _ = db.Result(&result{})
+
+// sortColumn is a column given to OrderBy.
+type sortColumn struct {
+	column string
+	desc   bool
+	nulls  db.NullsOrder
+}
+
+func (r *result) clone(fn func(res *result)) db.Result {
+	res := &result{
+		sess:       r.sess,
+		table:      r.table,
+		conds:      r.conds,
+		orderBy:    r.orderBy,
+		limit:      r.limit,
+		offset:     r.offset,
+		pageSize:   r.pageSize,
+		pageNumber: r.pageNumber,
+		err:        r.err,
+	}
+	if res.err == nil {
+		fn(res)
+	}
+	return res
+}
+
+func (r *result) unsupported() db.Result {
+	return r.clone(func(res *result) {
+		res.err = db.ErrUnsupported
+	})
+}
+
+func (r *result) String() string {
+	return fmt.Sprintf("dbtest: SELECT * FROM %q WHERE %v", r.table, r.conds)
+}
+
+func (r *result) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	r.iterMu.Lock()
+	defer r.iterMu.Unlock()
+	if r.iter != nil {
+		return r.iter.Err()
+	}
+	return nil
+}
+
+func (r *result) Limit(n int) db.Result {
+	return r.clone(func(res *result) {
+		res.limit = n
+	})
+}
+
+func (r *result) Offset(n int) db.Result {
+	return r.clone(func(res *result) {
+		res.offset = n
+	})
+}
+
+func (r *result) OrderBy(fields ...interface{}) db.Result {
+	return r.clone(func(res *result) {
+		res.orderBy = fields
+	})
+}
+
+// Select returns a result set that fails with db.ErrUnsupported.
+func (r *result) Select(...interface{}) db.Result {
+	return r.unsupported()
+}
+
+// Group returns a result set that fails with db.ErrUnsupported.
+func (r *result) Group(...interface{}) db.Result {
+	return r.unsupported()
+}
+
+func (r *result) Where(conds ...interface{}) db.Result {
+	return r.clone(func(res *result) {
+		res.conds, res.err = toCompounds(conds)
+	})
+}
+
+func (r *result) And(conds ...interface{}) db.Result {
+	return r.clone(func(res *result) {
+		var and []interface{}
+		if and, res.err = toCompounds(conds); res.err == nil {
+			res.conds = append(append([]interface{}(nil), r.conds...), and...)
+		}
+	})
+}
+
+func (r *result) Or(conds ...interface{}) db.Result {
+	return r.clone(func(res *result) {
+		or, err := toCompounds(conds)
+		if err != nil {
+			res.err = err
+			return
+		}
+		res.conds = []interface{}{db.Or(intersection(r.conds), intersection(or))}
+	})
+}
+
+func intersection(conds []interface{}) db.Compound {
+	compounds := make([]db.Compound, len(conds))
+	for i := range conds {
+		compounds[i] = conds[i].(db.Compound)
+	}
+	return db.And(compounds...)
+}
+
+func (r *result) Paginate(pageSize uint) db.Result {
+	return r.clone(func(res *result) {
+		res.pageSize = pageSize
+	})
+}
+
+func (r *result) Page(pageNumber uint) db.Result {
+	return r.clone(func(res *result) {
+		res.pageNumber = pageNumber
+	})
+}
+
+// Cursor returns a result set that fails with db.ErrUnsupported.
+func (r *result) Cursor(string) db.Result {
+	return r.unsupported()
+}
+
+// NextPage returns a result set that fails with db.ErrUnsupported.
+func (r *result) NextPage(interface{}) db.Result {
+	return r.unsupported()
+}
+
+// PrevPage returns a result set that fails with db.ErrUnsupported.
+func (r *result) PrevPage(interface{}) db.Result {
+	return r.unsupported()
+}
+
+func (r *result) TotalPages() (uint, error) {
+	n, err := r.Count()
+	if err != nil {
+		return 0, err
+	}
+	if r.pageSize == 0 {
+		return 1, nil
+	}
+	return uint((n + uint64(r.pageSize) - 1) / uint64(r.pageSize)), nil
+}
+
+func (r *result) TotalEntries() (uint64, error) {
+	return r.Count()
+}
+
+func (r *result) Count() (uint64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	r.sess.tables.mu.Lock()
+	defer r.sess.tables.mu.Unlock()
+
+	rows, err := r.matching(r.sess.tables.table(r.table, false))
+	return uint64(len(rows)), err
+}
+
+func (r *result) Exists() (bool, error) {
+	n, err := r.Count()
+	return n > 0, err
+}
+
+func (r *result) One(dst interface{}) error {
+	t, rows, err := r.fetch()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return db.ErrNoMoreRows
+	}
+	return scanOne(t, rows[0], dst)
+}
+
+func (r *result) All(dst interface{}) error {
+	t, rows, err := r.fetch()
+	if err != nil {
+		return err
+	}
+	iter, err := iterate(t.cachedRows(rows))
+	if err != nil {
+		return err
+	}
+	return iter.All(dst)
+}
+
+func (r *result) Next(dst interface{}) bool {
+	r.iterMu.Lock()
+	defer r.iterMu.Unlock()
+
+	if r.iter == nil {
+		t, rows, err := r.fetch()
+		if err != nil {
+			r.err = err
+			return false
+		}
+		if r.iter, err = iterate(t.cachedRows(rows)); err != nil {
+			r.err = err
+			return false
+		}
+	}
+	if r.iter.Next(dst) {
+		return true
+	}
+	r.iter.Close()
+	return false
+}
+
+func (r *result) Close() error {
+	r.iterMu.Lock()
+	defer r.iterMu.Unlock()
+	if r.iter != nil {
+		err := r.iter.Close()
+		r.iter = nil
+		return err
+	}
+	return nil
+}
+
+func (r *result) Delete() error {
+	_, err := r.DeleteLimit(0)
+	return err
+}
+
+func (r *result) DeleteLimit(n int) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	pk := r.sess.primaryKeys(r.table)
+
+	r.sess.tables.mu.Lock()
+	t := r.sess.tables.table(r.table, false)
+	rows, err := r.limited(t, n)
+	if err != nil || len(rows) == 0 {
+		r.sess.tables.mu.Unlock()
+		return 0, err
+	}
+	deleted := make(map[uintptr]bool, len(rows))
+	for _, row := range rows {
+		deleted[reflect.ValueOf(row).Pointer()] = true
+	}
+	kept := t.rows[:0]
+	for _, row := range t.rows {
+		if !deleted[reflect.ValueOf(row).Pointer()] {
+			kept = append(kept, row)
+		}
+	}
+	t.rows = kept
+	r.sess.tables.mu.Unlock()
+
+	r.emit(db.OperationDelete, rows, pk)
+	return int64(len(rows)), nil
+}
+
+func (r *result) Update(values interface{}) error {
+	_, err := r.UpdateLimit(values, 0)
+	return err
+}
+
+func (r *result) UpdateLimit(values interface{}, n int) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	set, err := toValues(values)
+	if err != nil {
+		return 0, err
+	}
+	return r.update(n, func(row) (row, error) {
+		return set, nil
+	})
+}
+
+func (r *result) Increment(column string, delta interface{}) (int64, error) {
+	return r.add(column, delta, false)
+}
+
+func (r *result) Decrement(column string, delta interface{}) (int64, error) {
+	return r.add(column, delta, true)
+}
+
+func (r *result) add(column string, delta interface{}, negate bool) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	delta = normalize(delta)
+	return r.update(0, func(current row) (row, error) {
+		v := normalize(current[column])
+		if v == nil {
+			return row{}, nil
+		}
+		if x, ok := toInt64(v); ok {
+			if y, ok := toInt64(delta); ok {
+				if negate {
+					y = -y
+				}
+				return row{column: x + y}, nil
+			}
+		}
+		x, ok := toFloat64(v)
+		y, deltaOK := toFloat64(delta)
+		if !ok || !deltaOK {
+			return nil, fmt.Errorf("dbtest: can't add %T to %T", delta, v)
+		}
+		if negate {
+			y = -y
+		}
+		return row{column: x + y}, nil
+	})
+}
+
+// update sets the values fn returns on at most n of the rows of the result,
+// or all of them if n is zero.
+func (r *result) update(n int, fn func(row) (row, error)) (int64, error) {
+	pk := r.sess.primaryKeys(r.table)
+
+	r.sess.tables.mu.Lock()
+	t := r.sess.tables.table(r.table, false)
+	rows, err := r.limited(t, n)
+	if err != nil || len(rows) == 0 {
+		r.sess.tables.mu.Unlock()
+		return 0, err
+	}
+
+	updates := make([]row, len(rows))
+	for i, current := range rows {
+		values, err := fn(current)
+		if err != nil {
+			r.sess.tables.mu.Unlock()
+			return 0, err
+		}
+		updated := current.clone()
+		for column, v := range values {
+			updated[column] = v
+		}
+		if err := t.checkKey(updated, pk, current); err != nil {
+			r.sess.tables.mu.Unlock()
+			return 0, err
+		}
+		updates[i] = values
+	}
+	for i, current := range rows {
+		t.set(current, updates[i])
+	}
+	r.sess.tables.mu.Unlock()
+
+	r.emit(db.OperationUpdate, rows, pk)
+	return int64(len(rows)), nil
+}
+
+func (r *result) emit(op db.Operation, rows []row, pk []string) {
+	keys := make([]interface{}, len(rows))
+	for i := range rows {
+		keys[i] = rows[i].key(pk)
+	}
+	r.sess.emit(db.Event{Operation: op, Table: r.table, Keys: keys})
+}
+
+func (r *result) EachBatch(ctx context.Context, batchSize int, sliceOfStructs interface{}, fn func() error) error {
+	if r.err != nil {
+		return r.err
+	}
+	if batchSize <= 0 {
+		return db.ErrInvalidBatchSize
+	}
+
+	pk := r.sess.primaryKeys(r.table)
+	order := make([]interface{}, len(pk))
+	for i := range pk {
+		order[i] = pk[i]
+	}
+
+	var last row
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r.sess.tables.mu.Lock()
+		t := r.sess.tables.table(r.table, false)
+		rows, err := r.matching(t)
+		if err == nil {
+			err = sortRows(rows, order)
+		}
+		var batch []row
+		for _, row := range rows {
+			if last != nil && compareKeys(row, last, pk) <= 0 {
+				continue
+			}
+			batch = append(batch, row)
+			if len(batch) == batchSize {
+				break
+			}
+		}
+		var cached *sqlbuilder.CachedRows
+		if t != nil {
+			cached = t.cachedRows(batch)
+		}
+		r.sess.tables.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		iter, err := iterate(cached)
+		if err != nil {
+			return err
+		}
+		if err := iter.All(sliceOfStructs); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		last = batch[len(batch)-1].clone()
+	}
+}
+
+func compareKeys(a, b row, pk []string) int {
+	for _, column := range pk {
+		if c, err := compare(a[column], b[column]); err == nil && c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// fetch returns the rows of the page of the result, in order.
+func (r *result) fetch() (*table, []row, error) {
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	r.sess.tables.mu.Lock()
+	defer r.sess.tables.mu.Unlock()
+
+	t := r.sess.tables.table(r.table, false)
+	if t == nil {
+		t = &table{}
+	}
+	rows, err := r.rows(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Rows are copied, as they may change once the lock is released.
+	for i := range rows {
+		rows[i] = rows[i].clone()
+	}
+	return t, rows, nil
+}
+
+// rows returns the rows of the page of the result in order, the lock of the
+// tables must be held.
+func (r *result) rows(t *table) ([]row, error) {
+	rows, err := r.matching(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := sortRows(rows, r.orderBy); err != nil {
+		return nil, err
+	}
+
+	limit, offset := r.limit, r.offset
+	if r.pageSize > 0 {
+		limit = int(r.pageSize)
+		offset = 0
+		if r.pageNumber > 1 {
+			offset = int(r.pageSize * (r.pageNumber - 1))
+		}
+	}
+	if offset > 0 {
+		if offset > len(rows) {
+			offset = len(rows)
+		}
+		rows = rows[offset:]
+	}
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// limited returns the first n rows that match the conditions of the result
+// in order, or all of them if n is zero.
+func (r *result) limited(t *table, n int) ([]row, error) {
+	rows, err := r.matching(t)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && n < len(rows) {
+		if err := sortRows(rows, r.orderBy); err != nil {
+			return nil, err
+		}
+		rows = rows[:n]
+	}
+	return rows, nil
+}
+
+// matching returns the rows of t that match the conditions of the result.
+func (r *result) matching(t *table) ([]row, error) {
+	if t == nil {
+		return nil, nil
+	}
+	var rows []row
+	for _, row := range t.rows {
+		ok, err := matches(row, r.conds)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// sortRows sorts rows by the given OrderBy fields, NULL values go last in
+// ascending order and first in descending order, like on PostgreSQL.
+func sortRows(rows []row, fields []interface{}) error {
+	columns, err := sortColumns(fields)
+	if err != nil || len(columns) == 0 {
+		return err
+	}
+
+	var sortErr error
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, sc := range columns {
+			a, b := normalize(rows[i][sc.column]), normalize(rows[j][sc.column])
+			if a == nil || b == nil {
+				if a == nil && b == nil {
+					continue
+				}
+				nullsFirst := sc.nulls == db.NullsFirst || (sc.nulls == db.NullsDefault && sc.desc)
+				return (a == nil) == nullsFirst
+			}
+			c, err := compare(a, b)
+			if err != nil {
+				sortErr = err
+				return false
+			}
+			if c != 0 {
+				return (c < 0) != sc.desc
+			}
+		}
+		return false
+	})
+	return sortErr
+}
+
+func sortColumns(fields []interface{}) ([]sortColumn, error) {
+	columns := make([]sortColumn, 0, len(fields))
+	for _, field := range fields {
+		switch f := field.(type) {
+		case string:
+			sc := sortColumn{column: f}
+			if strings.HasPrefix(f, "-") {
+				sc = sortColumn{column: f[1:], desc: true}
+			} else if parts := strings.Fields(f); len(parts) == 2 {
+				switch strings.ToUpper(parts[1]) {
+				case "ASC":
+					sc = sortColumn{column: parts[0]}
+				case "DESC":
+					sc = sortColumn{column: parts[0], desc: true}
+				default:
+					return nil, db.ErrUnsupported
+				}
+			} else if len(parts) != 1 {
+				return nil, db.ErrUnsupported
+			}
+			columns = append(columns, sc)
+		case db.Order:
+			if f.Collation() != "" {
+				return nil, db.ErrUnsupported
+			}
+			columns = append(columns, sortColumn{column: f.Column(), desc: f.Descending(), nulls: f.Nulls()})
+		default:
+			return nil, db.ErrUnsupported
+		}
+	}
+	return columns, nil
+}
+
+// scanOne scans r into dst.
+func scanOne(t *table, r row, dst interface{}) error {
+	iter, err := iterate(t.cachedRows([]row{r.clone()}))
+	if err != nil {
+		return err
+	}
+	return iter.One(dst)
+}
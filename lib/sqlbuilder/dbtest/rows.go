@@ -0,0 +1,96 @@
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// rowsDB reads the rows of a table through database/sql, so they're scanned
+// by sqlbuilder.NewIterator into structs and maps like the rows of a
+// database.
+var rowsDB = sql.OpenDB(rowsConnector{})
+
+var errRowsReadOnly = errors.New("dbtest: rows can only be read")
+
+// rowsArg is the only argument of the queries sent to rowsDB, it carries the
+// rows the query returns.
+type rowsArg struct {
+	rows *sqlbuilder.CachedRows
+}
+
+// iterate returns an iterator that reads rows.
+func iterate(rows *sqlbuilder.CachedRows) (sqlbuilder.Iterator, error) {
+	r, err := rowsDB.Query("", rowsArg{rows})
+	if err != nil {
+		return nil, err
+	}
+	return sqlbuilder.NewIterator(r), nil
+}
+
+type rowsConnector struct{}
+
+func (rowsConnector) Connect(context.Context) (driver.Conn, error) {
+	return rowsConn{}, nil
+}
+
+func (rowsConnector) Driver() driver.Driver {
+	return rowsDriver{}
+}
+
+type rowsDriver struct{}
+
+func (rowsDriver) Open(string) (driver.Conn, error) {
+	return rowsConn{}, nil
+}
+
+type rowsConn struct{}
+
+// CheckNamedValue lets rowsArg through as it is.
+func (rowsConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (rowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &rowsCursor{rows: args[0].Value.(rowsArg).rows}, nil
+}
+
+func (rowsConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errRowsReadOnly
+}
+
+func (rowsConn) Begin() (driver.Tx, error) {
+	return nil, errRowsReadOnly
+}
+
+func (rowsConn) Close() error {
+	return nil
+}
+
+type rowsCursor struct {
+	rows *sqlbuilder.CachedRows
+	pos  int
+}
+
+func (r *rowsCursor) Columns() []string {
+	return r.rows.Columns
+}
+
+func (r *rowsCursor) Close() error {
+	return nil
+}
+
+func (r *rowsCursor) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows.Values) {
+		return io.EOF
+	}
+	for i, v := range r.rows.Values[r.pos] {
+		dest[i] = v
+	}
+	r.pos++
+	return nil
+}
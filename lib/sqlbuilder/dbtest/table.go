@@ -0,0 +1,331 @@
+package dbtest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/reflectx"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+var mapper = reflectx.NewMapper("db")
+
+// tables holds the tables of a session.
+type tables struct {
+	mu sync.Mutex
+	m  map[string]*table
+}
+
+// table holds rows as maps of column names to values, in insertion order.
+type table struct {
+	columns []string // in the order they were first written
+	rows    []row
+	seq     int64 // the last key given to a row
+
+	// The rows and seq of the table that a transaction's table was cloned
+	// from, see merge.
+	origins map[uintptr]origin
+	baseSeq int64
+}
+
+// origin is a row a transaction's row was cloned from, along with its values
+// at the time. The clone is kept so its identity is not reused.
+type origin struct {
+	row      row
+	clone    row
+	snapshot row
+}
+
+type row map[string]interface{}
+
+func (ts *tables) clone() *tables {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	nts := &tables{m: make(map[string]*table, len(ts.m))}
+	for name, t := range ts.m {
+		nt := &table{
+			columns: append([]string(nil), t.columns...),
+			rows:    make([]row, len(t.rows)),
+			seq:     t.seq,
+			origins: make(map[uintptr]origin, len(t.rows)),
+			baseSeq: t.seq,
+		}
+		for i := range t.rows {
+			nt.rows[i] = t.rows[i].clone()
+			nt.origins[rowID(nt.rows[i])] = origin{row: t.rows[i], clone: nt.rows[i], snapshot: t.rows[i].clone()}
+		}
+		nts.m[name] = nt
+	}
+	return nts
+}
+
+// merge applies the changes of other, a clone of the tables made by a
+// transaction, like the transaction does on commit: the rows it inserted,
+// changed or deleted. The changes made to other rows since the tables were
+// cloned are kept.
+func (ts *tables) merge(other *tables) {
+	other.mu.Lock()
+	defer other.mu.Unlock()
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for name, t := range other.m {
+		ts.table(name, true).merge(t)
+	}
+}
+
+// merge applies the changes of other, a clone of t, to t.
+func (t *table) merge(other *table) {
+	for _, column := range other.columns {
+		if !t.hasColumn(column) {
+			t.columns = append(t.columns, column)
+		}
+	}
+
+	kept := make(map[uintptr]bool, len(other.origins))
+	for _, r := range other.rows {
+		o, ok := other.origins[rowID(r)]
+		if !ok {
+			t.rows = append(t.rows, r.clone())
+			continue
+		}
+		kept[rowID(o.row)] = true
+		if !reflect.DeepEqual(r, o.snapshot) {
+			for column, v := range r {
+				o.row[column] = v
+			}
+		}
+	}
+
+	if len(kept) < len(other.origins) {
+		cloned := make(map[uintptr]bool, len(other.origins))
+		for _, o := range other.origins {
+			cloned[rowID(o.row)] = true
+		}
+		rows := t.rows[:0]
+		for _, r := range t.rows {
+			if !cloned[rowID(r)] || kept[rowID(r)] {
+				rows = append(rows, r)
+			}
+		}
+		t.rows = rows
+	}
+
+	switch {
+	case other.seq > t.seq:
+		t.seq = other.seq
+	case other.seq < other.baseSeq && t.seq == other.baseSeq:
+		// The table was truncated and no keys were given since.
+		t.seq = other.seq
+	}
+}
+
+// table returns the table with the given name, creating it if it doesn't
+// exist and create is true.
+func (ts *tables) table(name string, create bool) *table {
+	t, ok := ts.m[name]
+	if !ok && create {
+		t = &table{}
+		ts.m[name] = t
+	}
+	return t
+}
+
+// rowID identifies a row, rows are compared by identity and not by value.
+func rowID(r row) uintptr {
+	return reflect.ValueOf(r).Pointer()
+}
+
+func (r row) clone() row {
+	nr := make(row, len(r))
+	for k, v := range r {
+		nr[k] = v
+	}
+	return nr
+}
+
+// set sets the given columns of r, adding the ones t didn't have.
+func (t *table) set(r row, values row) {
+	for _, column := range sortedColumns(values) {
+		if !t.hasColumn(column) {
+			t.columns = append(t.columns, column)
+		}
+		r[column] = values[column]
+	}
+}
+
+func sortedColumns(values row) []string {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+func (t *table) hasColumn(column string) bool {
+	for _, c := range t.columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// insert adds a row with the given values and returns its key.
+func (t *table) insert(values row, pk []string) (interface{}, error) {
+	if len(pk) == 1 {
+		if v, ok := values[pk[0]]; !ok || isZero(v) {
+			t.seq++
+			values[pk[0]] = t.seq
+		} else if n, ok := toInt64(v); ok && n > t.seq {
+			t.seq = n
+		}
+	}
+	for _, column := range pk {
+		if values[column] == nil {
+			return nil, &db.Error{
+				Kind: db.ErrNotNullViolation,
+				Err:  fmt.Errorf("dbtest: primary key %q is not set", column),
+			}
+		}
+	}
+	if err := t.checkKey(values, pk, nil); err != nil {
+		return nil, err
+	}
+
+	r := row{}
+	t.set(r, values)
+	t.rows = append(t.rows, r)
+
+	return r.key(pk), nil
+}
+
+// checkKey returns an error if a row other than except has the same primary
+// key as values.
+func (t *table) checkKey(values row, pk []string, except row) error {
+	if len(pk) == 0 {
+		return nil
+	}
+	for _, other := range t.rows {
+		if reflect.ValueOf(other).Pointer() == reflect.ValueOf(except).Pointer() {
+			continue
+		}
+		same := true
+		for _, column := range pk {
+			if c, err := compare(other[column], values[column]); err != nil || c != 0 {
+				same = false
+				break
+			}
+		}
+		if same {
+			return &db.Error{
+				Kind: db.ErrUniqueViolation,
+				Err:  fmt.Errorf("dbtest: duplicate primary key %v", values.key(pk)),
+			}
+		}
+	}
+	return nil
+}
+
+// key returns the primary key of r as Collection.Insert does: its value, or
+// a db.Cond for composite keys.
+func (r row) key(pk []string) interface{} {
+	switch len(pk) {
+	case 0:
+		return nil
+	case 1:
+		return r[pk[0]]
+	}
+	cond := db.Cond{}
+	for _, column := range pk {
+		cond[column] = r[column]
+	}
+	return cond
+}
+
+// cachedRows returns rows with all the columns of t.
+func (t *table) cachedRows(rows []row) *sqlbuilder.CachedRows {
+	cached := &sqlbuilder.CachedRows{
+		Columns: t.columns,
+		Values:  make([][]interface{}, len(rows)),
+	}
+	for i, r := range rows {
+		values := make([]interface{}, len(t.columns))
+		for j, column := range t.columns {
+			values[j] = r[column]
+		}
+		cached.Values[i] = values
+	}
+	return cached
+}
+
+// toRow maps an item, as given to Insert, to the values of a row. Unlike
+// sqlbuilder.Map, it keeps the zero values of struct fields, except for the
+// primary keys.
+func toRow(item interface{}, pk []string) (row, error) {
+	values, err := toValues(item)
+	if err != nil {
+		return nil, err
+	}
+
+	itemV := reflect.Indirect(reflect.ValueOf(item))
+	if itemV.Kind() != reflect.Struct {
+		return values, nil
+	}
+	for _, fi := range mapper.TypeMap(itemV.Type()).Names {
+		if _, ok := values[fi.Name]; ok || isKey(fi.Name, pk) {
+			continue
+		}
+		values[fi.Name] = normalize(reflectx.FieldByIndexesReadOnly(itemV, fi.Index).Interface())
+	}
+	return values, nil
+}
+
+// toValues maps a map or struct to values like sqlbuilder.Map does.
+func toValues(item interface{}) (row, error) {
+	columns, values, err := sqlbuilder.Map(item, nil)
+	if err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return nil, sqlbuilder.ErrExpectingPointerToEitherMapOrStruct
+	}
+
+	r := make(row, len(columns))
+	for i, column := range columns {
+		v := values[i]
+		if raw, ok := v.(db.RawValue); ok && raw.Raw() == db.Default.Raw() {
+			continue
+		}
+		if err := checkValue(v); err != nil {
+			return nil, err
+		}
+		r[column] = normalize(v)
+	}
+	return r, nil
+}
+
+func isKey(column string, pk []string) bool {
+	for _, c := range pk {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// checkValue returns db.ErrUnsupported for values that only a database can
+// evaluate.
+func checkValue(v interface{}) error {
+	switch v.(type) {
+	case db.RawValue, db.Function, sqlbuilder.Selector:
+		return db.ErrUnsupported
+	}
+	return nil
+}
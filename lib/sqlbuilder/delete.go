@@ -3,6 +3,7 @@ package sqlbuilder
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/frazercomputing/upper-io-db/internal/immutable"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
@@ -12,14 +13,26 @@ type deleterQuery struct {
 	table string
 	limit int
 
+	using     *exql.Columns
+	usingArgs []interface{}
+
+	joins     []*exql.Join
+	joinsArgs []interface{}
+
 	where     *exql.Where
 	whereArgs []interface{}
 
+	returning []exql.Fragment
+
 	amendFn func(string) string
+	comment string
 }
 
 func (dq *deleterQuery) and(b *sqlBuilder, terms ...interface{}) error {
-	where, whereArgs := b.t.toWhereWithArguments(terms)
+	where, whereArgs, err := b.t.toWhereWithArguments(terms)
+	if err != nil {
+		return err
+	}
 
 	if dq.where == nil {
 		dq.where, dq.whereArgs = &exql.Where{}, []interface{}{}
@@ -30,6 +43,21 @@ func (dq *deleterQuery) and(b *sqlBuilder, terms ...interface{}) error {
 	return nil
 }
 
+func (dq *deleterQuery) or(b *sqlBuilder, terms ...interface{}) error {
+	where, whereArgs, err := b.t.toWhereWithArguments(terms)
+	if err != nil {
+		return err
+	}
+
+	if dq.whereArgs == nil {
+		dq.whereArgs = []interface{}{}
+	}
+	dq.where = orWhere(dq.where, where)
+	dq.whereArgs = append(dq.whereArgs, whereArgs...)
+
+	return nil
+}
+
 func (dq *deleterQuery) statement() *exql.Statement {
 	stmt := &exql.Statement{
 		Type:  exql.Delete,
@@ -44,7 +72,23 @@ func (dq *deleterQuery) statement() *exql.Statement {
 		stmt.Limit = exql.Limit(dq.limit)
 	}
 
+	if dq.using != nil {
+		using := &exql.DeleteUsing{
+			Table:   stmt.Table,
+			Sources: dq.using,
+		}
+		if len(dq.joins) > 0 {
+			using.Joins = exql.JoinConditions(dq.joins...)
+		}
+		stmt.From = using
+	}
+
+	if len(dq.returning) > 0 {
+		stmt.Returning = exql.ReturningColumns(dq.returning...)
+	}
+
 	stmt.SetAmendment(dq.amendFn)
+	stmt.Comment = dq.comment
 
 	return stmt
 }
@@ -70,11 +114,15 @@ func (del *deleter) template() *exql.Template {
 }
 
 func (del *deleter) String() string {
-	s, err := del.Compile()
+	stmt, err := del.statement()
 	if err != nil {
 		panic(err.Error())
 	}
-	return prepareQueryForDisplay(s)
+	s, err := stmt.Compile(del.template())
+	if err != nil {
+		panic(err.Error())
+	}
+	return exql.CommentPrefix(stmt.Comment) + prepareQueryForDisplay(del.template(), s)
 }
 
 func (del *deleter) setTable(table string) *deleter {
@@ -101,6 +149,12 @@ func (del *deleter) And(terms ...interface{}) Deleter {
 	})
 }
 
+func (del *deleter) Or(terms ...interface{}) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		return dq.or(del.SQLBuilder(), terms...)
+	})
+}
+
 func (del *deleter) Limit(limit int) Deleter {
 	return del.frame(func(dq *deleterQuery) error {
 		dq.limit = limit
@@ -115,8 +169,95 @@ func (del *deleter) Amend(fn func(string) string) Deleter {
 	})
 }
 
+func (del *deleter) Comment(comment string) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		dq.comment = comment
+		return nil
+	})
+}
+
 func (dq *deleterQuery) arguments() []interface{} {
-	return joinArguments(dq.whereArgs)
+	return joinArguments(
+		dq.usingArgs,
+		dq.joinsArgs,
+		dq.whereArgs,
+	)
+}
+
+func (dq *deleterQuery) pushJoin(layout *exql.Template, t string, tables []interface{}) error {
+	if dq.using == nil {
+		return errors.New(`cannot use Join() without a preceding Using() expression`)
+	}
+
+	fragments, args, err := columnFragments(layout, tables)
+	if err != nil {
+		return err
+	}
+
+	dq.joins = append(dq.joins,
+		&exql.Join{
+			Type:  t,
+			Table: exql.JoinColumns(fragments...),
+		},
+	)
+
+	dq.joinsArgs = append(dq.joinsArgs, args...)
+
+	return nil
+}
+
+func (del *deleter) Using(tables ...interface{}) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		if del.template().DeleteUsingLayout == "" {
+			return exql.ErrDeleteUsingUnsupported
+		}
+		fragments, args, err := columnFragments(del.template(), tables)
+		if err != nil {
+			return err
+		}
+		dq.using = exql.JoinColumns(fragments...)
+		dq.usingArgs = args
+		return nil
+	})
+}
+
+func (del *deleter) Join(tables ...interface{}) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		return dq.pushJoin(del.template(), "", tables)
+	})
+}
+
+func (del *deleter) LeftJoin(tables ...interface{}) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		return dq.pushJoin(del.template(), "LEFT", tables)
+	})
+}
+
+func (del *deleter) On(terms ...interface{}) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		joins := len(dq.joins)
+
+		if joins == 0 {
+			return errors.New(`cannot use On() without a preceding Join() expression`)
+		}
+
+		lastJoin := dq.joins[joins-1]
+		if lastJoin.On != nil {
+			return errors.New(`cannot use On() twice with the same Join() expression`)
+		}
+
+		w, a, err := del.SQLBuilder().t.toWhereWithArguments(terms)
+		if err != nil {
+			return err
+		}
+		o := exql.On(w)
+
+		lastJoin.On = &o
+
+		dq.joinsArgs = append(dq.joinsArgs, a...)
+
+		return nil
+	})
 }
 
 func (del *deleter) Arguments() []interface{} {
@@ -139,6 +280,26 @@ func (del *deleter) PrepareContext(ctx context.Context) (*sql.Stmt, error) {
 	return del.SQLBuilder().sess.StatementPrepare(ctx, dq.statement())
 }
 
+func (del *deleter) Explain(ctx context.Context, analyze bool) (string, error) {
+	return del.explain(ctx, ExplainOptions{Analyze: analyze})
+}
+
+func (del *deleter) ExplainJSON(ctx context.Context, analyze bool) (string, error) {
+	return del.explain(ctx, ExplainOptions{Analyze: analyze, JSON: true})
+}
+
+func (del *deleter) explain(ctx context.Context, opts ExplainOptions) (string, error) {
+	dq, err := del.build()
+	if err != nil {
+		return "", err
+	}
+	return explain(ctx, del.SQLBuilder().sess, dq.statement(), dq.arguments(), opts)
+}
+
+func (del *deleter) Fingerprint() string {
+	return fingerprint(del)
+}
+
 func (del *deleter) Exec() (sql.Result, error) {
 	return del.ExecContext(del.SQLBuilder().sess.Context())
 }
@@ -151,6 +312,25 @@ func (del *deleter) ExecContext(ctx context.Context) (sql.Result, error) {
 	return del.SQLBuilder().sess.StatementExec(ctx, dq.statement(), dq.arguments()...)
 }
 
+func (del *deleter) Returning(columns ...string) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		return columnsToFragments(&dq.returning, columns)
+	})
+}
+
+func (del *deleter) Iterator() Iterator {
+	return del.IteratorContext(del.SQLBuilder().sess.Context())
+}
+
+func (del *deleter) IteratorContext(ctx context.Context) Iterator {
+	dq, err := del.build()
+	if err != nil {
+		return &iterator{sess: del.SQLBuilder().sess, err: err}
+	}
+	rows, err := queryCursor(ctx, del.SQLBuilder().sess, dq.statement(), dq.arguments())
+	return &iterator{sess: del.SQLBuilder().sess, cursor: rows, err: err}
+}
+
 func (del *deleter) statement() (*exql.Statement, error) {
 	iq, err := del.build()
 	if err != nil {
@@ -160,11 +340,15 @@ func (del *deleter) statement() (*exql.Statement, error) {
 }
 
 func (del *deleter) build() (*deleterQuery, error) {
-	dq, err := immutable.FastForward(del)
+	v, err := immutable.FastForward(del)
 	if err != nil {
 		return nil, err
 	}
-	return dq.(*deleterQuery), nil
+	dq := v.(*deleterQuery)
+	if dq.using != nil && dq.limit != 0 {
+		return nil, errors.New(`cannot use Limit() and Using() in the same delete`)
+	}
+	return dq, nil
 }
 
 func (del *deleter) Compile() (string, error) {
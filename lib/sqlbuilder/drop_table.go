@@ -0,0 +1,131 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/frazercomputing/upper-io-db/internal/immutable"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+)
+
+type tableDropperQuery struct {
+	table    string
+	ifExists bool
+	cascade  bool
+}
+
+func (dq *tableDropperQuery) statement() *exql.Statement {
+	return &exql.Statement{
+		Type:     exql.DropTable,
+		Table:    exql.TableWithName(dq.table),
+		IfExists: dq.ifExists,
+		Cascade:  dq.cascade,
+	}
+}
+
+type tableDropper struct {
+	builder *sqlBuilder
+
+	fn   func(*tableDropperQuery) error
+	prev *tableDropper
+}
+
+var _ = immutable.Immutable(&tableDropper{})
+
+func (td *tableDropper) SQLBuilder() *sqlBuilder {
+	if td.prev == nil {
+		return td.builder
+	}
+	return td.prev.SQLBuilder()
+}
+
+func (td *tableDropper) template() *exql.Template {
+	return td.SQLBuilder().t.Template
+}
+
+func (td *tableDropper) String() string {
+	s, err := td.Compile()
+	if err != nil {
+		panic(err.Error())
+	}
+	return prepareQueryForDisplay(td.template(), s)
+}
+
+func (td *tableDropper) setTable(table string) *tableDropper {
+	return td.frame(func(dq *tableDropperQuery) error {
+		dq.table = table
+		return nil
+	})
+}
+
+func (td *tableDropper) frame(fn func(*tableDropperQuery) error) *tableDropper {
+	return &tableDropper{prev: td, fn: fn}
+}
+
+func (td *tableDropper) IfExists() TableDropper {
+	return td.frame(func(dq *tableDropperQuery) error {
+		dq.ifExists = true
+		return nil
+	})
+}
+
+func (td *tableDropper) Cascade() TableDropper {
+	return td.frame(func(dq *tableDropperQuery) error {
+		dq.cascade = true
+		return nil
+	})
+}
+
+func (td *tableDropper) Exec() (sql.Result, error) {
+	return td.ExecContext(td.SQLBuilder().sess.Context())
+}
+
+func (td *tableDropper) ExecContext(ctx context.Context) (sql.Result, error) {
+	dq, err := td.build()
+	if err != nil {
+		return nil, err
+	}
+	return td.SQLBuilder().sess.StatementExec(ctx, dq.statement())
+}
+
+func (td *tableDropper) statement() (*exql.Statement, error) {
+	dq, err := td.build()
+	if err != nil {
+		return nil, err
+	}
+	return dq.statement(), nil
+}
+
+func (td *tableDropper) build() (*tableDropperQuery, error) {
+	dq, err := immutable.FastForward(td)
+	if err != nil {
+		return nil, err
+	}
+	return dq.(*tableDropperQuery), nil
+}
+
+func (td *tableDropper) Compile() (string, error) {
+	s, err := td.statement()
+	if err != nil {
+		return "", err
+	}
+	return s.Compile(td.template())
+}
+
+func (td *tableDropper) Prev() immutable.Immutable {
+	if td == nil {
+		return nil
+	}
+	return td.prev
+}
+
+func (td *tableDropper) Fn(in interface{}) error {
+	if td.fn == nil {
+		return nil
+	}
+	return td.fn(in.(*tableDropperQuery))
+}
+
+func (td *tableDropper) Base() interface{} {
+	return &tableDropperQuery{}
+}
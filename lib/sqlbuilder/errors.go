@@ -2,6 +2,7 @@ package sqlbuilder
 
 import (
 	"errors"
+	"fmt"
 )
 
 // Common error messages.
@@ -11,4 +12,33 @@ var (
 	ErrExpectingSliceMapStruct             = errors.New(`argument must be a slice address of maps or structs`)
 	ErrExpectingMapOrStruct                = errors.New(`argument must be either a map or a struct`)
 	ErrExpectingPointerToEitherMapOrStruct = errors.New(`expecting a pointer to either a map or a struct`)
+	ErrExpectingStruct                     = errors.New(`argument must be a struct`)
+	ErrExpectingSingleColumn               = errors.New(`a slice of values requires a result with exactly one column`)
+	ErrExpectingTwoColumns                 = errors.New(`a map requires a result with exactly two columns`)
+	ErrDuplicateKey                        = errors.New(`result has more than one row with the same key`)
+	ErrOrderByNotAllowed                   = errors.New(`sort field is not allowed`)
+	ErrInvalidSortDirection                = errors.New(`sort direction must be either "asc" or "desc"`)
+	ErrMissingConflictTarget               = errors.New(`upsert requires the columns of a unique constraint, see OnConflict`)
+	ErrInvalidSamplePercent                = errors.New(`sample percent must be greater than 0 and at most 100`)
+	ErrReturningColumnsOnly                = errors.New(`this database can only return columns of the inserted rows, not expressions`)
+	ErrUnsupportedExprOperator             = errors.New(`unsupported expression operator %v`)
 )
+
+// CloseTimeoutError is returned by CloseContext when the context expires
+// before all in-flight operations are done.
+type CloseTimeoutError struct {
+	// Err is the context's error, usually context.DeadlineExceeded.
+	Err error
+
+	// Pending is the number of operations that were still running.
+	Pending int
+}
+
+func (e *CloseTimeoutError) Error() string {
+	return fmt.Sprintf("%v while waiting for %d in-flight operation(s) to finish", e.Err, e.Pending)
+}
+
+// Unwrap returns the context's error.
+func (e *CloseTimeoutError) Unwrap() error {
+	return e.Err
+}
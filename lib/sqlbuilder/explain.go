@@ -0,0 +1,31 @@
+package sqlbuilder
+
+import (
+	"context"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+)
+
+// ExplainOptions tells adapters which kind of plan to return.
+type ExplainOptions struct {
+	// Analyze runs the statement and includes actual row counts and timings
+	// in the plan. The statement runs inside a transaction that is rolled
+	// back, so it has no lasting effects.
+	Analyze bool
+
+	// JSON asks for a machine-readable plan.
+	JSON bool
+}
+
+type hasStatementExplain interface {
+	StatementExplain(ctx context.Context, stmt *exql.Statement, args []interface{}, opts ExplainOptions) (string, error)
+}
+
+func explain(ctx context.Context, sess exprDB, stmt *exql.Statement, args []interface{}, opts ExplainOptions) (string, error) {
+	explainer, ok := sess.(hasStatementExplain)
+	if !ok {
+		return "", db.ErrUnsupported
+	}
+	return explainer.StatementExplain(ctx, stmt, args, opts)
+}
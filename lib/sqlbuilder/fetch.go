@@ -23,6 +23,8 @@ package sqlbuilder
 
 import (
 	"reflect"
+	"strconv"
+	"time"
 
 	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/lib/reflectx"
@@ -32,6 +34,10 @@ type hasConvertValues interface {
 	ConvertValues(values []interface{}) []interface{}
 }
 
+type hasForceUTC interface {
+	ForceUTC() bool
+}
+
 var mapper = reflectx.NewMapper("db")
 
 // fetchRow receives a *sql.Rows value and tries to map all the rows into a
@@ -41,6 +47,7 @@ func fetchRow(iter *iterator, dst interface{}) error {
 	var err error
 
 	rows := iter.cursor
+	iter.resetTotal()
 
 	dstv := reflect.ValueOf(dst)
 
@@ -86,7 +93,15 @@ func fetchRow(iter *iterator, dst interface{}) error {
 func fetchRows(iter *iterator, dst interface{}) error {
 	var err error
 	rows := iter.cursor
-	defer rows.Close()
+	if rows != nil {
+		defer rows.Close()
+	}
+	iter.resetTotal()
+
+	overwrite := false
+	if ow, ok := dst.(overwriteKeys); ok {
+		dst, overwrite = ow.dst, true
+	}
 
 	// Destination.
 	dstv := reflect.ValueOf(dst)
@@ -95,6 +110,10 @@ func fetchRows(iter *iterator, dst interface{}) error {
 		return ErrExpectingPointer
 	}
 
+	if dstv.Elem().Kind() == reflect.Map {
+		return fetchMap(iter, dstv.Elem(), overwrite)
+	}
+
 	if dstv.Elem().Kind() != reflect.Slice {
 		return ErrExpectingSlicePointer
 	}
@@ -103,6 +122,12 @@ func fetchRows(iter *iterator, dst interface{}) error {
 		return ErrExpectingSliceMapStruct
 	}
 
+	if rows == nil {
+		// The cursor was already exhausted, this is an empty result and not an
+		// error.
+		return reset(dst)
+	}
+
 	var columns []string
 	if columns, err = rows.Columns(); err != nil {
 		return err
@@ -113,6 +138,22 @@ func fetchRows(iter *iterator, dst interface{}) error {
 
 	reset(dst)
 
+	if !isRowDestination(reflect.New(itemT).Interface()) {
+		// A slice of plain values, like []int64 or []*string.
+		if len(columns) != 1 {
+			return ErrExpectingSingleColumn
+		}
+		for rows.Next() {
+			item := reflect.New(itemT)
+			if err := scanValues(iter, rows, item.Interface()); err != nil {
+				return err
+			}
+			slicev = reflect.Append(slicev, item.Elem())
+		}
+		dstv.Elem().Set(slicev)
+		return rows.Err()
+	}
+
 	for rows.Next() {
 		item, err := fetchResult(iter, itemT, columns)
 		if err != nil {
@@ -143,11 +184,18 @@ func fetchResult(iter *iterator, itemT reflect.Type, columns []string) (reflect.
 	case reflect.Struct:
 		item = reflect.New(objT)
 	case reflect.Ptr:
+		// A new element is allocated for each row, like for []*Artist or
+		// []*map[string]interface{}.
 		objT = itemT.Elem()
-		if objT.Kind() != reflect.Struct {
+		switch objT.Kind() {
+		case reflect.Struct:
+			item = reflect.New(objT)
+		case reflect.Map:
+			item = reflect.New(objT)
+			item.Elem().Set(reflect.MakeMap(objT))
+		default:
 			return item, ErrExpectingMapOrStruct
 		}
-		item = reflect.New(objT)
 	default:
 		return item, ErrExpectingMapOrStruct
 	}
@@ -179,6 +227,7 @@ func fetchResult(iter *iterator, itemT reflect.Type, columns []string) (reflect.
 			}
 		}
 
+		scanned := values
 		if converter, ok := iter.sess.(hasConvertValues); ok {
 			values = converter.ConvertValues(values)
 		}
@@ -186,6 +235,8 @@ func fetchResult(iter *iterator, itemT reflect.Type, columns []string) (reflect.
 		if err = rows.Scan(values...); err != nil {
 			return item, err
 		}
+		forceUTC(iter.sess, values)
+		iter.setTotal(columns, scanned)
 	case reflect.Map:
 
 		columns, err := rows.Columns()
@@ -195,25 +246,173 @@ func fetchResult(iter *iterator, itemT reflect.Type, columns []string) (reflect.
 
 		values := make([]interface{}, len(columns))
 		for i := range values {
-			if itemT.Elem().Kind() == reflect.Interface {
+			if objT.Elem().Kind() == reflect.Interface {
 				values[i] = new(interface{})
 			} else {
-				values[i] = reflect.New(itemT.Elem()).Interface()
+				values[i] = reflect.New(objT.Elem()).Interface()
 			}
 		}
 
 		if err = rows.Scan(values...); err != nil {
 			return item, err
 		}
+		forceUTC(iter.sess, values)
+		iter.setTotal(columns, values)
 
+		m := reflect.Indirect(item)
 		for i, column := range columns {
-			item.SetMapIndex(reflect.ValueOf(column), reflect.Indirect(reflect.ValueOf(values[i])))
+			m.SetMapIndex(reflect.ValueOf(column), reflect.Indirect(reflect.ValueOf(values[i])))
 		}
 	}
 
 	return item, nil
 }
 
+// resetTotal zeroes the destination given to WithTotalCount, which keeps
+// that value when there are no rows to read the total from.
+func (iter *iterator) resetTotal() {
+	if iter.total != nil && iter.total.dst != nil {
+		*iter.total.dst = 0
+	}
+}
+
+// setTotal copies the value of the WithTotalCount column, out of the values
+// that were scanned for the current row, into the destination given to
+// WithTotalCount.
+func (iter *iterator) setTotal(columns []string, values []interface{}) {
+	if iter.total == nil || iter.total.dst == nil {
+		return
+	}
+	for i, column := range columns {
+		if column != iter.total.column {
+			continue
+		}
+		if n, ok := toInt(reflect.Indirect(reflect.ValueOf(values[i])).Interface()); ok {
+			*iter.total.dst = n
+		}
+		return
+	}
+}
+
+// toInt converts the value drivers return for a COUNT into an int.
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	case []byte:
+		i, err := strconv.Atoi(string(n))
+		return i, err == nil
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// overwriteKeys wraps a map destination whose duplicated keys are
+// overwritten.
+type overwriteKeys struct {
+	dst interface{}
+}
+
+// OverwriteKeys wraps a pointer to a map given to All, so that rows with a
+// key that was already seen replace the previous value instead of failing
+// with ErrDuplicateKey:
+//
+//	err = iter.All(sqlbuilder.OverwriteKeys(&namesByID))
+func OverwriteKeys(dst interface{}) interface{} {
+	return overwriteKeys{dst}
+}
+
+// fetchMap dumps a two-column result into a map, the first column is the
+// key and the second one the value.
+func fetchMap(iter *iterator, mapv reflect.Value, overwrite bool) error {
+	mapT := mapv.Type()
+	m := reflect.MakeMap(mapT)
+
+	rows := iter.cursor
+	if rows == nil {
+		// The cursor was already exhausted.
+		mapv.Set(m)
+		return nil
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(columns) != 2 {
+		return ErrExpectingTwoColumns
+	}
+
+	for rows.Next() {
+		key, value := reflect.New(mapT.Key()), reflect.New(mapT.Elem())
+		if err := scanValues(iter, rows, key.Interface(), value.Interface()); err != nil {
+			return err
+		}
+		k := key.Elem()
+		if b, ok := k.Interface().([]byte); ok && k.Kind() == reflect.Interface {
+			// Slices can't be map keys.
+			k = reflect.ValueOf(string(b))
+		}
+		if !overwrite && m.MapIndex(k).IsValid() {
+			return ErrDuplicateKey
+		}
+		m.SetMapIndex(k, value.Elem())
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	mapv.Set(m)
+	return nil
+}
+
+// scanValues scans the current row into the given destinations, after
+// converting them with the session's ConvertValues, if any.
+func scanValues(iter *iterator, rows cursor, dst ...interface{}) error {
+	values := dst
+	if converter, ok := iter.sess.(hasConvertValues); ok {
+		values = converter.ConvertValues(append([]interface{}(nil), dst...))
+	}
+	if err := rows.Scan(values...); err != nil {
+		return err
+	}
+	forceUTC(iter.sess, dst)
+	return nil
+}
+
+// forceUTC converts the time.Time values that were scanned into values to
+// UTC, if the session was configured to do so.
+func forceUTC(sess interface{}, values []interface{}) {
+	if s, ok := sess.(hasForceUTC); !ok || !s.ForceUTC() {
+		return
+	}
+	for _, v := range values {
+		switch t := v.(type) {
+		case *time.Time:
+			*t = t.UTC()
+		case **time.Time:
+			if *t != nil {
+				u := (*t).UTC()
+				*t = &u
+			}
+		case *interface{}:
+			if u, ok := (*t).(time.Time); ok {
+				*t = u.UTC()
+			}
+		}
+	}
+}
+
 func reset(data interface{}) error {
 	// Resetting element.
 	v := reflect.ValueOf(data).Elem()
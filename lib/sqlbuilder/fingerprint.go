@@ -0,0 +1,113 @@
+package sqlbuilder
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+)
+
+var (
+	reRepeatedPlaceholders = regexp.MustCompile(`\?(\s*,\s*\?)+`)
+)
+
+// Fingerprint returns the normalized text of the given query. Queries that
+// differ only in their argument values, literals, comments, whitespace or in
+// the length of a list of values, like the one given to IN, share the same
+// fingerprint:
+//
+//	SELECT * FROM "artist" WHERE ("id" IN (?))
+//
+// Fingerprints can be used to group queries in logs and metrics.
+func Fingerprint(query string) string {
+	return normalizeQuery(query)
+}
+
+// normalizeQuery replaces placeholders and literals in query with "?" and
+// removes comments and redundant whitespace.
+func normalizeQuery(in string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(in); i++ {
+		c := in[i]
+		if n := exql.LiteralLen(in, i); n > 0 {
+			switch {
+			case c == '"' || c == '`':
+				// Quoted identifier.
+				b.WriteString(in[i : i+n])
+			case c == '-' || c == '/':
+				// Comment.
+				b.WriteByte(' ')
+			default:
+				// String literal, including dollar-quoted ones.
+				b.WriteByte('?')
+			}
+			i += n - 1
+			continue
+		}
+		switch {
+		case c == '[':
+			// Quoted identifier.
+			j := strings.IndexByte(in[i+1:], ']')
+			if j < 0 {
+				b.WriteString(in[i:])
+				i = len(in)
+				continue
+			}
+			b.WriteString(in[i : i+j+2])
+			i += j + 1
+		case placeholderLen(in[i:]) > 0:
+			// Numbered placeholders: $1, @p1 or :1.
+			i += placeholderLen(in[i:]) - 1
+			b.WriteByte('?')
+		case isDigit(c) && (i == 0 || !isIdentifierChar(in[i-1])):
+			for ; i+1 < len(in) && (isDigit(in[i+1]) || in[i+1] == '.'); i++ {
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	out := reInvisibleChars.ReplaceAllString(b.String(), " ")
+	out = reRepeatedPlaceholders.ReplaceAllString(out, "?")
+	return strings.TrimSpace(out)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// placeholderLen returns the length of the numbered placeholder at the
+// beginning of s, if any.
+func placeholderLen(s string) int {
+	var n int
+	switch {
+	case strings.HasPrefix(s, "$"), strings.HasPrefix(s, ":"):
+		n = 1
+	case strings.HasPrefix(s, "@p"):
+		n = 2
+	default:
+		return 0
+	}
+	digits := 0
+	for n+digits < len(s) && isDigit(s[n+digits]) {
+		digits++
+	}
+	if digits == 0 {
+		return 0
+	}
+	return n + digits
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func fingerprint(c compilable) string {
+	q, err := c.Compile()
+	if err != nil {
+		return ""
+	}
+	return Fingerprint(q)
+}
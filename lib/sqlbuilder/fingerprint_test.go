@@ -0,0 +1,53 @@
+package sqlbuilder
+
+import (
+	"testing"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out string
+	}{
+		{`SELECT * FROM "artist" WHERE ("id" = $1)`, `SELECT * FROM "artist" WHERE ("id" = ?)`},
+		{`SELECT * FROM [artist] WHERE ([id] = @p12)`, `SELECT * FROM [artist] WHERE ([id] = ?)`},
+		{"SELECT * FROM `artist2` WHERE (`id` IN (?, ?, ?))", "SELECT * FROM `artist2` WHERE (`id` IN (?))"},
+		{`SELECT * FROM "t1" WHERE name = 'O''Brien' AND x > 3.5 LIMIT 10`, `SELECT * FROM "t1" WHERE name = ? AND x > ? LIMIT ?`},
+		{"/* checkout */ SELECT  1 -- trailing\n  FROM \"x\"", `SELECT ? FROM "x"`},
+		{`SELECT "a::1" FROM t WHERE b::int = :1`, `SELECT "a::1" FROM t WHERE b::int = ?`},
+		{`SELECT $$it's $1$$ AS a, $fn$ -- 2 $fn$ AS b FROM "x"`, `SELECT ? AS a, ? AS b FROM "x"`},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.out, normalizeQuery(tc.in))
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	a := b.SelectFrom("artist").Where("name", "Ozzie").Limit(5)
+	c := b.SelectFrom("artist").Where("name", "Flea").Limit(10)
+	assert.Equal(t, a.Fingerprint(), c.Fingerprint())
+	assert.Equal(t, `SELECT * FROM "artist" WHERE ("name" = ?) LIMIT ?`, a.Fingerprint())
+
+	d := b.SelectFrom("artist").Where("id", 1)
+	assert.NotEqual(t, a.Fingerprint(), d.Fingerprint())
+
+	in1 := b.DeleteFrom("artist").Where(db.Cond{"id IN": []int{1, 2}})
+	in2 := b.DeleteFrom("artist").Where(db.Cond{"id IN": []int{3, 4, 5}})
+	assert.Equal(t, in1.Fingerprint(), in2.Fingerprint())
+
+	assert.Equal(t,
+		b.Update("artist").Set("name", "A").Where("id", 1).Fingerprint(),
+		Fingerprint(`UPDATE "artist" SET "name" = $1 WHERE ("id" = $2)`),
+	)
+
+	assert.Equal(t,
+		b.InsertInto("artist").Values(map[string]string{"name": "B"}).Fingerprint(),
+		b.InsertInto("artist").Values(map[string]string{"name": "C"}).Fingerprint(),
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 
+	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/immutable"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 )
@@ -12,11 +13,19 @@ type inserterQuery struct {
 	table          string
 	enqueuedValues [][]interface{}
 	returning      []exql.Fragment
+	returningArgs  []interface{}
 	columns        []exql.Fragment
 	values         []*exql.Values
 	arguments      []interface{}
 	extra          string
 	amendFn        func(string) string
+	comment        string
+
+	conflictTarget    []exql.Fragment
+	conflictUpdate    []exql.Fragment
+	conflictUpdateAll bool
+	inserted          bool
+	placeholders      bool
 }
 
 func (iq *inserterQuery) processValues() ([]*exql.Values, []interface{}, error) {
@@ -56,12 +65,17 @@ func (iq *inserterQuery) processValues() ([]*exql.Values, []interface{}, error)
 		}
 
 		if len(iq.columns) == 0 || len(enqueuedValue) == len(iq.columns) {
-			arguments = append(arguments, enqueuedValue...)
-
 			l := len(enqueuedValue)
 			placeholders := make([]exql.Fragment, l)
 			for i := 0; i < l; i++ {
+				if raw, ok := enqueuedValue[i].(db.RawValue); ok {
+					// Raw values, like db.Default, are put in place.
+					placeholders[i] = exql.RawValue(raw.Raw())
+					arguments = append(arguments, raw.Arguments()...)
+					continue
+				}
 				placeholders[i] = exql.RawValue(`?`)
+				arguments = append(arguments, enqueuedValue[i])
 			}
 			values = append(values, exql.NewValueGroup(placeholders...))
 		}
@@ -78,17 +92,40 @@ func (iq *inserterQuery) statement() *exql.Statement {
 
 	if len(iq.values) > 0 {
 		stmt.Values = exql.JoinValueGroups(iq.values...)
+	} else if iq.placeholders && len(iq.columns) > 0 {
+		// Prepared columns without values leave a placeholder for each column,
+		// values are given when the statement is executed.
+		placeholders := make([]exql.Fragment, len(iq.columns))
+		for i := range placeholders {
+			placeholders[i] = sqlPlaceholder
+		}
+		stmt.Values = exql.JoinValueGroups(exql.NewValueGroup(placeholders...))
 	}
 
 	if len(iq.columns) > 0 {
 		stmt.Columns = exql.JoinColumns(iq.columns...)
 	}
 
-	if len(iq.returning) > 0 {
-		stmt.Returning = exql.ReturningColumns(iq.returning...)
+	if len(iq.conflictTarget) > 0 {
+		update := iq.conflictUpdate
+		if iq.conflictUpdateAll {
+			update = columnsExcept(iq.columns, iq.conflictTarget)
+		}
+		stmt.OnConflict = &exql.OnConflict{
+			Target: exql.JoinColumns(iq.conflictTarget...),
+			Update: exql.JoinColumns(update...),
+			Insert: exql.JoinColumns(iq.columns...),
+		}
+	}
+
+	if len(iq.returning) > 0 || iq.inserted {
+		returning := exql.ReturningColumns(iq.returning...)
+		returning.Inserted = iq.inserted
+		stmt.Returning = returning
 	}
 
 	stmt.SetAmendment(iq.amendFn)
+	stmt.Comment = iq.comment
 
 	return stmt
 }
@@ -114,11 +151,15 @@ func (ins *inserter) template() *exql.Template {
 }
 
 func (ins *inserter) String() string {
-	s, err := ins.Compile()
+	stmt, err := ins.statement()
 	if err != nil {
 		panic(err.Error())
 	}
-	return prepareQueryForDisplay(s)
+	s, err := stmt.Compile(ins.template())
+	if err != nil {
+		panic(err.Error())
+	}
+	return exql.CommentPrefix(stmt.Comment) + prepareQueryForDisplay(ins.template(), s)
 }
 
 func (ins *inserter) frame(fn func(*inserterQuery) error) *inserter {
@@ -136,6 +177,13 @@ func (ins *inserter) Amend(fn func(string) string) Inserter {
 	})
 }
 
+func (ins *inserter) Comment(comment string) Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		iq.comment = comment
+		return nil
+	})
+}
+
 func (ins *inserter) Arguments() []interface{} {
 	iq, err := ins.build()
 	if err != nil {
@@ -151,6 +199,58 @@ func (ins *inserter) Returning(columns ...string) Inserter {
 	})
 }
 
+func (ins *inserter) ReturningExpr(columns ...interface{}) Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		fragments, args, err := columnFragments(ins.template(), columns)
+		if err != nil {
+			return err
+		}
+		if ins.template().ReturningColumnsOnly {
+			for _, f := range fragments {
+				if c, ok := f.(*exql.Column); !ok || !isColumnName(c) {
+					return ErrReturningColumnsOnly
+				}
+			}
+		}
+		iq.returning = append(iq.returning, fragments...)
+		iq.returningArgs = append(iq.returningArgs, args...)
+		return nil
+	})
+}
+
+func (ins *inserter) OnConflict(columns ...string) Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		if len(columns) == 0 {
+			return ErrMissingConflictTarget
+		}
+		iq.conflictTarget = nil
+		return columnsToFragments(&iq.conflictTarget, columns)
+	})
+}
+
+func (ins *inserter) DoUpdate(columns ...string) Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		iq.conflictUpdate = nil
+		iq.conflictUpdateAll = len(columns) == 0
+		return columnsToFragments(&iq.conflictUpdate, columns)
+	})
+}
+
+func (ins *inserter) DoNothing() Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		iq.conflictUpdate = nil
+		iq.conflictUpdateAll = false
+		return nil
+	})
+}
+
+func (ins *inserter) ReturningInserted() Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		iq.inserted = true
+		return nil
+	})
+}
+
 func (ins *inserter) Exec() (sql.Result, error) {
 	return ins.ExecContext(ins.SQLBuilder().sess.Context())
 }
@@ -168,13 +268,42 @@ func (ins *inserter) Prepare() (*sql.Stmt, error) {
 }
 
 func (ins *inserter) PrepareContext(ctx context.Context) (*sql.Stmt, error) {
-	iq, err := ins.build()
+	iq, err := ins.placeholders().build()
 	if err != nil {
 		return nil, err
 	}
 	return ins.SQLBuilder().sess.StatementPrepare(ctx, iq.statement())
 }
 
+// placeholders makes columns given without values take a placeholder each,
+// for statements that are prepared and executed with the values later.
+func (ins *inserter) placeholders() *inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		iq.placeholders = true
+		return nil
+	})
+}
+
+func (ins *inserter) Explain(ctx context.Context, analyze bool) (string, error) {
+	return ins.explain(ctx, ExplainOptions{Analyze: analyze})
+}
+
+func (ins *inserter) ExplainJSON(ctx context.Context, analyze bool) (string, error) {
+	return ins.explain(ctx, ExplainOptions{Analyze: analyze, JSON: true})
+}
+
+func (ins *inserter) explain(ctx context.Context, opts ExplainOptions) (string, error) {
+	iq, err := ins.build()
+	if err != nil {
+		return "", err
+	}
+	return explain(ctx, ins.SQLBuilder().sess, iq.statement(), iq.arguments, opts)
+}
+
+func (ins *inserter) Fingerprint() string {
+	return fingerprint(ins)
+}
+
 func (ins *inserter) Query() (*sql.Rows, error) {
 	return ins.QueryContext(ins.SQLBuilder().sess.Context())
 }
@@ -204,8 +333,12 @@ func (ins *inserter) Iterator() Iterator {
 }
 
 func (ins *inserter) IteratorContext(ctx context.Context) Iterator {
-	rows, err := ins.QueryContext(ctx)
-	return &iterator{ins.SQLBuilder().sess, rows, err}
+	iq, err := ins.build()
+	if err != nil {
+		return &iterator{sess: ins.SQLBuilder().sess, err: err}
+	}
+	rows, err := queryCursor(ctx, ins.SQLBuilder().sess, iq.statement(), iq.arguments)
+	return &iterator{sess: ins.SQLBuilder().sess, cursor: rows, err: err}
 }
 
 func (ins *inserter) Into(table string) Inserter {
@@ -243,10 +376,18 @@ func (ins *inserter) build() (*inserterQuery, error) {
 		return nil, err
 	}
 	ret := iq.(*inserterQuery)
+	if len(ret.conflictTarget) == 0 && (ret.inserted || len(ret.conflictUpdate) > 0 || ret.conflictUpdateAll) {
+		return nil, ErrMissingConflictTarget
+	}
+	if t := ins.template(); (len(ret.conflictTarget) > 0 && t.OnConflictLayout == "") || (ret.inserted && t.InsertedLayout == "") {
+		return nil, exql.ErrUpsertUnsupported
+	}
 	ret.values, ret.arguments, err = ret.processValues()
 	if err != nil {
 		return nil, err
 	}
+	// RETURNING goes after VALUES.
+	ret.arguments = append(ret.arguments, ret.returningArgs...)
 	return ret, nil
 }
 
@@ -285,3 +426,28 @@ func columnsToFragments(dst *[]exql.Fragment, columns []string) error {
 	*dst = append(*dst, f...)
 	return nil
 }
+
+// isColumnName tells whether the column is made of a column name, and maybe
+// an alias, as opposed to an expression.
+func isColumnName(c *exql.Column) bool {
+	_, ok := c.Name.(string)
+	return ok
+}
+
+// columnsExcept returns the columns that are not in except.
+func columnsExcept(columns []exql.Fragment, except []exql.Fragment) []exql.Fragment {
+	out := make([]exql.Fragment, 0, len(columns))
+	for _, column := range columns {
+		found := false
+		for _, e := range except {
+			if column.Hash() == e.Hash() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, column)
+		}
+	}
+	return out
+}
@@ -25,6 +25,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
 )
 
 // SQLBuilder defines methods that can be used to build a SQL query with
@@ -82,6 +86,30 @@ type SQLBuilder interface {
 	//  q := sqlbuilder.Update("profile").Set(...).Where(...)
 	Update(table string) Updater
 
+	// CreateTable prepares a TableCreator that creates the given table. It's
+	// meant as a convenience for tests and prototypes, not as a replacement
+	// for proper migrations.
+	//
+	// Example:
+	//
+	//  q := sqlbuilder.CreateTable("accounts").Struct(Account{}).IfNotExists()
+	CreateTable(table string) TableCreator
+
+	// DropTable prepares a TableDropper that drops the given table.
+	//
+	// Example:
+	//
+	//  q := sqlbuilder.DropTable("accounts").IfExists()
+	DropTable(table string) TableDropper
+
+	// AlterTable prepares a TableAlterer that changes the columns of the given
+	// table.
+	//
+	// Example:
+	//
+	//  q := sqlbuilder.AlterTable("accounts").AddColumn("nickname", "text")
+	AlterTable(table string) TableAlterer
+
 	// Exec executes a SQL query that does not return any rows, like sql.Exec.
 	// Queries can be either strings or upper-db statements.
 	//
@@ -103,11 +131,33 @@ type SQLBuilder interface {
 	// longer needed.
 	Prepare(query interface{}) (*sql.Stmt, error)
 
-	// Prepare creates a prepared statement on the guiven context for later
+	// PrepareContext creates a prepared statement on the given context for later
 	// queries or executions. The caller must call the statement's Close method
 	// when the statement is no longer needed.
 	PrepareContext(ctx context.Context, query interface{}) (*sql.Stmt, error)
 
+	// PrepareStmt is like Prepare, but queries can also be builders and the
+	// statement converts its arguments like the session does, see Stmt. An
+	// inserter given columns without values takes a placeholder for each
+	// column.
+	//
+	// Prepared within a transaction, a statement is parsed once and can be run
+	// many times on the transaction's connection, it's closed along with the
+	// transaction.
+	//
+	// Example:
+	//
+	//  stmt, err := tx.PrepareStmt(tx.InsertInto("books").Columns("title", "author_id"))
+	//  ...
+	//  for _, book := range books {
+	//    _, err = stmt.Exec(book.Title, book.AuthorID)
+	//    ...
+	//  }
+	PrepareStmt(query interface{}) (*Stmt, error)
+
+	// PrepareStmtContext is like PrepareStmt, on the given context.
+	PrepareStmtContext(ctx context.Context, query interface{}) (*Stmt, error)
+
 	// Query executes a SQL query that returns rows, like sql.Query.  Queries can
 	// be either strings or upper-db statements.
 	//
@@ -158,6 +208,18 @@ type SQLBuilder interface {
 }
 
 // Selector represents a SELECT statement.
+//
+// Selectors are immutable, every method that changes the statement returns a
+// new Selector and leaves the one it was called on as it was, so a base query
+// can be shared and extended safely, even from several goroutines:
+//
+//	base := sess.SelectFrom("artist").Where("active", true)
+//
+//	top := base.OrderBy("-rating").Limit(10)
+//	newest := base.OrderBy("-created_at")
+//
+// Values given to a Selector, like a db.Cond map or a slice of columns, are
+// read when the statement is compiled and must not be modified afterwards.
 type Selector interface {
 	// Columns defines which columns to retrive.
 	//
@@ -244,6 +306,14 @@ type Selector interface {
 	// conditions that have been already set.
 	And(conds ...interface{}) Selector
 
+	// Or groups the conditions that have been already set and the given ones
+	// and joins both groups with OR, e.g.:
+	//
+	//   s.Where("a", 1).And("b", 2).Or("c", 3)
+	//
+	// is equivalent to WHERE ((a = 1 AND b = 2) OR (c = 3)).
+	Or(conds ...interface{}) Selector
+
 	// GroupBy represents a GROUP BY statement.
 	//
 	// GROUP BY defines which columns should be used to aggregate and group
@@ -279,6 +349,16 @@ type Selector interface {
 	//   s.OrderBy("last_name ASC")
 	//
 	//   s.OrderBy("last_name DESC", "name ASC")
+	//
+	// Use db.Asc and db.Desc to control where NULL values are placed.
+	//
+	//   // "last_name" DESC NULLS LAST
+	//   s.OrderBy(db.Desc("last_name").NullsLast())
+	//
+	// Collate sorts by a collation other than the column's own.
+	//
+	//   // "last_name" COLLATE "C" ASC
+	//   s.OrderBy(db.Col("last_name").Collate("C"))
 	OrderBy(columns ...interface{}) Selector
 
 	// Join represents a JOIN statement.
@@ -342,10 +422,89 @@ type Selector interface {
 	// s.Offset(56)
 	Offset(int) Selector
 
+	// ForUpdate locks the selected rows against concurrent updates until the
+	// end of the current transaction (SELECT ... FOR UPDATE). Databases
+	// without row locks return an error when the query is run.
+	//
+	//   tx.SelectFrom("jobs").Where("status", "pending").ForUpdate()
+	ForUpdate() Selector
+
+	// SkipLocked works like ForUpdate but rows that are already locked by
+	// other transactions are skipped instead of waited on (SKIP LOCKED on
+	// PostgreSQL and MySQL, READPAST on SQL Server). This is the building block
+	// for table-backed job queues.
+	SkipLocked() Selector
+
+	// WithTotalCount adds a column with the given name that holds the number
+	// of rows the query would return without Limit and Offset, computed with
+	// the COUNT(*) OVER () window function, so a page of rows and the total
+	// come in the same query. The total is scanned into the struct field or
+	// map key with that name, if any, and into total when it's given:
+	//
+	//   var total int
+	//   err = sess.SelectFrom("artist").
+	//     OrderBy("name").
+	//     Limit(20).Offset(40).
+	//     WithTotalCount("total", &total).
+	//     All(&artists)
+	//
+	// total is set to zero when the page is empty, even if previous pages are
+	// not. Databases without window functions return an error when the query
+	// runs.
+	WithTotalCount(column string, total ...*int) Selector
+
+	// Sample reads a sample of about the given percent of the rows of the
+	// table, with TABLESAMPLE SYSTEM on PostgreSQL and TABLESAMPLE ... PERCENT
+	// on SQL Server, for exploring data or picking test data:
+	//
+	//   q := sess.SelectFrom("events").Sample(1.5)
+	//
+	// Sampling is fast as whole pages of the table are picked at random
+	// instead of rows, so the rows of a sample tend to be stored together and
+	// its size varies, small tables may even give empty samples. Conditions
+	// are applied to the rows of the sample. Other databases return an error.
+	Sample(percent float64) Selector
+
+	// Random sorts the rows randomly, replacing any previous order, which
+	// together with Limit gives a small sample where every row is equally
+	// likely to be picked:
+	//
+	//   q := sess.SelectFrom("artist").Random().Limit(10)
+	//
+	// Unlike Sample, the database reads every row that matches the conditions
+	// and sorts them all, so it's slow on large tables. It uses random() on
+	// PostgreSQL and SQLite, RAND() on MySQL and NEWID() on SQL Server, QL
+	// returns an error.
+	Random() Selector
+
+	// Cacheable lets the rows of the query be served from the session's
+	// result cache, see Database.SetResultCache, for up to ttl after they're
+	// read from the database:
+	//
+	//   err = sess.SelectFrom("country").
+	//     OrderBy("name").
+	//     Cacheable(10 * time.Minute).
+	//     All(&countries)
+	//
+	// Rows are cached under the query and its arguments, so changing either
+	// reads the database again. Cached rows are not invalidated by writes,
+	// made through this session or any other, they're served until ttl
+	// expires even if they're stale by then, so Cacheable is meant for data
+	// that rarely changes or that can be shown a bit out of date. Queries
+	// are not cached when ttl is not positive, when the session has no result
+	// cache or within transactions.
+	Cacheable(ttl time.Duration) Selector
+
 	// Amend lets you alter the query's text just before sending it to the
 	// database server.
 	Amend(func(queryIn string) (queryOut string)) Selector
 
+	// Comment tags the query with a SQL comment, like
+	// "/* checkout-service:recalc */", that is sent along with it and shows up
+	// in server-side statistics and activity views. Comment delimiters are
+	// removed from the text.
+	Comment(comment string) Selector
+
 	// Paginate returns a paginator that can display a paginated lists of items.
 	// Paginators ignore previous Offset and Limit settings. Page numbering
 	// starts at 1.
@@ -362,6 +521,9 @@ type Selector interface {
 	// Preparer provides methods for creating prepared statements.
 	Preparer
 
+	// Explainer provides methods for inspecting query plans.
+	Explainer
+
 	// Getter provides methods to compile and execute a query that returns
 	// results.
 	Getter
@@ -375,9 +537,15 @@ type Selector interface {
 
 	// Arguments returns the arguments that are prepared for this query.
 	Arguments() []interface{}
+
+	// Fingerprint returns the normalized text of the query, queries that only
+	// differ in their argument values share the same fingerprint. See
+	// sqlbuilder.Fingerprint.
+	Fingerprint() string
 }
 
-// Inserter represents an INSERT statement.
+// Inserter represents an INSERT statement. Inserters are immutable, see
+// Selector.
 type Inserter interface {
 	// Columns represents the COLUMNS clause.
 	//
@@ -398,6 +566,11 @@ type Inserter interface {
 	// Arguments returns the arguments that are prepared for this query.
 	Arguments() []interface{}
 
+	// Fingerprint returns the normalized text of the query, queries that only
+	// differ in their argument values share the same fingerprint. See
+	// sqlbuilder.Fingerprint.
+	Fingerprint() string
+
 	// Returning represents a RETURNING clause.
 	//
 	// RETURNING specifies which columns should be returned after INSERT.
@@ -405,6 +578,51 @@ type Inserter interface {
 	// RETURNING may not be supported by all SQL databases.
 	Returning(columns ...string) Inserter
 
+	// ReturningExpr works like Returning but, besides column names, which may
+	// be given names like "created_at AS added", it takes expressions, like
+	// db.Raw and db.Func, which are scanned by their alias:
+	//
+	//   i.Values(item).ReturningExpr("id", db.Raw("now() - created_at AS age"))
+	//
+	// SQL Server's OUTPUT only returns columns, expressions make the query
+	// fail with ErrReturningColumnsOnly.
+	ReturningExpr(columns ...interface{}) Inserter
+
+	// OnConflict turns the statement into an upsert, rows that conflict with
+	// an existing one on the unique constraint made of the given columns are
+	// either left alone, see DoNothing, which is the default, or update the
+	// existing row, see DoUpdate.
+	//
+	//   i.Values(item).OnConflict("email").DoUpdate("name")
+	//
+	// PostgreSQL and SQLite use ON CONFLICT, MySQL uses ON DUPLICATE KEY
+	// UPDATE, where the columns are only used by DoNothing, and MSSQL uses
+	// MERGE.
+	OnConflict(columns ...string) Inserter
+
+	// DoUpdate sets the given columns of the conflicting row to the values
+	// that were going to be inserted. With no columns, every inserted column
+	// that is not part of the OnConflict constraint is set.
+	DoUpdate(columns ...string) Inserter
+
+	// DoNothing leaves conflicting rows as they are, no rows are returned for
+	// them when using Returning.
+	DoNothing() Inserter
+
+	// ReturningInserted adds a boolean "inserted" column to the returned
+	// rows, it's true for the rows that were inserted and false for the ones
+	// that were updated by DoUpdate. It requires OnConflict and it's
+	// supported on PostgreSQL, which checks (xmax = 0), and MSSQL, which
+	// checks $action.
+	//
+	//   var res struct {
+	//     ID       int64 `db:"id"`
+	//     Inserted bool  `db:"inserted"`
+	//   }
+	//   err = i.Values(item).OnConflict("email").DoUpdate().
+	//     Returning("id").ReturningInserted().Iterator().One(&res)
+	ReturningInserted() Inserter
+
 	// Iterator provides methods to iterate over the results returned by the
 	// Inserter. This is only possible when using Returning().
 	Iterator() Iterator
@@ -417,6 +635,12 @@ type Inserter interface {
 	// database server.
 	Amend(func(queryIn string) (queryOut string)) Inserter
 
+	// Comment tags the query with a SQL comment, like
+	// "/* checkout-service:recalc */", that is sent along with it and shows up
+	// in server-side statistics and activity views. Comment delimiters are
+	// removed from the text.
+	Comment(comment string) Inserter
+
 	// Batch provies a BatchInserter that can be used to insert many elements at
 	// once by issuing several calls to Values(). It accepts a size parameter
 	// which defines the batch size. If size is < 1, the batch size is set to 1.
@@ -428,6 +652,9 @@ type Inserter interface {
 	// Preparer provides methods for creating prepared statements.
 	Preparer
 
+	// Explainer provides methods for inspecting query plans.
+	Explainer
+
 	// Getter provides methods to return query results from INSERT statements
 	// that support such feature (e.g.: queries with Returning).
 	Getter
@@ -437,7 +664,84 @@ type Inserter interface {
 	fmt.Stringer
 }
 
-// Deleter represents a DELETE statement.
+// TableCreator represents a CREATE TABLE statement.
+type TableCreator interface {
+	// Struct defines the table's columns after the fields of the given struct.
+	// Column names are taken from `db` tags and column types are inferred from
+	// Go types, fields that are not pointers are NOT NULL. A type can be set
+	// explicitly with the "type" option:
+	//
+	//  Amount float64 `db:"amount,type=numeric(12,2)"`
+	//
+	// The primary key is made of the fields that have the "pk" option or, if
+	// there are none, the "id" field. An integer primary key made of a single
+	// column is auto incremented.
+	Struct(structValue interface{}) TableCreator
+
+	// Column appends a column definition. The column type is either a generic
+	// type, like "serial", "text" or "timestamp", which is translated into the
+	// adapter's own type, or a type the database understands, which is used
+	// verbatim.
+	//
+	//  q.Column("id", "serial", db.PrimaryKey).Column("name", "text", db.NotNull)
+	Column(name string, columnType string, constraints ...db.ColumnConstraint) TableCreator
+
+	// IfNotExists makes the statement a no-op if the table already exists.
+	IfNotExists() TableCreator
+
+	// Temporary creates a temporary table. Temporary tables are visible only
+	// to the connection that created them, so they're meant to be created
+	// within a transaction. PostgreSQL drops the table when the transaction
+	// ends (ON COMMIT DROP), MySQL and SQLite when the connection is closed.
+	// MSSQL ignores Temporary, name the table with a leading "#" instead.
+	Temporary() TableCreator
+
+	// Execer provides the Exec method.
+	Execer
+
+	// fmt.Stringer provides `String() string`, you can use `String()` to compile
+	// the `TableCreator` into a string.
+	fmt.Stringer
+}
+
+// TableDropper represents a DROP TABLE statement.
+type TableDropper interface {
+	// IfExists makes the statement a no-op if the table does not exist.
+	IfExists() TableDropper
+
+	// Cascade drops the objects that depend on the table as well, like views
+	// and foreign key constraints. It's ignored by adapters that don't support
+	// it (SQLite and MSSQL).
+	Cascade() TableDropper
+
+	// Execer provides the Exec method.
+	Execer
+
+	// fmt.Stringer provides `String() string`, you can use `String()` to compile
+	// the `TableDropper` into a string.
+	fmt.Stringer
+}
+
+// TableAlterer represents an ALTER TABLE statement. Actions are applied in the
+// order they were given, note that SQLite and MSSQL take a single action per
+// statement.
+type TableAlterer interface {
+	// AddColumn adds a column to the table, see TableCreator.Column.
+	AddColumn(name string, columnType string, constraints ...db.ColumnConstraint) TableAlterer
+
+	// DropColumn drops a column from the table.
+	DropColumn(name string) TableAlterer
+
+	// Execer provides the Exec method.
+	Execer
+
+	// fmt.Stringer provides `String() string`, you can use `String()` to compile
+	// the `TableAlterer` into a string.
+	fmt.Stringer
+}
+
+// Deleter represents a DELETE statement. Deleters are immutable, see
+// Selector.
 type Deleter interface {
 	// Where represents the WHERE clause.
 	//
@@ -448,18 +752,74 @@ type Deleter interface {
 	// conditions that have been already set.
 	And(conds ...interface{}) Deleter
 
+	// Or joins the conditions that have been already set and the given ones
+	// with OR.
+	//
+	// See Selector.Or for documentation and usage examples.
+	Or(conds ...interface{}) Deleter
+
 	// Limit represents the LIMIT clause.
 	//
 	// See Selector.Limit for documentation and usage examples.
 	Limit(int) Deleter
 
+	// Using sets the tables the deleted rows are matched with, in Where:
+	//
+	//   q := sess.DeleteFrom("artist").
+	//     Using("banned AS b").
+	//     Where("artist.name = b.name")
+	//
+	// This is compiled as DELETE ... USING on PostgreSQL and as DELETE t FROM
+	// t, ... on MySQL and SQL Server, other databases return an error when the
+	// query is run. Using can't be combined with Limit.
+	Using(tables ...interface{}) Deleter
+
+	// Join joins more tables to the ones given to Using.
+	//
+	// See Selector.Join for documentation and usage examples.
+	Join(tables ...interface{}) Deleter
+
+	// LeftJoin joins more tables to the ones given to Using with a LEFT JOIN.
+	LeftJoin(tables ...interface{}) Deleter
+
+	// On represents the ON clause of the last Join or LeftJoin.
+	On(conds ...interface{}) Deleter
+
+	// Returning adds a RETURNING clause with the given columns of the deleted
+	// rows, which are read with Iterator:
+	//
+	//   var deleted []Artist
+	//   err = sess.DeleteFrom("artist").Where("name = ?", "Banned").
+	//     Returning("id", "name").Iterator().All(&deleted)
+	//
+	// RETURNING is only supported by PostgreSQL, other databases ignore it
+	// and return no rows.
+	Returning(columns ...string) Deleter
+
+	// Iterator runs the statement and provides methods to iterate over the
+	// rows it returned. This is only possible when using Returning().
+	Iterator() Iterator
+
+	// IteratorContext runs the statement and provides methods to iterate over
+	// the rows it returned. This is only possible when using Returning().
+	IteratorContext(ctx context.Context) Iterator
+
 	// Amend lets you alter the query's text just before sending it to the
 	// database server.
 	Amend(func(queryIn string) (queryOut string)) Deleter
 
+	// Comment tags the query with a SQL comment, like
+	// "/* checkout-service:recalc */", that is sent along with it and shows up
+	// in server-side statistics and activity views. Comment delimiters are
+	// removed from the text.
+	Comment(comment string) Deleter
+
 	// Preparer provides methods for creating prepared statements.
 	Preparer
 
+	// Explainer provides methods for inspecting query plans.
+	Explainer
+
 	// Execer provides the Exec method.
 	Execer
 
@@ -469,9 +829,15 @@ type Deleter interface {
 
 	// Arguments returns the arguments that are prepared for this query.
 	Arguments() []interface{}
+
+	// Fingerprint returns the normalized text of the query, queries that only
+	// differ in their argument values share the same fingerprint. See
+	// sqlbuilder.Fingerprint.
+	Fingerprint() string
 }
 
-// Updater represents an UPDATE statement.
+// Updater represents an UPDATE statement. Updaters are immutable, see
+// Selector.
 type Updater interface {
 	// Set represents the SET clause.
 	Set(...interface{}) Updater
@@ -485,14 +851,58 @@ type Updater interface {
 	// conditions that have been already set.
 	And(conds ...interface{}) Updater
 
+	// Or joins the conditions that have been already set and the given ones
+	// with OR.
+	//
+	// See Selector.Or for documentation and usage examples.
+	Or(conds ...interface{}) Updater
+
 	// Limit represents the LIMIT parameter.
 	//
 	// See Selector.Limit for documentation and usage examples.
 	Limit(int) Updater
 
+	// From sets the tables the new values are read from, rows of the updated
+	// table are matched with theirs in Where:
+	//
+	//   q := sess.Update("artist").
+	//     Set("name = s.name").
+	//     From("staging AS s").
+	//     Where("artist.id = s.id")
+	//
+	// PostgreSQL and SQL Server support this, other databases return an error
+	// when the query is run. From can't be combined with Limit.
+	From(tables ...interface{}) Updater
+
+	// Join joins more tables to the ones given to From.
+	//
+	// See Selector.Join for documentation and usage examples.
+	Join(tables ...interface{}) Updater
+
+	// LeftJoin joins more tables to the ones given to From with a LEFT JOIN.
+	LeftJoin(tables ...interface{}) Updater
+
+	// On represents the ON clause of the last Join or LeftJoin.
+	On(conds ...interface{}) Updater
+
+	// Returning adds a RETURNING clause with the given columns of the updated
+	// rows, which are read with Iterator. See Deleter.Returning.
+	Returning(columns ...string) Updater
+
+	// Iterator runs the statement and provides methods to iterate over the
+	// rows it returned. This is only possible when using Returning().
+	Iterator() Iterator
+
+	// IteratorContext runs the statement and provides methods to iterate over
+	// the rows it returned. This is only possible when using Returning().
+	IteratorContext(ctx context.Context) Iterator
+
 	// Preparer provides methods for creating prepared statements.
 	Preparer
 
+	// Explainer provides methods for inspecting query plans.
+	Explainer
+
 	// Execer provides the Exec method.
 	Execer
 
@@ -503,9 +913,20 @@ type Updater interface {
 	// Arguments returns the arguments that are prepared for this query.
 	Arguments() []interface{}
 
+	// Fingerprint returns the normalized text of the query, queries that only
+	// differ in their argument values share the same fingerprint. See
+	// sqlbuilder.Fingerprint.
+	Fingerprint() string
+
 	// Amend lets you alter the query's text just before sending it to the
 	// database server.
 	Amend(func(queryIn string) (queryOut string)) Updater
+
+	// Comment tags the query with a SQL comment, like
+	// "/* checkout-service:recalc */", that is sent along with it and shows up
+	// in server-side statistics and activity views. Comment delimiters are
+	// removed from the text.
+	Comment(comment string) Updater
 }
 
 // Execer provides methods for executing statements that do not return results.
@@ -517,6 +938,20 @@ type Execer interface {
 	ExecContext(context.Context) (sql.Result, error)
 }
 
+// Explainer provides methods for inspecting how the database is going to run
+// a statement. Adapters that can't show query plans return db.ErrUnsupported.
+type Explainer interface {
+	// Explain returns the query plan as text. If analyze is true the statement
+	// is also run, inside a transaction that is rolled back, and the plan
+	// includes actual row counts and timings.
+	//
+	//   plan, err := sess.SelectFrom("artist").Where("name", "Ozzie").Explain(ctx, false)
+	Explain(ctx context.Context, analyze bool) (string, error)
+
+	// ExplainJSON is like Explain, but returns the plan in JSON format.
+	ExplainJSON(ctx context.Context, analyze bool) (string, error)
+}
+
 // Preparer provides the Prepare and PrepareContext methods for creating
 // prepared statements.
 type Preparer interface {
@@ -615,6 +1050,23 @@ type ResultMapper interface {
 	// slice of maps or structs.
 	//
 	// The behaviour of One() extends to each one of the results.
+	//
+	// Results with a single column can also be dumped into a slice of plain
+	// values, like []int64, []string or any sql.Scanner, use pointer elements
+	// like []*string for nullable columns:
+	//
+	//   var ids []int64
+	//   err = sess.Select("id").From("artist").Iterator().All(&ids)
+	//
+	// Results with two columns can be dumped into a map instead, the first
+	// column is the key and the second one the value. ErrDuplicateKey is
+	// returned if two rows have the same key, see OverwriteKeys:
+	//
+	//   var names map[int64]string
+	//   err = sess.Select("id", "name").From("artist").Iterator().All(&names)
+	//
+	// A query that matches no rows is not an error, All() leaves an empty
+	// (non-nil) slice in destSlice and returns nil.
 	All(destSlice interface{}) error
 
 	// One maps the row that is in the current query cursor into the
@@ -630,6 +1082,9 @@ type ResultMapper interface {
 	// If dest if a pointer to struct, each one of the fields will be tested for
 	// a `db` tag which defines the column mapping. The value of the result will
 	// be set as the value of the field.
+	//
+	// If the query matches no rows, dest is set to its zero value and
+	// db.ErrNoMoreRows is returned.
 	One(dest interface{}) error
 }
 
@@ -647,13 +1102,104 @@ type Iterator interface {
 	// ScanOne advances the iterator, performs Scan and closes the iterator.
 	ScanOne(dest ...interface{}) error
 
-	// Next dumps the current element into the given destination, which could be
-	// a pointer to either a map or a struct.
+	// Next advances the iterator and dumps the current element into the given
+	// destination, which could be a pointer to either a map or a struct. When
+	// given pointers to plain values, columns are scanned into them in order,
+	// like Scan does:
+	//
+	//   for iter.Next(&item) {
+	//     ...
+	//   }
+	//
+	//   for iter.Next(&id, &name) {
+	//     ...
+	//   }
+	//
+	// Next returns false when there are no more rows or when an error happens,
+	// use Err() to tell them apart. Calling Next() without arguments only
+	// advances the iterator.
 	Next(dest ...interface{}) bool
 
+	// RawBytes returns the value of the given column on the current row as
+	// the driver sent it, for column types that can't be mapped to a Go value
+	// otherwise:
+	//
+	//   for iter.Next() {
+	//     b, err := iter.RawBytes("addr")
+	//     ...
+	//   }
+	//
+	// A NULL value is returned as nil. The slice points to memory owned by the
+	// driver and is only valid until the next call to Next or Close, copy it to
+	// keep it around.
+	RawBytes(column string) ([]byte, error)
+
 	// Err returns the last error produced by the cursor.
 	Err() error
 
 	// Close closes the iterator and frees up the cursor.
 	Close() error
 }
+
+// ColumnStreamer is satisfied by the db.Result values of adapters that can
+// read and write binary columns in chunks, so large values don't have to be
+// held in memory at once:
+//
+//   r, err := col.Find(id).(sqlbuilder.ColumnStreamer).StreamColumn(ctx, "data")
+//   ...
+//   defer r.Close()
+//   io.Copy(w, r)
+//
+// Each chunk is sent in its own statement, run StreamColumn and WriteColumn
+// within a transaction to get a consistent value or to write it atomically.
+type ColumnStreamer interface {
+	// StreamColumn returns a reader of the value of column on the first row
+	// of the result set. db.ErrNoMoreRows is returned if the set is empty, a
+	// NULL value reads as empty.
+	StreamColumn(ctx context.Context, column string) (io.ReadCloser, error)
+
+	// WriteColumn replaces the value of column on every row of the result set
+	// with the contents of r.
+	WriteColumn(ctx context.Context, column string, r io.Reader) error
+}
+
+// Dequeuer is satisfied by the db.Result values of adapters that can lock
+// rows while skipping the ones other transactions already hold, which turns
+// any table into a work queue that can be consumed concurrently:
+//
+//   jobs, err := col.Find("status", "pending").OrderBy("id").(sqlbuilder.Dequeuer).Dequeue(ctx, 10)
+//   ...
+//   var items []Job
+//   if err := jobs.All(&items); err != nil {
+//     jobs.Release()
+//     ...
+//   }
+//   ...
+//   err = jobs.Complete(map[string]interface{}{"status": "done"})
+type Dequeuer interface {
+	// Dequeue begins a transaction and locks up to limit rows of the result
+	// set that are not locked by other transactions. The transaction stays
+	// open until Complete or Release is called on the returned value.
+	Dequeue(ctx context.Context, limit int) (Dequeued, error)
+}
+
+// Dequeued represents a batch of rows locked by Dequeue.
+type Dequeued interface {
+	// Len returns the number of locked rows.
+	Len() int
+
+	// All dumps the locked rows into the given slice.
+	All(sliceOfStructs interface{}) error
+
+	// Tx returns the transaction that holds the locks, it can be used to
+	// write related changes that are committed along with the batch.
+	Tx() Tx
+
+	// Complete updates the locked rows with the given values, if any, and
+	// commits the transaction.
+	Complete(values interface{}) error
+
+	// Release rolls back the transaction, the rows are unlocked and can be
+	// dequeued again.
+	Release() error
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sqlbuilder
+
+// ColumnDescriptor describes a column of a table, as reported by the
+// database.
+type ColumnDescriptor struct {
+	// Name is the name of the column.
+	Name string
+
+	// Position is the 1-based ordinal position of the column in the table.
+	Position int
+
+	// DataType is the name of the column type, as reported by the database.
+	DataType string
+
+	// MaxLength is the maximum length of character and binary columns, nil
+	// for other types or if the length is unbounded.
+	MaxLength *int64
+
+	// Precision and Scale are set for numeric columns.
+	Precision *int64
+	Scale     *int64
+
+	// Nullable is true if the column accepts NULL values.
+	Nullable bool
+
+	// Default is the expression used as default value for the column, nil if
+	// the column has no default.
+	Default *string
+}
+
+// ForeignKeyDescriptor describes a foreign key constraint of a table.
+type ForeignKeyDescriptor struct {
+	// Name is the name of the constraint.
+	Name string
+
+	// Columns are the referencing columns, in order.
+	Columns []string
+
+	// ReferencedTable is the name of the referenced table, qualified with a
+	// schema when needed.
+	ReferencedTable string
+
+	// ReferencedColumns are the referenced columns, in the same order as
+	// Columns.
+	ReferencedColumns []string
+
+	// OnUpdate and OnDelete are the referential actions of the constraint,
+	// like "NO ACTION", "CASCADE" or "SET NULL".
+	OnUpdate string
+	OnDelete string
+}
+
+// IndexDescriptor describes an index of a table.
+type IndexDescriptor struct {
+	// Name is the name of the index.
+	Name string
+
+	// Columns are the indexed columns or expressions, in order.
+	Columns []string
+
+	// Unique is true if the index enforces uniqueness.
+	Unique bool
+
+	// Primary is true if the index backs the primary key.
+	Primary bool
+
+	// Method is the access method of the index, as reported by the database,
+	// like "btree" or "gin" on PostgreSQL and "CLUSTERED" or "NONCLUSTERED" on
+	// SQL Server.
+	Method string
+}
@@ -0,0 +1,47 @@
+package sqlbuilder
+
+import (
+	"strings"
+)
+
+// OrderByAllowed validates a sort field and direction that come from an
+// untrusted source, like the query string of an HTTP request, against an
+// allowlist that maps the names exposed to clients to column names. It
+// returns a clause that can be passed to OrderBy:
+//
+//	order, err := sqlbuilder.OrderByAllowed(map[string]string{
+//		"name":    "name",
+//		"created": "created_at",
+//	}, r.URL.Query().Get("sort"), r.URL.Query().Get("dir"))
+//	if err != nil {
+//		// 400 Bad Request
+//	}
+//	res = res.OrderBy(order)
+//
+// dir may be "asc", "desc" or empty, in any case. userField may also carry a
+// "-" prefix to sort in descending order when dir is empty. Fields that are
+// not in allowed return ErrOrderByNotAllowed and any other direction returns
+// ErrInvalidSortDirection.
+func OrderByAllowed(allowed map[string]string, userField, dir string) (string, error) {
+	desc := false
+	switch strings.ToLower(dir) {
+	case "":
+		if strings.HasPrefix(userField, "-") {
+			userField, desc = userField[1:], true
+		}
+	case "asc":
+	case "desc":
+		desc = true
+	default:
+		return "", ErrInvalidSortDirection
+	}
+
+	column, ok := allowed[userField]
+	if !ok || column == "" {
+		return "", ErrOrderByNotAllowed
+	}
+	if desc {
+		return "-" + column, nil
+	}
+	return column, nil
+}
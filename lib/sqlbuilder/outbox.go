@@ -0,0 +1,37 @@
+package sqlbuilder
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxMessage is an event written with Tx.Outbox, as Database.DrainOutbox
+// reads it back. The outbox table is expected to have these columns, plus a
+// nullable published_at timestamp that DrainOutbox sets:
+//
+//	CREATE TABLE outbox (
+//		id BIGSERIAL PRIMARY KEY,
+//		type TEXT NOT NULL,
+//		payload JSONB NOT NULL,
+//		created_at TIMESTAMPTZ NOT NULL,
+//		published_at TIMESTAMPTZ
+//	);
+//
+// An index on id WHERE published_at IS NULL keeps draining fast as published
+// rows pile up, they may be deleted at any time.
+type OutboxMessage struct {
+	ID int64 `db:"id"`
+
+	// Type is the Go type of the event, like "orders.Placed".
+	Type string `db:"type"`
+
+	// Payload is the JSON encoding of the event.
+	Payload []byte `db:"payload"`
+
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Decode decodes the payload of the message into dst.
+func (m *OutboxMessage) Decode(dst interface{}) error {
+	return json.Unmarshal(m.Payload, dst)
+}
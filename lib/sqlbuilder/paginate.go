@@ -168,7 +168,7 @@ func (pag *paginator) Iterator() Iterator {
 	pq, err := pag.buildWithCursor()
 	if err != nil {
 		sess := pq.sel.(*selector).SQLBuilder().sess
-		return &iterator{sess, nil, err}
+		return &iterator{sess: sess, err: err}
 	}
 	return pq.sel.Iterator()
 }
@@ -177,7 +177,7 @@ func (pag *paginator) IteratorContext(ctx context.Context) Iterator {
 	pq, err := pag.buildWithCursor()
 	if err != nil {
 		sess := pq.sel.(*selector).SQLBuilder().sess
-		return &iterator{sess, nil, err}
+		return &iterator{sess: sess, err: err}
 	}
 	return pq.sel.IteratorContext(ctx)
 }
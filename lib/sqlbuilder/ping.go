@@ -0,0 +1,82 @@
+package sqlbuilder
+
+import (
+	"context"
+	"sync"
+)
+
+// pingAllConcurrency is the maximum number of sessions PingAll pings at the
+// same time.
+const pingAllConcurrency = 8
+
+// PingAll pings the given sessions concurrently and returns the result of
+// each one under the same key, nil for the ones that could be reached:
+//
+//	errs := sqlbuilder.PingAll(ctx, map[string]sqlbuilder.Database{
+//		"shard-a": shardA,
+//		"shard-b": shardB,
+//	})
+//	for name, err := range errs {
+//		...
+//	}
+//
+// At most eight sessions are pinged at a time. ctx bounds the whole
+// operation, sessions that were not reached before it expires get ctx's
+// error.
+func PingAll(ctx context.Context, sessions map[string]Database) map[string]error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = make(map[string]error, len(sessions))
+		sem  = make(chan struct{}, pingAllConcurrency)
+	)
+
+	for name, sess := range sessions {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			errs[name] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, sess Database) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := pingContext(ctx, sess)
+
+			mu.Lock()
+			errs[name] = err
+			mu.Unlock()
+		}(name, sess)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+// pingContext pings sess, giving up when ctx expires.
+func pingContext(ctx context.Context, sess Database) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p, ok := sess.Driver().(interface {
+		PingContext(context.Context) error
+	}); ok {
+		return p.PingContext(ctx)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sess.Ping()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,80 @@
+package sqlbuilder
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pingStub struct {
+	Database
+
+	err     error
+	delay   time.Duration
+	running *int32
+	maxRun  *int32
+}
+
+func (p *pingStub) Driver() interface{} {
+	return nil
+}
+
+func (p *pingStub) Ping() error {
+	if p.running != nil {
+		n := atomic.AddInt32(p.running, 1)
+		defer atomic.AddInt32(p.running, -1)
+		for {
+			max := atomic.LoadInt32(p.maxRun)
+			if n <= max || atomic.CompareAndSwapInt32(p.maxRun, max, n) {
+				break
+			}
+		}
+	}
+	time.Sleep(p.delay)
+	return p.err
+}
+
+func TestPingAll(t *testing.T) {
+	errDown := errors.New("down")
+
+	{
+		errs := PingAll(context.Background(), map[string]Database{
+			"a": &pingStub{},
+			"b": &pingStub{err: errDown},
+		})
+		assert.Equal(t, map[string]error{"a": nil, "b": errDown}, errs)
+	}
+
+	{
+		var running, maxRun int32
+		sessions := map[string]Database{}
+		for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+			sessions[name] = &pingStub{delay: 10 * time.Millisecond, running: &running, maxRun: &maxRun}
+		}
+		errs := PingAll(context.Background(), sessions)
+		assert.Len(t, errs, len(sessions))
+		for _, err := range errs {
+			assert.NoError(t, err)
+		}
+		assert.True(t, maxRun <= pingAllConcurrency)
+		assert.True(t, maxRun > 1)
+	}
+
+	{
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		errs := PingAll(ctx, map[string]Database{
+			"fast": &pingStub{},
+			"slow": &pingStub{delay: time.Second},
+		})
+		assert.True(t, time.Since(start) < time.Second)
+		assert.NoError(t, errs["fast"])
+		assert.Equal(t, context.DeadlineExceeded, errs["slow"])
+	}
+}
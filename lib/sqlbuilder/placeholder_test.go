@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 )
 
 func TestPlaceholderSimple(t *testing.T) {
@@ -81,3 +82,47 @@ func TestPlaceholderReplace(t *testing.T) {
 		assert.Equal(t, []interface{}{1, 3}, args)
 	}
 }
+
+func TestPlaceholderByteSlice(t *testing.T) {
+	type digest []byte
+
+	{
+		ret, args := Preprocess("?, ?", []interface{}{[]byte("data"), digest("sum")})
+		assert.Equal(t, "?, ?", ret)
+		assert.Equal(t, []interface{}{[]byte("data"), digest("sum")}, args)
+	}
+
+	{
+		ret, args := Preprocess("?", []interface{}{[][]byte{[]byte("a"), []byte("b")}})
+		assert.Equal(t, "(?, ?)", ret)
+		assert.Equal(t, []interface{}{[]byte("a"), []byte("b")}, args)
+	}
+}
+
+func TestPlaceholderLiterals(t *testing.T) {
+	body := "CREATE FUNCTION f(a int) RETURNS int AS $fn$ SELECT CASE WHEN '?' = $1::text THEN $1 END; $fn$ LANGUAGE sql"
+	{
+		ret, args := Preprocess(body, []interface{}{[]interface{}{1, 2}})
+		assert.Equal(t, body, ret)
+		assert.Equal(t, []interface{}{[]interface{}{1, 2}}, args)
+	}
+	{
+		ret, args := Preprocess(`SELECT 'a ?', "b?", $$ ? $$ /* ? */ FROM t WHERE c IN ? -- ?`, []interface{}{[]interface{}{1, 2}})
+		assert.Equal(t, `SELECT 'a ?', "b?", $$ ? $$ /* ? */ FROM t WHERE c IN (?, ?) -- ?`, ret)
+		assert.Equal(t, []interface{}{1, 2}, args)
+	}
+}
+
+func TestPlaceholderBackslashEscapes(t *testing.T) {
+	in := `SELECT 'it\'s ?' FROM t WHERE c IN ?`
+	{
+		ret, args := PreprocessWithTemplate(&exql.Template{BackslashEscapes: true}, in, []interface{}{[]interface{}{1, 2}})
+		assert.Equal(t, `SELECT 'it\'s ?' FROM t WHERE c IN (?, ?)`, ret)
+		assert.Equal(t, []interface{}{1, 2}, args)
+	}
+	{
+		ret, args := Preprocess(in, []interface{}{[]interface{}{1, 2}})
+		assert.Equal(t, `SELECT 'it\'s (?, ?)' FROM t WHERE c IN ?`, ret)
+		assert.Equal(t, []interface{}{1, 2}, args)
+	}
+}
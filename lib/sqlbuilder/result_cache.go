@@ -0,0 +1,176 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ResultCache stores the rows of the queries marked with Selector.Cacheable,
+// see Database.SetResultCache. Implementations must be safe for concurrent
+// use and are expected to drop entries once their ttl expires.
+type ResultCache interface {
+	// Get returns the rows stored under key, if they did not expire.
+	Get(key string) (*CachedRows, bool)
+
+	// Set stores rows under key for ttl.
+	Set(key string, rows *CachedRows, ttl time.Duration)
+}
+
+// CachedRows holds the result of a query as read from the driver. Values
+// must not be modified once stored, as they're shared by all the hits.
+type CachedRows struct {
+	Columns []string
+	Values  [][]interface{}
+}
+
+type hasResultCache interface {
+	ResultCache() ResultCache
+}
+
+// resultCacheKey returns the key the rows of query are cached under: the
+// fingerprint of query followed by a hash of query and its arguments, as
+// queries that share a fingerprint may differ in their literals.
+func resultCacheKey(query string, args []interface{}) string {
+	h := fnv.New64a()
+	io.WriteString(h, query)
+	for _, arg := range args {
+		if valuer, ok := arg.(driver.Valuer); ok {
+			if v, err := valuer.Value(); err == nil {
+				arg = v
+			}
+		}
+		v := reflect.ValueOf(arg)
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.IsValid() {
+			arg = v.Interface()
+		}
+		if t, ok := arg.(time.Time); ok {
+			// The monotonic clock reading is not part of the value.
+			arg = t.Round(0)
+		}
+		fmt.Fprintf(h, "\x00%T:%v", arg, arg)
+	}
+	return Fingerprint(query) + ":" + strconv.FormatUint(h.Sum64(), 16)
+}
+
+// readCachedRows reads and closes rows.
+func readCachedRows(rows *sql.Rows) (*CachedRows, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &CachedRows{Columns: columns}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dst := make([]interface{}, len(columns))
+		for i := range values {
+			dst[i] = &values[i]
+		}
+		if err := rows.Scan(dst...); err != nil {
+			return nil, err
+		}
+		cached.Values = append(cached.Values, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return cached, nil
+}
+
+// cachedRowsDB replays cached rows through database/sql, so they're scanned
+// by *sql.Rows, conversions included, like the rows of the query were.
+var cachedRowsDB = sql.OpenDB(cachedRowsConnector{})
+
+var errCachedRowsReadOnly = errors.New("sqlbuilder: cached rows can only be read")
+
+// cachedRowsArg is the only argument of the queries sent to cachedRowsDB, it
+// carries the rows the query returns.
+type cachedRowsArg struct {
+	rows *CachedRows
+}
+
+// replayCachedRows returns a *sql.Rows that reads rows.
+func replayCachedRows(ctx context.Context, rows *CachedRows) (*sql.Rows, error) {
+	return cachedRowsDB.QueryContext(ctx, "", cachedRowsArg{rows})
+}
+
+type cachedRowsConnector struct{}
+
+func (cachedRowsConnector) Connect(context.Context) (driver.Conn, error) {
+	return cachedRowsConn{}, nil
+}
+
+func (cachedRowsConnector) Driver() driver.Driver {
+	return cachedRowsDriver{}
+}
+
+type cachedRowsDriver struct{}
+
+func (cachedRowsDriver) Open(string) (driver.Conn, error) {
+	return cachedRowsConn{}, nil
+}
+
+type cachedRowsConn struct{}
+
+// CheckNamedValue lets cachedRowsArg through as it is.
+func (cachedRowsConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (cachedRowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &cachedRowsReader{rows: args[0].Value.(cachedRowsArg).rows}, nil
+}
+
+func (cachedRowsConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errCachedRowsReadOnly
+}
+
+func (cachedRowsConn) Begin() (driver.Tx, error) {
+	return nil, errCachedRowsReadOnly
+}
+
+func (cachedRowsConn) Close() error {
+	return nil
+}
+
+type cachedRowsReader struct {
+	rows *CachedRows
+	pos  int
+}
+
+func (r *cachedRowsReader) Columns() []string {
+	return r.rows.Columns
+}
+
+func (r *cachedRowsReader) Close() error {
+	return nil
+}
+
+// Next copies byte slices, as cached values are shared by all the hits and
+// sql.RawBytes destinations are not copied by Rows.Scan.
+func (r *cachedRowsReader) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows.Values) {
+		return io.EOF
+	}
+	for i, v := range r.rows.Values[r.pos] {
+		if b, ok := v.([]byte); ok && b != nil {
+			v = append([]byte{}, b...)
+		}
+		dest[i] = v
+	}
+	r.pos++
+	return nil
+}
@@ -0,0 +1,217 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/stretchr/testify/assert"
+)
+
+type mapResultCache struct {
+	mu   sync.Mutex
+	m    map[string]*CachedRows
+	ttls map[string]time.Duration
+}
+
+func (c *mapResultCache) Get(key string) (*CachedRows, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rows, ok := c.m[key]
+	return rows, ok
+}
+
+func (c *mapResultCache) Set(key string, rows *CachedRows, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m, c.ttls = map[string]*CachedRows{}, map[string]time.Duration{}
+	}
+	c.m[key], c.ttls[key] = rows, ttl
+}
+
+// cachingSession answers every query with rows and counts the queries.
+type cachingSession struct {
+	exprDB
+
+	rows    *CachedRows
+	cache   ResultCache
+	queries int
+}
+
+func (s *cachingSession) Context() context.Context {
+	return context.Background()
+}
+
+func (s *cachingSession) StatementQuery(ctx context.Context, stmt *exql.Statement, args ...interface{}) (*sql.Rows, error) {
+	s.queries++
+	return replayCachedRows(ctx, s.rows)
+}
+
+func (s *cachingSession) ResultCache() ResultCache {
+	return s.cache
+}
+
+func TestResultCacheKey(t *testing.T) {
+	q1 := `SELECT * FROM "artist" WHERE id = 1`
+	q2 := `SELECT * FROM "artist" WHERE id = 2`
+
+	// Literals share the fingerprint but not the key.
+	assert.Equal(t, Fingerprint(q1), Fingerprint(q2))
+	assert.NotEqual(t, resultCacheKey(q1, nil), resultCacheKey(q2, nil))
+
+	q := `SELECT * FROM "artist" WHERE id = $1`
+	assert.Equal(t, resultCacheKey(q, []interface{}{1}), resultCacheKey(q, []interface{}{1}))
+	assert.NotEqual(t, resultCacheKey(q, []interface{}{1}), resultCacheKey(q, []interface{}{2}))
+	assert.NotEqual(t, resultCacheKey(q, []interface{}{1}), resultCacheKey(q, []interface{}{"1"}))
+
+	// Pointers are keyed by the values they point to.
+	a, b := 1, 1
+	assert.Equal(t, resultCacheKey(q, []interface{}{&a}), resultCacheKey(q, []interface{}{&b}))
+	b = 2
+	assert.NotEqual(t, resultCacheKey(q, []interface{}{&a}), resultCacheKey(q, []interface{}{&b}))
+
+	// Times are keyed without their monotonic clock reading.
+	now := time.Now()
+	assert.Equal(t, resultCacheKey(q, []interface{}{now}), resultCacheKey(q, []interface{}{now.Round(0)}))
+}
+
+func TestReadCachedRows(t *testing.T) {
+	cached := &CachedRows{
+		Columns: []string{"id", "name"},
+		Values: [][]interface{}{
+			{int64(1), []byte("Ozzy")},
+			{int64(2), "Flea"},
+		},
+	}
+
+	rows, err := replayCachedRows(context.Background(), cached)
+	assert.NoError(t, err)
+
+	read, err := readCachedRows(rows)
+	assert.NoError(t, err)
+	assert.Equal(t, cached, read)
+
+	// Cached byte slices are not shared with destinations.
+	rows, err = replayCachedRows(context.Background(), cached)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	var (
+		id   int
+		name sql.RawBytes
+	)
+	assert.True(t, rows.Next())
+	assert.NoError(t, rows.Scan(&id, &name))
+	name[0] = 'o'
+	assert.Equal(t, []byte("Ozzy"), cached.Values[0][1])
+}
+
+func TestCachedRowsScan(t *testing.T) {
+	now := time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC)
+	rows := &CachedRows{
+		Columns: []string{"n", "f", "s", "b", "t", "null"},
+		Values:  [][]interface{}{{int64(7), 1.5, "Ozzy", []byte("Flea"), now, nil}},
+	}
+
+	tests := []struct {
+		name string
+		dest func() []interface{}
+	}{
+		{"same types", func() []interface{} {
+			return []interface{}{new(int), new(float32), new(string), new([]byte), new(time.Time), new(*string)}
+		}},
+		{"as text", func() []interface{} {
+			return []interface{}{new(string), new(string), new([]byte), new(string), new(string), new(sql.NullString)}
+		}},
+		{"as nullables", func() []interface{} {
+			return []interface{}{new(sql.NullInt64), new(sql.NullFloat64), new(sql.NullString), new(sql.NullString), new(sql.NullString), new(sql.NullInt64)}
+		}},
+		{"as interfaces", func() []interface{} {
+			return []interface{}{new(interface{}), new(interface{}), new(interface{}), new(interface{}), new(interface{}), new(interface{})}
+		}},
+		{"invalid number", func() []interface{} {
+			return []interface{}{new(int), new(int), new(int), new(int), new(int), new(*int)}
+		}},
+		{"invalid bool", func() []interface{} {
+			return []interface{}{new(bool), new(bool), new(bool), new(bool), new(bool), new(bool)}
+		}},
+		{"invalid null", func() []interface{} {
+			return []interface{}{new(int), new(float32), new(string), new([]byte), new(time.Time), new(int)}
+		}},
+	}
+
+	scan := func(q Selector, dest []interface{}) ([]interface{}, error) {
+		iter := q.Iterator()
+		defer iter.Close()
+		if !iter.Next() {
+			return nil, iter.Err()
+		}
+		err := iter.Scan(dest...)
+		values := make([]interface{}, len(dest))
+		for j := range dest {
+			values[j] = reflect.ValueOf(dest[j]).Elem().Interface()
+		}
+		return values, err
+	}
+
+	for _, test := range tests {
+		sess := &cachingSession{rows: rows, cache: &mapResultCache{}}
+		q := (&sqlBuilder{sess: sess, t: newTemplateWithUtils(&testTemplate)}).
+			SelectFrom("t").
+			Cacheable(time.Minute)
+
+		miss, missErr := scan(q, test.dest())
+		hit, hitErr := scan(q, test.dest())
+
+		assert.Equal(t, 1, sess.queries, test.name)
+		assert.Equal(t, miss, hit, test.name)
+		assert.Equal(t, missErr, hitErr, test.name)
+	}
+}
+
+func TestSelectCacheable(t *testing.T) {
+	cache := &mapResultCache{}
+	sess := &cachingSession{
+		rows: &CachedRows{
+			Columns: []string{"id", "name"},
+			Values:  [][]interface{}{{int64(1), "Ozzy"}},
+		},
+		cache: cache,
+	}
+	b := &sqlBuilder{sess: sess, t: newTemplateWithUtils(&testTemplate)}
+
+	var artists []map[string]interface{}
+
+	// Not cached unless asked to.
+	assert.NoError(t, b.SelectFrom("artist").All(&artists))
+	assert.NoError(t, b.SelectFrom("artist").All(&artists))
+	assert.Equal(t, 2, sess.queries)
+	assert.Empty(t, cache.m)
+
+	q := b.SelectFrom("artist").Where("id", 1).Cacheable(time.Minute)
+	for i := 0; i < 3; i++ {
+		artists = nil
+		assert.NoError(t, q.All(&artists))
+		assert.Equal(t, []map[string]interface{}{{"id": int64(1), "name": "Ozzy"}}, artists)
+	}
+	assert.Equal(t, 3, sess.queries)
+	assert.Len(t, cache.m, 1)
+	for _, ttl := range cache.ttls {
+		assert.Equal(t, time.Minute, ttl)
+	}
+
+	// Different arguments are cached apart.
+	assert.NoError(t, b.SelectFrom("artist").Where("id", 2).Cacheable(time.Minute).All(&artists))
+	assert.Equal(t, 4, sess.queries)
+	assert.Len(t, cache.m, 2)
+
+	// Without a cache, Cacheable queries go to the database.
+	sess.cache = nil
+	assert.NoError(t, q.All(&artists))
+	assert.Equal(t, 5, sess.queries)
+}
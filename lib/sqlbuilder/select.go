@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/immutable"
@@ -32,6 +33,10 @@ type selectorQuery struct {
 	limit  exql.Limit
 	offset exql.Offset
 
+	lock *exql.Lock
+
+	sample *exql.Sample
+
 	columns     *exql.Columns
 	columnsArgs []interface{}
 
@@ -39,10 +44,25 @@ type selectorQuery struct {
 	joinsArgs []interface{}
 
 	amendFn func(string) string
+	comment string
+
+	total *totalCount
+
+	cacheTTL time.Duration
+}
+
+// totalCount is the window column added by WithTotalCount.
+type totalCount struct {
+	column   string
+	fragment exql.Fragment
+	dst      *int
 }
 
 func (sq *selectorQuery) and(b *sqlBuilder, terms ...interface{}) error {
-	where, whereArgs := b.t.toWhereWithArguments(terms)
+	where, whereArgs, err := b.t.toWhereWithArguments(terms)
+	if err != nil {
+		return err
+	}
 
 	if sq.where == nil {
 		sq.where, sq.whereArgs = &exql.Where{}, []interface{}{}
@@ -53,6 +73,21 @@ func (sq *selectorQuery) and(b *sqlBuilder, terms ...interface{}) error {
 	return nil
 }
 
+func (sq *selectorQuery) or(b *sqlBuilder, terms ...interface{}) error {
+	where, whereArgs, err := b.t.toWhereWithArguments(terms)
+	if err != nil {
+		return err
+	}
+
+	if sq.whereArgs == nil {
+		sq.whereArgs = []interface{}{}
+	}
+	sq.where = orWhere(sq.where, where)
+	sq.whereArgs = append(sq.whereArgs, whereArgs...)
+
+	return nil
+}
+
 func (sq *selectorQuery) arguments() []interface{} {
 	return joinArguments(
 		sq.columnsArgs,
@@ -65,10 +100,19 @@ func (sq *selectorQuery) arguments() []interface{} {
 }
 
 func (sq *selectorQuery) statement() *exql.Statement {
+	columns := sq.columns
+	if sq.total != nil {
+		fragments := []exql.Fragment{exql.RawValue("*")}
+		if columns != nil {
+			fragments = append([]exql.Fragment{}, columns.Columns...)
+		}
+		columns = exql.JoinColumns(append(fragments, sq.total.fragment)...)
+	}
+
 	stmt := &exql.Statement{
 		Type:     exql.Select,
 		Table:    sq.table,
-		Columns:  sq.columns,
+		Columns:  columns,
 		Distinct: sq.distinct,
 		Limit:    sq.limit,
 		Offset:   sq.offset,
@@ -77,17 +121,26 @@ func (sq *selectorQuery) statement() *exql.Statement {
 		GroupBy:  sq.groupBy,
 	}
 
+	if sq.lock != nil {
+		stmt.Lock = sq.lock
+	}
+
+	if sq.sample != nil {
+		stmt.Sample = sq.sample
+	}
+
 	if len(sq.joins) > 0 {
 		stmt.Joins = exql.JoinConditions(sq.joins...)
 	}
 
 	stmt.SetAmendment(sq.amendFn)
+	stmt.Comment = sq.comment
 
 	return stmt
 }
 
-func (sq *selectorQuery) pushJoin(t string, tables []interface{}) error {
-	fragments, args, err := columnFragments(tables)
+func (sq *selectorQuery) pushJoin(layout *exql.Template, t string, tables []interface{}) error {
+	fragments, args, err := columnFragments(layout, tables)
 	if err != nil {
 		return err
 	}
@@ -124,11 +177,12 @@ func (sel *selector) SQLBuilder() *sqlBuilder {
 }
 
 func (sel *selector) String() string {
-	s, err := sel.Compile()
+	stmt := sel.statement()
+	s, err := stmt.Compile(sel.template())
 	if err != nil {
 		panic(err.Error())
 	}
-	return prepareQueryForDisplay(s)
+	return exql.CommentPrefix(stmt.Comment) + prepareQueryForDisplay(sel.template(), s)
 }
 
 func (sel *selector) frame(fn func(*selectorQuery) error) *selector {
@@ -144,7 +198,7 @@ func (sel *selector) clone() Selector {
 func (sel *selector) From(tables ...interface{}) Selector {
 	return sel.frame(
 		func(sq *selectorQuery) error {
-			fragments, args, err := columnFragments(tables)
+			fragments, args, err := columnFragments(sel.template(), tables)
 			if err != nil {
 				return err
 			}
@@ -158,18 +212,19 @@ func (sel *selector) From(tables ...interface{}) Selector {
 func (sel *selector) setColumns(columns ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
 		sq.columns = nil
-		return sq.pushColumns(columns...)
+		sq.total = nil
+		return sq.pushColumns(sel.template(), columns...)
 	})
 }
 
 func (sel *selector) Columns(columns ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushColumns(columns...)
+		return sq.pushColumns(sel.template(), columns...)
 	})
 }
 
-func (sq *selectorQuery) pushColumns(columns ...interface{}) error {
-	f, args, err := columnFragments(columns)
+func (sq *selectorQuery) pushColumns(layout *exql.Template, columns ...interface{}) error {
+	f, args, err := columnFragments(layout, columns)
 	if err != nil {
 		return err
 	}
@@ -189,7 +244,7 @@ func (sq *selectorQuery) pushColumns(columns ...interface{}) error {
 func (sel *selector) Distinct(exps ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
 		sq.distinct = true
-		return sq.pushColumns(exps...)
+		return sq.pushColumns(sel.template(), exps...)
 	})
 }
 
@@ -209,6 +264,12 @@ func (sel *selector) And(terms ...interface{}) Selector {
 	})
 }
 
+func (sel *selector) Or(terms ...interface{}) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		return sq.or(sel.SQLBuilder(), terms...)
+	})
+}
+
 func (sel *selector) Amend(fn func(string) string) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
 		sq.amendFn = fn
@@ -216,6 +277,13 @@ func (sel *selector) Amend(fn func(string) string) Selector {
 	})
 }
 
+func (sel *selector) Comment(comment string) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		sq.comment = comment
+		return nil
+	})
+}
+
 func (sel *selector) Arguments() []interface{} {
 	sq, err := sel.build()
 	if err != nil {
@@ -226,7 +294,7 @@ func (sel *selector) Arguments() []interface{} {
 
 func (sel *selector) GroupBy(columns ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		fragments, args, err := columnFragments(columns)
+		fragments, args, err := columnFragments(sel.template(), columns)
 		if err != nil {
 			return err
 		}
@@ -256,23 +324,35 @@ func (sel *selector) OrderBy(columns ...interface{}) Selector {
 
 			switch value := columns[i].(type) {
 			case db.RawValue:
-				query, args := Preprocess(value.Raw(), value.Arguments())
+				query, args := PreprocessWithTemplate(sel.template(), value.Raw(), value.Arguments())
 				sort = &exql.SortColumn{
 					Column: exql.RawValue(query),
 				}
 				sq.orderByArgs = append(sq.orderByArgs, args...)
 			case db.Function:
-				fnName, fnArgs := value.Name(), value.Arguments()
-				if len(fnArgs) == 0 {
-					fnName = fnName + "()"
-				} else {
-					fnName = fnName + "(?" + strings.Repeat("?, ", len(fnArgs)-1) + ")"
+				fn, fnArgs, err := functionFragment(value)
+				if err != nil {
+					return err
 				}
-				fnName, fnArgs = Preprocess(fnName, fnArgs)
 				sort = &exql.SortColumn{
-					Column: exql.RawValue(fnName),
+					Column: fn,
 				}
 				sq.orderByArgs = append(sq.orderByArgs, fnArgs...)
+			case db.Order:
+				sort = &exql.SortColumn{
+					Column:    exql.ColumnWithName(value.Column()),
+					Order:     exql.Ascendent,
+					Collation: value.Collation(),
+				}
+				if value.Descending() {
+					sort.Order = exql.Descendent
+				}
+				switch value.Nulls() {
+				case db.NullsFirst:
+					sort.Nulls = exql.NullsFirst
+				case db.NullsLast:
+					sort.Nulls = exql.NullsLast
+				}
 			case string:
 				if strings.HasPrefix(value, "-") {
 					sort = &exql.SortColumn{
@@ -318,7 +398,7 @@ func (sel *selector) Using(columns ...interface{}) Selector {
 			return errors.New(`cannot use Using() and On() with the same Join() expression`)
 		}
 
-		fragments, args, err := columnFragments(columns)
+		fragments, args, err := columnFragments(sel.template(), columns)
 		if err != nil {
 			return err
 		}
@@ -332,31 +412,31 @@ func (sel *selector) Using(columns ...interface{}) Selector {
 
 func (sel *selector) FullJoin(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("FULL", tables)
+		return sq.pushJoin(sel.template(), "FULL", tables)
 	})
 }
 
 func (sel *selector) CrossJoin(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("CROSS", tables)
+		return sq.pushJoin(sel.template(), "CROSS", tables)
 	})
 }
 
 func (sel *selector) RightJoin(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("RIGHT", tables)
+		return sq.pushJoin(sel.template(), "RIGHT", tables)
 	})
 }
 
 func (sel *selector) LeftJoin(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("LEFT", tables)
+		return sq.pushJoin(sel.template(), "LEFT", tables)
 	})
 }
 
 func (sel *selector) Join(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("", tables)
+		return sq.pushJoin(sel.template(), "", tables)
 	})
 }
 
@@ -373,7 +453,10 @@ func (sel *selector) On(terms ...interface{}) Selector {
 			return errors.New(`cannot use Using() and On() with the same Join() expression`)
 		}
 
-		w, a := sel.SQLBuilder().t.toWhereWithArguments(terms)
+		w, a, err := sel.SQLBuilder().t.toWhereWithArguments(terms)
+		if err != nil {
+			return err
+		}
 		o := exql.On(w)
 
 		lastJoin.On = &o
@@ -404,6 +487,76 @@ func (sel *selector) Offset(n int) Selector {
 	})
 }
 
+func (sel *selector) ForUpdate() Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		if sel.template().LockLayout == "" {
+			return exql.ErrRowLocksUnsupported
+		}
+		sq.lock = &exql.Lock{}
+		return nil
+	})
+}
+
+func (sel *selector) SkipLocked() Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		if sel.template().LockLayout == "" {
+			return exql.ErrRowLocksUnsupported
+		}
+		sq.lock = &exql.Lock{SkipLocked: true}
+		return nil
+	})
+}
+
+func (sel *selector) Sample(percent float64) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		if sel.template().SampleLayout == "" {
+			return exql.ErrSampleUnsupported
+		}
+		if percent <= 0 || percent > 100 {
+			return ErrInvalidSamplePercent
+		}
+		sq.sample = &exql.Sample{Percent: percent}
+		return nil
+	})
+}
+
+func (sel *selector) Random() Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		if sel.template().RandomFunction == "" {
+			return exql.ErrRandomOrderUnsupported
+		}
+		sq.orderBy = &exql.OrderBy{
+			SortColumns: exql.JoinSortColumns(&exql.SortColumn{Column: &exql.Random{}}),
+		}
+		sq.orderByArgs = nil
+		return nil
+	})
+}
+
+func (sel *selector) Cacheable(ttl time.Duration) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		sq.cacheTTL = ttl
+		return nil
+	})
+}
+
+func (sel *selector) WithTotalCount(column string, total ...*int) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		compiled, err := exql.ColumnWithName(column).Compile(sel.template())
+		if err != nil {
+			return err
+		}
+		sq.total = &totalCount{
+			column:   column,
+			fragment: exql.RawValue("COUNT(*) OVER () AS " + compiled),
+		}
+		if len(total) > 0 {
+			sq.total.dst = total[0]
+		}
+		return nil
+	})
+}
+
 func (sel *selector) template() *exql.Template {
 	return sel.SQLBuilder().t.Template
 }
@@ -455,6 +608,26 @@ func (sel *selector) PrepareContext(ctx context.Context) (*sql.Stmt, error) {
 	return sel.SQLBuilder().sess.StatementPrepare(ctx, sq.statement())
 }
 
+func (sel *selector) Explain(ctx context.Context, analyze bool) (string, error) {
+	return sel.explain(ctx, ExplainOptions{Analyze: analyze})
+}
+
+func (sel *selector) ExplainJSON(ctx context.Context, analyze bool) (string, error) {
+	return sel.explain(ctx, ExplainOptions{Analyze: analyze, JSON: true})
+}
+
+func (sel *selector) explain(ctx context.Context, opts ExplainOptions) (string, error) {
+	sq, err := sel.build()
+	if err != nil {
+		return "", err
+	}
+	return explain(ctx, sel.SQLBuilder().sess, sq.statement(), sq.arguments(), opts)
+}
+
+func (sel *selector) Fingerprint() string {
+	return fingerprint(sel)
+}
+
 func (sel *selector) Query() (*sql.Rows, error) {
 	return sel.QueryContext(sel.SQLBuilder().sess.Context())
 }
@@ -475,11 +648,45 @@ func (sel *selector) IteratorContext(ctx context.Context) Iterator {
 	sess := sel.SQLBuilder().sess
 	sq, err := sel.build()
 	if err != nil {
-		return &iterator{sess, nil, err}
+		return &iterator{sess: sess, err: err}
+	}
+
+	if sq.cacheTTL > 0 {
+		if c, ok := sess.(hasResultCache); ok {
+			if cache := c.ResultCache(); cache != nil {
+				rows, err := sel.cachedQuery(ctx, cache, sq)
+				return &iterator{sess: sess, cursor: rows, err: err, total: sq.total}
+			}
+		}
+	}
+
+	rows, err := queryCursor(ctx, sess, sq.statement(), sq.arguments())
+	return &iterator{sess: sess, cursor: rows, err: err, total: sq.total}
+}
+
+// cachedQuery returns the rows of sq from cache, querying the database and
+// storing them on a miss.
+func (sel *selector) cachedQuery(ctx context.Context, cache ResultCache, sq *selectorQuery) (cursor, error) {
+	stmt, args := sq.statement(), sq.arguments()
+	query, err := stmt.Compile(sel.template())
+	if err != nil {
+		return nil, err
 	}
+	key := resultCacheKey(query, args)
 
-	rows, err := sess.StatementQuery(ctx, sq.statement(), sq.arguments()...)
-	return &iterator{sess, rows, err}
+	cached, ok := cache.Get(key)
+	if !ok {
+		rows, err := sel.SQLBuilder().sess.StatementQuery(ctx, stmt, args...)
+		if err != nil {
+			return nil, err
+		}
+		if cached, err = readCachedRows(rows); err != nil {
+			return nil, err
+		}
+		cache.Set(key, cached, sq.cacheTTL)
+	}
+	rows, err := replayCachedRows(ctx, cached)
+	return rowsCursor(rows), err
 }
 
 func (sel *selector) Paginate(pageSize uint) Paginator {
@@ -503,7 +710,11 @@ func (sel *selector) build() (*selectorQuery, error) {
 }
 
 func (sel *selector) Compile() (string, error) {
-	return sel.statement().Compile(sel.template())
+	sq, err := sel.build()
+	if err != nil {
+		return "", err
+	}
+	return sq.statement().Compile(sel.template())
 }
 
 func (sel *selector) Prev() immutable.Immutable {
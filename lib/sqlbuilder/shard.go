@@ -0,0 +1,91 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ShardedDatabase routes work to one of several sessions, the shards, using a
+// key like a tenant ID:
+//
+//	sharded := sqlbuilder.NewShardedDatabase(func(key interface{}) int {
+//		return int(key.(int64) % 2)
+//	}, shardA, shardB)
+//
+//	err := sharded.Shard(tenantID).Collection("orders").Find().All(&orders)
+//
+// Queries that span shards are not supported, use ForEachShard to run the same
+// query on every shard and combine the results.
+type ShardedDatabase struct {
+	shards []Database
+	route  func(key interface{}) int
+}
+
+// NewShardedDatabase returns a ShardedDatabase over the given sessions. route
+// maps a key to the index of its shard in shards, it must return the same
+// index for the same key every time.
+func NewShardedDatabase(route func(key interface{}) int, shards ...Database) *ShardedDatabase {
+	if route == nil {
+		panic(`sqlbuilder.NewShardedDatabase() called with a nil route function`)
+	}
+	if len(shards) == 0 {
+		panic(`sqlbuilder.NewShardedDatabase() called without shards`)
+	}
+	return &ShardedDatabase{
+		shards: append([]Database(nil), shards...),
+		route:  route,
+	}
+}
+
+// Shard returns the session the given key is routed to. It panics if the
+// route function returns an index out of range.
+func (s *ShardedDatabase) Shard(key interface{}) Database {
+	i := s.route(key)
+	if i < 0 || i >= len(s.shards) {
+		panic(fmt.Sprintf("sqlbuilder: key %v was routed to shard %d, there are %d shards", key, i, len(s.shards)))
+	}
+	return s.shards[i]
+}
+
+// Shards returns all the sessions, in the order they were given.
+func (s *ShardedDatabase) Shards() []Database {
+	return append([]Database(nil), s.shards...)
+}
+
+// ForEachShard calls fn concurrently with every shard and its index and waits
+// for all of them to return. The error of the shard with the lowest index that
+// failed is returned.
+func (s *ShardedDatabase) ForEachShard(fn func(shard int, sess Database) error) error {
+	errs := make([]error, len(s.shards))
+
+	var wg sync.WaitGroup
+	for i := range s.shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fn(i, s.shards[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping pings every shard and returns the first error.
+func (s *ShardedDatabase) Ping() error {
+	return s.ForEachShard(func(shard int, sess Database) error {
+		return sess.Ping()
+	})
+}
+
+// Close closes every shard and returns the first error.
+func (s *ShardedDatabase) Close() error {
+	return s.ForEachShard(func(shard int, sess Database) error {
+		return sess.Close()
+	})
+}
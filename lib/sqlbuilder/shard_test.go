@@ -0,0 +1,56 @@
+package sqlbuilder
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedDatabase(t *testing.T) {
+	errDown := errors.New("down")
+	shards := []Database{&pingStub{}, &pingStub{err: errDown}, &pingStub{}}
+
+	sharded := NewShardedDatabase(func(key interface{}) int {
+		return key.(int) % 3
+	}, shards...)
+
+	assert.True(t, shards[0] == sharded.Shard(3))
+	assert.True(t, shards[1] == sharded.Shard(4))
+	assert.True(t, shards[2] == sharded.Shard(5))
+	assert.Equal(t, shards, sharded.Shards())
+
+	assert.Panics(t, func() {
+		sharded.Shard(-1)
+	})
+
+	var mu sync.Mutex
+	seen := map[int]bool{}
+	err := sharded.ForEachShard(func(shard int, sess Database) error {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.True(t, shards[shard] == sess)
+		seen[shard] = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]bool{0: true, 1: true, 2: true}, seen)
+
+	err = sharded.ForEachShard(func(shard int, sess Database) error {
+		if shard > 0 {
+			return errors.New(string(rune('a' + shard)))
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "b")
+
+	assert.Equal(t, errDown, sharded.Ping())
+
+	assert.Panics(t, func() {
+		NewShardedDatabase(nil, shards...)
+	})
+	assert.Panics(t, func() {
+		NewShardedDatabase(func(interface{}) int { return 0 })
+	})
+}
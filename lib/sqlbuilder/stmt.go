@@ -0,0 +1,63 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Stmt is a prepared statement created with PrepareStmt. Arguments are converted
+// the same way the session converts them, and the ones given to a builder
+// when it was prepared are sent before the ones given to each call.
+//
+// Statements prepared within a transaction run on the transaction's
+// connection and are closed when it's committed or rolled back.
+type Stmt struct {
+	*sql.Stmt
+
+	sess exprDB
+	args []interface{}
+}
+
+// Exec executes the prepared statement with the given arguments.
+func (s *Stmt) Exec(args ...interface{}) (sql.Result, error) {
+	return s.ExecContext(s.sess.Context(), args...)
+}
+
+// ExecContext executes the prepared statement with the given arguments.
+func (s *Stmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	return s.Stmt.ExecContext(ctx, s.arguments(args)...)
+}
+
+// Query executes the prepared statement with the given arguments and returns
+// its rows.
+func (s *Stmt) Query(args ...interface{}) (*sql.Rows, error) {
+	return s.QueryContext(s.sess.Context(), args...)
+}
+
+// QueryContext executes the prepared statement with the given arguments and
+// returns its rows.
+func (s *Stmt) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	return s.Stmt.QueryContext(ctx, s.arguments(args)...)
+}
+
+// QueryRow executes the prepared statement with the given arguments and
+// returns at most one row.
+func (s *Stmt) QueryRow(args ...interface{}) *sql.Row {
+	return s.QueryRowContext(s.sess.Context(), args...)
+}
+
+// QueryRowContext executes the prepared statement with the given arguments
+// and returns at most one row.
+func (s *Stmt) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+	return s.Stmt.QueryRowContext(ctx, s.arguments(args)...)
+}
+
+func (s *Stmt) arguments(args []interface{}) []interface{} {
+	if len(s.args) > 0 {
+		args = append(append([]interface{}(nil), s.args...), args...)
+	}
+	if converter, ok := s.sess.(hasConvertValues); ok {
+		args = converter.ConvertValues(args)
+	}
+	return args
+}
@@ -17,33 +17,30 @@ func newTemplateWithUtils(template *exql.Template) *templateWithUtils {
 	return &templateWithUtils{template}
 }
 
-func (tu *templateWithUtils) PlaceholderValue(in interface{}) (exql.Fragment, []interface{}) {
+// preprocess works like Preprocess, with the template's rules for literals.
+func (tu *templateWithUtils) preprocess(in string, args []interface{}) (string, []interface{}) {
+	return PreprocessWithTemplate(tu.Template, in, args)
+}
+
+func (tu *templateWithUtils) PlaceholderValue(in interface{}) (exql.Fragment, []interface{}, error) {
 	switch t := in.(type) {
 	case db.RawValue:
-		return exql.RawValue(t.String()), t.Arguments()
+		return exql.RawValue(t.String()), t.Arguments(), nil
 	case db.Function:
-		fnName := t.Name()
-		fnArgs := []interface{}{}
-		args, _ := toInterfaceArguments(t.Arguments())
-		fragments := []string{}
-		for i := range args {
-			frag, args := tu.PlaceholderValue(args[i])
-			fragment, err := frag.Compile(tu.Template)
-			if err == nil {
-				fragments = append(fragments, fragment)
-				fnArgs = append(fnArgs, args...)
-			}
-		}
-		return exql.RawValue(fnName + `(` + strings.Join(fragments, `, `) + `)`), fnArgs
+		return functionFragment(t)
+	case db.Expr:
+		return exprFragment(t)
+	case db.Column:
+		return exql.ColumnWithName(t.ColumnName()), nil, nil
 	default:
 		// Value must be escaped.
-		return sqlPlaceholder, []interface{}{in}
+		return sqlPlaceholder, []interface{}{in}, nil
 	}
 }
 
 // toWhereWithArguments converts the given parameters into a exql.Where
 // value.
-func (tu *templateWithUtils) toWhereWithArguments(term interface{}) (where exql.Where, args []interface{}) {
+func (tu *templateWithUtils) toWhereWithArguments(term interface{}) (where exql.Where, args []interface{}, err error) {
 	args = []interface{}{}
 
 	switch t := term.(type) {
@@ -51,7 +48,7 @@ func (tu *templateWithUtils) toWhereWithArguments(term interface{}) (where exql.
 		if len(t) > 0 {
 			if s, ok := t[0].(string); ok {
 				if strings.ContainsAny(s, "?") || len(t) == 1 {
-					s, args = Preprocess(s, t[1:])
+					s, args = tu.preprocess(s, t[1:])
 					where.Conditions = []exql.Fragment{exql.RawValue(s)}
 				} else {
 					var val interface{}
@@ -61,7 +58,10 @@ func (tu *templateWithUtils) toWhereWithArguments(term interface{}) (where exql.
 					} else {
 						val = t[1]
 					}
-					cv, v := tu.toColumnValues(db.NewConstraint(key, val))
+					cv, v, err := tu.toColumnValues(db.NewConstraint(key, val))
+					if err != nil {
+						return where, nil, err
+					}
 					args = append(args, v...)
 					for i := range cv.ColumnValues {
 						where.Conditions = append(where.Conditions, cv.ColumnValues[i])
@@ -70,8 +70,30 @@ func (tu *templateWithUtils) toWhereWithArguments(term interface{}) (where exql.
 				return
 			}
 		}
+		if len(t) > 1 {
+			if fn, ok := t[0].(db.Function); ok {
+				var val interface{}
+				if len(t) > 2 {
+					val = t[1:]
+				} else {
+					val = t[1]
+				}
+				cv, v, err := tu.toColumnValues(db.NewConstraint(fn, val))
+				if err != nil {
+					return where, nil, err
+				}
+				args = append(args, v...)
+				for i := range cv.ColumnValues {
+					where.Conditions = append(where.Conditions, cv.ColumnValues[i])
+				}
+				return where, args, nil
+			}
+		}
 		for i := range t {
-			w, v := tu.toWhereWithArguments(t[i])
+			w, v, err := tu.toWhereWithArguments(t[i])
+			if err != nil {
+				return where, nil, err
+			}
 			if len(w.Conditions) == 0 {
 				continue
 			}
@@ -80,13 +102,16 @@ func (tu *templateWithUtils) toWhereWithArguments(term interface{}) (where exql.
 		}
 		return
 	case db.RawValue:
-		r, v := Preprocess(t.Raw(), t.Arguments())
+		r, v := tu.preprocess(t.Raw(), t.Arguments())
 		where.Conditions = []exql.Fragment{exql.RawValue(r)}
 		args = append(args, v...)
 		return
 	case db.Constraints:
 		for _, c := range t.Constraints() {
-			w, v := tu.toWhereWithArguments(c)
+			w, v, err := tu.toWhereWithArguments(c)
+			if err != nil {
+				return where, nil, err
+			}
 			if len(w.Conditions) == 0 {
 				continue
 			}
@@ -98,7 +123,10 @@ func (tu *templateWithUtils) toWhereWithArguments(term interface{}) (where exql.
 		var cond exql.Where
 
 		for _, c := range t.Sentences() {
-			w, v := tu.toWhereWithArguments(c)
+			w, v, err := tu.toWhereWithArguments(c)
+			if err != nil {
+				return where, nil, err
+			}
 			if len(w.Conditions) == 0 {
 				continue
 			}
@@ -123,10 +151,13 @@ func (tu *templateWithUtils) toWhereWithArguments(term interface{}) (where exql.
 
 		return
 	case db.Constraint:
-		cv, v := tu.toColumnValues(t)
+		cv, v, err := tu.toColumnValues(t)
+		if err != nil {
+			return where, nil, err
+		}
 		args = append(args, v...)
 		where.Conditions = append(where.Conditions, cv.ColumnValues...)
-		return where, args
+		return where, args, nil
 	}
 
 	panic(fmt.Sprintf("Unknown condition type %T", term))
@@ -147,7 +178,7 @@ func (tu *templateWithUtils) comparisonOperatorMapper(t db.ComparisonOperator) s
 	panic(fmt.Sprintf("unsupported comparison operator %v", t))
 }
 
-func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnValues, args []interface{}) {
+func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnValues, args []interface{}, err error) {
 	args = []interface{}{}
 
 	switch t := term.(type) {
@@ -165,6 +196,12 @@ func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnVal
 			if rawValue, ok := t.Key().(db.RawValue); ok {
 				columnValue.Column = exql.RawValue(rawValue.Raw())
 				args = append(args, rawValue.Arguments()...)
+			} else if fn, ok := t.Key().(db.Function); ok {
+				var fnArgs []interface{}
+				if columnValue.Column, fnArgs, err = functionFragment(fn); err != nil {
+					return cv, nil, err
+				}
+				args = append(args, fnArgs...)
 			} else {
 				columnValue.Column = exql.RawValue(fmt.Sprintf("%v", t.Key()))
 			}
@@ -172,19 +209,21 @@ func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnVal
 
 		switch value := t.Value().(type) {
 		case db.Function:
-			fnName, fnArgs := value.Name(), value.Arguments()
-			if len(fnArgs) == 0 {
-				// A function with no arguments.
-				fnName = fnName + "()"
-			} else {
-				// A function with one or more arguments.
-				fnName = fnName + "(?" + strings.Repeat("?, ", len(fnArgs)-1) + ")"
+			fn, fnArgs, err := functionFragment(value)
+			if err != nil {
+				return cv, nil, err
 			}
-			fnName, fnArgs = Preprocess(fnName, fnArgs)
-			columnValue.Value = exql.RawValue(fnName)
+			columnValue.Value = fn
 			args = append(args, fnArgs...)
+		case db.Expr:
+			expr, exprArgs, err := exprFragment(value)
+			if err != nil {
+				return cv, nil, err
+			}
+			columnValue.Value = expr
+			args = append(args, exprArgs...)
 		case db.RawValue:
-			q, a := Preprocess(value.Raw(), value.Arguments())
+			q, a := tu.preprocess(value.Raw(), value.Arguments())
 			columnValue.Value = exql.RawValue(q)
 			args = append(args, a...)
 		case driver.Valuer:
@@ -198,7 +237,7 @@ func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnVal
 			}
 
 			q, a := wrapper.preprocess()
-			q, a = Preprocess(q, a)
+			q, a = tu.preprocess(q, a)
 
 			columnValue = exql.ColumnValue{
 				Column: exql.RawValue(q),
@@ -208,7 +247,7 @@ func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnVal
 			}
 
 			cv.ColumnValues = append(cv.ColumnValues, &columnValue)
-			return cv, args
+			return cv, args, nil
 		default:
 			wrapper := &operatorWrapper{
 				tu: tu,
@@ -217,7 +256,7 @@ func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnVal
 			}
 
 			q, a := wrapper.preprocess()
-			q, a = Preprocess(q, a)
+			q, a = tu.preprocess(q, a)
 
 			columnValue = exql.ColumnValue{
 				Column: exql.RawValue(q),
@@ -227,33 +266,46 @@ func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnVal
 			}
 
 			cv.ColumnValues = append(cv.ColumnValues, &columnValue)
-			return cv, args
+			return cv, args, nil
 		}
 
 		if columnValue.Operator == "" {
 			columnValue.Operator = tu.comparisonOperatorMapper(db.ComparisonOperatorEqual)
 		}
 		cv.ColumnValues = append(cv.ColumnValues, &columnValue)
-		return cv, args
+		return cv, args, nil
 	case db.RawValue:
 		columnValue := exql.ColumnValue{}
-		p, q := Preprocess(t.Raw(), t.Arguments())
+		p, q := tu.preprocess(t.Raw(), t.Arguments())
 		columnValue.Column = exql.RawValue(p)
 		cv.ColumnValues = append(cv.ColumnValues, &columnValue)
 		args = append(args, q...)
-		return cv, args
+		return cv, args, nil
 	case db.Constraints:
 		for _, constraint := range t.Constraints() {
-			p, q := tu.toColumnValues(constraint)
+			p, q, err := tu.toColumnValues(constraint)
+			if err != nil {
+				return cv, nil, err
+			}
 			cv.ColumnValues = append(cv.ColumnValues, p.ColumnValues...)
 			args = append(args, q...)
 		}
-		return cv, args
+		return cv, args, nil
 	}
 
 	panic(fmt.Sprintf("Unknown term type %T.", term))
 }
 
+// rawSetValue returns the raw value given to a "column = ?" assignment, if
+// any.
+func rawSetValue(format string, args []interface{}) (db.RawValue, bool) {
+	if format != "?" || len(args) == 0 {
+		return nil, false
+	}
+	raw, ok := args[0].(db.RawValue)
+	return raw, ok
+}
+
 func (tu *templateWithUtils) setColumnValues(term interface{}) (cv exql.ColumnValues, args []interface{}) {
 	args = []interface{}{}
 
@@ -286,6 +338,14 @@ func (tu *templateWithUtils) setColumnValues(term interface{}) (cv exql.ColumnVa
 			}
 
 			ps := strings.Count(format, "?")
+			if raw, ok := rawSetValue(format, t[i+1:]); ok {
+				// Raw values, like db.Default, are put in place.
+				columnValue.Value = exql.RawValue(raw.Raw())
+				args = append(args, raw.Arguments()...)
+				cv.ColumnValues = append(cv.ColumnValues, &columnValue)
+				i++
+				continue
+			}
 			if i+ps < l {
 				for j := 0; j < ps; j++ {
 					args = append(args, t[i+j+1])
@@ -300,7 +360,7 @@ func (tu *templateWithUtils) setColumnValues(term interface{}) (cv exql.ColumnVa
 		return cv, args
 	case db.RawValue:
 		columnValue := exql.ColumnValue{}
-		p, q := Preprocess(t.Raw(), t.Arguments())
+		p, q := tu.preprocess(t.Raw(), t.Arguments())
 		columnValue.Column = exql.RawValue(p)
 		cv.ColumnValues = append(cv.ColumnValues, &columnValue)
 		args = append(args, q...)
@@ -15,7 +15,12 @@ const (
 	defaultOrKeyword           = `OR`
 	defaultDescKeyword         = `DESC`
 	defaultAscKeyword          = `ASC`
+	defaultCollateLayout       = `COLLATE "{{.}}"`
+	defaultInsertedLayout      = `(xmax = 0) AS "inserted"`
+	defaultNullsFirstKeyword   = `NULLS FIRST`
+	defaultNullsLastKeyword    = `NULLS LAST`
 	defaultAssignmentOperator  = `=`
+	defaultConcatOperator      = `||`
 	defaultClauseGroup         = `({{.}})`
 	defaultClauseOperator      = ` {{.}} `
 	defaultColumnValue         = `{{.Column}} {{.Operator}} {{.Value}}`
@@ -63,6 +68,12 @@ const (
     {{end}}
   `
 
+	defaultLockLayout = `FOR UPDATE{{if .SkipLocked}} SKIP LOCKED{{end}}`
+
+	defaultSampleLayout = `TABLESAMPLE SYSTEM ({{.Percent}})`
+
+	defaultRandomFunction = `random()`
+
 	defaultSelectLayout = `
     SELECT
       {{if .Distinct}}
@@ -76,7 +87,7 @@ const (
       {{end}}
 
       {{if defined .Table}}
-        FROM {{.Table | compile}}
+        FROM {{.Table | compile}} {{.Sample | compile}}
       {{end}}
 
       {{.Joins | compile}}
@@ -96,19 +107,29 @@ const (
       {{if .Offset}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{.Lock | compile}}
   `
 	defaultDeleteLayout = `
     DELETE
       FROM {{.Table | compile}}
+      {{.From | compile}}
       {{.Where | compile}}
   `
 	defaultUpdateLayout = `
     UPDATE
       {{.Table | compile}}
     SET {{.ColumnValues | compile}}
+      {{.From | compile}}
       {{.Where | compile}}
   `
 
+	defaultUpdateFromLayout = `FROM {{.Sources | compile}} {{.Joins | compile}}`
+
+	defaultValuesTableLayout = `(VALUES {{.Rows | compile}}) AS {{.Alias | compile}} ({{.Columns | compile}})`
+
+	defaultDeleteUsingLayout = `USING {{.Sources | compile}} {{.Joins | compile}}`
+
 	defaultCountLayout = `
     SELECT
       COUNT(1) AS _t
@@ -133,11 +154,23 @@ const (
     {{else}}
       (default)
     {{end}}
+    {{if defined .OnConflict}}
+      {{.OnConflict | compile}}
+    {{end}}
     {{if defined .Returning}}
       RETURNING {{.Returning | compile}}
     {{end}}
   `
 
+	defaultOnConflictLayout = `
+    ON CONFLICT ({{range $i, $c := .Target}}{{if $i}}, {{end}}{{$c}}{{end}})
+    {{if .Update}}
+      DO UPDATE SET {{range $i, $c := .Update}}{{if $i}}, {{end}}{{$c}} = EXCLUDED.{{$c}}{{end}}
+    {{else}}
+      DO NOTHING
+    {{end}}
+  `
+
 	defaultTruncateLayout = `
     TRUNCATE TABLE {{.Table | compile}}
   `
@@ -147,7 +180,19 @@ const (
   `
 
 	defaultDropTableLayout = `
-    DROP TABLE {{.Table | compile}}
+    DROP TABLE {{if .IfExists}}IF EXISTS {{end}}{{.Table | compile}}{{if .Cascade}} CASCADE{{end}}
+  `
+
+	defaultAlterTableLayout = `
+    ALTER TABLE {{.Table | compile}} {{.Alterations | compile}}
+  `
+
+	defaultAddColumnLayout = `ADD COLUMN {{.Column}}`
+
+	defaultDropColumnLayout = `DROP COLUMN {{.Column}}`
+
+	defaultCreateTableLayout = `
+    CREATE {{if .Temporary}}TEMPORARY {{end}}TABLE {{if .IfNotExists}}IF NOT EXISTS {{end}}{{.Table | compile}} ({{.Definitions | compile}})
   `
 
 	defaultGroupByColumnLayout = `{{.Column}}`
@@ -169,7 +214,11 @@ var testTemplate = exql.Template{
 	OrKeyword:           defaultOrKeyword,
 	DescKeyword:         defaultDescKeyword,
 	AscKeyword:          defaultAscKeyword,
+	CollateLayout:       defaultCollateLayout,
+	NullsFirstKeyword:   defaultNullsFirstKeyword,
+	NullsLastKeyword:    defaultNullsLastKeyword,
 	AssignmentOperator:  defaultAssignmentOperator,
+	ConcatOperator:      defaultConcatOperator,
 	ClauseGroup:         defaultClauseGroup,
 	ClauseOperator:      defaultClauseOperator,
 	ColumnValue:         defaultColumnValue,
@@ -180,14 +229,26 @@ var testTemplate = exql.Template{
 	OnLayout:            defaultOnLayout,
 	UsingLayout:         defaultUsingLayout,
 	JoinLayout:          defaultJoinLayout,
+	LockLayout:          defaultLockLayout,
+	SampleLayout:        defaultSampleLayout,
+	RandomFunction:      defaultRandomFunction,
+	UpdateFromLayout:    defaultUpdateFromLayout,
+	DeleteUsingLayout:   defaultDeleteUsingLayout,
+	ValuesTableLayout:   defaultValuesTableLayout,
 	OrderByLayout:       defaultOrderByLayout,
 	InsertLayout:        defaultInsertLayout,
+	InsertedLayout:      defaultInsertedLayout,
+	OnConflictLayout:    defaultOnConflictLayout,
 	SelectLayout:        defaultSelectLayout,
 	UpdateLayout:        defaultUpdateLayout,
 	DeleteLayout:        defaultDeleteLayout,
 	TruncateLayout:      defaultTruncateLayout,
 	DropDatabaseLayout:  defaultDropDatabaseLayout,
 	DropTableLayout:     defaultDropTableLayout,
+	CreateTableLayout:   defaultCreateTableLayout,
+	AlterTableLayout:    defaultAlterTableLayout,
+	AddColumnLayout:     defaultAddColumnLayout,
+	DropColumnLayout:    defaultDropColumnLayout,
 	CountLayout:         defaultCountLayout,
 	GroupByLayout:       defaultGroupByLayout,
 	Cache:               cache.NewCache(),
@@ -3,6 +3,7 @@ package sqlbuilder
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/frazercomputing/upper-io-db/internal/immutable"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
@@ -16,16 +17,28 @@ type updaterQuery struct {
 
 	limit int
 
+	from     *exql.Columns
+	fromArgs []interface{}
+
+	joins     []*exql.Join
+	joinsArgs []interface{}
+
 	where     *exql.Where
 	whereArgs []interface{}
 
+	returning []exql.Fragment
+
 	err error
 
 	amendFn func(string) string
+	comment string
 }
 
 func (uq *updaterQuery) and(b *sqlBuilder, terms ...interface{}) error {
-	where, whereArgs := b.t.toWhereWithArguments(terms)
+	where, whereArgs, err := b.t.toWhereWithArguments(terms)
+	if err != nil {
+		return err
+	}
 
 	if uq.where == nil {
 		uq.where, uq.whereArgs = &exql.Where{}, []interface{}{}
@@ -36,6 +49,21 @@ func (uq *updaterQuery) and(b *sqlBuilder, terms ...interface{}) error {
 	return nil
 }
 
+func (uq *updaterQuery) or(b *sqlBuilder, terms ...interface{}) error {
+	where, whereArgs, err := b.t.toWhereWithArguments(terms)
+	if err != nil {
+		return err
+	}
+
+	if uq.whereArgs == nil {
+		uq.whereArgs = []interface{}{}
+	}
+	uq.where = orWhere(uq.where, where)
+	uq.whereArgs = append(uq.whereArgs, whereArgs...)
+
+	return nil
+}
+
 func (uq *updaterQuery) statement() *exql.Statement {
 	stmt := &exql.Statement{
 		Type:         exql.Update,
@@ -51,7 +79,23 @@ func (uq *updaterQuery) statement() *exql.Statement {
 		stmt.Limit = exql.Limit(uq.limit)
 	}
 
+	if uq.from != nil {
+		from := &exql.UpdateFrom{
+			Table:   stmt.Table,
+			Sources: uq.from,
+		}
+		if len(uq.joins) > 0 {
+			from.Joins = exql.JoinConditions(uq.joins...)
+		}
+		stmt.From = from
+	}
+
+	if len(uq.returning) > 0 {
+		stmt.Returning = exql.ReturningColumns(uq.returning...)
+	}
+
 	stmt.SetAmendment(uq.amendFn)
+	stmt.Comment = uq.comment
 
 	return stmt
 }
@@ -59,10 +103,34 @@ func (uq *updaterQuery) statement() *exql.Statement {
 func (uq *updaterQuery) arguments() []interface{} {
 	return joinArguments(
 		uq.columnValuesArgs,
+		uq.fromArgs,
+		uq.joinsArgs,
 		uq.whereArgs,
 	)
 }
 
+func (uq *updaterQuery) pushJoin(layout *exql.Template, t string, tables []interface{}) error {
+	if uq.from == nil {
+		return errors.New(`cannot use Join() without a preceding From() expression`)
+	}
+
+	fragments, args, err := columnFragments(layout, tables)
+	if err != nil {
+		return err
+	}
+
+	uq.joins = append(uq.joins,
+		&exql.Join{
+			Type:  t,
+			Table: exql.JoinColumns(fragments...),
+		},
+	)
+
+	uq.joinsArgs = append(uq.joinsArgs, args...)
+
+	return nil
+}
+
 type updater struct {
 	builder *sqlBuilder
 
@@ -84,11 +152,15 @@ func (upd *updater) template() *exql.Template {
 }
 
 func (upd *updater) String() string {
-	s, err := upd.Compile()
+	stmt, err := upd.statement()
 	if err != nil {
 		panic(err.Error())
 	}
-	return prepareQueryForDisplay(s)
+	s, err := stmt.Compile(upd.template())
+	if err != nil {
+		panic(err.Error())
+	}
+	return exql.CommentPrefix(stmt.Comment) + prepareQueryForDisplay(upd.template(), s)
 }
 
 func (upd *updater) setTable(table string) *updater {
@@ -121,7 +193,9 @@ func (upd *updater) Set(terms ...interface{}) Updater {
 					}
 
 					var localArgs []interface{}
-					cv.Value, localArgs = upd.SQLBuilder().t.PlaceholderValue(vv[i])
+					if cv.Value, localArgs, err = upd.SQLBuilder().t.PlaceholderValue(vv[i]); err != nil {
+						return err
+					}
 
 					args = append(args, localArgs...)
 					cvs = append(cvs, cv)
@@ -148,6 +222,13 @@ func (upd *updater) Amend(fn func(string) string) Updater {
 	})
 }
 
+func (upd *updater) Comment(comment string) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		uq.comment = comment
+		return nil
+	})
+}
+
 func (upd *updater) Arguments() []interface{} {
 	uq, err := upd.build()
 	if err != nil {
@@ -169,6 +250,66 @@ func (upd *updater) And(terms ...interface{}) Updater {
 	})
 }
 
+func (upd *updater) Or(terms ...interface{}) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		return uq.or(upd.SQLBuilder(), terms...)
+	})
+}
+
+func (upd *updater) From(tables ...interface{}) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		if upd.template().UpdateFromLayout == "" {
+			return exql.ErrUpdateFromUnsupported
+		}
+		fragments, args, err := columnFragments(upd.template(), tables)
+		if err != nil {
+			return err
+		}
+		uq.from = exql.JoinColumns(fragments...)
+		uq.fromArgs = args
+		return nil
+	})
+}
+
+func (upd *updater) Join(tables ...interface{}) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		return uq.pushJoin(upd.template(), "", tables)
+	})
+}
+
+func (upd *updater) LeftJoin(tables ...interface{}) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		return uq.pushJoin(upd.template(), "LEFT", tables)
+	})
+}
+
+func (upd *updater) On(terms ...interface{}) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		joins := len(uq.joins)
+
+		if joins == 0 {
+			return errors.New(`cannot use On() without a preceding Join() expression`)
+		}
+
+		lastJoin := uq.joins[joins-1]
+		if lastJoin.On != nil {
+			return errors.New(`cannot use On() twice with the same Join() expression`)
+		}
+
+		w, a, err := upd.SQLBuilder().t.toWhereWithArguments(terms)
+		if err != nil {
+			return err
+		}
+		o := exql.On(w)
+
+		lastJoin.On = &o
+
+		uq.joinsArgs = append(uq.joinsArgs, a...)
+
+		return nil
+	})
+}
+
 func (upd *updater) Prepare() (*sql.Stmt, error) {
 	return upd.PrepareContext(upd.SQLBuilder().sess.Context())
 }
@@ -181,6 +322,26 @@ func (upd *updater) PrepareContext(ctx context.Context) (*sql.Stmt, error) {
 	return upd.SQLBuilder().sess.StatementPrepare(ctx, uq.statement())
 }
 
+func (upd *updater) Explain(ctx context.Context, analyze bool) (string, error) {
+	return upd.explain(ctx, ExplainOptions{Analyze: analyze})
+}
+
+func (upd *updater) ExplainJSON(ctx context.Context, analyze bool) (string, error) {
+	return upd.explain(ctx, ExplainOptions{Analyze: analyze, JSON: true})
+}
+
+func (upd *updater) explain(ctx context.Context, opts ExplainOptions) (string, error) {
+	uq, err := upd.build()
+	if err != nil {
+		return "", err
+	}
+	return explain(ctx, upd.SQLBuilder().sess, uq.statement(), uq.arguments(), opts)
+}
+
+func (upd *updater) Fingerprint() string {
+	return fingerprint(upd)
+}
+
 func (upd *updater) Exec() (sql.Result, error) {
 	return upd.ExecContext(upd.SQLBuilder().sess.Context())
 }
@@ -193,6 +354,25 @@ func (upd *updater) ExecContext(ctx context.Context) (sql.Result, error) {
 	return upd.SQLBuilder().sess.StatementExec(ctx, uq.statement(), uq.arguments()...)
 }
 
+func (upd *updater) Returning(columns ...string) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		return columnsToFragments(&uq.returning, columns)
+	})
+}
+
+func (upd *updater) Iterator() Iterator {
+	return upd.IteratorContext(upd.SQLBuilder().sess.Context())
+}
+
+func (upd *updater) IteratorContext(ctx context.Context) Iterator {
+	uq, err := upd.build()
+	if err != nil {
+		return &iterator{sess: upd.SQLBuilder().sess, err: err}
+	}
+	rows, err := queryCursor(ctx, upd.SQLBuilder().sess, uq.statement(), uq.arguments())
+	return &iterator{sess: upd.SQLBuilder().sess, cursor: rows, err: err}
+}
+
 func (upd *updater) Limit(limit int) Updater {
 	return upd.frame(func(uq *updaterQuery) error {
 		uq.limit = limit
@@ -209,11 +389,15 @@ func (upd *updater) statement() (*exql.Statement, error) {
 }
 
 func (upd *updater) build() (*updaterQuery, error) {
-	uq, err := immutable.FastForward(upd)
+	v, err := immutable.FastForward(upd)
 	if err != nil {
 		return nil, err
 	}
-	return uq.(*updaterQuery), nil
+	uq := v.(*updaterQuery)
+	if uq.from != nil && uq.limit != 0 {
+		return nil, errors.New(`cannot use Limit() and From() in the same update`)
+	}
+	return uq, nil
 }
 
 func (upd *updater) Compile() (string, error) {
@@ -0,0 +1,128 @@
+package sqlbuilder
+
+import (
+	"errors"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+)
+
+// ValuesList is a list of rows that can be used as a table, see Values.
+type ValuesList struct {
+	rows    [][]interface{}
+	columns []string
+	alias   string
+	types   []string
+}
+
+// Values returns a list of rows that can be used as a table in From, Join,
+// Updater.From and Deleter.Using, like
+// (VALUES (1, 0.5), (2, 0.8)) AS "v" ("id", "score"):
+//
+//	scores := sqlbuilder.Values([][]interface{}{{1, 0.5}, {2, 0.8}}, []string{"id", "score"}).As("s")
+//	q := sess.Select("a.name", "s.score").From("artist AS a").Join(scores).On("s.id = a.id")
+//
+// Every value is sent as an argument. The ones on the first row are cast to
+// a type that matches their Go type, as PostgreSQL guesses the types of the
+// columns from it, use Types to choose them. The list is named "v" unless As
+// is used. PostgreSQL and SQL Server support this, other databases return an
+// error when the query is run.
+func Values(rows [][]interface{}, columns []string) *ValuesList {
+	return &ValuesList{rows: rows, columns: columns, alias: "v"}
+}
+
+// As sets the name the list is referred to with.
+func (v *ValuesList) As(alias string) *ValuesList {
+	c := *v
+	c.alias = alias
+	return &c
+}
+
+// Types sets the types the columns are cast to, like "integer" or "text".
+// Types are translated like the ones of CreateTable, an empty type means no
+// cast.
+func (v *ValuesList) Types(types ...string) *ValuesList {
+	c := *v
+	c.types = types
+	return &c
+}
+
+func (v *ValuesList) fragment(layout *exql.Template) (exql.Fragment, []interface{}, error) {
+	if layout.ValuesTableLayout == "" {
+		return nil, nil, exql.ErrValuesTableUnsupported
+	}
+	if len(v.rows) == 0 {
+		return nil, nil, errors.New(`a VALUES list needs at least one row`)
+	}
+
+	args := []interface{}{}
+	groups := make([]*exql.Values, 0, len(v.rows))
+
+	for i, row := range v.rows {
+		if len(row) != len(v.columns) {
+			return nil, nil, errors.New(`all rows of a VALUES list must have a value for each column`)
+		}
+
+		values := make([]exql.Fragment, 0, len(row))
+		for j := range row {
+			var value exql.Fragment = sqlPlaceholder
+			if raw, ok := row[j].(db.RawValue); ok {
+				q, a := PreprocessWithTemplate(layout, raw.Raw(), raw.Arguments())
+				value = exql.RawValue(q)
+				args = append(args, a...)
+			} else {
+				args = append(args, row[j])
+			}
+			if i == 0 {
+				if t := v.columnType(j); t != "" {
+					value = &exql.Cast{Value: value, Type: t}
+				}
+			}
+			values = append(values, value)
+		}
+		groups = append(groups, exql.NewValueGroup(values...))
+	}
+
+	columns := make([]exql.Fragment, 0, len(v.columns))
+	for i := range v.columns {
+		columns = append(columns, exql.ColumnWithName(v.columns[i]))
+	}
+
+	return &exql.ValuesTable{
+		Rows:    exql.JoinValueGroups(groups...),
+		Alias:   exql.ColumnWithName(v.alias),
+		Columns: exql.JoinColumns(columns...),
+	}, args, nil
+}
+
+// columnType returns the type the i-th column is cast to.
+func (v *ValuesList) columnType(i int) string {
+	if v.types != nil {
+		if i < len(v.types) {
+			return v.types[i]
+		}
+		return ""
+	}
+	switch v.rows[0][i].(type) {
+	case int, int64, uint, uint32, uint64:
+		return "bigint"
+	case int32, uint16:
+		return "integer"
+	case int8, int16, uint8:
+		return "smallint"
+	case float32:
+		return "real"
+	case float64:
+		return "double precision"
+	case bool:
+		return "boolean"
+	case string:
+		return "text"
+	case []byte:
+		return "blob"
+	case time.Time:
+		return "timestamp with time zone"
+	}
+	return ""
+}
@@ -26,6 +26,7 @@ import (
 	"database/sql"
 	"fmt"
 	"sync"
+	"time"
 
 	db "github.com/frazercomputing/upper-io-db"
 )
@@ -58,6 +59,16 @@ type Tx interface {
 	// db.Tx adds Commit and Rollback methods to the transaction.
 	db.Tx
 
+	// Done returns true once the transaction was committed or rolled back,
+	// or its context expired, statements sent after that fail. It's safe to
+	// call concurrently.
+	Done() bool
+
+	// TxErr returns nil while the transaction can be used, sql.ErrTxDone after
+	// it was committed or rolled back, or the context's error if it expired
+	// first.
+	TxErr() error
+
 	// Context returns the context used as default for queries on this transaction.
 	// If no context has been set, a default context.Background() is returned.
 	Context() context.Context
@@ -73,9 +84,50 @@ type Tx interface {
 
 	// TxOptions returns the defaultx TxOptions.
 	TxOptions() *sql.TxOptions
+
+	// Outbox writes event, encoded as JSON, to the session's outbox table
+	// (see db.Settings.SetOutboxTable and OutboxMessage) within the
+	// transaction, so it's stored if and only if the transaction's other
+	// changes are committed. Database.DrainOutbox reads it back:
+	//
+	//	err := sess.Tx(ctx, func(tx sqlbuilder.Tx) error {
+	//		if err := tx.Collection("orders").InsertReturning(&order); err != nil {
+	//			return err
+	//		}
+	//		return tx.Outbox(OrderPlaced{OrderID: order.ID})
+	//	})
+	Outbox(event interface{}) error
+}
+
+// TxStats describes a transaction run by Database.TxWithStats, it's meant to
+// be reported to metrics, as a rising number of attempts or stale object
+// errors is a sign of contention.
+type TxStats struct {
+	// Attempts is the number of times the transaction was run.
+	Attempts int
+
+	// Wait is the time spent waiting between attempts.
+	Wait time.Duration
+
+	// Duration is the total time it took to run the transaction, including
+	// all attempts.
+	Duration time.Duration
+
+	// Err is the error the last attempt failed with, or nil.
+	Err error
+
+	// ErrKind is the portable error that matches Err, like
+	// db.ErrStaleObject, db.ErrUniqueViolation or context.Canceled, or nil if
+	// Err is nil or doesn't match any.
+	ErrKind error
 }
 
 // Database represents a SQL database.
+//
+// A Database is safe to use from many goroutines at once, each query takes
+// its own connection from the pool. Transactions (Tx) are bound to a single
+// connection, statements on a Tx run one at a time and it must not be
+// committed or rolled back while others still use it.
 type Database interface {
 	// All db.Database methods are available on this session.
 	db.Database
@@ -96,6 +148,11 @@ type Database interface {
 	// exits, regardless of the error value returned by fn.
 	Tx(ctx context.Context, fn func(sess Tx) error) error
 
+	// TxWithStats works like Tx and fills stats in with the details of the
+	// run, like the number of attempts and the kind of error the last one
+	// failed with. stats is filled in even if fn succeeds.
+	TxWithStats(ctx context.Context, fn func(sess Tx) error, stats *TxStats) error
+
 	// Context returns the context used as default for queries on this session
 	// and for new transactions.  If no context has been set, a default
 	// context.Background() is returned.
@@ -113,6 +170,70 @@ type Database interface {
 
 	// TxOptions returns the defaultx TxOptions.
 	TxOptions() *sql.TxOptions
+
+	// CloseContext stops accepting new transactions and queries, waits for
+	// in-flight transactions to be committed or rolled back and then closes
+	// the session. If ctx expires before that, a *CloseTimeoutError that
+	// carries ctx.Err() and the number of pending operations is returned and
+	// the session is left open.
+	CloseContext(ctx context.Context) error
+
+	// Warmup opens and pings up to n connections and leaves them idle in the
+	// pool, so the first queries of the session don't wait for connections
+	// to be established. n is capped by MaxOpenConns and MaxIdleConns, as
+	// the pool would close the connections that don't fit. Connections are
+	// opened like any other, so adapter hooks that initialize them, like
+	// postgresql's SetConnectInit, run as well. The first error found is
+	// returned.
+	Warmup(ctx context.Context, n int) error
+
+	// Subscribe calls fn with a db.Event for each change this session, its
+	// copies and the transactions started on them make to table, or to any
+	// table if table is empty, through collections. It's meant for in-process
+	// cache invalidation; changes made by other clients, or with plain SQL or
+	// the SQL builder, are not reported. Changes within a transaction are
+	// reported after it's committed. fn is called in the goroutine that made
+	// or committed the change, so it should not block. Subscribe returns a
+	// function that stops the calls, Subscribe and unsubscribe are safe to
+	// call concurrently, even from fn:
+	//
+	//	unsubscribe := sess.Subscribe("accounts", func(ev db.Event) {
+	//		for _, id := range ev.Keys {
+	//			cache.Delete(id)
+	//		}
+	//		if ev.Keys == nil {
+	//			cache.Clear()
+	//		}
+	//	})
+	//	defer unsubscribe()
+	Subscribe(table string, fn func(db.Event)) (unsubscribe func())
+
+	// DrainOutbox passes the messages of the outbox table that were not
+	// published yet to handler, in the order they were written, and marks
+	// the ones it accepts as published. Messages are read in batches within
+	// transactions that lock them with SKIP LOCKED, so many processes can
+	// drain the same outbox, each message going to one of them. It returns
+	// once there are no unpublished messages left, or with the first error
+	// handler returns; the messages handled before that are marked as
+	// published.
+	//
+	// Delivery is at least once: a message that was handled is passed to a
+	// handler again if its batch can't be committed, so handlers, or the
+	// consumers of what they publish, should be idempotent. Messages are
+	// passed in order within a process, but not across processes.
+	DrainOutbox(ctx context.Context, handler func(OutboxMessage) error) error
+
+	// SetResultCache sets the cache that the rows of the queries marked with
+	// Selector.Cacheable are read from and stored in, for this session and
+	// its copies. A nil cache turns caching off. Only the queries marked with
+	// Cacheable are cached, on a hit the database is not queried and the
+	// cached rows are scanned into the destination as if they had just been
+	// read. Queries run within transactions skip the cache, as they may read
+	// changes that were not committed.
+	SetResultCache(cache ResultCache)
+
+	// ResultCache returns the cache set with SetResultCache, if any.
+	ResultCache() ResultCache
 }
 
 // AdapterFuncMap is a struct that defines a set of functions that adapters
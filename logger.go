@@ -40,6 +40,7 @@ const (
 	fmtLogError        = `Error:          %v`
 	fmtLogTimeTaken    = `Time taken:     %0.5fs`
 	fmtLogContext      = `Context:        %v`
+	fmtLogRetried      = `Retried:        %v`
 )
 
 var (
@@ -60,6 +61,10 @@ type QueryStatus struct {
 
 	Err error
 
+	// Retried is true if the query failed because of a broken connection and
+	// was sent again.
+	Retried bool
+
 	Start time.Time
 	End   time.Time
 
@@ -99,6 +104,10 @@ func (q *QueryStatus) String() string {
 		lines = append(lines, fmt.Sprintf(fmtLogError, q.Err))
 	}
 
+	if q.Retried {
+		lines = append(lines, fmt.Sprintf(fmtLogRetried, q.Retried))
+	}
+
 	lines = append(lines, fmt.Sprintf(fmtLogTimeTaken, float64(q.End.UnixNano()-q.Start.UnixNano())/float64(1e9)))
 
 	if q.Context != nil {
@@ -0,0 +1,179 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics represents a collector of aggregated statement statistics. You can
+// pass a Metrics collector to db.DefaultSettings.SetMetrics(myCollector) or
+// sess.SetMetrics(myCollector) to make it record every statement after it
+// runs.
+type Metrics interface {
+	// RecordQuery is called after every statement. op is the kind of
+	// statement, like "select", "insert", "update", "delete" or "raw" for
+	// plain SQL, and table is the name of the main table of the statement, if
+	// it can be inferred. RecordQuery must be safe for concurrent use.
+	RecordQuery(op string, table string, dur time.Duration, err error)
+}
+
+// QueryMetricsBuckets are the upper bounds, in seconds, of the latency
+// histogram buckets kept by QueryMetrics.
+var QueryMetricsBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// QueryMetric holds the statistics of a single operation on a single table.
+type QueryMetric struct {
+	Op    string
+	Table string
+
+	// Count is the number of statements and Errors the number of statements
+	// that failed.
+	Count  uint64
+	Errors uint64
+
+	// Sum is the total amount of time spent running statements.
+	Sum time.Duration
+
+	// Buckets holds the cumulative number of statements that took at most the
+	// matching number of seconds in QueryMetricsBuckets.
+	Buckets []uint64
+}
+
+type queryMetricKey struct {
+	op    string
+	table string
+}
+
+// QueryMetrics is a Metrics collector that keeps statement counters and
+// latency histograms by operation and table in memory. Its statistics can be
+// written in the Prometheus text format with WriteTo, or served over HTTP
+// with the handler of the lib/metrics package.
+type QueryMetrics struct {
+	mu      sync.Mutex
+	metrics map[queryMetricKey]*QueryMetric
+}
+
+var _ = Metrics(&QueryMetrics{})
+
+// NewQueryMetrics creates an empty QueryMetrics collector.
+func NewQueryMetrics() *QueryMetrics {
+	return &QueryMetrics{
+		metrics: map[queryMetricKey]*QueryMetric{},
+	}
+}
+
+// RecordQuery adds a statement to the statistics of op and table.
+func (m *QueryMetrics) RecordQuery(op string, table string, dur time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := queryMetricKey{op: op, table: table}
+	metric, ok := m.metrics[key]
+	if !ok {
+		metric = &QueryMetric{
+			Op:      op,
+			Table:   table,
+			Buckets: make([]uint64, len(QueryMetricsBuckets)),
+		}
+		m.metrics[key] = metric
+	}
+
+	metric.Count++
+	if err != nil {
+		metric.Errors++
+	}
+	metric.Sum += dur
+	for i := range QueryMetricsBuckets {
+		if dur.Seconds() <= QueryMetricsBuckets[i] {
+			metric.Buckets[i]++
+		}
+	}
+}
+
+// Snapshot returns a copy of the current statistics, sorted by operation and
+// table.
+func (m *QueryMetrics) Snapshot() []QueryMetric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make([]QueryMetric, 0, len(m.metrics))
+	for _, metric := range m.metrics {
+		copied := *metric
+		copied.Buckets = append([]uint64(nil), metric.Buckets...)
+		snapshot = append(snapshot, copied)
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Op != snapshot[j].Op {
+			return snapshot[i].Op < snapshot[j].Op
+		}
+		return snapshot[i].Table < snapshot[j].Table
+	})
+
+	return snapshot
+}
+
+// WriteTo writes the current statistics to w in the Prometheus text format.
+func (m *QueryMetrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	snapshot := m.Snapshot()
+
+	b.WriteString("# HELP upper_queries_total Number of statements.\n")
+	b.WriteString("# TYPE upper_queries_total counter\n")
+	for _, metric := range snapshot {
+		fmt.Fprintf(&b, "upper_queries_total{%s} %d\n", metric.labels(), metric.Count)
+	}
+
+	b.WriteString("# HELP upper_query_errors_total Number of statements that failed.\n")
+	b.WriteString("# TYPE upper_query_errors_total counter\n")
+	for _, metric := range snapshot {
+		fmt.Fprintf(&b, "upper_query_errors_total{%s} %d\n", metric.labels(), metric.Errors)
+	}
+
+	b.WriteString("# HELP upper_query_duration_seconds Statement latency.\n")
+	b.WriteString("# TYPE upper_query_duration_seconds histogram\n")
+	for _, metric := range snapshot {
+		labels := metric.labels()
+		for i := range QueryMetricsBuckets {
+			fmt.Fprintf(&b, "upper_query_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, QueryMetricsBuckets[i], metric.Buckets[i])
+		}
+		fmt.Fprintf(&b, "upper_query_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, metric.Count)
+		fmt.Fprintf(&b, "upper_query_duration_seconds_sum{%s} %g\n", labels, metric.Sum.Seconds())
+		fmt.Fprintf(&b, "upper_query_duration_seconds_count{%s} %d\n", labels, metric.Count)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (metric *QueryMetric) labels() string {
+	return fmt.Sprintf(`op="%s",table="%s"`, labelValueReplacer.Replace(metric.Op), labelValueReplacer.Replace(metric.Table))
+}
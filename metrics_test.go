@@ -0,0 +1,54 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryMetrics(t *testing.T) {
+	m := NewQueryMetrics()
+
+	m.RecordQuery("select", "artist", 2*time.Millisecond, nil)
+	m.RecordQuery("select", "artist", 200*time.Millisecond, errors.New("failed"))
+	m.RecordQuery("insert", "artist", 20*time.Second, nil)
+
+	snapshot := m.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("expecting 2 metrics, got %d", len(snapshot))
+	}
+
+	insert, sel := snapshot[0], snapshot[1]
+	if insert.Op != "insert" || sel.Op != "select" {
+		t.Fatalf("unexpected order: %q, %q", insert.Op, sel.Op)
+	}
+	if sel.Count != 2 || sel.Errors != 1 || sel.Sum != 202*time.Millisecond {
+		t.Fatalf("unexpected select metric: %#v", sel)
+	}
+	// 0.001, 0.005, ..., 0.25
+	if sel.Buckets[0] != 0 || sel.Buckets[1] != 1 || sel.Buckets[6] != 2 {
+		t.Fatalf("unexpected select buckets: %v", sel.Buckets)
+	}
+	if insert.Buckets[len(insert.Buckets)-1] != 0 {
+		t.Fatalf("unexpected insert buckets: %v", insert.Buckets)
+	}
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, line := range []string{
+		`upper_queries_total{op="select",table="artist"} 2`,
+		`upper_query_errors_total{op="select",table="artist"} 1`,
+		`upper_query_duration_seconds_bucket{op="select",table="artist",le="0.005"} 1`,
+		`upper_query_duration_seconds_bucket{op="insert",table="artist",le="+Inf"} 1`,
+		`upper_query_duration_seconds_count{op="insert",table="artist"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+}
@@ -38,6 +38,9 @@ type Collection struct {
 	name       string
 	parent     *Source
 	collection *mgo.Collection
+
+	defaultOrderMu sync.RWMutex
+	defaultOrder   []interface{}
 }
 
 var (
@@ -61,9 +64,23 @@ func (col *Collection) Find(terms ...interface{}) db.Result {
 		return nil
 	})
 
+	col.defaultOrderMu.RLock()
+	defaultOrder := col.defaultOrder
+	col.defaultOrderMu.RUnlock()
+
+	if len(defaultOrder) > 0 {
+		return res.OrderBy(defaultOrder...)
+	}
 	return res
 }
 
+// SetDefaultOrderBy sets the order of the result sets created with Find.
+func (col *Collection) SetDefaultOrderBy(fields ...interface{}) {
+	col.defaultOrderMu.Lock()
+	col.defaultOrder = append([]interface{}(nil), fields...)
+	col.defaultOrderMu.Unlock()
+}
+
 var comparisonOperators = map[db.ComparisonOperator]string{
 	db.ComparisonOperatorEqual:    "$eq",
 	db.ComparisonOperatorNotEqual: "$ne",
@@ -264,6 +281,18 @@ func (col *Collection) UpdateReturning(item interface{}) error {
 	return db.ErrUnsupported
 }
 
+func (col *Collection) Update(item interface{}) error {
+	return db.ErrUnsupported
+}
+
+func (col *Collection) Delete(item interface{}) error {
+	return db.ErrUnsupported
+}
+
+func (col *Collection) FindOrCreate(cond db.Cond, item interface{}) (bool, error) {
+	return false, db.ErrUnsupported
+}
+
 // Insert inserts an item (map or struct) into the collection.
 func (col *Collection) Insert(item interface{}) (interface{}, error) {
 	var err error
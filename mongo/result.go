@@ -22,8 +22,10 @@
 package mongo
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -84,6 +86,20 @@ func (r *resultQuery) and(terms ...interface{}) error {
 	return nil
 }
 
+func (r *resultQuery) or(terms ...interface{}) error {
+	if r.conditions == nil {
+		return r.where(terms...)
+	}
+
+	r.conditions = map[string]interface{}{
+		"$or": []interface{}{
+			r.conditions,
+			r.c.compileQuery(terms...),
+		},
+	}
+	return nil
+}
+
 func (r *resultQuery) where(terms ...interface{}) error {
 	r.conditions = r.c.compileQuery(terms...)
 	return nil
@@ -95,6 +111,12 @@ func (res *result) And(terms ...interface{}) db.Result {
 	})
 }
 
+func (res *result) Or(terms ...interface{}) db.Result {
+	return res.frame(func(r *resultQuery) error {
+		return r.or(terms...)
+	})
+}
+
 func (res *result) Where(terms ...interface{}) db.Result {
 	return res.frame(func(r *resultQuery) error {
 		return r.where(terms...)
@@ -396,6 +418,179 @@ func (res *result) Update(src interface{}) (err error) {
 	return nil
 }
 
+// DeleteLimit removes at most n matching items from the collection.
+func (res *result) DeleteLimit(n int) (affected int64, err error) {
+	rq, err := res.build()
+	if err != nil {
+		return 0, err
+	}
+
+	if rq.c.parent.LoggingEnabled() {
+		defer func(start time.Time) {
+			rq.c.parent.Logger().Log(&db.QueryStatus{
+				Query: rq.debugQuery("RemoveLimit"),
+				Err:   err,
+				Start: start,
+				End:   time.Now(),
+			})
+		}(time.Now())
+	}
+
+	ids, err := rq.limitedIDs(n)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	info, err := rq.c.collection.RemoveAll(bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	return int64(info.Removed), nil
+}
+
+// UpdateLimit modifies at most n matching items from the collection.
+func (res *result) UpdateLimit(src interface{}, n int) (affected int64, err error) {
+	rq, err := res.build()
+	if err != nil {
+		return 0, err
+	}
+
+	if rq.c.parent.LoggingEnabled() {
+		defer func(start time.Time) {
+			rq.c.parent.Logger().Log(&db.QueryStatus{
+				Query: rq.debugQuery("UpdateLimit"),
+				Err:   err,
+				Start: start,
+				End:   time.Now(),
+			})
+		}(time.Now())
+	}
+
+	ids, err := rq.limitedIDs(n)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	info, err := rq.c.collection.UpdateAll(bson.M{"_id": bson.M{"$in": ids}}, map[string]interface{}{"$set": src})
+	if err != nil {
+		return 0, err
+	}
+	return int64(info.Updated), nil
+}
+
+// EachBatch walks over the matching items in batches of up to batchSize
+// items, ordered by _id.
+func (res *result) EachBatch(ctx context.Context, batchSize int, sliceOfStructs interface{}, fn func() error) error {
+	if batchSize < 1 {
+		return db.ErrInvalidBatchSize
+	}
+
+	rq, err := res.build()
+	if err != nil {
+		return err
+	}
+
+	var last interface{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		conditions := rq.conditions
+		if last != nil {
+			after := bson.M{"_id": bson.M{"$gt": last}}
+			if conditions == nil {
+				conditions = after
+			} else {
+				conditions = bson.M{"$and": []interface{}{conditions, after}}
+			}
+		}
+
+		var docs []bson.M
+		err := rq.c.collection.Find(conditions).Select(bson.M{"_id": 1}).Sort("_id").Limit(batchSize).All(&docs)
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		ids := make([]interface{}, len(docs))
+		for i := range docs {
+			ids[i] = docs[i]["_id"]
+		}
+		last = ids[len(ids)-1]
+
+		if err := rq.c.collection.Find(bson.M{"_id": bson.M{"$in": ids}}).Sort("_id").All(sliceOfStructs); err != nil {
+			return err
+		}
+
+		if err := fn(); err != nil {
+			return err
+		}
+
+		if len(docs) < batchSize {
+			return nil
+		}
+	}
+}
+
+// limitedIDs returns the ids of at most n matching items.
+func (r *resultQuery) limitedIDs(n int) ([]interface{}, error) {
+	var docs []bson.M
+	err := r.c.collection.Find(r.conditions).Select(bson.M{"_id": 1}).Limit(n).All(&docs)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc["_id"])
+	}
+	return ids, nil
+}
+
+func (res *result) Increment(column string, delta interface{}) (int64, error) {
+	return res.inc(column, delta, "Increment")
+}
+
+func (res *result) Decrement(column string, delta interface{}) (int64, error) {
+	v := reflect.ValueOf(delta)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		delta = -v.Int()
+	case reflect.Float32, reflect.Float64:
+		delta = -v.Float()
+	default:
+		return 0, fmt.Errorf("upper: cannot decrement by %T", delta)
+	}
+	return res.inc(column, delta, "Decrement")
+}
+
+func (res *result) inc(column string, delta interface{}, name string) (n int64, err error) {
+	rq, err := res.build()
+	if err != nil {
+		return 0, err
+	}
+
+	if rq.c.parent.LoggingEnabled() {
+		defer func(start time.Time) {
+			rq.c.parent.Logger().Log(&db.QueryStatus{
+				Query: rq.debugQuery(name),
+				Err:   err,
+				Start: start,
+				End:   time.Now(),
+			})
+		}(time.Now())
+	}
+
+	info, err := rq.c.collection.UpdateAll(rq.conditions, map[string]interface{}{"$inc": map[string]interface{}{column: delta}})
+	if err != nil {
+		return 0, err
+	}
+	return int64(info.Updated), nil
+}
+
 func (res *result) build() (*resultQuery, error) {
 	rqi, err := immutable.FastForward(res)
 	if err != nil {
@@ -22,8 +22,11 @@
 package mssql
 
 import (
+	"database/sql"
+
 	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
@@ -60,8 +63,23 @@ func (t *table) Database() sqladapter.Database {
 	return t.d
 }
 
+// selectScopeIdentity appends a query for the identity value generated by
+// the insert statement to it. SCOPE_IDENTITY() is used instead of @@IDENTITY,
+// which would return the values generated by triggers on the table.
+func selectScopeIdentity(query string) string {
+	return query + "; SELECT CAST(SCOPE_IDENTITY() AS BIGINT)"
+}
+
 // Insert inserts an item (map or struct) into the collection.
 func (t *table) Insert(item interface{}) (interface{}, error) {
+	id, err := t.insert(item)
+	if err == nil {
+		sqladapter.EmitInsert(t, id)
+	}
+	return id, err
+}
+
+func (t *table) insert(item interface{}) (interface{}, error) {
 	columnNames, columnValues, err := sqlbuilder.Map(item, nil)
 	if err != nil {
 		return nil, err
@@ -104,11 +122,15 @@ func (t *table) Insert(item interface{}) (interface{}, error) {
 		}
 
 		if *t.hasIdentityColumn {
-			_, err = t.d.Exec("SET IDENTITY_INSERT " + t.Name() + " ON")
+			tableName, err := exql.TableWithName(t.Name()).Compile(template)
 			if err != nil {
 				return nil, err
 			}
-			defer t.d.Exec("SET IDENTITY_INSERT " + t.Name() + " OFF")
+			_, err = t.d.Exec("SET IDENTITY_INSERT " + tableName + " ON")
+			if err != nil {
+				return nil, err
+			}
+			defer t.d.Exec("SET IDENTITY_INSERT " + tableName + " OFF")
 		}
 	}
 
@@ -117,11 +139,22 @@ func (t *table) Insert(item interface{}) (interface{}, error) {
 		Values(columnValues...)
 
 	if len(pKey) < 1 {
-		_, err = q.Exec()
+		// The table may still have an identity column, its new value is read
+		// in the same batch as the insert, as SCOPE_IDENTITY() is only set
+		// within it.
+		row, err := q.Amend(selectScopeIdentity).QueryRow()
 		if err != nil {
 			return nil, err
 		}
-		return nil, nil
+		var id sql.NullInt64
+		if err = row.Scan(&id); err != nil {
+			return nil, err
+		}
+		if !id.Valid {
+			// No identity column.
+			return nil, nil
+		}
+		return id.Int64, nil
 	}
 
 	q = q.Returning(pKey...)
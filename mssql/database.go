@@ -39,6 +39,30 @@ import (
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
+// Database represents a SQL Server session with features that are specific to
+// this adapter. Sessions created with Open satisfy this interface:
+//
+//	sess, err := mssql.Open(settings)
+//	...
+//	columns, err := sess.(mssql.Database).Columns("dbo.users")
+type Database interface {
+	sqlbuilder.Database
+
+	// Columns returns the name, type, length, precision, nullability and
+	// default value of all the columns of the given table, in order. The
+	// table name may be qualified with a schema, like "dbo.users", and
+	// db.ErrCollectionDoesNotExist is returned if it can't be found.
+	Columns(tableName string) ([]sqlbuilder.ColumnDescriptor, error)
+
+	// ForeignKeys returns the foreign key constraints of the given table,
+	// ordered by name.
+	ForeignKeys(tableName string) ([]sqlbuilder.ForeignKeyDescriptor, error)
+
+	// Indexes returns the indexes of the given table, including the one that
+	// backs the primary key, ordered by name.
+	Indexes(tableName string) ([]sqlbuilder.IndexDescriptor, error)
+}
+
 // database is the actual implementation of Database
 type database struct {
 	sqladapter.BaseDatabase
@@ -50,7 +74,7 @@ type database struct {
 }
 
 var (
-	_ = sqlbuilder.Database(&database{})
+	_ = Database(&database{})
 	_ = sqlbuilder.Database(&database{})
 )
 
@@ -117,7 +141,9 @@ func (d *database) open() error {
 	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
 
 	connFn := func() error {
-		sess, err := sql.Open("mssql", d.ConnectionURL().String())
+		// The "sqlserver" driver binds @p1, ..., @pN and @name parameters, the
+		// "mssql" one only binds ?, $N and :N.
+		sess, err := sql.Open("sqlserver", d.ConnectionURL().String())
 		if err == nil {
 			sess.SetConnMaxLifetime(db.DefaultSettings.ConnMaxLifetime())
 			sess.SetMaxIdleConns(db.DefaultSettings.MaxIdleConns())
@@ -154,15 +180,17 @@ func (d *database) clone(ctx context.Context, checkConn bool) (*database, error)
 // CompileStatement compiles a *exql.Statement into arguments that sql/database
 // accepts.
 func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
-	compiled, err := stmt.Compile(template)
+	compiled, err := stmt.Compile(sqladapter.SessionTemplate(d.BaseDatabase, template))
 	if err != nil {
 		panic(err.Error())
 	}
-	return sqlbuilder.Preprocess(compiled, args)
+	query, args := sqlbuilder.PreprocessWithTemplate(template, compiled, args)
+	return template.BindArguments(query, args)
 }
 
-// Err allows sqladapter to translate specific MySQL string errors into custom
-// error values.
+// Err allows sqladapter to translate specific MSSQL errors into custom error
+// values. Constraint violations and deadlocks are wrapped in a *db.Error that
+// matches the portable error, like db.ErrUniqueViolation.
 func (d *database) Err(err error) error {
 	if err != nil {
 		// This error is not exported so we have to check it by its string value.
@@ -170,10 +198,72 @@ func (d *database) Err(err error) error {
 		if strings.Contains(s, `many connections`) {
 			return db.ErrTooManyClients
 		}
+		if e, ok := err.(hasSQLErrorNumber); ok {
+			if kind := errorKind(e.SQLErrorNumber(), s); kind != nil {
+				return &db.Error{Kind: kind, Err: err}
+			}
+		}
 	}
 	return err
 }
 
+// LockHolders returns the sessions other than this one that hold locks on
+// the given table, it's used by lock diagnostics.
+func (d *database) LockHolders(ctx context.Context, table string) ([]db.LockHolder, error) {
+	rows, err := d.Session().QueryContext(ctx, `
+		SELECT DISTINCT l.request_session_id, COALESCE(t.text, '')
+		FROM sys.dm_tran_locks l
+		LEFT JOIN sys.dm_exec_connections c ON c.session_id = l.request_session_id
+		OUTER APPLY sys.dm_exec_sql_text(c.most_recent_sql_handle) t
+		WHERE l.resource_type = 'OBJECT'
+			AND l.resource_database_id = DB_ID()
+			AND l.resource_associated_entity_id = OBJECT_ID(@p1)
+			AND l.request_status = 'GRANT'
+			AND l.request_session_id <> @@SPID
+		ORDER BY l.request_session_id`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holders []db.LockHolder
+	for rows.Next() {
+		var h db.LockHolder
+		if err := rows.Scan(&h.PID, &h.Query); err != nil {
+			return nil, err
+		}
+		holders = append(holders, h)
+	}
+	return holders, rows.Err()
+}
+
+// hasSQLErrorNumber is satisfied by the errors of the MSSQL driver.
+type hasSQLErrorNumber interface {
+	SQLErrorNumber() int32
+}
+
+// errorKind returns the portable error that matches the given MSSQL error
+// number, if any.
+func errorKind(number int32, message string) error {
+	switch number {
+	case 2601, 2627:
+		return db.ErrUniqueViolation
+	case 547:
+		// 547 is reported for both foreign key and check constraints.
+		if strings.Contains(message, "CHECK constraint") {
+			return db.ErrCheckViolation
+		}
+		return db.ErrForeignKeyViolation
+	case 515:
+		return db.ErrNotNullViolation
+	case 1205:
+		return db.ErrStaleObject
+	case 1222:
+		return db.ErrLockNotAcquired
+	}
+	return nil
+}
+
 // NewCollection creates a db.Collection by name.
 func (d *database) NewCollection(name string) db.Collection {
 	return newTable(d, name)
@@ -187,6 +277,11 @@ func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) erro
 	return sqladapter.RunTx(d, ctx, fn)
 }
 
+// TxWithStats works like Tx and fills stats in with the details of the run.
+func (d *database) TxWithStats(ctx context.Context, fn func(tx sqlbuilder.Tx) error, stats *sqlbuilder.TxStats) error {
+	return sqladapter.RunTxWithStats(d, ctx, fn, stats)
+}
+
 // NewDatabaseTx begins a transaction block.
 func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
 	clone, err := d.clone(ctx, true)
@@ -278,8 +373,145 @@ func (d *database) PrimaryKeys(tableName string) ([]string, error) {
 	return pk, nil
 }
 
+// Columns returns a description of all the columns of the given table, in
+// order. The table name may be qualified with a schema, otherwise the default
+// schema of the current user is used.
+func (d *database) Columns(tableName string) ([]sqlbuilder.ColumnDescriptor, error) {
+	schema, table := sqladapter.SplitTableName(tableName)
+
+	q := d.Select(sqladapter.InformationSchemaColumns...).
+		From(`information_schema.columns`).
+		Where(`table_name = ?`, table)
+
+	if schema != "" {
+		q = q.And(`table_schema = ?`, schema)
+	} else {
+		q = q.And(db.Raw(`table_schema = SCHEMA_NAME()`))
+	}
+
+	var rows []sqladapter.InformationSchemaColumn
+	if err := q.OrderBy(`ordinal_position`).All(&rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, db.ErrCollectionDoesNotExist
+	}
+
+	columns := make([]sqlbuilder.ColumnDescriptor, 0, len(rows))
+	for i := range rows {
+		columns = append(columns, rows[i].Descriptor())
+	}
+	return columns, nil
+}
+
+// ForeignKeys returns the foreign key constraints of the given table.
+func (d *database) ForeignKeys(tableName string) ([]sqlbuilder.ForeignKeyDescriptor, error) {
+	iter := d.Iterator(`
+		SELECT
+			fk.name AS name,
+			pc.name AS column_name,
+			SCHEMA_NAME(rt.schema_id) + '.' + rt.name AS referenced_table,
+			rc.name AS referenced_column,
+			fk.update_referential_action_desc AS on_update,
+			fk.delete_referential_action_desc AS on_delete
+		FROM sys.foreign_keys AS fk
+		JOIN sys.foreign_key_columns AS fkc ON fkc.constraint_object_id = fk.object_id
+		JOIN sys.columns AS pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+		JOIN sys.tables AS rt ON rt.object_id = fk.referenced_object_id
+		JOIN sys.columns AS rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+		WHERE fk.parent_object_id = OBJECT_ID(?)
+		ORDER BY fk.name, fkc.constraint_column_id
+	`, tableName)
+
+	var rows []struct {
+		Name             string `db:"name"`
+		Column           string `db:"column_name"`
+		ReferencedTable  string `db:"referenced_table"`
+		ReferencedColumn string `db:"referenced_column"`
+		OnUpdate         string `db:"on_update"`
+		OnDelete         string `db:"on_delete"`
+	}
+	if err := iter.All(&rows); err != nil {
+		return nil, err
+	}
+
+	// One row per column, grouped by constraint.
+	fks := []sqlbuilder.ForeignKeyDescriptor{}
+	for _, row := range rows {
+		if n := len(fks); n == 0 || fks[n-1].Name != row.Name {
+			fks = append(fks, sqlbuilder.ForeignKeyDescriptor{
+				Name:            row.Name,
+				ReferencedTable: row.ReferencedTable,
+				OnUpdate:        strings.Replace(row.OnUpdate, "_", " ", -1),
+				OnDelete:        strings.Replace(row.OnDelete, "_", " ", -1),
+			})
+		}
+		fk := &fks[len(fks)-1]
+		fk.Columns = append(fk.Columns, row.Column)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, row.ReferencedColumn)
+	}
+	return fks, nil
+}
+
+// Indexes returns the indexes of the given table.
+func (d *database) Indexes(tableName string) ([]sqlbuilder.IndexDescriptor, error) {
+	iter := d.Iterator(`
+		SELECT
+			i.name AS name,
+			c.name AS column_name,
+			i.is_unique AS is_unique,
+			i.is_primary_key AS is_primary,
+			i.type_desc AS method
+		FROM sys.indexes AS i
+		JOIN sys.index_columns AS ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN sys.columns AS c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE i.object_id = OBJECT_ID(?) AND ic.is_included_column = 0
+		ORDER BY i.name, ic.key_ordinal
+	`, tableName)
+
+	var rows []struct {
+		Name      string `db:"name"`
+		Column    string `db:"column_name"`
+		IsUnique  bool   `db:"is_unique"`
+		IsPrimary bool   `db:"is_primary"`
+		Method    string `db:"method"`
+	}
+	if err := iter.All(&rows); err != nil {
+		return nil, err
+	}
+
+	// One row per column, grouped by index.
+	indexes := []sqlbuilder.IndexDescriptor{}
+	for _, row := range rows {
+		if n := len(indexes); n == 0 || indexes[n-1].Name != row.Name {
+			indexes = append(indexes, sqlbuilder.IndexDescriptor{
+				Name:    row.Name,
+				Unique:  row.IsUnique,
+				Primary: row.IsPrimary,
+				Method:  row.Method,
+			})
+		}
+		index := &indexes[len(indexes)-1]
+		index.Columns = append(index.Columns, row.Column)
+	}
+	return indexes, nil
+}
+
 // WithContext creates a copy of the session on the given context.
 func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
 	newDB, _ := d.clone(ctx, false)
 	return newDB
 }
+
+// BlobChunk returns an expression that takes a part of a VARBINARY(MAX)
+// column.
+func (d *database) BlobChunk(column string) string {
+	c, _ := exql.ColumnWithName(column).Compile(template)
+	return `SUBSTRING(` + c + `, ?, ?)`
+}
+
+// BlobAppend returns a .WRITE clause that appends to a VARBINARY(MAX) column.
+func (d *database) BlobAppend(column string) string {
+	c, _ := exql.ColumnWithName(column).Compile(template)
+	return c + `.WRITE(?, NULL, 0)`
+}
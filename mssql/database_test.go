@@ -0,0 +1,97 @@
+package mssql
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErr(t *testing.T) {
+	d := &database{}
+
+	tests := []struct {
+		err  mssql.Error
+		kind error
+	}{
+		{mssql.Error{Number: 2627, Message: "Violation of PRIMARY KEY constraint 'PK_artist'."}, db.ErrUniqueViolation},
+		{mssql.Error{Number: 2601, Message: "Cannot insert duplicate key row in object 'dbo.artist' with unique index 'ix_name'."}, db.ErrUniqueViolation},
+		{mssql.Error{Number: 547, Message: "The INSERT statement conflicted with the FOREIGN KEY constraint \"fk_artist\"."}, db.ErrForeignKeyViolation},
+		{mssql.Error{Number: 547, Message: "The INSERT statement conflicted with the CHECK constraint \"ck_age\"."}, db.ErrCheckViolation},
+		{mssql.Error{Number: 515, Message: "Cannot insert the value NULL into column 'name'."}, db.ErrNotNullViolation},
+		{mssql.Error{Number: 1205, Message: "Transaction (Process ID 52) was deadlocked on lock resources with another process."}, db.ErrStaleObject},
+	}
+
+	for _, test := range tests {
+		err := d.Err(test.err)
+		dbErr, ok := err.(*db.Error)
+		if assert.True(t, ok) {
+			assert.Equal(t, test.kind, dbErr.Kind)
+			assert.True(t, dbErr.Is(test.kind))
+			assert.Equal(t, test.err, dbErr.Unwrap())
+			assert.Equal(t, test.err.Error(), err.Error())
+		}
+	}
+
+	other := mssql.Error{Number: 208, Message: "Invalid object name 'foo'."}
+	assert.Equal(t, other, d.Err(other))
+
+	plain := errors.New("something else")
+	assert.Equal(t, plain, d.Err(plain))
+}
+
+func TestMoney(t *testing.T) {
+	var m Money
+	assert.NoError(t, m.Scan([]byte("-214748.3648")))
+	assert.Equal(t, Money(-21474836), m)
+	assert.NoError(t, m.Scan(nil))
+	assert.Equal(t, Money(0), m)
+
+	v, err := Money(-123456).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "-1234.56", v)
+}
+
+func TestDeferConstraints(t *testing.T) {
+	var sqlTx Tx = &tx{}
+	assert.Equal(t, ErrDeferConstraintsUnsupported, sqlTx.DeferConstraints())
+}
+
+func TestCompileStatementPlaceholders(t *testing.T) {
+	d := &database{}
+
+	stmt := &exql.Statement{
+		Type:  exql.Select,
+		Table: exql.TableWithName("artist"),
+		Where: exql.WhereConditions(
+			&exql.ColumnValue{Column: exql.ColumnWithName("id"), Operator: ">", Value: exql.RawValue("?")},
+			&exql.ColumnValue{Column: exql.ColumnWithName("name"), Operator: "=", Value: exql.RawValue("?")},
+			&exql.ColumnValue{Column: exql.ColumnWithName("genre"), Operator: "IN", Value: exql.RawValue("?")},
+		),
+	}
+
+	query, args := d.CompileStatement(stmt, []interface{}{10, "Ozzie", []string{"rock", "metal"}})
+	assert.Equal(t, "SELECT * FROM [artist] WHERE ([id] > @p1 AND [name] = @p2 AND [genre] IN (@p3, @p4))", strings.Join(strings.Fields(query), " "))
+	assert.Equal(t, []interface{}{10, "Ozzie", "rock", "metal"}, args)
+}
+
+func TestCompileStatementNamedArgs(t *testing.T) {
+	d := &database{}
+
+	stmt := exql.RawSQL(`EXEC add_artist @name = ?, @genre = ?, @alias = ?, @id = @id OUTPUT`)
+
+	var id int64
+	query, args := d.CompileStatement(stmt, []interface{}{
+		sql.Named("name", "Ozzie"),
+		"rock",
+		sql.Named("name", "Ozzie"),
+		sql.Named("id", sql.Out{Dest: &id}),
+	})
+	assert.Equal(t, "EXEC add_artist @name = @name, @genre = @p2, @alias = @name, @id = @id OUTPUT", query)
+	assert.Equal(t, []interface{}{sql.Named("name", "Ozzie"), "rock", sql.Named("id", sql.Out{Dest: &id})}, args)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// showplanSetting returns the session option that makes SQL Server return
+// query plans. SHOWPLAN_TEXT describes the query without running it, while
+// STATISTICS PROFILE runs it and adds actual row counts.
+func showplanSetting(opts sqlbuilder.ExplainOptions) string {
+	if opts.Analyze {
+		return "STATISTICS PROFILE"
+	}
+	return "SHOWPLAN_TEXT"
+}
+
+// ExplainStatement returns the plan of the given query. SQL Server has no
+// JSON plans, so asking for one returns db.ErrUnsupported.
+func (d *database) ExplainStatement(ctx context.Context, tx *sql.Tx, query string, args []interface{}, opts sqlbuilder.ExplainOptions) (string, error) {
+	if opts.JSON {
+		return "", db.ErrUnsupported
+	}
+
+	setting := showplanSetting(opts)
+	if _, err := tx.ExecContext(ctx, "SET "+setting+" ON"); err != nil {
+		return "", err
+	}
+	// Session options outlive the transaction, so the connection must be
+	// restored before it goes back to the pool.
+	defer tx.ExecContext(context.Background(), "SET "+setting+" OFF")
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	// Plans come in result sets of their own, with one step per row in the
+	// StmtText column, any other result set is discarded.
+	lines := []string{}
+	for {
+		columns, err := rows.Columns()
+		if err != nil {
+			return "", err
+		}
+
+		stmtText := -1
+		for i := range columns {
+			if columns[i] == "StmtText" {
+				stmtText = i
+			}
+		}
+
+		for rows.Next() {
+			if stmtText < 0 {
+				continue
+			}
+			values := make([]interface{}, len(columns))
+			for i := range values {
+				values[i] = new(interface{})
+			}
+			if err := rows.Scan(values...); err != nil {
+				return "", err
+			}
+			if line, ok := (*values[stmtText].(*interface{})).(string); ok {
+				lines = append(lines, line)
+			}
+		}
+
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -22,6 +22,9 @@
 package mssql
 
 import (
+	"strconv"
+
+	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/cache"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 )
@@ -36,7 +39,10 @@ const (
 	adapterOrKeyword           = `OR`
 	adapterDescKeyword         = `DESC`
 	adapterAscKeyword          = `ASC`
+	adapterCollateLayout       = `COLLATE {{.}}`
+	adapterInsertedLayout      = `CAST(CASE $action WHEN 'INSERT' THEN 1 ELSE 0 END AS BIT) AS [inserted]`
 	adapterAssignmentOperator  = `=`
+	adapterConcatOperator      = `+`
 	adapterClauseGroup         = `({{.}})`
 	adapterClauseOperator      = ` {{.}} `
 	adapterColumnValue         = `{{.Column}} {{.Operator}} {{.Value}}`
@@ -80,6 +86,12 @@ const (
     {{end}}
   `
 
+	adapterLockLayout = `WITH (UPDLOCK, ROWLOCK{{if .SkipLocked}}, READPAST{{end}})`
+
+	adapterSampleLayout = `TABLESAMPLE ({{.Percent}} PERCENT)`
+
+	adapterRandomFunction = `NEWID()`
+
 	adapterSelectLayout = `
     {{if or .Limit .Offset}}
       SELECT __q0.* FROM (
@@ -110,7 +122,7 @@ const (
         {{end}}
 
         {{if defined .Table}}
-          FROM {{.Table | compile}}
+          FROM {{.Table | compile}} {{.Sample | compile}} {{.Lock | compile}}
         {{end}}
 
         {{.Joins | compile}}
@@ -129,16 +141,33 @@ const (
   `
 	adapterDeleteLayout = `
     DELETE
-      FROM {{.Table | compile}}
+      {{if .Limit}}
+        TOP ({{.Limit}})
+      {{end}}
+      {{if .From}}
+        {{.Table | compile}} {{.From | compile}}
+      {{else}}
+        FROM {{.Table | compile}}
+      {{end}}
       {{.Where | compile}}
   `
 	adapterUpdateLayout = `
     UPDATE
+      {{if .Limit}}
+        TOP ({{.Limit}})
+      {{end}}
       {{.Table | compile}}
     SET {{.ColumnValues | compile}}
+      {{.From | compile}}
       {{.Where | compile}}
   `
 
+	adapterUpdateFromLayout = `FROM {{.Table | compile}}, {{.Sources | compile}} {{.Joins | compile}}`
+
+	adapterValuesTableLayout = `(VALUES {{.Rows | compile}}) AS {{.Alias | compile}} ({{.Columns | compile}})`
+
+	adapterDeleteUsingLayout = adapterUpdateFromLayout
+
 	adapterSelectCountLayout = `
     SELECT
       COUNT(1) AS _t
@@ -147,6 +176,23 @@ const (
   `
 
 	adapterInsertLayout = `
+    {{if defined .OnConflict}}
+      MERGE INTO {{.Table | compile}} WITH (HOLDLOCK) AS [target]
+      USING (VALUES {{.Values | compile}}) AS [source] ({{.Columns | compile}})
+      {{.OnConflict | compile}}
+      {{if .Returning }}
+        OUTPUT
+        {{range $key, $value := .Returning.Columns.Columns}}
+          {{- if $key}},{{end}}
+          [inserted].{{ $value | compile }}
+        {{end}}
+        {{if .Returning.Inserted}}
+          {{- if .Returning.Columns.Columns}},{{end}}
+          ` + adapterInsertedLayout + `
+        {{end}}
+      {{end}}
+      ;
+    {{else}}
     INSERT INTO {{.Table | compile}}
       {{if .Columns }}({{.Columns | compile}}){{end}}
       {{if .Returning }}
@@ -162,6 +208,18 @@ const (
     {{else}}
       (DEFAULT)
     {{end}}
+    {{end}}
+  `
+
+	adapterOnConflictLayout = `
+    ON ({{range $i, $c := .Target}}{{if $i}} AND {{end}}[target].{{$c}} = [source].{{$c}}{{end}})
+    {{if .Update}}
+      WHEN MATCHED THEN
+        UPDATE SET {{range $i, $c := .Update}}{{if $i}}, {{end}}{{$c}} = [source].{{$c}}{{end}}
+    {{end}}
+    WHEN NOT MATCHED THEN
+      INSERT ({{range $i, $c := .Insert}}{{if $i}}, {{end}}{{$c}}{{end}})
+      VALUES ({{range $i, $c := .Insert}}{{if $i}}, {{end}}[source].{{$c}}{{end}})
   `
 
 	adapterTruncateLayout = `
@@ -173,7 +231,22 @@ const (
   `
 
 	adapterDropTableLayout = `
-    DROP TABLE {{.Table | compile}}
+    DROP TABLE {{if .IfExists}}IF EXISTS {{end}}{{.Table | compile}}
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table | compile}} {{.Alterations | compile}}
+  `
+
+	adapterAddColumnLayout = `ADD {{.Column}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.Column}}`
+
+	adapterCreateTableLayout = `
+    {{if .IfNotExists}}
+      IF OBJECT_ID('{{.Table | compile}}', 'U') IS NULL
+    {{end}}
+    CREATE TABLE {{.Table | compile}} ({{.Definitions | compile}})
   `
 
 	adapterGroupByLayout = `
@@ -183,6 +256,18 @@ const (
   `
 )
 
+// placeholder renders ordinal parameters as @p1, @p2, ..., @pN, which the
+// driver binds to positional arguments and sp_executesql accepts.
+func placeholder(i int) string {
+	return "@p" + strconv.Itoa(i)
+}
+
+// namedPlaceholder renders a sql.NamedArg as @name, it's bound by the driver
+// by name, which is also how procedures get output parameters.
+func namedPlaceholder(name string) string {
+	return "@" + name
+}
+
 var template = &exql.Template{
 	ColumnSeparator:     adapterColumnSeparator,
 	IdentifierSeparator: adapterIdentifierSeparator,
@@ -193,7 +278,9 @@ var template = &exql.Template{
 	OrKeyword:           adapterOrKeyword,
 	DescKeyword:         adapterDescKeyword,
 	AscKeyword:          adapterAscKeyword,
+	CollateLayout:       adapterCollateLayout,
 	AssignmentOperator:  adapterAssignmentOperator,
+	ConcatOperator:      adapterConcatOperator,
 	ClauseGroup:         adapterClauseGroup,
 	ClauseOperator:      adapterClauseOperator,
 	ColumnValue:         adapterColumnValue,
@@ -202,17 +289,47 @@ var template = &exql.Template{
 	SortByColumnLayout:  adapterSortByColumnLayout,
 	WhereLayout:         adapterWhereLayout,
 	JoinLayout:          adapterJoinLayout,
+	LockLayout:          adapterLockLayout,
+	SampleLayout:        adapterSampleLayout,
+	RandomFunction:      adapterRandomFunction,
 	OnLayout:            adapterOnLayout,
 	UsingLayout:         adapterUsingLayout,
 	OrderByLayout:       adapterOrderByLayout,
 	InsertLayout:        adapterInsertLayout,
+	InsertedLayout:      adapterInsertedLayout,
+	OnConflictLayout:    adapterOnConflictLayout,
 	SelectLayout:        adapterSelectLayout,
 	UpdateLayout:        adapterUpdateLayout,
+	UpdateFromLayout:    adapterUpdateFromLayout,
+	DeleteUsingLayout:   adapterDeleteUsingLayout,
+	ValuesTableLayout:   adapterValuesTableLayout,
 	DeleteLayout:        adapterDeleteLayout,
 	TruncateLayout:      adapterTruncateLayout,
 	DropDatabaseLayout:  adapterDropDatabaseLayout,
 	DropTableLayout:     adapterDropTableLayout,
+	CreateTableLayout:   adapterCreateTableLayout,
+	AlterTableLayout:    adapterAlterTableLayout,
+	AddColumnLayout:     adapterAddColumnLayout,
+	DropColumnLayout:    adapterDropColumnLayout,
 	CountLayout:         adapterSelectCountLayout,
 	GroupByLayout:       adapterGroupByLayout,
+	Placeholder:         placeholder,
+	NamedPlaceholder:    namedPlaceholder,
 	Cache:               cache.NewCache(),
+	ComparisonOperator: map[db.ComparisonOperator]string{
+		db.ComparisonOperatorILike:    "LOWER(:column) LIKE LOWER(?)",
+		db.ComparisonOperatorNotILike: "LOWER(:column) NOT LIKE LOWER(?)",
+	},
+	ColumnTypes: map[string]string{
+		"serial":           `INT IDENTITY(1,1)`,
+		"bigserial":        `BIGINT IDENTITY(1,1)`,
+		"boolean":          `BIT`,
+		"double precision": `FLOAT`,
+		"text":             `NVARCHAR(MAX)`,
+		"blob":             `VARBINARY(MAX)`,
+		"timestamp":        `DATETIME2`,
+
+		"timestamp with time zone": `DATETIMEOFFSET`,
+	},
+	ReturningColumnsOnly: true,
 }
@@ -2,6 +2,7 @@ package mssql
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	db "github.com/frazercomputing/upper-io-db"
@@ -42,6 +43,16 @@ func TestTemplateSelect(t *testing.T) {
 		b.Select().From("artist").OrderBy("name ASC").String(),
 	)
 
+	assert.Equal(
+		"SELECT * FROM [artist] ORDER BY CASE WHEN [rating] IS NULL THEN 1 ELSE 0 END, [rating] DESC",
+		b.Select().From("artist").OrderBy(db.Desc("rating").NullsLast()).String(),
+	)
+
+	assert.Equal(
+		"SELECT * FROM [artist] ORDER BY CASE WHEN [name] IS NULL THEN 1 ELSE 0 END, [name] COLLATE Latin1_General_CI_AS DESC",
+		b.Select().From("artist").OrderBy(db.Desc("name").Collate("Latin1_General_CI_AS").NullsLast()).String(),
+	)
+
 	assert.Equal(
 		"SELECT __q0.* FROM ( SELECT TOP 100 PERCENT __q1.*, ROW_NUMBER() OVER (ORDER BY (SELECT 1)) AS rnum FROM ( SELECT TOP 100 PERCENT * FROM [artist] ) __q1) __q0 WHERE rnum > 5",
 		b.Select().From("artist").Limit(-1).Offset(5).String(),
@@ -58,32 +69,32 @@ func TestTemplateSelect(t *testing.T) {
 	)
 
 	assert.Equal(
-		"SELECT * FROM [artist] WHERE ([name] = $1)",
+		"SELECT * FROM [artist] WHERE ([name] = @p1)",
 		b.SelectFrom("artist").Where("name", "Haruki").String(),
 	)
 
 	assert.Equal(
-		"SELECT * FROM [artist] WHERE (name LIKE $1)",
+		"SELECT * FROM [artist] WHERE (name LIKE @p1)",
 		b.SelectFrom("artist").Where("name LIKE ?", `%F%`).String(),
 	)
 
 	assert.Equal(
-		"SELECT [id] FROM [artist] WHERE (name LIKE $1 OR name LIKE $2)",
+		"SELECT [id] FROM [artist] WHERE (name LIKE @p1 OR name LIKE @p2)",
 		b.Select("id").From("artist").Where(`name LIKE ? OR name LIKE ?`, `%Miya%`, `F%`).String(),
 	)
 
 	assert.Equal(
-		"SELECT * FROM [artist] WHERE ([id] > $1)",
+		"SELECT * FROM [artist] WHERE ([id] > @p1)",
 		b.SelectFrom("artist").Where("id >", 2).String(),
 	)
 
 	assert.Equal(
-		"SELECT * FROM [artist] WHERE (id <= 2 AND name != $1)",
+		"SELECT * FROM [artist] WHERE (id <= 2 AND name != @p1)",
 		b.SelectFrom("artist").Where("id <= 2 AND name != ?", "A").String(),
 	)
 
 	assert.Equal(
-		"SELECT * FROM [artist] WHERE ([id] IN ($1, $2, $3, $4))",
+		"SELECT * FROM [artist] WHERE ([id] IN (@p1, @p2, @p3, @p4))",
 		b.SelectFrom("artist").Where("id IN", []int{1, 9, 8, 7}).String(),
 	)
 
@@ -108,7 +119,7 @@ func TestTemplateSelect(t *testing.T) {
 	)
 
 	assert.Equal(
-		"SELECT __q0.* FROM ( SELECT TOP 100 PERCENT __q1.*, ROW_NUMBER() OVER (ORDER BY (SELECT 1)) AS rnum FROM ( SELECT TOP (1 + 0) * FROM [artist] AS [a] JOIN [publication] AS [p] ON (p.author_id = a.id) WHERE ([a].[id] = $1) ) __q1) __q0 WHERE rnum > 0",
+		"SELECT __q0.* FROM ( SELECT TOP 100 PERCENT __q1.*, ROW_NUMBER() OVER (ORDER BY (SELECT 1)) AS rnum FROM ( SELECT TOP (1 + 0) * FROM [artist] AS [a] JOIN [publication] AS [p] ON (p.author_id = a.id) WHERE ([a].[id] = @p1) ) __q1) __q0 WHERE rnum > 0",
 		b.SelectFrom("artist a").Join("publication p").On("p.author_id = a.id").Where("a.id", 2).Limit(1).String(),
 	)
 
@@ -118,7 +129,7 @@ func TestTemplateSelect(t *testing.T) {
 	)
 
 	assert.Equal(
-		"SELECT __q0.* FROM ( SELECT TOP 100 PERCENT __q1.*, ROW_NUMBER() OVER (ORDER BY (SELECT 1)) AS rnum FROM ( SELECT TOP (1 + 0) * FROM [artist] AS [a] JOIN [publication] AS [p] ON (p.title LIKE $1 OR p.title LIKE $2) WHERE (a.id = $3) ) __q1) __q0 WHERE rnum > 0",
+		"SELECT __q0.* FROM ( SELECT TOP 100 PERCENT __q1.*, ROW_NUMBER() OVER (ORDER BY (SELECT 1)) AS rnum FROM ( SELECT TOP (1 + 0) * FROM [artist] AS [a] JOIN [publication] AS [p] ON (p.title LIKE @p1 OR p.title LIKE @p2) WHERE (a.id = @p3) ) __q1) __q0 WHERE rnum > 0",
 		b.SelectFrom("artist a").Join("publication p").On("p.title LIKE ? OR p.title LIKE ?", "%Totoro%", "%Robot%").Where("a.id = ?", 2).Limit(1).String(),
 	)
 
@@ -144,6 +155,48 @@ func TestTemplateSelect(t *testing.T) {
 		"SELECT DATE()",
 		b.Select(db.Raw("DATE()")).String(),
 	)
+
+	{
+		sel := b.SelectFrom("artist").Where(db.Cond{"name": db.ILike("%foo%")})
+		assert.Equal(
+			"SELECT * FROM [artist] WHERE (LOWER([name]) LIKE LOWER(@p1))",
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{"%foo%"},
+			sel.Arguments(),
+		)
+	}
+
+	assert.Equal(
+		"SELECT * FROM [jobs] WITH (UPDLOCK, ROWLOCK) WHERE ([id] = @p1)",
+		b.SelectFrom("jobs").Where("id", 1).ForUpdate().String(),
+	)
+
+	assert.Equal(
+		"SELECT [id] FROM [jobs] WITH (UPDLOCK, ROWLOCK, READPAST) WHERE ([status] = @p1)",
+		b.Select("id").From("jobs").Where("status", "pending").SkipLocked().String(),
+	)
+
+	assert.Equal(
+		"SELECT * FROM [events] TABLESAMPLE (10 PERCENT) WITH (UPDLOCK, ROWLOCK) WHERE ([kind] = @p1)",
+		b.SelectFrom("events").Sample(10).Where("kind", "click").ForUpdate().String(),
+	)
+
+	assert.Equal(
+		"SELECT __q0.* FROM ( SELECT TOP 100 PERCENT __q1.*, ROW_NUMBER() OVER (ORDER BY (SELECT 1)) AS rnum FROM ( SELECT TOP (5 + 0) * FROM [artist] ORDER BY NEWID() ) __q1) __q0 WHERE rnum > 0",
+		b.SelectFrom("artist").Random().Limit(5).String(),
+	)
+
+	{
+		scores := sqlbuilder.Values([][]interface{}{{1, "a", 0.5}, {2, "b", 0.8}}, []string{"id", "tag", "score"}).As("s")
+		q := b.Select("a.name", "s.score").From("artist AS a").Join(scores).On("s.id = a.id")
+		assert.Equal(
+			`SELECT [a].[name], [s].[score] FROM [artist] AS [a] JOIN (VALUES (CAST(@p1 AS bigint), CAST(@p2 AS NVARCHAR(MAX)), CAST(@p3 AS FLOAT)), (@p4, @p5, @p6)) AS [s] ([id], [tag], [score]) ON (s.id = a.id)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{1, "a", 0.5, 2, "b", 0.8}, q.Arguments())
+	}
 }
 
 func TestTemplateInsert(t *testing.T) {
@@ -151,7 +204,7 @@ func TestTemplateInsert(t *testing.T) {
 	assert := assert.New(t)
 
 	assert.Equal(
-		"INSERT INTO [artist] VALUES ($1, $2), ($3, $4), ($5, $6)",
+		"INSERT INTO [artist] VALUES (@p1, @p2), (@p3, @p4), (@p5, @p6)",
 		b.InsertInto("artist").
 			Values(10, "Ryuichi Sakamoto").
 			Values(11, "Alondra de la Parra").
@@ -160,22 +213,34 @@ func TestTemplateInsert(t *testing.T) {
 	)
 
 	assert.Equal(
-		"INSERT INTO [artist] ([id], [name]) VALUES ($1, $2)",
+		"INSERT INTO [artist] ([id], [name]) VALUES (@p1, @p2)",
 		b.InsertInto("artist").Values(map[string]string{"id": "12", "name": "Chavela Vargas"}).String(),
 	)
 
 	assert.Equal(
-		"INSERT INTO [artist] ([id], [name]) OUTPUT [inserted].[id] VALUES ($1, $2)",
+		"INSERT INTO [artist] ([id], [name]) OUTPUT [inserted].[id] VALUES (@p1, @p2)",
 		b.InsertInto("artist").Values(map[string]string{"id": "12", "name": "Chavela Vargas"}).Returning("id").String(),
 	)
 
 	assert.Equal(
-		"INSERT INTO [artist] ([id], [name]) VALUES ($1, $2)",
+		"INSERT INTO [artist] ([name]) OUTPUT [inserted].[id] , [inserted].[created_at] AS [added] VALUES (@p1)",
+		b.InsertInto("artist").Values(map[string]string{"name": "Chavela Vargas"}).ReturningExpr("id", db.Col("created_at AS added")).String(),
+	)
+
+	func() {
+		defer func() {
+			assert.Equal(sqlbuilder.ErrReturningColumnsOnly.Error(), recover())
+		}()
+		_ = b.InsertInto("artist").Values(map[string]string{"name": "Chavela Vargas"}).ReturningExpr("id", db.Raw("GETDATE()")).String()
+	}()
+
+	assert.Equal(
+		"INSERT INTO [artist] ([id], [name]) VALUES (@p1, @p2)",
 		b.InsertInto("artist").Values(map[string]interface{}{"name": "Chavela Vargas", "id": 12}).String(),
 	)
 
 	assert.Equal(
-		"INSERT INTO [artist] ([id], [name]) VALUES ($1, $2)",
+		"INSERT INTO [artist] ([id], [name]) VALUES (@p1, @p2)",
 		b.InsertInto("artist").Values(struct {
 			ID   int    `db:"id"`
 			Name string `db:"name"`
@@ -183,9 +248,31 @@ func TestTemplateInsert(t *testing.T) {
 	)
 
 	assert.Equal(
-		"INSERT INTO [artist] ([name], [id]) VALUES ($1, $2)",
+		"INSERT INTO [artist] ([name], [id]) VALUES (@p1, @p2)",
 		b.InsertInto("artist").Columns("name", "id").Values("Chavela Vargas", 12).String(),
 	)
+
+	assert.Equal(
+		"INSERT INTO [artist] ([name]) VALUES (@p1); SELECT CAST(SCOPE_IDENTITY() AS BIGINT)",
+		b.InsertInto("artist").Values(map[string]string{"name": "Chavela Vargas"}).Amend(selectScopeIdentity).String(),
+	)
+}
+
+func TestTemplateUpsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	item := map[string]interface{}{"email": "ozzy@example.com", "name": "Ozzy"}
+
+	assert.Equal(
+		"MERGE INTO [artist] WITH (HOLDLOCK) AS [target] USING (VALUES (@p1, @p2)) AS [source] ([email], [name]) ON ([target].[email] = [source].[email]) WHEN NOT MATCHED THEN INSERT ([email], [name]) VALUES ([source].[email], [source].[name]) ;",
+		b.InsertInto("artist").Values(item).OnConflict("email").String(),
+	)
+
+	assert.Equal(
+		"MERGE INTO [artist] WITH (HOLDLOCK) AS [target] USING (VALUES (@p1, @p2)) AS [source] ([email], [name]) ON ([target].[email] = [source].[email]) WHEN MATCHED THEN UPDATE SET [name] = [source].[name] WHEN NOT MATCHED THEN INSERT ([email], [name]) VALUES ([source].[email], [source].[name]) OUTPUT [inserted].[id] , CAST(CASE $action WHEN 'INSERT' THEN 1 ELSE 0 END AS BIT) AS [inserted] ;",
+		b.InsertInto("artist").Values(item).OnConflict("email").DoUpdate().Returning("id").ReturningInserted().String(),
+	)
 }
 
 func TestTemplateUpdate(t *testing.T) {
@@ -193,41 +280,77 @@ func TestTemplateUpdate(t *testing.T) {
 	assert := assert.New(t)
 
 	assert.Equal(
-		"UPDATE [artist] SET [name] = $1",
+		`UPDATE [counters] SET [n] = [n] + @p1`,
+		b.Update("counters").Set(db.Cond{"n": db.Col("n").Add(1)}).String(),
+	)
+
+	assert.Equal(
+		`SELECT [first_name] + @p1 + [last_name] FROM [artist]`,
+		b.Select(db.Concat(db.Col("first_name"), " ", db.Col("last_name"))).From("artist").String(),
+	)
+
+	assert.Equal(
+		"UPDATE [artist] SET [name] = @p1",
 		b.Update("artist").Set("name", "Artist").String(),
 	)
 
 	assert.Equal(
-		"UPDATE [artist] SET [name] = $1 WHERE ([id] < $2)",
+		"UPDATE [artist] SET [name] = @p1 WHERE ([id] < @p2)",
 		b.Update("artist").Set("name = ?", "Artist").Where("id <", 5).String(),
 	)
 
 	assert.Equal(
-		"UPDATE [artist] SET [name] = $1 WHERE ([id] < $2)",
+		"UPDATE [artist] SET [name] = @p1 WHERE ([id] < @p2)",
 		b.Update("artist").Set(map[string]string{"name": "Artist"}).Where(db.Cond{"id <": 5}).String(),
 	)
 
 	assert.Equal(
-		"UPDATE [artist] SET [name] = $1 WHERE ([id] < $2)",
+		"UPDATE [artist] SET [name] = @p1 WHERE ([id] < @p2)",
 		b.Update("artist").Set(struct {
 			Nombre string `db:"name"`
 		}{"Artist"}).Where(db.Cond{"id <": 5}).String(),
 	)
 
 	assert.Equal(
-		"UPDATE [artist] SET [name] = $1, [last_name] = $2 WHERE ([id] < $3)",
+		"UPDATE [artist] SET [name] = @p1, [last_name] = @p2 WHERE ([id] < @p3)",
 		b.Update("artist").Set(struct {
 			Nombre string `db:"name"`
 		}{"Artist"}).Set(map[string]string{"last_name": "Foo"}).Where(db.Cond{"id <": 5}).String(),
 	)
 
 	assert.Equal(
-		"UPDATE [artist] SET [name] = $1 || ' ' || $2 || id, [id] = id + $3 WHERE (id > $4)",
+		"UPDATE [artist] SET [name] = @p1 || ' ' || @p2 || id, [id] = id + @p3 WHERE (id > @p4)",
 		b.Update("artist").Set(
 			"name = ? || ' ' || ? || id", "Artist", "#",
 			"id = id + ?", 10,
 		).Where("id > ?", 0).String(),
 	)
+
+	{
+		q := b.Update("artist").
+			Set("name = s.name").
+			From("staging AS s").
+			Where("artist.id = s.id").
+			And("s.batch", 3)
+		assert.Equal(
+			`UPDATE [artist] SET [name] = s.name FROM [artist], [staging] AS [s] WHERE (artist.id = s.id AND [s].[batch] = @p1)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{3}, q.Arguments())
+	}
+
+	{
+		q := b.Update("artist").
+			Set(db.Cond{"name": db.Raw("s.name"), "rank": 1}).
+			From("staging AS s").
+			Join("batches AS b").On("b.id = s.batch_id AND b.state = ?", "ready").
+			Where("artist.id = s.id AND s.kind = ?", "solo")
+		assert.Equal(
+			`UPDATE [artist] SET [name] = s.name, [rank] = @p1 FROM [artist], [staging] AS [s] JOIN [batches] AS [b] ON (b.id = s.batch_id AND b.state = @p2) WHERE (artist.id = s.id AND s.kind = @p3)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{1, "ready", "solo"}, q.Arguments())
+	}
 }
 
 func TestTemplateDelete(t *testing.T) {
@@ -235,7 +358,7 @@ func TestTemplateDelete(t *testing.T) {
 	assert := assert.New(t)
 
 	assert.Equal(
-		"DELETE FROM [artist] WHERE (name = $1)",
+		"DELETE FROM [artist] WHERE (name = @p1)",
 		b.DeleteFrom("artist").Where("name = ?", "Chavela Vargas").String(),
 	)
 
@@ -243,4 +366,145 @@ func TestTemplateDelete(t *testing.T) {
 		"DELETE FROM [artist] WHERE (id > 5)",
 		b.DeleteFrom("artist").Where("id > 5").String(),
 	)
+
+	assert.Equal(
+		"DELETE TOP (1000) FROM [artist] WHERE (id > 5)",
+		b.DeleteFrom("artist").Where("id > 5").Limit(1000).String(),
+	)
+
+	assert.Equal(
+		"UPDATE TOP (1000) [artist] SET [name] = @p1 WHERE (id > 5)",
+		b.Update("artist").Set("name", "Rick").Where("id > 5").Limit(1000).String(),
+	)
+
+	{
+		q := b.DeleteFrom("artist").
+			Using("banned AS b").
+			Join("reasons AS r").On("r.id = b.reason_id AND r.severity > ?", 2).
+			Where("artist.name = b.name AND b.since < ?", "2019-01-01")
+		assert.Equal(
+			`DELETE [artist] FROM [artist], [banned] AS [b] JOIN [reasons] AS [r] ON (r.id = b.reason_id AND r.severity > @p1) WHERE (artist.name = b.name AND b.since < @p2)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{2, "2019-01-01"}, q.Arguments())
+	}
+}
+
+func TestTemplateConditionGrouping(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+
+	testCases := []struct {
+		sel  sqlbuilder.Selector
+		sql  string
+		args []interface{}
+	}{
+		{
+			b.SelectFrom("t").Where(db.Or(
+				db.And(db.Cond{"a": 1}, db.Cond{"b": 2}),
+				db.And(db.Cond{"c": 3}, db.Cond{"d": 4}),
+			)),
+			`SELECT * FROM [t] WHERE ((([a] = @p1 AND [b] = @p2) OR ([c] = @p3 AND [d] = @p4)))`,
+			[]interface{}{1, 2, 3, 4},
+		},
+		{
+			b.SelectFrom("t").Where(db.Or(
+				db.Cond{"a": 1, "b": 2},
+				db.And(
+					db.Cond{"c": 3},
+					db.Or(db.Cond{"d": 4}, db.And(db.Cond{"e": 5}, db.Or(db.Cond{"f": 6}, db.Cond{"g": 7}))),
+				),
+			)),
+			`SELECT * FROM [t] WHERE ((([a] = @p1 AND [b] = @p2) OR ([c] = @p3 AND ([d] = @p4 OR ([e] = @p5 AND ([f] = @p6 OR [g] = @p7))))))`,
+			[]interface{}{1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			b.SelectFrom("t").Where(db.And(
+				db.Or(db.Cond{"a": 1}, db.Cond{"b": 2}),
+				db.Or(db.Cond{"c": 3}, db.Cond{"d": 4}),
+			)),
+			`SELECT * FROM [t] WHERE ((([a] = @p1 OR [b] = @p2) AND ([c] = @p3 OR [d] = @p4)))`,
+			[]interface{}{1, 2, 3, 4},
+		},
+		{
+			b.SelectFrom("t").Where("a = ? OR b = ?", 1, 2).And(db.Cond{"c": 3}),
+			`SELECT * FROM [t] WHERE ((a = @p1 OR b = @p2) AND [c] = @p3)`,
+			[]interface{}{1, 2, 3},
+		},
+		{
+			b.SelectFrom("t").Where(db.And(db.Raw("a = ? OR b = ?", 1, 2), db.Raw("c = ? AND d = ?", 3, 4))),
+			`SELECT * FROM [t] WHERE (((a = @p1 OR b = @p2) AND c = @p3 AND d = @p4))`,
+			[]interface{}{1, 2, 3, 4},
+		},
+		{
+			b.SelectFrom("t").Where("a", 1).And("b", 2).Or("c", 3),
+			`SELECT * FROM [t] WHERE ((([a] = @p1 AND [b] = @p2) OR ([c] = @p3)))`,
+			[]interface{}{1, 2, 3},
+		},
+		{
+			b.SelectFrom("t").Where("a", 1).Or(db.Cond{"b": 2, "c": 3}).And(db.Cond{"d": 4}).Or(db.Or(db.Cond{"e": 5}, db.Cond{"f": 6})),
+			`SELECT * FROM [t] WHERE ((((([a] = @p1) OR ([b] = @p2 AND [c] = @p3)) AND [d] = @p4) OR (([e] = @p5 OR [f] = @p6))))`,
+			[]interface{}{1, 2, 3, 4, 5, 6},
+		},
+		{
+			b.SelectFrom("t").Or("a", 1),
+			`SELECT * FROM [t] WHERE ([a] = @p1)`,
+			[]interface{}{1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.sql, testCase.sel.String())
+		assert.Equal(t, testCase.args, testCase.sel.Arguments())
+	}
+}
+
+func TestTemplateCreateTable(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	type account struct {
+		ID        int64      `db:"id,omitempty"`
+		Name      string     `db:"name"`
+		Active    bool       `db:"active"`
+		Avatar    []byte     `db:"avatar"`
+		CreatedAt *time.Time `db:"created_at"`
+	}
+
+	assert.Equal(
+		"CREATE TABLE [accounts] ([id] BIGINT IDENTITY(1,1) PRIMARY KEY, [name] NVARCHAR(MAX) NOT NULL, [active] BIT NOT NULL, [avatar] VARBINARY(MAX) NOT NULL, [created_at] DATETIME2)",
+		b.CreateTable("accounts").Struct(account{}).String(),
+	)
+
+	assert.Equal(
+		"IF OBJECT_ID('[accounts]', 'U') IS NULL CREATE TABLE [accounts] ([id] BIGINT IDENTITY(1,1) PRIMARY KEY, [name] NVARCHAR(MAX) NOT NULL, [active] BIT NOT NULL, [avatar] VARBINARY(MAX) NOT NULL, [created_at] DATETIME2)",
+		b.CreateTable("accounts").Struct(account{}).IfNotExists().String(),
+	)
+}
+
+func TestTemplateDDL(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		"CREATE TABLE [accounts] ([id] INT IDENTITY(1,1) PRIMARY KEY, [name] NVARCHAR(MAX) NOT NULL)",
+		b.CreateTable("accounts").
+			Column("id", "serial", db.PrimaryKey).
+			Column("name", "text", db.NotNull).
+			String(),
+	)
+
+	assert.Equal(
+		"DROP TABLE IF EXISTS [accounts]",
+		b.DropTable("accounts").IfExists().Cascade().String(),
+	)
+
+	assert.Equal(
+		"ALTER TABLE [accounts] ADD [active] BIT NOT NULL DEFAULT 1",
+		b.AlterTable("accounts").AddColumn("active", "boolean", db.NotNull, db.ColumnDefault("1")).String(),
+	)
+
+	assert.Equal(
+		"ALTER TABLE [accounts] DROP COLUMN [avatar]",
+		b.AlterTable("accounts").DropColumn("avatar").String(),
+	)
 }
@@ -23,19 +23,57 @@ package mssql
 
 import (
 	"context"
+	"errors"
 
+	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter"
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
+// Tx represents a MSSQL transaction with features that are specific to this
+// adapter. Transactions created by this adapter, including the ones passed to
+// the function given to Tx(), satisfy this interface.
+type Tx interface {
+	sqlbuilder.Tx
+
+	// CreateTempTable creates a local temporary table (#name) with the columns
+	// of the given struct (see sqlbuilder.TableCreator.Struct) and returns a
+	// collection bound to it. A random suffix is appended to name, so
+	// concurrent transactions can use the same name; use the collection's
+	// Name() to refer to the table in queries. The table is dropped when the
+	// connection is closed or reset.
+	CreateTempTable(name string, columns interface{}) (db.Collection, error)
+
+	// DeferConstraints always returns ErrDeferConstraintsUnsupported, SQL
+	// Server checks constraints on each statement and has no deferrable
+	// constraints.
+	DeferConstraints(names ...string) error
+}
+
+// ErrDeferConstraintsUnsupported is returned by DeferConstraints.
+var ErrDeferConstraintsUnsupported = errors.New(`upper: SQL Server can't defer constraint checks, disable them with ALTER TABLE ... NOCHECK CONSTRAINT instead`)
+
 type tx struct {
 	sqladapter.DatabaseTx
 }
 
 var (
+	_ = Tx(&tx{})
 	_ = sqlbuilder.Tx(&tx{})
 )
 
+func (t *tx) CreateTempTable(name string, columns interface{}) (db.Collection, error) {
+	name = "#" + sqladapter.TempTableName(name)
+	if _, err := t.CreateTable(name).Struct(columns).Exec(); err != nil {
+		return nil, err
+	}
+	return t.Collection(name), nil
+}
+
+func (t *tx) DeferConstraints(names ...string) error {
+	return ErrDeferConstraintsUnsupported
+}
+
 func (t *tx) WithContext(ctx context.Context) sqlbuilder.Tx {
 	var newTx tx
 	newTx = *t
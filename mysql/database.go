@@ -182,11 +182,12 @@ func (d *database) ConvertValues(values []interface{}) []interface{} {
 // CompileStatement compiles a *exql.Statement into arguments that sql/database
 // accepts.
 func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
-	compiled, err := stmt.Compile(template)
+	compiled, err := stmt.Compile(sqladapter.SessionTemplate(d.BaseDatabase, template))
 	if err != nil {
 		panic(err.Error())
 	}
-	return sqlbuilder.Preprocess(compiled, args)
+	query, args := sqlbuilder.PreprocessWithTemplate(template, compiled, args)
+	return template.BindArguments(query, args)
 }
 
 // Err allows sqladapter to translate specific MySQL string errors into custom
@@ -198,6 +199,10 @@ func (d *database) Err(err error) error {
 		if strings.Contains(s, `many connections`) {
 			return db.ErrTooManyClients
 		}
+		// max_execution_time exceeded (3024).
+		if strings.Contains(s, `maximum statement execution time exceeded`) {
+			return &db.Error{Kind: db.ErrQueryTimeout, Err: err}
+		}
 	}
 	return err
 }
@@ -215,6 +220,11 @@ func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) erro
 	return sqladapter.RunTx(d, ctx, fn)
 }
 
+// TxWithStats works like Tx and fills stats in with the details of the run.
+func (d *database) TxWithStats(ctx context.Context, fn func(tx sqlbuilder.Tx) error, stats *sqlbuilder.TxStats) error {
+	return sqladapter.RunTxWithStats(d, ctx, fn, stats)
+}
+
 // NewDatabaseTx begins a transaction block.
 func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
 	clone, err := d.clone(ctx, true)
@@ -22,6 +22,7 @@
 package mysql
 
 import (
+	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/cache"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 )
@@ -37,6 +38,7 @@ const (
 	adapterNotKeyword          = `NOT`
 	adapterDescKeyword         = `DESC`
 	adapterAscKeyword          = `ASC`
+	adapterCollateLayout       = `COLLATE {{.}}`
 	adapterDefaultOperator     = `=`
 	adapterAssignmentOperator  = `=`
 	adapterClauseGroup         = `({{.}})`
@@ -86,6 +88,10 @@ const (
     {{end}}
   `
 
+	adapterLockLayout = `FOR UPDATE{{if .SkipLocked}} SKIP LOCKED{{end}}`
+
+	adapterRandomFunction = `RAND()`
+
 	adapterSelectLayout = `
     SELECT
       {{if .Distinct}}
@@ -133,17 +139,32 @@ const (
         {{end}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{.Lock | compile}}
   `
 	adapterDeleteLayout = `
     DELETE
-      FROM {{.Table | compile}}
+      {{if .From}}
+        {{.Table | compile}} {{.From | compile}}
+      {{else}}
+        FROM {{.Table | compile}}
+      {{end}}
       {{.Where | compile}}
+      {{if .Limit}}
+        LIMIT {{.Limit}}
+      {{end}}
   `
+	adapterDeleteUsingLayout = `FROM {{.Table | compile}}, {{.Sources | compile}} {{.Joins | compile}}`
+
 	adapterUpdateLayout = `
     UPDATE
       {{.Table | compile}}
     SET {{.ColumnValues | compile}}
+      {{.From | compile}}
       {{.Where | compile}}
+      {{if .Limit}}
+        LIMIT {{.Limit}}
+      {{end}}
   `
 
 	adapterSelectCountLayout = `
@@ -162,11 +183,23 @@ const (
     {{else}}
       ()
     {{end}}
+    {{if defined .OnConflict}}
+      {{.OnConflict | compile}}
+    {{end}}
     {{if defined .Returning}}
       RETURNING {{.Returning | compile}}
     {{end}}
   `
 
+	adapterOnConflictLayout = `
+    ON DUPLICATE KEY UPDATE
+    {{if .Update}}
+      {{range $i, $c := .Update}}{{if $i}}, {{end}}{{$c}} = VALUES({{$c}}){{end}}
+    {{else}}
+      {{index .Target 0}} = {{index .Target 0}}
+    {{end}}
+  `
+
 	adapterTruncateLayout = `
     TRUNCATE TABLE {{.Table | compile}}
   `
@@ -176,7 +209,19 @@ const (
   `
 
 	adapterDropTableLayout = `
-    DROP TABLE {{.Table | compile}}
+    DROP TABLE {{if .IfExists}}IF EXISTS {{end}}{{.Table | compile}}{{if .Cascade}} CASCADE{{end}}
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table | compile}} {{.Alterations | compile}}
+  `
+
+	adapterAddColumnLayout = `ADD COLUMN {{.Column}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.Column}}`
+
+	adapterCreateTableLayout = `
+    CREATE {{if .Temporary}}TEMPORARY {{end}}TABLE {{if .IfNotExists}}IF NOT EXISTS {{end}}{{.Table | compile}} ({{.Definitions | compile}})
   `
 
 	adapterGroupByLayout = `
@@ -192,10 +237,12 @@ var template = &exql.Template{
 	IdentifierQuote:     adapterIdentifierQuote,
 	ValueSeparator:      adapterValueSeparator,
 	ValueQuote:          adapterValueQuote,
+	BackslashEscapes:    true,
 	AndKeyword:          adapterAndKeyword,
 	OrKeyword:           adapterOrKeyword,
 	DescKeyword:         adapterDescKeyword,
 	AscKeyword:          adapterAscKeyword,
+	CollateLayout:       adapterCollateLayout,
 	AssignmentOperator:  adapterAssignmentOperator,
 	ClauseGroup:         adapterClauseGroup,
 	ClauseOperator:      adapterClauseOperator,
@@ -205,17 +252,34 @@ var template = &exql.Template{
 	SortByColumnLayout:  adapterSortByColumnLayout,
 	WhereLayout:         adapterWhereLayout,
 	JoinLayout:          adapterJoinLayout,
+	LockLayout:          adapterLockLayout,
+	RandomFunction:      adapterRandomFunction,
 	OnLayout:            adapterOnLayout,
 	UsingLayout:         adapterUsingLayout,
 	OrderByLayout:       adapterOrderByLayout,
 	InsertLayout:        adapterInsertLayout,
+	OnConflictLayout:    adapterOnConflictLayout,
 	SelectLayout:        adapterSelectLayout,
 	UpdateLayout:        adapterUpdateLayout,
 	DeleteLayout:        adapterDeleteLayout,
+	DeleteUsingLayout:   adapterDeleteUsingLayout,
 	TruncateLayout:      adapterTruncateLayout,
 	DropDatabaseLayout:  adapterDropDatabaseLayout,
 	DropTableLayout:     adapterDropTableLayout,
+	CreateTableLayout:   adapterCreateTableLayout,
+	AlterTableLayout:    adapterAlterTableLayout,
+	AddColumnLayout:     adapterAddColumnLayout,
+	DropColumnLayout:    adapterDropColumnLayout,
 	CountLayout:         adapterSelectCountLayout,
 	GroupByLayout:       adapterGroupByLayout,
 	Cache:               cache.NewCache(),
+	ComparisonOperator: map[db.ComparisonOperator]string{
+		db.ComparisonOperatorILike:    "LIKE",
+		db.ComparisonOperatorNotILike: "NOT LIKE",
+	},
+	ColumnTypes: map[string]string{
+		"serial":    `INT AUTO_INCREMENT`,
+		"bigserial": `BIGINT AUTO_INCREMENT`,
+		"timestamp": `DATETIME`,
+	},
 }
@@ -1,10 +1,13 @@
 package mysql
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
@@ -162,6 +165,11 @@ func TestTemplateSelect(t *testing.T) {
 			b.SelectFrom("artist").Where(db.Cond{"name LIKE": "%foo", "id": db.In([]byte{1, 2})}).String(),
 		)
 	}
+
+	assert.Equal(
+		"SELECT * FROM `artist` WHERE (`name` LIKE $1 ESCAPE '\\\\')",
+		b.SelectFrom("artist").Where(db.Cond{"name": db.Like("%" + db.EscapeLike("50%") + "%")}).String(),
+	)
 }
 
 func TestTemplateInsert(t *testing.T) {
@@ -206,10 +214,40 @@ func TestTemplateInsert(t *testing.T) {
 	)
 }
 
+func TestTemplateUpsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	item := map[string]interface{}{"email": "ozzy@example.com", "name": "Ozzy"}
+
+	assert.Equal(
+		"INSERT INTO `artist` (`email`, `name`) VALUES ($1, $2) ON DUPLICATE KEY UPDATE `email` = `email`",
+		b.InsertInto("artist").Values(item).OnConflict("email").String(),
+	)
+
+	assert.Equal(
+		"INSERT INTO `artist` (`email`, `name`) VALUES ($1, $2) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)",
+		b.InsertInto("artist").Values(item).OnConflict("email").DoUpdate().String(),
+	)
+
+	_, err := b.InsertInto("artist").Values(item).OnConflict("email").ReturningInserted().ExecContext(context.Background())
+	assert.Equal(exql.ErrUpsertUnsupported, err)
+}
+
 func TestTemplateUpdate(t *testing.T) {
 	b := sqlbuilder.WithTemplate(template)
 	assert := assert.New(t)
 
+	assert.Equal(
+		"UPDATE `counters` SET `n` = `n` + $1",
+		b.Update("counters").Set(db.Cond{"n": db.Col("n").Add(1)}).String(),
+	)
+
+	assert.Equal(
+		"SELECT CONCAT(`first_name`, $1, `last_name`) FROM `artist`",
+		b.Select(db.Concat(db.Col("first_name"), " ", db.Col("last_name"))).From("artist").String(),
+	)
+
 	assert.Equal(
 		"UPDATE `artist` SET `name` = $1",
 		b.Update("artist").Set("name", "Artist").String(),
@@ -246,6 +284,9 @@ func TestTemplateUpdate(t *testing.T) {
 			"id = id + ?", 10,
 		).Where("id > ?", 0).String(),
 	)
+
+	_, err := b.Update("artist").Set("name = s.name").From("staging AS s").Where("artist.id = s.id").ExecContext(context.Background())
+	assert.Equal(exql.ErrUpdateFromUnsupported, err)
 }
 
 func TestTemplateDelete(t *testing.T) {
@@ -261,4 +302,42 @@ func TestTemplateDelete(t *testing.T) {
 		"DELETE FROM `artist` WHERE (id > 5)",
 		b.DeleteFrom("artist").Where("id > 5").String(),
 	)
+
+	assert.Equal(
+		"DELETE FROM `artist` WHERE (id > 5) LIMIT 1000",
+		b.DeleteFrom("artist").Where("id > 5").Limit(1000).String(),
+	)
+
+	assert.Equal(
+		"UPDATE `artist` SET `name` = $1 WHERE (id > 5) LIMIT 1000",
+		b.Update("artist").Set("name", "Rick").Where("id > 5").Limit(1000).String(),
+	)
+
+	assert.Equal(
+		"DELETE `artist` FROM `artist`, `banned` AS `b` WHERE (artist.name = b.name AND b.since < $1)",
+		b.DeleteFrom("artist").Using("banned AS b").Where("artist.name = b.name AND b.since < ?", "2019-01-01").String(),
+	)
+}
+
+func TestTemplateCreateTable(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	type account struct {
+		ID        int64      `db:"id,omitempty"`
+		Name      string     `db:"name"`
+		Active    bool       `db:"active"`
+		Avatar    []byte     `db:"avatar"`
+		CreatedAt *time.Time `db:"created_at"`
+	}
+
+	assert.Equal(
+		"CREATE TABLE `accounts` (`id` BIGINT AUTO_INCREMENT PRIMARY KEY, `name` TEXT NOT NULL, `active` BOOLEAN NOT NULL, `avatar` BLOB NOT NULL, `created_at` DATETIME)",
+		b.CreateTable("accounts").Struct(account{}).String(),
+	)
+
+	assert.Equal(
+		"CREATE TABLE IF NOT EXISTS `accounts` (`id` BIGINT AUTO_INCREMENT PRIMARY KEY, `name` TEXT NOT NULL, `active` BOOLEAN NOT NULL, `avatar` BLOB NOT NULL, `created_at` DATETIME)",
+		b.CreateTable("accounts").Struct(account{}).IfNotExists().String(),
+	)
 }
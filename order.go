@@ -0,0 +1,108 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+// NullsOrder tells where NULL values go in a sort.
+type NullsOrder uint8
+
+// Possible NullsOrder values.
+const (
+	// NullsDefault leaves NULL values where the database puts them, which
+	// depends on the database and the direction of the sort.
+	NullsDefault NullsOrder = iota
+	NullsFirst
+	NullsLast
+)
+
+// Order represents a column in an ORDER BY clause, it's accepted by OrderBy
+// along with column names.
+//
+//	res.OrderBy(db.Desc("rating").NullsLast(), db.Asc("name"))
+type Order struct {
+	column    string
+	desc      bool
+	nulls     NullsOrder
+	collation string
+}
+
+// Asc sorts the given column in ascending order.
+func Asc(column string) Order {
+	return Order{column: column}
+}
+
+// Desc sorts the given column in descending order.
+func Desc(column string) Order {
+	return Order{column: column, desc: true}
+}
+
+// NullsFirst puts NULL values before any other value, regardless of the
+// direction of the sort. Databases without NULLS FIRST get an equivalent
+// CASE expression.
+func (o Order) NullsFirst() Order {
+	o.nulls = NullsFirst
+	return o
+}
+
+// NullsLast puts NULL values after any other value, regardless of the
+// direction of the sort. Databases without NULLS LAST get an equivalent CASE
+// expression.
+func (o Order) NullsLast() Order {
+	o.nulls = NullsLast
+	return o
+}
+
+// Collate compares values using the given collation, the name is passed to
+// the database as it is:
+//
+//	// ORDER BY "name" COLLATE "C" DESC
+//	res.OrderBy(db.Desc("name").Collate("C"))
+//
+//	// ORDER BY [name] COLLATE Latin1_General_CI_AS ASC
+//	res.OrderBy(db.Col("name").Collate("Latin1_General_CI_AS"))
+//
+// Collation names may only contain letters, digits, "_", ".", "@" and "-",
+// other names make the query fail to build.
+func (o Order) Collate(collation string) Order {
+	o.collation = collation
+	return o
+}
+
+// Column returns the name of the column.
+func (o Order) Column() string {
+	return o.column
+}
+
+// Descending returns true if the column is sorted in descending order.
+func (o Order) Descending() bool {
+	return o.desc
+}
+
+// Nulls returns where NULL values go.
+func (o Order) Nulls() NullsOrder {
+	return o.nulls
+}
+
+// Collation returns the name of the collation, or an empty string if the
+// column is sorted with its default collation.
+func (o Order) Collation() string {
+	return o.collation
+}
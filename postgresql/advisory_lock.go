@@ -0,0 +1,226 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+)
+
+// Errors returned by AdvisoryLock.
+var (
+	ErrAdvisoryLockHeld    = errors.New(`upper: advisory lock is already held`)
+	ErrAdvisoryLockNotHeld = errors.New(`upper: advisory lock is not held`)
+)
+
+// AdvisoryLockKey identifies an advisory lock, PostgreSQL takes either a
+// single 64-bit key or a pair of 32-bit keys. Both forms live in different
+// key spaces, so AdvisoryKey(1) and AdvisoryKeyPair(0, 1) are different locks.
+type AdvisoryLockKey struct {
+	key  int64
+	a, b int32
+	pair bool
+}
+
+// AdvisoryKey returns the key of an advisory lock identified by a single
+// 64-bit number.
+func AdvisoryKey(key int64) AdvisoryLockKey {
+	return AdvisoryLockKey{key: key}
+}
+
+// AdvisoryKeyPair returns the key of an advisory lock identified by two 32-bit
+// numbers, like a class and an object ID.
+func AdvisoryKeyPair(a, b int32) AdvisoryLockKey {
+	return AdvisoryLockKey{a: a, b: b, pair: true}
+}
+
+// call returns a statement that calls the given advisory lock function with
+// the key.
+func (k AdvisoryLockKey) call(fn string) (string, []interface{}) {
+	if k.pair {
+		return `SELECT ` + fn + `($1, $2)`, []interface{}{k.a, k.b}
+	}
+	return `SELECT ` + fn + `($1)`, []interface{}{k.key}
+}
+
+// AdvisoryLock is a session-level advisory lock. A session-level lock belongs
+// to the connection that took it, so AdvisoryLock keeps a connection of its
+// own from the moment it's acquired until Unlock is called. Locks that are
+// never unlocked are held until that connection is closed.
+//
+//	lock := sess.(postgresql.Database).AdvisoryLock(postgresql.AdvisoryKey(42))
+//	ok, err := lock.TryLock(ctx)
+//	if err != nil || !ok {
+//	  return err
+//	}
+//	defer lock.Unlock()
+//
+// Within a transaction, Tx.AdvisoryXactLock is simpler, as the lock is
+// released when the transaction ends.
+type AdvisoryLock struct {
+	sess *database
+	key  AdvisoryLockKey
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+// Lock waits until the lock is acquired or ctx is done.
+func (l *AdvisoryLock) Lock(ctx context.Context) error {
+	_, err := l.acquire(ctx, true)
+	return err
+}
+
+// TryLock acquires the lock if it's available and returns false otherwise,
+// without waiting.
+func (l *AdvisoryLock) TryLock(ctx context.Context) (bool, error) {
+	return l.acquire(ctx, false)
+}
+
+func (l *AdvisoryLock) acquire(ctx context.Context, wait bool) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		return false, ErrAdvisoryLockHeld
+	}
+
+	sess := l.sess.Session()
+	if sess == nil {
+		return false, db.ErrNotConnected
+	}
+
+	conn, err := sess.Conn(ctx)
+	if err != nil {
+		return false, l.sess.Err(err)
+	}
+
+	acquired := false
+	if wait {
+		query, args := l.key.call("pg_advisory_lock")
+		if _, err = conn.ExecContext(ctx, query, args...); err == nil {
+			acquired = true
+		} else {
+			// The lock may have been granted right before the wait was
+			// canceled, make sure it doesn't stay with the pooled connection.
+			query, args = l.key.call("pg_advisory_unlock")
+			_, _ = conn.ExecContext(context.Background(), query, args...)
+		}
+	} else {
+		query, args := l.key.call("pg_try_advisory_lock")
+		err = conn.QueryRowContext(ctx, query, args...).Scan(&acquired)
+	}
+
+	if err != nil || !acquired {
+		conn.Close()
+		if err != nil {
+			return false, l.sess.Err(err)
+		}
+		return false, nil
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Unlock releases the lock and the connection that holds it.
+func (l *AdvisoryLock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return ErrAdvisoryLockNotHeld
+	}
+	conn := l.conn
+	l.conn = nil
+	defer conn.Close()
+
+	query, args := l.key.call("pg_advisory_unlock")
+
+	var released bool
+	if err := conn.QueryRowContext(context.Background(), query, args...).Scan(&released); err != nil {
+		return l.sess.Err(err)
+	}
+	if !released {
+		return ErrAdvisoryLockNotHeld
+	}
+	return nil
+}
+
+// AdvisoryLock returns a session-level advisory lock on the given key, the
+// lock is not acquired until Lock or TryLock is called.
+func (d *database) AdvisoryLock(key AdvisoryLockKey) *AdvisoryLock {
+	return &AdvisoryLock{sess: d, key: key}
+}
+
+// LockOptions tells WithLock how to acquire its lock.
+type LockOptions struct {
+	// Wait makes WithLock wait until the lock is available. Otherwise
+	// db.ErrLockNotAcquired is returned right away if the lock is taken.
+	Wait bool
+
+	// Timeout limits the wait, db.ErrLockNotAcquired is returned if it
+	// expires. Zero means no limit other than the context's.
+	Timeout time.Duration
+}
+
+// WithLock runs fn while holding the session-level advisory lock on the
+// given key. The lock is released when fn returns or panics.
+func (d *database) WithLock(ctx context.Context, key AdvisoryLockKey, opts LockOptions, fn func() error) (err error) {
+	lock := d.AdvisoryLock(key)
+
+	if opts.Wait {
+		lockCtx, cancel := ctx, context.CancelFunc(func() {})
+		if opts.Timeout > 0 {
+			lockCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		}
+		err = lock.Lock(lockCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && lockCtx.Err() != nil {
+				return db.ErrLockNotAcquired
+			}
+			return err
+		}
+	} else {
+		acquired, err := lock.TryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			return db.ErrLockNotAcquired
+		}
+	}
+
+	defer func() {
+		if unlockErr := lock.Unlock(); unlockErr != nil && err == nil {
+			err = unlockErr
+		}
+	}()
+
+	return fn()
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	byteSliceType = reflect.TypeOf([]byte(nil))
+	valuerType    = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// arrayLiteral encodes a slice or array as a PostgreSQL array literal, like
+// {1,2,3} or {{"a",NULL},{"b","c"}}. Nested slices become nested arrays and
+// elements are converted the same way database/sql converts arguments, so
+// elements satisfying driver.Valuer are written with their own values.
+func arrayLiteral(v interface{}) (driver.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+	case reflect.Array:
+	default:
+		return nil, fmt.Errorf("upper: can't use %T as an array", v)
+	}
+	var buf bytes.Buffer
+	if err := appendArray(&buf, rv); err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
+
+func appendArray(buf *bytes.Buffer, rv reflect.Value) error {
+	buf.WriteByte('{')
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := appendArrayElement(buf, rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func appendArrayElement(buf *bytes.Buffer, rv reflect.Value) error {
+	if rv.CanAddr() && rv.Kind() != reflect.Ptr && !rv.Type().Implements(valuerType) && reflect.PtrTo(rv.Type()).Implements(valuerType) {
+		// Valuer with a pointer receiver.
+		rv = rv.Addr()
+	}
+	if k := rv.Kind(); k == reflect.Slice || k == reflect.Array {
+		if t := rv.Type(); t != byteSliceType && !t.Implements(valuerType) {
+			return appendArray(buf, rv)
+		}
+	}
+
+	v, err := driver.DefaultParameterConverter.ConvertValue(rv.Interface())
+	if err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("NULL")
+	case []byte:
+		if v == nil {
+			buf.WriteString("NULL")
+			break
+		}
+		appendArrayQuoted(buf, `\x`+hex.EncodeToString(v))
+	case string:
+		appendArrayQuoted(buf, v)
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case time.Time:
+		appendArrayQuoted(buf, v.Format(time.RFC3339Nano))
+	default:
+		return fmt.Errorf("upper: can't use %T as an array element", v)
+	}
+	return nil
+}
+
+var arrayQuoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func appendArrayQuoted(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	buf.WriteString(arrayQuoteReplacer.Replace(s))
+	buf.WriteByte('"')
+}
+
+// parseArray splits a one-dimensional PostgreSQL array literal, like
+// {1,NULL,"a \"b\""}, into its elements. NULL elements are returned as nil
+// and empty strings as non-nil empty slices.
+func parseArray(src []byte) ([][]byte, error) {
+	if len(src) < 2 || src[0] != '{' || src[len(src)-1] != '}' {
+		return nil, fmt.Errorf("upper: invalid array literal %q", src)
+	}
+	body := src[1 : len(src)-1]
+	elems := [][]byte{}
+	if len(body) == 0 {
+		return elems, nil
+	}
+	for i := 0; ; {
+		var elem []byte
+		if i < len(body) && body[i] == '"' {
+			elem = []byte{}
+			for i++; ; i++ {
+				if i >= len(body) {
+					return nil, fmt.Errorf("upper: unterminated element in array literal %q", src)
+				}
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				} else if body[i] == '"' {
+					i++
+					break
+				}
+				elem = append(elem, body[i])
+			}
+		} else {
+			end := bytes.IndexByte(body[i:], ',')
+			if end < 0 {
+				end = len(body) - i
+			}
+			elem = body[i : i+end]
+			if bytes.IndexAny(elem, `{}"`) >= 0 {
+				return nil, fmt.Errorf("upper: only one-dimensional arrays are supported, got %q", src)
+			}
+			if strings.EqualFold(string(elem), "NULL") {
+				elem = nil
+			}
+			i += end
+		}
+		elems = append(elems, elem)
+		if i >= len(body) {
+			return elems, nil
+		}
+		if body[i] != ',' {
+			return nil, fmt.Errorf("upper: invalid array literal %q", src)
+		}
+		i++
+	}
+}
+
+// scanArray reads the elements of a one-dimensional array from src. It
+// returns nil if src is NULL and a non-nil empty slice for an empty array.
+func scanArray(src interface{}) ([][]byte, error) {
+	switch v := src.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return parseArray(v)
+	case string:
+		return parseArray([]byte(v))
+	}
+	return nil, fmt.Errorf("upper: can't scan %T into an array", src)
+}
+
+// decodeBytea decodes a bytea value in its text representation, either the
+// hex format, like \x6869, or the escape format used before PostgreSQL 9.0,
+// like hi\000.
+func decodeBytea(src []byte) ([]byte, error) {
+	if bytes.HasPrefix(src, []byte(`\x`)) {
+		out := make([]byte, hex.DecodedLen(len(src)-2))
+		if _, err := hex.Decode(out, src[2:]); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	out := make([]byte, 0, len(src))
+	for i := 0; i < len(src); i++ {
+		if src[i] != '\\' {
+			out = append(out, src[i])
+			continue
+		}
+		if i+1 < len(src) && src[i+1] == '\\' {
+			out = append(out, '\\')
+			i++
+			continue
+		}
+		if i+3 >= len(src) {
+			return nil, fmt.Errorf("upper: invalid bytea %q", src)
+		}
+		b, err := strconv.ParseUint(string(src[i+1:i+4]), 8, 8)
+		if err != nil {
+			return nil, fmt.Errorf("upper: invalid bytea %q", src)
+		}
+		out = append(out, byte(b))
+		i += 3
+	}
+	return out, nil
+}
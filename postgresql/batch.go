@@ -0,0 +1,147 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// ErrBatchAborted is the error of the statements of a batch that were not
+// sent because a previous statement failed.
+var ErrBatchAborted = errors.New(`upper: batch aborted by a previous statement`)
+
+// compilable is satisfied by the statements of sqlbuilder, like Selector or
+// Inserter.
+type compilable interface {
+	Compile() (string, error)
+	Arguments() []interface{}
+}
+
+// BatchResult is the outcome of a statement sent with a Batch.
+type BatchResult struct {
+	// Result is the result of a statement queued with Queue, it's nil for
+	// statements queued with QueueQuery.
+	Result sql.Result
+
+	// Err is the error returned by the statement, or ErrBatchAborted if the
+	// statement was not sent because a previous one failed.
+	Err error
+}
+
+type batchItem struct {
+	query interface{}
+	args  []interface{}
+	dst   interface{}
+}
+
+// Batch collects statements that are sent together, in order, within a
+// single transaction. Statements can be either sqlbuilder statements, like
+// Inserter or Selector, or SQL strings with arguments. Statements built from
+// a session are sent with the batch's transaction, not with the session they
+// were built from.
+//
+//	results, err := sess.(postgresql.Database).Batch().
+//		Queue(sess.InsertInto("artist").Values(artist)).
+//		Queue(`UPDATE counters SET n = n + 1 WHERE name = ?`, "artist").
+//		QueueQuery(&artists, sess.SelectFrom("artist")).
+//		Send(ctx)
+//
+// The statements are currently sent one at a time; Send is the only place
+// that talks to the server, so it can switch to protocol-level pipelining
+// without changing the API.
+type Batch struct {
+	sess  *database
+	items []batchItem
+}
+
+// Queue adds a statement that does not return rows.
+func (b *Batch) Queue(query interface{}, args ...interface{}) *Batch {
+	b.items = append(b.items, batchItem{query: query, args: args})
+	return b
+}
+
+// QueueQuery adds a statement that returns rows, the rows are loaded into dst
+// as with sqlbuilder.Iterator.All.
+func (b *Batch) QueueQuery(dst interface{}, query interface{}, args ...interface{}) *Batch {
+	b.items = append(b.items, batchItem{query: query, args: args, dst: dst})
+	return b
+}
+
+// Len returns the number of queued statements.
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// Send runs the queued statements in order within a single transaction and
+// returns their results in the same order. If a statement fails the
+// transaction is rolled back, the statements that follow are not sent and
+// the error of the failed statement is returned along with the results.
+func (b *Batch) Send(ctx context.Context) ([]BatchResult, error) {
+	results := make([]BatchResult, len(b.items))
+	for i := range results {
+		results[i].Err = ErrBatchAborted
+	}
+
+	tx, err := b.sess.NewTx(ctx)
+	if err != nil {
+		return results, err
+	}
+	defer tx.Close()
+
+	for i, item := range b.items {
+		results[i].Result, results[i].Err = sendBatchItem(tx, item)
+		if results[i].Err != nil {
+			_ = tx.Rollback()
+			return results, results[i].Err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func sendBatchItem(tx sqlbuilder.Tx, item batchItem) (sql.Result, error) {
+	query, args := item.query, item.args
+	if c, ok := query.(compilable); ok {
+		compiled, err := c.Compile()
+		if err != nil {
+			return nil, err
+		}
+		query, args = compiled, append(c.Arguments(), args...)
+	}
+
+	if item.dst == nil {
+		return tx.Exec(query, args...)
+	}
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return nil, sqlbuilder.NewIterator(rows).All(item.dst)
+}
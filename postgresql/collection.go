@@ -22,10 +22,17 @@
 package postgresql
 
 import (
+	"reflect"
+
 	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/reflectx"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
+var mapper = reflectx.NewMapper("db")
+
 // collection is the actual implementation of a collection.
 type collection struct {
 	sqladapter.BaseCollection // Leveraged by sqladapter
@@ -59,6 +66,14 @@ func (c *collection) Database() sqladapter.Database {
 
 // Insert inserts an item (map or struct) into the collection.
 func (c *collection) Insert(item interface{}) (interface{}, error) {
+	id, err := c.insert(item)
+	if err == nil {
+		sqladapter.EmitInsert(c, id)
+	}
+	return id, err
+}
+
+func (c *collection) insert(item interface{}) (interface{}, error) {
 	pKey := c.BaseCollection.PrimaryKeys()
 
 	q := c.d.InsertInto(c.Name()).Values(item)
@@ -95,3 +110,89 @@ func (c *collection) Insert(item interface{}) (interface{}, error) {
 	// This was a compound key and no interface matched it, let's return a map.
 	return keyMap, nil
 }
+
+// InsertReturning inserts the struct item points to and writes the columns
+// the database generated back into it, using RETURNING, so there's no extra
+// query. The columns returned are the primary keys and the ones the struct
+// gave no value to: zero-valued fields tagged omitempty, which get the
+// column's default, like a serial or now(), and fields set to db.Default.
+// Maps are handled by sqladapter, which reads the inserted row back.
+func (c *collection) InsertReturning(item interface{}) error {
+	itemV := reflect.ValueOf(item)
+	if item == nil || itemV.Kind() != reflect.Ptr || itemV.Elem().Kind() != reflect.Struct {
+		return c.BaseCollection.InsertReturning(item)
+	}
+
+	columns, err := generatedColumns(item, c.BaseCollection.PrimaryKeys())
+	if err != nil {
+		return err
+	}
+
+	q := c.d.InsertInto(c.Name()).Values(item)
+	if len(columns) == 0 {
+		if _, err := q.Exec(); err != nil {
+			return err
+		}
+		sqladapter.EmitInsert(c, nil)
+		return nil
+	}
+
+	newItem := reflect.New(itemV.Elem().Type())
+	if err := q.Returning(columns...).Iterator().One(newItem.Interface()); err != nil {
+		return err
+	}
+
+	fields := mapper.TypeMap(newItem.Type()).Names
+	for _, column := range columns {
+		if fi, ok := fields[column]; ok {
+			reflectx.FieldByIndexes(itemV, fi.Index).Set(reflectx.FieldByIndexes(newItem, fi.Index))
+		}
+	}
+
+	sqladapter.EmitInsert(c, insertedID(itemV, fields, c.BaseCollection.PrimaryKeys()))
+	return nil
+}
+
+// insertedID returns the primary key of the struct itemV points to in the
+// form Insert returns it, or nil if the struct doesn't have it.
+func insertedID(itemV reflect.Value, fields map[string]*reflectx.FieldInfo, pKey []string) interface{} {
+	id := db.Cond{}
+	for _, column := range pKey {
+		fi, ok := fields[column]
+		if !ok {
+			return nil
+		}
+		id[column] = reflectx.FieldByIndexes(itemV, fi.Index).Interface()
+	}
+	if len(pKey) == 1 {
+		return id[pKey[0]]
+	}
+	return id
+}
+
+// generatedColumns returns the primary keys followed by the columns of item
+// that are left for the database to fill.
+func generatedColumns(item interface{}, pKey []string) ([]string, error) {
+	fields, values, err := sqlbuilder.Map(item, &sqlbuilder.MapOptions{IncludeZeroed: true, IncludeNil: true})
+	if err != nil {
+		return nil, err
+	}
+
+	columns := append([]string{}, pKey...)
+	seen := make(map[string]bool, len(fields))
+	for _, column := range pKey {
+		seen[column] = true
+	}
+
+	for i := range fields {
+		if seen[fields[i]] {
+			continue
+		}
+		switch values[i].(type) {
+		case *exql.Raw, db.RawValue:
+			columns = append(columns, fields[i])
+			seen[fields[i]] = true
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,44 @@
+package postgresql
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratedColumns(t *testing.T) {
+	type post struct {
+		ID        int64     `db:"id,omitempty"`
+		Title     string    `db:"title"`
+		Slug      string    `db:"slug,omitempty"`
+		CreatedAt time.Time `db:"created_at,omitempty"`
+		Views     *int64    `db:"views,omitempty"`
+		Rank      int64     `db:"rank"`
+	}
+
+	columns, err := generatedColumns(&post{Title: "Hello", Slug: "hello"}, []string{"id"})
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, columns) {
+		assert.Equal(t, "id", columns[0])
+		rest := columns[1:]
+		sort.Strings(rest)
+		assert.Equal(t, []string{"created_at", "views"}, rest)
+	}
+
+	// Primary keys are returned even if they're given.
+	columns, err = generatedColumns(&post{ID: 3, Title: "Hello", Slug: "hello", CreatedAt: time.Now()}, []string{"id"})
+	assert.NoError(t, err)
+	sort.Strings(columns)
+	assert.Equal(t, []string{"id", "views"}, columns)
+
+	type tag struct {
+		Name  string      `db:"name"`
+		Color interface{} `db:"color"`
+	}
+	columns, err = generatedColumns(tag{Name: "go", Color: db.Default}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"color"}, columns)
+}
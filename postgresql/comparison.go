@@ -0,0 +1,68 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	db "github.com/frazercomputing/upper-io-db"
+)
+
+// IsDistinctFrom indicates whether the reference is different from the given
+// value, treating NULL values as comparable.
+func IsDistinctFrom(v interface{}) db.Comparison {
+	return db.Op("IS DISTINCT FROM", v)
+}
+
+// IsNotDistinctFrom indicates whether the reference is equal to the given
+// value, treating NULL values as comparable.
+func IsNotDistinctFrom(v interface{}) db.Comparison {
+	return db.Op("IS NOT DISTINCT FROM", v)
+}
+
+// ILike indicates whether the reference matches the given wildcard pattern,
+// ignoring case.
+func ILike(v string) db.Comparison {
+	return db.ILike(v)
+}
+
+// NotILike indicates whether the reference does not match the given wildcard
+// pattern, ignoring case.
+func NotILike(v string) db.Comparison {
+	return db.NotILike(v)
+}
+
+// RegexpMatch indicates whether the reference matches the given POSIX regular
+// expression, using the ~* operator when caseInsensitive is true and ~
+// otherwise.
+func RegexpMatch(pattern string, caseInsensitive bool) db.Comparison {
+	if caseInsensitive {
+		return db.Op("~*", pattern)
+	}
+	return db.RegExp(pattern)
+}
+
+// NotRegexpMatch is the negated form of RegexpMatch.
+func NotRegexpMatch(pattern string, caseInsensitive bool) db.Comparison {
+	if caseInsensitive {
+		return db.Op("!~*", pattern)
+	}
+	return db.NotRegExp(pattern)
+}
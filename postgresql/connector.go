@@ -0,0 +1,240 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+)
+
+// ConnectInitFunc is a function that is called on every new physical
+// connection right after it's been established and before it is handed over
+// to the connection pool.
+type ConnectInitFunc func(ctx context.Context, conn *sql.Conn) error
+
+// connector is a driver.Connector that opens connections with the adapter's
+// driver and runs an optional initialization function on each one of them.
+type connector struct {
+	dsn    string
+	driver driver.Driver
+
+	connectInit atomic.Value
+	timeZone    atomic.Value
+
+	// generation is increased each time the connection initialization
+	// changes, connections made before that are discarded by the pool
+	// instead of being reused.
+	generation int64
+}
+
+var _ = driver.Connector(&connector{})
+
+func newConnector(dsn string) *connector {
+	return &connector{dsn: dsn, driver: newDriver()}
+}
+
+func (c *connector) setConnectInit(fn ConnectInitFunc) {
+	c.connectInit.Store(fn)
+	atomic.AddInt64(&c.generation, 1)
+}
+
+func (c *connector) getConnectInit() ConnectInitFunc {
+	if fn, ok := c.connectInit.Load().(ConnectInitFunc); ok {
+		return fn
+	}
+	return nil
+}
+
+func (c *connector) setTimeZone(name string) {
+	c.timeZone.Store(name)
+	atomic.AddInt64(&c.generation, 1)
+}
+
+func (c *connector) getTimeZone() string {
+	name, _ := c.timeZone.Load().(string)
+	return name
+}
+
+// Connect opens a new connection, sets its time zone and runs the
+// initialization function on it, if any.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	generation := atomic.LoadInt64(&c.generation)
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if tz := c.getTimeZone(); tz != "" {
+		if err := runConnectInit(ctx, conn, setTimeZone(tz)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if fn := c.getConnectInit(); fn != nil {
+		if err := runConnectInit(ctx, conn, fn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return &initConn{Conn: conn, connector: c, generation: generation}, nil
+}
+
+// setTimeZone returns a ConnectInitFunc that sets the TimeZone of the
+// connection, like SET TIME ZONE does.
+func setTimeZone(name string) ConnectInitFunc {
+	return func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, `SELECT set_config('TimeZone', $1, false)`, name)
+		return err
+	}
+}
+
+// Driver returns the underlying driver.
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// initConn is a connection opened by connector. The pool asks it whether it
+// can be reused, which it can't once the connection initialization changed,
+// so connections that were in use when SetConnectInit or SetTimeZone were
+// called are discarded once they're released. The optional interfaces of the
+// driver's connection are passed through.
+type initConn struct {
+	driver.Conn
+
+	connector  *connector
+	generation int64
+}
+
+func (c *initConn) stale() bool {
+	return atomic.LoadInt64(&c.connector.generation) != c.generation
+}
+
+// ResetSession is called by the pool before reusing the connection.
+func (c *initConn) ResetSession(ctx context.Context) error {
+	if c.stale() {
+		return driver.ErrBadConn
+	}
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid is called by the pool, on Go 1.15 and later, before reusing the
+// connection, even if it was not used since it was last reset.
+func (c *initConn) IsValid() bool {
+	if c.stale() {
+		return false
+	}
+	if v, ok := c.Conn.(interface{ IsValid() bool }); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *initConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *initConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("upper: the driver doesn't support transaction options")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *initConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *initConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *initConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *initConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// uncloseableConn prevents the temporary pool created by runConnectInit from
+// closing the connection that is being initialized.
+type uncloseableConn struct {
+	driver.Conn
+}
+
+func (uncloseableConn) Close() error {
+	return nil
+}
+
+// singleConnConnector is a driver.Connector that always returns the same
+// connection.
+type singleConnConnector struct {
+	conn driver.Conn
+}
+
+func (s singleConnConnector) Connect(context.Context) (driver.Conn, error) {
+	return uncloseableConn{s.conn}, nil
+}
+
+func (s singleConnConnector) Driver() driver.Driver {
+	return newDriver()
+}
+
+// runConnectInit wraps the given driver.Conn into a *sql.Conn and passes it to
+// fn.
+func runConnectInit(ctx context.Context, conn driver.Conn, fn ConnectInitFunc) error {
+	sess := sql.OpenDB(singleConnConnector{conn})
+	defer sess.Close()
+
+	sess.SetMaxOpenConns(1)
+
+	sqlConn, err := sess.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sqlConn.Close()
+
+	return fn(ctx, sqlConn)
+}
@@ -0,0 +1,56 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDriver struct{}
+
+func (testDriver) Open(string) (driver.Conn, error) {
+	return testConn{}, nil
+}
+
+type testConn struct{}
+
+func (testConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (testConn) Close() error {
+	return nil
+}
+
+func (testConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestConnectorDiscardsStaleConns(t *testing.T) {
+	c := &connector{driver: testDriver{}}
+
+	conn, err := c.Connect(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	ic := conn.(*initConn)
+	assert.NoError(t, ic.ResetSession(context.Background()))
+	assert.True(t, ic.IsValid())
+
+	// Connections made before the initialization changed can't be reused.
+	c.setConnectInit(nil)
+	assert.Equal(t, driver.ErrBadConn, ic.ResetSession(context.Background()))
+	assert.False(t, ic.IsValid())
+
+	conn, err = c.Connect(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, conn.(*initConn).IsValid())
+
+	c.setTimeZone("")
+	assert.False(t, conn.(*initConn).IsValid())
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"reflect"
+	"sync"
+)
+
+// typeRegistry maps types to the functions that wrap their values for the
+// driver.
+type typeRegistry struct {
+	mu sync.RWMutex
+	m  map[reflect.Type]func(interface{}) interface{}
+}
+
+var converters = &typeRegistry{}
+
+// RegisterType makes ConvertValues pass values of the type of sample through
+// wrap, so types this package doesn't know about can be written and read
+// without wrapping them by hand:
+//
+//	postgresql.RegisterType(decimal.Decimal{}, func(v interface{}) interface{} {
+//		switch v := v.(type) {
+//		case decimal.Decimal:
+//			return myDecimalValuer(v)
+//		case *decimal.Decimal:
+//			return (*myDecimalScanner)(v)
+//		}
+//		return v
+//	})
+//
+// wrap is called with values of the type of sample when they're used as
+// arguments, and must return a driver.Valuer or a value the driver accepts.
+// It's also called with pointers to that type when they're used as scan
+// destinations, and must return an sql.Scanner that writes to them.
+//
+// The registry is global to the process and safe for concurrent use,
+// registering a type again replaces its previous wrap function and a nil wrap
+// removes it. Values are converted by the first of these that knows their
+// type:
+//
+//  1. Types registered on the session with Database.RegisterType.
+//  2. Types registered with RegisterType.
+//  3. The types this package handles, like []int64 or *big.Rat.
+//  4. JSONB, for any other slice or map.
+func RegisterType(sample interface{}, wrap func(interface{}) interface{}) {
+	converters.register(sample, wrap)
+}
+
+func (r *typeRegistry) register(sample interface{}, wrap func(interface{}) interface{}) {
+	t := reflect.TypeOf(sample)
+	if t == nil {
+		panic(`postgresql.RegisterType() called with a nil sample`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if wrap == nil {
+		delete(r.m, t)
+		return
+	}
+	if r.m == nil {
+		r.m = make(map[reflect.Type]func(interface{}) interface{})
+	}
+	r.m[t] = wrap
+}
+
+// lookup returns the function registered for t, or for the type t points to.
+func (r *typeRegistry) lookup(t reflect.Type) func(interface{}) interface{} {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.m) == 0 {
+		return nil
+	}
+	if wrap, ok := r.m[t]; ok {
+		return wrap
+	}
+	if t.Kind() == reflect.Ptr {
+		return r.m[t.Elem()]
+	}
+	return nil
+}
+
+// copy returns a registry with the same types as r.
+func (r *typeRegistry) copy() *typeRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c := &typeRegistry{}
+	if len(r.m) > 0 {
+		c.m = make(map[reflect.Type]func(interface{}) interface{}, len(r.m))
+		for t, wrap := range r.m {
+			c.m[t] = wrap
+		}
+	}
+	return c
+}
+
+// convertRegistered wraps v using the function registered for its type on
+// the session or globally. It returns false if there is none.
+func (d *database) convertRegistered(v interface{}) (interface{}, bool) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, false
+	}
+	wrap := d.types.lookup(t)
+	if wrap == nil {
+		wrap = converters.lookup(t)
+	}
+	if wrap == nil {
+		return nil, false
+	}
+	return wrap(v), true
+}
+
+func (d *database) RegisterType(sample interface{}, wrap func(interface{}) interface{}) {
+	d.types.register(sample, wrap)
+}
@@ -0,0 +1,119 @@
+package postgresql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTemperature struct {
+	Celsius float64
+}
+
+type testTemperatureValuer testTemperature
+
+func (t testTemperatureValuer) Value() (driver.Value, error) {
+	return strconv.FormatFloat(t.Celsius, 'f', -1, 64), nil
+}
+
+type testTemperatureScanner testTemperature
+
+func (t *testTemperatureScanner) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("can't scan %T", src)
+	}
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return err
+	}
+	t.Celsius = f
+	return nil
+}
+
+func wrapTestTemperature(v interface{}) interface{} {
+	switch v := v.(type) {
+	case testTemperature:
+		return testTemperatureValuer(v)
+	case *testTemperature:
+		return (*testTemperatureScanner)(v)
+	}
+	return v
+}
+
+func TestRegisterType(t *testing.T) {
+	d := &database{}
+
+	temp := testTemperature{Celsius: 21.5}
+
+	values := d.ConvertValues([]interface{}{temp, &temp})
+	assert.Equal(t, temp, values[0])
+	assert.Equal(t, &temp, values[1])
+
+	RegisterType(testTemperature{}, wrapTestTemperature)
+	defer RegisterType(testTemperature{}, nil)
+
+	values = d.ConvertValues([]interface{}{temp, &temp, 5})
+	assert.Equal(t, testTemperatureValuer(temp), values[0])
+	assert.Equal(t, int(5), values[2])
+
+	if assert.IsType(t, &testTemperatureScanner{}, values[1]) {
+		assert.NoError(t, values[1].(*testTemperatureScanner).Scan([]byte("-3.25")))
+		assert.Equal(t, -3.25, temp.Celsius)
+	}
+
+	v, err := values[0].(driver.Valuer).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "21.5", v)
+
+	RegisterType(testTemperature{}, nil)
+	values = d.ConvertValues([]interface{}{temp})
+	assert.Equal(t, temp, values[0])
+
+	assert.Panics(t, func() {
+		RegisterType(nil, wrapTestTemperature)
+	})
+}
+
+func TestSessionRegisterType(t *testing.T) {
+	d := newDatabase(nil)
+
+	temp := testTemperature{Celsius: 21.5}
+
+	RegisterType(testTemperature{}, func(v interface{}) interface{} {
+		return "global"
+	})
+	defer RegisterType(testTemperature{}, nil)
+
+	values := d.ConvertValues([]interface{}{temp})
+	assert.Equal(t, "global", values[0])
+
+	d.RegisterType(testTemperature{}, wrapTestTemperature)
+
+	other := newDatabase(nil)
+	values = other.ConvertValues([]interface{}{temp})
+	assert.Equal(t, "global", values[0])
+
+	values = d.ConvertValues([]interface{}{temp, &temp})
+	assert.Equal(t, testTemperatureValuer(temp), values[0])
+	assert.IsType(t, &testTemperatureScanner{}, values[1])
+
+	// A session's types take precedence over the ones this package handles.
+	d.RegisterType(int64(0), func(v interface{}) interface{} {
+		return v.(int64) * 2
+	})
+	values = d.ConvertValues([]interface{}{int64(2)})
+	assert.Equal(t, int64(4), values[0])
+
+	c := &database{types: d.types.copy()}
+	d.RegisterType(testTemperature{}, nil)
+
+	values = d.ConvertValues([]interface{}{temp})
+	assert.Equal(t, "global", values[0])
+
+	values = c.ConvertValues([]interface{}{temp})
+	assert.Equal(t, testTemperatureValuer(temp), values[0])
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+	"github.com/lib/pq"
+)
+
+// CopyOptions configures the CSV output of CopyTo.
+type CopyOptions struct {
+	// Header writes the names of the columns on the first line.
+	Header bool
+
+	// Delimiter separates columns, it defaults to ",".
+	Delimiter rune
+
+	// Null is the text that represents NULL values, it defaults to an empty
+	// string.
+	Null string
+}
+
+// copyStatement wraps query into a COPY ... TO STDOUT statement.
+func copyStatement(query string, opts CopyOptions) string {
+	options := []string{"FORMAT csv"}
+	if opts.Header {
+		options = append(options, "HEADER true")
+	}
+	if opts.Delimiter != 0 {
+		options = append(options, "DELIMITER "+pq.QuoteLiteral(string(opts.Delimiter)))
+	}
+	if opts.Null != "" {
+		options = append(options, "NULL "+pq.QuoteLiteral(opts.Null))
+	}
+	return "COPY (" + query + ") TO STDOUT WITH (" + strings.Join(options, ", ") + ")"
+}
+
+// inlineArgs replaces the ? placeholders of query with the literal values of
+// args, as COPY does not take parameters. "??" stands for a literal "?", as
+// in any other statement, and question marks within strings, comments and
+// dollar-quoted strings are not placeholders.
+func inlineArgs(query string, args []interface{}) (string, error) {
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if l := exql.LiteralLen(query, i); l > 0 {
+			b.WriteString(query[i : i+l])
+			i += l - 1
+			continue
+		}
+		if query[i] != '?' {
+			b.WriteByte(query[i])
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == '?' {
+			b.WriteByte('?')
+			i++
+			continue
+		}
+		if n >= len(args) {
+			return "", fmt.Errorf("upper: missing argument for placeholder %d", n+1)
+		}
+		lit, err := quoteLiteral(args[n])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(lit)
+		n++
+	}
+	return b.String(), nil
+}
+
+// quoteLiteral returns v as a SQL literal.
+func quoteLiteral(v interface{}) (string, error) {
+	v, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case []byte:
+		return `'\x` + hex.EncodeToString(v) + `'::bytea`, nil
+	case string:
+		return pq.QuoteLiteral(v), nil
+	case time.Time:
+		return pq.QuoteLiteral(v.Format("2006-01-02 15:04:05.999999999Z07:00")), nil
+	}
+	return "", fmt.Errorf("upper: can't use %T as a literal", v)
+}
+
+// copyText returns the text PostgreSQL's COPY would write for v, a value read
+// from a column of type bytea if isBytea is set.
+func copyText(v interface{}, isBytea bool) sql.NullString {
+	switch v := v.(type) {
+	case nil:
+		return sql.NullString{}
+	case []byte:
+		if isBytea {
+			return sql.NullString{String: `\x` + hex.EncodeToString(v), Valid: true}
+		}
+		return sql.NullString{String: string(v), Valid: true}
+	case string:
+		return sql.NullString{String: v, Valid: true}
+	case bool:
+		if v {
+			return sql.NullString{String: "t", Valid: true}
+		}
+		return sql.NullString{String: "f", Valid: true}
+	case int64:
+		return sql.NullString{String: strconv.FormatInt(v, 10), Valid: true}
+	case float64:
+		return sql.NullString{String: strconv.FormatFloat(v, 'g', -1, 64), Valid: true}
+	case time.Time:
+		return sql.NullString{String: v.Format("2006-01-02 15:04:05.999999Z07:00"), Valid: true}
+	}
+	return sql.NullString{Valid: true}
+}
+
+// copyWriter writes records as CSV the way PostgreSQL's COPY does: NULL
+// values are written as the NULL marker, and values that equal the marker or
+// that hold the delimiter, a quote or a line break are quoted. With the
+// default marker this tells NULL apart from the empty string, which is
+// written as "".
+type copyWriter struct {
+	w     *bufio.Writer
+	comma string
+	null  string
+}
+
+func newCopyWriter(w io.Writer, opts CopyOptions) *copyWriter {
+	cw := &copyWriter{w: bufio.NewWriter(w), comma: ",", null: opts.Null}
+	if opts.Delimiter != 0 {
+		cw.comma = string(opts.Delimiter)
+	}
+	return cw
+}
+
+// write writes a record, fields that are not Valid are NULL.
+func (cw *copyWriter) write(record []sql.NullString) error {
+	for i, field := range record {
+		if i > 0 {
+			cw.w.WriteString(cw.comma)
+		}
+		switch {
+		case !field.Valid:
+			cw.w.WriteString(cw.null)
+		case field.String == cw.null || strings.ContainsAny(field.String, cw.comma+"\"\r\n"):
+			cw.w.WriteString(`"` + strings.Replace(field.String, `"`, `""`, -1) + `"`)
+		default:
+			cw.w.WriteString(field.String)
+		}
+	}
+	_, err := cw.w.WriteString("\n")
+	return err
+}
+
+// flush writes any buffered data to the underlying writer.
+func (cw *copyWriter) flush() error {
+	return cw.w.Flush()
+}
+
+// CopyTo writes the rows of query to w as CSV.
+func (d *database) CopyTo(ctx context.Context, w io.Writer, query sqlbuilder.Selector, opts CopyOptions) (int64, error) {
+	sess := d.Session()
+	if sess == nil {
+		return 0, db.ErrNotConnected
+	}
+
+	q, ok := query.(compilable)
+	if !ok {
+		return 0, db.ErrUnsupported
+	}
+	compiled, err := q.Compile()
+	if err != nil {
+		return 0, err
+	}
+	compiled, args := sqlbuilder.PreprocessWithTemplate(template, compiled, q.Arguments())
+	args = d.BaseDatabase.ConvertValues(args)
+
+	conn, err := sess.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	n, err := copyOut(ctx, conn, compiled, args, opts, w)
+	if err != nil {
+		return n, d.Err(err)
+	}
+	return n, nil
+}
@@ -0,0 +1,117 @@
+package postgresql
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyStatement(t *testing.T) {
+	assert.Equal(t,
+		`COPY (SELECT * FROM "artist") TO STDOUT WITH (FORMAT csv)`,
+		copyStatement(`SELECT * FROM "artist"`, CopyOptions{}),
+	)
+	assert.Equal(t,
+		`COPY (SELECT 1) TO STDOUT WITH (FORMAT csv, HEADER true, DELIMITER ';', NULL '\N')`,
+		copyStatement(`SELECT 1`, CopyOptions{Header: true, Delimiter: ';', Null: `\N`}),
+	)
+}
+
+func TestInlineArgs(t *testing.T) {
+	q, err := inlineArgs(
+		`SELECT * FROM "artist" WHERE name = ? AND id IN (?, ?) AND data = ? AND ok = ? AND x IS ? AND y ?? 'k' AND t = ?`,
+		[]interface{}{"O'Brien", 1, 2.5, []byte{0xca, 0xfe}, true, nil, time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`SELECT * FROM "artist" WHERE name = 'O''Brien' AND id IN (1, 2.5) AND data = '\xcafe'::bytea AND ok = TRUE AND x IS NULL AND y ? 'k' AND t = '2019-01-02 03:04:05Z'`,
+		q,
+	)
+
+	_, err = inlineArgs(`SELECT ?, ?`, []interface{}{1})
+	assert.Error(t, err)
+
+	_, err = inlineArgs(`SELECT ?`, []interface{}{struct{}{}})
+	assert.Error(t, err)
+}
+
+// readCopyRecord parses a line written by copyWriter, unquoted fields that
+// equal null are NULL.
+func readCopyRecord(line string, null string) []sql.NullString {
+	var record []sql.NullString
+	for {
+		var field sql.NullString
+		if strings.HasPrefix(line, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(line); i++ {
+				if line[i] == '"' {
+					if i+1 < len(line) && line[i+1] == '"' {
+						b.WriteByte('"')
+						i++
+						continue
+					}
+					break
+				}
+				b.WriteByte(line[i])
+			}
+			field = sql.NullString{String: b.String(), Valid: true}
+			line = line[i+1:]
+		} else {
+			end := strings.IndexByte(line, ',')
+			if end < 0 {
+				end = len(line)
+			}
+			if line[:end] != null {
+				field = sql.NullString{String: line[:end], Valid: true}
+			}
+			line = line[end:]
+		}
+		record = append(record, field)
+		if line == "" {
+			return record
+		}
+		line = line[1:]
+	}
+}
+
+func TestCopyWriter(t *testing.T) {
+	data := []byte{0x00, 0xca, 0xfe, '"', ','}
+	row := []interface{}{data, nil, "", `say "hi", bye`, int64(1)}
+	isBytea := []bool{true, false, false, false, false}
+
+	for _, null := range []string{"", `\N`} {
+		var buf bytes.Buffer
+		cw := newCopyWriter(&buf, CopyOptions{Null: null})
+		record := make([]sql.NullString, len(row))
+		for i := range row {
+			record[i] = copyText(row[i], isBytea[i])
+		}
+		assert.NoError(t, cw.write(record))
+		assert.NoError(t, cw.flush())
+
+		line := strings.TrimSuffix(buf.String(), "\n")
+		got := readCopyRecord(line, null)
+		assert.Equal(t, record, got)
+
+		assert.True(t, strings.HasPrefix(got[0].String, `\x`))
+		decoded, err := hex.DecodeString(strings.TrimPrefix(got[0].String, `\x`))
+		assert.NoError(t, err)
+		assert.Equal(t, data, decoded)
+
+		assert.False(t, got[1].Valid)
+		assert.Equal(t, sql.NullString{String: "", Valid: true}, got[2])
+		assert.Equal(t, `say "hi", bye`, got[3].String)
+	}
+
+	var buf bytes.Buffer
+	cw := newCopyWriter(&buf, CopyOptions{})
+	assert.NoError(t, cw.write([]sql.NullString{{}, {Valid: true}, {String: "a", Valid: true}}))
+	assert.NoError(t, cw.flush())
+	assert.Equal(t, ",\"\",a\n", buf.String())
+}
@@ -26,15 +26,25 @@ import (
 	"encoding/json"
 	"errors"
 	"reflect"
+	"strconv"
 
 	"github.com/lib/pq"
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
-// Array returns a sqlbuilder.ScannerValuer for any given slice. Slice elements
-// may require their own sqlbuilder.ScannerValuer.
+// Array wraps any slice, or a pointer to one, into a GenericArray, so it can
+// be passed as a PostgreSQL array argument or scanned from an array column:
+//
+//	sess.Exec(`INSERT INTO t (grid) VALUES (?)`, postgresql.Array([][]int{{1, 2}, {3, 4}}))
+//
+//	var tags []Tag
+//	row.Scan(postgresql.Array(&tags))
+//
+// Slices of slices map to multi-dimensional arrays, elements that satisfy
+// driver.Valuer are written using their own values and elements that satisfy
+// sql.Scanner are read with it.
 func Array(in interface{}) sqlbuilder.ScannerValuer {
-	return pq.Array(in)
+	return &GenericArray{A: in}
 }
 
 // JSONB represents a PostgreSQL's JSONB value:
@@ -174,15 +184,174 @@ func (b *BoolArray) Scan(src interface{}) error {
 	return nil
 }
 
-// GenericArray represents a one-dimensional array of any type
-// (`[]interface{}`) that is compatible with PostgreSQL's array type.
-// GenericArray satisfies sqlbuilder.ScannerValuer and its elements may need to
-// satisfy sqlbuilder.ScannerValuer too.
+// NullInt64Array represents a one-dimensional array of nullable int64s
+// (`[]*int64{}`) that is compatible with PostgreSQL's integer array
+// (`integer[]`). A nil element is a NULL element and a nil array is a NULL
+// value, an empty array is written and read as `{}`. NullInt64Array satisfies
+// sqlbuilder.ScannerValuer.
+type NullInt64Array []*int64
+
+// Value satisfies the driver.Valuer interface.
+func (a NullInt64Array) Value() (driver.Value, error) {
+	return arrayLiteral([]*int64(a))
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (a *NullInt64Array) Scan(src interface{}) error {
+	elems, err := scanArray(src)
+	if err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+	out := make(NullInt64Array, len(elems))
+	for i := range elems {
+		if elems[i] == nil {
+			continue
+		}
+		n, err := strconv.ParseInt(string(elems[i]), 10, 64)
+		if err != nil {
+			return err
+		}
+		out[i] = &n
+	}
+	*a = out
+	return nil
+}
+
+// NullStringArray represents a one-dimensional array of nullable strings
+// (`[]*string{}`) that is compatible with PostgreSQL's text array
+// (`text[]`). See NullInt64Array.
+type NullStringArray []*string
+
+// Value satisfies the driver.Valuer interface.
+func (a NullStringArray) Value() (driver.Value, error) {
+	return arrayLiteral([]*string(a))
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (a *NullStringArray) Scan(src interface{}) error {
+	elems, err := scanArray(src)
+	if err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+	out := make(NullStringArray, len(elems))
+	for i := range elems {
+		if elems[i] == nil {
+			continue
+		}
+		s := string(elems[i])
+		out[i] = &s
+	}
+	*a = out
+	return nil
+}
+
+// NullFloat64Array represents a one-dimensional array of nullable float64s
+// (`[]*float64{}`) that is compatible with PostgreSQL's double precision
+// array (`double precision[]`). See NullInt64Array.
+type NullFloat64Array []*float64
+
+// Value satisfies the driver.Valuer interface.
+func (a NullFloat64Array) Value() (driver.Value, error) {
+	return arrayLiteral([]*float64(a))
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (a *NullFloat64Array) Scan(src interface{}) error {
+	elems, err := scanArray(src)
+	if err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+	out := make(NullFloat64Array, len(elems))
+	for i := range elems {
+		if elems[i] == nil {
+			continue
+		}
+		f, err := strconv.ParseFloat(string(elems[i]), 64)
+		if err != nil {
+			return err
+		}
+		out[i] = &f
+	}
+	*a = out
+	return nil
+}
+
+// NullBoolArray represents a one-dimensional array of nullable bools
+// (`[]*bool{}`) that is compatible with PostgreSQL's boolean array
+// (`boolean[]`). See NullInt64Array.
+type NullBoolArray []*bool
+
+// Value satisfies the driver.Valuer interface.
+func (a NullBoolArray) Value() (driver.Value, error) {
+	return arrayLiteral([]*bool(a))
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (a *NullBoolArray) Scan(src interface{}) error {
+	elems, err := scanArray(src)
+	if err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+	out := make(NullBoolArray, len(elems))
+	for i := range elems {
+		if elems[i] == nil {
+			continue
+		}
+		b, err := strconv.ParseBool(string(elems[i]))
+		if err != nil {
+			return err
+		}
+		out[i] = &b
+	}
+	*a = out
+	return nil
+}
+
+// ByteaArray represents a one-dimensional array of byte slices
+// (`[][]byte{}`) that is compatible with PostgreSQL's bytea array
+// (`bytea[]`). A nil element is a NULL element and a nil array is a NULL
+// value. ByteaArray satisfies sqlbuilder.ScannerValuer.
+type ByteaArray [][]byte
+
+// Value satisfies the driver.Valuer interface.
+func (a ByteaArray) Value() (driver.Value, error) {
+	return arrayLiteral([][]byte(a))
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (a *ByteaArray) Scan(src interface{}) error {
+	elems, err := scanArray(src)
+	if err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+	out := make(ByteaArray, len(elems))
+	for i := range elems {
+		if elems[i] == nil {
+			continue
+		}
+		if out[i], err = decodeBytea(elems[i]); err != nil {
+			return err
+		}
+	}
+	*a = out
+	return nil
+}
+
+// GenericArray represents an array of any type that is compatible with
+// PostgreSQL's array type, A must be a slice or a pointer to one. Slices of
+// slices, like [][]int, represent multi-dimensional arrays. GenericArray
+// satisfies sqlbuilder.ScannerValuer and its elements may need to satisfy
+// sqlbuilder.ScannerValuer too. See Array.
 type GenericArray pq.GenericArray
 
 // Value satisfies the driver.Valuer interface.
 func (g GenericArray) Value() (driver.Value, error) {
-	return pq.GenericArray(g).Value()
+	return arrayLiteral(g.A)
 }
 
 // Scan satisfies the sql.Scanner interface.
@@ -301,6 +470,14 @@ func autoWrap(elem reflect.Value, v interface{}) interface{} {
 	case reflect.Ptr:
 		return autoWrap(elem.Elem(), v)
 	case reflect.Slice:
+		if elem.Type().Elem().Kind() == reflect.Uint8 {
+			// Named byte slices are bytea, not JSONB. Pointers to them are
+			// scanned by database/sql.
+			if reflect.TypeOf(v).Kind() == reflect.Ptr {
+				return v
+			}
+			return elem.Convert(byteSliceType).Interface()
+		}
 		return &JSONB{v}
 	case reflect.Map:
 		if reflect.TypeOf(v).Kind() == reflect.Ptr {
@@ -323,6 +500,11 @@ var (
 	_ sqlbuilder.ScannerValuer = &Float64Array{}
 	_ sqlbuilder.ScannerValuer = &BoolArray{}
 	_ sqlbuilder.ScannerValuer = &GenericArray{}
+	_ sqlbuilder.ScannerValuer = &NullInt64Array{}
+	_ sqlbuilder.ScannerValuer = &NullStringArray{}
+	_ sqlbuilder.ScannerValuer = &NullFloat64Array{}
+	_ sqlbuilder.ScannerValuer = &NullBoolArray{}
+	_ sqlbuilder.ScannerValuer = &ByteaArray{}
 	_ sqlbuilder.ScannerValuer = &JSONBMap{}
 	_ sqlbuilder.ScannerValuer = &JSONBArray{}
 )
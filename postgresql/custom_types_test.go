@@ -1,9 +1,16 @@
 package postgresql
 
 import (
+	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -85,3 +92,346 @@ func TestScanJSONB(t *testing.T) {
 		assert.Equal(t, 12.34, a[0].V.V)
 	}
 }
+
+func TestNumeric(t *testing.T) {
+	for _, s := range []string{"0", "-1", "123.45", "-0.000001", "12345678901234567890.123456789012345678", "1000000000000000000000000"} {
+		var n Numeric
+		assert.NoError(t, n.Scan([]byte(s)))
+		v, err := n.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, s, v)
+	}
+
+	{
+		v, err := (*Numeric)(big.NewRat(1, 1e12)).Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "0.000000000001", v)
+	}
+
+	{
+		_, err := (*Numeric)(big.NewRat(1, 3)).Value()
+		assert.Equal(t, ErrInexactNumeric, err)
+	}
+
+	{
+		v, err := (*Numeric)(nil).Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+	}
+
+	{
+		n := (*Numeric)(big.NewRat(5, 1))
+		assert.NoError(t, n.Scan(nil))
+		assert.Equal(t, "0", n.String())
+		assert.Error(t, n.Scan([]byte("NaN")))
+	}
+
+	{
+		r := big.NewRat(5, 1)
+		assert.NoError(t, nullNumeric{&r}.Scan(nil))
+		assert.Nil(t, r)
+		assert.NoError(t, nullNumeric{&r}.Scan("-1.5"))
+		assert.Equal(t, "-3/2", r.String())
+	}
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		in       string
+		interval Interval
+		out      string
+	}{
+		{"00:00:00", Interval{}, "00:00:00"},
+		{"1 day 02:03:04", Interval{Days: 1, Microseconds: 7384000000}, "1 days 02:03:04"},
+		{"1 mon", Interval{Months: 1}, "1 mons 00:00:00"},
+		{"1 year 2 mons -3 days +04:05:06.789", Interval{Months: 14, Days: -3, Microseconds: 14706789000}, "14 mons -3 days 04:05:06.789000"},
+		{"-1 years -00:00:00.000001", Interval{Months: -12, Microseconds: -1}, "-12 mons -00:00:00.000001"},
+		{"100:00:00", Interval{Microseconds: 360000000000}, "100:00:00"},
+	}
+
+	for _, test := range tests {
+		var i Interval
+		if assert.NoError(t, i.Scan([]byte(test.in)), test.in) {
+			assert.Equal(t, test.interval, i, test.in)
+			assert.Equal(t, test.out, i.String())
+
+			var j Interval
+			assert.NoError(t, j.Scan(test.out))
+			assert.Equal(t, i, j)
+		}
+	}
+
+	{
+		var i Interval
+		assert.Error(t, i.Scan("1 fortnight"))
+		assert.Error(t, i.Scan("1"))
+		assert.NoError(t, i.Scan(nil))
+		assert.Equal(t, Interval{}, i)
+	}
+
+	{
+		d, err := Interval{Days: 1, Microseconds: 1500000}.Duration()
+		assert.NoError(t, err)
+		assert.Equal(t, 24*time.Hour+1500*time.Millisecond, d)
+
+		_, err = Interval{Months: 1}.Duration()
+		assert.Equal(t, ErrAmbiguousInterval, err)
+
+		assert.Equal(t, Interval{Microseconds: 90000000}, IntervalFromDuration(90*time.Second))
+	}
+
+	{
+		var d time.Duration
+		assert.NoError(t, durationScanner{&d}.Scan([]byte("2 days 00:00:01")))
+		assert.Equal(t, 48*time.Hour+time.Second, d)
+		assert.NoError(t, durationScanner{&d}.Scan(int64(time.Minute)))
+		assert.Equal(t, time.Minute, d)
+	}
+}
+
+func TestInet(t *testing.T) {
+	tests := []struct {
+		in   string
+		ip   string
+		ones int
+		bits int
+	}{
+		{"192.168.0.10", "192.168.0.10", 32, 32},
+		{"192.168.0.10/24", "192.168.0.10", 24, 32},
+		{"10.0.0.0/8", "10.0.0.0", 8, 32},
+		{"::1", "::1", 128, 128},
+		{"2001:db8::/32", "2001:db8::", 32, 128},
+		{"2001:db8::5/64", "2001:db8::5", 64, 128},
+	}
+
+	for _, test := range tests {
+		var n Inet
+		if assert.NoError(t, n.Scan([]byte(test.in)), test.in) {
+			assert.Equal(t, test.ip, n.IP.String(), test.in)
+			ones, bits := n.Mask.Size()
+			assert.Equal(t, test.ones, ones, test.in)
+			assert.Equal(t, test.bits, bits, test.in)
+
+			v, err := n.Value()
+			assert.NoError(t, err)
+			assert.Equal(t, test.in, v)
+		}
+	}
+
+	{
+		var n Inet
+		assert.Error(t, n.Scan("300.1.1.1"))
+		assert.Error(t, n.Scan("10.0.0.0/33"))
+		assert.NoError(t, n.Scan(nil))
+		assert.Equal(t, Inet{}, n)
+
+		v, err := n.Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+	}
+
+	{
+		var ip net.IP
+		assert.NoError(t, (*inetIP)(&ip).Scan("192.168.0.10/24"))
+		assert.Equal(t, "192.168.0.10", ip.String())
+	}
+}
+
+func TestMAC(t *testing.T) {
+	var m MAC
+	assert.NoError(t, m.Scan([]byte("08:00:2b:01:02:03")))
+	assert.Equal(t, "08:00:2b:01:02:03", m.String())
+
+	v, err := m.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "08:00:2b:01:02:03", v)
+
+	assert.NoError(t, m.Scan("08:00:2b:01:02:03:04:05"))
+	assert.Equal(t, "08:00:2b:01:02:03:04:05", m.String())
+
+	assert.Error(t, m.Scan("not a mac"))
+
+	assert.NoError(t, m.Scan(nil))
+	assert.Nil(t, m)
+	v, err = m.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+type testEnum string
+
+type testPoint struct {
+	X, Y int
+}
+
+func (p testPoint) Value() (driver.Value, error) {
+	return fmt.Sprintf("(%d,%d)", p.X, p.Y), nil
+}
+
+type testCelsius float64
+
+func (c *testCelsius) Value() (driver.Value, error) {
+	return fmt.Sprintf("%.1fC", float64(*c)), nil
+}
+
+func TestArray(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out driver.Value
+	}{
+		{[]int{1, 2, 3}, `{1,2,3}`},
+		{[]int{}, `{}`},
+		{[]int(nil), nil},
+		{[][]int{{1, 2}, {3, 4}}, `{{1,2},{3,4}}`},
+		{[][][]string{{{"a"}, {"b"}}}, `{{{"a"},{"b"}}}`},
+		{[]string{`a "quoted", \string`, ""}, `{"a \"quoted\", \\string",""}`},
+		{[]testEnum{"on", "off"}, `{"on","off"}`},
+		{[]*string{nil}, `{NULL}`},
+		{[]bool{true, false}, `{true,false}`},
+		{[]float64{1.5, -2}, `{1.5,-2}`},
+		{[][]byte{{0xde, 0xad}}, `{"\\xdead"}`},
+		{[]testPoint{{1, 2}, {3, 4}}, `{"(1,2)","(3,4)"}`},
+		{[]testCelsius{21.5}, `{"21.5C"}`},
+		{[2]int{5, 6}, `{5,6}`},
+		{&[]int{7}, `{7}`},
+	}
+
+	for _, test := range tests {
+		v, err := Array(test.in).Value()
+		if assert.NoError(t, err) {
+			assert.Equal(t, test.out, v)
+		}
+	}
+
+	_, err := Array(5).Value()
+	assert.Error(t, err)
+
+	_, err = Array([]struct{}{{}}).Value()
+	assert.Error(t, err)
+}
+
+func TestNullArrays(t *testing.T) {
+	one, three := int64(1), int64(3)
+	a, b := "a", `"NULL"`
+
+	tests := []struct {
+		in  sqlbuilder.ScannerValuer
+		out driver.Value
+	}{
+		{&NullInt64Array{&one, nil, &three}, `{1,NULL,3}`},
+		{&NullInt64Array{nil}, `{NULL}`},
+		{&NullInt64Array{}, `{}`},
+		{&NullStringArray{&a, nil, &b}, `{"a",NULL,"\"NULL\""}`},
+		{&NullStringArray{}, `{}`},
+	}
+
+	for _, test := range tests {
+		v, err := test.in.Value()
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, test.out, v)
+
+		out := reflect.New(reflect.TypeOf(test.in).Elem()).Interface().(sqlbuilder.ScannerValuer)
+		if assert.NoError(t, out.Scan([]byte(v.(string)))) {
+			assert.Equal(t, test.in, out)
+		}
+	}
+
+	{
+		var a NullInt64Array
+		v, err := a.Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+
+		a = NullInt64Array{&one}
+		assert.NoError(t, a.Scan(nil))
+		assert.Nil(t, a)
+
+		assert.NoError(t, a.Scan("{}"))
+		assert.NotNil(t, a)
+		assert.Len(t, a, 0)
+
+		assert.NoError(t, a.Scan("{NULL}"))
+		assert.Equal(t, NullInt64Array{nil}, a)
+
+		assert.Error(t, a.Scan("{{1},{2}}"))
+		assert.Error(t, a.Scan("{a}"))
+		assert.Error(t, a.Scan("1,2"))
+	}
+
+	{
+		var f NullFloat64Array
+		assert.NoError(t, f.Scan([]byte("{1.5,NULL}")))
+		if assert.Len(t, f, 2) {
+			assert.Equal(t, 1.5, *f[0])
+			assert.Nil(t, f[1])
+		}
+
+		var b NullBoolArray
+		assert.NoError(t, b.Scan([]byte("{t,f,NULL}")))
+		if assert.Len(t, b, 3) {
+			assert.True(t, *b[0])
+			assert.False(t, *b[1])
+			assert.Nil(t, b[2])
+		}
+
+		var s NullStringArray
+		assert.NoError(t, s.Scan([]byte(`{"a,b","back\\slash",""}`)))
+		if assert.Len(t, s, 3) {
+			assert.Equal(t, "a,b", *s[0])
+			assert.Equal(t, `back\slash`, *s[1])
+			assert.Equal(t, "", *s[2])
+		}
+	}
+}
+
+func TestByteaArray(t *testing.T) {
+	a := ByteaArray{[]byte("hi"), nil, {}, {0, '\\', '"'}}
+
+	v, err := a.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"\\x6869",NULL,"\\x","\\x005c22"}`, v)
+
+	var out ByteaArray
+	if assert.NoError(t, out.Scan([]byte(v.(string)))) {
+		assert.Equal(t, a, out)
+	}
+
+	// Servers with bytea_output set to escape.
+	assert.NoError(t, out.Scan(`{"hi\\000","a\\\\b"}`))
+	assert.Equal(t, ByteaArray{[]byte("hi\x00"), []byte(`a\b`)}, out)
+
+	assert.NoError(t, out.Scan(nil))
+	assert.Nil(t, out)
+
+	assert.Error(t, out.Scan(`{"\\xzz"}`))
+	assert.Error(t, out.Scan(`{"\\9"}`))
+
+	v, err = ByteaArray(nil).Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestConvertByteSlices(t *testing.T) {
+	type digest []byte
+
+	d := &database{}
+
+	var (
+		data    = []byte("data")
+		sum     = digest("sum")
+		list    = [][]byte{data}
+		dataPtr = &data
+		sumPtr  = &sum
+		listPtr = &list
+	)
+
+	values := d.ConvertValues([]interface{}{data, sum, list, dataPtr, sumPtr, listPtr})
+	assert.Equal(t, data, values[0])
+	assert.Equal(t, []byte("sum"), values[1])
+	assert.Equal(t, ByteaArray(list), values[2])
+	assert.Equal(t, dataPtr, values[3])
+	assert.Equal(t, sumPtr, values[4])
+	assert.Equal(t, (*ByteaArray)(listPtr), values[5])
+}
@@ -22,6 +22,9 @@
 // Package postgresql wraps the github.com/lib/pq PostgreSQL driver. See
 // https://github.com/frazercomputing/upper-io-db/postgresql for documentation, particularities and
 // usage examples.
+//
+// Building with the pgx tag (go build -tags pgx) replaces lib/pq with the
+// github.com/jackc/pgx/v5/stdlib driver, the adapter API stays the same.
 package postgresql
 
 import (
@@ -29,9 +32,14 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"io"
+	"math/big"
+	"net"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/lib/pq" // PostgreSQL driver.
@@ -42,17 +50,171 @@ import (
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
+// Database represents a PostgreSQL session with features that are specific
+// to this adapter. Sessions created with Open satisfy this interface:
+//
+//	sess, err := postgresql.Open(settings)
+//	...
+//	err = sess.(postgresql.Database).SetConnectInit(fn)
+type Database interface {
+	sqlbuilder.Database
+
+	// SetConnectInit sets a function that is called exactly once on every new
+	// physical connection, right after it's established and before it's used
+	// by any query. This is the place to set per-connection state, like
+	// search_path or statement_timeout. Connections that were opened before
+	// SetConnectInit was called are discarded instead of being reused, idle
+	// ones right away and the ones in use once they're released.
+	//
+	// fn must be idempotent and fast, as it delays the first query of every
+	// new connection, and it must not retain the given *sql.Conn. Returns
+	// db.ErrUnsupported on sessions that were not created with Open.
+	SetConnectInit(fn ConnectInitFunc) error
+
+	// SetTimeZone sets the time zone of every connection of the session, as
+	// SET TIME ZONE would, e.g. "UTC" or "America/Mexico_City". timestamptz
+	// values are then scanned in that time zone, while timestamp values,
+	// which carry no zone, are always scanned with a zero offset. Idle
+	// connections are discarded and an error is returned if the server
+	// rejects the name, in which case the previous time zone is kept. An
+	// empty name keeps the server's default. See also
+	// db.Settings.SetForceUTC, which normalizes scanned values on the client.
+	SetTimeZone(name string) error
+
+	// WithSessionVars returns a copy of the session that sets the given
+	// run-time parameters at the beginning of every transaction, as if
+	// Tx.SetLocal was called for each one of them. This is useful to feed
+	// row-level security policies, e.g.:
+	//
+	//	tenantSess, err := sess.(postgresql.Database).WithSessionVars(map[string]string{
+	//		"app.tenant_id": tenantID,
+	//	})
+	//
+	// Parameters are set only within transactions, queries that run outside a
+	// transaction are not affected.
+	WithSessionVars(vars map[string]string) (Database, error)
+
+	// RegisterType is like the package's RegisterType, but the type is only
+	// converted on this session, where it takes precedence over types
+	// registered globally. Transactions and copies made afterwards start with
+	// the types registered on the session at that point.
+	RegisterType(sample interface{}, wrap func(interface{}) interface{})
+
+	// Columns returns the name, type, length, precision, nullability and
+	// default value of all the columns of the given table, in order. The
+	// table name may be qualified with a schema, like "public.users", and
+	// db.ErrCollectionDoesNotExist is returned if it can't be found.
+	Columns(tableName string) ([]sqlbuilder.ColumnDescriptor, error)
+
+	// ForeignKeys returns the foreign key constraints of the given table,
+	// ordered by name.
+	ForeignKeys(tableName string) ([]sqlbuilder.ForeignKeyDescriptor, error)
+
+	// Indexes returns the indexes of the given table, including the one that
+	// backs the primary key, ordered by name.
+	Indexes(tableName string) ([]sqlbuilder.IndexDescriptor, error)
+
+	// Batch returns an empty Batch that sends its statements with this
+	// session.
+	Batch() *Batch
+
+	// CopyTo exports the rows of query to w as CSV and returns the number of
+	// rows written. It runs on a connection of its own, outside any
+	// transaction of the session, and stops when ctx is done:
+	//
+	//	n, err := sess.(postgresql.Database).CopyTo(ctx, w,
+	//		sess.SelectFrom("artist").Where("id > ?", 10),
+	//		postgresql.CopyOptions{Header: true},
+	//	)
+	//
+	// With the pgx build tag the query is run by the server with COPY ... TO
+	// STDOUT WITH (FORMAT csv), arguments are inlined as literals as COPY does
+	// not take parameters. lib/pq doesn't support COPY TO, so with the
+	// default driver rows are read as usual and encoded on the client.
+	CopyTo(ctx context.Context, w io.Writer, query sqlbuilder.Selector, opts CopyOptions) (int64, error)
+
+	// AdvisoryLock returns a session-level advisory lock on the given key,
+	// see AdvisoryLock. Use Tx.AdvisoryXactLock for locks that are released
+	// along with a transaction.
+	AdvisoryLock(key AdvisoryLockKey) *AdvisoryLock
+
+	// WithLock runs fn while holding the session-level advisory lock on the
+	// given key, so at most one process runs it at a time. The lock is
+	// released when fn returns, even if it panics. If the lock is taken,
+	// db.ErrLockNotAcquired is returned without waiting, unless opts.Wait
+	// is set:
+	//
+	//	err := sess.(postgresql.Database).WithLock(ctx, postgresql.AdvisoryKey(jobID),
+	//		postgresql.LockOptions{Wait: true, Timeout: 5 * time.Second},
+	//		func() error {
+	//			return runJob(ctx)
+	//		},
+	//	)
+	//
+	// A pooled connection is set aside to hold the lock for as long as fn
+	// runs, fn itself uses the session's other connections.
+	WithLock(ctx context.Context, key AdvisoryLockKey, opts LockOptions, fn func() error) error
+
+	// ServerVersion returns the version of the server, as SHOW server_version
+	// reports it. It's read once and cached.
+	ServerVersion() (string, error)
+
+	// SupportsOnConflict returns true if the server supports INSERT ... ON
+	// CONFLICT (PostgreSQL 9.5), which Inserter.OnConflict uses. Statements
+	// using features the server is too old for fail with a *VersionError.
+	//
+	// On older servers, upserts that are run with Exec fall back to updating
+	// each row and inserting it if there was nothing to update, within a
+	// transaction. The fallback needs a unique constraint on the conflict
+	// target: a row inserted by another session in between makes the insert
+	// fail with a unique violation, and the row is then updated again (or
+	// skipped, with DoNothing, which also skips rows that violate other
+	// unique constraints). Upserts with RETURNING have no fallback.
+	SupportsOnConflict() bool
+
+	// SupportsSkipLocked returns true if the server supports SKIP LOCKED
+	// (PostgreSQL 9.5), which Selector.SkipLocked uses.
+	SupportsSkipLocked() bool
+
+	// SupportsMerge returns true if the server supports MERGE (PostgreSQL 15).
+	SupportsMerge() bool
+
+	// ExecScript runs a script of statements separated by semicolons, like a
+	// migration, within a transaction, and returns the result of each
+	// statement in order:
+	//
+	//	results, err := sess.ExecScript(ctx, `
+	//		CREATE TABLE accounts (id serial PRIMARY KEY, name text);
+	//		CREATE FUNCTION touch() RETURNS trigger AS $$
+	//		BEGIN
+	//			NEW.name := trim(NEW.name);
+	//			RETURN NEW;
+	//		END;
+	//		$$ LANGUAGE plpgsql;
+	//	`)
+	//
+	// Semicolons within strings, quoted identifiers, comments and
+	// dollar-quoted strings don't end statements. Statements are sent as they
+	// are: placeholders are not replaced, so ? can be used as an operator.
+	ExecScript(ctx context.Context, script string) ([]sql.Result, error)
+}
+
 // database is the actual implementation of Database
 type database struct {
 	sqladapter.BaseDatabase
 
 	sqlbuilder.SQLBuilder
 
-	connURL db.ConnectionURL
-	mu      sync.Mutex
+	connURL       db.ConnectionURL
+	connector     *connector
+	sessionVars   map[string]string
+	types         *typeRegistry
+	serverVersion *atomic.Value // shared with clones
+	mu            sync.Mutex
 }
 
 var (
+	_ = Database(&database{})
 	_ = sqlbuilder.Database(&database{})
 	_ = sqladapter.Database(&database{})
 )
@@ -60,7 +222,9 @@ var (
 // newDatabase creates a new *database session for internal use.
 func newDatabase(settings db.ConnectionURL) *database {
 	return &database{
-		connURL: settings,
+		connURL:       settings,
+		types:         &typeRegistry{},
+		serverVersion: &atomic.Value{},
 	}
 }
 
@@ -118,15 +282,14 @@ func (d *database) open() error {
 	// Binding with sqlbuilder.
 	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
 
+	d.connector = newConnector(d.ConnectionURL().String())
+
 	connFn := func() error {
-		sess, err := sql.Open("postgres", d.ConnectionURL().String())
-		if err == nil {
-			sess.SetConnMaxLifetime(db.DefaultSettings.ConnMaxLifetime())
-			sess.SetMaxIdleConns(db.DefaultSettings.MaxIdleConns())
-			sess.SetMaxOpenConns(db.DefaultSettings.MaxOpenConns())
-			return d.BaseDatabase.BindSession(sess)
-		}
-		return err
+		sess := sql.OpenDB(d.connector)
+		sess.SetConnMaxLifetime(db.DefaultSettings.ConnMaxLifetime())
+		sess.SetMaxIdleConns(db.DefaultSettings.MaxIdleConns())
+		sess.SetMaxOpenConns(db.DefaultSettings.MaxOpenConns())
+		return d.BaseDatabase.BindSession(sess)
 	}
 
 	if err := d.BaseDatabase.WaitForConnection(connFn); err != nil {
@@ -136,9 +299,92 @@ func (d *database) open() error {
 	return nil
 }
 
+// SetConnectInit sets a function to be called on every new connection.
+func (d *database) SetConnectInit(fn ConnectInitFunc) error {
+	if d.connector == nil {
+		return db.ErrUnsupported
+	}
+	d.connector.setConnectInit(fn)
+
+	if sess := d.Session(); sess != nil {
+		// Discard idle connections that were not initialized with fn.
+		sess.SetMaxIdleConns(0)
+		sess.SetMaxIdleConns(d.MaxIdleConns())
+	}
+	return nil
+}
+
+// SetTimeZone sets the time zone of every connection.
+func (d *database) SetTimeZone(name string) error {
+	if d.connector == nil {
+		return db.ErrUnsupported
+	}
+	prev := d.connector.getTimeZone()
+	d.connector.setTimeZone(name)
+
+	sess := d.Session()
+	if sess == nil {
+		return nil
+	}
+	// Discard idle connections that were opened with a different time zone,
+	// the next one will tell whether the time zone is valid.
+	sess.SetMaxIdleConns(0)
+	sess.SetMaxIdleConns(d.MaxIdleConns())
+	if err := sess.PingContext(d.Context()); err != nil {
+		d.connector.setTimeZone(prev)
+		return err
+	}
+	return nil
+}
+
+// Batch returns an empty Batch bound to the session.
+func (d *database) Batch() *Batch {
+	return &Batch{sess: d}
+}
+
+// WithSessionVars creates a copy of the session that sets the given run-time
+// parameters on every transaction.
+func (d *database) WithSessionVars(vars map[string]string) (Database, error) {
+	newDB, err := d.clone(d.Context(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	newDB.sessionVars = make(map[string]string, len(d.sessionVars)+len(vars))
+	for k, v := range d.sessionVars {
+		newDB.sessionVars[k] = v
+	}
+	for k, v := range vars {
+		newDB.sessionVars[k] = v
+	}
+
+	return newDB, nil
+}
+
+// setSessionVars sets the session's run-time parameters on the current
+// transaction.
+func (d *database) setSessionVars() error {
+	names := make([]string, 0, len(d.sessionVars))
+	for name := range d.sessionVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := d.Exec(`SELECT set_config(?, ?, true)`, name, d.sessionVars[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Clone creates a copy of the database session on the given context.
 func (d *database) clone(ctx context.Context, checkConn bool) (*database, error) {
 	clone := newDatabase(d.connURL)
+	clone.connector = d.connector
+	clone.serverVersion = d.serverVersion
+	clone.sessionVars = d.sessionVars
+	clone.types = d.types.copy()
 
 	var err error
 	clone.BaseDatabase, err = d.NewClone(clone, checkConn)
@@ -155,16 +401,25 @@ func (d *database) clone(ctx context.Context, checkConn bool) (*database, error)
 
 func (d *database) ConvertValues(values []interface{}) []interface{} {
 	for i := range values {
+		if v, ok := d.convertRegistered(values[i]); ok {
+			values[i] = v
+			continue
+		}
+
 		switch v := values[i].(type) {
-		case *string, *bool, *int, *uint, *int64, *uint64, *int32, *uint32, *int16, *uint16, *int8, *uint8, *float32, *float64, *[]uint8, sql.Scanner, *sql.Scanner, *time.Time:
-			// Handled by pq.
-		case string, bool, int, uint, int64, uint64, int32, uint32, int16, uint16, int8, uint8, float32, float64, []uint8, driver.Valuer, *driver.Valuer, time.Time:
-			// Handled by pq.
-		case StringArray, Int64Array, BoolArray, GenericArray, Float64Array, JSONBMap, JSONB:
+		case *string, *bool, *int, *uint, *int64, *uint64, *int32, *uint32, *int16, *uint16, *int8, *uint8, *float32, *float64, *[]byte, sql.Scanner, *sql.Scanner, *time.Time:
+			// Handled by pq, *[]byte scans bytea.
+		case string, bool, int, uint, int64, uint64, int32, uint32, int16, uint16, int8, uint8, float32, float64, []byte, driver.Valuer, *driver.Valuer, time.Time:
+			// Handled by pq, []byte (the same type as []uint8) is sent as bytea.
+		case StringArray, Int64Array, BoolArray, GenericArray, Float64Array, JSONBMap, JSONB, Money, Interval, Inet, MAC, NullInt64Array, NullStringArray, NullFloat64Array, NullBoolArray, ByteaArray:
 			// Already with scanner/valuer.
-		case *StringArray, *Int64Array, *BoolArray, *GenericArray, *Float64Array, *JSONBMap, *JSONB:
+		case *StringArray, *Int64Array, *BoolArray, *GenericArray, *Float64Array, *JSONBMap, *JSONB, *Money, *Interval, *Inet, *MAC, *NullInt64Array, *NullStringArray, *NullFloat64Array, *NullBoolArray, *ByteaArray:
 			// Already with scanner/valuer.
 
+		case sql.NamedArg:
+			v.Value = d.ConvertValues([]interface{}{v.Value})[0]
+			values[i] = v
+
 		case *[]int64:
 			values[i] = (*Int64Array)(v)
 		case *[]string:
@@ -173,8 +428,30 @@ func (d *database) ConvertValues(values []interface{}) []interface{} {
 			values[i] = (*Float64Array)(v)
 		case *[]bool:
 			values[i] = (*BoolArray)(v)
+		case *[]*int64:
+			values[i] = (*NullInt64Array)(v)
+		case *[]*string:
+			values[i] = (*NullStringArray)(v)
+		case *[]*float64:
+			values[i] = (*NullFloat64Array)(v)
+		case *[]*bool:
+			values[i] = (*NullBoolArray)(v)
+		case *[][]byte:
+			values[i] = (*ByteaArray)(v)
 		case *map[string]interface{}:
 			values[i] = (*JSONBMap)(v)
+		case *big.Rat:
+			values[i] = (*Numeric)(v)
+		case **big.Rat:
+			values[i] = nullNumeric{v}
+		case *time.Duration:
+			values[i] = durationScanner{v}
+		case *net.IPNet:
+			values[i] = (*Inet)(v)
+		case *net.IP:
+			values[i] = (*inetIP)(v)
+		case *net.HardwareAddr:
+			values[i] = (*MAC)(v)
 
 		case []int64:
 			values[i] = (*Int64Array)(&v)
@@ -184,8 +461,26 @@ func (d *database) ConvertValues(values []interface{}) []interface{} {
 			values[i] = (*Float64Array)(&v)
 		case []bool:
 			values[i] = (*BoolArray)(&v)
+		case []*int64:
+			values[i] = NullInt64Array(v)
+		case []*string:
+			values[i] = NullStringArray(v)
+		case []*float64:
+			values[i] = NullFloat64Array(v)
+		case []*bool:
+			values[i] = NullBoolArray(v)
+		case [][]byte:
+			values[i] = ByteaArray(v)
 		case map[string]interface{}:
 			values[i] = (*JSONBMap)(&v)
+		case big.Rat:
+			values[i] = (*Numeric)(&v)
+		case net.IPNet:
+			values[i] = Inet(v)
+		case net.IP:
+			values[i] = inetIP(v)
+		case net.HardwareAddr:
+			values[i] = MAC(v)
 
 		case sqlbuilder.ValueWrapper:
 			values[i] = v.WrapValue(v)
@@ -201,16 +496,17 @@ func (d *database) ConvertValues(values []interface{}) []interface{} {
 // CompileStatement compiles a *exql.Statement into arguments that sql/database
 // accepts.
 func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
-	compiled, err := stmt.Compile(template)
+	compiled, err := stmt.Compile(sqladapter.SessionTemplate(d.BaseDatabase, template))
 	if err != nil {
 		panic(err.Error())
 	}
-	query, args := sqlbuilder.Preprocess(compiled, args)
-	return sqladapter.ReplaceWithDollarSign(query), args
+	query, args := sqlbuilder.PreprocessWithTemplate(template, compiled, args)
+	return template.BindArguments(query, args)
 }
 
 // Err allows sqladapter to translate specific PostgreSQL string errors into
-// custom error values.
+// custom error values. Other errors reported by the server are wrapped in an
+// *Error.
 func (d *database) Err(err error) error {
 	if err != nil {
 		s := err.Error()
@@ -218,10 +514,38 @@ func (d *database) Err(err error) error {
 		if strings.Contains(s, `too many clients`) || strings.Contains(s, `remaining connection slots are reserved`) || strings.Contains(s, `too many open`) {
 			return db.ErrTooManyClients
 		}
+		if e := driverError(err); e != nil {
+			return e
+		}
 	}
 	return err
 }
 
+// LockHolders returns the sessions other than this one that hold locks on
+// the given table, it's used by lock diagnostics.
+func (d *database) LockHolders(ctx context.Context, table string) ([]db.LockHolder, error) {
+	rows, err := d.Session().QueryContext(ctx, `
+		SELECT DISTINCT a.pid, COALESCE(a.query, '')
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.relation = to_regclass($1) AND l.granted AND l.pid <> pg_backend_pid()
+		ORDER BY a.pid`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holders []db.LockHolder
+	for rows.Next() {
+		var h db.LockHolder
+		if err := rows.Scan(&h.PID, &h.Query); err != nil {
+			return nil, err
+		}
+		holders = append(holders, h)
+	}
+	return holders, rows.Err()
+}
+
 // NewCollection creates a db.Collection by name.
 func (d *database) NewCollection(name string) db.Collection {
 	return newCollection(d, name)
@@ -235,6 +559,11 @@ func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) erro
 	return sqladapter.RunTx(d, ctx, fn)
 }
 
+// TxWithStats works like Tx and fills stats in with the details of the run.
+func (d *database) TxWithStats(ctx context.Context, fn func(tx sqlbuilder.Tx) error, stats *sqlbuilder.TxStats) error {
+	return sqladapter.RunTxWithStats(d, ctx, fn, stats)
+}
+
 // NewDatabaseTx begins a transaction block.
 func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
 	clone, err := d.clone(ctx, true)
@@ -256,6 +585,11 @@ func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, er
 		return nil, err
 	}
 
+	if err := clone.setSessionVars(); err != nil {
+		clone.Close() // Rolls back the transaction.
+		return nil, err
+	}
+
 	return sqladapter.NewDatabaseTx(clone), nil
 }
 
@@ -337,8 +671,156 @@ func (d *database) PrimaryKeys(tableName string) ([]string, error) {
 	return pk, nil
 }
 
+// Columns returns a description of all the columns of the given table, in
+// order. The table name may be qualified with a schema, otherwise the current
+// schema is used.
+func (d *database) Columns(tableName string) ([]sqlbuilder.ColumnDescriptor, error) {
+	schema, table := sqladapter.SplitTableName(tableName)
+
+	q := d.Select(sqladapter.InformationSchemaColumns...).
+		From("information_schema.columns").
+		Where("table_name = ?", table)
+
+	if schema != "" {
+		q = q.And("table_schema = ?", schema)
+	} else {
+		q = q.And(db.Raw("table_schema = current_schema()"))
+	}
+
+	var rows []sqladapter.InformationSchemaColumn
+	if err := q.OrderBy("ordinal_position").All(&rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, db.ErrCollectionDoesNotExist
+	}
+
+	columns := make([]sqlbuilder.ColumnDescriptor, 0, len(rows))
+	for i := range rows {
+		columns = append(columns, rows[i].Descriptor())
+	}
+	return columns, nil
+}
+
+// referentialActions maps pg_constraint action codes to their SQL names.
+var referentialActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+// ForeignKeys returns the foreign key constraints of the given table.
+func (d *database) ForeignKeys(tableName string) ([]sqlbuilder.ForeignKeyDescriptor, error) {
+	iter := d.Iterator(`
+		SELECT
+			con.conname AS name,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, n)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.n
+			) AS columns,
+			con.confrelid::regclass::text AS referenced_table,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, n)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.n
+			) AS referenced_columns,
+			con.confupdtype AS on_update,
+			con.confdeltype AS on_delete
+		FROM pg_constraint con
+		WHERE con.conrelid = ?::regclass
+			AND con.contype = 'f'
+		ORDER BY con.conname
+	`, quotedTableName(tableName))
+
+	var rows []struct {
+		Name              string      `db:"name"`
+		Columns           StringArray `db:"columns"`
+		ReferencedTable   string      `db:"referenced_table"`
+		ReferencedColumns StringArray `db:"referenced_columns"`
+		OnUpdate          string      `db:"on_update"`
+		OnDelete          string      `db:"on_delete"`
+	}
+	if err := iter.All(&rows); err != nil {
+		return nil, err
+	}
+
+	fks := make([]sqlbuilder.ForeignKeyDescriptor, 0, len(rows))
+	for _, row := range rows {
+		fks = append(fks, sqlbuilder.ForeignKeyDescriptor{
+			Name:              row.Name,
+			Columns:           []string(row.Columns),
+			ReferencedTable:   row.ReferencedTable,
+			ReferencedColumns: []string(row.ReferencedColumns),
+			OnUpdate:          referentialActions[row.OnUpdate],
+			OnDelete:          referentialActions[row.OnDelete],
+		})
+	}
+	return fks, nil
+}
+
+// Indexes returns the indexes of the given table.
+func (d *database) Indexes(tableName string) ([]sqlbuilder.IndexDescriptor, error) {
+	iter := d.Iterator(`
+		SELECT
+			ic.relname AS name,
+			ARRAY(
+				SELECT pg_get_indexdef(i.indexrelid, k, true)
+				FROM generate_series(1, i.indnatts) AS k
+				ORDER BY k
+			) AS columns,
+			i.indisunique AS is_unique,
+			i.indisprimary AS is_primary,
+			am.amname AS method
+		FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_am am ON am.oid = ic.relam
+		WHERE i.indrelid = ?::regclass
+		ORDER BY ic.relname
+	`, quotedTableName(tableName))
+
+	var rows []struct {
+		Name      string      `db:"name"`
+		Columns   StringArray `db:"columns"`
+		IsUnique  bool        `db:"is_unique"`
+		IsPrimary bool        `db:"is_primary"`
+		Method    string      `db:"method"`
+	}
+	if err := iter.All(&rows); err != nil {
+		return nil, err
+	}
+
+	indexes := make([]sqlbuilder.IndexDescriptor, 0, len(rows))
+	for _, row := range rows {
+		indexes = append(indexes, sqlbuilder.IndexDescriptor{
+			Name:    row.Name,
+			Columns: []string(row.Columns),
+			Unique:  row.IsUnique,
+			Primary: row.IsPrimary,
+			Method:  row.Method,
+		})
+	}
+	return indexes, nil
+}
+
 // WithContext creates a copy of the session on the given context.
 func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
 	newDB, _ := d.clone(ctx, false)
 	return newDB
 }
+
+// BlobChunk returns an expression that takes a part of a bytea column.
+func (d *database) BlobChunk(column string) string {
+	c, _ := exql.ColumnWithName(column).Compile(template)
+	return `substring(` + c + ` FROM ? FOR ?)`
+}
+
+// BlobAppend returns an assignment that appends to a bytea column.
+func (d *database) BlobAppend(column string) string {
+	c, _ := exql.ColumnWithName(column).Compile(template)
+	return c + ` = ` + c + ` || ?`
+}
@@ -0,0 +1,88 @@
+//go:build pgx
+// +build pgx
+
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+)
+
+// DriverName is the name of the driver the adapter was built with, either
+// "pq" (the default) or "pgx" (with the pgx build tag).
+const DriverName = "pgx"
+
+func newDriver() driver.Driver {
+	return stdlib.GetDefaultDriver()
+}
+
+// driverError converts an error reported by the server into an *Error, it
+// returns nil for any other error.
+func driverError(err error) *Error {
+	pgErr, ok := err.(*pgconn.PgError)
+	if !ok {
+		return nil
+	}
+	return &Error{
+		Severity:   pgErr.Severity,
+		Code:       pq.ErrorCode(pgErr.Code),
+		Message:    pgErr.Message,
+		Detail:     pgErr.Detail,
+		Hint:       pgErr.Hint,
+		Where:      pgErr.Where,
+		Schema:     pgErr.SchemaName,
+		Table:      pgErr.TableName,
+		Column:     pgErr.ColumnName,
+		DataType:   pgErr.DataTypeName,
+		Constraint: pgErr.ConstraintName,
+		err:        pgErr,
+	}
+}
+
+// copyOut runs query with COPY ... TO STDOUT on conn and writes its output to
+// w.
+func copyOut(ctx context.Context, conn *sql.Conn, query string, args []interface{}, opts CopyOptions, w io.Writer) (int64, error) {
+	query, err := inlineArgs(query, args)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = conn.Raw(func(driverConn interface{}) error {
+		if c, ok := driverConn.(*initConn); ok {
+			driverConn = c.Conn
+		}
+		pgConn := driverConn.(*stdlib.Conn).Conn().PgConn()
+		tag, err := pgConn.CopyTo(ctx, w, copyStatement(query, opts))
+		n = tag.RowsAffected()
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,122 @@
+//go:build !pgx
+// +build !pgx
+
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+
+	"github.com/lib/pq"
+)
+
+// DriverName is the name of the driver the adapter was built with, either
+// "pq" (the default) or "pgx" (with the pgx build tag).
+const DriverName = "pq"
+
+func newDriver() driver.Driver {
+	return pq.Driver{}
+}
+
+// driverError converts an error reported by the server into an *Error, it
+// returns nil for any other error.
+func driverError(err error) *Error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return nil
+	}
+	return &Error{
+		Severity:   pqErr.Severity,
+		Code:       pqErr.Code,
+		Message:    pqErr.Message,
+		Detail:     pqErr.Detail,
+		Hint:       pqErr.Hint,
+		Where:      pqErr.Where,
+		Schema:     pqErr.Schema,
+		Table:      pqErr.Table,
+		Column:     pqErr.Column,
+		DataType:   pqErr.DataTypeName,
+		Constraint: pqErr.Constraint,
+		err:        pqErr,
+	}
+}
+
+// copyOut runs query on conn and writes its rows to w as CSV. lib/pq does not
+// support COPY TO STDOUT, so rows are read as usual and encoded on the
+// client, following PostgreSQL's text representation of values.
+func copyOut(ctx context.Context, conn *sql.Conn, query string, args []interface{}, opts CopyOptions, w io.Writer) (int64, error) {
+	rows, err := conn.QueryContext(ctx, template.ReplacePlaceholders(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+
+	cw := newCopyWriter(w, opts)
+	record := make([]sql.NullString, len(columnTypes))
+	if opts.Header {
+		for i := range columnTypes {
+			record[i] = sql.NullString{String: columnTypes[i].Name(), Valid: true}
+		}
+		if err := cw.write(record); err != nil {
+			return 0, err
+		}
+	}
+
+	// lib/pq decodes bytea values but returns the text of any other type it
+	// does not know about as []byte too.
+	isBytea := make([]bool, len(columnTypes))
+	for i := range columnTypes {
+		isBytea[i] = columnTypes[i].DatabaseTypeName() == "BYTEA"
+	}
+
+	var n int64
+	values := make([]interface{}, len(columnTypes))
+	for rows.Next() {
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		if err := rows.Scan(values...); err != nil {
+			return n, err
+		}
+		for i := range values {
+			record[i] = copyText(*(values[i].(*interface{})), isBytea[i])
+		}
+		if err := cw.write(record); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	return n, cw.flush()
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/lib/pq"
+)
+
+// Error represents an error reported by the PostgreSQL server. It keeps the
+// fields of the driver's error (*pq.Error, or *pgconn.PgError with the pgx
+// build tag), which is returned by Unwrap, and it's what the adapter returns
+// in its place:
+//
+//	if err, ok := err.(*postgresql.Error); ok {
+//		log.Printf("%+v", err)
+//	}
+//
+// Formatting with %+v includes the detail, hint and context of the error,
+// the other verbs print the error message alone. Error implements Is, so
+// constraint violations and deadlocks match the portable errors of the db
+// package, like db.ErrUniqueViolation.
+type Error struct {
+	Severity   string
+	Code       pq.ErrorCode
+	Message    string
+	Detail     string
+	Hint       string
+	Where      string
+	Schema     string
+	Table      string
+	Column     string
+	DataType   string
+	Constraint string
+
+	err error
+}
+
+// Error returns the message of the original error.
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the driver's error.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is the portable error that matches the error
+// code, like db.ErrUniqueViolation for unique_violation (23505).
+// query_canceled (57014) matches db.ErrQueryTimeout only when it was caused
+// by statement_timeout, as it's also reported for cancel requests.
+func (e *Error) Is(target error) bool {
+	if e.Code == "57014" {
+		return target == db.ErrQueryTimeout && strings.Contains(e.Message, "statement timeout")
+	}
+	kind, ok := errorKinds[e.Code]
+	return ok && kind == target
+}
+
+// errorKinds maps PostgreSQL error codes to portable errors.
+var errorKinds = map[pq.ErrorCode]error{
+	"23505": db.ErrUniqueViolation,
+	"23503": db.ErrForeignKeyViolation,
+	"23514": db.ErrCheckViolation,
+	"23502": db.ErrNotNullViolation,
+	"40001": db.ErrStaleObject,
+	"40P01": db.ErrStaleObject,
+	"55P03": db.ErrLockNotAcquired,
+}
+
+// Format implements fmt.Formatter.
+func (e *Error) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if f.Flag('+') {
+			io.WriteString(f, e.Error())
+			for _, field := range []struct{ name, value string }{
+				{"DETAIL", e.Detail},
+				{"HINT", e.Hint},
+				{"WHERE", e.Where},
+			} {
+				if field.value != "" {
+					fmt.Fprintf(f, "\n%s: %s", field.name, field.value)
+				}
+			}
+			return
+		}
+		io.WriteString(f, e.Error())
+	case 's':
+		io.WriteString(f, e.Error())
+	case 'q':
+		fmt.Fprintf(f, "%q", e.Error())
+	}
+}
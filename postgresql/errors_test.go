@@ -0,0 +1,53 @@
+package postgresql
+
+import (
+	"fmt"
+	"testing"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	pqErr := &pq.Error{
+		Code:       "23505",
+		Message:    `duplicate key value violates unique constraint "artist_name_key"`,
+		Detail:     `Key (name)=(Ozzie) already exists.`,
+		Table:      "artist",
+		Constraint: "artist_name_key",
+	}
+
+	d := &database{}
+	err := d.Err(pqErr)
+
+	pgErr, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, pqErr, pgErr.Unwrap())
+	assert.Equal(t, pq.ErrorCode("23505"), pgErr.Code)
+	assert.Equal(t, "artist", pgErr.Table)
+	assert.Equal(t, "artist_name_key", pgErr.Constraint)
+
+	assert.Equal(t, pqErr.Error(), fmt.Sprintf("%v", err))
+	assert.Equal(t, pqErr.Error(), fmt.Sprintf("%s", err))
+	assert.Equal(t, pqErr.Error()+"\nDETAIL: Key (name)=(Ozzie) already exists.", fmt.Sprintf("%+v", err))
+
+	pqErr.Hint = "Try another name."
+	assert.Equal(t, pqErr.Error()+"\nDETAIL: Key (name)=(Ozzie) already exists.\nHINT: Try another name.", fmt.Sprintf("%+v", d.Err(pqErr)))
+
+	assert.True(t, pgErr.Is(db.ErrUniqueViolation))
+	assert.False(t, pgErr.Is(db.ErrForeignKeyViolation))
+
+	// Errors are wrapped only once.
+	assert.Equal(t, err, d.Err(err))
+}
+
+func TestErrQueryCanceled(t *testing.T) {
+	d := &database{}
+
+	err := d.Err(&pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"})
+	assert.True(t, err.(*Error).Is(db.ErrQueryTimeout))
+
+	err = d.Err(&pq.Error{Code: "57014", Message: "canceling statement due to user request"})
+	assert.False(t, err.(*Error).Is(db.ErrQueryTimeout))
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// explainStatement prefixes query with an EXPLAIN statement.
+func explainStatement(query string, opts sqlbuilder.ExplainOptions) string {
+	options := []string{}
+	if opts.Analyze {
+		options = append(options, "ANALYZE", "BUFFERS")
+	}
+	if opts.JSON {
+		options = append(options, "FORMAT JSON")
+	} else {
+		options = append(options, "FORMAT TEXT")
+	}
+	return "EXPLAIN (" + strings.Join(options, ", ") + ") " + query
+}
+
+// ExplainStatement returns the plan of the given query, one line per row.
+func (d *database) ExplainStatement(ctx context.Context, tx *sql.Tx, query string, args []interface{}, opts sqlbuilder.ExplainOptions) (string, error) {
+	rows, err := tx.QueryContext(ctx, explainStatement(query, opts), args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	lines := []string{}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,19 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainStatement(t *testing.T) {
+	assert.Equal(t,
+		`EXPLAIN (FORMAT TEXT) SELECT 1`,
+		explainStatement(`SELECT 1`, sqlbuilder.ExplainOptions{}),
+	)
+	assert.Equal(t,
+		`EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) DELETE FROM "artist"`,
+		explainStatement(`DELETE FROM "artist"`, sqlbuilder.ExplainOptions{Analyze: true, JSON: true}),
+	)
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAmbiguousInterval is returned when an interval with months is converted
+// into a time.Duration, as the length of a month is not fixed.
+var ErrAmbiguousInterval = errors.New(`upper: interval with months can't be converted into a duration`)
+
+// Interval represents a PostgreSQL INTERVAL value. Months and days are kept
+// apart from the rest of the interval, as PostgreSQL does, since neither has a
+// fixed length. *time.Duration destinations are also accepted, see
+// Interval.Duration.
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+}
+
+// IntervalFromDuration returns an interval of the given duration, rounded to
+// microseconds.
+func IntervalFromDuration(d time.Duration) Interval {
+	return Interval{Microseconds: int64(d / time.Microsecond)}
+}
+
+// Duration returns the interval as a time.Duration, taking days as 24 hours.
+// ErrAmbiguousInterval is returned if the interval has months.
+func (i Interval) Duration() (time.Duration, error) {
+	if i.Months != 0 {
+		return 0, ErrAmbiguousInterval
+	}
+	return time.Duration(i.Days)*24*time.Hour + time.Duration(i.Microseconds)*time.Microsecond, nil
+}
+
+// String returns the interval in the format PostgreSQL accepts as input,
+// like "14 mons 3 days 04:05:06.789000".
+func (i Interval) String() string {
+	var b strings.Builder
+	if i.Months != 0 {
+		fmt.Fprintf(&b, "%d mons ", i.Months)
+	}
+	if i.Days != 0 {
+		fmt.Fprintf(&b, "%d days ", i.Days)
+	}
+
+	us := i.Microseconds
+	if us < 0 {
+		b.WriteByte('-')
+		us = -us
+	}
+	fmt.Fprintf(&b, "%02d:%02d:%02d", us/3600000000, us/60000000%60, us/1000000%60)
+	if frac := us % 1000000; frac != 0 {
+		fmt.Fprintf(&b, ".%06d", frac)
+	}
+	return b.String()
+}
+
+// Value satisfies the driver.Valuer interface.
+func (i Interval) Value() (driver.Value, error) {
+	return i.String(), nil
+}
+
+// Scan satisfies the sql.Scanner interface. NULL is scanned as a zero
+// interval.
+func (i *Interval) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*i = Interval{}
+		return nil
+	case []byte:
+		return i.parse(string(v))
+	case string:
+		return i.parse(v)
+	}
+	return fmt.Errorf("upper: can't scan %T into Interval", src)
+}
+
+// parse reads an interval in PostgreSQL's default output format, like
+// "1 year 2 mons -3 days +04:05:06.789".
+func (i *Interval) parse(s string) error {
+	var out Interval
+
+	fields := strings.Fields(s)
+	for k := 0; k < len(fields); k++ {
+		field := fields[k]
+
+		if strings.Contains(field, ":") {
+			us, err := parseIntervalTime(field)
+			if err != nil {
+				return fmt.Errorf("upper: can't parse interval %q: %v", s, err)
+			}
+			out.Microseconds += us
+			continue
+		}
+
+		if k+1 >= len(fields) {
+			return fmt.Errorf("upper: can't parse interval %q", s)
+		}
+		n, err := strconv.ParseInt(field, 10, 32)
+		if err != nil {
+			return fmt.Errorf("upper: can't parse interval %q: %v", s, err)
+		}
+		k++
+
+		switch strings.TrimSuffix(fields[k], "s") {
+		case "year":
+			out.Months += int32(n) * 12
+		case "mon":
+			out.Months += int32(n)
+		case "day":
+			out.Days += int32(n)
+		default:
+			return fmt.Errorf("upper: can't parse interval %q: unknown unit %q", s, fields[k])
+		}
+	}
+
+	*i = out
+	return nil
+}
+
+// parseIntervalTime parses the [-+]hh:mm:ss[.ffffff] part of an interval into
+// microseconds.
+func parseIntervalTime(s string) (int64, error) {
+	sign := int64(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	secs, frac := parts[2], ""
+	if p := strings.IndexByte(secs, '.'); p >= 0 {
+		secs, frac = secs[:p], secs[p+1:]
+	}
+	seconds, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	var us int64
+	if frac != "" {
+		frac = (frac + "000000")[:6]
+		if us, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return 0, err
+		}
+	}
+
+	return sign * (((hours*60+minutes)*60+seconds)*1000000 + us), nil
+}
+
+// durationScanner scans an INTERVAL, or an integer number of nanoseconds,
+// into a *time.Duration.
+type durationScanner struct {
+	dst *time.Duration
+}
+
+func (d durationScanner) Scan(src interface{}) error {
+	if v, ok := src.(int64); ok {
+		*d.dst = time.Duration(v)
+		return nil
+	}
+	var i Interval
+	if err := i.Scan(src); err != nil {
+		return err
+	}
+	v, err := i.Duration()
+	if err != nil {
+		return err
+	}
+	*d.dst = v
+	return nil
+}
+
+// Value sends the duration as an integer number of nanoseconds, like the
+// driver does for a plain time.Duration.
+func (d durationScanner) Value() (driver.Value, error) {
+	return int64(*d.dst), nil
+}
+
+var (
+	_ driver.Valuer = Interval{}
+	_ driver.Valuer = durationScanner{}
+)
@@ -0,0 +1,198 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	db "github.com/frazercomputing/upper-io-db"
+)
+
+// Inet represents a PostgreSQL INET or CIDR value, an IP address with an
+// optional network mask:
+//
+//	var addr postgresql.Inet
+//	row.Scan(&addr) // 192.168.0.10/24
+//	addr.IP         // 192.168.0.10
+//	addr.Mask       // ffffff00
+//
+// An address without a mask is read with a full one (/32 or /128) and
+// written without one. The zero value is read from and written as NULL.
+// net.IP and net.IPNet values are converted to Inet automatically.
+type Inet net.IPNet
+
+// Value satisfies the driver.Valuer interface.
+func (n Inet) Value() (driver.Value, error) {
+	if n.IP == nil {
+		return nil, nil
+	}
+	return n.String(), nil
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (n *Inet) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*n = Inet{}
+		return nil
+	case []byte:
+		return n.parse(string(v))
+	case string:
+		return n.parse(v)
+	}
+	return fmt.Errorf("upper: can't scan %T into Inet", src)
+}
+
+func (n *Inet) parse(s string) error {
+	if strings.Contains(s, "/") {
+		ip, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return err
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		*n = Inet{IP: ip, Mask: ipNet.Mask}
+		return nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return fmt.Errorf("upper: invalid IP address %q", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	*n = Inet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}
+	return nil
+}
+
+// String returns the address in the format PostgreSQL uses, like
+// "192.168.0.10/24" or "::1".
+func (n Inet) String() string {
+	ones, bits := n.Mask.Size()
+	if ones == bits {
+		return n.IP.String()
+	}
+	return n.IP.String() + "/" + strconv.Itoa(ones)
+}
+
+// inetIP scans an INET or CIDR value into a net.IP, dropping the mask.
+type inetIP net.IP
+
+func (ip *inetIP) Scan(src interface{}) error {
+	var n Inet
+	if err := n.Scan(src); err != nil {
+		return err
+	}
+	*ip = inetIP(n.IP)
+	return nil
+}
+
+func (ip inetIP) Value() (driver.Value, error) {
+	if ip == nil {
+		return nil, nil
+	}
+	return net.IP(ip).String(), nil
+}
+
+// MAC represents a PostgreSQL MACADDR or MACADDR8 value. The zero value is
+// read from and written as NULL. net.HardwareAddr values are converted to MAC
+// automatically.
+type MAC net.HardwareAddr
+
+// Value satisfies the driver.Valuer interface.
+func (m MAC) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return net.HardwareAddr(m).String(), nil
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (m *MAC) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("upper: can't scan %T into MAC", src)
+	}
+	addr, err := net.ParseMAC(s)
+	if err != nil {
+		return err
+	}
+	*m = MAC(addr)
+	return nil
+}
+
+// String returns the address in the colon-separated format, like
+// "08:00:2b:01:02:03".
+func (m MAC) String() string {
+	return net.HardwareAddr(m).String()
+}
+
+// ContainedBy indicates whether the reference is a subnet of, or an address
+// in, the given network, using the << operator:
+//
+//	// "addr" << '10.0.0.0/8'
+//	db.Cond{"addr": postgresql.ContainedBy("10.0.0.0/8")}
+func ContainedBy(network interface{}) db.Comparison {
+	return db.Op("<<", network)
+}
+
+// ContainedByOrEquals is like ContainedBy but it's also true when both
+// networks are equal, using the <<= operator.
+func ContainedByOrEquals(network interface{}) db.Comparison {
+	return db.Op("<<=", network)
+}
+
+// Contains indicates whether the reference is a network that contains the
+// given address or subnet, using the >> operator.
+func Contains(v interface{}) db.Comparison {
+	return db.Op(">>", v)
+}
+
+// ContainsOrEquals is like Contains but it's also true when both networks are
+// equal, using the >>= operator.
+func ContainsOrEquals(v interface{}) db.Comparison {
+	return db.Op(">>=", v)
+}
+
+// Overlaps indicates whether the reference and the given network contain or
+// are contained by each other, using the && operator.
+func Overlaps(network interface{}) db.Comparison {
+	return db.Op("&&", network)
+}
+
+var (
+	_ driver.Valuer = Inet{}
+	_ driver.Valuer = MAC{}
+)
@@ -0,0 +1,148 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrInexactNumeric is returned when a value can't be represented as a
+// finite decimal number (e.g. 1/3).
+var ErrInexactNumeric = errors.New(`upper: value has no exact decimal representation`)
+
+// Numeric represents a PostgreSQL NUMERIC value backed by a big.Rat, it's
+// read and written in its exact text form so no precision is lost. *big.Rat
+// values and destinations are converted to Numeric automatically; scanning
+// NULL into a **big.Rat sets it to nil.
+type Numeric big.Rat
+
+// Value satisfies the driver.Valuer interface.
+func (n *Numeric) Value() (driver.Value, error) {
+	if n == nil {
+		return nil, nil
+	}
+	s, err := formatRat((*big.Rat)(n))
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Scan satisfies the sql.Scanner interface. NULL is scanned as zero.
+func (n *Numeric) Scan(src interface{}) error {
+	r := (*big.Rat)(n)
+	switch v := src.(type) {
+	case nil:
+		r.SetInt64(0)
+	case []byte:
+		return parseRat(r, string(v))
+	case string:
+		return parseRat(r, v)
+	case int64:
+		r.SetInt64(v)
+	case float64:
+		if r.SetFloat64(v) == nil {
+			return fmt.Errorf("upper: can't scan %v into Numeric", v)
+		}
+	default:
+		return fmt.Errorf("upper: can't scan %T into Numeric", src)
+	}
+	return nil
+}
+
+func (n *Numeric) String() string {
+	s, err := formatRat((*big.Rat)(n))
+	if err != nil {
+		return (*big.Rat)(n).String()
+	}
+	return s
+}
+
+// nullNumeric scans a nullable NUMERIC into a **big.Rat.
+type nullNumeric struct {
+	dst **big.Rat
+}
+
+func (n nullNumeric) Scan(src interface{}) error {
+	if src == nil {
+		*n.dst = nil
+		return nil
+	}
+	r := new(big.Rat)
+	if err := (*Numeric)(r).Scan(src); err != nil {
+		return err
+	}
+	*n.dst = r
+	return nil
+}
+
+func parseRat(r *big.Rat, s string) error {
+	if _, ok := r.SetString(s); !ok {
+		return fmt.Errorf("upper: can't scan %q into Numeric", s)
+	}
+	return nil
+}
+
+// formatRat formats r as a plain decimal number with as many digits after the
+// decimal point as it needs, never using scientific notation.
+func formatRat(r *big.Rat) (string, error) {
+	if r.IsInt() {
+		return r.Num().String(), nil
+	}
+
+	var (
+		d     = new(big.Int).Set(r.Denom())
+		m     = new(big.Int)
+		twos  = 0
+		fives = 0
+	)
+	for _, f := range []struct {
+		n     int64
+		count *int
+	}{{2, &twos}, {5, &fives}} {
+		div := big.NewInt(f.n)
+		for {
+			q, rem := new(big.Int).QuoRem(d, div, m)
+			if rem.Sign() != 0 {
+				break
+			}
+			d = q
+			*f.count++
+		}
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return "", ErrInexactNumeric
+	}
+
+	scale := twos
+	if fives > scale {
+		scale = fives
+	}
+	return r.FloatString(scale), nil
+}
+
+var (
+	_ driver.Valuer = &Numeric{}
+)
@@ -22,13 +22,20 @@
 package postgresql
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"math/big"
 	"math/rand"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -36,6 +43,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter"
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 	"github.com/frazercomputing/upper-io-db/testsuite"
 )
@@ -985,6 +993,1607 @@ func (s *AdapterTests) TestEscapeQuestionMark() {
 	}
 }
 
+func (s *AdapterTests) TestConnectInit() {
+	sess, err := Open(settings)
+	s.NoError(err)
+	defer sess.Close()
+
+	var calls int32
+	err = sess.(Database).SetConnectInit(func(ctx context.Context, conn *sql.Conn) error {
+		atomic.AddInt32(&calls, 1)
+		_, err := conn.ExecContext(ctx, `SET application_name = 'connect_init'`)
+		return err
+	})
+	s.NoError(err)
+
+	for i := 0; i < 5; i++ {
+		row, err := sess.QueryRow(`SHOW application_name`)
+		s.NoError(err)
+
+		var name string
+		s.NoError(row.Scan(&name))
+		s.Equal("connect_init", name)
+	}
+
+	// Idle connections are reused, fn runs once per connection and not once
+	// per query.
+	s.Equal(int32(1), atomic.LoadInt32(&calls))
+
+	{
+		sess, err := New(sess.Driver().(*sql.DB))
+		s.NoError(err)
+		s.Equal(db.ErrUnsupported, sess.(Database).SetConnectInit(nil))
+	}
+}
+
+func (s *AdapterTests) TestWarmup() {
+	sess, err := Open(settings)
+	s.NoError(err)
+	defer sess.Close()
+
+	var calls int32
+	err = sess.(Database).SetConnectInit(func(ctx context.Context, conn *sql.Conn) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	s.NoError(err)
+
+	sess.SetMaxIdleConns(4)
+	s.NoError(sess.Warmup(context.Background(), 3))
+	s.Equal(int32(3), atomic.LoadInt32(&calls))
+	s.Equal(3, sess.Driver().(*sql.DB).Stats().OpenConnections)
+
+	// Warm connections are reused.
+	for i := 0; i < 3; i++ {
+		_, err := sess.Exec(`SELECT 1`)
+		s.NoError(err)
+	}
+	s.Equal(int32(3), atomic.LoadInt32(&calls))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Error(sess.Warmup(canceled, 4))
+}
+
+func (s *AdapterTests) TestTxSetLocal() {
+	sess := s.SQLBuilder()
+
+	var currentUser string
+	{
+		row, err := sess.QueryRow(`SELECT current_user`)
+		s.NoError(err)
+		s.NoError(row.Scan(&currentUser))
+	}
+
+	err := sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		pgTx := tx.(Tx)
+
+		s.NoError(pgTx.SetLocal("app.current_user", "42"))
+		s.NoError(pgTx.SetRole(currentUser))
+
+		var value, role string
+		row, err := tx.QueryRow(`SELECT current_setting('app.current_user'), current_user`)
+		s.NoError(err)
+		s.NoError(row.Scan(&value, &role))
+
+		s.Equal("42", value)
+		s.Equal(currentUser, role)
+		return nil
+	})
+	s.NoError(err)
+
+	{
+		var value sql.NullString
+		row, err := sess.QueryRow(`SELECT current_setting('app.current_user', true)`)
+		s.NoError(err)
+		s.NoError(row.Scan(&value))
+		s.Equal("", value.String)
+	}
+}
+
+func (s *AdapterTests) TestTxCreateTempTable() {
+	sess := s.SQLBuilder()
+
+	type staging struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	var tableName string
+	err := sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		col, err := tx.(Tx).CreateTempTable("staging", staging{})
+		if err != nil {
+			return err
+		}
+		tableName = col.Name()
+		s.True(strings.HasPrefix(tableName, "staging_"))
+
+		_, err = col.Insert(staging{ID: 10, Name: "Ozzie"})
+		s.NoError(err)
+
+		var items []staging
+		s.NoError(col.Find().All(&items))
+		s.Equal([]staging{{ID: 10, Name: "Ozzie"}}, items)
+		return nil
+	})
+	s.NoError(err)
+
+	var exists bool
+	row, err := sess.QueryRow(`SELECT to_regclass(?) IS NOT NULL`, tableName)
+	s.NoError(err)
+	s.NoError(row.Scan(&exists))
+	s.False(exists)
+}
+
+func (s *AdapterTests) TestTxDeferConstraints() {
+	sess := s.SQLBuilder()
+
+	queries := []string{
+		`DROP TABLE IF EXISTS deferred_children`,
+		`DROP TABLE IF EXISTS deferred_parents`,
+		`CREATE TABLE deferred_parents (id INTEGER PRIMARY KEY)`,
+		`CREATE TABLE deferred_children (
+			id INTEGER PRIMARY KEY,
+			parent_id INTEGER NOT NULL,
+			CONSTRAINT deferred_children_parent_fkey FOREIGN KEY (parent_id) REFERENCES deferred_parents (id) DEFERRABLE INITIALLY IMMEDIATE
+		)`,
+	}
+	for _, q := range queries {
+		_, err := sess.Exec(q)
+		s.NoError(err)
+	}
+
+	insertOutOfOrder := func(tx sqlbuilder.Tx, id int) error {
+		if _, err := tx.Collection("deferred_children").Insert(map[string]int{"id": id, "parent_id": id}); err != nil {
+			return err
+		}
+		_, err := tx.Collection("deferred_parents").Insert(map[string]int{"id": id})
+		return err
+	}
+
+	// Without deferring, the child row is rejected right away.
+	err := sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		return insertOutOfOrder(tx, 1)
+	})
+	s.Error(err)
+
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		if err := tx.(Tx).DeferConstraints(); err != nil {
+			return err
+		}
+		return insertOutOfOrder(tx, 2)
+	})
+	s.NoError(err)
+
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		if err := tx.(Tx).DeferConstraints("deferred_children_parent_fkey"); err != nil {
+			return err
+		}
+		return insertOutOfOrder(tx, 3)
+	})
+	s.NoError(err)
+
+	// Deferred checks still run on commit.
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		if err := tx.(Tx).DeferConstraints(); err != nil {
+			return err
+		}
+		_, err := tx.Collection("deferred_children").Insert(map[string]int{"id": 4, "parent_id": 4})
+		return err
+	})
+	s.Error(err)
+
+	count, err := sess.Collection("deferred_children").Find().Count()
+	s.NoError(err)
+	s.Equal(uint64(2), count)
+}
+
+func (s *AdapterTests) TestErrorFields() {
+	sess := s.SQLBuilder()
+
+	col := sess.Collection("varchar_primary_key")
+	s.NoError(col.Truncate())
+
+	_, err := col.Insert(map[string]string{"address": "1234", "name": "a"})
+	s.NoError(err)
+
+	_, err = col.Insert(map[string]string{"address": "1234", "name": "b"})
+	s.Error(err)
+
+	pgErr, ok := err.(*Error)
+	s.True(ok)
+	s.Equal("23505", string(pgErr.Code))
+	s.Equal("varchar_primary_key", pgErr.Table)
+	s.Equal("varchar_primary_key_pkey", pgErr.Constraint)
+	s.NotEmpty(pgErr.Detail)
+	s.Contains(fmt.Sprintf("%+v", err), "DETAIL: "+pgErr.Detail)
+}
+
+func (s *AdapterTests) TestDefaultQueryTimeout() {
+	sess := s.SQLBuilder()
+	defer sess.SetDefaultQueryTimeout(0)
+
+	sess.SetDefaultQueryTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	_, err := sess.Exec(`SELECT pg_sleep(2)`)
+	s.Error(err)
+	s.True(time.Since(start) < time.Second)
+
+	// Transactions inherit the timeout.
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		_, err := tx.Exec(`SELECT pg_sleep(2)`)
+		return err
+	})
+	s.Error(err)
+	s.True(time.Since(start) < 2*time.Second)
+
+	// Queries that finish in time are not affected.
+	_, err = sess.Exec(`SELECT pg_sleep(0.01)`)
+	s.NoError(err)
+}
+
+func (s *AdapterTests) TestAcquireTimeout() {
+	sess := s.SQLBuilder()
+	defer sess.SetMaxOpenConns(sess.MaxOpenConns())
+	defer sess.SetAcquireTimeout(0)
+
+	sess.SetMaxOpenConns(1)
+	sess.SetAcquireTimeout(100 * time.Millisecond)
+
+	// The only connection is held by the transaction.
+	tx, err := sess.NewTx(context.Background())
+	s.NoError(err)
+
+	start := time.Now()
+	_, err = sess.Exec(`SELECT 1`)
+	s.Equal(db.ErrTooManyClients, err)
+	s.True(time.Since(start) < time.Second)
+
+	_, err = sess.Query(`SELECT 1`)
+	s.Equal(db.ErrTooManyClients, err)
+
+	s.NoError(tx.Rollback())
+
+	var one int
+	row, err := sess.QueryRow(`SELECT 1`)
+	s.NoError(err)
+	s.NoError(row.Scan(&one))
+	s.Equal(1, one)
+
+	rows, err := sess.Query(`SELECT 1`)
+	s.NoError(err)
+	s.NoError(rows.Close())
+}
+
+func (s *AdapterTests) TestQueryCanceled() {
+	sess := s.SQLBuilder()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	_, err := sess.ExecContext(ctx, `SELECT pg_sleep(2)`)
+	if s.Error(err) {
+		s.True(isKind(err, context.Canceled))
+		s.False(isKind(err, db.ErrQueryTimeout))
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = sess.QueryContext(ctx, `SELECT pg_sleep(2)`)
+	if s.Error(err) {
+		s.True(isKind(err, db.ErrQueryTimeout))
+		s.False(isKind(err, context.Canceled))
+	}
+
+	// Server-side timeouts are reported in the same way.
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		if _, err := tx.Exec(`SET LOCAL statement_timeout = 100`); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`SELECT pg_sleep(2)`)
+		return err
+	})
+	if s.Error(err) {
+		s.True(isKind(err, db.ErrQueryTimeout))
+	}
+}
+
+func (s *AdapterTests) TestLockDiagnostics() {
+	sess := s.SQLBuilder()
+	defer sess.SetLockDiagnostics(false)
+
+	sess.SetLockDiagnostics(true)
+
+	tx, err := sess.NewTx(context.Background())
+	s.NoError(err)
+	defer tx.Rollback()
+
+	_, err = tx.Update("artist").Set("name = name").Exec()
+	s.NoError(err)
+
+	var pid int64
+	row, err := tx.QueryRow(`SELECT pg_backend_pid()`)
+	s.NoError(err)
+	s.NoError(row.Scan(&pid))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err = sess.Update("artist").Set("name = name").ExecContext(ctx)
+	if s.Error(err) {
+		lockErr, ok := err.(*db.LockWaitError)
+		if s.True(ok) {
+			s.Equal("artist", lockErr.Table)
+			s.True(isKind(lockErr, db.ErrQueryTimeout))
+
+			pids := []int64{}
+			for _, h := range lockErr.Holders {
+				pids = append(pids, h.PID)
+			}
+			s.Contains(pids, pid)
+		}
+	}
+}
+
+func (s *AdapterTests) TestValuesList() {
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+	err := artist.Truncate()
+	s.NoError(err)
+
+	for _, name := range []string{"Ozzie", "Flea"} {
+		_, err := artist.Insert(map[string]string{"name": name})
+		s.NoError(err)
+	}
+
+	renames := sqlbuilder.Values([][]interface{}{
+		{"Ozzie", "Ozzy"},
+		{"Flea", "Michael"},
+		{"Nobody", "Someone"},
+	}, []string{"old", "new"}).As("r")
+
+	var renamed []struct {
+		New string `db:"new"`
+	}
+	err = sess.Select("r.new").From("artist AS a").Join(renames).On("r.old = a.name").OrderBy("r.new").All(&renamed)
+	s.NoError(err)
+	if s.Len(renamed, 2) {
+		s.Equal("Michael", renamed[0].New)
+		s.Equal("Ozzy", renamed[1].New)
+	}
+
+	res, err := sess.Update("artist").Set("name = r.new").From(renames).Where("artist.name = r.old").Exec()
+	s.NoError(err)
+	rows, err := res.RowsAffected()
+	s.NoError(err)
+	s.Equal(int64(2), rows)
+
+	count, err := artist.Find("name", []string{"Ozzy", "Michael"}).Count()
+	s.NoError(err)
+	s.Equal(uint64(2), count)
+}
+
+func (s *AdapterTests) TestNetworkTypes() {
+	sess := s.SQLBuilder()
+
+	queries := []string{
+		`DROP TABLE IF EXISTS network_hosts`,
+		`CREATE TABLE network_hosts (id SERIAL PRIMARY KEY, addr INET, subnet CIDR, mac MACADDR)`,
+	}
+	for _, query := range queries {
+		_, err := sess.Exec(query)
+		s.NoError(err)
+	}
+
+	type host struct {
+		ID     int  `db:"id,omitempty"`
+		Addr   Inet `db:"addr"`
+		Subnet Inet `db:"subnet"`
+		MAC    MAC  `db:"mac"`
+	}
+
+	parse := func(in string) Inet {
+		var n Inet
+		s.NoError(n.Scan(in))
+		return n
+	}
+
+	mac, err := net.ParseMAC("08:00:2b:01:02:03")
+	s.NoError(err)
+
+	hosts := sess.Collection("network_hosts")
+	for _, in := range []struct {
+		addr   string
+		subnet string
+	}{
+		{"192.168.0.10", "192.168.0.0/24"},
+		{"10.1.2.3/8", "10.0.0.0/8"},
+		{"2001:db8::5", "2001:db8::/32"},
+	} {
+		_, err := hosts.Insert(host{Addr: parse(in.addr), Subnet: parse(in.subnet), MAC: MAC(mac)})
+		s.NoError(err)
+	}
+
+	var all []host
+	s.NoError(hosts.Find().OrderBy("id").All(&all))
+	if s.Len(all, 3) {
+		s.Equal("192.168.0.10", all[0].Addr.String())
+		s.Equal("10.1.2.3/8", all[1].Addr.String())
+		s.Equal("2001:db8::5", all[2].Addr.String())
+		s.Equal("192.168.0.0/24", all[0].Subnet.String())
+		s.Equal("2001:db8::/32", all[2].Subnet.String())
+		s.Equal(mac.String(), all[0].MAC.String())
+	}
+
+	var ip net.IP
+	row, err := sess.QueryRow(`SELECT addr FROM network_hosts WHERE id = ?`, all[0].ID)
+	s.NoError(err)
+	s.NoError(row.Scan(&ip))
+	s.Equal("192.168.0.10", ip.String())
+
+	count, err := hosts.Find(db.Cond{"addr": ContainedBy("192.168.0.0/16")}).Count()
+	s.NoError(err)
+	s.Equal(uint64(1), count)
+
+	count, err = hosts.Find(db.Cond{"subnet": Contains(net.ParseIP("10.200.0.1"))}).Count()
+	s.NoError(err)
+	s.Equal(uint64(1), count)
+
+	count, err = hosts.Find(db.Cond{"subnet": Overlaps("2001:db8:1::/48")}).Count()
+	s.NoError(err)
+	s.Equal(uint64(1), count)
+
+	count, err = hosts.Find(db.Cond{"subnet": ContainedByOrEquals("10.0.0.0/8")}).Count()
+	s.NoError(err)
+	s.Equal(uint64(1), count)
+}
+
+func (s *AdapterTests) TestArray() {
+	sess := s.SQLBuilder()
+
+	{
+		var out [][]int64
+		row, err := sess.QueryRow(`SELECT ?::integer[][]`, Array([][]int{{1, 2}, {3, 4}}))
+		s.NoError(err)
+		s.NoError(row.Scan(Array(&out)))
+		s.Equal([][]int64{{1, 2}, {3, 4}}, out)
+	}
+
+	{
+		type mood string
+
+		var out []mood
+		row, err := sess.QueryRow(`SELECT ?::text[]`, Array([]mood{"happy", `"quoted"`, `back\slash`}))
+		s.NoError(err)
+		s.NoError(row.Scan(Array(&out)))
+		s.Equal([]mood{"happy", `"quoted"`, `back\slash`}, out)
+	}
+
+	{
+		var out []string
+		row, err := sess.QueryRow(`SELECT ?::text[]`, Array([]Money{150, -1}))
+		s.NoError(err)
+		s.NoError(row.Scan(Array(&out)))
+		s.Equal([]string{"1.50", "-0.01"}, out)
+	}
+}
+
+func (s *AdapterTests) TestNullArrays() {
+	sess := s.SQLBuilder()
+
+	queries := []string{
+		`DROP TABLE IF EXISTS null_arrays`,
+		`CREATE TABLE null_arrays (id SERIAL PRIMARY KEY, ints INTEGER[], names TEXT[])`,
+	}
+	for _, query := range queries {
+		_, err := sess.Exec(query)
+		s.NoError(err)
+	}
+
+	type item struct {
+		ID    int       `db:"id,omitempty"`
+		Ints  []*int64  `db:"ints"`
+		Names []*string `db:"names"`
+	}
+
+	one, name := int64(1), "a"
+	items := []item{
+		{Ints: []*int64{nil}, Names: []*string{nil}},
+		{Ints: []*int64{}, Names: []*string{}},
+		{Ints: nil, Names: nil},
+		{Ints: []*int64{&one, nil}, Names: []*string{nil, &name}},
+	}
+
+	col := sess.Collection("null_arrays")
+	for i := range items {
+		_, err := col.Insert(items[i])
+		s.NoError(err)
+	}
+
+	for _, cond := range []string{"ints = '{NULL}'", "ints = '{}'", "ints IS NULL"} {
+		count, err := col.Find(db.Raw(cond)).Count()
+		s.NoError(err)
+		s.Equal(uint64(1), count, cond)
+	}
+
+	var out []item
+	s.NoError(col.Find().OrderBy("id").All(&out))
+	if s.Len(out, len(items)) {
+		for i := range items {
+			s.Equal(items[i].Ints, out[i].Ints)
+			s.Equal(items[i].Names, out[i].Names)
+		}
+	}
+}
+
+func (s *AdapterTests) TestRegisterType() {
+	sess := s.SQLBuilder()
+
+	RegisterType(testTemperature{}, wrapTestTemperature)
+	defer RegisterType(testTemperature{}, nil)
+
+	queries := []string{
+		`DROP TABLE IF EXISTS readings`,
+		`CREATE TABLE readings (id SERIAL PRIMARY KEY, temp NUMERIC(5, 2))`,
+	}
+	for _, query := range queries {
+		_, err := sess.Exec(query)
+		s.NoError(err)
+	}
+
+	type reading struct {
+		ID   int             `db:"id,omitempty"`
+		Temp testTemperature `db:"temp"`
+	}
+
+	col := sess.Collection("readings")
+	_, err := col.Insert(reading{Temp: testTemperature{Celsius: -3.25}})
+	s.NoError(err)
+
+	var text string
+	row, err := sess.QueryRow(`SELECT temp::text FROM readings`)
+	s.NoError(err)
+	s.NoError(row.Scan(&text))
+	s.Equal("-3.25", text)
+
+	var out reading
+	s.NoError(col.Find().One(&out))
+	s.Equal(-3.25, out.Temp.Celsius)
+}
+
+func (s *AdapterTests) TestSessionRegisterType() {
+	sess, err := Open(settings)
+	s.NoError(err)
+	defer sess.Close()
+
+	sess.(Database).RegisterType(testTemperature{}, wrapTestTemperature)
+
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		var out []struct {
+			Temp testTemperature `db:"temp"`
+		}
+		err := tx.Iterator(`SELECT ?::numeric + 1 AS temp`, testTemperature{Celsius: 1.5}).All(&out)
+		if err != nil {
+			return err
+		}
+		if s.Len(out, 1) {
+			s.Equal(2.5, out[0].Temp.Celsius)
+		}
+		return nil
+	})
+	s.NoError(err)
+}
+
+func (s *AdapterTests) TestUpsertInserted() {
+	sess := s.SQLBuilder()
+
+	queries := []string{
+		`DROP TABLE IF EXISTS subscribers`,
+		`CREATE TABLE subscribers (id SERIAL PRIMARY KEY, email TEXT UNIQUE NOT NULL, name TEXT)`,
+	}
+	for _, query := range queries {
+		_, err := sess.Exec(query)
+		s.NoError(err)
+	}
+
+	type result struct {
+		ID       int64 `db:"id"`
+		Inserted bool  `db:"inserted"`
+	}
+
+	upsert := func(name string) (res result, err error) {
+		err = sess.InsertInto("subscribers").
+			Values(map[string]string{"email": "ozzy@example.com", "name": name}).
+			OnConflict("email").DoUpdate().
+			Returning("id").ReturningInserted().
+			Iterator().One(&res)
+		return
+	}
+
+	first, err := upsert("Ozzy")
+	s.NoError(err)
+	s.True(first.Inserted)
+
+	second, err := upsert("Ozzy Osbourne")
+	s.NoError(err)
+	s.False(second.Inserted)
+	s.Equal(first.ID, second.ID)
+
+	var name string
+	row, err := sess.QueryRow(`SELECT name FROM subscribers WHERE id = ?`, first.ID)
+	s.NoError(err)
+	s.NoError(row.Scan(&name))
+	s.Equal("Ozzy Osbourne", name)
+
+	// Conflicting rows are not returned by DoNothing.
+	var skipped []result
+	err = sess.InsertInto("subscribers").
+		Values(map[string]string{"email": "ozzy@example.com", "name": "Nobody"}).
+		OnConflict("email").
+		Returning("id").ReturningInserted().
+		Iterator().All(&skipped)
+	s.NoError(err)
+	s.Len(skipped, 0)
+}
+
+func (s *AdapterTests) TestInsertReturningGenerated() {
+	sess := s.SQLBuilder()
+
+	queries := []string{
+		`DROP TABLE IF EXISTS notes`,
+		`CREATE TABLE notes (id SERIAL PRIMARY KEY, body TEXT NOT NULL, created_at TIMESTAMP NOT NULL DEFAULT now())`,
+	}
+	for _, query := range queries {
+		_, err := sess.Exec(query)
+		s.NoError(err)
+	}
+
+	type note struct {
+		ID        int64     `db:"id,omitempty"`
+		Body      string    `db:"body"`
+		CreatedAt time.Time `db:"created_at,omitempty"`
+	}
+
+	item := note{Body: "Hello"}
+	err := s.Session().Collection("notes").InsertReturning(&item)
+	s.NoError(err)
+
+	s.NotZero(item.ID)
+	s.False(item.CreatedAt.IsZero())
+	s.Equal("Hello", item.Body)
+
+	var stored note
+	err = s.Session().Collection("notes").Find(item.ID).One(&stored)
+	s.NoError(err)
+	s.Equal(item.ID, stored.ID)
+	s.Equal(item.CreatedAt.Unix(), stored.CreatedAt.Unix())
+}
+
+func (s *AdapterTests) TestByteaColumns() {
+	sess := s.SQLBuilder()
+
+	queries := []string{
+		`DROP TABLE IF EXISTS blobs`,
+		`CREATE TABLE blobs (id SERIAL PRIMARY KEY, data BYTEA, parts BYTEA[])`,
+	}
+	for _, query := range queries {
+		_, err := sess.Exec(query)
+		s.NoError(err)
+	}
+
+	type blob struct {
+		ID    int64    `db:"id,omitempty"`
+		Data  []byte   `db:"data"`
+		Parts [][]byte `db:"parts"`
+	}
+
+	// Bytes that are not valid text, nor a valid array literal.
+	data := []byte{0, '{', '\\', 0xff, ',', '}'}
+	parts := [][]byte{[]byte("a"), nil, {0, 0xff}, {}}
+
+	item := blob{Data: data, Parts: parts}
+	err := s.Session().Collection("blobs").InsertReturning(&item)
+	s.NoError(err)
+
+	var stored blob
+	err = sess.SelectFrom("blobs").Where("data = ?", data).One(&stored)
+	s.NoError(err)
+	s.Equal(data, stored.Data)
+	s.Equal(parts, stored.Parts)
+
+	var scanned []byte
+	var scannedParts [][]byte
+	row, err := sess.QueryRow(`SELECT data, parts FROM blobs WHERE id = ?`, item.ID)
+	s.NoError(err)
+	s.NoError(row.Scan(&scanned, (*ByteaArray)(&scannedParts)))
+	s.Equal(data, scanned)
+	s.Equal(parts, scannedParts)
+}
+
+func (s *AdapterTests) TestCancelStopsBackend() {
+	sess := s.SQLBuilder()
+
+	sess.SetPreparedStatementCache(true)
+	defer sess.SetPreparedStatementCache(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := sess.WithContext(ctx).Exec(`SELECT pg_sleep(30)`)
+	s.Error(err)
+	s.True(time.Since(start) < 5*time.Second)
+
+	// The statement went through the prepared statement cache and must not
+	// keep running on the server: lib/pq cancels prepared statements too.
+	var running int
+	for i := 0; i < 20; i++ {
+		row, err := sess.QueryRow(`SELECT COUNT(1) FROM pg_stat_activity WHERE state = 'active' AND query = 'SELECT pg_sleep(30)'`)
+		s.NoError(err)
+		s.NoError(row.Scan(&running))
+		if running == 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	s.Equal(0, running)
+}
+
+func (s *AdapterTests) TestServerVersion() {
+	sess := s.Session().(Database)
+
+	version, err := sess.ServerVersion()
+	s.NoError(err)
+
+	v, ok := parseServerVersion(version)
+	s.True(ok)
+	s.True(v.major >= 9)
+
+	cached, err := sess.ServerVersion()
+	s.NoError(err)
+	s.Equal(version, cached)
+
+	s.Equal(v.atLeast(versionOnConflict), sess.SupportsOnConflict())
+	s.Equal(v.atLeast(versionMerge), sess.SupportsMerge())
+}
+
+func (s *AdapterTests) TestUpsertFallback() {
+	sess := s.Session().(*database)
+
+	// Pretend the server has no ON CONFLICT.
+	prev := sess.serverVersion
+	sess.serverVersion = &atomic.Value{}
+	sess.serverVersion.Store("9.4.26")
+	defer func() {
+		sess.serverVersion = prev
+	}()
+
+	queries := []string{
+		`DROP TABLE IF EXISTS subscribers`,
+		`CREATE TABLE subscribers (id SERIAL PRIMARY KEY, email TEXT UNIQUE NOT NULL, name TEXT)`,
+	}
+	for _, query := range queries {
+		_, err := sess.Exec(query)
+		s.NoError(err)
+	}
+
+	upsert := func(name string) sqlbuilder.Inserter {
+		return sess.InsertInto("subscribers").
+			Columns("email", "name").
+			Values("ozzy@example.com", name).
+			Values("flea@example.com", "Flea").
+			OnConflict("email")
+	}
+
+	res, err := upsert("Ozzy").DoUpdate().Exec()
+	s.NoError(err)
+	n, err := res.RowsAffected()
+	s.NoError(err)
+	s.Equal(int64(2), n)
+
+	_, err = upsert("Ozzy Osbourne").DoUpdate().Exec()
+	s.NoError(err)
+
+	_, err = upsert("Nobody").DoNothing().Exec()
+	s.NoError(err)
+
+	var names []string
+	err = sess.Select("name").From("subscribers").OrderBy("email").All(&names)
+	s.NoError(err)
+	s.Equal([]string{"Flea", "Ozzy Osbourne"}, names)
+
+	// Within a transaction.
+	err = sess.Tx(nil, func(tx sqlbuilder.Tx) error {
+		_, err := tx.InsertInto("subscribers").
+			Values(map[string]string{"email": "ozzy@example.com", "name": "Ozzy"}).
+			OnConflict("email").DoUpdate().
+			Exec()
+		return err
+	})
+	s.NoError(err)
+
+	// RETURNING needs ON CONFLICT.
+	var id int64
+	err = upsert("Ozzy").DoUpdate().Returning("id").Iterator().One(&id)
+	if s.IsType(&VersionError{}, err) {
+		s.Equal("ON CONFLICT", err.(*VersionError).Feature)
+	}
+}
+
+func (s *AdapterTests) TestExecScript() {
+	sess := s.Session().(*database)
+
+	results, err := sess.ExecScript(context.Background(), `
+		DROP TABLE IF EXISTS scripted;
+		CREATE TABLE scripted (id SERIAL PRIMARY KEY, name TEXT, tags JSONB);
+
+		-- Trims names; see the trigger below.
+		CREATE OR REPLACE FUNCTION scripted_trim() RETURNS trigger AS $$
+		BEGIN
+			NEW.name := trim(NEW.name);
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS scripted_trim ON scripted;
+		CREATE TRIGGER scripted_trim BEFORE INSERT ON scripted
+			FOR EACH ROW EXECUTE PROCEDURE scripted_trim();
+
+		INSERT INTO scripted (name, tags) VALUES ('  a;b  ', '{"x": 1}'), ('c', '{}');
+		DELETE FROM scripted WHERE tags ? 'x' AND name = 'nobody';
+	`)
+	s.NoError(err)
+	s.Equal(7, len(results))
+
+	n, err := results[5].RowsAffected()
+	s.NoError(err)
+	s.Equal(int64(2), n)
+
+	var names []string
+	err = sess.Select("name").From("scripted").OrderBy("id").All(&names)
+	s.NoError(err)
+	s.Equal([]string{"a;b", "c"}, names)
+
+	// A failing statement rolls back the whole script.
+	_, err = sess.ExecScript(context.Background(), `
+		INSERT INTO scripted (name) VALUES ('d');
+		INSERT INTO missing_table (name) VALUES ('e');
+	`)
+	s.Error(err)
+
+	count, err := sess.Collection("scripted").Find().Count()
+	s.NoError(err)
+	s.Equal(uint64(2), count)
+}
+
+func (s *AdapterTests) TestDollarQuotedPlaceholders() {
+	sess := s.SQLBuilder()
+
+	_, err := sess.Exec(`
+		CREATE OR REPLACE FUNCTION has_key(doc jsonb, k text) RETURNS boolean AS $fn$
+			-- ? is the jsonb operator here and $1 is the first argument.
+			SELECT doc ? k AND $1::text <> '?'
+		$fn$ LANGUAGE sql
+	`)
+	s.NoError(err)
+
+	var found bool
+	row, err := sess.QueryRow(`SELECT has_key(?::jsonb, ?) /* ? */`, `{"a": 1}`, "a")
+	s.NoError(err)
+	s.NoError(row.Scan(&found))
+	s.True(found)
+
+	row, err = sess.QueryRow(`SELECT has_key(?::jsonb, $$?$$)`, `{"a": 1}`)
+	s.NoError(err)
+	s.NoError(row.Scan(&found))
+	s.False(found)
+}
+
+func (s *AdapterTests) TestOutbox() {
+	sess := s.SQLBuilder()
+
+	_, err := sess.Exec(`DROP TABLE IF EXISTS outbox`)
+	s.NoError(err)
+	_, err = sess.Exec(`CREATE TABLE outbox (
+		id BIGSERIAL PRIMARY KEY,
+		type TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		published_at TIMESTAMPTZ
+	)`)
+	s.NoError(err)
+
+	type orderPlaced struct {
+		OrderID int64 `json:"order_id"`
+	}
+
+	err = sess.Tx(nil, func(tx sqlbuilder.Tx) error {
+		for i := 1; i <= 3; i++ {
+			if err := tx.Outbox(orderPlaced{OrderID: int64(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	s.NoError(err)
+
+	// Events of rolled back transactions are discarded along with them.
+	err = sess.Tx(nil, func(tx sqlbuilder.Tx) error {
+		if err := tx.Outbox(orderPlaced{OrderID: 4}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	s.Error(err)
+
+	// Handler errors stop draining, the messages handled before are
+	// published.
+	var orders []int64
+	errHandler := errors.New("broker is down")
+	err = sess.DrainOutbox(context.Background(), func(msg sqlbuilder.OutboxMessage) error {
+		if len(orders) == 2 {
+			return errHandler
+		}
+		s.Equal("postgresql.orderPlaced", msg.Type)
+		var ev orderPlaced
+		if err := msg.Decode(&ev); err != nil {
+			return err
+		}
+		orders = append(orders, ev.OrderID)
+		return nil
+	})
+	s.Equal(errHandler, err)
+	s.Equal([]int64{1, 2}, orders)
+
+	err = sess.DrainOutbox(context.Background(), func(msg sqlbuilder.OutboxMessage) error {
+		var ev orderPlaced
+		if err := msg.Decode(&ev); err != nil {
+			return err
+		}
+		orders = append(orders, ev.OrderID)
+		return nil
+	})
+	s.NoError(err)
+	s.Equal([]int64{1, 2, 3}, orders)
+
+	count, err := sess.Collection("outbox").Find("published_at IS NULL").Count()
+	s.NoError(err)
+	s.Equal(uint64(0), count)
+
+	// Outbox is only available within transactions.
+	s.Equal(db.ErrNotWithinTransaction, s.Session().(*database).Outbox(orderPlaced{}))
+}
+
+func (s *AdapterTests) TestWithTotalCount() {
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+	s.NoError(artist.Truncate())
+	for i := 0; i < 5; i++ {
+		_, err := artist.Insert(map[string]string{"name": fmt.Sprintf("Artist %d", i)})
+		s.NoError(err)
+	}
+
+	var total int
+	var page []struct {
+		Name  string `db:"name"`
+		Total int    `db:"total"`
+	}
+	err := sess.SelectFrom("artist").
+		OrderBy("name").
+		Limit(2).Offset(2).
+		WithTotalCount("total", &total).
+		All(&page)
+	s.NoError(err)
+	s.Equal(5, total)
+	s.Len(page, 2)
+	s.Equal("Artist 2", page[0].Name)
+	s.Equal(5, page[0].Total)
+
+	var row map[string]interface{}
+	total = -1
+	err = sess.SelectFrom("artist").
+		Where("name", "Artist 4").
+		WithTotalCount("total", &total).
+		One(&row)
+	s.NoError(err)
+	s.Equal(1, total)
+
+	err = sess.SelectFrom("artist").
+		Limit(2).Offset(10).
+		WithTotalCount("total", &total).
+		All(&page)
+	s.NoError(err)
+	s.Len(page, 0)
+	s.Equal(0, total)
+}
+
+func (s *AdapterTests) TestReturningExpressions() {
+	sess := s.SQLBuilder()
+
+	var row struct {
+		ID    int64  `db:"id"`
+		Name  string `db:"artist_name"`
+		Upper string `db:"upper"`
+		Greet string `db:"greet"`
+	}
+	err := sess.InsertInto("artist").
+		Values(map[string]string{"name": "Chavela Vargas"}).
+		ReturningExpr("id", "name AS artist_name", db.Func("upper", db.Col("name")), db.Raw("? || name AS greet", "Hi, ")).
+		Iterator().One(&row)
+	s.NoError(err)
+	s.NotZero(row.ID)
+	s.Equal("Chavela Vargas", row.Name)
+	s.Equal("CHAVELA VARGAS", row.Upper)
+	s.Equal("Hi, Chavela Vargas", row.Greet)
+}
+
+func (s *AdapterTests) TestQueryComment() {
+	sess := s.SQLBuilder()
+	defer sess.SetQueryComment("")
+
+	sess.SetQueryComment("request:42")
+
+	var query string
+	row, err := sess.Select("query").
+		From("pg_stat_activity").
+		Where("pid = pg_backend_pid()").
+		Comment("checkout-service:recalc").
+		QueryRow()
+	s.NoError(err)
+	s.NoError(row.Scan(&query))
+
+	s.True(strings.HasPrefix(query, "/* request:42 */ /* checkout-service:recalc */ SELECT"), query)
+	s.Contains(query, "WHERE (pid = pg_backend_pid())")
+}
+
+func (s *AdapterTests) TestBatch() {
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+	s.NoError(artist.Truncate())
+
+	var artists []struct {
+		Name string `db:"name"`
+	}
+
+	batch := sess.(Database).Batch().
+		Queue(sess.InsertInto("artist").Values(map[string]string{"name": "Ozzie"})).
+		Queue(`INSERT INTO artist (name) VALUES (?)`, "Flea").
+		Queue(sess.Update("artist").Set("name", "Slash").Where("name = ?", "Flea")).
+		QueueQuery(&artists, sess.SelectFrom("artist").OrderBy("name"))
+	s.Equal(4, batch.Len())
+
+	results, err := batch.Send(context.Background())
+	s.NoError(err)
+	s.Equal(4, len(results))
+
+	for _, res := range results {
+		s.NoError(res.Err)
+	}
+	rowsAffected, err := results[2].Result.RowsAffected()
+	s.NoError(err)
+	s.Equal(int64(1), rowsAffected)
+	s.Nil(results[3].Result)
+
+	s.Equal(2, len(artists))
+	s.Equal("Ozzie", artists[0].Name)
+	s.Equal("Slash", artists[1].Name)
+
+	// A failed statement rolls back the whole batch.
+	results, err = sess.(Database).Batch().
+		Queue(`INSERT INTO artist (name) VALUES (?)`, "Chrono").
+		Queue(`INSERT INTO artist (id) VALUES (?)`, "not a number").
+		Queue(`DELETE FROM artist`).
+		Send(context.Background())
+	s.Error(err)
+	s.NoError(results[0].Err)
+	s.Equal(err, results[1].Err)
+	s.Equal(ErrBatchAborted, results[2].Err)
+
+	count, err := artist.Find().Count()
+	s.NoError(err)
+	s.Equal(uint64(2), count)
+}
+
+func (s *AdapterTests) TestNumeric() {
+	sess := s.SQLBuilder()
+
+	type account struct {
+		ID      int64    `db:"id"`
+		Balance big.Rat  `db:"balance,type=NUMERIC"`
+		Credit  *big.Rat `db:"credit,type=NUMERIC"`
+	}
+
+	err := sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		col, err := tx.(Tx).CreateTempTable("accounts", account{})
+		if err != nil {
+			return err
+		}
+
+		balance, _ := new(big.Rat).SetString("-12345678901234.123456")
+		_, err = col.Insert(account{ID: 1, Balance: *balance})
+		s.NoError(err)
+
+		credit := big.NewRat(1, 1000000000000)
+		_, err = col.Insert(account{ID: 2, Credit: credit})
+		s.NoError(err)
+
+		var items []account
+		s.NoError(col.Find().OrderBy("id").All(&items))
+		s.Equal(2, len(items))
+
+		s.Equal("-12345678901234.123456", (*Numeric)(&items[0].Balance).String())
+		s.Nil(items[0].Credit)
+		s.Equal("0", (*Numeric)(&items[1].Balance).String())
+		s.Equal(0, credit.Cmp(items[1].Credit))
+
+		var text string
+		row, err := tx.QueryRow(`SELECT credit::text FROM `+col.Name()+` WHERE id = 2`)
+		s.NoError(err)
+		s.NoError(row.Scan(&text))
+		s.Equal("0.000000000001", text)
+
+		_, err = col.Insert(account{ID: 3, Balance: *big.NewRat(1, 3)})
+		s.Error(err)
+		return nil
+	})
+	s.NoError(err)
+}
+
+func (s *AdapterTests) TestMoney() {
+	sess := s.SQLBuilder()
+
+	for _, locale := range []string{"C", "en_US.UTF-8", "de_DE.UTF-8"} {
+		tx, err := sess.NewTx(context.Background())
+		s.NoError(err)
+
+		if _, err := tx.Exec(`SELECT set_config('lc_monetary', ?, true)`, locale); err != nil {
+			// Locale not available on this server.
+			s.NoError(tx.Rollback())
+			continue
+		}
+
+		for _, amount := range []string{"0", "-1234567.89", "1234.5", "-0.01"} {
+			var m Money
+			row, err := tx.QueryRow(`SELECT ?::numeric::money`, amount)
+			s.NoError(err)
+			s.NoError(row.Scan(&m))
+
+			expected, err := sqladapter.ParseMoney(amount)
+			s.NoError(err)
+			s.Equal(Money(expected), m, locale+" "+amount)
+		}
+
+		s.NoError(tx.Rollback())
+	}
+
+	var text string
+	row, err := sess.QueryRow(`SELECT (?::money)::numeric::text`, Money(-123456))
+	s.NoError(err)
+	s.NoError(row.Scan(&text))
+	s.Equal("-1234.56", text)
+}
+
+func (s *AdapterTests) TestTimeZone() {
+	sess := s.SQLBuilder().(Database)
+	defer sess.SetTimeZone("")
+	defer sess.SetForceUTC(false)
+
+	s.NoError(sess.SetTimeZone("America/Mexico_City"))
+	s.Error(sess.SetTimeZone("Not/A_Zone"))
+
+	var name string
+	row, err := sess.QueryRow(`SELECT current_setting('TimeZone')`)
+	s.NoError(err)
+	s.NoError(row.Scan(&name))
+	s.Equal("America/Mexico_City", name)
+
+	type event struct {
+		At time.Time `db:"at"`
+	}
+
+	var item event
+	s.NoError(sess.Iterator(`SELECT '2019-01-01 12:00:00+00'::timestamptz AS at`).One(&item))
+	_, offset := item.At.Zone()
+	s.Equal(-6*60*60, offset)
+
+	sess.SetForceUTC(true)
+
+	s.NoError(sess.Iterator(`SELECT '2019-01-01 12:00:00+00'::timestamptz AS at`).One(&item))
+	s.Equal(time.UTC, item.At.Location())
+	s.Equal(time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC), item.At)
+
+	var at time.Time
+	s.NoError(sess.Iterator(`SELECT '2019-01-01 12:00:00+00'::timestamptz`).ScanOne(&at))
+	s.Equal(time.UTC, at.Location())
+}
+
+func (s *AdapterTests) TestInterval() {
+	sess := s.SQLBuilder()
+
+	for _, in := range []Interval{
+		{},
+		{Months: 14, Days: -3, Microseconds: 14706789000},
+		{Months: -1, Microseconds: -1},
+		{Days: 40, Microseconds: 360000000000},
+	} {
+		var out Interval
+		row, err := sess.QueryRow(`SELECT ?::interval`, in)
+		s.NoError(err)
+		s.NoError(row.Scan(&out))
+		s.Equal(in, out)
+	}
+
+	type job struct {
+		Timeout time.Duration `db:"timeout"`
+	}
+
+	var item job
+	s.NoError(sess.Iterator(`SELECT '1 day 00:00:30'::interval AS timeout`).One(&item))
+	s.Equal(24*time.Hour+30*time.Second, item.Timeout)
+
+	s.Error(sess.Iterator(`SELECT '1 mon'::interval AS timeout`).One(&item))
+}
+
+func (s *AdapterTests) TestStreamColumn() {
+	sess := s.SQLBuilder()
+
+	type document struct {
+		ID   int64  `db:"id"`
+		Data []byte `db:"data,type=BYTEA,omitempty"`
+	}
+
+	data := make([]byte, 5<<19)
+	for i := range data {
+		data[i] = byte(rand.Intn(256))
+	}
+
+	ctx := context.Background()
+	err := sess.Tx(ctx, func(tx sqlbuilder.Tx) error {
+		col, err := tx.(Tx).CreateTempTable("documents", document{})
+		if err != nil {
+			return err
+		}
+		_, err = col.Insert(document{ID: 1})
+		s.NoError(err)
+
+		res := col.Find(1)
+		s.NoError(res.(sqlbuilder.ColumnStreamer).WriteColumn(ctx, "data", bytes.NewReader(data)))
+
+		r, err := res.(sqlbuilder.ColumnStreamer).StreamColumn(ctx, "data")
+		s.NoError(err)
+		out, err := ioutil.ReadAll(r)
+		s.NoError(err)
+		s.NoError(r.Close())
+		s.True(bytes.Equal(data, out))
+
+		_, err = col.Find(2).(sqlbuilder.ColumnStreamer).StreamColumn(ctx, "data")
+		s.Equal(db.ErrNoMoreRows, err)
+		return nil
+	})
+	s.NoError(err)
+}
+
+func (s *AdapterTests) TestCopyTo() {
+	sess := s.SQLBuilder()
+
+	col := sess.Collection("varchar_primary_key")
+	s.NoError(col.Truncate())
+
+	_, err := col.Insert(map[string]string{"address": "1", "name": "Ozzie, Jr."})
+	s.NoError(err)
+	_, err = col.Insert(map[string]string{"address": "2", "name": "Ronnie"})
+	s.NoError(err)
+
+	var buf bytes.Buffer
+	n, err := sess.(Database).CopyTo(context.Background(), &buf,
+		sess.Select("address", "name").From("varchar_primary_key").Where("address > ?", "0").OrderBy("address"),
+		CopyOptions{Header: true},
+	)
+	s.NoError(err)
+	s.Equal(int64(2), n)
+	s.Equal("address,name\n1,\"Ozzie, Jr.\"\n2,Ronnie\n", buf.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = sess.(Database).CopyTo(ctx, &buf, sess.SelectFrom("varchar_primary_key"), CopyOptions{})
+	s.Error(err)
+}
+
+func (s *AdapterTests) TestExplain() {
+	sess := s.SQLBuilder()
+	ctx := context.Background()
+
+	plan, err := sess.SelectFrom("artist").Where("name", "Ozzie").Explain(ctx, false)
+	s.NoError(err)
+	s.Contains(plan, "artist")
+	s.False(strings.Contains(plan, "actual time"))
+
+	plan, err = sess.SelectFrom("artist").ExplainJSON(ctx, false)
+	s.NoError(err)
+	s.True(strings.HasPrefix(strings.TrimSpace(plan), "["))
+
+	count, err := sess.Collection("artist").Find().Count()
+	s.NoError(err)
+	s.NotZero(count)
+
+	// ANALYZE runs the statement, but its effects are rolled back.
+	plan, err = sess.DeleteFrom("artist").Explain(ctx, true)
+	s.NoError(err)
+	s.Contains(plan, "actual time")
+
+	after, err := sess.Collection("artist").Find().Count()
+	s.NoError(err)
+	s.Equal(count, after)
+}
+
+func (s *AdapterTests) TestAdvisoryLock() {
+	sess := s.SQLBuilder().(Database)
+	ctx := context.Background()
+	key := AdvisoryKeyPair(7, 42)
+
+	first := sess.AdvisoryLock(key)
+	second := sess.AdvisoryLock(key)
+
+	ok, err := first.TryLock(ctx)
+	s.NoError(err)
+	s.True(ok)
+
+	s.Equal(ErrAdvisoryLockHeld, first.Lock(ctx))
+
+	ok, err = second.TryLock(ctx)
+	s.NoError(err)
+	s.False(ok)
+
+	// Waiting for a lock can be canceled.
+	timeout, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	err = second.Lock(timeout)
+	cancel()
+	s.Error(err)
+
+	// The single key form is a different lock.
+	other := sess.AdvisoryLock(AdvisoryKey(42))
+	ok, err = other.TryLock(ctx)
+	s.NoError(err)
+	s.True(ok)
+	s.NoError(other.Unlock())
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		s.NoError(first.Unlock())
+	}()
+	s.NoError(second.Lock(ctx))
+	s.NoError(second.Unlock())
+	s.Equal(ErrAdvisoryLockNotHeld, second.Unlock())
+
+	err = sess.Tx(ctx, func(tx sqlbuilder.Tx) error {
+		if err := tx.(Tx).AdvisoryXactLock(key); err != nil {
+			return err
+		}
+
+		ok, err := tx.(Tx).TryAdvisoryXactLock(key)
+		s.NoError(err)
+		s.True(ok) // Locks can be taken more than once by the same session.
+
+		ok, err = first.TryLock(ctx)
+		s.NoError(err)
+		s.False(ok)
+		return nil
+	})
+	s.NoError(err)
+
+	// Released with the transaction.
+	ok, err = first.TryLock(ctx)
+	s.NoError(err)
+	s.True(ok)
+	s.NoError(first.Unlock())
+}
+
+func (s *AdapterTests) TestWithLock() {
+	sess := s.SQLBuilder().(Database)
+	ctx := context.Background()
+	key := AdvisoryKey(146)
+
+	var inside int32
+	err := sess.WithLock(ctx, key, LockOptions{}, func() error {
+		atomic.AddInt32(&inside, 1)
+
+		// Nested attempts from elsewhere fail or time out.
+		err := sess.WithLock(ctx, key, LockOptions{}, func() error {
+			panic("must not run")
+		})
+		s.Equal(db.ErrLockNotAcquired, err)
+
+		err = sess.WithLock(ctx, key, LockOptions{Wait: true, Timeout: 100 * time.Millisecond}, func() error {
+			panic("must not run")
+		})
+		s.Equal(db.ErrLockNotAcquired, err)
+		return nil
+	})
+	s.NoError(err)
+	s.Equal(int32(1), inside)
+
+	// The lock is released on panic.
+	func() {
+		defer func() {
+			s.Equal("boom", recover())
+		}()
+		_ = sess.WithLock(ctx, key, LockOptions{}, func() error {
+			panic("boom")
+		})
+	}()
+
+	// Errors from fn are returned as they are.
+	errJob := errors.New("job failed")
+	err = sess.WithLock(ctx, key, LockOptions{Wait: true}, func() error {
+		return errJob
+	})
+	s.Equal(errJob, err)
+
+	// Concurrent critical sections don't overlap.
+	var running, overlaps int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := sess.WithLock(ctx, key, LockOptions{Wait: true}, func() error {
+				if atomic.AddInt32(&running, 1) > 1 {
+					atomic.AddInt32(&overlaps, 1)
+				}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+			s.NoError(err)
+		}()
+	}
+	wg.Wait()
+	s.Equal(int32(0), overlaps)
+}
+
+func (s *AdapterTests) TestSessionVarsRLS() {
+	sess := s.SQLBuilder()
+
+	queries := []string{
+		`DROP TABLE IF EXISTS rls_items`,
+		`CREATE TABLE rls_items (id SERIAL PRIMARY KEY, tenant_id INTEGER NOT NULL, name TEXT)`,
+		`INSERT INTO rls_items (tenant_id, name) VALUES (1, 'a'), (1, 'b'), (2, 'c')`,
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'upper_rls_user') THEN
+				CREATE ROLE upper_rls_user;
+			END IF;
+		END $$`,
+		`GRANT SELECT ON rls_items TO upper_rls_user`,
+		`ALTER TABLE rls_items ENABLE ROW LEVEL SECURITY`,
+		`CREATE POLICY rls_items_tenant ON rls_items USING (tenant_id = current_setting('app.tenant_id')::INTEGER)`,
+	}
+	for _, query := range queries {
+		_, err := sess.Exec(query)
+		s.NoError(err)
+	}
+
+	for tenantID, expected := range map[string]uint64{"1": 2, "2": 1, "3": 0} {
+		tenantSess, err := sess.(Database).WithSessionVars(map[string]string{
+			"role":          "upper_rls_user",
+			"app.tenant_id": tenantID,
+		})
+		s.NoError(err)
+
+		err = tenantSess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+			count, err := tx.Collection("rls_items").Find().Count()
+			s.NoError(err)
+			s.Equal(expected, count)
+			return nil
+		})
+		s.NoError(err)
+	}
+
+	// The original session is not affected.
+	count, err := sess.Collection("rls_items").Find().Count()
+	s.NoError(err)
+	s.Equal(uint64(3), count)
+}
+
+func (s *AdapterTests) TestColumns() {
+	sess := s.SQLBuilder().(Database)
+
+	columns, err := sess.Columns("artist")
+	s.NoError(err)
+	s.Equal(2, len(columns))
+
+	s.Equal("id", columns[0].Name)
+	s.Equal(1, columns[0].Position)
+	s.Equal("integer", columns[0].DataType)
+	s.False(columns[0].Nullable)
+	if s.NotNil(columns[0].Default) {
+		s.Contains(*columns[0].Default, "nextval")
+	}
+
+	s.Equal("name", columns[1].Name)
+	s.Equal(2, columns[1].Position)
+	s.Equal("character varying", columns[1].DataType)
+	s.True(columns[1].Nullable)
+	s.Nil(columns[1].Default)
+	if s.NotNil(columns[1].MaxLength) {
+		s.Equal(int64(60), *columns[1].MaxLength)
+	}
+
+	columns, err = sess.Columns("test_schema.test")
+	s.NoError(err)
+	s.Equal(1, len(columns))
+	s.Equal("id", columns[0].Name)
+
+	_, err = sess.Columns("does_not_exist")
+	s.Equal(db.ErrCollectionDoesNotExist, err)
+}
+
+func (s *AdapterTests) TestForeignKeysAndIndexes() {
+	sess := s.SQLBuilder().(Database)
+
+	for _, stmt := range []string{
+		`DROP TABLE IF EXISTS introspect_child`,
+		`DROP TABLE IF EXISTS introspect_parent`,
+		`CREATE TABLE introspect_parent (
+			id integer,
+			code varchar(10),
+			PRIMARY KEY (id, code)
+		)`,
+		`CREATE TABLE introspect_child (
+			id serial primary key,
+			parent_id integer,
+			parent_code varchar(10),
+			name text,
+			CONSTRAINT child_parent_fk FOREIGN KEY (parent_id, parent_code)
+				REFERENCES introspect_parent (id, code) ON DELETE CASCADE
+		)`,
+		`CREATE UNIQUE INDEX introspect_child_name_idx ON introspect_child (lower(name))`,
+	} {
+		_, err := sess.Exec(stmt)
+		s.NoError(err)
+	}
+
+	fks, err := sess.ForeignKeys("introspect_child")
+	s.NoError(err)
+	if s.Equal(1, len(fks)) {
+		s.Equal("child_parent_fk", fks[0].Name)
+		s.Equal([]string{"parent_id", "parent_code"}, fks[0].Columns)
+		s.Equal("introspect_parent", fks[0].ReferencedTable)
+		s.Equal([]string{"id", "code"}, fks[0].ReferencedColumns)
+		s.Equal("NO ACTION", fks[0].OnUpdate)
+		s.Equal("CASCADE", fks[0].OnDelete)
+	}
+
+	fks, err = sess.ForeignKeys("public.introspect_parent")
+	s.NoError(err)
+	s.Equal(0, len(fks))
+
+	indexes, err := sess.Indexes("introspect_child")
+	s.NoError(err)
+	if s.Equal(2, len(indexes)) {
+		s.Equal("introspect_child_name_idx", indexes[0].Name)
+		s.Equal([]string{"lower(name)"}, indexes[0].Columns)
+		s.True(indexes[0].Unique)
+		s.False(indexes[0].Primary)
+		s.Equal("btree", indexes[0].Method)
+
+		s.Equal("introspect_child_pkey", indexes[1].Name)
+		s.Equal([]string{"id"}, indexes[1].Columns)
+		s.True(indexes[1].Unique)
+		s.True(indexes[1].Primary)
+	}
+
+	indexes, err = sess.Indexes("introspect_parent")
+	s.NoError(err)
+	if s.Equal(1, len(indexes)) {
+		s.Equal([]string{"id", "code"}, indexes[0].Columns)
+	}
+}
+
 func (s *AdapterTests) Test_Issue391_TextMode() {
 	testPostgreSQLTypes(s.T(), s.SQLBuilder())
 }
@@ -1281,3 +2890,22 @@ func (s *AdapterTests) TestNonTrivialSubqueries() {
 func TestAdapter(t *testing.T) {
 	suite.Run(t, &AdapterTests{})
 }
+
+// isKind reports whether err or any of the errors it wraps is or matches
+// kind.
+func isKind(err, kind error) bool {
+	for err != nil {
+		if err == kind {
+			return true
+		}
+		if e, ok := err.(interface{ Is(error) bool }); ok && e.Is(kind) {
+			return true
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/compat"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// ExecScript runs the statements of script, which are separated by
+// semicolons, in order and within a transaction, and returns the result of
+// each one of them. Semicolons within strings, quoted identifiers, comments
+// and dollar-quoted bodies, like the ones of CREATE FUNCTION, don't end
+// statements. Statements are sent as they are, without placeholders.
+//
+// If the session already runs a transaction, the statements run in it.
+func (d *database) ExecScript(ctx context.Context, script string) ([]sql.Result, error) {
+	statements := splitScript(script)
+	results := make([]sql.Result, 0, len(statements))
+
+	run := func(execer compat.Execer) error {
+		for _, statement := range statements {
+			res, err := compat.ExecContext(execer, ctx, statement, nil)
+			if err != nil {
+				return d.Err(err)
+			}
+			results = append(results, res)
+		}
+		return nil
+	}
+
+	var err error
+	if tx := d.Transaction(); tx != nil {
+		err = run(d.Driver().(*sql.Tx))
+	} else {
+		err = d.Tx(ctx, func(tx sqlbuilder.Tx) error {
+			return run(tx.Driver().(*sql.Tx))
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// splitScript splits script into statements on the semicolons that are not
+// within strings, quoted identifiers, comments or dollar-quoted strings.
+// Statements are trimmed and the ones that are empty or have only comments
+// are left out.
+func splitScript(script string) []string {
+	var statements []string
+
+	start, empty := 0, true
+	add := func(end int) {
+		if !empty {
+			statements = append(statements, strings.TrimSpace(script[start:end]))
+		}
+		start, empty = end+1, true
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		if n := exql.LiteralLen(script, i); n > 0 {
+			if c != '-' && c != '/' {
+				empty = false
+			}
+			i += n - 1
+			continue
+		}
+		switch c {
+		case ';':
+			add(i)
+		case ' ', '\t', '\n', '\r', '\f':
+		default:
+			empty = false
+		}
+	}
+	add(len(script))
+
+	return statements
+}
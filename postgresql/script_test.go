@@ -0,0 +1,59 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitScript(t *testing.T) {
+	testCases := []struct {
+		script     string
+		statements []string
+	}{
+		{
+			"",
+			nil,
+		},
+		{
+			" ;\n; -- nothing here\n/* or here */;",
+			nil,
+		},
+		{
+			"SELECT 1; SELECT 2",
+			[]string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			"SELECT 1;\n-- SELECT 2;\nSELECT 3;",
+			[]string{"SELECT 1", "-- SELECT 2;\nSELECT 3"},
+		},
+		{
+			"SELECT 'a;b', 'it''s;'; SELECT \"weird;\"\"name\" FROM t",
+			[]string{"SELECT 'a;b', 'it''s;'", "SELECT \"weird;\"\"name\" FROM t"},
+		},
+		{
+			`SELECT E'it\'s;'; SELECT 'a\'; SELECT 2`,
+			[]string{`SELECT E'it\'s;'`, `SELECT 'a\'`, `SELECT 2`},
+		},
+		{
+			"SELECT /* a; /* nested; */ b; */ 1; SELECT 2",
+			[]string{"SELECT /* a; /* nested; */ b; */ 1", "SELECT 2"},
+		},
+		{
+			"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql; SELECT f()",
+			[]string{"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql", "SELECT f()"},
+		},
+		{
+			"SELECT $body$ a; $$ b; $body$; SELECT $1::int; SELECT a$b; SELECT 2",
+			[]string{"SELECT $body$ a; $$ b; $body$", "SELECT $1::int", "SELECT a$b", "SELECT 2"},
+		},
+		{
+			"SELECT 'unterminated; SELECT 2",
+			[]string{"SELECT 'unterminated", "SELECT 2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.statements, splitScript(tc.script), tc.script)
+	}
+}
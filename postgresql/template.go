@@ -37,7 +37,12 @@ const (
 	adapterOrKeyword           = `OR`
 	adapterDescKeyword         = `DESC`
 	adapterAscKeyword          = `ASC`
+	adapterCollateLayout       = `COLLATE "{{.}}"`
+	adapterInsertedLayout      = `(xmax = 0) AS "inserted"`
+	adapterNullsFirstKeyword   = `NULLS FIRST`
+	adapterNullsLastKeyword    = `NULLS LAST`
 	adapterAssignmentOperator  = `=`
+	adapterConcatOperator      = `||`
 	adapterClauseGroup         = `({{.}})`
 	adapterClauseOperator      = ` {{.}} `
 	adapterColumnValue         = `{{.Column}} {{.Operator}} {{.Value}}`
@@ -85,6 +90,12 @@ const (
     {{end}}
   `
 
+	adapterLockLayout = `FOR UPDATE{{if .SkipLocked}} SKIP LOCKED{{end}}`
+
+	adapterSampleLayout = `TABLESAMPLE SYSTEM ({{.Percent}})`
+
+	adapterRandomFunction = `random()`
+
 	adapterSelectLayout = `
     SELECT
       {{if .Distinct}}
@@ -98,7 +109,7 @@ const (
       {{end}}
 
       {{if defined .Table}}
-        FROM {{.Table | compile}}
+        FROM {{.Table | compile}} {{.Sample | compile}}
       {{end}}
 
       {{.Joins | compile}}
@@ -118,19 +129,43 @@ const (
       {{if .Offset}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{.Lock | compile}}
   `
 	adapterDeleteLayout = `
     DELETE
       FROM {{.Table | compile}}
-      {{.Where | compile}}
+      {{if .Limit}}
+        WHERE ctid IN (SELECT ctid FROM {{.Table | compile}} {{.Where | compile}} LIMIT {{.Limit}})
+      {{else}}
+        {{.From | compile}}
+        {{.Where | compile}}
+      {{end}}
+      {{if .Returning}}
+        RETURNING {{.Returning | compile}}
+      {{end}}
   `
 	adapterUpdateLayout = `
     UPDATE
       {{.Table | compile}}
     SET {{.ColumnValues | compile}}
-      {{.Where | compile}}
+      {{if .Limit}}
+        WHERE ctid IN (SELECT ctid FROM {{.Table | compile}} {{.Where | compile}} LIMIT {{.Limit}})
+      {{else}}
+        {{.From | compile}}
+        {{.Where | compile}}
+      {{end}}
+      {{if .Returning}}
+        RETURNING {{.Returning | compile}}
+      {{end}}
   `
 
+	adapterUpdateFromLayout = `FROM {{.Sources | compile}} {{.Joins | compile}}`
+
+	adapterValuesTableLayout = `(VALUES {{.Rows | compile}}) AS {{.Alias | compile}} ({{.Columns | compile}})`
+
+	adapterDeleteUsingLayout = `USING {{.Sources | compile}} {{.Joins | compile}}`
+
 	adapterSelectCountLayout = `
     SELECT
       COUNT(1) AS _t
@@ -147,11 +182,23 @@ const (
     {{else}}
       (default)
     {{end}}
+    {{if defined .OnConflict}}
+      {{.OnConflict | compile}}
+    {{end}}
     {{if defined .Returning}}
       RETURNING {{.Returning | compile}}
     {{end}}
   `
 
+	adapterOnConflictLayout = `
+    ON CONFLICT ({{range $i, $c := .Target}}{{if $i}}, {{end}}{{$c}}{{end}})
+    {{if .Update}}
+      DO UPDATE SET {{range $i, $c := .Update}}{{if $i}}, {{end}}{{$c}} = EXCLUDED.{{$c}}{{end}}
+    {{else}}
+      DO NOTHING
+    {{end}}
+  `
+
 	adapterTruncateLayout = `
     TRUNCATE TABLE {{.Table | compile}} RESTART IDENTITY
   `
@@ -161,7 +208,19 @@ const (
   `
 
 	adapterDropTableLayout = `
-    DROP TABLE {{.Table | compile}}
+    DROP TABLE {{if .IfExists}}IF EXISTS {{end}}{{.Table | compile}}{{if .Cascade}} CASCADE{{end}}
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table | compile}} {{.Alterations | compile}}
+  `
+
+	adapterAddColumnLayout = `ADD COLUMN {{.Column}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.Column}}`
+
+	adapterCreateTableLayout = `
+    CREATE {{if .Temporary}}TEMPORARY {{end}}TABLE {{if .IfNotExists}}IF NOT EXISTS {{end}}{{.Table | compile}} ({{.Definitions | compile}}){{if .Temporary}} ON COMMIT DROP{{end}}
   `
 
 	adapterGroupByLayout = `
@@ -181,7 +240,11 @@ var template = &exql.Template{
 	OrKeyword:           adapterOrKeyword,
 	DescKeyword:         adapterDescKeyword,
 	AscKeyword:          adapterAscKeyword,
+	CollateLayout:       adapterCollateLayout,
+	NullsFirstKeyword:   adapterNullsFirstKeyword,
+	NullsLastKeyword:    adapterNullsLastKeyword,
 	AssignmentOperator:  adapterAssignmentOperator,
+	ConcatOperator:      adapterConcatOperator,
 	ClauseGroup:         adapterClauseGroup,
 	ClauseOperator:      adapterClauseOperator,
 	ColumnValue:         adapterColumnValue,
@@ -190,21 +253,37 @@ var template = &exql.Template{
 	SortByColumnLayout:  adapterSortByColumnLayout,
 	WhereLayout:         adapterWhereLayout,
 	JoinLayout:          adapterJoinLayout,
+	LockLayout:          adapterLockLayout,
+	SampleLayout:        adapterSampleLayout,
+	RandomFunction:      adapterRandomFunction,
 	OnLayout:            adapterOnLayout,
 	UsingLayout:         adapterUsingLayout,
 	OrderByLayout:       adapterOrderByLayout,
 	InsertLayout:        adapterInsertLayout,
+	InsertedLayout:      adapterInsertedLayout,
+	OnConflictLayout:    adapterOnConflictLayout,
 	SelectLayout:        adapterSelectLayout,
 	UpdateLayout:        adapterUpdateLayout,
+	UpdateFromLayout:    adapterUpdateFromLayout,
+	DeleteUsingLayout:   adapterDeleteUsingLayout,
+	ValuesTableLayout:   adapterValuesTableLayout,
 	DeleteLayout:        adapterDeleteLayout,
 	TruncateLayout:      adapterTruncateLayout,
 	DropDatabaseLayout:  adapterDropDatabaseLayout,
 	DropTableLayout:     adapterDropTableLayout,
+	CreateTableLayout:   adapterCreateTableLayout,
+	AlterTableLayout:    adapterAlterTableLayout,
+	AddColumnLayout:     adapterAddColumnLayout,
+	DropColumnLayout:    adapterDropColumnLayout,
 	CountLayout:         adapterSelectCountLayout,
 	GroupByLayout:       adapterGroupByLayout,
+	Placeholder:         exql.DollarPlaceholder,
 	Cache:               cache.NewCache(),
 	ComparisonOperator: map[db.ComparisonOperator]string{
 		db.ComparisonOperatorRegExp:    "~",
 		db.ComparisonOperatorNotRegExp: "!~",
 	},
+	ColumnTypes: map[string]string{
+		"blob": `BYTEA`,
+	},
 }
@@ -1,10 +1,15 @@
 package postgresql
 
 import (
+	"context"
+	"database/sql"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
@@ -43,6 +48,16 @@ func TestTemplateSelect(t *testing.T) {
 		b.Select().From("artist").OrderBy("name ASC").String(),
 	)
 
+	assert.Equal(
+		`SELECT * FROM "artist" ORDER BY "name" COLLATE "C" ASC`,
+		b.Select().From("artist").OrderBy(db.Col("name").Collate("C")).String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "artist" ORDER BY "name" ASC NULLS FIRST`,
+		b.Select().From("artist").OrderBy(db.Asc("name").NullsFirst()).String(),
+	)
+
 	assert.Equal(
 		`SELECT * FROM "artist" LIMIT 1 OFFSET 5`,
 		b.Select().From("artist").Limit(1).Offset(5).String(),
@@ -160,6 +175,83 @@ func TestTemplateSelect(t *testing.T) {
 		`SELECT DATE()`,
 		b.Select(db.Raw("DATE()")).String(),
 	)
+
+	{
+		sel := b.SelectFrom("artist").Where(db.Cond{"name": IsDistinctFrom("Rick")})
+		assert.Equal(
+			`SELECT * FROM "artist" WHERE ("name" IS DISTINCT FROM $1)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{"Rick"},
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("artist").Where(db.Cond{"name": IsNotDistinctFrom(nil), "id": db.Between(1, 5)})
+		assert.Equal(
+			`SELECT * FROM "artist" WHERE ("id" BETWEEN $1 AND $2 AND "name" IS NOT DISTINCT FROM NULL)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{1, 5},
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("artist").Where(db.Cond{"name": ILike("%foo%"), "id": NotILike("%bar%")})
+		assert.Equal(
+			`SELECT * FROM "artist" WHERE ("id" NOT ILIKE $1 AND "name" ILIKE $2)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{"%bar%", "%foo%"},
+			sel.Arguments(),
+		)
+	}
+
+	{
+		sel := b.SelectFrom("artist").Where(db.Cond{"name": RegexpMatch("^foo", false)}).And(db.Cond{"name": RegexpMatch("bar$", true)})
+		assert.Equal(
+			`SELECT * FROM "artist" WHERE ("name" ~ $1 AND "name" ~* $2)`,
+			sel.String(),
+		)
+		assert.Equal(
+			[]interface{}{"^foo", "bar$"},
+			sel.Arguments(),
+		)
+	}
+
+	assert.Equal(
+		`SELECT "id" FROM "jobs" WHERE ("status" = $1) ORDER BY "id" ASC LIMIT 10 FOR UPDATE SKIP LOCKED`,
+		b.Select("id").From("jobs").Where("status", "pending").OrderBy("id").Limit(10).SkipLocked().String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "jobs" WHERE ("id" = $1) FOR UPDATE`,
+		b.SelectFrom("jobs").Where("id", 1).ForUpdate().String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "events" AS "e" TABLESAMPLE SYSTEM (0.5) WHERE ("kind" = $1)`,
+		b.SelectFrom("events AS e").Sample(0.5).Where("kind", "click").String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "artist" ORDER BY random() LIMIT 5`,
+		b.SelectFrom("artist").Random().Limit(5).String(),
+	)
+
+	{
+		scores := sqlbuilder.Values([][]interface{}{{1, []byte("a"), 0.5}, {2, []byte("b"), 0.8}}, []string{"id", "tag", "score"}).As("s")
+		q := b.Select("a.name", "s.score").From("artist AS a").Join(scores).On("s.id = a.id")
+		assert.Equal(
+			`SELECT "a"."name", "s"."score" FROM "artist" AS "a" JOIN (VALUES (CAST($1 AS bigint), CAST($2 AS BYTEA), CAST($3 AS double precision)), ($4, $5, $6)) AS "s" ("id", "tag", "score") ON (s.id = a.id)`,
+			q.String(),
+		)
+	}
 }
 
 func TestTemplateInsert(t *testing.T) {
@@ -204,10 +296,37 @@ func TestTemplateInsert(t *testing.T) {
 	)
 }
 
+func TestTemplateUpsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	item := map[string]interface{}{"email": "ozzy@example.com", "name": "Ozzy"}
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO NOTHING`,
+		b.InsertInto("artist").Values(item).OnConflict("email").String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name" RETURNING "id", (xmax = 0) AS "inserted"`,
+		b.InsertInto("artist").Values(item).OnConflict("email").DoUpdate().Returning("id").ReturningInserted().String(),
+	)
+}
+
 func TestTemplateUpdate(t *testing.T) {
 	b := sqlbuilder.WithTemplate(template)
 	assert := assert.New(t)
 
+	assert.Equal(
+		`UPDATE "counters" SET "n" = "n" + $1`,
+		b.Update("counters").Set(db.Cond{"n": db.Col("n").Add(1)}).String(),
+	)
+
+	assert.Equal(
+		`SELECT "first_name" || $1 || "last_name" FROM "artist"`,
+		b.Select(db.Concat(db.Col("first_name"), " ", db.Col("last_name"))).From("artist").String(),
+	)
+
 	assert.Equal(
 		`UPDATE "artist" SET "name" = $1`,
 		b.Update("artist").Set("name", "Artist").String(),
@@ -244,6 +363,38 @@ func TestTemplateUpdate(t *testing.T) {
 			"id = id + ?", 10,
 		).Where("id > ?", 0).String(),
 	)
+
+	{
+		q := b.Update("artist").
+			Set("name = s.name").
+			From("staging AS s").
+			Where("artist.id = s.id").
+			And("s.batch", 3)
+		assert.Equal(
+			`UPDATE "artist" SET "name" = s.name FROM "staging" AS "s" WHERE (artist.id = s.id AND "s"."batch" = $1)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{3}, q.Arguments())
+	}
+
+	{
+		q := b.Update("artist").
+			Set(db.Cond{"name": db.Raw("s.name"), "rank": 1}).
+			From("staging AS s").
+			Join("batches AS b").On("b.id = s.batch_id AND b.state = ?", "ready").
+			Where("artist.id = s.id AND s.kind = ?", "solo")
+		assert.Equal(
+			`UPDATE "artist" SET "name" = s.name, "rank" = $1 FROM "staging" AS "s" JOIN "batches" AS "b" ON (b.id = s.batch_id AND b.state = $2) WHERE (artist.id = s.id AND s.kind = $3)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{1, "ready", "solo"}, q.Arguments())
+	}
+
+	_, err := b.Update("artist").Set("name", "x").Join("staging").ExecContext(context.Background())
+	assert.Error(err)
+
+	_, err = b.Update("artist").Set("name", "x").From("staging").Limit(1).ExecContext(context.Background())
+	assert.Error(err)
 }
 
 func TestTemplateDelete(t *testing.T) {
@@ -251,7 +402,7 @@ func TestTemplateDelete(t *testing.T) {
 	assert := assert.New(t)
 
 	assert.Equal(
-		`DELETE FROM "artist" WHERE (name = $1)`,
+		`DELETE FROM "artist" WHERE ctid IN (SELECT ctid FROM "artist" WHERE (name = $1) LIMIT 1)`,
 		b.DeleteFrom("artist").Where("name = ?", "Chavela Vargas").Limit(1).String(),
 	)
 
@@ -259,4 +410,194 @@ func TestTemplateDelete(t *testing.T) {
 		`DELETE FROM "artist" WHERE (id > 5)`,
 		b.DeleteFrom("artist").Where("id > 5").String(),
 	)
+
+	assert.Equal(
+		`DELETE FROM "artist" WHERE ctid IN (SELECT ctid FROM "artist" WHERE (id > 5) LIMIT 1000)`,
+		b.DeleteFrom("artist").Where("id > 5").Limit(1000).String(),
+	)
+
+	assert.Equal(
+		`UPDATE "artist" SET "name" = $1 WHERE ctid IN (SELECT ctid FROM "artist" WHERE (id > 5) LIMIT 1000)`,
+		b.Update("artist").Set("name", "Rick").Where("id > 5").Limit(1000).String(),
+	)
+
+	assert.Equal(
+		`DELETE FROM "artist" WHERE (id > 5) RETURNING "id"`,
+		b.DeleteFrom("artist").Where("id > 5").Returning("id").String(),
+	)
+
+	assert.Equal(
+		`UPDATE "artist" SET "name" = $1 WHERE ctid IN (SELECT ctid FROM "artist" WHERE (id > 5) LIMIT 10) RETURNING "id", "name"`,
+		b.Update("artist").Set("name", "Rick").Where("id > 5").Limit(10).Returning("id", "name").String(),
+	)
+
+	{
+		q := b.DeleteFrom("artist").
+			Using("banned AS b").
+			Join("reasons AS r").On("r.id = b.reason_id AND r.severity > ?", 2).
+			Where("artist.name = b.name AND b.since < ?", "2019-01-01")
+		assert.Equal(
+			`DELETE FROM "artist" USING "banned" AS "b" JOIN "reasons" AS "r" ON (r.id = b.reason_id AND r.severity > $1) WHERE (artist.name = b.name AND b.since < $2)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{2, "2019-01-01"}, q.Arguments())
+	}
+
+	{
+		// RETURNING can refer to the joined tables too.
+		q := b.DeleteFrom("artist").
+			Using("banned AS b").
+			Where("artist.name = b.name").
+			Returning("artist.id", "b.reason_id")
+		assert.Equal(
+			`DELETE FROM "artist" USING "banned" AS "b" WHERE (artist.name = b.name) RETURNING "artist"."id", "b"."reason_id"`,
+			q.String(),
+		)
+	}
+
+	_, err := b.DeleteFrom("artist").Join("banned").ExecContext(context.Background())
+	assert.Error(err)
+
+	_, err = b.DeleteFrom("artist").Using("banned").Limit(1).ExecContext(context.Background())
+	assert.Error(err)
+}
+
+func TestTemplateConditionGrouping(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+
+	testCases := []struct {
+		sel  sqlbuilder.Selector
+		sql  string
+		args []interface{}
+	}{
+		{
+			b.SelectFrom("t").Where(db.Or(
+				db.And(db.Cond{"a": 1}, db.Cond{"b": 2}),
+				db.And(db.Cond{"c": 3}, db.Cond{"d": 4}),
+			)),
+			`SELECT * FROM "t" WHERE ((("a" = $1 AND "b" = $2) OR ("c" = $3 AND "d" = $4)))`,
+			[]interface{}{1, 2, 3, 4},
+		},
+		{
+			b.SelectFrom("t").Where(db.Or(
+				db.Cond{"a": 1, "b": 2},
+				db.And(
+					db.Cond{"c": 3},
+					db.Or(db.Cond{"d": 4}, db.And(db.Cond{"e": 5}, db.Or(db.Cond{"f": 6}, db.Cond{"g": 7}))),
+				),
+			)),
+			`SELECT * FROM "t" WHERE ((("a" = $1 AND "b" = $2) OR ("c" = $3 AND ("d" = $4 OR ("e" = $5 AND ("f" = $6 OR "g" = $7))))))`,
+			[]interface{}{1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			b.SelectFrom("t").Where(db.And(
+				db.Or(db.Cond{"a": 1}, db.Cond{"b": 2}),
+				db.Or(db.Cond{"c": 3}, db.Cond{"d": 4}),
+			)),
+			`SELECT * FROM "t" WHERE ((("a" = $1 OR "b" = $2) AND ("c" = $3 OR "d" = $4)))`,
+			[]interface{}{1, 2, 3, 4},
+		},
+		{
+			b.SelectFrom("t").Where("a = ? OR b = ?", 1, 2).And(db.Cond{"c": 3}),
+			`SELECT * FROM "t" WHERE ((a = $1 OR b = $2) AND "c" = $3)`,
+			[]interface{}{1, 2, 3},
+		},
+		{
+			b.SelectFrom("t").Where(db.And(db.Raw("a = ? OR b = ?", 1, 2), db.Raw("c = ? AND d = ?", 3, 4))),
+			`SELECT * FROM "t" WHERE (((a = $1 OR b = $2) AND c = $3 AND d = $4))`,
+			[]interface{}{1, 2, 3, 4},
+		},
+		{
+			b.SelectFrom("t").Where("a", 1).And("b", 2).Or("c", 3),
+			`SELECT * FROM "t" WHERE ((("a" = $1 AND "b" = $2) OR ("c" = $3)))`,
+			[]interface{}{1, 2, 3},
+		},
+		{
+			b.SelectFrom("t").Where("a", 1).Or(db.Cond{"b": 2, "c": 3}).And(db.Cond{"d": 4}).Or(db.Or(db.Cond{"e": 5}, db.Cond{"f": 6})),
+			`SELECT * FROM "t" WHERE ((((("a" = $1) OR ("b" = $2 AND "c" = $3)) AND "d" = $4) OR (("e" = $5 OR "f" = $6))))`,
+			[]interface{}{1, 2, 3, 4, 5, 6},
+		},
+		{
+			b.SelectFrom("t").Or("a", 1),
+			`SELECT * FROM "t" WHERE ("a" = $1)`,
+			[]interface{}{1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.sql, testCase.sel.String())
+		assert.Equal(t, testCase.args, testCase.sel.Arguments())
+	}
+}
+
+func TestTemplateCreateTable(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	type account struct {
+		ID        int64      `db:"id,omitempty"`
+		Name      string     `db:"name"`
+		Active    bool       `db:"active"`
+		Avatar    []byte     `db:"avatar"`
+		CreatedAt *time.Time `db:"created_at"`
+	}
+
+	assert.Equal(
+		`CREATE TABLE "accounts" ("id" BIGSERIAL PRIMARY KEY, "name" TEXT NOT NULL, "active" BOOLEAN NOT NULL, "avatar" BYTEA NOT NULL, "created_at" TIMESTAMP)`,
+		b.CreateTable("accounts").Struct(account{}).String(),
+	)
+
+	assert.Equal(
+		`CREATE TABLE IF NOT EXISTS "accounts" ("id" BIGSERIAL PRIMARY KEY, "name" TEXT NOT NULL, "active" BOOLEAN NOT NULL, "avatar" BYTEA NOT NULL, "created_at" TIMESTAMP)`,
+		b.CreateTable("accounts").Struct(account{}).IfNotExists().String(),
+	)
+
+	assert.Equal(
+		`CREATE TEMPORARY TABLE "accounts" ("id" BIGSERIAL PRIMARY KEY, "name" TEXT NOT NULL, "active" BOOLEAN NOT NULL, "avatar" BYTEA NOT NULL, "created_at" TIMESTAMP) ON COMMIT DROP`,
+		b.CreateTable("accounts").Struct(account{}).Temporary().String(),
+	)
+}
+
+func TestTemplateDDL(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`CREATE TABLE IF NOT EXISTS "accounts" ("id" serial PRIMARY KEY, "name" text NOT NULL UNIQUE, "created_at" timestamp DEFAULT now())`,
+		b.CreateTable("accounts").
+			Column("id", "serial", db.PrimaryKey).
+			Column("name", "text", db.NotNull, db.Unique).
+			Column("created_at", "timestamp", db.ColumnDefault("now()")).
+			IfNotExists().
+			String(),
+	)
+
+	assert.Equal(
+		`DROP TABLE "accounts"`,
+		b.DropTable("accounts").String(),
+	)
+
+	assert.Equal(
+		`DROP TABLE IF EXISTS "accounts" CASCADE`,
+		b.DropTable("accounts").IfExists().Cascade().String(),
+	)
+
+	assert.Equal(
+		`ALTER TABLE "accounts" ADD COLUMN "avatar" BYTEA, DROP COLUMN "nickname"`,
+		b.AlterTable("accounts").AddColumn("avatar", "blob").DropColumn("nickname").String(),
+	)
+}
+
+func TestCompileStatementNamedArgs(t *testing.T) {
+	d := &database{}
+
+	stmt := exql.RawSQL(`SELECT * FROM artist WHERE id > ? AND (name = ? OR alias = ?) AND genre IN ?`)
+	query, args := d.CompileStatement(stmt, []interface{}{
+		10,
+		sql.Named("name", "Ozzie"),
+		db.Raw("lower(?)", sql.Named("name", "Ozzie")),
+		[]string{"rock", "metal"},
+	})
+	assert.Equal(t, `SELECT * FROM artist WHERE id > $1 AND (name = $2 OR alias = lower($2)) AND genre IN ($3, $4)`, strings.Join(strings.Fields(query), " "))
+	assert.Equal(t, []interface{}{10, "Ozzie", "rock", "metal"}, args)
 }
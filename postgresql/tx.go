@@ -23,19 +23,140 @@ package postgresql
 
 import (
 	"context"
+	"strings"
 
+	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
+// Tx represents a PostgreSQL transaction with features that are specific to
+// this adapter. Transactions created by this adapter, including the ones
+// passed to the function given to Tx(), satisfy this interface:
+//
+//	err := sess.Tx(ctx, func(tx sqlbuilder.Tx) error {
+//	  if err := tx.(postgresql.Tx).SetLocal("app.tenant_id", tenantID); err != nil {
+//	    return err
+//	  }
+//	  ...
+//	})
+type Tx interface {
+	sqlbuilder.Tx
+
+	// SetLocal sets the given run-time parameter for the remainder of the
+	// transaction, like SET LOCAL does. The value is sent as a query argument
+	// and the setting goes back to its previous value when the transaction
+	// ends.
+	SetLocal(name string, value interface{}) error
+
+	// SetRole sets the current role for the remainder of the transaction, like
+	// SET LOCAL ROLE does.
+	SetRole(role string) error
+
+	// CreateTempTable creates a temporary table with the columns of the given
+	// struct (see sqlbuilder.TableCreator.Struct) and returns a collection
+	// bound to it. A random suffix is appended to name, so concurrent
+	// transactions can use the same name; use the collection's Name() to
+	// refer to the table in queries. The table is dropped when the
+	// transaction ends.
+	CreateTempTable(name string, columns interface{}) (db.Collection, error)
+
+	// DeferConstraints postpones the checks of the given deferrable
+	// constraints until the transaction is committed, like SET CONSTRAINTS
+	// ... DEFERRED does. All deferrable constraints are deferred if no name is
+	// given. This lets related rows be written in any order:
+	//
+	//	if err := tx.(postgresql.Tx).DeferConstraints(); err != nil {
+	//	  return err
+	//	}
+	//	// Children first, the foreign keys are checked on commit.
+	DeferConstraints(names ...string) error
+
+	// AdvisoryXactLock waits until the advisory lock on the given key is
+	// acquired, like pg_advisory_xact_lock does. The lock is released when
+	// the transaction ends, use WithContext to limit the wait.
+	AdvisoryXactLock(key AdvisoryLockKey) error
+
+	// TryAdvisoryXactLock acquires the advisory lock on the given key if it's
+	// available and returns false otherwise, like pg_try_advisory_xact_lock
+	// does.
+	TryAdvisoryXactLock(key AdvisoryLockKey) (bool, error)
+}
+
 type tx struct {
 	sqladapter.DatabaseTx
 }
 
 var (
+	_ = Tx(&tx{})
 	_ = sqlbuilder.Tx(&tx{})
 )
 
+func (t *tx) SetLocal(name string, value interface{}) error {
+	// SET LOCAL does not accept parameters, set_config() with is_local = true is
+	// equivalent.
+	_, err := t.Exec(`SELECT set_config(?, ?, true)`, name, value)
+	return err
+}
+
+func (t *tx) SetRole(role string) error {
+	return t.SetLocal("role", role)
+}
+
+func (t *tx) CreateTempTable(name string, columns interface{}) (db.Collection, error) {
+	name = sqladapter.TempTableName(name)
+	if _, err := t.CreateTable(name).Struct(columns).Temporary().Exec(); err != nil {
+		return nil, err
+	}
+	return t.Collection(name), nil
+}
+
+func (t *tx) DeferConstraints(names ...string) error {
+	stmt, err := deferConstraintsStatement(names)
+	if err != nil {
+		return err
+	}
+	_, err = t.Exec(stmt)
+	return err
+}
+
+// deferConstraintsStatement returns a SET CONSTRAINTS statement that defers
+// the given constraints, or all of them. Names may be schema-qualified.
+func deferConstraintsStatement(names []string) (string, error) {
+	if len(names) == 0 {
+		return `SET CONSTRAINTS ALL DEFERRED`, nil
+	}
+	quoted := make([]string, len(names))
+	for i := range names {
+		s, err := exql.ColumnWithName(names[i]).Compile(template)
+		if err != nil {
+			return "", err
+		}
+		quoted[i] = s
+	}
+	return `SET CONSTRAINTS ` + strings.Join(quoted, `, `) + ` DEFERRED`, nil
+}
+
+func (t *tx) AdvisoryXactLock(key AdvisoryLockKey) error {
+	query, args := key.call("pg_advisory_xact_lock")
+	_, err := t.Exec(query, args...)
+	return err
+}
+
+func (t *tx) TryAdvisoryXactLock(key AdvisoryLockKey) (bool, error) {
+	query, args := key.call("pg_try_advisory_xact_lock")
+	row, err := t.QueryRow(query, args...)
+	if err != nil {
+		return false, err
+	}
+	var acquired bool
+	if err := row.Scan(&acquired); err != nil {
+		return false, err
+	}
+	return acquired, nil
+}
+
 func (t *tx) WithContext(ctx context.Context) sqlbuilder.Tx {
 	var newTx tx
 	newTx = *t
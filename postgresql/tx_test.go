@@ -0,0 +1,27 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeferConstraintsStatement(t *testing.T) {
+	stmt, err := deferConstraintsStatement(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `SET CONSTRAINTS ALL DEFERRED`, stmt)
+
+	stmt, err = deferConstraintsStatement([]string{"child_parent_id_fkey", "public.other_fkey"})
+	assert.NoError(t, err)
+	assert.Equal(t, `SET CONSTRAINTS "child_parent_id_fkey", "public"."other_fkey" DEFERRED`, stmt)
+}
+
+func TestAdvisoryLockKey(t *testing.T) {
+	query, args := AdvisoryKey(42).call("pg_advisory_lock")
+	assert.Equal(t, `SELECT pg_advisory_lock($1)`, query)
+	assert.Equal(t, []interface{}{int64(42)}, args)
+
+	query, args = AdvisoryKeyPair(1, 2).call("pg_try_advisory_xact_lock")
+	assert.Equal(t, `SELECT pg_try_advisory_xact_lock($1, $2)`, query)
+	assert.Equal(t, []interface{}{int32(1), int32(2)}, args)
+}
@@ -0,0 +1,301 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
+)
+
+// upsertFallbackAttempts is how many times a row is tried again when another
+// session inserts a conflicting row between the update and the insert.
+const upsertFallbackAttempts = 3
+
+// upsertRow is a row of an upsert, with its compiled columns and values, like
+// "email" and ?, and the arguments of each value.
+type upsertRow struct {
+	columns []string
+	values  []string
+	args    [][]interface{}
+}
+
+// upsertResult is the sql.Result of an upsert run by ExecFallback.
+type upsertResult int64
+
+func (r upsertResult) LastInsertId() (int64, error) {
+	return 0, db.ErrUnsupported
+}
+
+func (r upsertResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+// ExecFallback runs inserts with OnConflict on servers older than PostgreSQL
+// 9.5, which have no ON CONFLICT, the way upserts were written before it.
+// Each row is updated first, when DoUpdate is used, and inserted if there was
+// nothing to update. The insert runs within a savepoint, if it fails with a
+// unique violation another session inserted the row in the meantime: the row
+// is skipped with DoNothing and updated again with DoUpdate. Rows are handled
+// one by one, within a transaction.
+//
+// Unlike ON CONFLICT, the fallback relies on the unique constraint over the
+// target columns to detect the race, and with DoNothing it also skips rows
+// that violate any other unique constraint. Upserts with Returning or
+// ReturningInserted are not handled and fail with a *VersionError.
+func (d *database) ExecFallback(ctx context.Context, stmt *exql.Statement, args []interface{}) (sql.Result, bool, error) {
+	if stmt.Type != exql.Insert || stmt.OnConflict == nil || stmt.Returning != nil {
+		return nil, false, nil
+	}
+	if d.SupportsOnConflict() {
+		return nil, false, nil
+	}
+
+	layout := sqladapter.SessionTemplate(d.BaseDatabase, template)
+	table, rows, conflict, err := upsertRows(layout, stmt, args)
+	if err != nil {
+		return nil, true, err
+	}
+
+	var affected upsertResult
+	run := func(sess sqlbuilder.SQLBuilder) error {
+		for _, row := range rows {
+			n, err := upsertFallbackRow(ctx, sess, table, row, conflict)
+			if err != nil {
+				return err
+			}
+			affected += upsertResult(n)
+		}
+		return nil
+	}
+
+	if d.Transaction() != nil {
+		err = run(d)
+	} else {
+		err = d.Tx(ctx, func(tx sqlbuilder.Tx) error {
+			return run(tx)
+		})
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	return affected, true, nil
+}
+
+// upsertFallbackRow updates or inserts a single row and returns the number of
+// rows that changed.
+func upsertFallbackRow(ctx context.Context, sess sqlbuilder.SQLBuilder, table string, row upsertRow, conflict *upsertConflict) (int64, error) {
+	updateQuery, updateArgs, err := row.update(table, conflict)
+	if err != nil {
+		return 0, err
+	}
+	insertQuery, insertArgs := row.insert(table)
+
+	for attempt := 0; attempt < upsertFallbackAttempts; attempt++ {
+		if updateQuery != "" {
+			res, err := sess.ExecContext(ctx, updateQuery, updateArgs...)
+			if err != nil {
+				return 0, err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				return n, nil
+			}
+		}
+
+		if _, err = sess.ExecContext(ctx, `SAVEPOINT upper_upsert`); err != nil {
+			return 0, err
+		}
+		res, insertErr := sess.ExecContext(ctx, insertQuery, insertArgs...)
+		if insertErr == nil {
+			if _, err = sess.ExecContext(ctx, `RELEASE SAVEPOINT upper_upsert`); err != nil {
+				return 0, err
+			}
+			n, _ := res.RowsAffected()
+			return n, nil
+		}
+		if !isUniqueViolation(insertErr) {
+			return 0, insertErr
+		}
+		if _, err = sess.ExecContext(ctx, `ROLLBACK TO SAVEPOINT upper_upsert`); err != nil {
+			return 0, err
+		}
+		if updateQuery == "" {
+			// DoNothing.
+			return 0, nil
+		}
+		err = insertErr
+	}
+	return 0, err
+}
+
+// upsertConflict has the compiled columns of the OnConflict clause.
+type upsertConflict struct {
+	target []string
+	update []string
+}
+
+// upsertRows splits an insert into its rows.
+func upsertRows(layout *exql.Template, stmt *exql.Statement, args []interface{}) (string, []upsertRow, *upsertConflict, error) {
+	table, err := stmt.Table.Compile(layout)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	onConflict, ok := stmt.OnConflict.(*exql.OnConflict)
+	if !ok {
+		return "", nil, nil, db.ErrUnsupported
+	}
+	conflict := &upsertConflict{}
+	if conflict.target, err = compileFragments(layout, onConflict.Target); err != nil {
+		return "", nil, nil, err
+	}
+	if conflict.update, err = compileFragments(layout, onConflict.Update); err != nil {
+		return "", nil, nil, err
+	}
+
+	columns, ok := stmt.Columns.(*exql.Columns)
+	if !ok {
+		return "", nil, nil, db.ErrUnsupported
+	}
+	compiledColumns, err := compileFragments(layout, columns)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	groups, ok := stmt.Values.(*exql.ValueGroups)
+	if !ok {
+		return "", nil, nil, db.ErrUnsupported
+	}
+
+	rows := make([]upsertRow, 0, len(groups.Values))
+	for _, group := range groups.Values {
+		row := upsertRow{columns: compiledColumns}
+		for _, value := range group.Values {
+			compiled, err := value.Compile(layout)
+			if err != nil {
+				return "", nil, nil, err
+			}
+			n := 0
+			exql.ReplacePlaceholders(compiled, func(i int) string {
+				n = i
+				return "?"
+			})
+			if n > len(args) {
+				return "", nil, nil, db.ErrUnsupported
+			}
+			row.values = append(row.values, compiled)
+			row.args = append(row.args, args[:n])
+			args = args[n:]
+		}
+		if len(row.values) != len(row.columns) {
+			return "", nil, nil, db.ErrUnsupported
+		}
+		rows = append(rows, row)
+	}
+	return table, rows, conflict, nil
+}
+
+func compileFragments(layout *exql.Template, columns *exql.Columns) ([]string, error) {
+	if columns == nil {
+		return nil, nil
+	}
+	out := make([]string, 0, len(columns.Columns))
+	for _, column := range columns.Columns {
+		compiled, err := column.Compile(layout)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, compiled)
+	}
+	return out, nil
+}
+
+// value returns the value inserted into column, the fallback can't tell
+// which row conflicts, or what to update it with, if column is not inserted.
+func (r upsertRow) value(column string) (string, []interface{}, error) {
+	for i := range r.columns {
+		if r.columns[i] == column {
+			return r.values[i], r.args[i], nil
+		}
+	}
+	return "", nil, fmt.Errorf("upper: conflict column %s is not one of the inserted columns", column)
+}
+
+// update returns the statement that updates the row, or an empty string if
+// there's nothing to update.
+func (r upsertRow) update(table string, conflict *upsertConflict) (string, []interface{}, error) {
+	if len(conflict.update) == 0 {
+		return "", nil, nil
+	}
+
+	var args []interface{}
+	set := make([]string, 0, len(conflict.update))
+	for _, column := range conflict.update {
+		value, valueArgs, err := r.value(column)
+		if err != nil {
+			return "", nil, err
+		}
+		set = append(set, column+" = "+value)
+		args = append(args, valueArgs...)
+	}
+	where := make([]string, 0, len(conflict.target))
+	for _, column := range conflict.target {
+		value, valueArgs, err := r.value(column)
+		if err != nil {
+			return "", nil, err
+		}
+		where = append(where, column+" = "+value)
+		args = append(args, valueArgs...)
+	}
+	return "UPDATE " + table + " SET " + strings.Join(set, ", ") + " WHERE " + strings.Join(where, " AND "), args, nil
+}
+
+// insert returns the statement that inserts the row.
+func (r upsertRow) insert(table string) (string, []interface{}) {
+	var args []interface{}
+	for i := range r.args {
+		args = append(args, r.args[i]...)
+	}
+	return "INSERT INTO " + table + " (" + strings.Join(r.columns, ", ") + ") VALUES (" + strings.Join(r.values, ", ") + ")", args
+}
+
+// isUniqueViolation returns true if err, or an error it wraps, is a unique
+// violation reported by the server.
+func isUniqueViolation(err error) bool {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e.Is(db.ErrUniqueViolation)
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+	}
+	return false
+}
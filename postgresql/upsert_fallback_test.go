@@ -0,0 +1,64 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpsertRows(t *testing.T) {
+	columns := exql.JoinColumns(exql.ColumnWithName("email"), exql.ColumnWithName("name"), exql.ColumnWithName("slug"))
+	stmt := &exql.Statement{
+		Type:    exql.Insert,
+		Table:   exql.TableWithName("subscribers"),
+		Columns: columns,
+		Values: exql.JoinValueGroups(
+			exql.NewValueGroup(exql.RawValue("?"), exql.RawValue("?"), exql.RawValue("lower(?)")),
+			exql.NewValueGroup(exql.RawValue("?"), exql.RawValue("DEFAULT"), exql.RawValue("?")),
+		),
+		OnConflict: &exql.OnConflict{
+			Target: exql.JoinColumns(exql.ColumnWithName("email")),
+			Update: exql.JoinColumns(exql.ColumnWithName("name"), exql.ColumnWithName("slug")),
+			Insert: columns,
+		},
+	}
+	args := []interface{}{"a@example.com", "A", "A-SLUG", "b@example.com", "b-slug"}
+
+	table, rows, conflict, err := upsertRows(template, stmt, args)
+	assert.NoError(t, err)
+	assert.Equal(t, `"subscribers"`, table)
+	if !assert.Len(t, rows, 2) {
+		return
+	}
+
+	query, queryArgs, err := rows[0].update(table, conflict)
+	assert.NoError(t, err)
+	assert.Equal(t, `UPDATE "subscribers" SET "name" = ?, "slug" = lower(?) WHERE "email" = ?`, query)
+	assert.Equal(t, []interface{}{"A", "A-SLUG", "a@example.com"}, queryArgs)
+
+	query, queryArgs = rows[0].insert(table)
+	assert.Equal(t, `INSERT INTO "subscribers" ("email", "name", "slug") VALUES (?, ?, lower(?))`, query)
+	assert.Equal(t, []interface{}{"a@example.com", "A", "A-SLUG"}, queryArgs)
+
+	query, queryArgs, err = rows[1].update(table, conflict)
+	assert.NoError(t, err)
+	assert.Equal(t, `UPDATE "subscribers" SET "name" = DEFAULT, "slug" = ? WHERE "email" = ?`, query)
+	assert.Equal(t, []interface{}{"b-slug", "b@example.com"}, queryArgs)
+
+	// DoNothing.
+	conflict.update = nil
+	query, _, err = rows[1].update(table, conflict)
+	assert.NoError(t, err)
+	assert.Equal(t, "", query)
+
+	// Conflict columns must be inserted.
+	conflict.update = []string{`"name"`}
+	conflict.target = []string{`"id"`}
+	_, _, err = rows[0].update(table, conflict)
+	assert.Error(t, err)
+
+	// Arguments must match the placeholders.
+	_, _, _, err = upsertRows(template, stmt, args[:3])
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+)
+
+// VersionError is returned when a statement uses a feature the server is too
+// old for, instead of the server's syntax error. It matches db.ErrUnsupported
+// with Is.
+type VersionError struct {
+	// Feature is the SQL feature the statement uses, like "ON CONFLICT".
+	Feature string
+
+	// Required is the first PostgreSQL version with the feature.
+	Required string
+
+	// Version is the version the server runs.
+	Version string
+}
+
+// Error returns a message with the feature and the versions.
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("upper: %s requires PostgreSQL %s or later, the server runs %s", e.Feature, e.Required, e.Version)
+}
+
+// Is reports whether target is db.ErrUnsupported.
+func (e *VersionError) Is(target error) bool {
+	return target == db.ErrUnsupported
+}
+
+// serverVersion is a PostgreSQL version, like 9.6 or 15.2.
+type serverVersion struct {
+	major, minor int
+}
+
+var (
+	versionOnConflict = serverVersion{9, 5}
+	versionSkipLocked = serverVersion{9, 5}
+	versionMerge      = serverVersion{15, 0}
+)
+
+// parseServerVersion parses the leading numbers of server_version, which may
+// be followed by a build description, like "10.5 (Debian 10.5-1)", or a
+// pre-release, like "16beta1".
+func parseServerVersion(s string) (serverVersion, bool) {
+	var v serverVersion
+	parts := strings.SplitN(s, ".", 3)
+	for i, dst := range []*int{&v.major, &v.minor} {
+		if i >= len(parts) {
+			break
+		}
+		digits := strings.IndexFunc(parts[i], func(r rune) bool { return !unicode.IsDigit(r) })
+		if digits < 0 {
+			digits = len(parts[i])
+		}
+		n, err := strconv.Atoi(parts[i][:digits])
+		if err != nil {
+			if i == 0 {
+				return v, false
+			}
+			break
+		}
+		*dst = n
+		if digits < len(parts[i]) {
+			break
+		}
+	}
+	return v, true
+}
+
+func (v serverVersion) atLeast(w serverVersion) bool {
+	return v.major > w.major || (v.major == w.major && v.minor >= w.minor)
+}
+
+func (v serverVersion) String() string {
+	return strconv.Itoa(v.major) + "." + strconv.Itoa(v.minor)
+}
+
+// ServerVersion returns the version of the PostgreSQL server, like "9.6.24"
+// or "15.2 (Debian 15.2-1.pgdg110+1)", as SHOW server_version reports it.
+// It's read once and cached for the session and its clones.
+func (d *database) ServerVersion() (string, error) {
+	if v, _ := d.serverVersion.Load().(string); v != "" {
+		return v, nil
+	}
+
+	row, err := d.QueryRow(`SHOW server_version`)
+	if err != nil {
+		return "", err
+	}
+	var v string
+	if err := row.Scan(&v); err != nil {
+		return "", d.Err(err)
+	}
+
+	d.serverVersion.Store(v)
+	return v, nil
+}
+
+// supports returns true if the server is at least the given version. It also
+// returns true if the version can't be read, so the server has the last
+// word.
+func (d *database) supports(required serverVersion) bool {
+	s, err := d.ServerVersion()
+	if err != nil {
+		return true
+	}
+	v, ok := parseServerVersion(s)
+	return !ok || v.atLeast(required)
+}
+
+// SupportsOnConflict returns true if the server supports INSERT ... ON
+// CONFLICT, added in PostgreSQL 9.5.
+func (d *database) SupportsOnConflict() bool {
+	return d.supports(versionOnConflict)
+}
+
+// SupportsSkipLocked returns true if the server supports FOR UPDATE SKIP
+// LOCKED, added in PostgreSQL 9.5.
+func (d *database) SupportsSkipLocked() bool {
+	return d.supports(versionSkipLocked)
+}
+
+// SupportsMerge returns true if the server supports MERGE, added in
+// PostgreSQL 15.
+func (d *database) SupportsMerge() bool {
+	return d.supports(versionMerge)
+}
+
+// SupportsReturning returns true, UPDATE and DELETE ... RETURNING are
+// supported by every version.
+func (d *database) SupportsReturning() bool {
+	return true
+}
+
+// CheckStatement returns a *VersionError if stmt uses syntax the server
+// doesn't support, it's called by sqladapter before sending statements.
+func (d *database) CheckStatement(stmt *exql.Statement) error {
+	if stmt.OnConflict != nil && !d.SupportsOnConflict() {
+		return d.versionError("ON CONFLICT", versionOnConflict)
+	}
+	if lock, ok := stmt.Lock.(*exql.Lock); ok && lock.SkipLocked && !d.SupportsSkipLocked() {
+		return d.versionError("SKIP LOCKED", versionSkipLocked)
+	}
+	return nil
+}
+
+func (d *database) versionError(feature string, required serverVersion) error {
+	v, _ := d.ServerVersion()
+	return &VersionError{Feature: feature, Required: required.String(), Version: v}
+}
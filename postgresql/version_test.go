@@ -0,0 +1,72 @@
+package postgresql
+
+import (
+	"testing"
+
+	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	tests := []struct {
+		in  string
+		out serverVersion
+		ok  bool
+	}{
+		{"9.4.26", serverVersion{9, 4}, true},
+		{"9.6.24", serverVersion{9, 6}, true},
+		{"10.5 (Debian 10.5-1.pgdg90+1)", serverVersion{10, 5}, true},
+		{"15.2", serverVersion{15, 2}, true},
+		{"16beta1", serverVersion{16, 0}, true},
+		{"17devel", serverVersion{17, 0}, true},
+		{"", serverVersion{}, false},
+		{"unknown", serverVersion{}, false},
+	}
+
+	for _, test := range tests {
+		v, ok := parseServerVersion(test.in)
+		assert.Equal(t, test.ok, ok, test.in)
+		if ok {
+			assert.Equal(t, test.out, v, test.in)
+		}
+	}
+
+	assert.True(t, serverVersion{9, 5}.atLeast(versionOnConflict))
+	assert.True(t, serverVersion{10, 0}.atLeast(versionOnConflict))
+	assert.False(t, serverVersion{9, 4}.atLeast(versionOnConflict))
+	assert.False(t, serverVersion{14, 7}.atLeast(versionMerge))
+}
+
+func TestCheckStatement(t *testing.T) {
+	upsert := &exql.Statement{Type: exql.Insert, OnConflict: &exql.OnConflict{}}
+	skipLocked := &exql.Statement{Type: exql.Select, Lock: &exql.Lock{SkipLocked: true}}
+	forUpdate := &exql.Statement{Type: exql.Select, Lock: &exql.Lock{}}
+
+	d := newDatabase(nil)
+	d.serverVersion.Store("9.4.26")
+
+	clone := newDatabase(nil)
+	clone.serverVersion = d.serverVersion
+
+	for _, sess := range []*database{d, clone} {
+		assert.False(t, sess.SupportsOnConflict())
+		assert.False(t, sess.SupportsSkipLocked())
+		assert.False(t, sess.SupportsMerge())
+
+		err := sess.CheckStatement(upsert)
+		if assert.IsType(t, &VersionError{}, err) {
+			assert.Equal(t, "upper: ON CONFLICT requires PostgreSQL 9.5 or later, the server runs 9.4.26", err.Error())
+			assert.True(t, err.(*VersionError).Is(db.ErrUnsupported))
+		}
+		assert.IsType(t, &VersionError{}, sess.CheckStatement(skipLocked))
+		assert.NoError(t, sess.CheckStatement(forUpdate))
+	}
+
+	d = newDatabase(nil)
+	d.serverVersion.Store("15.2")
+	assert.True(t, d.SupportsOnConflict())
+	assert.True(t, d.SupportsMerge())
+	assert.NoError(t, d.CheckStatement(upsert))
+	assert.NoError(t, d.CheckStatement(skipLocked))
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/frazercomputing/upper-io-db/lib/reflectx"
+)
+
+// PreloadBatchSize is the maximum number of parent keys Preload puts in a
+// single query, it keeps queries within the parameter limits of databases
+// like SQLite (999) and SQL Server (2100).
+var PreloadBatchSize = 500
+
+var preloadMapper = reflectx.NewMapper("db")
+
+var errPreloadDestination = errors.New("upper: Preload expects a pointer to a map of slices of structs, like *map[int64][]Post")
+
+// Preload loads the children of the given parents with one query for each
+// PreloadBatchSize parents, instead of one for each parent, and groups them
+// by parent key into the map dst points to:
+//
+//	var users []User
+//	...
+//	var postsByUser map[int64][]Post
+//	err = db.Preload(sess, users, "UserID", sess.Collection("post"), &postsByUser)
+//
+// parents is a slice of structs, or of pointers to structs, whose keys are
+// the fields tagged with the "pk" option, or the "id" field if none is, or a
+// slice of keys, like []int64. foreignKey is the field of the children that
+// holds the key of their parent, given by its name in the struct or by the
+// column it's mapped to.
+//
+// The children are read from the table of the given collection through sess,
+// so Preload can be used within a transaction, with a WHERE foreignKey IN
+// (...) condition. Parents without children, and children with a NULL
+// foreign key, have no entry in dst. This is
+// not a relationship system, there's no nesting and nothing is written back
+// to the parents.
+func Preload(sess Database, parents interface{}, foreignKey string, children Collection, dst interface{}) error {
+	dstV := reflect.ValueOf(dst)
+	if dstV.Kind() != reflect.Ptr || dstV.IsNil() || dstV.Elem().Kind() != reflect.Map || dstV.Elem().Type().Elem().Kind() != reflect.Slice {
+		return errPreloadDestination
+	}
+	mapT := dstV.Elem().Type()
+	sliceT := mapT.Elem()
+
+	childT := sliceT.Elem()
+	if childT.Kind() == reflect.Ptr {
+		childT = childT.Elem()
+	}
+	if childT.Kind() != reflect.Struct {
+		return errPreloadDestination
+	}
+
+	fk, err := preloadField(childT, foreignKey)
+	if err != nil {
+		return err
+	}
+
+	keys, err := preloadKeys(parents)
+	if err != nil {
+		return err
+	}
+
+	if sess != nil {
+		children = sess.Collection(children.Name())
+	}
+
+	grouped := reflect.MakeMap(mapT)
+	for len(keys) > 0 {
+		n := PreloadBatchSize
+		if n < 1 || n > len(keys) {
+			n = len(keys)
+		}
+
+		batch := reflect.New(sliceT)
+		if err := children.Find(Cond{fk.Name: In(keys[:n])}).All(batch.Interface()); err != nil {
+			return err
+		}
+		keys = keys[n:]
+
+		items := batch.Elem()
+		for i := 0; i < items.Len(); i++ {
+			item := items.Index(i)
+			key := reflectx.FieldByIndexesReadOnly(reflect.Indirect(item), fk.Index)
+			if key.Kind() == reflect.Ptr {
+				if key.IsNil() {
+					continue
+				}
+				key = key.Elem()
+			}
+			if !key.Type().AssignableTo(mapT.Key()) {
+				if !key.Type().ConvertibleTo(mapT.Key()) {
+					return fmt.Errorf("upper: Preload can't use %v values of %q as %v keys", key.Type(), foreignKey, mapT.Key())
+				}
+				key = key.Convert(mapT.Key())
+			}
+			group := grouped.MapIndex(key)
+			if !group.IsValid() {
+				group = reflect.MakeSlice(sliceT, 0, 1)
+			}
+			grouped.SetMapIndex(key, reflect.Append(group, item))
+		}
+	}
+
+	dstV.Elem().Set(grouped)
+	return nil
+}
+
+// preloadField returns the field of the given struct type that has the given
+// name or is mapped to the given column.
+func preloadField(t reflect.Type, name string) (*reflectx.FieldInfo, error) {
+	typeMap := preloadMapper.TypeMap(t)
+	if fi, ok := typeMap.Names[name]; ok {
+		return fi, nil
+	}
+	for _, fi := range typeMap.Index {
+		if fi.Field.Name == name && typeMap.Names[fi.Name] == fi {
+			return fi, nil
+		}
+	}
+	return nil, fmt.Errorf("upper: %v has no field or column named %q", t, name)
+}
+
+// preloadKeys returns the distinct keys of the given parents.
+func preloadKeys(parents interface{}) ([]interface{}, error) {
+	parentsV := reflect.Indirect(reflect.ValueOf(parents))
+	if parentsV.Kind() != reflect.Slice && parentsV.Kind() != reflect.Array {
+		return nil, fmt.Errorf("upper: Preload expects a slice of parents, got %T", parents)
+	}
+
+	elemT := parentsV.Type().Elem()
+	if elemT.Kind() == reflect.Ptr {
+		elemT = elemT.Elem()
+	}
+
+	var pk *reflectx.FieldInfo
+	if elemT.Kind() == reflect.Struct {
+		typeMap := preloadMapper.TypeMap(elemT)
+		for _, fi := range typeMap.Index {
+			if _, ok := fi.Options["pk"]; !ok || typeMap.Names[fi.Name] != fi {
+				continue
+			}
+			if pk != nil {
+				return nil, fmt.Errorf("upper: Preload can't use the composite key of %v", elemT)
+			}
+			pk = fi
+		}
+		if pk == nil {
+			pk = typeMap.Names["id"]
+		}
+		if pk == nil {
+			return nil, fmt.Errorf("upper: %v has no field tagged \"pk\" or mapped to \"id\"", elemT)
+		}
+	}
+
+	keyT := elemT
+	if pk != nil {
+		keyT = pk.Field.Type
+	}
+	if !keyT.Comparable() {
+		return nil, fmt.Errorf("upper: Preload can't use %v values as keys", keyT)
+	}
+
+	keys := make([]interface{}, 0, parentsV.Len())
+	seen := make(map[interface{}]struct{}, parentsV.Len())
+	for i := 0; i < parentsV.Len(); i++ {
+		parent := parentsV.Index(i)
+		if pk != nil {
+			if parent = reflect.Indirect(parent); !parent.IsValid() {
+				continue
+			}
+			parent = reflectx.FieldByIndexesReadOnly(parent, pk.Index)
+		}
+		key := parent.Interface()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
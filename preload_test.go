@@ -0,0 +1,89 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreloadKeys(t *testing.T) {
+	type user struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	type account struct {
+		Code string `db:"code,pk"`
+		ID   int64  `db:"id"`
+	}
+	type membership struct {
+		GroupID int64 `db:"group_id,pk"`
+		UserID  int64 `db:"user_id,pk"`
+	}
+
+	tests := []struct {
+		parents interface{}
+		keys    []interface{}
+	}{
+		{[]user{{ID: 1}, {ID: 2}, {ID: 1}}, []interface{}{int64(1), int64(2)}},
+		{&[]*user{{ID: 3}, nil}, []interface{}{int64(3)}},
+		{[]account{{Code: "a", ID: 1}}, []interface{}{"a"}},
+		{[]string{"a", "b", "a"}, []interface{}{"a", "b"}},
+	}
+	for _, test := range tests {
+		keys, err := preloadKeys(test.parents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(test.keys, keys) {
+			t.Fatalf("expecting keys %v of %T, got %v", test.keys, test.parents, keys)
+		}
+	}
+
+	for _, parents := range []interface{}{
+		[]membership{},
+		[]struct{ Name string }{},
+		[][]byte{},
+		user{},
+	} {
+		if _, err := preloadKeys(parents); err == nil {
+			t.Fatalf("expecting an error for %T", parents)
+		}
+	}
+}
+
+func TestPreloadField(t *testing.T) {
+	type post struct {
+		ID     int64  `db:"id"`
+		UserID int64  `db:"user_id"`
+		Title  string `db:"title"`
+	}
+
+	postT := reflect.TypeOf(post{})
+
+	for _, name := range []string{"UserID", "user_id"} {
+		fi, err := preloadField(postT, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Name != "user_id" {
+			t.Fatalf("expecting %q to be user_id, got %q", name, fi.Name)
+		}
+	}
+
+	if _, err := preloadField(postT, "AuthorID"); err == nil {
+		t.Fatal("expecting an error")
+	}
+}
+
+func TestPreloadDestination(t *testing.T) {
+	for _, dst := range []interface{}{
+		nil,
+		map[int64][]struct{}{},
+		&map[int64]struct{}{},
+		&map[int64][]int64{},
+		&[]struct{}{},
+	} {
+		if err := Preload(nil, []int64{1}, "id", nil, dst); err != errPreloadDestination {
+			t.Fatalf("expecting errPreloadDestination for %T, got %v", dst, err)
+		}
+	}
+}
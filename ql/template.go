@@ -38,6 +38,7 @@ const (
 	adapterDescKeyword         = `DESC`
 	adapterAscKeyword          = `ASC`
 	adapterAssignmentOperator  = `=`
+	adapterConcatOperator      = `+`
 	adapterClauseGroup         = `({{.}})`
 	adapterClauseOperator      = ` {{.}} `
 	adapterColumnValue         = `{{.Column}} {{.Operator}} {{.Value}}`
@@ -120,17 +121,29 @@ const (
       {{if .Offset}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{.Lock | compile}}
   `
 	adapterDeleteLayout = `
     DELETE
       FROM {{.Table | compile}}
-      {{.Where | compile}}
+      {{if .Limit}}
+        WHERE id() IN (SELECT id() FROM {{.Table | compile}} {{.Where | compile}} LIMIT {{.Limit}})
+      {{else}}
+        {{.From | compile}}
+        {{.Where | compile}}
+      {{end}}
   `
 	adapterUpdateLayout = `
     UPDATE
       {{.Table | compile}}
     SET {{.ColumnValues | compile}}
-      {{.Where | compile}}
+      {{if .Limit}}
+        WHERE id() IN (SELECT id() FROM {{.Table | compile}} {{.Where | compile}} LIMIT {{.Limit}})
+      {{else}}
+        {{.From | compile}}
+        {{.Where | compile}}
+      {{end}}
   `
 
 	adapterSelectCountLayout = `
@@ -163,7 +176,7 @@ const (
   `
 
 	adapterDropTableLayout = `
-    DROP TABLE {{.Table | compile}}
+    DROP TABLE {{if .IfExists}}IF EXISTS {{end}}{{.Table | compile}}
   `
 
 	adapterGroupByLayout = `
@@ -184,6 +197,7 @@ var template = &exql.Template{
 	DescKeyword:         adapterDescKeyword,
 	AscKeyword:          adapterAscKeyword,
 	AssignmentOperator:  adapterAssignmentOperator,
+	ConcatOperator:      adapterConcatOperator,
 	ClauseGroup:         adapterClauseGroup,
 	ClauseOperator:      adapterClauseOperator,
 	ColumnValue:         adapterColumnValue,
@@ -204,11 +218,16 @@ var template = &exql.Template{
 	DropTableLayout:     adapterDropTableLayout,
 	CountLayout:         adapterSelectCountLayout,
 	GroupByLayout:       adapterGroupByLayout,
+	Placeholder:         exql.DollarPlaceholder,
 	Cache:               cache.NewCache(),
 	ComparisonOperator: map[db.ComparisonOperator]string{
 		db.ComparisonOperatorEqual:     "==",
 		db.ComparisonOperatorNotLike:   "!(:column LIKE ?)",
 		db.ComparisonOperatorRegExp:    "LIKE",
 		db.ComparisonOperatorNotRegExp: "!(:column LIKE ?)",
+		// LIKE patterns are regular expressions in QL, the (?i) flag makes
+		// them case insensitive.
+		db.ComparisonOperatorILike:    `:column LIKE "(?i)" + ?`,
+		db.ComparisonOperatorNotILike: `!(:column LIKE "(?i)" + ?)`,
 	},
 }
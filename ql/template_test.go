@@ -144,6 +144,11 @@ func TestTemplateSelect(t *testing.T) {
 		"SELECT DATE()",
 		b.Select(db.Raw("DATE()")).String(),
 	)
+
+	assert.Equal(
+		`SELECT * FROM artist WHERE (name LIKE "(?i)" + $1 && !(name LIKE "(?i)" + $2)) ORDER BY id() ASC`,
+		b.SelectFrom("artist").Where(db.Cond{"name": db.ILike("^f")}).And(db.Cond{"name": db.NotILike("x$")}).String(),
+	)
 }
 
 func TestTemplateInsert(t *testing.T) {
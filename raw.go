@@ -93,3 +93,13 @@ func Raw(value string, args ...interface{}) RawValue {
 }
 
 var _ = RawValue(&rawValue{})
+
+// Default stands for the DEFAULT keyword, it can be given as a value to
+// inserts and updates to set a column to its default value explicitly, even
+// if other columns get values.
+//
+// Example:
+//
+//	// INSERT INTO "t" ("a", "b") VALUES ($1, DEFAULT)
+//	sess.InsertInto("t").Columns("a", "b").Values(1, db.Default)
+var Default = Raw("DEFAULT")
@@ -21,6 +21,10 @@
 
 package db
 
+import (
+	"context"
+)
+
 // Result is an interface that defines methods which are useful for working
 // with result sets.
 type Result interface {
@@ -41,7 +45,9 @@ type Result interface {
 	// OrderBy receives one or more field names that define the order in which
 	// elements will be returned in a query, field names may be prefixed with a
 	// minus sign (-) indicating descending order, ascending order will be used
-	// otherwise.
+	// otherwise. Use Asc and Desc to control where NULL values are placed:
+	//
+	//   res.OrderBy(db.Desc("rating").NullsLast())
 	OrderBy(...interface{}) Result
 
 	// Select defines specific columns to be returned from the elements of the
@@ -66,6 +72,20 @@ type Result interface {
 	//   res := col.Find(...).And(...)
 	And(...interface{}) Result
 
+	// Or returns a result set that matches either the existing constraints
+	// or the given ones.
+	//
+	//   res := col.Find(db.Cond{"active": true}).Or(db.Cond{"role": "admin"})
+	//
+	// Like And, it returns a new result set and leaves the original one
+	// untouched, so a base filter can be narrowed or widened many times:
+	//
+	//   res := col.Find(db.Cond{"active": true})
+	//   if name != "" {
+	//     res = res.And(db.Cond{"name LIKE": name + "%"})
+	//   }
+	Or(...interface{}) Result
+
 	// Group is used to group results that have the same value in the same column
 	// or columns.
 	Group(...interface{}) Result
@@ -78,6 +98,53 @@ type Result interface {
 	// are not honoured by `Update()`.
 	Update(interface{}) error
 
+	// DeleteLimit deletes at most n items within the result set and returns
+	// the number of deleted items, so large sets can be deleted in batches
+	// that don't hold locks for long:
+	//
+	//   for {
+	//     n, err := res.DeleteLimit(1000)
+	//     if err != nil || n == 0 {
+	//       break
+	//     }
+	//   }
+	//
+	// Which items are picked is up to the database.
+	DeleteLimit(n int) (int64, error)
+
+	// UpdateLimit is like Update but it modifies at most n items within the
+	// result set and returns the number of modified items. Conditions must
+	// exclude the items that were already updated for batches to progress.
+	UpdateLimit(values interface{}, n int) (int64, error)
+
+	// Increment atomically adds delta to the given column on all items within
+	// the result set and returns the number of affected items. The new value
+	// is computed by the database, so concurrent increments on the same item
+	// are never lost:
+	//
+	//   // UPDATE "posts" SET "views" = "views" + 1 WHERE ("id" = 7)
+	//   n, err := col.Find(7).Increment("views", 1)
+	Increment(column string, delta interface{}) (int64, error)
+
+	// Decrement is like Increment, but it subtracts delta from the column.
+	Decrement(column string, delta interface{}) (int64, error)
+
+	// EachBatch walks over all items within the result set in batches of up
+	// to batchSize items, the slice sliceOfStructs points to is replaced with
+	// the next batch before each call to fn. Items are paginated by primary
+	// key (WHERE pk > last LIMIT n), so no transaction or cursor is held
+	// between batches and items that are changed by fn are not visited twice.
+	// `OrderBy()`, `Offset()` and `Limit()` are not honoured by `EachBatch()`.
+	//
+	//   var users []User
+	//   err := col.Find("migrated", false).EachBatch(ctx, 500, &users, func() error {
+	//     ...
+	//   })
+	//
+	// EachBatch stops and returns the error when fn returns one or when ctx is
+	// done.
+	EachBatch(ctx context.Context, batchSize int, sliceOfStructs interface{}, fn func() error) error
+
 	// Count returns the number of items that match the set conditions. `Offset()`
 	// and `Limit()` are not honoured by `Count()`
 	Count() (uint64, error)
@@ -99,12 +166,23 @@ type Result interface {
 	// given pointer to struct or pointer to map. The result set is automatically
 	// closed after picking the element, so there is no need to call Close()
 	// after using One().
+	//
+	// If the result set is empty, One() returns ErrNoMoreRows, which can be
+	// compared with == or errors.Is, and the destination is set to its zero
+	// value.
 	One(ptrToStruct interface{}) error
 
 	// All fetches all results within the result set and dumps them into the
 	// given pointer to slice of maps or structs.  The result set is
 	// automatically closed, so there is no need to call Close() after
-	// using All().
+	// using All(). When a single column is selected, results may also be
+	// dumped into a slice of plain values, like []int64, and when two
+	// columns are selected, into a map of the first one to the second one,
+	// like map[int64]string.
+	//
+	// An empty result set is not an error: All() sets the destination to an
+	// empty slice and returns nil. The same goes for Next(), which returns
+	// false and leaves Err() as nil.
 	All(sliceOfStructs interface{}) error
 
 	// Paginate splits the results of the query into pages containing pageSize
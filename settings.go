@@ -39,6 +39,12 @@ type Settings interface {
 	// Returns the currently configured logger.
 	Logger() Logger
 
+	// SetMetrics defines a collector of statement statistics, nil disables
+	// it.
+	SetMetrics(Metrics)
+	// Metrics returns the currently configured metrics collector, or nil.
+	Metrics() Metrics
+
 	// SetPreparedStatementCache enables or disables the prepared statement
 	// cache.
 	SetPreparedStatementCache(bool)
@@ -69,21 +75,120 @@ type Settings interface {
 	// MaxOpenConns returns the default maximum number of open connections to the
 	// database.
 	MaxOpenConns() int
+
+	// SetDefaultQueryTimeout sets the maximum amount of time a statement may
+	// run, including transaction statements. The timeout is applied on top of
+	// the statement's context, so contexts with an earlier deadline win. For
+	// statements that return rows the timeout bounds reading the rows as
+	// well. Zero, the default, means no timeout.
+	SetDefaultQueryTimeout(time.Duration)
+
+	// DefaultQueryTimeout returns the maximum amount of time a statement may
+	// run.
+	DefaultQueryTimeout() time.Duration
+
+	// SetAcquireTimeout sets the maximum amount of time a statement may wait
+	// for a connection from the pool once MaxOpenConns connections are in
+	// use. Statements that can't get a connection in time fail with
+	// ErrTooManyClients, regardless of their own deadline. The timeout does
+	// not apply to transactions, which hold a connection already, and
+	// statements that wait on it skip the prepared statement cache. Zero, the
+	// default, means waiting for as long as the statement's context allows.
+	SetAcquireTimeout(time.Duration)
+
+	// AcquireTimeout returns the maximum amount of time a statement may wait
+	// for a connection from the pool.
+	AcquireTimeout() time.Duration
+
+	// SetQueryComment sets a SQL comment, like a service name, that is sent
+	// along with every statement, before the statement's own comment.
+	// Statements that are already in the prepared statement cache keep the
+	// comment they were prepared with.
+	SetQueryComment(string)
+
+	// QueryComment returns the SQL comment that is sent along with every
+	// statement.
+	QueryComment() string
+
+	// SetSQLCommenter sets a function that returns the sqlcommenter tags of a
+	// statement given its context. Tags set with WithSQLCommentTags are
+	// included as well, the ones returned by fn win. Statements that run
+	// through the prepared statement cache are not tagged, as their text
+	// can't change from one execution to the next.
+	SetSQLCommenter(fn SQLCommenter)
+
+	// SQLCommenter returns the function that returns the sqlcommenter tags of
+	// a statement, or nil.
+	SQLCommenter() SQLCommenter
+
+	// SetForceUTC enables or disables converting the time.Time values that
+	// are fetched with One, All and Scan to UTC, regardless of the time zone
+	// the driver or the server used.
+	SetForceUTC(bool)
+
+	// ForceUTC returns true if fetched time.Time values are converted to UTC.
+	ForceUTC() bool
+
+	// SetLockDiagnostics enables or disables looking up the sessions that hold
+	// locks on the table of a statement that timed out or couldn't get a lock,
+	// they're attached to the error as a *LockWaitError. It's a debugging aid,
+	// each of those errors costs an extra query.
+	SetLockDiagnostics(bool)
+
+	// LockDiagnostics returns true if lock holders are attached to timeout
+	// errors.
+	LockDiagnostics() bool
+
+	// SetQuoteAllIdentifiers sets whether every table and column name is
+	// quoted, which is the default, or only the ones that need it: the words
+	// given to SetReservedWords and names made of anything other than lowercase
+	// letters, digits and underscores.
+	SetQuoteAllIdentifiers(bool)
+
+	// QuoteAllIdentifiers returns false if only the identifiers that need it
+	// are quoted.
+	QuoteAllIdentifiers() bool
+
+	// SetReservedWords sets the names that are always quoted when
+	// QuoteAllIdentifiers is false, like "order" or "user".
+	SetReservedWords(words ...string)
+
+	// ReservedWords returns the words given to SetReservedWords.
+	ReservedWords() []string
+
+	// SetOutboxTable sets the name of the table the events given to
+	// Tx.Outbox are written to and DrainOutbox reads, "outbox" by default.
+	SetOutboxTable(name string)
+
+	// OutboxTable returns the name of the outbox table.
+	OutboxTable() string
 }
 
 type settings struct {
 	sync.RWMutex
 
 	preparedStatementCacheEnabled uint32
-
-	connMaxLifetime time.Duration
-	maxOpenConns    int
-	maxIdleConns    int
+	forceUTC                      uint32
+	lockDiagnostics               uint32
+	minimalQuoting                uint32
+
+	connMaxLifetime     time.Duration
+	maxOpenConns        int
+	maxIdleConns        int
+	defaultQueryTimeout time.Duration
+	acquireTimeout      time.Duration
+	queryComment        string
+	sqlCommenter        SQLCommenter
+	reservedWords       []string
+	outboxTable         string
 
 	loggingEnabled uint32
 	queryLogger    Logger
 	queryLoggerMu  sync.RWMutex
 	defaultLogger  defaultLogger
+
+	metrics   Metrics
+	metricsMu sync.RWMutex
 }
 
 func (c *settings) Logger() Logger {
@@ -104,6 +209,20 @@ func (c *settings) SetLogger(lg Logger) {
 	c.queryLogger = lg
 }
 
+func (c *settings) Metrics() Metrics {
+	c.metricsMu.RLock()
+	defer c.metricsMu.RUnlock()
+
+	return c.metrics
+}
+
+func (c *settings) SetMetrics(m Metrics) {
+	c.metricsMu.Lock()
+	defer c.metricsMu.Unlock()
+
+	c.metrics = m
+}
+
 func (c *settings) binaryOption(opt *uint32) bool {
 	if atomic.LoadUint32(opt) == 1 {
 		return true
@@ -135,6 +254,42 @@ func (c *settings) PreparedStatementCacheEnabled() bool {
 	return c.binaryOption(&c.preparedStatementCacheEnabled)
 }
 
+func (c *settings) SetForceUTC(value bool) {
+	c.setBinaryOption(&c.forceUTC, value)
+}
+
+func (c *settings) ForceUTC() bool {
+	return c.binaryOption(&c.forceUTC)
+}
+
+func (c *settings) SetLockDiagnostics(value bool) {
+	c.setBinaryOption(&c.lockDiagnostics, value)
+}
+
+func (c *settings) LockDiagnostics() bool {
+	return c.binaryOption(&c.lockDiagnostics)
+}
+
+func (c *settings) SetQuoteAllIdentifiers(value bool) {
+	c.setBinaryOption(&c.minimalQuoting, !value)
+}
+
+func (c *settings) QuoteAllIdentifiers() bool {
+	return !c.binaryOption(&c.minimalQuoting)
+}
+
+func (c *settings) SetReservedWords(words ...string) {
+	c.Lock()
+	c.reservedWords = append([]string(nil), words...)
+	c.Unlock()
+}
+
+func (c *settings) ReservedWords() []string {
+	c.RLock()
+	defer c.RUnlock()
+	return append([]string(nil), c.reservedWords...)
+}
+
 func (c *settings) SetConnMaxLifetime(t time.Duration) {
 	c.Lock()
 	c.connMaxLifetime = t
@@ -171,6 +326,66 @@ func (c *settings) MaxOpenConns() int {
 	return c.maxOpenConns
 }
 
+func (c *settings) SetDefaultQueryTimeout(t time.Duration) {
+	c.Lock()
+	c.defaultQueryTimeout = t
+	c.Unlock()
+}
+
+func (c *settings) DefaultQueryTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.defaultQueryTimeout
+}
+
+func (c *settings) SetAcquireTimeout(t time.Duration) {
+	c.Lock()
+	c.acquireTimeout = t
+	c.Unlock()
+}
+
+func (c *settings) AcquireTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.acquireTimeout
+}
+
+func (c *settings) SetQueryComment(comment string) {
+	c.Lock()
+	c.queryComment = comment
+	c.Unlock()
+}
+
+func (c *settings) QueryComment() string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.queryComment
+}
+
+func (c *settings) SetSQLCommenter(fn SQLCommenter) {
+	c.Lock()
+	c.sqlCommenter = fn
+	c.Unlock()
+}
+
+func (c *settings) SQLCommenter() SQLCommenter {
+	c.RLock()
+	defer c.RUnlock()
+	return c.sqlCommenter
+}
+
+func (c *settings) SetOutboxTable(name string) {
+	c.Lock()
+	c.outboxTable = name
+	c.Unlock()
+}
+
+func (c *settings) OutboxTable() string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.outboxTable
+}
+
 // NewSettings returns a new settings value prefilled with the current default
 // settings.
 func NewSettings() Settings {
@@ -185,4 +400,5 @@ var DefaultSettings Settings = &settings{
 	connMaxLifetime:               time.Duration(0),
 	maxIdleConns:                  10,
 	maxOpenConns:                  0,
+	outboxTable:                   "outbox",
 }
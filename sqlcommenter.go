@@ -0,0 +1,63 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"context"
+)
+
+// SQLCommenter returns the tags that are appended to a statement as a
+// sqlcommenter comment (https://google.github.io/sqlcommenter/), like
+// /*action='checkout',traceparent='00-...'*/, given the statement's context.
+// It's the place to map tracing and request data into the comment:
+//
+//	sess.SetSQLCommenter(func(ctx context.Context) map[string]string {
+//		return map[string]string{"traceparent": traceParentFromContext(ctx)}
+//	})
+type SQLCommenter func(ctx context.Context) map[string]string
+
+type sqlCommentTagsKey struct{}
+
+// WithSQLCommentTags returns a copy of ctx that carries the given sqlcommenter
+// tags, like "controller" or "action", in addition to the ones ctx already
+// carries. Statements that run with the returned context are tagged with
+// them.
+func WithSQLCommentTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := map[string]string{}
+	for k, v := range SQLCommentTags(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, sqlCommentTagsKey{}, merged)
+}
+
+// SQLCommentTags returns the sqlcommenter tags carried by ctx. The returned
+// map must not be modified.
+func SQLCommentTags(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	tags, _ := ctx.Value(sqlCommentTagsKey{}).(map[string]string)
+	return tags
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSQLCommentTags(t *testing.T) {
+	ctx := context.Background()
+	if tags := SQLCommentTags(ctx); tags != nil {
+		t.Fatalf("expecting no tags, got %v", tags)
+	}
+
+	ctx = WithSQLCommentTags(ctx, map[string]string{"controller": "cart", "action": "view"})
+	child := WithSQLCommentTags(ctx, map[string]string{"action": "checkout"})
+
+	if tags := SQLCommentTags(ctx); len(tags) != 2 || tags["action"] != "view" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+	if tags := SQLCommentTags(child); len(tags) != 2 || tags["controller"] != "cart" || tags["action"] != "checkout" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+}
@@ -62,6 +62,14 @@ func (t *table) Database() sqladapter.Database {
 
 // Insert inserts an item (map or struct) into the collection.
 func (t *table) Insert(item interface{}) (interface{}, error) {
+	id, err := t.insert(item)
+	if err == nil {
+		sqladapter.EmitInsert(t, id)
+	}
+	return id, err
+}
+
+func (t *table) insert(item interface{}) (interface{}, error) {
 	columnNames, columnValues, err := sqlbuilder.Map(item, nil)
 	if err != nil {
 		return nil, err
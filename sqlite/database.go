@@ -168,11 +168,12 @@ func (d *database) clone(ctx context.Context, checkConn bool) (*database, error)
 // CompileStatement allows sqladapter to compile the given statement into the
 // format SQLite expects.
 func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
-	compiled, err := stmt.Compile(template)
+	compiled, err := stmt.Compile(sqladapter.SessionTemplate(d.BaseDatabase, template))
 	if err != nil {
 		panic(err.Error())
 	}
-	return sqlbuilder.Preprocess(compiled, args)
+	query, args := sqlbuilder.PreprocessWithTemplate(template, compiled, args)
+	return template.BindArguments(query, args)
 }
 
 // Err allows sqladapter to translate some known errors into generic errors.
@@ -221,6 +222,11 @@ func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) erro
 	return sqladapter.RunTx(d, ctx, fn)
 }
 
+// TxWithStats works like Tx and fills stats in with the details of the run.
+func (d *database) TxWithStats(ctx context.Context, fn func(tx sqlbuilder.Tx) error, stats *sqlbuilder.TxStats) error {
+	return sqladapter.RunTxWithStats(d, ctx, fn, stats)
+}
+
 // NewDatabaseTx allows sqladapter start a transaction block.
 func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
 	clone, err := d.clone(ctx, true)
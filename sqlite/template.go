@@ -22,6 +22,7 @@
 package sqlite
 
 import (
+	db "github.com/frazercomputing/upper-io-db"
 	"github.com/frazercomputing/upper-io-db/internal/cache"
 	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 )
@@ -37,8 +38,12 @@ const (
 	adapterNotKeyword          = `NOT`
 	adapterDescKeyword         = `DESC`
 	adapterAscKeyword          = `ASC`
+	adapterCollateLayout       = `COLLATE {{.}}`
+	adapterNullsFirstKeyword   = `NULLS FIRST`
+	adapterNullsLastKeyword    = `NULLS LAST`
 	adapterDefaultOperator     = `=`
 	adapterAssignmentOperator  = `=`
+	adapterConcatOperator      = `||`
 	adapterClauseGroup         = `({{.}})`
 	adapterClauseOperator      = ` {{.}} `
 	adapterColumnValue         = `{{.Column}} {{.Operator}} {{.Value}}`
@@ -122,17 +127,29 @@ const (
         {{end}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{.Lock | compile}}
   `
 	adapterDeleteLayout = `
     DELETE
       FROM {{.Table | compile}}
-      {{.Where | compile}}
+      {{if .Limit}}
+        WHERE rowid IN (SELECT rowid FROM {{.Table | compile}} {{.Where | compile}} LIMIT {{.Limit}})
+      {{else}}
+        {{.From | compile}}
+        {{.Where | compile}}
+      {{end}}
   `
 	adapterUpdateLayout = `
     UPDATE
       {{.Table | compile}}
     SET {{.ColumnValues | compile}}
-      {{.Where | compile}}
+      {{if .Limit}}
+        WHERE rowid IN (SELECT rowid FROM {{.Table | compile}} {{.Where | compile}} LIMIT {{.Limit}})
+      {{else}}
+        {{.From | compile}}
+        {{.Where | compile}}
+      {{end}}
   `
 
 	adapterSelectCountLayout = `
@@ -151,11 +168,25 @@ const (
     {{else}}
       DEFAULT VALUES
     {{end}}
+    {{if defined .OnConflict}}
+      {{.OnConflict | compile}}
+    {{end}}
     {{if defined .Returning}}
       RETURNING {{.Returning | compile}}
     {{end}}
   `
 
+	adapterRandomFunction = `random()`
+
+	adapterOnConflictLayout = `
+    ON CONFLICT ({{range $i, $c := .Target}}{{if $i}}, {{end}}{{$c}}{{end}})
+    {{if .Update}}
+      DO UPDATE SET {{range $i, $c := .Update}}{{if $i}}, {{end}}{{$c}} = excluded.{{$c}}{{end}}
+    {{else}}
+      DO NOTHING
+    {{end}}
+  `
+
 	adapterTruncateLayout = `
     DELETE FROM {{.Table | compile}}
   `
@@ -165,7 +196,19 @@ const (
   `
 
 	adapterDropTableLayout = `
-    DROP TABLE {{.Table | compile}}
+    DROP TABLE {{if .IfExists}}IF EXISTS {{end}}{{.Table | compile}}
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table | compile}} {{.Alterations | compile}}
+  `
+
+	adapterAddColumnLayout = `ADD COLUMN {{.Column}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.Column}}`
+
+	adapterCreateTableLayout = `
+    CREATE {{if .Temporary}}TEMPORARY {{end}}TABLE {{if .IfNotExists}}IF NOT EXISTS {{end}}{{.Table | compile}} ({{.Definitions | compile}})
   `
 
 	adapterGroupByLayout = `
@@ -185,7 +228,11 @@ var template = &exql.Template{
 	OrKeyword:           adapterOrKeyword,
 	DescKeyword:         adapterDescKeyword,
 	AscKeyword:          adapterAscKeyword,
+	CollateLayout:       adapterCollateLayout,
+	NullsFirstKeyword:   adapterNullsFirstKeyword,
+	NullsLastKeyword:    adapterNullsLastKeyword,
 	AssignmentOperator:  adapterAssignmentOperator,
+	ConcatOperator:      adapterConcatOperator,
 	ClauseGroup:         adapterClauseGroup,
 	ClauseOperator:      adapterClauseOperator,
 	ColumnValue:         adapterColumnValue,
@@ -198,13 +245,27 @@ var template = &exql.Template{
 	UsingLayout:         adapterUsingLayout,
 	OrderByLayout:       adapterOrderByLayout,
 	InsertLayout:        adapterInsertLayout,
+	OnConflictLayout:    adapterOnConflictLayout,
+	RandomFunction:      adapterRandomFunction,
 	SelectLayout:        adapterSelectLayout,
 	UpdateLayout:        adapterUpdateLayout,
 	DeleteLayout:        adapterDeleteLayout,
 	TruncateLayout:      adapterTruncateLayout,
 	DropDatabaseLayout:  adapterDropDatabaseLayout,
 	DropTableLayout:     adapterDropTableLayout,
+	CreateTableLayout:   adapterCreateTableLayout,
+	AlterTableLayout:    adapterAlterTableLayout,
+	AddColumnLayout:     adapterAddColumnLayout,
+	DropColumnLayout:    adapterDropColumnLayout,
 	CountLayout:         adapterSelectCountLayout,
 	GroupByLayout:       adapterGroupByLayout,
 	Cache:               cache.NewCache(),
+	ComparisonOperator: map[db.ComparisonOperator]string{
+		db.ComparisonOperatorILike:    "LIKE",
+		db.ComparisonOperatorNotILike: "NOT LIKE",
+	},
+	ColumnTypes: map[string]string{
+		"serial":    `INTEGER`,
+		"bigserial": `INTEGER`,
+	},
 }
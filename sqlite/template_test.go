@@ -1,10 +1,13 @@
 package sqlite
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/internal/sqladapter/exql"
 	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
@@ -42,6 +45,11 @@ func TestTemplateSelect(t *testing.T) {
 		b.Select().From("artist").OrderBy("name ASC").String(),
 	)
 
+	assert.Equal(
+		`SELECT * FROM "artist" ORDER BY "name" COLLATE NOCASE ASC`,
+		b.Select().From("artist").OrderBy(db.Col("name").Collate("NOCASE")).String(),
+	)
+
 	assert.Equal(
 		`SELECT * FROM "artist" LIMIT -1 OFFSET 5`,
 		b.Select().From("artist").Limit(-1).Offset(5).String(),
@@ -144,6 +152,19 @@ func TestTemplateSelect(t *testing.T) {
 		`SELECT DATE()`,
 		b.Select(db.Raw("DATE()")).String(),
 	)
+
+	{
+		err := b.SelectFrom("jobs").ForUpdate().IteratorContext(context.Background()).Err()
+		assert.Equal(exql.ErrRowLocksUnsupported, err)
+
+		err = b.SelectFrom("jobs").SkipLocked().IteratorContext(context.Background()).Err()
+		assert.Equal(exql.ErrRowLocksUnsupported, err)
+	}
+
+	{
+		err := b.SelectFrom(sqlbuilder.Values([][]interface{}{{1}}, []string{"id"})).IteratorContext(context.Background()).Err()
+		assert.Equal(exql.ErrValuesTableUnsupported, err)
+	}
 }
 
 func TestTemplateInsert(t *testing.T) {
@@ -188,6 +209,26 @@ func TestTemplateInsert(t *testing.T) {
 	)
 }
 
+func TestTemplateUpsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	item := map[string]interface{}{"email": "ozzy@example.com", "name": "Ozzy"}
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO NOTHING`,
+		b.InsertInto("artist").Values(item).OnConflict("email").String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("email", "name") VALUES ($1, $2) ON CONFLICT ("email") DO UPDATE SET "name" = excluded."name"`,
+		b.InsertInto("artist").Values(item).OnConflict("email").DoUpdate().String(),
+	)
+
+	_, err := b.InsertInto("artist").Values(item).OnConflict("email").ReturningInserted().ExecContext(context.Background())
+	assert.Equal(exql.ErrUpsertUnsupported, err)
+}
+
 func TestTemplateUpdate(t *testing.T) {
 	b := sqlbuilder.WithTemplate(template)
 	assert := assert.New(t)
@@ -228,6 +269,9 @@ func TestTemplateUpdate(t *testing.T) {
 			"id = id + ?", 10,
 		).Where("id > ?", 0).String(),
 	)
+
+	_, err := b.Update("artist").Set("name = s.name").From("staging AS s").Where("artist.id = s.id").ExecContext(context.Background())
+	assert.Equal(exql.ErrUpdateFromUnsupported, err)
 }
 
 func TestTemplateDelete(t *testing.T) {
@@ -243,4 +287,40 @@ func TestTemplateDelete(t *testing.T) {
 		`DELETE FROM "artist" WHERE (id > 5)`,
 		b.DeleteFrom("artist").Where("id > 5").String(),
 	)
+
+	assert.Equal(
+		`DELETE FROM "artist" WHERE rowid IN (SELECT rowid FROM "artist" WHERE (id > 5) LIMIT 1000)`,
+		b.DeleteFrom("artist").Where("id > 5").Limit(1000).String(),
+	)
+
+	assert.Equal(
+		`UPDATE "artist" SET "name" = $1 WHERE rowid IN (SELECT rowid FROM "artist" WHERE (id > 5) LIMIT 1000)`,
+		b.Update("artist").Set("name", "Rick").Where("id > 5").Limit(1000).String(),
+	)
+
+	_, err := b.DeleteFrom("artist").Using("banned AS b").Where("artist.name = b.name").ExecContext(context.Background())
+	assert.Equal(exql.ErrDeleteUsingUnsupported, err)
+}
+
+func TestTemplateCreateTable(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	type account struct {
+		ID        int64      `db:"id,omitempty"`
+		Name      string     `db:"name"`
+		Active    bool       `db:"active"`
+		Avatar    []byte     `db:"avatar"`
+		CreatedAt *time.Time `db:"created_at"`
+	}
+
+	assert.Equal(
+		`CREATE TABLE "accounts" ("id" INTEGER PRIMARY KEY, "name" TEXT NOT NULL, "active" BOOLEAN NOT NULL, "avatar" BLOB NOT NULL, "created_at" TIMESTAMP)`,
+		b.CreateTable("accounts").Struct(account{}).String(),
+	)
+
+	assert.Equal(
+		`CREATE TABLE IF NOT EXISTS "accounts" ("id" INTEGER PRIMARY KEY, "name" TEXT NOT NULL, "active" BOOLEAN NOT NULL, "avatar" BLOB NOT NULL, "created_at" TIMESTAMP)`,
+		b.CreateTable("accounts").Struct(account{}).IfNotExists().String(),
+	)
 }
@@ -1,7 +1,9 @@
 package testsuite
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/stretchr/testify/suite"
 	db "github.com/frazercomputing/upper-io-db"
+	"github.com/frazercomputing/upper-io-db/lib/sqlbuilder"
 )
 
 type customLogger struct {
@@ -514,6 +517,1102 @@ func (s *SQLTestSuite) TestGetOneResult() {
 	}
 }
 
+func (s *SQLTestSuite) TestEmptyResult() {
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+
+	// One() on an empty set returns db.ErrNoMoreRows and resets dst.
+	someArtist := artistType{Name: "Nobody"}
+	err := artist.Find(db.Cond{"name": "Does not exist"}).One(&someArtist)
+	s.Equal(db.ErrNoMoreRows, err)
+	s.Zero(someArtist.Name)
+
+	someArtist = artistType{Name: "Nobody"}
+	err = sess.SelectFrom("artist").Where(db.Cond{"name": "Does not exist"}).One(&someArtist)
+	s.Equal(db.ErrNoMoreRows, err)
+	s.Zero(someArtist.Name)
+
+	// All() on an empty set returns an empty slice and no error.
+	var artists []artistType
+	err = artist.Find(db.Cond{"name": "Does not exist"}).All(&artists)
+	s.NoError(err)
+	s.NotNil(artists)
+	s.Equal(0, len(artists))
+
+	artists = nil
+	err = sess.SelectFrom("artist").Where(db.Cond{"name": "Does not exist"}).All(&artists)
+	s.NoError(err)
+	s.NotNil(artists)
+	s.Equal(0, len(artists))
+
+	// Iterating over an empty set is not an error either.
+	res := artist.Find(db.Cond{"name": "Does not exist"})
+	s.False(res.Next(&someArtist))
+	s.NoError(res.Err())
+	s.NoError(res.Close())
+
+	// An empty One() does not poison the result set.
+	res = artist.Find(db.Cond{"name": "Somebody"})
+	s.Equal(db.ErrNoMoreRows, res.One(&someArtist))
+	s.NoError(res.Err())
+
+	_, err = artist.Insert(artistType{Name: "Somebody"})
+	s.NoError(err)
+
+	s.NoError(res.One(&someArtist))
+	s.Equal("Somebody", someArtist.Name)
+}
+
+func (s *SQLTestSuite) TestResultExists() {
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+
+	exists, err := artist.Find().Exists()
+	s.NoError(err)
+	s.True(exists)
+
+	exists, err = artist.Find(db.Cond{"name": "Ozzie"}).Exists()
+	s.NoError(err)
+	s.True(exists)
+
+	exists, err = artist.Find(db.Cond{"name": "Ozzie"}).And(db.Cond{"name": "Flea"}).Exists()
+	s.NoError(err)
+	s.False(exists)
+
+	// The session's context is honored.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = sess.WithContext(ctx).Collection("artist").Find().Exists()
+	s.Error(err)
+}
+
+func (s *SQLTestSuite) TestResultAndOr() {
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+
+	base := artist.Find(db.Cond{"name": "Ozzie"})
+
+	count, err := base.Or(db.Cond{"name": "Flea"}).Count()
+	s.NoError(err)
+	s.Equal(uint64(2), count)
+
+	count, err = base.And(db.Cond{"name": "Flea"}).Count()
+	s.NoError(err)
+	s.Equal(uint64(0), count)
+
+	count, err = base.Or(db.Cond{"name": "Flea"}).And(db.Cond{"name": "Flea"}).Count()
+	s.NoError(err)
+	s.Equal(uint64(1), count)
+
+	count, err = base.Or("name = ?", "Flea").Or(db.Cond{"name": "Slash"}).Count()
+	s.NoError(err)
+	s.Equal(uint64(3), count)
+
+	// The base result set is not modified.
+	count, err = base.Count()
+	s.NoError(err)
+	s.Equal(uint64(1), count)
+}
+
+func (s *SQLTestSuite) TestExpressions() {
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+
+	err := artist.Find(db.Cond{"name": "Ozzie"}).Update(db.Cond{"name": db.Concat(db.Col("name"), "!")})
+	s.NoError(err)
+
+	count, err := artist.Find(db.Cond{"name": "Ozzie!"}).Count()
+	s.NoError(err)
+	s.Equal(uint64(1), count)
+
+	count, err = artist.Find(db.Cond{"id": db.Col("id").Add(1).Sub(1)}).Count()
+	s.NoError(err)
+	s.Equal(uint64(4), count)
+}
+
+func (s *SQLTestSuite) TestIncrementConcurrently() {
+	if s.Adapter() == "ql" {
+		s.T().Skip("Currently not supported.")
+	}
+
+	type counter struct {
+		ID    int64 `db:"id,omitempty"`
+		Views int64 `db:"views"`
+	}
+
+	sess := s.SQLBuilder()
+
+	_, err := sess.Exec(`DROP TABLE IF EXISTS counters`)
+	s.NoError(err)
+
+	_, err = sess.CreateTable("counters").Struct(counter{}).Exec()
+	s.NoError(err)
+
+	counters := sess.Collection("counters")
+
+	id, err := counters.Insert(counter{Views: 0})
+	s.NoError(err)
+
+	const workers, rounds = 10, 20
+
+	var mu sync.Mutex
+	var errs []error
+	addErr := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				if _, err := counters.Find(id).Increment("views", 2); err != nil {
+					addErr(err)
+				}
+				if _, err := counters.Find(id).Decrement("views", 1); err != nil {
+					addErr(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		s.NoError(err)
+	}
+
+	var item counter
+	s.NoError(counters.Find(id).One(&item))
+	s.Equal(int64(workers*rounds), item.Views)
+
+	affected, err := counters.Find(db.Cond{"id": -1}).Increment("views", 1)
+	s.NoError(err)
+	s.Equal(int64(0), affected)
+
+	affected, err = counters.Find(id).Increment("views", 1)
+	s.NoError(err)
+	s.Equal(int64(1), affected)
+
+	_, err = sess.Exec(`DROP TABLE counters`)
+	s.NoError(err)
+}
+
+func (s *SQLTestSuite) TestExplain() {
+	sess := s.SQLBuilder()
+
+	plan, err := sess.SelectFrom("artist").Explain(context.Background(), false)
+	switch s.Adapter() {
+	case "postgresql", "mssql":
+		s.NoError(err)
+		s.NotEmpty(plan)
+	default:
+		s.Equal(db.ErrUnsupported, err)
+	}
+}
+
+func (s *SQLTestSuite) TestDequeue() {
+	if s.Adapter() == "sqlite" || s.Adapter() == "ql" {
+		s.T().Skip("Row locks are not supported.")
+	}
+
+	type job struct {
+		ID     int64  `db:"id,omitempty"`
+		Status string `db:"status"`
+	}
+
+	sess := s.SQLBuilder()
+
+	_, err := sess.Exec(`DROP TABLE IF EXISTS jobs`)
+	s.NoError(err)
+
+	_, err = sess.CreateTable("jobs").Struct(job{}).Exec()
+	s.NoError(err)
+
+	jobs := sess.Collection("jobs")
+
+	const total = 40
+	for i := 0; i < total; i++ {
+		_, err := jobs.Insert(job{Status: "pending"})
+		s.NoError(err)
+	}
+
+	ctx := context.Background()
+
+	// A released batch can be dequeued again.
+	{
+		batch, err := jobs.Find("status", "pending").OrderBy("id").(sqlbuilder.Dequeuer).Dequeue(ctx, 5)
+		s.NoError(err)
+		s.Equal(5, batch.Len())
+		s.NoError(batch.Release())
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = map[int64]int{}
+		wg   sync.WaitGroup
+	)
+
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				batch, err := jobs.Find("status", "pending").OrderBy("id").(sqlbuilder.Dequeuer).Dequeue(ctx, 3)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if batch.Len() == 0 {
+					errs <- batch.Release()
+					return
+				}
+
+				var items []job
+				if err := batch.All(&items); err != nil {
+					_ = batch.Release()
+					errs <- err
+					return
+				}
+
+				mu.Lock()
+				for _, item := range items {
+					seen[item.ID]++
+				}
+				mu.Unlock()
+
+				if err := batch.Complete(map[string]interface{}{"status": "done"}); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		s.NoError(err)
+	}
+
+	s.Equal(total, len(seen))
+	for _, n := range seen {
+		s.Equal(1, n, "job was dequeued more than once")
+	}
+
+	count, err := jobs.Find("status", "done").Count()
+	s.NoError(err)
+	s.Equal(uint64(total), count)
+
+	_, err = sess.Exec(`DROP TABLE jobs`)
+	s.NoError(err)
+}
+
+func (s *SQLTestSuite) TestDeleteAndUpdateLimit() {
+	type event struct {
+		ID        int64  `db:"id,omitempty"`
+		Processed bool   `db:"processed"`
+		Name      string `db:"name"`
+	}
+
+	sess := s.SQLBuilder()
+
+	_, err := sess.Exec(`DROP TABLE IF EXISTS events`)
+	s.NoError(err)
+
+	_, err = sess.CreateTable("events").Struct(event{}).Exec()
+	s.NoError(err)
+
+	events := sess.Collection("events")
+
+	for i := 0; i < 25; i++ {
+		_, err := events.Insert(event{Name: fmt.Sprintf("event-%d", i)})
+		s.NoError(err)
+	}
+
+	var updated []int64
+	for {
+		n, err := events.Find("processed", false).UpdateLimit(map[string]interface{}{"processed": true}, 10)
+		s.NoError(err)
+		if n == 0 {
+			break
+		}
+		updated = append(updated, n)
+	}
+	s.Equal([]int64{10, 10, 5}, updated)
+
+	var deleted []int64
+	for {
+		n, err := events.Find().DeleteLimit(10)
+		s.NoError(err)
+		if n == 0 {
+			break
+		}
+		deleted = append(deleted, n)
+	}
+	s.Equal([]int64{10, 10, 5}, deleted)
+
+	count, err := events.Find().Count()
+	s.NoError(err)
+	s.Equal(uint64(0), count)
+
+	_, err = sess.Exec(`DROP TABLE events`)
+	s.NoError(err)
+}
+
+func (s *SQLTestSuite) TestEachBatch() {
+	if s.Adapter() == "ql" {
+		s.T().Skip("Primary keys are not supported.")
+	}
+
+	type reading struct {
+		SensorID int64   `db:"sensor_id"`
+		Seq      int64   `db:"seq"`
+		Amount   float64 `db:"amount"`
+	}
+
+	sess := s.SQLBuilder()
+
+	_, err := sess.Exec(`DROP TABLE IF EXISTS readings`)
+	s.NoError(err)
+
+	_, err = sess.Exec(`CREATE TABLE readings (
+		sensor_id INTEGER NOT NULL,
+		seq INTEGER NOT NULL,
+		amount FLOAT NOT NULL,
+		PRIMARY KEY (sensor_id, seq)
+	)`)
+	s.NoError(err)
+
+	readings := sess.Collection("readings")
+
+	for sensor := int64(1); sensor <= 3; sensor++ {
+		for seq := int64(1); seq <= 7; seq++ {
+			_, err := readings.Insert(reading{SensorID: sensor, Seq: seq, Amount: float64(seq)})
+			s.NoError(err)
+		}
+	}
+
+	ctx := context.Background()
+
+	var (
+		batch   []reading
+		sizes   []int
+		visited []reading
+	)
+	err = readings.Find(db.Cond{"amount >": 1}).EachBatch(ctx, 5, &batch, func() error {
+		sizes = append(sizes, len(batch))
+		visited = append(visited, batch...)
+		return nil
+	})
+	s.NoError(err)
+	s.Equal([]int{5, 5, 5, 3}, sizes)
+	s.Equal(18, len(visited))
+	for i := 1; i < len(visited); i++ {
+		prev, curr := visited[i-1], visited[i]
+		s.True(prev.SensorID < curr.SensorID || (prev.SensorID == curr.SensorID && prev.Seq < curr.Seq))
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = readings.Find().EachBatch(ctx, 4, &batch, func() error {
+		calls++
+		return errStop
+	})
+	s.Equal(errStop, err)
+	s.Equal(1, calls)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = readings.Find().EachBatch(canceled, 4, &batch, func() error {
+		return nil
+	})
+	s.Equal(context.Canceled, err)
+
+	_, err = sess.Exec(`DROP TABLE readings`)
+	s.NoError(err)
+}
+
+func (s *SQLTestSuite) TestDefaultOrderBy() {
+	type post struct {
+		ID    int64  `db:"id,omitempty"`
+		Title string `db:"title"`
+	}
+
+	sess := s.SQLBuilder()
+
+	_, err := sess.Exec(`DROP TABLE IF EXISTS posts`)
+	s.NoError(err)
+
+	_, err = sess.CreateTable("posts").Struct(post{}).Exec()
+	s.NoError(err)
+
+	posts := sess.Collection("posts")
+	defer posts.SetDefaultOrderBy()
+
+	for i := 1; i <= 5; i++ {
+		_, err := posts.Insert(post{Title: fmt.Sprintf("post-%d", i)})
+		s.NoError(err)
+	}
+
+	titles := func(res db.Result) []string {
+		var items []post
+		s.NoError(res.All(&items))
+		out := make([]string, len(items))
+		for i := range items {
+			out[i] = items[i].Title
+		}
+		return out
+	}
+
+	posts.SetDefaultOrderBy("-id")
+
+	s.Equal([]string{"post-5", "post-4", "post-3", "post-2", "post-1"}, titles(posts.Find()))
+	s.Equal([]string{"post-4", "post-3"}, titles(posts.Find().Limit(2).Offset(1)))
+
+	// An explicit order replaces the default.
+	s.Equal([]string{"post-1", "post-2", "post-3", "post-4", "post-5"}, titles(posts.Find().OrderBy("id")))
+
+	// The default is shared with transactions.
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		s.Equal([]string{"post-5", "post-4"}, titles(tx.Collection("posts").Find().Limit(2)))
+		return nil
+	})
+	s.NoError(err)
+
+	posts.SetDefaultOrderBy()
+	s.Equal([]string{"post-1", "post-2"}, titles(posts.Find().OrderBy("id").Limit(2)))
+
+	_, err = sess.Exec(`DROP TABLE posts`)
+	s.NoError(err)
+}
+
+func (s *SQLTestSuite) TestTxWithStats() {
+	sess := s.SQLBuilder()
+
+	var stats sqlbuilder.TxStats
+	err := sess.TxWithStats(context.Background(), func(tx sqlbuilder.Tx) error {
+		_, err := tx.Collection("artist").Insert(map[string]string{"name": "Stats"})
+		return err
+	}, &stats)
+	s.NoError(err)
+	s.Equal(1, stats.Attempts)
+	s.NoError(stats.Err)
+	s.Nil(stats.ErrKind)
+	s.True(stats.Duration > 0)
+
+	errAbort := errors.New("abort")
+	err = sess.TxWithStats(context.Background(), func(tx sqlbuilder.Tx) error {
+		return errAbort
+	}, &stats)
+	s.Equal(errAbort, err)
+	s.Equal(1, stats.Attempts)
+	s.Equal(errAbort, stats.Err)
+	s.Nil(stats.ErrKind)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = sess.TxWithStats(ctx, func(tx sqlbuilder.Tx) error {
+		return nil
+	}, &stats)
+	s.Error(err)
+	s.Equal(context.Canceled, stats.ErrKind)
+}
+
+func (s *SQLTestSuite) TestTxDone() {
+	sess := s.SQLBuilder()
+
+	tx, err := sess.NewTx(context.Background())
+	s.NoError(err)
+	s.False(tx.Done())
+	s.NoError(tx.TxErr())
+
+	_, err = tx.Collection("artist").Find().Count()
+	s.NoError(err)
+
+	s.NoError(tx.Commit())
+	s.True(tx.Done())
+	s.Equal(sql.ErrTxDone, tx.TxErr())
+
+	tx, err = sess.NewTx(context.Background())
+	s.NoError(err)
+	s.NoError(tx.Rollback())
+	s.True(tx.Done())
+	s.Equal(sql.ErrTxDone, tx.TxErr())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err = sess.NewTx(ctx)
+	s.NoError(err)
+	cancel()
+	s.True(tx.Done())
+	s.Equal(context.Canceled, tx.TxErr())
+	_ = tx.Rollback()
+}
+
+func (s *SQLTestSuite) TestTxClosedCollection() {
+	sess := s.SQLBuilder()
+
+	var artist db.Collection
+	err := sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		artist = tx.Collection("artist")
+		_, err := artist.Find().Count()
+		return err
+	})
+	s.NoError(err)
+
+	_, err = artist.Find().Count()
+	s.Equal(db.ErrTransactionClosed, err)
+
+	_, err = artist.Insert(map[string]string{"name": "Late"})
+	s.Equal(db.ErrTransactionClosed, err)
+
+	tx, err := sess.NewTx(context.Background())
+	s.NoError(err)
+
+	selector := tx.SelectFrom("artist")
+	s.NoError(tx.Rollback())
+
+	var artists []map[string]interface{}
+	s.Equal(db.ErrTransactionClosed, selector.All(&artists))
+
+	_, err = tx.Exec(`SELECT 1`)
+	s.Equal(db.ErrTransactionClosed, err)
+
+	// The session itself is still usable.
+	_, err = sess.Collection("artist").Find().Count()
+	s.NoError(err)
+}
+
+func (s *SQLTestSuite) TestTxPanic() {
+	sess := s.SQLBuilder()
+	artist := sess.Collection("artist")
+
+	count, err := artist.Find().Count()
+	s.NoError(err)
+
+	var inner sqlbuilder.Tx
+	var recovered interface{}
+	func() {
+		defer func() {
+			recovered = recover()
+		}()
+		_ = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+			inner = tx
+			if _, err := tx.Collection("artist").Insert(map[string]string{"name": "Panic"}); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+	s.Equal("boom", recovered)
+
+	s.True(inner.Done())
+	s.False(inner.(interface{ Committed() bool }).Committed())
+
+	after, err := artist.Find().Count()
+	s.NoError(err)
+	s.Equal(count, after)
+}
+
+func (s *SQLTestSuite) TestIteratorRawBytes() {
+	sess := s.SQLBuilder()
+	artist := sess.Collection("artist")
+
+	err := artist.Truncate()
+	s.NoError(err)
+
+	_, err = artist.Insert(map[string]string{"name": "Raw"})
+	s.NoError(err)
+
+	iter := sess.Select("id", "name", db.Raw("NULL AS nothing")).From("artist").Iterator()
+	defer iter.Close()
+
+	_, err = iter.RawBytes("name")
+	s.Error(err)
+
+	s.True(iter.Next())
+
+	name, err := iter.RawBytes("name")
+	s.NoError(err)
+	s.Equal("Raw", string(name))
+
+	nothing, err := iter.RawBytes("nothing")
+	s.NoError(err)
+	s.Nil(nothing)
+
+	_, err = iter.RawBytes("missing")
+	s.Error(err)
+
+	s.False(iter.Next())
+	s.NoError(iter.Err())
+
+	_, err = iter.RawBytes("name")
+	s.Error(err)
+}
+
+func (s *SQLTestSuite) TestConcurrentSessionUse() {
+	if s.Adapter() == "ql" {
+		s.T().Skip("Currently not supported.")
+	}
+
+	type artistType struct {
+		ID   int64  `db:"id,omitempty"`
+		Name string `db:"name"`
+	}
+
+	sess := s.SQLBuilder()
+
+	err := sess.Collection("artist").Truncate()
+	s.NoError(err)
+
+	// Selects, inserts and transactions on the same session, meant to be run
+	// with -race.
+	base := sess.SelectFrom("artist")
+
+	const workers, rounds = 8, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds*3)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				name := fmt.Sprintf("worker %d, round %d", i, j)
+
+				if _, err := sess.InsertInto("artist").Values(artistType{Name: name}).Exec(); err != nil {
+					errs <- err
+					continue
+				}
+
+				var item artistType
+				if err := base.Where("name", name).One(&item); err != nil {
+					errs <- err
+				} else if item.Name != name {
+					errs <- fmt.Errorf("expecting %q, got %q", name, item.Name)
+				}
+
+				err := sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+					_, err := tx.Update("artist").Set("name", name+" (tx)").Where("name", name).Exec()
+					return err
+				})
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		s.NoError(err)
+	}
+
+	count, err := sess.Collection("artist").Find(db.Cond{"name LIKE": "% (tx)"}).Count()
+	s.NoError(err)
+	s.Equal(uint64(workers*rounds), count)
+}
+
+func (s *SQLTestSuite) TestAllIntoPointers() {
+	type artistType struct {
+		ID   int64  `db:"id,omitempty"`
+		Name string `db:"name"`
+	}
+
+	sess := s.SQLBuilder()
+	artist := sess.Collection("artist")
+
+	err := artist.Truncate()
+	s.NoError(err)
+
+	for _, name := range []string{"Ozzy", "Flea", "Slash"} {
+		_, err := artist.Insert(artistType{Name: name})
+		s.NoError(err)
+	}
+
+	q := sess.Select("id", "name").From("artist").OrderBy("name")
+	if s.Adapter() == "ql" {
+		q = sess.Select("id() AS id", "name").From("artist").OrderBy("name")
+	}
+
+	var artists []*artistType
+	s.NoError(q.All(&artists))
+	if s.Len(artists, 3) {
+		s.Equal("Flea", artists[0].Name)
+		s.Equal("Ozzy", artists[1].Name)
+		s.Equal("Slash", artists[2].Name)
+		s.NotZero(artists[0].ID)
+		// Each row has its own element.
+		s.False(artists[0] == artists[1])
+	}
+
+	var maps []*map[string]interface{}
+	s.NoError(q.All(&maps))
+	if s.Len(maps, 3) {
+		s.NotNil((*maps[0])["name"])
+		s.False(maps[0] == maps[1])
+	}
+
+	var one *artistType
+	s.NoError(q.One(&one))
+	if s.NotNil(one) {
+		s.Equal("Flea", one.Name)
+	}
+
+	none := []*artistType{{Name: "Stale"}}
+	s.NoError(q.Where("name", "Nobody").All(&none))
+	s.NotNil(none)
+	s.Len(none, 0)
+}
+
+func (s *SQLTestSuite) TestInsertDefaultKeyword() {
+	if s.Adapter() == "sqlite" || s.Adapter() == "ql" {
+		s.T().Skip("DEFAULT is not supported in VALUES.")
+	}
+
+	sess := s.SQLBuilder()
+	artist := sess.Collection("artist")
+
+	err := artist.Truncate()
+	s.NoError(err)
+
+	_, err = sess.InsertInto("artist").Columns("id", "name").Values(db.Default, "Ozzy").Exec()
+	s.NoError(err)
+
+	var item struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	s.NoError(artist.Find().One(&item))
+	s.NotZero(item.ID)
+	s.Equal("Ozzy", item.Name)
+}
+
+func (s *SQLTestSuite) TestPreparedInsertInTx() {
+	sess := s.SQLBuilder()
+
+	err := sess.Collection("artist").Truncate()
+	s.NoError(err)
+
+	tx, err := sess.NewTx(nil)
+	s.NoError(err)
+
+	stmt, err := tx.PrepareStmt(tx.InsertInto("artist").Columns("name"))
+	s.NoError(err)
+
+	for i := 0; i < 100; i++ {
+		_, err = stmt.Exec(fmt.Sprintf("Artist %d", i))
+		s.NoError(err)
+	}
+
+	byName, err := tx.PrepareStmt(tx.Select("id").From("artist").Where("name = ?"))
+	s.NoError(err)
+
+	var id int64
+	s.NoError(byName.QueryRow("Artist 42").Scan(&id))
+	s.NotZero(id)
+
+	s.NoError(tx.Commit())
+
+	count, err := sess.Collection("artist").Find().Count()
+	s.NoError(err)
+	s.Equal(uint64(100), count)
+
+	// Statements are closed along with the transaction.
+	_, err = stmt.Exec("Artist 100")
+	s.Error(err)
+}
+
+func (s *SQLTestSuite) TestSubscribe() {
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+	s.NoError(artist.Truncate())
+
+	var mu sync.Mutex
+	var events []db.Event
+	unsubscribe := sess.Subscribe("artist", func(ev db.Event) {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	})
+	defer unsubscribe()
+
+	last := func() db.Event {
+		mu.Lock()
+		defer mu.Unlock()
+		s.NotEmpty(events)
+		return events[len(events)-1]
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events)
+	}
+
+	id, err := artist.Insert(map[string]string{"name": "Ozzy"})
+	s.NoError(err)
+	s.Equal(db.Event{Operation: db.OperationInsert, Table: "artist", Keys: []interface{}{id}}, last())
+
+	s.NoError(artist.Find(id).Update(map[string]string{"name": "Ozzy Osbourne"}))
+	ev := last()
+	s.Equal(db.OperationUpdate, ev.Operation)
+	if s.Adapter() != "ql" {
+		// ql finds rows by id() instead of by primary key.
+		s.Equal(1, len(ev.Keys))
+	}
+
+	// Rolled back changes are not reported.
+	err = sess.Tx(nil, func(tx sqlbuilder.Tx) error {
+		if _, err := tx.Collection("artist").Insert(map[string]string{"name": "Nobody"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	s.Error(err)
+	s.Equal(2, count())
+
+	// Committed ones are, after the commit.
+	err = sess.Tx(nil, func(tx sqlbuilder.Tx) error {
+		if _, err := tx.Collection("artist").Insert(map[string]string{"name": "Flea"}); err != nil {
+			return err
+		}
+		s.Equal(2, count())
+		return nil
+	})
+	s.NoError(err)
+	s.Equal(3, count())
+	s.Equal(db.OperationInsert, last().Operation)
+
+	// Statements built with the SQL builder are not reported.
+	_, err = sess.InsertInto("artist").Values(map[string]string{"name": "Nobody"}).Exec()
+	s.NoError(err)
+	_, err = sess.Update("artist").Set("name", "x").Exec()
+	s.NoError(err)
+	s.Equal(3, count())
+
+	s.NoError(artist.Find(db.Cond{"name": "x"}).Delete())
+	ev = last()
+	s.Equal(db.OperationDelete, ev.Operation)
+	if s.Adapter() == "postgresql" {
+		// The keys of the deleted rows are read from RETURNING.
+		s.Equal(3, len(ev.Keys))
+	} else {
+		s.Nil(ev.Keys)
+	}
+
+	// Changes that match no rows are not reported.
+	s.NoError(artist.Find(db.Cond{"name": "x"}).Update(map[string]string{"name": "y"}))
+	s.Equal(4, count())
+
+	unsubscribe()
+	_, err = artist.Insert(map[string]string{"name": "Ozzy"})
+	s.NoError(err)
+	s.Equal(4, count())
+}
+
+type testResultCache struct {
+	mu sync.Mutex
+	m  map[string]*sqlbuilder.CachedRows
+}
+
+func (c *testResultCache) Get(key string) (*sqlbuilder.CachedRows, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rows, ok := c.m[key]
+	return rows, ok
+}
+
+func (c *testResultCache) Set(key string, rows *sqlbuilder.CachedRows, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key] = rows
+}
+
+func (s *SQLTestSuite) TestResultCache() {
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+	s.NoError(artist.Truncate())
+
+	_, err := artist.Insert(artistType{Name: "Ozzy"})
+	s.NoError(err)
+
+	cache := &testResultCache{m: map[string]*sqlbuilder.CachedRows{}}
+	sess.SetResultCache(cache)
+	defer sess.SetResultCache(nil)
+
+	q := sess.SelectFrom("artist").OrderBy("name").Cacheable(time.Minute)
+
+	var artists []artistType
+	s.NoError(q.All(&artists))
+	s.Equal(1, len(artists))
+	s.Equal(1, len(cache.m))
+
+	// Cached rows are served until they expire, even if they're stale.
+	_, err = artist.Insert(artistType{Name: "Flea"})
+	s.NoError(err)
+
+	artists = nil
+	s.NoError(q.All(&artists))
+	s.Equal(1, len(artists))
+	s.Equal("Ozzy", artists[0].Name)
+
+	// Queries that are not marked as cacheable read the database.
+	artists = nil
+	s.NoError(sess.SelectFrom("artist").OrderBy("name").All(&artists))
+	s.Equal(2, len(artists))
+
+	// So do queries within transactions.
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		var artists []artistType
+		if err := tx.SelectFrom("artist").OrderBy("name").Cacheable(time.Minute).All(&artists); err != nil {
+			return err
+		}
+		s.Equal(2, len(artists))
+		return nil
+	})
+	s.NoError(err)
+	s.Equal(1, len(cache.m))
+}
+
+func (s *SQLTestSuite) TestPreload() {
+	if s.Adapter() == "ql" {
+		s.T().Skip("Currently not supported.")
+	}
+
+	sess := s.SQLBuilder()
+
+	artist := sess.Collection("artist")
+	publication := sess.Collection("publication")
+	s.NoError(artist.Truncate())
+	s.NoError(publication.Truncate())
+
+	type publicationType struct {
+		ID       int64  `db:"id,omitempty"`
+		Title    string `db:"title"`
+		AuthorID int64  `db:"author_id"`
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := artist.Insert(artistType{Name: fmt.Sprintf("Author %d", i)})
+		s.NoError(err)
+	}
+
+	var artists []artistType
+	s.NoError(artist.Find().OrderBy("name").All(&artists))
+	s.Equal(3, len(artists))
+
+	for i, title := range []string{"Pedro Páramo", "El Llano en llamas", "Aura"} {
+		_, err := publication.Insert(publicationType{Title: title, AuthorID: artists[i/2].ID})
+		s.NoError(err)
+	}
+
+	defer func(n int) {
+		db.PreloadBatchSize = n
+	}(db.PreloadBatchSize)
+	db.PreloadBatchSize = 2
+
+	var byAuthor map[int64][]publicationType
+	err := db.Preload(sess, artists, "AuthorID", publication, &byAuthor)
+	s.NoError(err)
+	s.Equal(2, len(byAuthor))
+	s.Equal(2, len(byAuthor[artists[0].ID]))
+	s.Equal(1, len(byAuthor[artists[1].ID]))
+	s.Equal("Aura", byAuthor[artists[1].ID][0].Title)
+	s.Nil(byAuthor[artists[2].ID])
+
+	var byKey map[int64][]*publicationType
+	err = db.Preload(sess, []int64{artists[1].ID}, "author_id", publication, &byKey)
+	s.NoError(err)
+	s.Equal(1, len(byKey))
+	s.Equal("Aura", byKey[artists[1].ID][0].Title)
+}
+
+func (s *SQLTestSuite) TestCreateTableFromStruct() {
+	if s.Adapter() == "ql" {
+		s.T().Skip("Currently not supported.")
+	}
+
+	type bookmark struct {
+		ID    int64   `db:"id,omitempty"`
+		URL   string  `db:"url,type=varchar(255)"`
+		Title *string `db:"title"`
+		Stars int32   `db:"stars"`
+	}
+
+	sess := s.SQLBuilder()
+
+	_, err := sess.Exec(`DROP TABLE IF EXISTS bookmarks`)
+	s.NoError(err)
+
+	_, err = sess.CreateTable("bookmarks").Struct(bookmark{}).Exec()
+	s.NoError(err)
+
+	// Running it again is harmless with IfNotExists.
+	_, err = sess.CreateTable("bookmarks").Struct(bookmark{}).IfNotExists().Exec()
+	s.NoError(err)
+
+	bookmarks := sess.Collection("bookmarks")
+
+	id, err := bookmarks.Insert(bookmark{URL: "https://example.org", Stars: 5})
+	s.NoError(err)
+	s.NotNil(id)
+
+	var item bookmark
+	s.NoError(bookmarks.Find().One(&item))
+	s.NotZero(item.ID)
+	s.Equal("https://example.org", item.URL)
+	s.Nil(item.Title)
+	s.Equal(int32(5), item.Stars)
+
+	_, err = sess.Exec(`DROP TABLE bookmarks`)
+	s.NoError(err)
+}
+
+func (s *SQLTestSuite) TestDDLStatements() {
+	if s.Adapter() == "ql" {
+		s.T().Skip("Currently not supported.")
+	}
+
+	sess := s.SQLBuilder()
+
+	_, err := sess.DropTable("tags").IfExists().Exec()
+	s.NoError(err)
+
+	_, err = sess.CreateTable("tags").
+		Column("id", "serial", db.PrimaryKey).
+		Column("name", "varchar(60)", db.NotNull).
+		Exec()
+	s.NoError(err)
+
+	_, err = sess.AlterTable("tags").AddColumn("color", "varchar(20)").Exec()
+	s.NoError(err)
+
+	_, err = sess.InsertInto("tags").Values(map[string]interface{}{"name": "go", "color": "blue"}).Exec()
+	s.NoError(err)
+
+	var tag struct {
+		Name  string `db:"name"`
+		Color string `db:"color"`
+	}
+	s.NoError(sess.SelectFrom("tags").One(&tag))
+	s.Equal("go", tag.Name)
+	s.Equal("blue", tag.Color)
+
+	_, err = sess.DropTable("tags").Exec()
+	s.NoError(err)
+}
+
 func (s *SQLTestSuite) TestGetWithOffset() {
 	sess := s.SQLBuilder()
 
@@ -635,6 +1734,61 @@ func (s *SQLTestSuite) TestGetResultsOneByOne() {
 	}
 }
 
+func (s *SQLTestSuite) TestIteratorNext() {
+	sess := s.SQLBuilder()
+
+	q := sess.SelectFrom("artist").OrderBy("name")
+	if s.Adapter() == "ql" {
+		q = sess.Select("id() as id", "name").From("artist").OrderBy("name")
+	}
+
+	// Mapping rows into a struct.
+	var artist artistType
+	names := []string{}
+
+	iter := q.Iterator()
+	for iter.Next(&artist) {
+		s.NotZero(artist.ID)
+		names = append(names, artist.Name)
+	}
+	s.NoError(iter.Err())
+	s.NoError(iter.Close())
+	s.Equal([]string{"Chrono", "Flea", "Ozzie", "Slash"}, names)
+
+	// Scanning columns into plain values.
+	var id int64
+	var name string
+	names = []string{}
+
+	iter = q.Iterator()
+	for iter.Next(&id, &name) {
+		s.NotZero(id)
+		names = append(names, name)
+	}
+	s.NoError(iter.Err())
+	s.NoError(iter.Close())
+	s.Equal([]string{"Chrono", "Flea", "Ozzie", "Slash"}, names)
+
+	// Scanning a single column.
+	names = []string{}
+
+	iter = sess.Select("name").From("artist").OrderBy("name").Iterator()
+	for iter.Next(&name) {
+		names = append(names, name)
+	}
+	s.NoError(iter.Err())
+	s.NoError(iter.Close())
+	s.Equal([]string{"Chrono", "Flea", "Ozzie", "Slash"}, names)
+
+	// Errors stop the loop and are available through Err().
+	iter = sess.Select("name").From("artist").Iterator()
+	for iter.Next(&id, &name) {
+		s.T().Fatal("Expecting a scan error.")
+	}
+	s.Error(iter.Err())
+	s.NoError(iter.Close())
+}
+
 func (s *SQLTestSuite) TestGetAllResults() {
 	sess := s.SQLBuilder()
 
@@ -673,6 +1827,95 @@ func (s *SQLTestSuite) TestGetAllResults() {
 	s.NotZero(artistObjs[0].ID)
 }
 
+func (s *SQLTestSuite) TestAllIntoScalars() {
+	if s.Adapter() == "ql" {
+		s.T().Skip("Currently not supported.")
+	}
+
+	sess := s.SQLBuilder()
+
+	total, err := sess.Collection("artist").Find().Count()
+	s.NoError(err)
+	s.NotZero(total)
+
+	var ids []int64
+	s.NoError(sess.Select("id").From("artist").OrderBy("id").Iterator().All(&ids))
+	s.Equal(int(total), len(ids))
+	for i := 1; i < len(ids); i++ {
+		s.True(ids[i-1] < ids[i])
+	}
+
+	var names []string
+	s.NoError(sess.Collection("artist").Find().Select("name").All(&names))
+	s.Equal(int(total), len(names))
+	s.NotZero(names[0])
+
+	var nullable []*string
+	s.NoError(sess.Select(db.Raw("NULL AS name")).From("artist").Iterator().All(&nullable))
+	s.Equal(int(total), len(nullable))
+	s.Nil(nullable[0])
+
+	var nullStrings []sql.NullString
+	s.NoError(sess.Select("name").From("artist").Iterator().All(&nullStrings))
+	s.Equal(int(total), len(nullStrings))
+	s.True(nullStrings[0].Valid)
+
+	var none []int64
+	s.NoError(sess.Select("id").From("artist").Where("id < 0").Iterator().All(&none))
+	s.NotNil(none)
+	s.Equal(0, len(none))
+
+	err = sess.Select("id", "name").From("artist").Iterator().All(&ids)
+	s.Equal(sqlbuilder.ErrExpectingSingleColumn, err)
+}
+
+func (s *SQLTestSuite) TestAllIntoMap() {
+	if s.Adapter() == "ql" {
+		s.T().Skip("Currently not supported.")
+	}
+
+	sess := s.SQLBuilder()
+
+	var artists []artistType
+	s.NoError(sess.SelectFrom("artist").All(&artists))
+	s.NotZero(len(artists))
+
+	var names map[int64]string
+	s.NoError(sess.Select("id", "name").From("artist").Iterator().All(&names))
+	s.Equal(len(artists), len(names))
+	for _, artist := range artists {
+		s.Equal(artist.Name, names[artist.ID])
+	}
+
+	var ids map[string]*int64
+	s.NoError(sess.Collection("artist").Find().Select("name", "id").All(&ids))
+	s.Equal(len(artists), len(ids))
+	s.Equal(artists[0].ID, *ids[artists[0].Name])
+
+	var counts map[string]int64
+	err := sess.Select(db.Raw("'all'"), "id").From("artist").Iterator().All(&counts)
+	if len(artists) > 1 {
+		s.Equal(sqlbuilder.ErrDuplicateKey, err)
+	}
+
+	var maxID int64
+	for _, artist := range artists {
+		if artist.ID > maxID {
+			maxID = artist.ID
+		}
+	}
+	s.NoError(sess.Select(db.Raw("'all'"), "id").From("artist").OrderBy("id").Iterator().All(sqlbuilder.OverwriteKeys(&counts)))
+	s.Equal(map[string]int64{"all": maxID}, counts)
+
+	var empty map[int64]string
+	s.NoError(sess.Select("id", "name").From("artist").Where("id < 0").Iterator().All(&empty))
+	s.NotNil(empty)
+	s.Equal(0, len(empty))
+
+	err = sess.Select("id").From("artist").Iterator().All(&names)
+	s.Equal(sqlbuilder.ErrExpectingTwoColumns, err)
+}
+
 func (s *SQLTestSuite) TestInlineStructs() {
 	type reviewTypeDetails struct {
 		Name     string    `db:"name"`
@@ -1071,6 +2314,99 @@ func (s *SQLTestSuite) TestCompositeKeys() {
 		err := compositeKeys.InsertReturning(&item)
 		s.NoError(err)
 	}
+
+	{
+		n := rand.Intn(100000)
+
+		item := itemWithCompoundKey{
+			"ABCDEF",
+			strconv.Itoa(n),
+			"Some value",
+		}
+
+		_, err := compositeKeys.Insert(&item)
+		s.NoError(err)
+
+		// Finding by the key fields of a struct.
+		var item2 itemWithCompoundKey
+		err = compositeKeys.Find(itemWithCompoundKey{Code: item.Code, UserID: item.UserID}).One(&item2)
+		s.NoError(err)
+		s.Equal(item, item2)
+
+		item.SomeVal = "Some other value"
+		s.NoError(compositeKeys.Update(item))
+
+		err = compositeKeys.Find(&item).One(&item2)
+		s.NoError(err)
+		s.Equal("Some other value", item2.SomeVal)
+
+		err = compositeKeys.Find(itemWithCompoundKey{Code: item.Code}).One(&item2)
+		s.Error(err)
+
+		err = compositeKeys.Delete(itemWithCompoundKey{UserID: item.UserID})
+		s.Error(err)
+
+		s.NoError(compositeKeys.Delete(item))
+
+		err = compositeKeys.Find(item).One(&item2)
+		s.Equal(db.ErrNoMoreRows, err)
+	}
+}
+
+func (s *SQLTestSuite) TestFindOrCreate() {
+	if s.Adapter() == "ql" {
+		s.T().Skip("Currently not supported.")
+	}
+
+	sess := s.SQLBuilder()
+
+	compositeKeys := sess.Collection("composite_keys")
+
+	cond := db.Cond{"code": "FOC", "user_id": strconv.Itoa(rand.Intn(100000))}
+
+	item := itemWithCompoundKey{SomeVal: "Created"}
+	created, err := compositeKeys.FindOrCreate(cond, &item)
+	s.NoError(err)
+	s.True(created)
+	s.Equal("FOC", item.Code)
+	s.Equal(cond["user_id"], item.UserID)
+	s.Equal("Created", item.SomeVal)
+
+	item = itemWithCompoundKey{SomeVal: "Ignored"}
+	created, err = compositeKeys.FindOrCreate(cond, &item)
+	s.NoError(err)
+	s.False(created)
+	s.Equal("Created", item.SomeVal)
+
+	// Sessions racing to create the same row get the same one.
+	cond = db.Cond{"code": "FOC", "user_id": strconv.Itoa(100000 + rand.Intn(100000))}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	createdCount := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			item := itemWithCompoundKey{SomeVal: strconv.Itoa(i)}
+			created, err := compositeKeys.FindOrCreate(cond, &item)
+			s.NoError(err)
+			mu.Lock()
+			if created {
+				createdCount++
+			}
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	s.Equal(1, createdCount)
+
+	count, err := compositeKeys.Find(cond).Count()
+	s.NoError(err)
+	s.Equal(uint64(1), count)
+
+	_, err = compositeKeys.FindOrCreate(db.Cond{"code": db.NotEq("FOC")}, &item)
+	s.Error(err)
 }
 
 // Attempts to test database transactions.
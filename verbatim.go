@@ -0,0 +1,53 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+// VerbatimValue represents a value that is passed to the driver as it is,
+// see Verbatim.
+type VerbatimValue interface {
+	// Verbatim returns the wrapped value.
+	Verbatim() interface{}
+}
+
+type verbatimValue struct {
+	v interface{}
+}
+
+func (v verbatimValue) Verbatim() interface{} {
+	return v.v
+}
+
+// Verbatim wraps a query argument so adapters hand it to the driver untouched,
+// skipping the conversions they'd otherwise apply, like turning slices into
+// arrays or maps into JSONB. It's unwrapped right before the query is sent.
+//
+// Example:
+//
+//	// attrs is given to the driver as a map, instead of as JSONB.
+//	sess.Query(`SELECT set_attrs(?)`, db.Verbatim(attrs))
+//
+// It also works with scan destinations.
+func Verbatim(value interface{}) VerbatimValue {
+	return verbatimValue{v: value}
+}
+
+var _ = VerbatimValue(verbatimValue{})